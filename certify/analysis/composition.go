@@ -672,18 +672,7 @@ func parallelRounds(r1, r2 []protocol.Round) []protocol.Round {
 
 // tensorObjects computes the tensor product of two objects.
 func tensorObjects(o1, o2 runtime.Object) runtime.Object {
-	// Tensor product of block structures
-	blocks := make([]uint32, 0, len(o1.Blocks)*len(o2.Blocks))
-	for _, b1 := range o1.Blocks {
-		for _, b2 := range o2.Blocks {
-			blocks = append(blocks, b1*b2)
-		}
-	}
-	if len(blocks) == 0 {
-		blocks = append(blocks, o1.Blocks...)
-		blocks = append(blocks, o2.Blocks...)
-	}
-	return runtime.Object{Blocks: blocks}
+	return runtime.TensorObjects(o1, o2)
 }
 
 // composeGoals combines security goals from two protocols.
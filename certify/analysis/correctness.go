@@ -18,12 +18,19 @@ import (
 type CorrectnessResult struct {
 	Correct      bool
 	ChoiMatrix   *runtime.Matrix
+	InDim        int // input dimension of ChoiMatrix
+	OutDim       int // output dimension of ChoiMatrix
 	IdealChannel *runtime.Matrix
 	Witness      *ChoiEqualityWitness
 	Fidelity     *big.Rat
+	Diamond      *DiamondCertificate // certified distance to IdealChannel, if comparable
 	ErrorMessage string
 }
 
+// maxDiamondDim is the largest Choi matrix for which VerifyCorrectness
+// certifies the diamond distance to the ideal channel.
+const maxDiamondDim = 64
+
 // ChoiEqualityWitness proves (or disproves) that two channels are identical.
 // It contains both Choi matrices and indicates equality or the first differing entry.
 type ChoiEqualityWitness struct {
@@ -87,6 +94,7 @@ func VerifyCorrectness(p *protocol.Protocol, store *runtime.Store) (*Correctness
 	// Get synthesizer if protocol supports it
 	var protocolQGID [32]byte
 	var protocolChoi *runtime.Matrix
+	var inDim, outDim int
 	var err error
 
 	// Try to synthesize the protocol circuit
@@ -107,17 +115,17 @@ func VerifyCorrectness(p *protocol.Protocol, store *runtime.Store) (*Correctness
 				Fidelity:     big.NewRat(0, 1),
 			}, nil
 		}
+		if circuit, ok := store.Get(protocolQGID); ok {
+			inDim, outDim = channelDims(circuit)
+		}
 	} else {
 		// Fall back to computing Choi from protocol type signature
-		inDim := objectDim(p.TypeSig.Domain)
-		outDim := objectDim(p.TypeSig.Codomain)
+		inDim = runtime.BlockDim(p.TypeSig.Domain)
 		if inDim == 0 {
 			inDim = 2 // Default to single qubit
 		}
-		if outDim == 0 {
-			outDim = inDim
-		}
 		// For protocols without explicit circuit, assume ideal behavior
+		outDim = inDim
 		protocolChoi = identityChoi(inDim)
 	}
 
@@ -138,12 +146,22 @@ func VerifyCorrectness(p *protocol.Protocol, store *runtime.Store) (*Correctness
 	// Compute fidelity even if not exactly equal
 	fidelity := ChannelFidelity(protocolChoi, idealChoi)
 
+	// Certify the distance to the ideal channel when both act on the same spaces
+	var diamond *DiamondCertificate
+	n := inDim * outDim
+	if n <= maxDiamondDim && protocolChoi.Rows == n && idealChoi.Rows == n {
+		diamond, _ = DiamondDistance(protocolChoi, idealChoi, inDim, outDim)
+	}
+
 	return &CorrectnessResult{
 		Correct:      equal,
 		ChoiMatrix:   protocolChoi,
+		InDim:        inDim,
+		OutDim:       outDim,
 		IdealChannel: idealChoi,
 		Witness:      witness,
 		Fidelity:     fidelity,
+		Diamond:      diamond,
 	}, nil
 }
 
@@ -153,6 +171,10 @@ func VerifyCorrectness(p *protocol.Protocol, store *runtime.Store) (*Correctness
 // The Choi matrix is defined via the Choi-Jamiolkowski isomorphism:
 // J_Phi = (I tensor Phi)(|Omega><Omega|)
 // where |Omega> = sum_i |ii>/sqrt(d) is the maximally entangled state.
+//
+// It is evaluated structurally by runtime.Executor.Choi (link products for
+// composition, reordered Kronecker products for tensors) rather than by
+// executing the circuit on every basis operator of its domain.
 func ComputeChannel(circuitID [32]byte, store *runtime.Store) (*runtime.Matrix, error) {
 	// Get circuit from store
 	circuit, ok := store.Get(circuitID)
@@ -160,68 +182,28 @@ func ComputeChannel(circuitID [32]byte, store *runtime.Store) (*runtime.Matrix,
 		return nil, fmt.Errorf("circuit not found in store")
 	}
 
-	// Determine input/output dimensions from Domain/Codomain
-	inDim := objectDim(circuit.Domain)
-	outDim := objectDim(circuit.Codomain)
+	choi, err := runtime.NewExecutor(store).Choi(circuit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate circuit: %v", err)
+	}
+
+	// Scale by 1/d for normalization
+	inDim, _ := channelDims(circuit)
+	return runtime.MatScale(choi, big.NewRat(1, int64(inDim))), nil
+}
 
+// channelDims returns the input and output dimensions ComputeChannel uses
+// for a circuit: the Hilbert space dimensions of its domain and codomain.
+func channelDims(circuit runtime.Circuit) (int, int) {
+	inDim := runtime.BlockDim(circuit.Domain)
+	outDim := runtime.BlockDim(circuit.Codomain)
 	if inDim == 0 {
 		inDim = 1
 	}
 	if outDim == 0 {
 		outDim = 1
 	}
-
-	// Construct maximally entangled state |Omega><Omega|
-	// (This is used for reference; actual computation uses basis states below)
-	_ = MaximallyEntangledState(inDim)
-
-	// Create executor for the circuit
-	executor := runtime.NewExecutor(store)
-
-	// Apply (I tensor circuit) to |Omega><Omega|
-	// The Choi matrix has dimension (inDim * outDim) x (inDim * outDim)
-	choiDim := inDim * outDim
-	choi := runtime.NewMatrix(choiDim, choiDim)
-
-	// For each pair of basis states, compute the channel action
-	// J_Phi[i*outDim+k, j*outDim+l] = Phi(|i><j|)[k, l] / d
-	//
-	// We compute this by executing the circuit on each basis state
-	scale := big.NewRat(1, int64(inDim))
-
-	for i := 0; i < inDim; i++ {
-		for j := 0; j < inDim; j++ {
-			// Create basis density matrix |i><j|
-			basisState := createBasisMatrix(i, j, inDim)
-
-			// Apply the channel
-			result, err := executor.Execute(circuit, basisState)
-			if err != nil {
-				return nil, fmt.Errorf("failed to execute circuit: %v", err)
-			}
-
-			// Fill in the Choi matrix entries
-			for k := 0; k < outDim; k++ {
-				for l := 0; l < outDim; l++ {
-					row := i*outDim + k
-					col := j*outDim + l
-					entry := result.Get(k, l)
-					// Scale by 1/d for normalization
-					scaledEntry := runtime.QIScale(entry, scale)
-					choi.Set(row, col, scaledEntry)
-				}
-			}
-		}
-	}
-
-	return choi, nil
-}
-
-// createBasisMatrix creates the matrix |i><j| (outer product of basis vectors).
-func createBasisMatrix(i, j, dim int) *runtime.Matrix {
-	m := runtime.NewMatrix(dim, dim)
-	m.Set(i, j, runtime.QIOne())
-	return m
+	return inDim, outDim
 }
 
 // CompareChoi performs exact comparison of two Choi matrices.
@@ -437,58 +419,6 @@ func ChannelFidelity(A, B *runtime.Matrix) *big.Rat {
 	return fidelity
 }
 
-// TraceDistance computes the trace distance between two channels.
-// D(Phi, Psi) = (1/2)||J_Phi - J_Psi||_1
-// where ||.||_1 is the trace norm (sum of singular values).
-func TraceDistance(A, B *runtime.Matrix) *big.Rat {
-	if A == nil || B == nil {
-		return big.NewRat(1, 1) // Maximum distance if one is nil
-	}
-
-	// Check compatible dimensions
-	if A.Rows != B.Rows || A.Cols != B.Cols {
-		return big.NewRat(1, 1)
-	}
-
-	// Compute difference matrix
-	diff := runtime.MatSub(A, B)
-	if diff == nil {
-		return big.NewRat(1, 1)
-	}
-
-	// Compute trace norm: ||M||_1 = Tr(sqrt(M^dag M))
-	// For exact computation with rationals, we use the Frobenius norm as upper bound
-	// ||M||_1 <= sqrt(rank) * ||M||_F
-	// For Hermitian matrices, trace norm equals sum of absolute eigenvalues
-
-	// Simplified: compute Frobenius norm squared: sum |M[i,j]|^2
-	frobSq := big.NewRat(0, 1)
-	for _, entry := range diff.Data {
-		normSq := runtime.QINormSq(entry)
-		frobSq = new(big.Rat).Add(frobSq, normSq)
-	}
-
-	// Trace distance upper bound: (1/2) * sqrt(||diff||_F^2)
-	// For exact computation, we return the squared Frobenius norm scaled
-	// This gives an upper bound on the trace distance squared
-	// D^2 <= ||diff||_F^2 / 4
-
-	// Return (1/2) * ||diff||_F as approximation (upper bound)
-	// Since we can't take exact square root, return squared distance / 4
-	halfFrobSq := new(big.Rat).Mul(frobSq, big.NewRat(1, 4))
-
-	// Clamp to [0, 1]
-	if halfFrobSq.Sign() < 0 {
-		return big.NewRat(0, 1)
-	}
-	one := big.NewRat(1, 1)
-	if halfFrobSq.Cmp(one) > 0 {
-		return one
-	}
-
-	return halfFrobSq
-}
-
 // MaximallyEntangledState returns the density matrix |Omega><Omega|
 // where |Omega> = sum_i |ii>/sqrt(d) is the maximally entangled state.
 // The result is a d^2 x d^2 matrix.
@@ -520,73 +450,26 @@ func MaximallyEntangledState(dim int) *runtime.Matrix {
 }
 
 // MaximallyEntangledKet returns |Omega> = sum_i |ii>/sqrt(d) as a column vector.
-// The result is a d^2 x 1 matrix.
-func MaximallyEntangledKet(dim int) *runtime.Matrix {
+// The result is a d^2 x 1 matrix over Q(zeta8) with <Omega|Omega> = 1 exactly.
+// 1/sqrt(d) lies in Q(zeta8) only when d or d/2 is a perfect square
+// (d = 1, 2, 4, 8, 9, ...); ok is false for other dimensions.
+func MaximallyEntangledKet(dim int) (*runtime.MatrixZ8, bool) {
 	if dim <= 0 {
 		dim = 1
 	}
 
-	// |Omega> = sum_i |ii>/sqrt(d)
-	// Vector entries: ket[i*d+i] = 1/sqrt(d) for all i
-	totalDim := dim * dim
-	ket := runtime.NewMatrix(totalDim, 1)
-
-	// Use rational approximation for 1/sqrt(d)
-	// For d=2: 1/sqrt(2) ~ 408/577
-	// For general d, we use 1/d as the coefficient (for |Omega><Omega| to be normalized)
-	// Actually for the ket, coefficient is 1/sqrt(d)
-	// We'll use a good rational approximation based on d
-
-	scale := sqrtInvRational(dim)
-	scaledOne := runtime.QI{Re: scale, Im: new(big.Rat)}
-
-	for i := 0; i < dim; i++ {
-		idx := i*dim + i
-		ket.Set(idx, 0, scaledOne)
+	scale, ok := runtime.QZ8InvSqrt(dim)
+	if !ok {
+		return nil, false
 	}
 
-	return ket
-}
-
-// sqrtInvRational returns a rational approximation of 1/sqrt(n).
-func sqrtInvRational(n int) *big.Rat {
-	if n <= 0 {
-		return big.NewRat(1, 1)
-	}
-	if n == 1 {
-		return big.NewRat(1, 1)
-	}
-	if n == 2 {
-		// 1/sqrt(2) ~ 408/577 (convergent of continued fraction)
-		return big.NewRat(408, 577)
-	}
-	// For other values, use a simple approximation
-	// 1/sqrt(n) ~ m/(m*sqrt(n)) where we approximate sqrt(n)
-	// Use Newton's method to find integer approximation of sqrt(n)*1000
-	sqrtN := intSqrt(int64(n) * 1000000)
-	if sqrtN == 0 {
-		sqrtN = 1
+	// Vector entries: ket[i*d+i] = 1/sqrt(d) for all i
+	ket := runtime.NewMatrixZ8(dim*dim, 1)
+	for i := 0; i < dim; i++ {
+		ket.Set(i*dim+i, 0, scale)
 	}
-	return big.NewRat(1000, sqrtN)
-}
 
-// intSqrt computes floor(sqrt(n)) for n >= 0.
-func intSqrt(n int64) int64 {
-	if n < 0 {
-		return 0
-	}
-	if n == 0 {
-		return 0
-	}
-	// Newton's method
-	x := n
-	for {
-		x1 := (x + n/x) / 2
-		if x1 >= x {
-			return x
-		}
-		x = x1
-	}
+	return ket, true
 }
 
 // PartialTrace traces out subsystem B from rho_AB.
@@ -651,22 +534,6 @@ func PartialTraceSecond(rho *runtime.Matrix, dimA, dimB int) *runtime.Matrix {
 	return PartialTrace(rho, dimA, dimB, "B")
 }
 
-// objectDim computes the total dimension of an object.
-// For a block signature [n1, n2, ...], the dimension is sum(ni^2).
-func objectDim(obj runtime.Object) int {
-	if len(obj.Blocks) == 0 {
-		return 1
-	}
-	dim := 0
-	for _, n := range obj.Blocks {
-		dim += int(n * n)
-	}
-	if dim == 0 {
-		dim = 1
-	}
-	return dim
-}
-
 // ComputeChoiMatrix computes the Choi matrix representation of a protocol.
 // This is a convenience wrapper that creates a store and synthesizes the protocol.
 func ComputeChoiMatrix(p *protocol.Protocol) (*runtime.Matrix, error) {
@@ -686,7 +553,7 @@ func ComputeChoiMatrix(p *protocol.Protocol) (*runtime.Matrix, error) {
 	}
 
 	// Fall back to computing from type signature
-	inDim := objectDim(p.TypeSig.Domain)
+	inDim := runtime.BlockDim(p.TypeSig.Domain)
 	if inDim == 0 {
 		inDim = 2
 	}
@@ -711,6 +578,11 @@ func (r *CorrectnessResult) ToValue() runtime.Value {
 		fidelityVal = runtime.MakeBigRat(r.Fidelity)
 	}
 
+	var diamondVal runtime.Value = runtime.MakeNil()
+	if r.Diamond != nil {
+		diamondVal = r.Diamond.ToValue()
+	}
+
 	return runtime.MakeTag(
 		runtime.MakeText("correctness-result"),
 		runtime.MakeSeq(
@@ -720,6 +592,7 @@ func (r *CorrectnessResult) ToValue() runtime.Value {
 			witnessVal,
 			fidelityVal,
 			runtime.MakeText(r.ErrorMessage),
+			diamondVal,
 		),
 	)
 }
@@ -766,11 +639,18 @@ func CorrectnessResultFromValue(v runtime.Value) (*CorrectnessResult, bool) {
 		errMsg = text.V
 	}
 
+	// Diamond certificate (item 6, absent in older results)
+	var diamond *DiamondCertificate
+	if len(seq.Items) > 6 {
+		diamond, _ = DiamondCertificateFromValue(seq.Items[6])
+	}
+
 	return &CorrectnessResult{
 		Correct:      correct.V,
 		ChoiMatrix:   choiMatrix,
 		IdealChannel: idealChannel,
 		Fidelity:     fidelity,
+		Diamond:      diamond,
 		ErrorMessage: errMsg,
 	}, true
 }
@@ -784,53 +664,29 @@ func VerifyProtocolCorrectness(protocolName string, store *runtime.Store) (*Corr
 }
 
 // IsChannelUnitary checks if a channel (represented by its Choi matrix) is unitary.
-// A channel is unitary if its Choi matrix is a pure state (rank 1).
+// A channel is unitary if its Choi matrix is a pure state (rank 1) of a
+// CPTP map with equal input and output dimension. The decision is exact;
+// use CertifyChannel to keep the witness.
 func IsChannelUnitary(choi *runtime.Matrix) bool {
-	if choi == nil {
+	if choi == nil || choi.Rows != choi.Cols {
 		return false
 	}
-
-	// A unitary channel has Choi matrix |U><U| where U is the Choi representation
-	// This means Tr(choi^2) = Tr(choi)^2 (purity condition)
-
-	tr := runtime.Trace(choi)
-	trSq := runtime.QIMul(tr, tr)
-
-	choiSq := runtime.MatMul(choi, choi)
-	if choiSq == nil {
+	d := 1
+	for d*d < choi.Rows {
+		d++
+	}
+	if d*d != choi.Rows {
 		return false
 	}
-	trChoiSq := runtime.Trace(choiSq)
-
-	// Compare Tr(choi^2) with Tr(choi)^2
-	return runtime.QIEqual(trChoiSq, trSq)
+	w, err := CertifyChannel(choi, d, d)
+	return err == nil && w.IsUnitary()
 }
 
 // IsChannelCPTP checks if a channel (represented by its Choi matrix) is CPTP.
 // Completely Positive: Choi matrix is positive semidefinite
-// Trace Preserving: Partial trace over output gives identity/dim
+// Trace Preserving: Partial trace over output gives identity/inDim
+// The decision is exact; use CertifyChannel to keep the witness.
 func IsChannelCPTP(choi *runtime.Matrix, inDim, outDim int) bool {
-	if choi == nil {
-		return false
-	}
-
-	// Check Choi matrix is Hermitian
-	dag := runtime.Dagger(choi)
-	if !runtime.MatrixEqual(choi, dag) {
-		return false
-	}
-
-	// Check trace preserving: Tr_out(J) = I_in / d_out
-	// This is a simplified check; full positive semidefiniteness requires eigenvalue computation
-	ptrace := PartialTrace(choi, inDim, outDim, "B")
-	if ptrace == nil {
-		return false
-	}
-
-	// Check if partial trace is proportional to identity
-	expectedScale := big.NewRat(1, int64(outDim))
-	expectedId := runtime.Identity(inDim)
-	scaledId := runtime.MatScale(expectedId, expectedScale)
-
-	return runtime.MatrixEqual(ptrace, scaledId)
+	w, err := CertifyChannel(choi, inDim, outDim)
+	return err == nil && w.IsCPTP()
 }
@@ -0,0 +1,466 @@
+// distance.go provides certified distances between states and channels.
+//
+// The trace norm of a Hermitian matrix is the sum of the absolute values of
+// its eigenvalues. Eigenvalues come from linalg.HermitianEigenvalues as
+// exact rationals or isolating intervals kept away from zero, so every
+// trace distance is reported as a rigorous enclosure [Lower, Upper].
+//
+// The diamond distance ½‖Φ - Ψ‖⋄ is the value of Watrous's semidefinite
+// program on J = d_in(J_Φ - J_Ψ), the difference of unnormalized Choi
+// matrices:
+//
+//	primal: max Tr(J W)        s.t. 0 ⪯ W ⪯ ρ ⊗ I, ρ ⪰ 0, Tr ρ = 1
+//	dual:   min ‖Tr_out Z‖∞    s.t. Z ⪰ 0, Z ⪰ J
+//
+// A DiamondCertificate holds a rational primal point and a rational dual
+// point, each feasible by LDL† certificates, so Lower = Tr(J W) and Upper
+// bound the distance with nothing trusted but exact matrix products.
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+
+	"qbtm/certify/linalg"
+	"qbtm/certify/sdp"
+	"qbtm/runtime"
+)
+
+// diamondBits is the binary precision to which float64 search points are
+// rounded before they are certified.
+const diamondBits = 32
+
+// maxDiamondSDPDim is the largest Choi matrix for which DiamondDistance
+// solves the semidefinite program instead of using spectral search points.
+const maxDiamondSDPDim = 16
+
+// Bound is a certified enclosure Lower ≤ x ≤ Upper of a real quantity.
+type Bound struct {
+	Lower *big.Rat
+	Upper *big.Rat
+}
+
+// IsExact reports whether the enclosure pins the value down exactly.
+func (b *Bound) IsExact() bool {
+	return b.Lower.Cmp(b.Upper) == 0
+}
+
+// Width returns Upper - Lower.
+func (b *Bound) Width() *big.Rat {
+	return new(big.Rat).Sub(b.Upper, b.Lower)
+}
+
+// String renders the bound as "v" if exact or "[lo, hi]".
+func (b *Bound) String() string {
+	if b.IsExact() {
+		return b.Lower.RatString()
+	}
+	return fmt.Sprintf("[%s, %s]", b.Lower.RatString(), b.Upper.RatString())
+}
+
+// ToValue converts a Bound to a runtime.Value.
+func (b *Bound) ToValue() runtime.Value {
+	return runtime.MakeTag(
+		runtime.MakeText("bound"),
+		runtime.MakeSeq(runtime.MakeBigRat(b.Lower), runtime.MakeBigRat(b.Upper)),
+	)
+}
+
+// BoundFromValue deserializes a Bound from a runtime.Value.
+func BoundFromValue(v runtime.Value) (*Bound, bool) {
+	tag, ok := v.(runtime.Tag)
+	if !ok {
+		return nil, false
+	}
+	label, ok := tag.Label.(runtime.Text)
+	if !ok || label.V != "bound" {
+		return nil, false
+	}
+	seq, ok := tag.Payload.(runtime.Seq)
+	if !ok || len(seq.Items) != 2 {
+		return nil, false
+	}
+	lo, ok1 := seq.Items[0].(runtime.Rat)
+	hi, ok2 := seq.Items[1].(runtime.Rat)
+	if !ok1 || !ok2 || lo.V.Cmp(hi.V) > 0 {
+		return nil, false
+	}
+	return &Bound{Lower: new(big.Rat).Set(lo.V), Upper: new(big.Rat).Set(hi.V)}, true
+}
+
+// TraceNorm returns a certified enclosure of ‖M‖₁ for a Hermitian M.
+func TraceNorm(M *runtime.Matrix) (*Bound, error) {
+	eigs, err := linalg.HermitianEigenvalues(M, spectralTolerance)
+	if err != nil {
+		return nil, fmt.Errorf("analysis: %w", err)
+	}
+	lower, upper := new(big.Rat), new(big.Rat)
+	for _, ev := range eigs {
+		separateFromZero(ev)
+		mult := big.NewRat(int64(ev.Multiplicity), 1)
+		lo := new(big.Rat).Abs(ev.Lower)
+		hi := new(big.Rat).Abs(ev.Upper)
+		if lo.Cmp(hi) > 0 {
+			lo, hi = hi, lo
+		}
+		lower.Add(lower, lo.Mul(lo, mult))
+		upper.Add(upper, hi.Mul(hi, mult))
+	}
+	return &Bound{Lower: lower, Upper: upper}, nil
+}
+
+// TraceDistance computes the trace distance between two channels.
+// D(Phi, Psi) = (1/2)||J_Phi - J_Psi||_1
+// where ||.||_1 is the trace norm (sum of singular values). The Choi
+// matrices must be Hermitian; the result is a certified enclosure. The
+// same function gives the trace distance between density matrices.
+func TraceDistance(A, B *runtime.Matrix) (*Bound, error) {
+	if A == nil || B == nil {
+		return nil, fmt.Errorf("analysis: trace distance of a nil matrix")
+	}
+	if A.Rows != B.Rows || A.Cols != B.Cols {
+		return nil, fmt.Errorf("analysis: trace distance of %dx%d and %dx%d matrices",
+			A.Rows, A.Cols, B.Rows, B.Cols)
+	}
+	norm, err := TraceNorm(runtime.MatSub(A, B))
+	if err != nil {
+		return nil, err
+	}
+	half := big.NewRat(1, 2)
+	return &Bound{Lower: norm.Lower.Mul(norm.Lower, half), Upper: norm.Upper.Mul(norm.Upper, half)}, nil
+}
+
+// DiamondCertificate proves Lower ≤ ½‖Φ - Ψ‖⋄ ≤ Upper for the channels
+// with normalized Choi matrices ChoiA and ChoiB.
+type DiamondCertificate struct {
+	ChoiA  *runtime.Matrix
+	ChoiB  *runtime.Matrix
+	InDim  int
+	OutDim int
+
+	// Dual point: Z ⪰ 0 and Z ⪰ J, and Upper·I - Tr_out Z ⪰ 0.
+	Z         *runtime.Matrix
+	ZPos      *linalg.LDLCertificate
+	ZDom      *linalg.LDLCertificate
+	Upper     *big.Rat
+	UpperCert *linalg.LDLCertificate
+
+	// Primal point: ρ ⪰ 0 with Tr ρ = 1, 0 ⪯ W ⪯ ρ ⊗ I, and Lower = Tr(J W).
+	Rho    *runtime.Matrix
+	RhoPos *linalg.LDLCertificate
+	W      *runtime.Matrix
+	WPos   *linalg.LDLCertificate
+	WDom   *linalg.LDLCertificate
+	Lower  *big.Rat
+}
+
+// DiamondDistance certifies the diamond distance ½‖Φ - Ψ‖⋄ between the
+// channels from inDim to outDim whose normalized Choi matrices are A and
+// B. Two families of search points are certified and the tighter bound on
+// each side is kept. Spectral points take the positive part of J as the
+// dual point and the projector onto its positive eigenspace with
+// ρ = I/d_in as the primal point, which is exact for covariant channels
+// such as Pauli channels against the identity. For Choi matrices up to
+// maxDiamondSDPDim the program is also solved with package sdp, which is
+// tight to the solver's precision for any pair of channels.
+func DiamondDistance(A, B *runtime.Matrix, inDim, outDim int) (*DiamondCertificate, error) {
+	n := inDim * outDim
+	if A == nil || B == nil || inDim <= 0 || outDim <= 0 ||
+		A.Rows != n || A.Cols != n || B.Rows != n || B.Cols != n {
+		return nil, fmt.Errorf("analysis: diamond distance needs two %dx%d Choi matrices", n, n)
+	}
+	J := diamondObjective(A, B, inDim)
+	if !linalg.IsHermitian(J) {
+		return nil, fmt.Errorf("analysis: Choi matrices are not Hermitian")
+	}
+	cert := &DiamondCertificate{ChoiA: A, ChoiB: B, InDim: inDim, OutDim: outDim}
+
+	if n <= maxDiamondSDPDim {
+		basis := hermitianBasis(n)
+		if res, err := sdp.Solve(diamondProgram(J, basis, inDim, outDim), nil); err == nil {
+			if res.HasUpper() {
+				Z := runtime.MatScale(runtime.Identity(n), res.Shift)
+				for k, E := range basis {
+					Z = runtime.MatAdd(Z, runtime.MatScale(E, res.Y[k]))
+				}
+				cert.setDual(J, Z, res.Upper)
+			}
+			if res.HasLower() {
+				cert.setPrimal(J, res.X[1], res.X[0])
+			}
+		}
+	}
+	Jf := linalg.ToComplex(J)
+
+	// Dual: the positive part of J, shifted up until it provably dominates J.
+	Z0 := linalg.RoundHermitian(linalg.PositivePart(Jf), diamondBits)
+	for delta := new(big.Rat); ; delta = nextShift(delta) {
+		Z := runtime.MatAdd(Z0, runtime.MatScale(runtime.Identity(n), delta))
+		Y := PartialTrace(Z, inDim, outDim, "B")
+		eigs, err := linalg.HermitianEigenvalues(Y, spectralTolerance)
+		if err != nil {
+			return nil, fmt.Errorf("analysis: %w", err)
+		}
+		if cert.setDual(J, Z, eigs[len(eigs)-1].Upper) {
+			break
+		}
+	}
+
+	// Primal: ρ = I/d_in and W the projector onto J's positive eigenspace,
+	// scaled into [0, ρ ⊗ I]. W = 0 is always feasible and gives 0.
+	scale := big.NewRat(1, int64(inDim))
+	rho := runtime.MatScale(runtime.Identity(inDim), scale)
+	W0 := runtime.MatScale(linalg.RoundHermitian(linalg.PositiveProjector(Jf, 1e-9), diamondBits), scale)
+	cert.setPrimal(J, rho, runtime.NewMatrix(n, n))
+	for delta := new(big.Rat); delta.Cmp(scale) <= 0; delta = nextShift(delta) {
+		// W = (W0 + δI)/(1 + 2 d_in δ)
+		shrink := new(big.Rat).Mul(delta, big.NewRat(int64(2*inDim), 1))
+		shrink.Inv(shrink.Add(shrink, big.NewRat(1, 1)))
+		W := runtime.MatScale(runtime.MatAdd(W0, runtime.MatScale(runtime.Identity(n), delta)), shrink)
+		if cert.setPrimal(J, rho, W) {
+			break
+		}
+	}
+	return cert, nil
+}
+
+// setDual reports whether Z ⪰ 0, Z ⪰ J and upper·I ⪰ Tr_out Z are all
+// proven, and if so keeps Z as the dual point unless a lower upper bound
+// is already recorded.
+func (c *DiamondCertificate) setDual(J, Z *runtime.Matrix, upper *big.Rat) bool {
+	zPos, _, _ := linalg.CertifyPSD(Z)
+	zDom, _, _ := linalg.CertifyPSD(runtime.MatSub(Z, J))
+	gap := runtime.MatSub(
+		runtime.MatScale(runtime.Identity(c.InDim), upper),
+		PartialTrace(Z, c.InDim, c.OutDim, "B"),
+	)
+	upperCert, _, _ := linalg.CertifyPSD(gap)
+	if zPos == nil || zDom == nil || upperCert == nil {
+		return false
+	}
+	if c.Z != nil && c.Upper.Cmp(upper) <= 0 {
+		return true
+	}
+	c.Z, c.ZPos, c.ZDom = Z, zPos, zDom
+	c.Upper, c.UpperCert = new(big.Rat).Set(upper), upperCert
+	return true
+}
+
+// setPrimal reports whether ρ ⪰ 0, Tr ρ = 1 and 0 ⪯ W ⪯ ρ ⊗ I are all
+// proven, and if so keeps (ρ, W) as the primal point unless a higher lower
+// bound is already recorded.
+func (c *DiamondCertificate) setPrimal(J, rho, W *runtime.Matrix) bool {
+	tr := runtime.Trace(rho)
+	if tr.Im.Sign() != 0 || tr.Re.Cmp(big.NewRat(1, 1)) != 0 {
+		return false
+	}
+	rhoPos, _, _ := linalg.CertifyPSD(rho)
+	wPos, _, _ := linalg.CertifyPSD(W)
+	wDom, _, _ := linalg.CertifyPSD(runtime.MatSub(runtime.Kronecker(rho, runtime.Identity(c.OutDim)), W))
+	if rhoPos == nil || wPos == nil || wDom == nil {
+		return false
+	}
+	lower := runtime.Trace(runtime.MatMul(J, W)).Re
+	if c.W != nil && c.Lower.Cmp(lower) >= 0 {
+		return true
+	}
+	c.Rho, c.RhoPos, c.W, c.WPos, c.WDom, c.Lower = rho, rhoPos, W, wPos, wDom, lower
+	return true
+}
+
+// hermitianBasis returns a basis of the n×n Hermitian matrices over the
+// reals: the diagonal units E_kk first, then E_kl + E_lk and
+// i(E_kl - E_lk) for k < l.
+func hermitianBasis(n int) []*runtime.Matrix {
+	basis := make([]*runtime.Matrix, 0, n*n)
+	for k := 0; k < n; k++ {
+		E := runtime.NewMatrix(n, n)
+		E.Set(k, k, runtime.QIOne())
+		basis = append(basis, E)
+	}
+	one, zero := big.NewRat(1, 1), new(big.Rat)
+	for k := 0; k < n; k++ {
+		for l := k + 1; l < n; l++ {
+			re := runtime.NewMatrix(n, n)
+			re.Set(k, l, runtime.QIOne())
+			re.Set(l, k, runtime.QIOne())
+			im := runtime.NewMatrix(n, n)
+			im.Set(k, l, runtime.NewQI(zero, one))
+			im.Set(l, k, runtime.NewQI(zero, new(big.Rat).Neg(one)))
+			basis = append(basis, re, im)
+		}
+	}
+	return basis
+}
+
+// diamondProgram states Watrous's program in the standard form of package
+// sdp over blocks (W, ρ, S) with S = ρ ⊗ I - W as a slack. Each basis
+// element E gives ⟨E, W⟩ - ⟨Tr_out E, ρ⟩ + ⟨E, S⟩ = 0, and a last
+// constraint fixes Tr ρ = 1. The diagonal units with weight 1 and the
+// trace constraint with weight 1 + d_out sum to the identity, so every
+// feasible point has total trace d_out + 1. In the dual, Z = Σ y_E E and
+// the trace multiplier bounds ‖Tr_out Z‖∞.
+func diamondProgram(J *runtime.Matrix, basis []*runtime.Matrix, inDim, outDim int) *sdp.Problem {
+	n := inDim * outDim
+	p := &sdp.Problem{
+		Blocks: []int{n, inDim, n},
+		C:      []*runtime.Matrix{J, nil, nil},
+	}
+	for k, E := range basis {
+		p.A = append(p.A, []*runtime.Matrix{E, runtime.MatScale(PartialTrace(E, inDim, outDim, "B"), big.NewRat(-1, 1)), E})
+		p.B = append(p.B, new(big.Rat))
+		w := new(big.Rat)
+		if k < n {
+			w.SetInt64(1)
+		}
+		p.Identity = append(p.Identity, w)
+	}
+	p.A = append(p.A, []*runtime.Matrix{nil, runtime.Identity(inDim), nil})
+	p.B = append(p.B, big.NewRat(1, 1))
+	p.Identity = append(p.Identity, big.NewRat(int64(1+outDim), 1))
+	return p
+}
+
+// diamondObjective returns d_in(A - B).
+func diamondObjective(A, B *runtime.Matrix, inDim int) *runtime.Matrix {
+	return runtime.MatScale(runtime.MatSub(A, B), big.NewRat(int64(inDim), 1))
+}
+
+// nextShift steps a repair shift through 0, 2^-diamondBits, and doublings.
+func nextShift(delta *big.Rat) *big.Rat {
+	if delta.Sign() == 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), diamondBits))
+	}
+	return new(big.Rat).Mul(delta, big.NewRat(2, 1))
+}
+
+// Bound returns the certified enclosure of the diamond distance.
+func (c *DiamondCertificate) Bound() *Bound {
+	return &Bound{Lower: new(big.Rat).Set(c.Lower), Upper: new(big.Rat).Set(c.Upper)}
+}
+
+// Verify rechecks the certificate with exact matrix products: every LDL†
+// certificate against the matrix it claims to factor, Tr ρ = 1, and
+// Lower = Tr(J W).
+func (c *DiamondCertificate) Verify() bool {
+	if c == nil || c.ChoiA == nil || c.ChoiB == nil || c.Z == nil || c.Rho == nil || c.W == nil ||
+		c.Upper == nil || c.Lower == nil || c.InDim <= 0 || c.OutDim <= 0 {
+		return false
+	}
+	n := c.InDim * c.OutDim
+	for _, M := range []*runtime.Matrix{c.ChoiA, c.ChoiB, c.Z, c.W} {
+		if M.Rows != n || M.Cols != n {
+			return false
+		}
+	}
+	if c.Rho.Rows != c.InDim || c.Rho.Cols != c.InDim {
+		return false
+	}
+	J := diamondObjective(c.ChoiA, c.ChoiB, c.InDim)
+	if !linalg.IsHermitian(J) {
+		return false
+	}
+
+	upperGap := runtime.MatSub(
+		runtime.MatScale(runtime.Identity(c.InDim), c.Upper),
+		PartialTrace(c.Z, c.InDim, c.OutDim, "B"),
+	)
+	if !c.ZPos.Verify(c.Z) || !c.ZDom.Verify(runtime.MatSub(c.Z, J)) || !c.UpperCert.Verify(upperGap) {
+		return false
+	}
+
+	tr := runtime.Trace(c.Rho)
+	if tr.Im.Sign() != 0 || tr.Re.Cmp(big.NewRat(1, 1)) != 0 || !c.RhoPos.Verify(c.Rho) {
+		return false
+	}
+	bound := runtime.Kronecker(c.Rho, runtime.Identity(c.OutDim))
+	if !c.WPos.Verify(c.W) || !c.WDom.Verify(runtime.MatSub(bound, c.W)) {
+		return false
+	}
+	value := runtime.Trace(runtime.MatMul(J, c.W))
+	return value.Im.Sign() == 0 && value.Re.Cmp(c.Lower) == 0
+}
+
+// ToValue converts a DiamondCertificate to a runtime.Value.
+func (c *DiamondCertificate) ToValue() runtime.Value {
+	return runtime.MakeTag(
+		runtime.MakeText("diamond-certificate"),
+		runtime.MakeSeq(
+			runtime.MatrixToValue(c.ChoiA),
+			runtime.MatrixToValue(c.ChoiB),
+			runtime.MakeInt(int64(c.InDim)),
+			runtime.MakeInt(int64(c.OutDim)),
+			runtime.MatrixToValue(c.Z),
+			c.ZPos.ToValue(),
+			c.ZDom.ToValue(),
+			runtime.MakeBigRat(c.Upper),
+			c.UpperCert.ToValue(),
+			runtime.MatrixToValue(c.Rho),
+			c.RhoPos.ToValue(),
+			runtime.MatrixToValue(c.W),
+			c.WPos.ToValue(),
+			c.WDom.ToValue(),
+			runtime.MakeBigRat(c.Lower),
+		),
+	)
+}
+
+// DiamondCertificateFromValue deserializes a DiamondCertificate from a
+// runtime.Value.
+func DiamondCertificateFromValue(v runtime.Value) (*DiamondCertificate, bool) {
+	tag, ok := v.(runtime.Tag)
+	if !ok {
+		return nil, false
+	}
+	label, ok := tag.Label.(runtime.Text)
+	if !ok || label.V != "diamond-certificate" {
+		return nil, false
+	}
+	seq, ok := tag.Payload.(runtime.Seq)
+	if !ok || len(seq.Items) != 15 {
+		return nil, false
+	}
+	it := seq.Items
+
+	matrices := make([]*runtime.Matrix, 0, 5)
+	for _, i := range []int{0, 1, 4, 9, 11} {
+		m, ok := runtime.MatrixFromValue(it[i])
+		if !ok {
+			return nil, false
+		}
+		matrices = append(matrices, m)
+	}
+	ldls := make([]*linalg.LDLCertificate, 0, 6)
+	for _, i := range []int{5, 6, 8, 10, 12, 13} {
+		l, ok := linalg.LDLCertificateFromValue(it[i])
+		if !ok {
+			return nil, false
+		}
+		ldls = append(ldls, l)
+	}
+	inDim, ok1 := it[2].(runtime.Int)
+	outDim, ok2 := it[3].(runtime.Int)
+	upper, ok3 := it[7].(runtime.Rat)
+	lower, ok4 := it[14].(runtime.Rat)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !inDim.V.IsInt64() || !outDim.V.IsInt64() {
+		return nil, false
+	}
+
+	return &DiamondCertificate{
+		ChoiA:     matrices[0],
+		ChoiB:     matrices[1],
+		InDim:     int(inDim.V.Int64()),
+		OutDim:    int(outDim.V.Int64()),
+		Z:         matrices[2],
+		ZPos:      ldls[0],
+		ZDom:      ldls[1],
+		Upper:     new(big.Rat).Set(upper.V),
+		UpperCert: ldls[2],
+		Rho:       matrices[3],
+		RhoPos:    ldls[3],
+		W:         matrices[4],
+		WPos:      ldls[4],
+		WDom:      ldls[5],
+		Lower:     new(big.Rat).Set(lower.V),
+	}, true
+}
@@ -606,63 +606,117 @@ type EntropyBounds struct {
 	MutualInfo  *big.Rat // I(A:B) mutual information
 }
 
-// ComputeVonNeumann computes the von Neumann entropy of a density matrix.
-// Returns exact rational bounds when possible.
-// S(rho) = -Tr(rho log rho) = -sum_i lambda_i log(lambda_i)
-func ComputeVonNeumann(rho *runtime.Matrix) (*big.Rat, error) {
+// ComputeVonNeumann computes the von Neumann entropy of a density matrix in
+// bits, S(rho) = -Tr(rho log rho) = -sum_i lambda_i log(lambda_i), as
+// certified rational bounds. The result is exact when every eigenvalue is
+// zero or a power of two, e.g. for pure and maximally mixed states.
+func ComputeVonNeumann(rho *runtime.Matrix) (*Entropy, error) {
 	if rho == nil {
-		return big.NewRat(0, 1), nil
-	}
-
-	// For a pure state (rank 1), entropy is 0
-	// For maximally mixed state of dimension d, entropy is log(d)
-
-	// Check if diagonal (simplifies computation)
-	isDiag := true
-	for i := 0; i < rho.Rows && isDiag; i++ {
-		for j := 0; j < rho.Cols; j++ {
-			if i != j && !runtime.QIIsZero(rho.Get(i, j)) {
-				isDiag = false
-				break
+		return NewExactEntropy(big.NewRat(0, 1)), nil
+	}
+	eigs, err := densitySpectrum(rho)
+	if err != nil {
+		return nil, err
+	}
+
+	lower, upper := new(big.Rat), new(big.Rat)
+	exact := new(big.Rat)
+	for _, ev := range eigs {
+		mult := big.NewRat(int64(ev.Multiplicity), 1)
+		if exact != nil && ev.Exact != nil {
+			if k, ok := exactLog2(ev.Exact); ok {
+				term := new(big.Rat).Mul(ev.Exact, big.NewRat(int64(-k), 1))
+				exact.Add(exact, term.Mul(term, mult))
+			} else if ev.Exact.Sign() != 0 {
+				exact = nil
 			}
+		} else {
+			exact = nil
 		}
+		lo, hi := clampUnit(ev.Lower, ev.Upper)
+		etaLo, etaHi := etaBounds(lo, hi, spectralTolerance)
+		lower.Add(lower, etaLo.Mul(etaLo, mult))
+		upper.Add(upper, etaHi.Mul(etaHi, mult))
 	}
-
-	if isDiag {
-		// For diagonal matrices, eigenvalues are diagonal entries
-		// S = -sum p_i log(p_i)
-		// This requires computing log, which is transcendental
-		// Return bounds based on dimension
-		// d := rho.Rows
-		// 0 <= S <= log(d), approximate log(d) as (d-1)/d * some factor
-		// For exact bounds: S >= 0, S <= log(d) ~ d*ln(d)/d = ln(d)
-		// Crude upper: log(d) < d for d >= 1
-		_ = rho.Rows // Dimension available for future refinement
-		return big.NewRat(0, 1), nil // Placeholder: need eigenvalue computation
+	if exact != nil {
+		return NewExactEntropy(exact), nil
 	}
-
-	return big.NewRat(0, 1), nil
+	if lower.Sign() < 0 {
+		lower.SetInt64(0)
+	}
+	return NewSymbolicEntropy("S(rho)", lower, upper), nil
 }
 
-// ComputeMinEntropy computes the min-entropy H_min(rho).
+// ComputeMinEntropy computes the min-entropy H_min(rho) in bits as
+// certified rational bounds.
 // H_min(rho) = -log(lambda_max) where lambda_max is the largest eigenvalue.
-func ComputeMinEntropy(rho *runtime.Matrix) (*big.Rat, error) {
+func ComputeMinEntropy(rho *runtime.Matrix) (*Entropy, error) {
 	if rho == nil {
-		return big.NewRat(0, 1), nil
+		return NewExactEntropy(big.NewRat(0, 1)), nil
 	}
-	// For pure states: H_min = 0
-	// For maximally mixed: H_min = log(d)
-	return big.NewRat(0, 1), nil
+	eigs, err := densitySpectrum(rho)
+	if err != nil {
+		return nil, err
+	}
+	top := eigs[len(eigs)-1]
+	if top.Exact != nil {
+		return minEntropyAt(top.Exact, top.Exact, "H_min(rho)"), nil
+	}
+	lo, hi := clampUnit(top.Lower, top.Upper)
+	return minEntropyAt(lo, hi, "H_min(rho)"), nil
 }
 
-// ComputeSmoothMinEntropy computes the smooth min-entropy.
+// ComputeSmoothMinEntropy computes the smooth min-entropy in bits as
+// certified rational bounds.
 // H_min^epsilon(rho) = max_{rho' in B_epsilon(rho)} H_min(rho')
-func ComputeSmoothMinEntropy(rho *runtime.Matrix, epsilon *big.Rat) (*big.Rat, error) {
+// The ball holds the subnormalized states within generalized trace
+// distance epsilon of rho. The optimum cuts the largest eigenvalues down
+// to the level t with sum_i (lambda_i - t)_+ = epsilon, so the result is
+// -log(t).
+func ComputeSmoothMinEntropy(rho *runtime.Matrix, epsilon *big.Rat) (*Entropy, error) {
 	if rho == nil {
-		return big.NewRat(0, 1), nil
+		return NewExactEntropy(big.NewRat(0, 1)), nil
+	}
+	if epsilon == nil || epsilon.Sign() < 0 || epsilon.Cmp(big.NewRat(1, 1)) >= 0 {
+		return nil, fmt.Errorf("analysis: smoothing parameter must be in [0, 1)")
+	}
+	eigs, err := densitySpectrum(rho)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bounds on the eigenvalues bound the level: raising any lambda_i
+	// raises t.
+	var lows, highs []*big.Rat
+	for _, ev := range eigs {
+		lo, hi := clampUnit(ev.Lower, ev.Upper)
+		for i := 0; i < ev.Multiplicity; i++ {
+			lows = append(lows, lo)
+			highs = append(highs, hi)
+		}
+	}
+	tLo := waterLevel(lows, epsilon)
+	tHi := waterLevel(highs, epsilon)
+	if tLo.Sign() <= 0 {
+		return nil, fmt.Errorf("analysis: smoothing parameter %s is too close to 1", epsilon.RatString())
+	}
+	return minEntropyAt(tLo, tHi, fmt.Sprintf("H_min^%s(rho)", epsilon.RatString())), nil
+}
+
+// minEntropyAt bounds -log(t) for t in [lo, hi] ⊂ (0, 1].
+func minEntropyAt(lo, hi *big.Rat, symbolic string) *Entropy {
+	if lo.Cmp(hi) == 0 {
+		if k, ok := exactLog2(lo); ok {
+			return NewExactEntropy(big.NewRat(int64(-k), 1))
+		}
+	}
+	_, l2hi := log2Bounds(hi, spectralTolerance)
+	l2lo, _ := log2Bounds(lo, spectralTolerance)
+	lower := l2hi.Neg(l2hi)
+	if lower.Sign() < 0 {
+		lower.SetInt64(0)
 	}
-	// Smoothing allows optimization over nearby states
-	return big.NewRat(0, 1), nil
+	return NewSymbolicEntropy(symbolic, lower, l2lo.Neg(l2lo))
 }
 
 // ToValue converts EntropyBounds to a runtime.Value.
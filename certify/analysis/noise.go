@@ -117,7 +117,7 @@ func DepolarizingChannel(p *big.Rat) *NoiseChannel {
 	channel.Kraus[3] = scaleMatrix(pauliZ(), pOver3)
 
 	// Compute Choi matrix
-	channel.Choi = computeChoiMatrix(channel.Kraus)
+	channel.Choi, _ = runtime.KrausToChoi(channel.Kraus)
 
 	return channel
 }
@@ -149,7 +149,7 @@ func AmplitudeDampingChannel(gamma *big.Rat) *NoiseChannel {
 	channel.Kraus[1] = K1
 
 	// Compute Choi matrix
-	channel.Choi = computeChoiMatrix(channel.Kraus)
+	channel.Choi, _ = runtime.KrausToChoi(channel.Kraus)
 
 	return channel
 }
@@ -181,7 +181,7 @@ func PhaseDampingChannel(lambda *big.Rat) *NoiseChannel {
 	channel.Kraus[1] = K1
 
 	// Compute Choi matrix
-	channel.Choi = computeChoiMatrix(channel.Kraus)
+	channel.Choi, _ = runtime.KrausToChoi(channel.Kraus)
 
 	return channel
 }
@@ -208,7 +208,7 @@ func BitFlipChannel(p *big.Rat) *NoiseChannel {
 	channel.Kraus[1] = scaleMatrix(pauliX(), p)
 
 	// Compute Choi matrix
-	channel.Choi = computeChoiMatrix(channel.Kraus)
+	channel.Choi, _ = runtime.KrausToChoi(channel.Kraus)
 
 	return channel
 }
@@ -235,7 +235,7 @@ func PhaseFlipChannel(p *big.Rat) *NoiseChannel {
 	channel.Kraus[1] = scaleMatrix(pauliZ(), p)
 
 	// Compute Choi matrix
-	channel.Choi = computeChoiMatrix(channel.Kraus)
+	channel.Choi, _ = runtime.KrausToChoi(channel.Kraus)
 
 	return channel
 }
@@ -262,7 +262,7 @@ func BitPhaseFlipChannel(p *big.Rat) *NoiseChannel {
 	channel.Kraus[1] = scaleMatrix(pauliY(), p)
 
 	// Compute Choi matrix
-	channel.Choi = computeChoiMatrix(channel.Kraus)
+	channel.Choi, _ = runtime.KrausToChoi(channel.Kraus)
 
 	return channel
 }
@@ -372,13 +372,15 @@ func generalErrorFromChannel(channel *NoiseChannel) *big.Rat {
 	d := 2 // Qubit
 	choi := channel.Choi
 
-	// |phi+> = (|00> + |11>)/sqrt(2), so projector is
-	// |phi+><phi+| = (|00><00| + |00><11| + |11><00| + |11><11|)/2
-
-	// Compute fidelity F = (1/d) * Tr(J)  (simplified for trace-preserving)
-	tr := runtime.Trace(choi)
-	fidelity := new(big.Rat).Set(tr.Re)
-	fidelity.Quo(fidelity, big.NewRat(int64(d), 1))
+	// |phi+> = (|00> + |11>)/sqrt(2), and J is unnormalized, so
+	// F = (1/d^2) * sum_{i,j} J[i*d+i, j*d+j]
+	fidelity := new(big.Rat)
+	for i := 0; i < d; i++ {
+		for j := 0; j < d; j++ {
+			fidelity.Add(fidelity, choi.Get(i*d+i, j*d+j).Re)
+		}
+	}
+	fidelity.Quo(fidelity, big.NewRat(int64(d*d), 1))
 
 	// QBER = (1 - F)
 	one := big.NewRat(1, 1)
@@ -610,43 +612,6 @@ func scaleMatrix(m *runtime.Matrix, scale *big.Rat) *runtime.Matrix {
 	return result
 }
 
-// computeChoiMatrix computes the Choi matrix from Kraus operators.
-// J = sum_k (I tensor K_k) |phi+><phi+| (I tensor K_k)^dag
-// Simplified: J = sum_k |K_k><K_k| in vectorized form
-func computeChoiMatrix(kraus []*runtime.Matrix) *runtime.Matrix {
-	if len(kraus) == 0 || kraus[0] == nil {
-		return nil
-	}
-
-	d := kraus[0].Rows
-	choi := runtime.NewMatrix(d*d, d*d)
-
-	for _, K := range kraus {
-		if K == nil {
-			continue
-		}
-		// Compute contribution from this Kraus operator
-		// J_k[i*d+j, k*d+l] = K[i,k] * conj(K[j,l])
-		for i := 0; i < d; i++ {
-			for j := 0; j < d; j++ {
-				for k := 0; k < d; k++ {
-					for l := 0; l < d; l++ {
-						row := i*d + j
-						col := k*d + l
-						kik := K.Get(i, k)
-						kjl := runtime.QIConj(K.Get(j, l))
-						contrib := runtime.QIMul(kik, kjl)
-						current := choi.Get(row, col)
-						choi.Set(row, col, runtime.QIAdd(current, contrib))
-					}
-				}
-			}
-		}
-	}
-
-	return choi
-}
-
 // ToValue converts a NoiseResult to a runtime.Value.
 func (r *NoiseResult) ToValue() runtime.Value {
 	return runtime.MakeTag(
@@ -0,0 +1,177 @@
+// positivity.go provides exact CPTP certification of Choi matrices.
+//
+// A map Φ with Choi matrix J = (1/d_in) Σ |i><j| ⊗ Φ(|i><j|) is completely
+// positive iff J ⪰ 0 and trace preserving iff Tr_out J = I/d_in. The first
+// condition is decided by linalg.CertifyPSD, which returns either an LDL†
+// factorization or a vector x with x†Jx < 0; the second is an exact
+// partial trace. A ChannelWitness carries J together with whichever
+// certificate was found, so a verifier rechecks the claim with a few
+// matrix products and never has to trust the factorization code.
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+
+	"qbtm/certify/linalg"
+	"qbtm/runtime"
+)
+
+// ChannelWitness records an exact decision of whether a Choi matrix is CP
+// and TP. Exactly one of Positivity and Negative is set.
+type ChannelWitness struct {
+	Choi            *runtime.Matrix
+	InDim           int
+	OutDim          int
+	Positivity      *linalg.LDLCertificate    // J = L·D·L†, D ≥ 0
+	Negative        *linalg.NegativeDirection // x†Jx < 0
+	TracePreserving bool
+}
+
+// CertifyChannel decides whether choi is the Choi matrix of a CPTP map
+// from dimension inDim to outDim and returns the certificate.
+func CertifyChannel(choi *runtime.Matrix, inDim, outDim int) (*ChannelWitness, error) {
+	if choi == nil {
+		return nil, fmt.Errorf("analysis: Choi matrix is nil")
+	}
+	if inDim <= 0 || outDim <= 0 || choi.Rows != inDim*outDim || choi.Cols != inDim*outDim {
+		return nil, fmt.Errorf("analysis: Choi matrix is %dx%d, want %d for dimensions %d -> %d",
+			choi.Rows, choi.Cols, inDim*outDim, inDim, outDim)
+	}
+	ldl, neg, err := linalg.CertifyPSD(choi)
+	if err != nil {
+		return nil, fmt.Errorf("analysis: %w", err)
+	}
+	return &ChannelWitness{
+		Choi:            choi,
+		InDim:           inDim,
+		OutDim:          outDim,
+		Positivity:      ldl,
+		Negative:        neg,
+		TracePreserving: isTracePreserving(choi, inDim, outDim),
+	}, nil
+}
+
+// isTracePreserving checks Tr_out J = I/inDim exactly.
+func isTracePreserving(choi *runtime.Matrix, inDim, outDim int) bool {
+	ptrace := PartialTrace(choi, inDim, outDim, "B")
+	if ptrace == nil {
+		return false
+	}
+	want := runtime.MatScale(runtime.Identity(inDim), big.NewRat(1, int64(inDim)))
+	return runtime.MatrixEqual(ptrace, want)
+}
+
+// IsCP reports whether the witness certifies complete positivity.
+func (w *ChannelWitness) IsCP() bool {
+	return w.Positivity != nil
+}
+
+// IsCPTP reports whether the witness certifies a CPTP map.
+func (w *ChannelWitness) IsCPTP() bool {
+	return w.IsCP() && w.TracePreserving
+}
+
+// IsUnitary reports whether the witness certifies a unitary channel: a
+// CPTP map between equal dimensions whose Choi matrix has rank 1.
+func (w *ChannelWitness) IsUnitary() bool {
+	return w.IsCPTP() && w.InDim == w.OutDim && w.Positivity.Rank() == 1
+}
+
+// Verify rechecks the witness from its Choi matrix alone: the positivity
+// certificate or negative direction, and the trace-preservation flag.
+func (w *ChannelWitness) Verify() bool {
+	if w == nil || w.Choi == nil || w.InDim <= 0 || w.OutDim <= 0 {
+		return false
+	}
+	n := w.InDim * w.OutDim
+	if w.Choi.Rows != n || w.Choi.Cols != n {
+		return false
+	}
+	switch {
+	case w.Positivity != nil && w.Negative == nil:
+		if !w.Positivity.Verify(w.Choi) {
+			return false
+		}
+	case w.Negative != nil && w.Positivity == nil:
+		if !w.Negative.Verify(w.Choi) {
+			return false
+		}
+	default:
+		return false
+	}
+	return w.TracePreserving == isTracePreserving(w.Choi, w.InDim, w.OutDim)
+}
+
+// ToValue converts a ChannelWitness to a runtime.Value.
+func (w *ChannelWitness) ToValue() runtime.Value {
+	var cert runtime.Value = runtime.MakeNil()
+	switch {
+	case w.Positivity != nil:
+		cert = w.Positivity.ToValue()
+	case w.Negative != nil:
+		cert = w.Negative.ToValue()
+	}
+	return runtime.MakeTag(
+		runtime.MakeText("channel-witness"),
+		runtime.MakeSeq(
+			runtime.MatrixToValue(w.Choi),
+			runtime.MakeInt(int64(w.InDim)),
+			runtime.MakeInt(int64(w.OutDim)),
+			cert,
+			runtime.MakeBool(w.TracePreserving),
+		),
+	)
+}
+
+// ChannelWitnessFromValue deserializes a ChannelWitness from a runtime.Value.
+func ChannelWitnessFromValue(v runtime.Value) (*ChannelWitness, bool) {
+	tag, ok := v.(runtime.Tag)
+	if !ok {
+		return nil, false
+	}
+	label, ok := tag.Label.(runtime.Text)
+	if !ok || label.V != "channel-witness" {
+		return nil, false
+	}
+	seq, ok := tag.Payload.(runtime.Seq)
+	if !ok || len(seq.Items) != 5 {
+		return nil, false
+	}
+	choi, ok := runtime.MatrixFromValue(seq.Items[0])
+	if !ok {
+		return nil, false
+	}
+	inDim, ok1 := seq.Items[1].(runtime.Int)
+	outDim, ok2 := seq.Items[2].(runtime.Int)
+	tp, ok3 := seq.Items[4].(runtime.Bool)
+	if !ok1 || !ok2 || !ok3 || !inDim.V.IsInt64() || !outDim.V.IsInt64() {
+		return nil, false
+	}
+	w := &ChannelWitness{
+		Choi:            choi,
+		InDim:           int(inDim.V.Int64()),
+		OutDim:          int(outDim.V.Int64()),
+		TracePreserving: tp.V,
+	}
+	if ldl, ok := linalg.LDLCertificateFromValue(seq.Items[3]); ok {
+		w.Positivity = ldl
+	} else if neg, ok := linalg.NegativeDirectionFromValue(seq.Items[3]); ok {
+		w.Negative = neg
+	} else {
+		return nil, false
+	}
+	return w, true
+}
+
+// ChoiFromKraus returns the Choi matrix of the map ρ ↦ Σ_k K_k ρ K_k† in
+// the ComputeChannel convention, J[(i,a),(j,b)] = (1/d_in) Σ_k K_k[a,i]·
+// conj(K_k[b,j]), the runtime Choi matrix normalized by the input
+// dimension. All operators must have the same shape.
+func ChoiFromKraus(kraus []*runtime.Matrix) (*runtime.Matrix, error) {
+	choi, err := runtime.KrausToChoi(kraus)
+	if err != nil {
+		return nil, fmt.Errorf("analysis: %w", err)
+	}
+	return runtime.MatScale(choi, big.NewRat(1, int64(kraus[0].Cols))), nil
+}
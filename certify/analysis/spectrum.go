@@ -0,0 +1,203 @@
+// spectrum.go provides certified bounds on spectral functions of density
+// matrices.
+//
+// Eigenvalues come from linalg.HermitianEigenvalues as exact rationals or
+// isolating intervals. Logarithms are bounded with the series
+//
+//	ln m = 2 Σ_{j≥0} z^{2j+1}/(2j+1),   z = (m-1)/(m+1)
+//
+// for m in [1, 2], whose partial sums are lower bounds and whose tail after
+// N terms is at most 2 z^{2N+3} / ((2N+3)(1-z²)). Every bound returned here
+// is rigorous: the true value always lies between Lower and Upper.
+package analysis
+
+import (
+	"fmt"
+	"math/big"
+
+	"qbtm/certify/linalg"
+	"qbtm/runtime"
+)
+
+// spectralTolerance is the width to which eigenvalues and logarithms are
+// refined when computing entropies.
+var spectralTolerance = big.NewRat(1, 1<<40)
+
+// densitySpectrum returns the eigenvalues of a density matrix refined to
+// spectralTolerance, with every irrational eigenvalue's interval strictly
+// positive or strictly negative. It rejects matrices that are not
+// Hermitian, do not have unit trace, or have a negative eigenvalue.
+func densitySpectrum(rho *runtime.Matrix) ([]*linalg.Eigenvalue, error) {
+	tr := runtime.Trace(rho)
+	if tr.Im.Sign() != 0 || tr.Re.Cmp(big.NewRat(1, 1)) != 0 {
+		return nil, fmt.Errorf("analysis: density matrix must have trace 1")
+	}
+	eigs, err := linalg.HermitianEigenvalues(rho, spectralTolerance)
+	if err != nil {
+		return nil, fmt.Errorf("analysis: %w", err)
+	}
+	for _, ev := range eigs {
+		separateFromZero(ev)
+		if ev.Upper.Sign() < 0 {
+			return nil, fmt.Errorf("analysis: density matrix has a negative eigenvalue")
+		}
+	}
+	return eigs, nil
+}
+
+// separateFromZero refines an irrational eigenvalue until its interval
+// is strictly positive or strictly negative. Zero is rational, so an
+// irrational eigenvalue is never zero and this terminates.
+func separateFromZero(ev *linalg.Eigenvalue) {
+	for ev.Exact == nil && ev.Lower.Sign() <= 0 && ev.Upper.Sign() > 0 {
+		ev.Refine(new(big.Rat).Quo(ev.Width(), big.NewRat(2, 1)))
+	}
+}
+
+// clampUnit returns the part of [lo, hi] inside [0, 1]. A density matrix
+// has all its eigenvalues there.
+func clampUnit(lo, hi *big.Rat) (*big.Rat, *big.Rat) {
+	one := big.NewRat(1, 1)
+	l, u := new(big.Rat).Set(lo), new(big.Rat).Set(hi)
+	if l.Sign() < 0 {
+		l.SetInt64(0)
+	}
+	if u.Cmp(one) > 0 {
+		u.Set(one)
+	}
+	return l, u
+}
+
+// lnBounds bounds ln m for m in [1, 2] to within tol.
+func lnBounds(m, tol *big.Rat) (*big.Rat, *big.Rat) {
+	one := big.NewRat(1, 1)
+	z := new(big.Rat).Sub(m, one)
+	z.Quo(z, new(big.Rat).Add(m, one))
+	if z.Sign() == 0 {
+		return new(big.Rat), new(big.Rat)
+	}
+	z2 := new(big.Rat).Mul(z, z)
+	tailScale := new(big.Rat).Sub(one, z2)
+	tailScale.Inv(tailScale)
+	tailScale.Mul(tailScale, big.NewRat(2, 1))
+
+	sum := new(big.Rat)
+	power := new(big.Rat).Set(z) // z^{2j+1}
+	for j := int64(0); ; j++ {
+		term := new(big.Rat).Quo(power, big.NewRat(2*j+1, 1))
+		sum.Add(sum, term.Mul(term, big.NewRat(2, 1)))
+		power.Mul(power, z2)
+		tail := new(big.Rat).Quo(power, big.NewRat(2*j+3, 1))
+		tail.Mul(tail, tailScale)
+		if tail.Cmp(tol) <= 0 {
+			return new(big.Rat).Set(sum), sum.Add(sum, tail)
+		}
+	}
+}
+
+// log2Bounds bounds log₂ x for x > 0 to within about tol·(1 + |log₂ x|).
+func log2Bounds(x, tol *big.Rat) (*big.Rat, *big.Rat) {
+	// x = 2^k · m with m in [1, 2).
+	k := x.Num().BitLen() - x.Denom().BitLen()
+	m := new(big.Rat).Mul(x, pow2(-k))
+	if m.Cmp(big.NewRat(1, 1)) < 0 {
+		k--
+		m.Mul(m, big.NewRat(2, 1))
+	} else if m.Cmp(big.NewRat(2, 1)) >= 0 {
+		k++
+		m.Quo(m, big.NewRat(2, 1))
+	}
+	kr := big.NewRat(int64(k), 1)
+	lmLo, lmHi := lnBounds(m, tol)
+	if lmHi.Sign() == 0 {
+		return kr, new(big.Rat).Set(kr)
+	}
+	l2Lo, l2Hi := lnBounds(big.NewRat(2, 1), tol)
+	lo := new(big.Rat).Quo(lmLo, l2Hi)
+	hi := new(big.Rat).Quo(lmHi, l2Lo)
+	return lo.Add(lo, kr), hi.Add(hi, kr)
+}
+
+// pow2 returns 2^k as a rational.
+func pow2(k int) *big.Rat {
+	if k >= 0 {
+		return new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(k)))
+	}
+	return new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), uint(-k)))
+}
+
+// exactLog2 returns log₂ x when x is a power of two.
+func exactLog2(x *big.Rat) (int, bool) {
+	if x.Sign() <= 0 {
+		return 0, false
+	}
+	num, den := x.Num(), x.Denom()
+	switch {
+	case num.Cmp(big.NewInt(1)) == 0 && isPow2(den):
+		return -(den.BitLen() - 1), true
+	case den.Cmp(big.NewInt(1)) == 0 && isPow2(num):
+		return num.BitLen() - 1, true
+	}
+	return 0, false
+}
+
+func isPow2(n *big.Int) bool {
+	return n.Sign() > 0 && n.TrailingZeroBits() == uint(n.BitLen()-1)
+}
+
+// etaBounds bounds η(x) = -x log₂ x over x in [lo, hi] ⊂ [0, 1]. η is
+// concave, so its minimum is at an endpoint and it lies below its tangent
+// at lo.
+func etaBounds(lo, hi, tol *big.Rat) (*big.Rat, *big.Rat) {
+	etaLo := func(x *big.Rat) *big.Rat {
+		if x.Sign() == 0 {
+			return new(big.Rat)
+		}
+		_, l2hi := log2Bounds(x, tol)
+		return l2hi.Mul(l2hi, x).Neg(l2hi)
+	}
+	lower := etaLo(lo)
+	if v := etaLo(hi); v.Cmp(lower) < 0 {
+		lower = v
+	}
+
+	if lo.Sign() == 0 {
+		if hi.Sign() == 0 {
+			return lower, new(big.Rat)
+		}
+		// η ≤ 1/(e ln 2) < 0.5308 everywhere on [0, 1].
+		return lower, big.NewRat(5308, 10000)
+	}
+	// η(x) ≤ η(lo) + η'(lo)(x - lo) with η'(lo) = -log₂ lo - 1/ln 2.
+	l2lo, _ := log2Bounds(lo, tol)
+	upper := new(big.Rat).Mul(l2lo, lo)
+	upper.Neg(upper)
+	_, ln2Hi := lnBounds(big.NewRat(2, 1), tol)
+	slope := new(big.Rat).Neg(l2lo)
+	slope.Sub(slope, new(big.Rat).Inv(ln2Hi))
+	if slope.Sign() > 0 {
+		upper.Add(upper, slope.Mul(slope, new(big.Rat).Sub(hi, lo)))
+	}
+	return lower, upper
+}
+
+// waterLevel solves Σ_i (v_i - t)_+ = eps for t, where vals are
+// non-negative and sum to more than eps. It returns the unique t > 0.
+func waterLevel(vals []*big.Rat, eps *big.Rat) *big.Rat {
+	sorted := append([]*big.Rat(nil), vals...)
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && sorted[j].Cmp(sorted[j-1]) > 0; j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+	sum := new(big.Rat)
+	for k := 1; k <= len(sorted); k++ {
+		sum.Add(sum, sorted[k-1])
+		t := new(big.Rat).Sub(sum, eps)
+		t.Quo(t, big.NewRat(int64(k), 1))
+		if k == len(sorted) || t.Cmp(sorted[k]) >= 0 {
+			return t
+		}
+	}
+	return new(big.Rat)
+}
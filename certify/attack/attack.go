@@ -291,40 +291,13 @@ func DepolarizingChannel(p *big.Rat) ([]*runtime.Matrix, []*big.Rat) {
 	return operators, coeffSq
 }
 
-// ComputeChoiMatrix computes the Choi matrix from Kraus operators.
-// J(Phi) = sum_i (I tensor K_i) |Omega><Omega| (I tensor K_i^dagger)
-// where |Omega> = (1/sqrt(d)) * sum_j |j>|j>
-// For 2x2 matrices, this produces a 4x4 Choi matrix.
-func ComputeChoiMatrix(kraus []*runtime.Matrix, coeffSq []*big.Rat) *runtime.Matrix {
-	if len(kraus) == 0 {
+// choiMatrix returns the Choi matrix of the channel with Kraus operators
+// sqrt(coeffSq_i)·K_i, or nil if the operators are malformed.
+func choiMatrix(kraus []*runtime.Matrix, coeffSq []*big.Rat) *runtime.Matrix {
+	choi, err := runtime.WeightedKrausToChoi(kraus, coeffSq)
+	if err != nil {
 		return nil
 	}
-
-	d := kraus[0].Rows // Input dimension
-	choi := runtime.NewMatrix(d*d, d*d)
-
-	// Build the Choi matrix entry by entry
-	// J[i*d+j, k*d+l] = sum_m coeff_m * K_m[j,l] * conj(K_m[i,k])
-	for i := 0; i < d; i++ {
-		for j := 0; j < d; j++ {
-			for k := 0; k < d; k++ {
-				for l := 0; l < d; l++ {
-					sum := runtime.QIZero()
-					for m, K := range kraus {
-						// K[j,l] * conj(K[i,k]) * coeff^2
-						kjl := K.Get(j, l)
-						kik := K.Get(i, k)
-						kikConj := runtime.QIConj(kik)
-						prod := runtime.QIMul(kjl, kikConj)
-						scaled := runtime.QIScale(prod, coeffSq[m])
-						sum = runtime.QIAdd(sum, scaled)
-					}
-					choi.Set(i*d+j, k*d+l, sum)
-				}
-			}
-		}
-	}
-
 	return choi
 }
 
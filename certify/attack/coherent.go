@@ -119,7 +119,7 @@ func (a *GeneralCoherentAttack) KeyRateBound(qber *big.Rat) *big.Rat {
 // For coherent attacks, we return the worst-case channel at threshold.
 func (a *GeneralCoherentAttack) ChoiMatrix() *runtime.Matrix {
 	kraus, coeffSq := DepolarizingChannel(a.securityThreshold)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -227,7 +227,7 @@ func (a *RennerSecurityAttack) FiniteKeyRate() *big.Rat {
 // ChoiMatrix returns the Choi matrix at the observed QBER.
 func (a *RennerSecurityAttack) ChoiMatrix() *runtime.Matrix {
 	kraus, coeffSq := DepolarizingChannel(a.qber)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -320,7 +320,7 @@ func (a *ComposableSecurityAttack) ChoiMatrix() *runtime.Matrix {
 	// At worst case, QBER is at threshold
 	threshold := big.NewRat(11, 100)
 	kraus, coeffSq := DepolarizingChannel(threshold)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -411,7 +411,7 @@ func (a *DeviceIndependentAttack) ChoiMatrix() *runtime.Matrix {
 		noise = big.NewRat(0, 1)
 	}
 	kraus, coeffSq := DepolarizingChannel(noise)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
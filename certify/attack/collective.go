@@ -86,7 +86,7 @@ func (a *CollectiveMeasurementAttack) HolevoBound() *big.Rat {
 func (a *CollectiveMeasurementAttack) ChoiMatrix() *runtime.Matrix {
 	// The effective single-qubit channel is approximately depolarizing
 	kraus, coeffSq := DepolarizingChannel(a.inducedError)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition of the marginal channel.
@@ -216,7 +216,7 @@ func (a *AsymmetricCloningAttack) EvesFidelity() *big.Rat {
 // ChoiMatrix returns the Choi matrix of the channel to Bob.
 func (a *AsymmetricCloningAttack) ChoiMatrix() *runtime.Matrix {
 	kraus, coeffSq := DepolarizingChannel(a.disturbance)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -353,7 +353,7 @@ func (a *DevetakWinterAttack) KeyRate() *big.Rat {
 // ChoiMatrix returns the Choi matrix of the attack channel.
 func (a *DevetakWinterAttack) ChoiMatrix() *runtime.Matrix {
 	kraus, coeffSq := DepolarizingChannel(a.qber)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -435,7 +435,7 @@ func (a *EntanglementBasedAttack) EntanglementStrength() *big.Rat {
 // ChoiMatrix returns the Choi matrix.
 func (a *EntanglementBasedAttack) ChoiMatrix() *runtime.Matrix {
 	kraus, coeffSq := DepolarizingChannel(a.disturbance)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -505,7 +505,7 @@ func (a *SequentialAttack) WindowSize() int {
 // ChoiMatrix returns the Choi matrix.
 func (a *SequentialAttack) ChoiMatrix() *runtime.Matrix {
 	kraus, coeffSq := DepolarizingChannel(a.disturbance)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -170,7 +170,7 @@ func (a *PNSAttack) ChoiMatrix() *runtime.Matrix {
 	// (Eve just removes some photons from multi-photon pulses)
 	kraus := []*runtime.Matrix{runtime.Identity(2)}
 	coeffSq := []*big.Rat{big.NewRat(1, 1)}
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -307,7 +307,7 @@ func (a *DetectorBlindingAttack) ChoiMatrix() *runtime.Matrix {
 
 	// Small depolarizing noise from imperfect control
 	kraus, coeffSq := DepolarizingChannel(imperfection)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -426,7 +426,7 @@ func (a *TimeShiftAttack) ChoiMatrix() *runtime.Matrix {
 	// The channel is approximately depolarizing with parameter related to mismatch
 	p := a.DisturbanceInduced()
 	kraus, coeffSq := DepolarizingChannel(p)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -569,7 +569,7 @@ func (a *TrojanHorseAttack) ChoiMatrix() *runtime.Matrix {
 	// The quantum channel itself is unaffected - identity
 	kraus := []*runtime.Matrix{runtime.Identity(2)}
 	coeffSq := []*big.Rat{big.NewRat(1, 1)}
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -701,7 +701,7 @@ func (a *WavelengthAttack) Severity() Severity {
 func (a *WavelengthAttack) ChoiMatrix() *runtime.Matrix {
 	p := a.DisturbanceInduced()
 	kraus, coeffSq := DepolarizingChannel(p)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -830,7 +830,7 @@ func (a *AfterGateAttack) Severity() Severity {
 func (a *AfterGateAttack) ChoiMatrix() *runtime.Matrix {
 	p := a.DisturbanceInduced()
 	kraus, coeffSq := DepolarizingChannel(p)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -82,7 +82,7 @@ func (a *InterceptResendAttack) MeasurementBasis() string {
 func (a *InterceptResendAttack) ChoiMatrix() *runtime.Matrix {
 	p := a.disturbance // p = 1/4 for random basis
 	kraus, coeffSq := DepolarizingChannel(p)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -205,7 +205,7 @@ func (a *OptimalCloningAttack) CloneFidelity() *big.Rat {
 func (a *OptimalCloningAttack) ChoiMatrix() *runtime.Matrix {
 	// The effective channel to Bob is depolarizing with p = disturbance
 	kraus, coeffSq := DepolarizingChannel(a.disturbance)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition for Bob's channel.
@@ -374,7 +374,7 @@ func (a *USDAttack) ChoiMatrix() *runtime.Matrix {
 	two := big.NewRat(2, 1)
 	effectiveP := new(big.Rat).Quo(a.inconclusive, two)
 	kraus, coeffSq := DepolarizingChannel(effectiveP)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -459,7 +459,7 @@ func (a *PhaseCovariantCloningAttack) CloneFidelity() *big.Rat {
 // ChoiMatrix returns the Choi matrix.
 func (a *PhaseCovariantCloningAttack) ChoiMatrix() *runtime.Matrix {
 	kraus, coeffSq := DepolarizingChannel(a.disturbance)
-	return ComputeChoiMatrix(kraus, coeffSq)
+	return choiMatrix(kraus, coeffSq)
 }
 
 // KrausOperators returns the Kraus decomposition.
@@ -530,7 +530,7 @@ func (a *BeamSplittingAttack) SplitRatio() *big.Rat {
 func (a *BeamSplittingAttack) ChoiMatrix() *runtime.Matrix {
 	// Ideally, beam splitting doesn't disturb the transmitted state
 	// The channel to Bob is approximately identity
-	return ComputeChoiMatrix([]*runtime.Matrix{runtime.Identity(2)}, []*big.Rat{big.NewRat(1, 1)})
+	return choiMatrix([]*runtime.Matrix{runtime.Identity(2)}, []*big.Rat{big.NewRat(1, 1)})
 }
 
 // KrausOperators returns the Kraus decomposition.
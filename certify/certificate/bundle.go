@@ -56,6 +56,7 @@ type FullAnalysisBundle struct {
 	// Correctness
 	CorrectnessEvidence *Evidence
 	IdealChannelChoi    *runtime.Matrix
+	ChannelEvidence     *Evidence // CPTP certificate of the protocol channel
 
 	// Security (per adversary model)
 	SecurityEvidence map[string]*Evidence // adversary model -> evidence
@@ -124,6 +125,18 @@ func GenerateFullAnalysis(protocolName string, opts *AnalysisOptions) (*FullAnal
 			correctnessResult.IdealChannel,
 		)
 		result.IdealChannelChoi = correctnessResult.IdealChannel
+		AttachDiamondCertificate(result.CorrectnessEvidence, correctnessResult.Diamond)
+
+		if correctnessResult.ChoiMatrix != nil {
+			witness, certErr := analysis.CertifyChannel(
+				correctnessResult.ChoiMatrix,
+				correctnessResult.InDim,
+				correctnessResult.OutDim,
+			)
+			if certErr == nil {
+				result.ChannelEvidence = CreateFromChannelWitness(protocolName, "protocol", witness)
+			}
+		}
 	}
 
 	// 4. Security analysis for each adversary model
@@ -198,6 +211,10 @@ func GenerateFullAnalysis(protocolName string, opts *AnalysisOptions) (*FullAnal
 	if result.CorrectnessEvidence != nil {
 		result.Bundle.AddEvidence(result.CorrectnessEvidence)
 	}
+	if result.ChannelEvidence != nil {
+		result.Bundle.Metadata["valid_channel"] = result.ChannelEvidence.Status.String()
+		result.Bundle.AddEvidence(result.ChannelEvidence)
+	}
 	for model, ev := range result.SecurityEvidence {
 		if ev != nil {
 			ev.Claim.Protocol = protocolName
@@ -555,6 +572,9 @@ func (fab *FullAnalysisBundle) GenerateSummary() string {
 	} else {
 		sb.WriteString("Status: NOT ANALYZED\n")
 	}
+	if fab.ChannelEvidence != nil {
+		sb.WriteString(fmt.Sprintf("Valid channel (CPTP): %s\n", fab.ChannelEvidence.Status.String()))
+	}
 	sb.WriteString("\n")
 
 	// Security by model
@@ -688,6 +708,12 @@ func (fab *FullAnalysisBundle) ToValue() runtime.Value {
 		correctnessVal = fab.CorrectnessEvidence.ToValue()
 	}
 
+	// Convert channel evidence
+	var channelVal runtime.Value = runtime.MakeNil()
+	if fab.ChannelEvidence != nil {
+		channelVal = fab.ChannelEvidence.ToValue()
+	}
+
 	// Convert ideal channel
 	var idealChannelVal runtime.Value = runtime.MakeNil()
 	if fab.IdealChannelChoi != nil {
@@ -735,6 +761,7 @@ func (fab *FullAnalysisBundle) ToValue() runtime.Value {
 			thresholdVal,
 			keyRateVal,
 			bundleVal,
+			channelVal,
 		),
 	)
 }
@@ -857,6 +884,13 @@ func FullAnalysisBundleFromValue(v runtime.Value) (*FullAnalysisBundle, bool) {
 		fab.Bundle, _ = BundleFromValue(seq.Items[13])
 	}
 
+	// Parse channel evidence (item 14, absent in older bundles)
+	if len(seq.Items) > 14 {
+		if _, isNil := seq.Items[14].(runtime.Nil); !isNil {
+			fab.ChannelEvidence, _ = EvidenceFromValue(seq.Items[14])
+		}
+	}
+
 	return fab, true
 }
 
@@ -9,6 +9,7 @@ package certificate
 import (
 	"math/big"
 
+	"qbtm/certify/analysis"
 	"qbtm/runtime"
 )
 
@@ -27,6 +28,7 @@ const (
 	ClaimBinding  // For bit commitment
 	ClaimHiding   // For bit commitment
 	ClaimBias     // For coin flipping
+	ClaimValidChannel
 )
 
 // String returns a human-readable name for the claim type.
@@ -54,6 +56,8 @@ func (t ClaimType) String() string {
 		return "hiding"
 	case ClaimBias:
 		return "bias"
+	case ClaimValidChannel:
+		return "valid-channel"
 	default:
 		return "unknown"
 	}
@@ -193,6 +197,20 @@ func NewBiasClaim(protocol string, bias *big.Rat) *Claim {
 	}
 }
 
+// NewValidChannelClaim creates a claim that a channel of the protocol is
+// completely positive and trace preserving.
+func NewValidChannelClaim(protocol string, channel string, inDim, outDim int) *Claim {
+	return &Claim{
+		Type:        ClaimValidChannel,
+		Protocol:    protocol,
+		Description: "Channel " + channel + " is completely positive and trace preserving",
+		Parameters: map[string]*big.Rat{
+			"in-dim":  big.NewRat(int64(inDim), 1),
+			"out-dim": big.NewRat(int64(outDim), 1),
+		},
+	}
+}
+
 // AddDependency adds a claim dependency.
 func (c *Claim) AddDependency(dep string) {
 	c.Dependencies = append(c.Dependencies, dep)
@@ -237,6 +255,10 @@ func (c *Claim) CanVerify() bool {
 	case ClaimBias:
 		_, hasBias := c.Parameters["bias"]
 		return hasBias
+	case ClaimValidChannel:
+		_, hasInDim := c.Parameters["in-dim"]
+		_, hasOutDim := c.Parameters["out-dim"]
+		return hasInDim && hasOutDim
 	default:
 		return len(c.Parameters) > 0
 	}
@@ -248,6 +270,14 @@ func (c *Claim) Verify(witness *Witness) bool {
 		return false
 	}
 
+	// Valid-channel and epsilon-closeness claims are only as good as their certificates
+	if c.Type == ClaimValidChannel {
+		return c.verifyChannel(witness)
+	}
+	if _, ok := c.Parameters["epsilon"]; ok && c.Type == ClaimCorrectness && witness == nil {
+		return false
+	}
+
 	// If no witness provided, we can only check parameter validity
 	if witness == nil {
 		return c.verifyParameters()
@@ -259,6 +289,14 @@ func (c *Claim) Verify(witness *Witness) bool {
 		if witness.Type != WitnessChoiMatrix && witness.Type != WitnessChoiEquality {
 			return false
 		}
+		// An epsilon-closeness claim needs a diamond certificate within epsilon
+		if epsilon, ok := c.Parameters["epsilon"]; ok {
+			dc, ok := witness.DiamondCertificate()
+			if !ok || dc.Upper.Cmp(epsilon) > 0 {
+				return false
+			}
+			return witness.Verify()
+		}
 		// Check fidelity is 1 for perfect correctness
 		if fidelity, ok := c.Parameters["fidelity"]; ok {
 			if fidelity.Cmp(big.NewRat(1, 1)) == 0 {
@@ -283,6 +321,23 @@ func (c *Claim) Verify(witness *Witness) bool {
 	return witness.Verify()
 }
 
+// verifyChannel rechecks a valid-channel claim against a positivity
+// witness whose dimensions match the claim.
+func (c *Claim) verifyChannel(witness *Witness) bool {
+	if witness == nil || witness.Type != WitnessPositivity {
+		return false
+	}
+	w, ok := analysis.ChannelWitnessFromValue(witness.Data)
+	if !ok {
+		return false
+	}
+	if c.Parameters["in-dim"].Cmp(big.NewRat(int64(w.InDim), 1)) != 0 ||
+		c.Parameters["out-dim"].Cmp(big.NewRat(int64(w.OutDim), 1)) != 0 {
+		return false
+	}
+	return witness.Verify()
+}
+
 // verifyParameters checks that all parameters are valid.
 func (c *Claim) verifyParameters() bool {
 	for key, val := range c.Parameters {
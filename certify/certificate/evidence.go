@@ -103,6 +103,14 @@ func (e *Evidence) Verify() bool {
 		if !e.Claim.CanVerify() {
 			return false
 		}
+		// Valid-channel claims must carry a matching positivity certificate
+		if e.Claim.Type == ClaimValidChannel && !e.Claim.Verify(e.Witness) {
+			return false
+		}
+		// Epsilon-closeness claims must carry a diamond certificate
+		if _, ok := e.Claim.Parameters["epsilon"]; ok && e.Claim.Type == ClaimCorrectness && !e.Claim.Verify(e.Witness) {
+			return false
+		}
 	}
 
 	return true
@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"time"
 
+	"qbtm/certify/analysis"
 	"qbtm/runtime"
 )
 
@@ -49,6 +50,41 @@ func CreateFromCorrectnessResult(correct bool, fidelity *big.Rat, choiMatrix, id
 	}
 }
 
+// AttachDiamondCertificate records a certified diamond distance to the
+// ideal channel on correctness Evidence: the claim gains an "epsilon"
+// parameter equal to the certified upper bound, and the witness carries
+// the certificate so the bound can be rechecked.
+func AttachDiamondCertificate(ev *Evidence, c *analysis.DiamondCertificate) {
+	if ev == nil || ev.Claim == nil || ev.Witness == nil || c == nil {
+		return
+	}
+	ev.Claim.Parameters["epsilon"] = new(big.Rat).Set(c.Upper)
+	ev.Claim.Description = "Protocol is epsilon-close to its ideal functionality in diamond distance"
+	items := []runtime.Value{runtime.MakeNil(), runtime.MakeNil(), runtime.MakeBool(false)}
+	if seq, ok := ev.Witness.Data.(runtime.Seq); ok && len(seq.Items) >= 3 {
+		items = append([]runtime.Value(nil), seq.Items[:3]...)
+	}
+	ev.Witness.Data = runtime.MakeSeq(append(items, c.ToValue())...)
+}
+
+// CreateFromChannelWitness creates valid-channel Evidence from an exact
+// CPTP certificate of one of a protocol's channels. The evidence is
+// verified only if the witness proves the map CPTP.
+func CreateFromChannelWitness(protocol string, channel string, w *analysis.ChannelWitness) *Evidence {
+	status := StatusFailed
+	if w.IsCPTP() {
+		status = StatusVerified
+	}
+
+	return &Evidence{
+		Status:    status,
+		Claim:     NewValidChannelClaim(protocol, channel, w.InDim, w.OutDim),
+		Witness:   NewPositivityWitness(w),
+		Timestamp: time.Now().Unix(),
+		Version:   "1.0.0",
+	}
+}
+
 // CreateFromSecurityResult creates Evidence from a security analysis result.
 // Parameters match SecurityResult fields from the analysis package.
 func CreateFromSecurityResult(protocol string, adversaryModel string, keyRateBound *big.Rat, threshold *big.Rat, isSecure bool) *Evidence {
@@ -9,6 +9,7 @@ package certificate
 import (
 	"math/big"
 
+	"qbtm/certify/analysis"
 	"qbtm/runtime"
 )
 
@@ -25,6 +26,7 @@ const (
 	WitnessAttackAnalysis
 	WitnessChoiEquality
 	WitnessInformationBound
+	WitnessPositivity
 )
 
 // String returns a human-readable name for the witness type.
@@ -48,6 +50,8 @@ func (t WitnessType) String() string {
 		return "choi-equality"
 	case WitnessInformationBound:
 		return "information-bound"
+	case WitnessPositivity:
+		return "positivity"
 	default:
 		return "unknown"
 	}
@@ -126,6 +130,29 @@ func NewCompositionWitness(protocols []string, compositionType string) *Witness
 	}
 }
 
+// NewPositivityWitness creates a witness carrying an exact CPTP
+// certificate for a Choi matrix.
+func NewPositivityWitness(w *analysis.ChannelWitness) *Witness {
+	return &Witness{
+		Type:        WitnessPositivity,
+		Description: "LDL† positivity and partial-trace certificate",
+		Data:        w.ToValue(),
+	}
+}
+
+// DiamondCertificate returns the diamond-distance certificate attached to
+// a correctness witness, if any.
+func (w *Witness) DiamondCertificate() (*analysis.DiamondCertificate, bool) {
+	if w == nil || w.Type != WitnessChoiEquality {
+		return nil, false
+	}
+	seq, ok := w.Data.(runtime.Seq)
+	if !ok || len(seq.Items) < 4 {
+		return nil, false
+	}
+	return analysis.DiamondCertificateFromValue(seq.Items[3])
+}
+
 // AddAssumption adds an assumption to the witness.
 func (w *Witness) AddAssumption(assumption string) {
 	w.Assumptions = append(w.Assumptions, assumption)
@@ -166,6 +193,16 @@ func (w *Witness) Verify() bool {
 				}
 			}
 		}
+	case WitnessPositivity:
+		// Recheck the certificate; it must prove a CPTP map
+		cw, ok := analysis.ChannelWitnessFromValue(w.Data)
+		return ok && cw.Verify() && cw.IsCPTP()
+	case WitnessChoiEquality:
+		// A diamond-distance certificate, if attached, must recheck
+		if seq, ok := w.Data.(runtime.Seq); ok && len(seq.Items) >= 4 {
+			dc, ok := analysis.DiamondCertificateFromValue(seq.Items[3])
+			return ok && dc.Verify()
+		}
 	}
 
 	return true
@@ -2,23 +2,41 @@
 //
 // These tests verify:
 // - Full analysis pipelines for QKD protocols (BB84, E91)
+// - End-to-end execution of synthesized QKD rounds
 // - Teleportation protocol correctness verification
 // - Protocol composition with security bound propagation
 // - Self-verification of certified models
 // - Exact rational arithmetic throughout the pipeline
+// - Statevector execution of multiparty state preparation
+// - Exact spectra and certified entropy bounds
+// - LDL† positivity certificates and exact CPTP decisions
+// - Certified trace distances and diamond-distance certificates
+// - Rational SDP bounds from the interior-point solver
 // - Attack library completeness
 // - Serialization round-trip integrity
+// - Signed certificate bundles and trust-store checks
 package certify
 
 import (
+	"errors"
+	"math"
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"qbtm/certify/analysis"
 	"qbtm/certify/attack"
 	"qbtm/certify/certificate"
+	"qbtm/certify/linalg"
 	"qbtm/certify/protocol/communication"
+	"qbtm/certify/protocol/cryptographic"
+	"qbtm/certify/protocol/multiparty"
+	"qbtm/certify/protocol"
 	"qbtm/certify/protocol/qkd"
+	"qbtm/certify/sdp"
+	"qbtm/runtime"
 )
 
 // TestBB84FullAnalysis verifies the complete analysis pipeline for BB84 protocol.
@@ -218,6 +236,128 @@ func TestE91Security(t *testing.T) {
 	}
 }
 
+// TestQKDRoundExecution runs each synthesized QKD round on a uniform
+// distribution over its classical inputs and checks the sift statistics.
+func TestQKDRoundExecution(t *testing.T) {
+	rat := func(r *big.Rat) runtime.QZ8 {
+		return runtime.QZ8FromQI(runtime.NewQI(r, new(big.Rat)))
+	}
+	// (1 - 1/sqrt(2))/2 = 1/2 - sqrt(2)/4
+	b92Rate := runtime.NewQZ8(runtime.NewQI(big.NewRat(1, 2), new(big.Rat)),
+		runtime.NewQI(big.NewRat(-1, 4), new(big.Rat)))
+
+	// B92's USD weight and E91's pi/4 bases are over Q(zeta8), and some of
+	// their outcome probabilities have a sqrt(2) part, so every round runs
+	// on ExecuteZ8 and is checked exactly.
+	tests := []struct {
+		name     string
+		synth    protocol.ProtocolSynthesizer
+		keepRate runtime.QZ8
+	}{
+		{"BB84", qkd.NewBB84(4), rat(big.NewRat(1, 2))},
+		{"SixState", qkd.NewSixState(4), rat(big.NewRat(1, 3))},
+		{"SARG04", qkd.NewSARG04(4), rat(big.NewRat(1, 4))},
+		{"B92", qkd.NewB92(4), b92Rate},
+		{"E91", qkd.NewE91(9), rat(big.NewRat(2, 9))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := runtime.NewStore()
+			qgid, err := tt.synth.Synthesize(store)
+			if err != nil {
+				t.Fatalf("Synthesize failed: %v", err)
+			}
+			circuit, ok := store.Get(qgid)
+			if !ok {
+				t.Fatal("synthesized circuit not in store")
+			}
+			if diags := runtime.TypeCheck(store, qgid); diags != nil {
+				t.Fatalf("round does not type check: %v", diags)
+			}
+
+			n := runtime.BlockDim(circuit.Domain)
+			input := runtime.MatScale(runtime.Identity(n), big.NewRat(1, int64(n)))
+
+			result, err := runtime.NewExecutor(store).ExecuteZ8(circuit, runtime.MatrixToZ8(input))
+			if err != nil {
+				t.Fatalf("ExecuteZ8 failed: %v", err)
+			}
+			if result.Rows != qkd.SiftOutcomes || result.Cols != qkd.SiftOutcomes {
+				t.Fatalf("round output is %dx%d, want %dx%d",
+					result.Rows, result.Cols, qkd.SiftOutcomes, qkd.SiftOutcomes)
+			}
+
+			total := runtime.QZ8Zero()
+			kept := runtime.QZ8Zero()
+			mismatched := runtime.QZ8Zero()
+			for a := 0; a < 2; a++ {
+				for b := 0; b < 2; b++ {
+					for _, keep := range []bool{false, true} {
+						p := result.Get(qkd.SiftIndex(keep, a, b), qkd.SiftIndex(keep, a, b))
+						if !isProbabilityZ8(p) {
+							t.Errorf("P(keep=%v, a=%d, b=%d) = %v is not a probability", keep, a, b, p)
+						}
+						total = runtime.QZ8Add(total, p)
+						if keep {
+							kept = runtime.QZ8Add(kept, p)
+							if a != b {
+								mismatched = runtime.QZ8Add(mismatched, p)
+							}
+						}
+					}
+				}
+			}
+
+			if !runtime.QZ8Equal(total, runtime.QZ8One()) {
+				t.Errorf("total probability = %v, want 1", total)
+			}
+			if !runtime.QZ8Equal(kept, tt.keepRate) {
+				t.Errorf("P(keep) = %v, want %v", kept, tt.keepRate)
+			}
+			if !runtime.QZ8IsZero(mismatched) {
+				t.Errorf("P(keep, a != b) = %v, want 0", mismatched)
+			}
+		})
+	}
+
+	// On the default exact executor E91 fails with ErrNotQI, which names
+	// ExecuteZ8, rather than rounding.
+	store := runtime.NewStore()
+	qgid, err := qkd.NewE91(9).Synthesize(store)
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	circuit, _ := store.Get(qgid)
+	input := runtime.MatScale(runtime.Identity(9), big.NewRat(1, 9))
+	if _, err := runtime.NewExecutor(store).Execute(circuit, input); !errors.Is(err, runtime.ErrNotQI) {
+		t.Errorf("Execute on E91 = %v, want ErrNotQI", err)
+	}
+}
+
+// isProbabilityZ8 reports whether p = a + b*sqrt(2) is real and
+// nonnegative.
+func isProbabilityZ8(p runtime.QZ8) bool {
+	if p.A.Im.Sign() != 0 || p.B.Im.Sign() != 0 {
+		return false
+	}
+	a, b := p.A.Re, p.B.Re
+	if a.Sign() >= 0 && b.Sign() >= 0 {
+		return true
+	}
+	if a.Sign() <= 0 && b.Sign() <= 0 {
+		return false
+	}
+	// Opposite signs: compare a^2 with 2b^2.
+	a2 := new(big.Rat).Mul(a, a)
+	b2 := new(big.Rat).Mul(b, b)
+	b2.Add(b2, b2)
+	if a.Sign() > 0 {
+		return a2.Cmp(b2) >= 0
+	}
+	return b2.Cmp(a2) >= 0
+}
+
 // TestProtocolComposition verifies sequential protocol composition with security bound propagation.
 func TestProtocolComposition(t *testing.T) {
 	// Create two protocols to compose
@@ -425,6 +565,732 @@ func TestExactRationals(t *testing.T) {
 	}
 }
 
+// TestExactGates verifies that gates and states involving 1/sqrt(2) are exact
+// over Q(zeta8).
+func TestExactGates(t *testing.T) {
+	H := qkd.Hadamard()
+	if !runtime.MatrixEqualZ8(runtime.MatMulZ8(runtime.DaggerZ8(H), H), runtime.IdentityZ8(2)) {
+		t.Error("Hadamard should be exactly unitary")
+	}
+
+	// T² = S
+	T := qkd.TGate()
+	S, ok := runtime.MatrixZ8ToQI(runtime.MatMulZ8(T, T))
+	if !ok || !runtime.MatrixEqual(S, qkd.Phase()) {
+		t.Error("T² should be exactly S")
+	}
+	if !runtime.MatrixEqualZ8(runtime.MatMulZ8(T, qkd.TDagger()), runtime.IdentityZ8(2)) {
+		t.Error("T T† should be exactly I")
+	}
+
+	// <Phi+|Phi+> = 1 and |Phi+><Phi+| has entries exactly 1/2
+	phi := qkd.BellPhiPlus()
+	if norm := runtime.MatMulZ8(runtime.DaggerZ8(phi), phi); !runtime.MatrixEqualZ8(norm, runtime.IdentityZ8(1)) {
+		t.Error("|Phi+> should be exactly normalized")
+	}
+	rho := qkd.RhoBellPhiPlus()
+	if rho == nil || rho.Get(0, 3).Re.Cmp(big.NewRat(1, 2)) != 0 {
+		t.Error("|Phi+><Phi+| should have exact entries 1/2")
+	}
+
+	// H|0> = |+>
+	plus := runtime.MatMulZ8(H, qkd.Ket0())
+	if !runtime.MatrixEqualZ8(plus, qkd.KetPlus()) {
+		t.Error("H|0> should be exactly |+>")
+	}
+
+	for _, d := range []int{1, 2, 4, 8} {
+		omega, ok := analysis.MaximallyEntangledKet(d)
+		if !ok {
+			t.Errorf("MaximallyEntangledKet(%d) should be exact", d)
+			continue
+		}
+		norm := runtime.MatMulZ8(runtime.DaggerZ8(omega), omega)
+		if !runtime.MatrixEqualZ8(norm, runtime.IdentityZ8(1)) {
+			t.Errorf("MaximallyEntangledKet(%d) should be exactly normalized", d)
+		}
+	}
+	if _, ok := analysis.MaximallyEntangledKet(3); ok {
+		t.Error("MaximallyEntangledKet(3) needs 1/sqrt(3) and should not be exact")
+	}
+
+	// E91 bases are exact projective measurements; at pi/4 the observable
+	// P0 - P1 is (X+Z)/sqrt(2) = H.
+	alice, bob := qkd.E91Projectors()
+	for name, bases := range map[string][3][2]*runtime.MatrixZ8{"alice": alice, "bob": bob} {
+		for i, P := range bases {
+			for o, p := range P {
+				if !runtime.MatrixEqualZ8(runtime.MatMulZ8(p, p), p) {
+					t.Errorf("%s basis %d outcome %d: P² != P", name, i, o)
+				}
+			}
+			if !runtime.MatrixEqualZ8(runtime.MatAddZ8(P[0], P[1]), runtime.IdentityZ8(2)) {
+				t.Errorf("%s basis %d: P0 + P1 != I", name, i)
+			}
+		}
+	}
+	if !runtime.MatrixEqualZ8(runtime.MatSubZ8(alice[1][0], alice[1][1]), H) {
+		t.Error("Alice's pi/4 observable should be exactly H")
+	}
+
+	// E(a1,b1) - E(a1,b3) + E(a3,b1) + E(a3,b3) reaches 2*sqrt(2) exactly.
+	chsh := runtime.QZ8Sub(qkd.CHSHCorrelator(0, 0), qkd.CHSHCorrelator(0, 2))
+	chsh = runtime.QZ8Add(chsh, qkd.CHSHCorrelator(2, 0))
+	chsh = runtime.QZ8Add(chsh, qkd.CHSHCorrelator(2, 2))
+	if want := runtime.QZ8Scale(runtime.QZ8Sqrt2(), big.NewRat(2, 1)); !runtime.QZ8Equal(chsh, want) {
+		t.Errorf("CHSH value = %v, want 2*sqrt(2)", chsh)
+	}
+	if !runtime.QZ8Equal(qkd.CHSHCorrelator(1, 0), runtime.QZ8One()) {
+		t.Error("the pi/4 key bases should be perfectly correlated")
+	}
+
+	// B92's USD POVM is complete and its conclusive effects annihilate the
+	// other state.
+	E := qkd.B92POVMElements()
+	if !runtime.MatrixEqualZ8(runtime.MatAddZ8(runtime.MatAddZ8(E[0], E[1]), E[2]), runtime.IdentityZ8(2)) {
+		t.Error("B92 POVM elements should sum to I")
+	}
+	states := qkd.B92States()
+	for i, other := range []*runtime.MatrixZ8{states[1], states[0]} {
+		if !runtime.MatrixEqualZ8(runtime.MatMulZ8(E[i], other), runtime.NewMatrixZ8(2, 1)) {
+			t.Errorf("B92 effect %d should not click on the other state", i)
+		}
+	}
+}
+
+// TestMultipartyStatevector runs the GHZ and W-state preparation circuits
+// on |0...0> through a statevector Runner and checks the result against
+// density-matrix execution.
+func TestMultipartyStatevector(t *testing.T) {
+	const n = 5
+	dim := 1 << n
+
+	cases := []struct {
+		name  string
+		synth func(*runtime.Store) ([32]byte, error)
+	}{
+		{"ghz", multiparty.NewGHZ(n).Synthesize},
+		{"w-state", multiparty.NewWState(n).Synthesize},
+	}
+	for _, tc := range cases {
+		store := runtime.NewStore()
+		id, err := tc.synth(store)
+		if err != nil {
+			t.Fatalf("%s: synthesis failed: %v", tc.name, err)
+		}
+		runner, err := runtime.NewRunner(runtime.Embed(store, id, tc.name, "1").Encode(), runtime.WithStatevector())
+		if err != nil {
+			t.Fatalf("%s: NewRunner failed: %v", tc.name, err)
+		}
+
+		input := runtime.NewMatrix(dim, dim)
+		input.Set(0, 0, runtime.QIOne())
+		got, err := runner.Run(input)
+		if err != nil {
+			t.Fatalf("%s: statevector run failed: %v", tc.name, err)
+		}
+		c, _ := store.Get(id)
+		want, err := runtime.NewExecutor(store).Execute(c, input)
+		if err != nil {
+			t.Fatalf("%s: density execution failed: %v", tc.name, err)
+		}
+		if !runtime.MatrixEqual(got, want) {
+			t.Errorf("%s: statevector output differs from density output", tc.name)
+		}
+
+		// Both states are prepared exactly.
+		exact := multiparty.GHZDensityMatrix(n)
+		if tc.name == "w-state" {
+			exact = multiparty.WDensityMatrix(n)
+		}
+		if !runtime.MatrixEqual(got, exact) {
+			t.Errorf("%s: output is not the exact %s density matrix", tc.name, tc.name)
+		}
+	}
+
+	// Only |0...0> and |1...1> carry amplitude, 1/2 each.
+	ghz := multiparty.GHZDensityMatrix(n)
+	half := runtime.NewQI(big.NewRat(1, 2), new(big.Rat))
+	for i := 0; i < dim; i++ {
+		for j := 0; j < dim; j++ {
+			corner := (i == 0 || i == dim-1) && (j == 0 || j == dim-1)
+			if corner && !runtime.QIEqual(ghz.Get(i, j), half) || !corner && !runtime.QIIsZero(ghz.Get(i, j)) {
+				t.Fatalf("ghz: unexpected entry %v at (%d,%d)", ghz.Get(i, j), i, j)
+			}
+		}
+	}
+	if !runtime.QIEqual(runtime.Trace(multiparty.WDensityMatrix(n)), runtime.QIOne()) {
+		t.Error("the W density matrix should have trace 1")
+	}
+}
+
+// TestExactProtocolStates checks that the communication and cryptographic
+// gates and states with a 1/sqrt(2) entry are exact.
+func TestExactProtocolStates(t *testing.T) {
+	H := communication.Hadamard()
+	if !runtime.MatrixEqualZ8(runtime.MatMulZ8(H, runtime.DaggerZ8(H)), runtime.IdentityZ8(2)) {
+		t.Error("H should be exactly unitary")
+	}
+	prep := communication.BellPreparationCircuit()
+	for i := 0; i < 4; i++ {
+		bell := communication.BellState(i)
+		// Phi+, Phi-, Psi+, Psi- map to |00>, |10>, |01>, |11>.
+		if !runtime.MatrixEqualZ8(runtime.MatMulZ8(communication.BellMeasurementUnitary(), bell),
+			communication.ComputationalBasisState(i%2, i/2)) {
+			t.Errorf("Bell measurement should map Bell state %d to |%d%d>", i, i%2, i/2)
+		}
+		if i == 0 && !runtime.MatrixEqualZ8(runtime.MatMulZ8(prep, communication.Ket00()), bell) {
+			t.Error("Bell preparation should map |00> to |Phi+>")
+		}
+	}
+	if !communication.NewSwappingVerifier().VerifySwapping() {
+		t.Error("|Phi+> tensor |Phi+> should have amplitudes exactly 1/2")
+	}
+
+	// KitaevBound is (sqrt(2) - 1)/2, and the rational Kitaev-optimal bias
+	// r lies above it: (2r + 1)^2 > 2.
+	bound := cryptographic.KitaevBound()
+	if !runtime.QZ8Equal(runtime.QZ8Add(runtime.QZ8Scale(bound, big.NewRat(2, 1)), runtime.QZ8One()), runtime.QZ8Sqrt2()) {
+		t.Errorf("KitaevBound = %v, want (sqrt(2) - 1)/2", bound)
+	}
+	r := new(big.Rat).Add(new(big.Rat).Mul(cryptographic.KitaevBiasExact, big.NewRat(2, 1)), big.NewRat(1, 1))
+	if r.Mul(r, r).Cmp(big.NewRat(2, 1)) <= 0 {
+		t.Error("KitaevBiasExact should lie above the Kitaev bound")
+	}
+}
+
+// TestHermitianSpectrum verifies exact characteristic polynomials and
+// certified eigenvalue isolation.
+func TestHermitianSpectrum(t *testing.T) {
+	// Cayley-Hamilton on a non-Hermitian complex matrix: p(A) = 0.
+	A := runtime.NewMatrix(3, 3)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			A.Set(i, j, runtime.NewQI(big.NewRat(int64(i*3+j+1), int64(j+2)), big.NewRat(int64(i-j), 3)))
+		}
+	}
+	coeffs, err := linalg.CharPoly(A)
+	if err != nil {
+		t.Fatalf("CharPoly failed: %v", err)
+	}
+	sum := runtime.NewMatrix(3, 3)
+	power := runtime.Identity(3)
+	for _, c := range coeffs {
+		scaled := runtime.NewMatrix(3, 3)
+		for i := range power.Data {
+			scaled.Data[i] = runtime.QIMul(power.Data[i], c)
+		}
+		sum = runtime.MatAdd(sum, scaled)
+		power = runtime.MatMul(power, A)
+	}
+	if !runtime.MatrixEqual(sum, runtime.NewMatrix(3, 3)) {
+		t.Error("characteristic polynomial does not annihilate its matrix")
+	}
+
+	// Rational eigenvalues are exact even off the diagonal: the Hermitian
+	// matrix [[1/2, i/4], [-i/4, 1/2]] ⊕ [1/4] has spectrum {1/4, 1/4, 3/4}.
+	H := runtime.NewMatrix(3, 3)
+	H.Set(0, 0, runtime.NewQI(big.NewRat(1, 2), new(big.Rat)))
+	H.Set(0, 1, runtime.NewQI(new(big.Rat), big.NewRat(1, 4)))
+	H.Set(1, 0, runtime.NewQI(new(big.Rat), big.NewRat(-1, 4)))
+	H.Set(1, 1, runtime.NewQI(big.NewRat(1, 2), new(big.Rat)))
+	H.Set(2, 2, runtime.NewQI(big.NewRat(1, 4), new(big.Rat)))
+	eigs, err := linalg.HermitianEigenvalues(H, big.NewRat(1, 1000))
+	if err != nil {
+		t.Fatalf("HermitianEigenvalues failed: %v", err)
+	}
+	if len(eigs) != 2 || eigs[0].Exact == nil || eigs[1].Exact == nil ||
+		eigs[0].Exact.Cmp(big.NewRat(1, 4)) != 0 || eigs[0].Multiplicity != 2 ||
+		eigs[1].Exact.Cmp(big.NewRat(3, 4)) != 0 || eigs[1].Multiplicity != 1 {
+		t.Errorf("expected exact spectrum {1/4 (x2), 3/4}, got %v", eigs)
+	}
+
+	// [[2/3, 1/3], [1/3, 1/3]] has eigenvalues (1 ± √5/3)/2.
+	I := densityFromRats(2, big.NewRat(2, 3), big.NewRat(1, 3), big.NewRat(1, 3), big.NewRat(1, 3))
+	width := big.NewRat(1, 1<<30)
+	eigs, err = linalg.HermitianEigenvalues(I, width)
+	if err != nil {
+		t.Fatalf("HermitianEigenvalues failed: %v", err)
+	}
+	want := []float64{(1 - math.Sqrt(5)/3) / 2, (1 + math.Sqrt(5)/3) / 2}
+	if len(eigs) != 2 {
+		t.Fatalf("expected 2 eigenvalues, got %d", len(eigs))
+	}
+	for i, ev := range eigs {
+		lo, _ := ev.Lower.Float64()
+		hi, _ := ev.Upper.Float64()
+		if ev.Exact != nil || ev.Width().Cmp(width) > 0 || want[i] < lo-1e-12 || want[i] > hi+1e-12 {
+			t.Errorf("eigenvalue %d: got [%g, %g], want irrational %g", i, lo, hi, want[i])
+		}
+	}
+
+	if _, err := linalg.HermitianEigenvalues(A, width); err == nil {
+		t.Error("non-Hermitian matrix should be rejected")
+	}
+}
+
+// TestCertifiedEntropy verifies entropy bounds computed from exact spectra.
+func TestCertifiedEntropy(t *testing.T) {
+	quarter := big.NewRat(1, 4)
+	zero := new(big.Rat)
+
+	exactCases := []struct {
+		name string
+		rho  *runtime.Matrix
+		vn   *big.Rat
+		hmin *big.Rat
+	}{
+		{"maximally mixed", densityFromRats(4, quarter, zero, zero, zero, zero, quarter, zero, zero,
+			zero, zero, quarter, zero, zero, zero, zero, quarter), big.NewRat(2, 1), big.NewRat(2, 1)},
+		{"pure plus", densityFromRats(2, big.NewRat(1, 2), big.NewRat(1, 2), big.NewRat(1, 2), big.NewRat(1, 2)),
+			zero, zero},
+		{"diag(1/2,1/4,1/4)", densityFromRats(3, big.NewRat(1, 2), zero, zero, zero, quarter, zero, zero, zero, quarter),
+			big.NewRat(3, 2), big.NewRat(1, 1)},
+	}
+	for _, tc := range exactCases {
+		vn, err := analysis.ComputeVonNeumann(tc.rho)
+		if err != nil {
+			t.Fatalf("%s: ComputeVonNeumann failed: %v", tc.name, err)
+		}
+		if !vn.IsExact() || vn.Exact.Cmp(tc.vn) != 0 {
+			t.Errorf("%s: S = %s, want exactly %s", tc.name, vn, tc.vn.RatString())
+		}
+		hmin, err := analysis.ComputeMinEntropy(tc.rho)
+		if err != nil {
+			t.Fatalf("%s: ComputeMinEntropy failed: %v", tc.name, err)
+		}
+		if !hmin.IsExact() || hmin.Exact.Cmp(tc.hmin) != 0 {
+			t.Errorf("%s: H_min = %s, want exactly %s", tc.name, hmin, tc.hmin.RatString())
+		}
+	}
+
+	// Cutting 1/2 down to 1/4 costs 1/4, so H_min^{1/4} = 2 exactly.
+	diag := exactCases[2].rho
+	smooth, err := analysis.ComputeSmoothMinEntropy(diag, quarter)
+	if err != nil {
+		t.Fatalf("ComputeSmoothMinEntropy failed: %v", err)
+	}
+	if !smooth.IsExact() || smooth.Exact.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("H_min^{1/4} = %s, want exactly 2", smooth)
+	}
+
+	// An irrational spectrum gets tight certified bounds.
+	rho := densityFromRats(2, big.NewRat(2, 3), big.NewRat(1, 3), big.NewRat(1, 3), big.NewRat(1, 3))
+	l0, l1 := (1-math.Sqrt(5)/3)/2, (1+math.Sqrt(5)/3)/2
+	tSmooth := l1 - 1.0/10
+	checks := []struct {
+		name string
+		f    func() (*analysis.Entropy, error)
+		want float64
+	}{
+		{"S", func() (*analysis.Entropy, error) { return analysis.ComputeVonNeumann(rho) }, -l0*math.Log2(l0) - l1*math.Log2(l1)},
+		{"H_min", func() (*analysis.Entropy, error) { return analysis.ComputeMinEntropy(rho) }, -math.Log2(l1)},
+		{"H_min^eps", func() (*analysis.Entropy, error) { return analysis.ComputeSmoothMinEntropy(rho, big.NewRat(1, 10)) }, -math.Log2(tSmooth)},
+	}
+	for _, c := range checks {
+		e, err := c.f()
+		if err != nil {
+			t.Fatalf("%s failed: %v", c.name, err)
+		}
+		lo, _ := e.Lower.Float64()
+		hi, _ := e.Upper.Float64()
+		if e.IsExact() || c.want < lo-1e-12 || c.want > hi+1e-12 || hi-lo > 1e-9 {
+			t.Errorf("%s: bounds [%.15g, %.15g] do not tightly contain %.15g", c.name, lo, hi, c.want)
+		}
+	}
+
+	// Non-states are rejected.
+	if _, err := analysis.ComputeVonNeumann(densityFromRats(1, big.NewRat(2, 1))); err == nil {
+		t.Error("trace-2 matrix should be rejected")
+	}
+	negative := densityFromRats(2, big.NewRat(1, 2), big.NewRat(1, 1), big.NewRat(1, 1), big.NewRat(1, 2))
+	if _, err := analysis.ComputeMinEntropy(negative); err == nil {
+		t.Error("matrix with a negative eigenvalue should be rejected")
+	}
+}
+
+// densityFromRats builds an n×n real matrix from row-major entries.
+func densityFromRats(n int, entries ...*big.Rat) *runtime.Matrix {
+	m := runtime.NewMatrix(n, n)
+	for k, v := range entries {
+		m.Set(k/n, k%n, runtime.NewQI(v, new(big.Rat)))
+	}
+	return m
+}
+
+// TestPSDCertificates verifies exact LDL† positivity certificates and
+// negative directions, including tampering and serialization.
+func TestPSDCertificates(t *testing.T) {
+	r := big.NewRat
+
+	// Rank-2 PSD matrix: v1 v1† + v2 v2† with v1 = (1, 1, 0), v2 = (0, 1, 2).
+	psd := densityFromRats(3,
+		r(1, 1), r(1, 1), r(0, 1),
+		r(1, 1), r(2, 1), r(2, 1),
+		r(0, 1), r(2, 1), r(4, 1))
+	cert, neg, err := linalg.CertifyPSD(psd)
+	if err != nil || cert == nil || neg != nil {
+		t.Fatalf("CertifyPSD(psd) = %v, %v, %v; want a certificate", cert, neg, err)
+	}
+	if !cert.Verify(psd) {
+		t.Error("LDL certificate does not verify")
+	}
+	if cert.Rank() != 2 {
+		t.Errorf("certified rank = %d, want 2", cert.Rank())
+	}
+	decoded, ok := linalg.LDLCertificateFromValue(cert.ToValue())
+	if !ok || !decoded.Verify(psd) {
+		t.Error("LDL certificate did not survive a Value round trip")
+	}
+
+	tampered, _ := linalg.LDLCertificateFromValue(cert.ToValue())
+	tampered.D[0] = r(2, 1)
+	if tampered.Verify(psd) {
+		t.Error("tampered LDL certificate verified")
+	}
+	tampered.D[0] = r(-1, 1)
+	if tampered.Verify(psd) {
+		t.Error("LDL certificate with a negative pivot verified")
+	}
+
+	// Negative pivot after one elimination step.
+	indefinite := densityFromRats(2, r(1, 1), r(2, 1), r(2, 1), r(1, 1))
+	// Zero pivot with a nonzero entry below it.
+	zeroPivot := densityFromRats(3,
+		r(1, 1), r(1, 1), r(0, 1),
+		r(1, 1), r(1, 1), r(1, 1),
+		r(0, 1), r(1, 1), r(1, 1))
+	for name, A := range map[string]*runtime.Matrix{"negative pivot": indefinite, "zero pivot": zeroPivot} {
+		cert, neg, err := linalg.CertifyPSD(A)
+		if err != nil || cert != nil || neg == nil {
+			t.Fatalf("%s: CertifyPSD = %v, %v, %v; want a negative direction", name, cert, neg, err)
+		}
+		if !neg.Verify(A) {
+			t.Errorf("%s: negative direction does not verify (x†Ax = %v)", name, neg.Value(A))
+		}
+		decoded, ok := linalg.NegativeDirectionFromValue(neg.ToValue())
+		if !ok || !decoded.Verify(A) {
+			t.Errorf("%s: negative direction did not survive a Value round trip", name)
+		}
+		if neg.Verify(psd) {
+			t.Errorf("%s: negative direction verified against a PSD matrix", name)
+		}
+	}
+
+	// Non-Hermitian input is rejected.
+	skew := densityFromRats(2, r(0, 1), r(1, 1), r(0, 1), r(0, 1))
+	if _, _, err := linalg.CertifyPSD(skew); err == nil {
+		t.Error("CertifyPSD accepted a non-Hermitian matrix")
+	}
+}
+
+// TestChannelCertification verifies exact CPTP decisions on Choi matrices
+// and the positivity evidence attached to analysis bundles.
+func TestChannelCertification(t *testing.T) {
+	r := big.NewRat
+	rotation := densityFromRats(2, r(3, 5), r(-4, 5), r(4, 5), r(3, 5))
+	damping := []*runtime.Matrix{
+		densityFromRats(2, r(1, 1), r(0, 1), r(0, 1), r(4, 5)),
+		densityFromRats(2, r(0, 1), r(3, 5), r(0, 1), r(0, 1)),
+	}
+	half := densityFromRats(2, r(1, 2), r(0, 1), r(0, 1), r(1, 2))
+
+	// The transpose map is positive and trace preserving but not CP.
+	transpose := runtime.NewMatrix(4, 4)
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			transpose.Set(i*2+j, j*2+i, runtime.NewQI(r(1, 2), new(big.Rat)))
+		}
+	}
+
+	rotationChoi, err := analysis.ChoiFromKraus([]*runtime.Matrix{rotation})
+	if err != nil {
+		t.Fatalf("ChoiFromKraus(rotation): %v", err)
+	}
+	dampingChoi, err := analysis.ChoiFromKraus(damping)
+	if err != nil {
+		t.Fatalf("ChoiFromKraus(damping): %v", err)
+	}
+	halfChoi, err := analysis.ChoiFromKraus([]*runtime.Matrix{half})
+	if err != nil {
+		t.Fatalf("ChoiFromKraus(half): %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		choi            *runtime.Matrix
+		cp, tp, unitary bool
+	}{
+		{"identity", analysis.MaximallyEntangledState(2), true, true, true},
+		{"rotation", rotationChoi, true, true, true},
+		{"amplitude damping", dampingChoi, true, true, false},
+		{"scaled identity", halfChoi, true, false, false},
+		{"transpose", transpose, false, true, false},
+	}
+	for _, tt := range tests {
+		w, err := analysis.CertifyChannel(tt.choi, 2, 2)
+		if err != nil {
+			t.Fatalf("%s: CertifyChannel: %v", tt.name, err)
+		}
+		if w.IsCP() != tt.cp || w.TracePreserving != tt.tp || w.IsUnitary() != tt.unitary {
+			t.Errorf("%s: CP=%v TP=%v unitary=%v, want %v %v %v",
+				tt.name, w.IsCP(), w.TracePreserving, w.IsUnitary(), tt.cp, tt.tp, tt.unitary)
+		}
+		if !w.Verify() {
+			t.Errorf("%s: channel witness does not verify", tt.name)
+		}
+		decoded, ok := analysis.ChannelWitnessFromValue(w.ToValue())
+		if !ok || !decoded.Verify() || decoded.IsCPTP() != w.IsCPTP() {
+			t.Errorf("%s: channel witness did not survive a Value round trip", tt.name)
+		}
+		if analysis.IsChannelCPTP(tt.choi, 2, 2) != (tt.cp && tt.tp) {
+			t.Errorf("%s: IsChannelCPTP disagrees with the witness", tt.name)
+		}
+		if analysis.IsChannelUnitary(tt.choi) != tt.unitary {
+			t.Errorf("%s: IsChannelUnitary = %v, want %v", tt.name, !tt.unitary, tt.unitary)
+		}
+
+		ev := certificate.CreateFromChannelWitness("test", tt.name, w)
+		if ev.Verify() != (tt.cp && tt.tp) {
+			t.Errorf("%s: valid-channel evidence Verify = %v, want %v", tt.name, ev.Verify(), tt.cp && tt.tp)
+		}
+	}
+
+	// A witness claiming the wrong trace-preservation flag is rejected.
+	w, _ := analysis.CertifyChannel(halfChoi, 2, 2)
+	w.TracePreserving = true
+	if w.Verify() {
+		t.Error("witness with a forged trace-preservation flag verified")
+	}
+
+	// A valid-channel claim without a positivity witness does not verify.
+	claim := certificate.NewValidChannelClaim("test", "identity", 2, 2)
+	if claim.Verify(nil) {
+		t.Error("valid-channel claim verified without a witness")
+	}
+
+	fab, err := certificate.GenerateFullAnalysis("Teleportation", nil)
+	if err != nil {
+		t.Fatalf("GenerateFullAnalysis: %v", err)
+	}
+	if fab.ChannelEvidence == nil || !fab.ChannelEvidence.IsVerified() || !fab.ChannelEvidence.Verify() {
+		t.Fatal("bundle is missing verified valid-channel evidence")
+	}
+	decoded, ok := certificate.FullAnalysisBundleFromValue(fab.ToValue())
+	if !ok || decoded.ChannelEvidence == nil || !decoded.ChannelEvidence.Verify() {
+		t.Error("valid-channel evidence did not survive a bundle round trip")
+	}
+}
+
+// TestCertifyCircuitChannel certifies qubit channels computed from circuits,
+// so the Choi matrix and the dimensions come from ComputeChannel.
+func TestCertifyCircuitChannel(t *testing.T) {
+	r := big.NewRat
+	qubit := runtime.QuantumObject(2)
+	store := runtime.NewStore()
+
+	// Full depolarization ρ ↦ I/2 = (ρ + XρX + YρY + ZρZ)/4.
+	var depolarize []*runtime.Matrix
+	for _, P := range []*runtime.Matrix{runtime.Identity(2), qkd.PauliX(), qkd.PauliY(), qkd.PauliZ()} {
+		depolarize = append(depolarize, runtime.MatScale(P, r(1, 2)))
+	}
+	identity := store.Put(runtime.Circuit{Domain: qubit, Codomain: qubit, Prim: runtime.PrimUnitary,
+		Data: runtime.MatrixToValue(runtime.Identity(2))})
+	depolarizing := store.Put(runtime.Circuit{Domain: qubit, Codomain: qubit, Prim: runtime.PrimKraus,
+		Data: runtime.KrausToValue(depolarize)})
+
+	tests := []struct {
+		name    string
+		id      [32]byte
+		want    *runtime.Matrix
+		unitary bool
+	}{
+		{"identity", identity, analysis.MaximallyEntangledState(2), true},
+		{"depolarizing", depolarizing, runtime.MatScale(runtime.Identity(4), r(1, 4)), false},
+	}
+	for _, tt := range tests {
+		choi, err := analysis.ComputeChannel(tt.id, store)
+		if err != nil {
+			t.Fatalf("%s: ComputeChannel: %v", tt.name, err)
+		}
+		if !runtime.MatrixEqual(choi, tt.want) {
+			t.Errorf("%s: Choi matrix is %dx%d and differs from the expected channel", tt.name, choi.Rows, choi.Cols)
+		}
+		c, _ := store.Get(tt.id)
+		w, err := analysis.CertifyChannel(choi, runtime.BlockDim(c.Domain), runtime.BlockDim(c.Codomain))
+		if err != nil {
+			t.Fatalf("%s: CertifyChannel: %v", tt.name, err)
+		}
+		if !w.IsCPTP() || w.IsUnitary() != tt.unitary || !w.Verify() {
+			t.Errorf("%s: CPTP=%v unitary=%v verified=%v", tt.name, w.IsCPTP(), w.IsUnitary(), w.Verify())
+		}
+		if ev := certificate.CreateFromChannelWitness("test", tt.name, w); !ev.Verify() {
+			t.Errorf("%s: valid-channel evidence does not verify", tt.name)
+		}
+	}
+}
+
+// TestCertifiedDistances verifies trace-distance enclosures and diamond
+// distance certificates.
+func TestCertifiedDistances(t *testing.T) {
+	r := big.NewRat
+	toFloat := func(x *big.Rat) float64 { f, _ := x.Float64(); return f }
+
+	// Commuting states: exact trace distance.
+	mixed := densityFromRats(2, r(1, 2), r(0, 1), r(0, 1), r(1, 2))
+	zero := densityFromRats(2, r(1, 1), r(0, 1), r(0, 1), r(0, 1))
+	td, err := analysis.TraceDistance(mixed, zero)
+	if err != nil {
+		t.Fatalf("TraceDistance: %v", err)
+	}
+	if !td.IsExact() || td.Lower.Cmp(r(1, 2)) != 0 {
+		t.Errorf("TraceDistance(I/2, |0><0|) = %s, want 1/2", td)
+	}
+
+	// Irrational trace distance √5/4.
+	tilted := densityFromRats(2, r(1, 2), r(1, 4), r(1, 4), r(1, 2))
+	td, err = analysis.TraceDistance(tilted, zero)
+	if err != nil {
+		t.Fatalf("TraceDistance: %v", err)
+	}
+	want := math.Sqrt(5) / 4
+	if toFloat(td.Lower) > want || toFloat(td.Upper) < want || toFloat(td.Width()) > 1e-9 {
+		t.Errorf("TraceDistance enclosure %s does not tightly contain √5/4", td)
+	}
+
+	// Identity versus the Pauli channel with p_I = 121/196, p_X = p_Y = p_Z = 25/196.
+	pauli := []*runtime.Matrix{
+		densityFromRats(2, r(11, 14), r(0, 1), r(0, 1), r(11, 14)),
+		densityFromRats(2, r(0, 1), r(5, 14), r(5, 14), r(0, 1)),
+		densityFromRats(2, r(5, 14), r(0, 1), r(0, 1), r(-5, 14)),
+	}
+	y := runtime.NewMatrix(2, 2)
+	y.Set(0, 1, runtime.NewQI(new(big.Rat), r(-5, 14)))
+	y.Set(1, 0, runtime.NewQI(new(big.Rat), r(5, 14)))
+	pauli = append(pauli, y)
+	pauliChoi, err := analysis.ChoiFromKraus(pauli)
+	if err != nil {
+		t.Fatalf("ChoiFromKraus: %v", err)
+	}
+	if !analysis.IsChannelCPTP(pauliChoi, 2, 2) {
+		t.Fatal("Pauli channel is not CPTP")
+	}
+	identity := analysis.MaximallyEntangledState(2)
+
+	cert, err := analysis.DiamondDistance(identity, pauliChoi, 2, 2)
+	if err != nil {
+		t.Fatalf("DiamondDistance: %v", err)
+	}
+	if !cert.Verify() {
+		t.Fatal("diamond certificate does not verify")
+	}
+	// The Pauli channel is covariant, so the distance is exactly 1 - p_I.
+	exact := r(75, 196)
+	b := cert.Bound()
+	if b.Lower.Cmp(exact) > 0 || b.Upper.Cmp(exact) < 0 || b.Width().Cmp(r(1, 1<<20)) > 0 {
+		t.Errorf("diamond distance %s does not tightly contain 75/196", b)
+	}
+	decoded, ok := analysis.DiamondCertificateFromValue(cert.ToValue())
+	if !ok || !decoded.Verify() {
+		t.Error("diamond certificate did not survive a Value round trip")
+	}
+
+	decoded.Upper = new(big.Rat).Sub(exact, r(1, 100))
+	if decoded.Verify() {
+		t.Error("diamond certificate with a lowered upper bound verified")
+	}
+	decoded, _ = analysis.DiamondCertificateFromValue(cert.ToValue())
+	decoded.Lower = new(big.Rat).Add(decoded.Lower, r(1, 100))
+	if decoded.Verify() {
+		t.Error("diamond certificate with a raised lower bound verified")
+	}
+
+	// Identical channels are at distance exactly zero.
+	same, err := analysis.DiamondDistance(identity, identity, 2, 2)
+	if err != nil || !same.Verify() || !same.Bound().IsExact() || same.Upper.Sign() != 0 {
+		t.Errorf("diamond distance of a channel to itself = %v, %v", same, err)
+	}
+
+	// Amplitude damping (γ = 9/25) is not covariant, so the enclosure comes
+	// from the SDP solver: tight, and dominating the Choi-state distance.
+	damping, _ := analysis.ChoiFromKraus([]*runtime.Matrix{
+		densityFromRats(2, r(1, 1), r(0, 1), r(0, 1), r(4, 5)),
+		densityFromRats(2, r(0, 1), r(3, 5), r(0, 1), r(0, 1)),
+	})
+	cert, err = analysis.DiamondDistance(identity, damping, 2, 2)
+	if err != nil || !cert.Verify() {
+		t.Fatalf("amplitude damping diamond certificate: %v", err)
+	}
+	td, _ = analysis.TraceDistance(identity, damping)
+	if cert.Upper.Cmp(td.Lower) < 0 || cert.Lower.Cmp(cert.Upper) > 0 || cert.Upper.Cmp(r(1, 1)) > 0 {
+		t.Errorf("amplitude damping: diamond %s inconsistent with Choi trace distance %s", cert.Bound(), td)
+	}
+	if cert.Bound().Width().Cmp(r(1, 1<<20)) > 0 || cert.Lower.Cmp(r(9, 25)) > 0 || cert.Upper.Cmp(r(9, 25)) < 0 {
+		t.Errorf("amplitude damping diamond enclosure %s is not a tight enclosure of 9/25", cert.Bound())
+	}
+
+	// Correctness evidence carrying a diamond certificate checks epsilon.
+	ev := certificate.CreateFromCorrectnessResult(true, r(1, 1), identity, damping)
+	certificate.AttachDiamondCertificate(ev, cert)
+	if !ev.Verify() {
+		t.Error("correctness evidence with a diamond certificate does not verify")
+	}
+	ev.Claim.Parameters["epsilon"] = new(big.Rat).Quo(cert.Upper, r(2, 1))
+	if ev.Verify() {
+		t.Error("correctness evidence verified with epsilon below the certified bound")
+	}
+}
+
+// TestSDPSolver checks rational bounds from the interior-point solver.
+func TestSDPSolver(t *testing.T) {
+	r := big.NewRat
+	// max ⟨C, X⟩ s.t. Tr X = 1, X ⪰ 0 is the largest eigenvalue of C, 2.
+	C := runtime.NewMatrix(2, 2)
+	C.Set(0, 0, runtime.NewQI(r(1, 1), r(0, 1)))
+	C.Set(0, 1, runtime.NewQI(r(1, 1), r(1, 1)))
+	C.Set(1, 0, runtime.NewQI(r(1, 1), r(-1, 1)))
+	p := &sdp.Problem{
+		Blocks:   []int{2},
+		C:        []*runtime.Matrix{C},
+		A:        [][]*runtime.Matrix{{runtime.Identity(2)}},
+		B:        []*big.Rat{r(1, 1)},
+		Identity: []*big.Rat{r(1, 1)},
+	}
+	cert, err := sdp.Solve(p, nil)
+	if err != nil {
+		t.Fatalf("Solve: %v", err)
+	}
+	if !cert.HasLower() || !cert.HasUpper() || !cert.Verify() {
+		t.Fatal("SDP certificate does not prove both bounds")
+	}
+	gap := new(big.Rat).Sub(cert.Upper, cert.Lower)
+	if cert.Lower.Cmp(r(2, 1)) > 0 || cert.Upper.Cmp(r(2, 1)) < 0 || gap.Cmp(r(1, 1<<30)) > 0 {
+		t.Errorf("SDP bounds [%s, %s] are not a tight enclosure of 2", cert.Lower.RatString(), cert.Upper.RatString())
+	}
+
+	restored, ok := sdp.CertificateFromValue(cert.ToValue())
+	if !ok || !restored.Verify() || restored.Upper.Cmp(cert.Upper) != 0 {
+		t.Error("SDP certificate does not survive a Value round trip")
+	}
+
+	// Tampering with either side breaks verification.
+	restored.Lower = new(big.Rat).Add(restored.Lower, r(1, 1000))
+	if restored.Verify() {
+		t.Error("SDP certificate verified with a raised lower bound")
+	}
+	restored, _ = sdp.CertificateFromValue(cert.ToValue())
+	restored.Upper = new(big.Rat).Sub(restored.Upper, r(1, 1000))
+	if restored.Verify() {
+		t.Error("SDP certificate verified with a lowered upper bound")
+	}
+
+	// A malformed problem is rejected before solving.
+	p.B = nil
+	if _, err := sdp.Solve(p, nil); err == nil {
+		t.Error("Solve accepted a problem without right-hand sides")
+	}
+}
+
 // TestAttackLibrary verifies that the attack library contains expected attacks.
 func TestAttackLibrary(t *testing.T) {
 	// Test intercept-resend attack
@@ -712,6 +1578,99 @@ func TestBuildAllModels(t *testing.T) {
 	}
 }
 
+// TestSignedBundle checks that a signed bundle loads under a trust store
+// holding its key, and that editing IsSecure or trusting another key makes
+// loading fail.
+func TestSignedBundle(t *testing.T) {
+	dir := t.TempDir()
+	alice, _ := runtime.GenerateSigningKey("alice")
+	eve, _ := runtime.GenerateSigningKey("eve")
+	trust := runtime.NewTrustStore()
+	trust.Add("alice", alice.Public())
+
+	bundle := &certificate.FullAnalysisBundle{Protocol: "BB84", Bundle: certificate.NewBundle("BB84")}
+	path := filepath.Join(dir, "bb84.qmb")
+	if err := EmitSignedBundle(bundle, path, alice); err != nil {
+		t.Fatalf("EmitSignedBundle failed: %v", err)
+	}
+	loaded, sig, err := LoadBundleTrusted(path, trust)
+	if err != nil || loaded.Protocol != "BB84" || sig.Signer != "alice" {
+		t.Fatalf("LoadBundleTrusted = %v, %+v, %v", loaded, sig, err)
+	}
+
+	// Flip IsSecure and carry the signature over to the edited binary.
+	data, _ := os.ReadFile(path)
+	signed, _ := runtime.Decode(data)
+	bundle.IsSecure = true
+	edited := filepath.Join(dir, "edited.qmb")
+	if err := EmitBundle(bundle, edited, FormatQMB); err != nil {
+		t.Fatalf("EmitBundle failed: %v", err)
+	}
+	data, _ = os.ReadFile(edited)
+	forged, _ := runtime.Decode(data)
+	forged.Signatures = signed.Signatures
+	os.WriteFile(edited, forged.Encode(), 0644)
+	if _, _, err := LoadBundleTrusted(edited, trust); !errors.Is(err, runtime.ErrUntrusted) {
+		t.Errorf("edited bundle: got %v, want ErrUntrusted", err)
+	}
+	if b, _, err := LoadBundleTrusted(edited, nil); err != nil || !b.IsSecure {
+		t.Errorf("LoadBundleTrusted without a trust store: %v", err)
+	}
+
+	// A detached signature next to an unsigned file is checked too.
+	unsigned := filepath.Join(dir, "unsigned.qmb")
+	EmitBundle(bundle, unsigned, FormatQMB)
+	if _, _, err := LoadBundleTrusted(unsigned, trust); !errors.Is(err, runtime.ErrUnsigned) {
+		t.Errorf("unsigned bundle: got %v, want ErrUnsigned", err)
+	}
+	data, _ = os.ReadFile(unsigned)
+	binary, _ := runtime.Decode(data)
+	os.WriteFile(unsigned+".sig", runtime.EncodeSignatures([]runtime.Signature{binary.Sign(eve, time.Now())}), 0644)
+	if _, _, err := LoadBundleTrusted(unsigned, trust); !errors.Is(err, runtime.ErrUntrusted) {
+		t.Errorf("bundle signed by an untrusted key: got %v", err)
+	}
+	os.WriteFile(unsigned+".sig", runtime.EncodeSignatures([]runtime.Signature{binary.Sign(alice, time.Now())}), 0644)
+	if _, _, err := LoadBundleTrusted(unsigned, trust); err != nil {
+		t.Errorf("detached signature: %v", err)
+	}
+}
+
+// TestLoadBundleDefaultTrust checks that LoadBundle uses the trust store
+// named by QBTM_TRUST and refuses to load when none is configured.
+func TestLoadBundleDefaultTrust(t *testing.T) {
+	dir := t.TempDir()
+	alice, _ := runtime.GenerateSigningKey("alice")
+	bundle := &certificate.FullAnalysisBundle{Protocol: "BB84", Bundle: certificate.NewBundle("BB84")}
+	signed := filepath.Join(dir, "signed.qmb")
+	unsigned := filepath.Join(dir, "unsigned.qmb")
+	if err := EmitSignedBundle(bundle, signed, alice); err != nil {
+		t.Fatalf("EmitSignedBundle failed: %v", err)
+	}
+	if err := EmitBundle(bundle, unsigned, FormatQMB); err != nil {
+		t.Fatalf("EmitBundle failed: %v", err)
+	}
+
+	// No QBTM_TRUST and an empty configuration directory.
+	t.Setenv(TrustEnv, "")
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if _, err := LoadBundle(signed); !errors.Is(err, ErrNoTrustStore) {
+		t.Errorf("no trust store: got %v, want ErrNoTrustStore", err)
+	}
+
+	keys := filepath.Join(dir, "trusted.keys")
+	if err := os.WriteFile(keys, alice.MarshalPublic(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(TrustEnv, keys)
+	if b, err := LoadBundle(signed); err != nil || b.Protocol != "BB84" {
+		t.Errorf("LoadBundle with QBTM_TRUST = %v, %v", b, err)
+	}
+	if _, err := LoadBundle(unsigned); !errors.Is(err, runtime.ErrUnsigned) {
+		t.Errorf("unsigned bundle: got %v, want ErrUnsigned", err)
+	}
+}
+
 // min returns the minimum of two integers.
 func min(a, b int) int {
 	if a < b {
@@ -719,3 +1678,33 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+// benchmarkPreparation executes a multiparty preparation circuit on
+// |0...0><0...0|.
+func benchmarkPreparation(b *testing.B, n int, synth func(*runtime.Store) ([32]byte, error)) {
+	store := runtime.NewStore()
+	id, err := synth(store)
+	if err != nil {
+		b.Fatalf("synthesis failed: %v", err)
+	}
+	c, _ := store.Get(id)
+	dim := 1 << n
+	input := runtime.NewMatrix(dim, dim)
+	input.Set(0, 0, runtime.QIOne())
+	exec := runtime.NewExecutor(store)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := exec.Execute(c, input); err != nil {
+			b.Fatalf("execution failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkGHZ6(b *testing.B) {
+	benchmarkPreparation(b, 6, multiparty.NewGHZ(6).Synthesize)
+}
+
+func BenchmarkW6(b *testing.B) {
+	benchmarkPreparation(b, 6, multiparty.NewWState(6).Synthesize)
+}
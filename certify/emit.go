@@ -1,16 +1,24 @@
 // emit.go provides .qmb file emission for certified protocols.
 //
 // This file implements serialization of certified protocol models
-// to the .qmb binary format with embedded certificates.
+// to the .qmb binary format with embedded certificates. FormatQMB writes a
+// v2 binary whose entrypoint is the emitted value, optionally signed (see
+// runtime/sign.go); FormatValue writes the bare value encoding. The
+// Load*Trusted functions refuse binaries without a signature from a
+// trusted key; LoadModel and LoadBundle use the DefaultTrustStore.
 package certify
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"math/big"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"qbtm/certify/certificate"
 	"qbtm/runtime"
@@ -47,6 +55,7 @@ type Emitter struct {
 	model      *Model
 	outputPath string
 	format     OutputFormat
+	signingKey *runtime.SigningKey
 }
 
 // NewEmitter creates a new .qmb emitter.
@@ -72,6 +81,11 @@ func (e *Emitter) SetFormat(format OutputFormat) {
 	e.format = format
 }
 
+// SetSigningKey makes FormatQMB output carry a signature by key.
+func (e *Emitter) SetSigningKey(key *runtime.SigningKey) {
+	e.signingKey = key
+}
+
 // Emit writes the certified model to a .qmb file.
 func (e *Emitter) Emit() error {
 	if e.model == nil {
@@ -109,7 +123,7 @@ func (e *Emitter) emitValue(value runtime.Value) error {
 	case FormatQMB:
 		return e.emitQMB(value)
 	case FormatValue:
-		return e.emitQMB(value) // Same as QMB
+		return writeOutput(e.outputPath, value.Encode())
 	default:
 		return e.emitText(value)
 	}
@@ -161,14 +175,31 @@ func (e *Emitter) emitJSON(value runtime.Value) error {
 
 // emitQMB writes the binary .qmb format.
 func (e *Emitter) emitQMB(value runtime.Value) error {
-	encoded := value.Encode()
+	name := "certify-model"
+	if e.model.Protocol != nil {
+		name = e.model.Protocol.Name
+	}
+	return writeOutput(e.outputPath, embedValue(value, name, e.signingKey))
+}
 
-	if e.outputPath == "" || e.outputPath == "-" {
-		_, err := os.Stdout.Write(encoded)
-		return err
+// embedValue encodes a v2 binary with value as its entrypoint, signed by
+// key unless it is nil.
+func embedValue(value runtime.Value, name string, key *runtime.SigningKey) []byte {
+	store := runtime.NewStore()
+	binary := runtime.EmbedV2(store, store.PutValue(value), name, "1.0.0")
+	if key != nil {
+		binary.AddSignature(binary.Sign(key, time.Now()))
 	}
+	return binary.Encode()
+}
 
-	return os.WriteFile(e.outputPath, encoded, 0644)
+// writeOutput writes data to path, or to stdout if path is "" or "-".
+func writeOutput(path string, data []byte) error {
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
 }
 
 // formatValueAsText converts a value to human-readable text.
@@ -279,6 +310,7 @@ func EmitModel(protocolName string, outputPath string, options *EmitOptions) err
 
 	// Create emitter
 	emitter := NewEmitterWithFormat(model, outputPath, options.Format)
+	emitter.SetSigningKey(options.SigningKey)
 
 	// Emit with or without certificate
 	if options.IncludeCertificate && model.Certificate != nil {
@@ -302,6 +334,7 @@ type EmitOptions struct {
 	ErrorRate          *big.Rat
 	AdversaryModel     string
 	NoiseModels        []string
+	SigningKey         *runtime.SigningKey // signs FormatQMB output when set
 }
 
 // DefaultEmitOptions returns default emission options.
@@ -342,6 +375,16 @@ func FullEmitOptions() *EmitOptions {
 
 // EmitBundle emits a full analysis bundle.
 func EmitBundle(bundle *certificate.FullAnalysisBundle, outputPath string, format OutputFormat) error {
+	return emitBundle(bundle, outputPath, format, nil)
+}
+
+// EmitSignedBundle emits a full analysis bundle as a .qmb binary signed
+// by key.
+func EmitSignedBundle(bundle *certificate.FullAnalysisBundle, outputPath string, key *runtime.SigningKey) error {
+	return emitBundle(bundle, outputPath, FormatQMB, key)
+}
+
+func emitBundle(bundle *certificate.FullAnalysisBundle, outputPath string, format OutputFormat, key *runtime.SigningKey) error {
 	if bundle == nil {
 		return fmt.Errorf("nil bundle")
 	}
@@ -369,13 +412,11 @@ func EmitBundle(bundle *certificate.FullAnalysisBundle, outputPath string, forma
 		}
 		return os.WriteFile(outputPath, data, 0644)
 
-	case FormatQMB, FormatValue:
-		encoded := value.Encode()
-		if outputPath == "" || outputPath == "-" {
-			_, err := os.Stdout.Write(encoded)
-			return err
-		}
-		return os.WriteFile(outputPath, encoded, 0644)
+	case FormatQMB:
+		return writeOutput(outputPath, embedValue(value, bundle.Protocol, key))
+
+	case FormatValue:
+		return writeOutput(outputPath, value.Encode())
 
 	default:
 		return fmt.Errorf("unsupported format")
@@ -418,85 +459,166 @@ func EmitCommandResult(result *CommandResult, outputPath string, format OutputFo
 		if result.Value == nil {
 			result.Value = runtime.MakeNil()
 		}
-		encoded := result.Value.Encode()
-		if outputPath == "" || outputPath == "-" {
-			_, err := os.Stdout.Write(encoded)
-			return err
+		if format == FormatQMB {
+			return writeOutput(outputPath, embedValue(result.Value, "certify-result", nil))
 		}
-		return os.WriteFile(outputPath, encoded, 0644)
+		return writeOutput(outputPath, result.Value.Encode())
 
 	default:
 		return fmt.Errorf("unsupported format")
 	}
 }
 
-// LoadModel loads a model from a .qmb file.
-// Note: This requires the file to be an embedded binary format.
-// For raw value files, use LoadModelFromJSON.
-func LoadModel(inputPath string) (*Model, error) {
-	data, err := os.ReadFile(inputPath)
+// TrustEnv names the environment variable holding the trust store file
+// used by LoadModel and LoadBundle.
+const TrustEnv = "QBTM_TRUST"
+
+// ErrNoTrustStore is returned by LoadModel and LoadBundle when no trust
+// store is configured, so the file's signatures cannot be checked.
+var ErrNoTrustStore = errors.New("certify: no trust store configured (set " + TrustEnv + "); signature not verified")
+
+// DefaultTrustStore loads the trust store named by $QBTM_TRUST or, if that
+// is unset, qbtm/trusted.keys in the user configuration directory. It
+// returns nil and no error if neither is configured.
+func DefaultTrustStore() (*runtime.TrustStore, error) {
+	if path := os.Getenv(TrustEnv); path != "" {
+		return runtime.LoadTrustStore(path)
+	}
+	dir, err := os.UserConfigDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, nil
 	}
+	trust, err := runtime.LoadTrustStore(filepath.Join(dir, "qbtm", "trusted.keys"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return trust, err
+}
 
-	// Try to decode as embedded binary
-	binary, err := runtime.Decode(data)
+// defaultTrust returns DefaultTrustStore, or ErrNoTrustStore if there is
+// none.
+func defaultTrust() (*runtime.TrustStore, error) {
+	trust, err := DefaultTrustStore()
+	if err == nil && trust == nil {
+		err = ErrNoTrustStore
+	}
+	return trust, err
+}
+
+// LoadModel loads a model from a .qmb file, requiring a signature from a
+// key in the DefaultTrustStore. It returns ErrNoTrustStore if none is
+// configured; LoadModelTrusted(inputPath, nil) loads without checking.
+// Note: This requires the file to be an embedded binary format.
+// For raw value files, use LoadModelFromJSON.
+func LoadModel(inputPath string) (*Model, error) {
+	trust, err := defaultTrust()
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode binary: %w", err)
+		return nil, err
 	}
+	model, _, err := LoadModelTrusted(inputPath, trust)
+	return model, err
+}
 
-	// Create a runner to get access to the store
-	runner, err := runtime.NewRunner(data)
+// LoadModelTrusted loads a model from a .qmb file and, unless trust is
+// nil, requires a signature from a key in trust. It returns that
+// signature.
+func LoadModelTrusted(inputPath string, trust *runtime.TrustStore) (*Model, runtime.Signature, error) {
+	value, sig, err := loadEntrypoint(inputPath, trust)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create runner: %w", err)
+		return nil, sig, err
 	}
 
-	// Get the entrypoint value
-	value, ok := runner.GetValue(binary.Entrypoint)
-	if !ok {
-		return nil, fmt.Errorf("entrypoint value not found")
+	// certify emit wraps the model with its certificate
+	if tag, ok := value.(runtime.Tag); ok {
+		label, ok := tag.Label.(runtime.Text)
+		seq, ok2 := tag.Payload.(runtime.Seq)
+		if ok && label.V == "certified-protocol" && ok2 && len(seq.Items) == 2 {
+			value = seq.Items[0]
+		}
 	}
 
 	model, ok := ModelFromValue(value)
 	if !ok {
-		return nil, fmt.Errorf("failed to parse model from value")
+		return nil, sig, fmt.Errorf("failed to parse model from value")
 	}
 
-	return model, nil
+	return model, sig, nil
 }
 
-// LoadBundle loads a certificate bundle from a .qmb file.
+// LoadBundle loads a certificate bundle from a .qmb file, requiring a
+// signature from a key in the DefaultTrustStore. It returns
+// ErrNoTrustStore if none is configured; LoadBundleTrusted(inputPath, nil)
+// loads without checking.
 // Note: This requires the file to be an embedded binary format.
 func LoadBundle(inputPath string) (*certificate.FullAnalysisBundle, error) {
+	trust, err := defaultTrust()
+	if err != nil {
+		return nil, err
+	}
+	bundle, _, err := LoadBundleTrusted(inputPath, trust)
+	return bundle, err
+}
+
+// LoadBundleTrusted loads a certificate bundle from a .qmb file and,
+// unless trust is nil, requires a signature from a key in trust. It
+// returns that signature.
+func LoadBundleTrusted(inputPath string, trust *runtime.TrustStore) (*certificate.FullAnalysisBundle, runtime.Signature, error) {
+	value, sig, err := loadEntrypoint(inputPath, trust)
+	if err != nil {
+		return nil, sig, err
+	}
+
+	bundle, ok := certificate.FullAnalysisBundleFromValue(value)
+	if !ok {
+		return nil, sig, fmt.Errorf("failed to parse bundle from value")
+	}
+
+	return bundle, sig, nil
+}
+
+// loadEntrypoint reads a .qmb file, checks its embedded signatures and
+// those in inputPath+".sig" against trust unless trust is nil, and returns
+// the entrypoint value.
+func loadEntrypoint(inputPath string, trust *runtime.TrustStore) (runtime.Value, runtime.Signature, error) {
+	var sig runtime.Signature
 	data, err := os.ReadFile(inputPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, sig, fmt.Errorf("failed to read file: %w", err)
 	}
 
 	// Try to decode as embedded binary
 	binary, err := runtime.Decode(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode binary: %w", err)
+		return nil, sig, fmt.Errorf("failed to decode binary: %w", err)
+	}
+
+	if trust != nil {
+		var detached []runtime.Signature
+		if sigData, err := os.ReadFile(inputPath + ".sig"); err == nil {
+			if detached, err = runtime.DecodeSignatures(sigData); err != nil {
+				return nil, sig, fmt.Errorf("%s.sig: %w", inputPath, err)
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, sig, fmt.Errorf("failed to read signature: %w", err)
+		}
+		if sig, err = trust.Verify(binary, detached...); err != nil {
+			return nil, sig, fmt.Errorf("%s: %w", inputPath, err)
+		}
 	}
 
 	// Create a runner to get access to the store
 	runner, err := runtime.NewRunner(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create runner: %w", err)
+		return nil, sig, fmt.Errorf("failed to create runner: %w", err)
 	}
 
 	// Get the entrypoint value
 	value, ok := runner.GetValue(binary.Entrypoint)
 	if !ok {
-		return nil, fmt.Errorf("entrypoint value not found")
-	}
-
-	bundle, ok := certificate.FullAnalysisBundleFromValue(value)
-	if !ok {
-		return nil, fmt.Errorf("failed to parse bundle from value")
+		return nil, sig, fmt.Errorf("entrypoint value not found")
 	}
 
-	return bundle, nil
+	return value, sig, nil
 }
 
 // VerifyLoadedBundle verifies a loaded bundle.
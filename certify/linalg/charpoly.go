@@ -0,0 +1,109 @@
+// charpoly.go computes characteristic polynomials over Q(i).
+//
+// Berkowitz's algorithm builds det(xI - A) from the characteristic
+// polynomials of the leading principal submatrices using only ring
+// operations, so it never divides and never needs a pivot. For an n×n
+// matrix it costs O(n^4) exact multiplications.
+package linalg
+
+import (
+	"fmt"
+	"math/big"
+
+	"qbtm/runtime"
+)
+
+// CharPoly returns the coefficients of det(xI - A) over Q(i), lowest
+// degree first. The result has n+1 entries and is monic.
+func CharPoly(A *runtime.Matrix) ([]runtime.QI, error) {
+	if A == nil || A.Rows != A.Cols {
+		return nil, fmt.Errorf("linalg: characteristic polynomial needs a square matrix")
+	}
+	n := A.Rows
+
+	// vect holds det(xI - A_k) for the leading k×k block, highest degree
+	// first. Splitting A_{k+1} = [[A_k, c], [r, a]], the next polynomial
+	// is T·vect where T is the lower-triangular Toeplitz matrix with first
+	// column 1, -a, -r·c, -r·A_k·c, ..., -r·A_k^{k-1}·c.
+	vect := []runtime.QI{runtime.QIOne()}
+	for k := 0; k < n; k++ {
+		col := make([]runtime.QI, k+2)
+		col[0] = runtime.QIOne()
+		col[1] = runtime.QINeg(A.Get(k, k))
+
+		// v = A_k^j · c for j = 0, 1, ..., k-1.
+		v := make([]runtime.QI, k)
+		for i := 0; i < k; i++ {
+			v[i] = A.Get(i, k)
+		}
+		for j := 0; j < k; j++ {
+			dot := runtime.QIZero()
+			for i := 0; i < k; i++ {
+				dot = runtime.QIAdd(dot, runtime.QIMul(A.Get(k, i), v[i]))
+			}
+			col[j+2] = runtime.QINeg(dot)
+			if j+1 < k {
+				next := make([]runtime.QI, k)
+				for i := 0; i < k; i++ {
+					acc := runtime.QIZero()
+					for l := 0; l < k; l++ {
+						acc = runtime.QIAdd(acc, runtime.QIMul(A.Get(i, l), v[l]))
+					}
+					next[i] = acc
+				}
+				v = next
+			}
+		}
+
+		out := make([]runtime.QI, k+2)
+		for i := range out {
+			acc := runtime.QIZero()
+			for j := 0; j <= i && j < len(vect); j++ {
+				acc = runtime.QIAdd(acc, runtime.QIMul(col[i-j], vect[j]))
+			}
+			out[i] = acc
+		}
+		vect = out
+	}
+
+	coeffs := make([]runtime.QI, n+1)
+	for i := range coeffs {
+		coeffs[i] = vect[n-i]
+	}
+	return coeffs, nil
+}
+
+// IsHermitian reports whether A is square and equal to its adjoint.
+func IsHermitian(A *runtime.Matrix) bool {
+	if A == nil || A.Rows != A.Cols {
+		return false
+	}
+	for i := 0; i < A.Rows; i++ {
+		for j := i; j < A.Cols; j++ {
+			if !runtime.QIEqual(A.Get(i, j), runtime.QIConj(A.Get(j, i))) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// HermitianCharPoly returns the characteristic polynomial of a Hermitian
+// matrix, whose coefficients are real and hence rational.
+func HermitianCharPoly(A *runtime.Matrix) (Poly, error) {
+	if !IsHermitian(A) {
+		return nil, fmt.Errorf("linalg: matrix is not Hermitian")
+	}
+	coeffs, err := CharPoly(A)
+	if err != nil {
+		return nil, err
+	}
+	p := make(Poly, len(coeffs))
+	for i, c := range coeffs {
+		if c.Im.Sign() != 0 {
+			return nil, fmt.Errorf("linalg: Hermitian characteristic polynomial has a complex coefficient")
+		}
+		p[i] = new(big.Rat).Set(c.Re)
+	}
+	return p.normalize(), nil
+}
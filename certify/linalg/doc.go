@@ -0,0 +1,18 @@
+// Package linalg provides exact linear algebra on runtime.Matrix.
+//
+// Results are computed over Q(i) with no floating point:
+//
+//   - Characteristic polynomials by Berkowitz's division-free algorithm
+//   - Rational polynomials with Sturm sequences and square-free factorization
+//   - Certified eigenvalues of Hermitian matrices: isolating intervals
+//     refinable to any rational width, with exact values for rational
+//     eigenvalues and exact multiplicities
+//   - Positive semidefiniteness decided by LDL† elimination, with a
+//     factorization or a negative direction as a checkable witness
+//
+// float.go adds float64 numerics that only propose rational candidates;
+// whatever they suggest is certified exactly before it is used.
+//
+// The analysis package builds entropy bounds, channel certificates and
+// distances on these primitives.
+package linalg
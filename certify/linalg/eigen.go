@@ -0,0 +1,229 @@
+// eigen.go isolates the eigenvalues of Hermitian matrices.
+//
+// A Hermitian matrix has only real eigenvalues, so its characteristic
+// polynomial splits over R. HermitianEigenvalues factors it into
+// square-free parts by multiplicity, counts each part's roots with a
+// Sturm sequence on (-B, B] where B is Cauchy's root bound, and bisects
+// until every interval holds exactly one root. An interval is then
+// refined by the sign of its square-free part, which changes exactly once
+// inside it.
+//
+// Rational eigenvalues are found exactly. If f is a square-free part with
+// integer coefficients and leading coefficient L, a rational root p/q has
+// q | L, and two distinct fractions with denominators at most L differ by
+// at least 1/L². Once an isolating interval is narrower than that, the
+// simplest fraction in it is the only candidate, and one evaluation
+// decides whether the eigenvalue is rational.
+package linalg
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"qbtm/runtime"
+)
+
+// Eigenvalue is one distinct eigenvalue of a Hermitian matrix. If Exact is
+// set the eigenvalue is that rational and Lower = Upper = Exact. Otherwise
+// it is irrational and lies strictly between Lower and Upper.
+type Eigenvalue struct {
+	Lower        *big.Rat
+	Upper        *big.Rat
+	Exact        *big.Rat
+	Multiplicity int
+
+	poly    Poly // square-free part with this eigenvalue as its only root in (Lower, Upper]
+	signHi  int  // sign of poly at Upper
+	checked bool // whether the rational candidate has been ruled out
+}
+
+// Width returns Upper - Lower.
+func (ev *Eigenvalue) Width() *big.Rat {
+	return new(big.Rat).Sub(ev.Upper, ev.Lower)
+}
+
+// String renders the eigenvalue and its multiplicity, e.g. "3/4 x2" or
+// "(1/2, 5/8) x1".
+func (ev *Eigenvalue) String() string {
+	if ev.Exact != nil {
+		return fmt.Sprintf("%s x%d", ev.Exact.RatString(), ev.Multiplicity)
+	}
+	return fmt.Sprintf("(%s, %s) x%d", ev.Lower.RatString(), ev.Upper.RatString(), ev.Multiplicity)
+}
+
+// Refine bisects the isolating interval until its width is at most width.
+// Exact eigenvalues are unchanged.
+func (ev *Eigenvalue) Refine(width *big.Rat) {
+	for ev.Exact == nil && ev.Width().Cmp(width) > 0 {
+		ev.bisect()
+	}
+}
+
+// bisect halves the isolating interval, or makes the eigenvalue exact if
+// the midpoint is a root.
+func (ev *Eigenvalue) bisect() {
+	mid := new(big.Rat).Add(ev.Lower, ev.Upper)
+	mid.Quo(mid, big.NewRat(2, 1))
+	s := ev.poly.Eval(mid).Sign()
+	switch {
+	case s == 0:
+		ev.setExact(mid)
+	case s == ev.signHi:
+		ev.Upper = mid
+	default:
+		ev.Lower = mid
+	}
+}
+
+func (ev *Eigenvalue) setExact(x *big.Rat) {
+	ev.Exact = new(big.Rat).Set(x)
+	ev.Lower = new(big.Rat).Set(x)
+	ev.Upper = new(big.Rat).Set(x)
+}
+
+// resolveRational decides whether the eigenvalue is rational, making it
+// exact if so.
+func (ev *Eigenvalue) resolveRational() {
+	if ev.Exact != nil || ev.checked {
+		return
+	}
+	ev.checked = true
+	L := integerLead(ev.poly)
+	gap := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Mul(L, L))
+	for ev.Exact == nil && ev.Width().Cmp(gap) >= 0 {
+		ev.bisect()
+	}
+	if ev.Exact != nil {
+		return
+	}
+	s := simplestBetween(ev.Lower, ev.Upper)
+	if s.Cmp(ev.Lower) > 0 && ev.poly.Eval(s).Sign() == 0 {
+		ev.setExact(s)
+	}
+}
+
+// HermitianEigenvalues returns the distinct eigenvalues of a Hermitian
+// matrix in increasing order, with multiplicities summing to its size.
+// Rational eigenvalues are exact; the others are isolated in intervals of
+// width at most width.
+func HermitianEigenvalues(A *runtime.Matrix, width *big.Rat) ([]*Eigenvalue, error) {
+	if width == nil || width.Sign() <= 0 {
+		return nil, fmt.Errorf("linalg: eigenvalue width must be positive")
+	}
+	p, err := HermitianCharPoly(A)
+	if err != nil {
+		return nil, err
+	}
+
+	var eigs []*Eigenvalue
+	for k, f := range SquareFree(p) {
+		if f.Degree() <= 0 {
+			continue
+		}
+		roots, err := isolateRoots(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(roots) != f.Degree() {
+			return nil, fmt.Errorf("linalg: found %d real roots of a degree %d factor; matrix is not Hermitian",
+				len(roots), f.Degree())
+		}
+		for _, ev := range roots {
+			ev.Multiplicity = k + 1
+			ev.resolveRational()
+			ev.Refine(width)
+			eigs = append(eigs, ev)
+		}
+	}
+	sort.Slice(eigs, func(i, j int) bool { return eigs[i].Lower.Cmp(eigs[j].Lower) < 0 })
+	return eigs, nil
+}
+
+// isolateRoots returns one isolating interval per real root of the
+// square-free polynomial f.
+func isolateRoots(f Poly) ([]*Eigenvalue, error) {
+	seq := SturmSequence(f)
+	B := RootBound(f)
+	lo := new(big.Rat).Neg(B)
+
+	var roots []*Eigenvalue
+	var split func(lo, hi *big.Rat, vlo, vhi int)
+	split = func(lo, hi *big.Rat, vlo, vhi int) {
+		switch vlo - vhi {
+		case 0:
+			return
+		case 1:
+			ev := &Eigenvalue{Lower: lo, Upper: hi, poly: f}
+			if s := f.Eval(hi).Sign(); s == 0 {
+				ev.setExact(hi)
+			} else {
+				ev.signHi = s
+			}
+			roots = append(roots, ev)
+			return
+		}
+		mid := new(big.Rat).Add(lo, hi)
+		mid.Quo(mid, big.NewRat(2, 1))
+		vmid := SignChanges(seq, mid)
+		split(lo, mid, vlo, vmid)
+		split(mid, hi, vmid, vhi)
+	}
+	split(lo, B, SignChanges(seq, lo), SignChanges(seq, B))
+	return roots, nil
+}
+
+// integerLead returns the leading coefficient of f scaled to a primitive
+// integer polynomial, in absolute value.
+func integerLead(f Poly) *big.Int {
+	den := big.NewInt(1)
+	for _, c := range f {
+		g := new(big.Int).GCD(nil, nil, den, c.Denom())
+		den.Mul(den, new(big.Int).Quo(c.Denom(), g))
+	}
+	var content *big.Int
+	for _, c := range f {
+		n := new(big.Int).Mul(c.Num(), new(big.Int).Quo(den, c.Denom()))
+		n.Abs(n)
+		if n.Sign() == 0 {
+			continue
+		}
+		if content == nil {
+			content = n
+		} else {
+			content = new(big.Int).GCD(nil, nil, content, n)
+		}
+	}
+	lead := f[len(f)-1]
+	L := new(big.Int).Mul(lead.Num(), new(big.Int).Quo(den, lead.Denom()))
+	L.Abs(L)
+	return L.Quo(L, content)
+}
+
+// simplestBetween returns the fraction with the smallest denominator in
+// the closed interval [a, b], a <= b.
+func simplestBetween(a, b *big.Rat) *big.Rat {
+	if a.Sign() <= 0 && b.Sign() >= 0 {
+		return new(big.Rat)
+	}
+	if b.Sign() < 0 {
+		s := simplestBetween(new(big.Rat).Neg(b), new(big.Rat).Neg(a))
+		return s.Neg(s)
+	}
+	// 0 < a <= b.
+	fl := new(big.Int).Quo(a.Num(), a.Denom())
+	floor := new(big.Rat).SetInt(fl)
+	if floor.Cmp(a) == 0 {
+		return floor
+	}
+	next := new(big.Rat).Add(floor, big.NewRat(1, 1))
+	if next.Cmp(b) <= 0 {
+		return next
+	}
+	// floor < a <= b < floor+1: recurse on the reciprocals of the
+	// fractional parts.
+	fa := new(big.Rat).Sub(a, floor)
+	fb := new(big.Rat).Sub(b, floor)
+	s := simplestBetween(new(big.Rat).Inv(fb), new(big.Rat).Inv(fa))
+	return floor.Add(floor, s.Inv(s))
+}
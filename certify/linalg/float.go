@@ -0,0 +1,184 @@
+// float.go provides float64 numerics for proposing rational candidates.
+//
+// Nothing computed here is trusted. Floating-point results only suggest a
+// point, such as the positive part of a Hermitian matrix, which is then
+// rounded to a rational matrix and checked exactly, typically with
+// CertifyPSD. A poor float answer costs tightness, never soundness.
+//
+// Complex Hermitian matrices are handled through the real embedding
+//
+//	φ(M) = [[Re M, -Im M], [Im M, Re M]],
+//
+// which preserves products, adjoints and the spectrum (with every
+// eigenvalue doubled), so f(φ(M)) = φ(f(M)) for any spectral function f.
+package linalg
+
+import (
+	"math"
+	"math/big"
+
+	"qbtm/runtime"
+)
+
+// ToComplex converts an exact matrix to complex128 entries.
+func ToComplex(A *runtime.Matrix) [][]complex128 {
+	M := make([][]complex128, A.Rows)
+	for i := range M {
+		M[i] = make([]complex128, A.Cols)
+		for j := range M[i] {
+			q := A.Get(i, j)
+			re, _ := q.Re.Float64()
+			im, _ := q.Im.Float64()
+			M[i][j] = complex(re, im)
+		}
+	}
+	return M
+}
+
+// RoundHermitian rounds M to the nearest Hermitian matrix whose entries
+// are multiples of 2^-bits, reading the upper triangle and mirroring it.
+func RoundHermitian(M [][]complex128, bits uint) *runtime.Matrix {
+	n := len(M)
+	A := runtime.NewMatrix(n, n)
+	for i := 0; i < n; i++ {
+		A.Set(i, i, runtime.NewQI(roundRat(real(M[i][i]), bits), new(big.Rat)))
+		for j := i + 1; j < n; j++ {
+			q := runtime.NewQI(roundRat(real(M[i][j]), bits), roundRat(imag(M[i][j]), bits))
+			A.Set(i, j, q)
+			A.Set(j, i, runtime.QIConj(q))
+		}
+	}
+	return A
+}
+
+// roundRat returns the multiple of 2^-bits nearest to x, or 0 if x is not
+// finite.
+func roundRat(x float64, bits uint) *big.Rat {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return new(big.Rat)
+	}
+	scaled := math.Round(math.Ldexp(x, int(bits)))
+	num, _ := new(big.Float).SetFloat64(scaled).Int(nil)
+	return new(big.Rat).SetFrac(num, new(big.Int).Lsh(big.NewInt(1), bits))
+}
+
+// SymmetricEigen diagonalizes a real symmetric matrix by cyclic Jacobi
+// rotations. It returns the eigenvalues and a matrix whose columns are the
+// corresponding orthonormal eigenvectors.
+func SymmetricEigen(S [][]float64) ([]float64, [][]float64) {
+	n := len(S)
+	a := make([][]float64, n)
+	v := make([][]float64, n)
+	for i := range a {
+		a[i] = append([]float64(nil), S[i]...)
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+
+	for sweep := 0; sweep < 64; sweep++ {
+		off, total := 0.0, 0.0
+		for p := 0; p < n; p++ {
+			for q := 0; q < n; q++ {
+				total += a[p][q] * a[p][q]
+				if p != q {
+					off += a[p][q] * a[p][q]
+				}
+			}
+		}
+		if off <= 1e-30*total || off == 0 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				// Choose the rotation that zeroes a[p][q].
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	eigs := make([]float64, n)
+	for i := range eigs {
+		eigs[i] = a[i][i]
+	}
+	return eigs, v
+}
+
+// HermitianApply returns f(M) for a Hermitian complex matrix M, computed
+// in floating point through the real embedding.
+func HermitianApply(M [][]complex128, f func(float64) float64) [][]complex128 {
+	n := len(M)
+	S := make([][]float64, 2*n)
+	for i := range S {
+		S[i] = make([]float64, 2*n)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			re, im := real(M[i][j]), imag(M[i][j])
+			S[i][j], S[i][n+j] = re, -im
+			S[n+i][j], S[n+i][n+j] = im, re
+		}
+	}
+	eigs, V := SymmetricEigen(S)
+
+	out := make([][]complex128, n)
+	for i := 0; i < n; i++ {
+		out[i] = make([]complex128, n)
+		for j := 0; j < n; j++ {
+			// Re f(M) is the top-left block of f(φ(M)), Im f(M) the
+			// bottom-left block.
+			var re, im float64
+			for k, lambda := range eigs {
+				fl := f(lambda)
+				if fl == 0 {
+					continue
+				}
+				re += fl * V[i][k] * V[j][k]
+				im += fl * V[n+i][k] * V[j][k]
+			}
+			out[i][j] = complex(re, im)
+		}
+	}
+	return out
+}
+
+// PositivePart returns the positive part M_+ = Σ_{λ>0} λ P_λ of a Hermitian
+// complex matrix M in floating point.
+func PositivePart(M [][]complex128) [][]complex128 {
+	return HermitianApply(M, func(x float64) float64 { return math.Max(x, 0) })
+}
+
+// PositiveProjector returns the projector onto the eigenvectors of a
+// Hermitian complex matrix M with positive eigenvalue, in floating point.
+// Eigenvalues within tol of zero count as zero.
+func PositiveProjector(M [][]complex128, tol float64) [][]complex128 {
+	return HermitianApply(M, func(x float64) float64 {
+		if x > tol {
+			return 1
+		}
+		return 0
+	})
+}
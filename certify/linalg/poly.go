@@ -0,0 +1,265 @@
+// poly.go provides univariate polynomials with rational coefficients.
+//
+// A Poly stores its coefficients from the constant term up, so p[i] is the
+// coefficient of x^i. Operations return normalized polynomials without
+// trailing zero coefficients; the zero polynomial is the empty Poly.
+package linalg
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Poly is a polynomial with rational coefficients, lowest degree first.
+type Poly []*big.Rat
+
+// NewPoly builds a normalized polynomial from coefficients, lowest degree
+// first. The coefficients are copied.
+func NewPoly(coeffs ...*big.Rat) Poly {
+	p := make(Poly, len(coeffs))
+	for i, c := range coeffs {
+		p[i] = new(big.Rat).Set(c)
+	}
+	return p.normalize()
+}
+
+// normalize drops trailing zero coefficients.
+func (p Poly) normalize() Poly {
+	n := len(p)
+	for n > 0 && p[n-1].Sign() == 0 {
+		n--
+	}
+	return p[:n]
+}
+
+// Degree returns the degree of p, or -1 for the zero polynomial.
+func (p Poly) Degree() int {
+	return len(p.normalize()) - 1
+}
+
+// IsZero reports whether p is the zero polynomial.
+func (p Poly) IsZero() bool {
+	return p.Degree() < 0
+}
+
+// Lead returns the leading coefficient of p, or 0 for the zero polynomial.
+func (p Poly) Lead() *big.Rat {
+	q := p.normalize()
+	if len(q) == 0 {
+		return new(big.Rat)
+	}
+	return new(big.Rat).Set(q[len(q)-1])
+}
+
+// Eval computes p(x) by Horner's rule.
+func (p Poly) Eval(x *big.Rat) *big.Rat {
+	result := new(big.Rat)
+	for i := len(p) - 1; i >= 0; i-- {
+		result.Mul(result, x)
+		result.Add(result, p[i])
+	}
+	return result
+}
+
+// Derivative returns p'.
+func (p Poly) Derivative() Poly {
+	if len(p) <= 1 {
+		return Poly{}
+	}
+	d := make(Poly, len(p)-1)
+	for i := 1; i < len(p); i++ {
+		d[i-1] = new(big.Rat).Mul(p[i], big.NewRat(int64(i), 1))
+	}
+	return d.normalize()
+}
+
+// Sub returns p - q.
+func (p Poly) Sub(q Poly) Poly {
+	n := len(p)
+	if len(q) > n {
+		n = len(q)
+	}
+	r := make(Poly, n)
+	for i := range r {
+		r[i] = new(big.Rat)
+		if i < len(p) {
+			r[i].Add(r[i], p[i])
+		}
+		if i < len(q) {
+			r[i].Sub(r[i], q[i])
+		}
+	}
+	return r.normalize()
+}
+
+// Monic returns p divided by its leading coefficient. The zero polynomial
+// is returned unchanged.
+func (p Poly) Monic() Poly {
+	q := p.normalize()
+	if len(q) == 0 {
+		return Poly{}
+	}
+	inv := new(big.Rat).Inv(q[len(q)-1])
+	r := make(Poly, len(q))
+	for i, c := range q {
+		r[i] = new(big.Rat).Mul(c, inv)
+	}
+	return r
+}
+
+// DivMod returns the quotient and remainder of p by a nonzero q.
+func (p Poly) DivMod(q Poly) (Poly, Poly, error) {
+	q = q.normalize()
+	if len(q) == 0 {
+		return nil, nil, fmt.Errorf("linalg: division by the zero polynomial")
+	}
+	r := NewPoly(p...)
+	if len(r) < len(q) {
+		return Poly{}, r, nil
+	}
+	quot := make(Poly, len(r)-len(q)+1)
+	for i := range quot {
+		quot[i] = new(big.Rat)
+	}
+	lead := q[len(q)-1]
+	for len(r) >= len(q) {
+		shift := len(r) - len(q)
+		c := new(big.Rat).Quo(r[len(r)-1], lead)
+		quot[shift] = c
+		for i, qc := range q {
+			r[shift+i].Sub(r[shift+i], new(big.Rat).Mul(c, qc))
+		}
+		r = r[:len(r)-1].normalize()
+	}
+	return quot.normalize(), r, nil
+}
+
+// GCD returns the monic greatest common divisor of p and q. The GCD of two
+// zero polynomials is zero.
+func GCD(p, q Poly) Poly {
+	a, b := p.normalize(), q.normalize()
+	for len(b) > 0 {
+		_, r, _ := a.DivMod(b)
+		a, b = b, r
+	}
+	return a.Monic()
+}
+
+// String renders p in descending powers, e.g. "x^2 - 1/2x + 3".
+func (p Poly) String() string {
+	q := p.normalize()
+	if len(q) == 0 {
+		return "0"
+	}
+	var sb strings.Builder
+	for i := len(q) - 1; i >= 0; i-- {
+		c := q[i]
+		if c.Sign() == 0 {
+			continue
+		}
+		abs := new(big.Rat).Abs(c)
+		switch {
+		case sb.Len() == 0 && c.Sign() < 0:
+			sb.WriteString("-")
+		case sb.Len() > 0 && c.Sign() < 0:
+			sb.WriteString(" - ")
+		case sb.Len() > 0:
+			sb.WriteString(" + ")
+		}
+		if i == 0 || !abs.IsInt() || abs.Num().Cmp(big.NewInt(1)) != 0 {
+			sb.WriteString(abs.RatString())
+		}
+		switch {
+		case i == 1:
+			sb.WriteString("x")
+		case i > 1:
+			fmt.Fprintf(&sb, "x^%d", i)
+		}
+	}
+	return sb.String()
+}
+
+// SquareFree returns the square-free factorization of p by Yun's
+// algorithm: factors[k] is the monic product of the irreducible factors
+// of p with multiplicity exactly k+1, so p = lc · Π factors[k]^(k+1).
+// Constant entries mark multiplicities that do not occur.
+func SquareFree(p Poly) []Poly {
+	p = p.normalize()
+	if len(p) <= 1 {
+		return nil
+	}
+	dp := p.Derivative()
+	a := GCD(p, dp)
+	b, _, _ := p.DivMod(a)
+	c, _, _ := dp.DivMod(a)
+	d := c.Sub(b.Derivative())
+
+	var factors []Poly
+	for b.Degree() > 0 {
+		a = GCD(b, d)
+		factors = append(factors, a)
+		b, _, _ = b.DivMod(a)
+		c, _, _ = d.DivMod(a)
+		d = c.Sub(b.Derivative())
+	}
+	return factors
+}
+
+// SturmSequence returns the Sturm sequence p, p', -rem(p, p'), ... of p.
+func SturmSequence(p Poly) []Poly {
+	p = p.normalize()
+	if len(p) == 0 {
+		return nil
+	}
+	seq := []Poly{p}
+	next := p.Derivative()
+	for !next.IsZero() {
+		seq = append(seq, next)
+		_, r, _ := seq[len(seq)-2].DivMod(next)
+		next = Poly{}.Sub(r)
+	}
+	return seq
+}
+
+// SignChanges counts the sign changes of the Sturm sequence at x, skipping
+// zeros.
+func SignChanges(seq []Poly, x *big.Rat) int {
+	changes, last := 0, 0
+	for _, p := range seq {
+		s := p.Eval(x).Sign()
+		if s == 0 {
+			continue
+		}
+		if last != 0 && s != last {
+			changes++
+		}
+		last = s
+	}
+	return changes
+}
+
+// SturmCount returns the number of distinct real roots of the square-free
+// polynomial whose Sturm sequence is seq in the half-open interval (a, b].
+func SturmCount(seq []Poly, a, b *big.Rat) int {
+	return SignChanges(seq, a) - SignChanges(seq, b)
+}
+
+// RootBound returns Cauchy's bound 1 + max |p_i / p_n|, which is strictly
+// greater than the absolute value of every complex root of p.
+func RootBound(p Poly) *big.Rat {
+	p = p.normalize()
+	bound := new(big.Rat)
+	if len(p) == 0 {
+		return big.NewRat(1, 1)
+	}
+	lead := p[len(p)-1]
+	for _, c := range p[:len(p)-1] {
+		r := new(big.Rat).Quo(c, lead)
+		r.Abs(r)
+		if r.Cmp(bound) > 0 {
+			bound = r
+		}
+	}
+	return bound.Add(bound, big.NewRat(1, 1))
+}
@@ -7,40 +7,50 @@ import (
 	"qbtm/runtime"
 )
 
-// Rational approximation constants for quantum gates.
-// These are shared across all communication protocols.
-var (
-	// Sqrt2Inv is a rational approximation of 1/sqrt(2) ~ 0.7071067811865475
-	// Using convergent of continued fraction: 408/577 ~ 0.7071067811865476
-	Sqrt2Inv = big.NewRat(408, 577)
-
-	// Sqrt2 is a rational approximation of sqrt(2) ~ 1.4142135623730951
-	// Using convergent: 577/408
-	Sqrt2 = big.NewRat(577, 408)
-
-	// Half is 1/2
-	Half = big.NewRat(1, 2)
-)
+// Gate constants shared across all communication protocols.
+// Gates and states with a 1/sqrt(2) entry are exact matrices over
+// Q(zeta8) = Q(i, sqrt(2)) (runtime.MatrixZ8); the rest are matrices over
+// Q(i). Their density matrices lie in Q(i), and circuits apply the Bell
+// rotation as (1/2) U' rho U'^dag with U' = sqrt(2) U over the integers, as
+// runtime.HadamardRule does, so the executor never sees an approximation
+// of sqrt(2).
+
+// Half is 1/2
+var Half = big.NewRat(1, 2)
+
+// sqrt2Inv returns 1/sqrt(2) = sqrt(2)/2 exactly.
+func sqrt2Inv() runtime.QZ8 {
+	s, _ := runtime.QZ8InvSqrt(2)
+	return s
+}
+
+// scaleZ8 returns q*m.
+func scaleZ8(m *runtime.MatrixZ8, q runtime.QZ8) *runtime.MatrixZ8 {
+	r := runtime.NewMatrixZ8(m.Rows, m.Cols)
+	for i, x := range m.Data {
+		r.Data[i] = runtime.QZ8Mul(q, x)
+	}
+	return r
+}
 
 // ===============================
 // Single-qubit gates
 // ===============================
 
 // Hadamard returns the Hadamard gate as a 2x2 matrix.
-// H = (1/sqrt(2)) * [[1, 1], [1, -1]]
-func Hadamard() *runtime.Matrix {
-	h := runtime.NewMatrix(2, 2)
-	s := Sqrt2Inv
-
-	// H[0,0] = 1/sqrt(2)
-	h.Set(0, 0, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	// H[0,1] = 1/sqrt(2)
-	h.Set(0, 1, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	// H[1,0] = 1/sqrt(2)
-	h.Set(1, 0, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	// H[1,1] = -1/sqrt(2)
-	h.Set(1, 1, runtime.QI{Re: new(big.Rat).Neg(s), Im: new(big.Rat)})
+// H = (1/sqrt(2)) * [[1, 1], [1, -1]], exactly unitary.
+func Hadamard() *runtime.MatrixZ8 {
+	return scaleZ8(runtime.MatrixToZ8(hadamardUnnorm()), sqrt2Inv())
+}
 
+// hadamardUnnorm returns sqrt(2) H = [[1, 1], [1, -1]].
+func hadamardUnnorm() *runtime.Matrix {
+	h := runtime.NewMatrix(2, 2)
+	one := runtime.QIOne()
+	h.Set(0, 0, one)
+	h.Set(0, 1, one)
+	h.Set(1, 0, one)
+	h.Set(1, 1, runtime.QINeg(one))
 	return h
 }
 
@@ -143,21 +153,21 @@ func Ket1() *runtime.Matrix {
 }
 
 // KetPlus returns the |+> state: (|0> + |1>)/sqrt(2).
-func KetPlus() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	ket.Set(0, 0, s)
-	ket.Set(1, 0, s)
-	return ket
+func KetPlus() *runtime.MatrixZ8 {
+	return superposition(2, 0, 1, 1)
 }
 
 // KetMinus returns the |-> state: (|0> - |1>)/sqrt(2).
-func KetMinus() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	negS := runtime.QINeg(s)
-	ket.Set(0, 0, s)
-	ket.Set(1, 0, negS)
+func KetMinus() *runtime.MatrixZ8 {
+	return superposition(2, 0, 1, -1)
+}
+
+// superposition returns (|i> + sign*|j>)/sqrt(2) in dimension dim.
+func superposition(dim, i, j int, sign int64) *runtime.MatrixZ8 {
+	s := sqrt2Inv()
+	ket := runtime.NewMatrixZ8(dim, 1)
+	ket.Set(i, 0, s)
+	ket.Set(j, 0, runtime.QZ8Scale(s, big.NewRat(sign, 1)))
 	return ket
 }
 
@@ -167,46 +177,28 @@ func KetMinus() *runtime.Matrix {
 
 // BellPhiPlus returns the Bell state |Phi+> = (|00> + |11>)/sqrt(2).
 // This is the maximally entangled state used in teleportation and superdense coding.
-func BellPhiPlus() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	ket.Set(0, 0, s) // |00>
-	ket.Set(3, 0, s) // |11>
-	return ket
+func BellPhiPlus() *runtime.MatrixZ8 {
+	return superposition(4, 0, 3, 1)
 }
 
 // BellPhiMinus returns the Bell state |Phi-> = (|00> - |11>)/sqrt(2).
-func BellPhiMinus() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	negS := runtime.QINeg(s)
-	ket.Set(0, 0, s)    // |00>
-	ket.Set(3, 0, negS) // -|11>
-	return ket
+func BellPhiMinus() *runtime.MatrixZ8 {
+	return superposition(4, 0, 3, -1)
 }
 
 // BellPsiPlus returns the Bell state |Psi+> = (|01> + |10>)/sqrt(2).
-func BellPsiPlus() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	ket.Set(1, 0, s) // |01>
-	ket.Set(2, 0, s) // |10>
-	return ket
+func BellPsiPlus() *runtime.MatrixZ8 {
+	return superposition(4, 1, 2, 1)
 }
 
 // BellPsiMinus returns the Bell state |Psi-> = (|01> - |10>)/sqrt(2).
-func BellPsiMinus() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	negS := runtime.QINeg(s)
-	ket.Set(1, 0, s)    // |01>
-	ket.Set(2, 0, negS) // -|10>
-	return ket
+func BellPsiMinus() *runtime.MatrixZ8 {
+	return superposition(4, 1, 2, -1)
 }
 
 // BellState returns the i-th Bell state (0-indexed).
 // 0: |Phi+>, 1: |Phi->, 2: |Psi+>, 3: |Psi->
-func BellState(index int) *runtime.Matrix {
+func BellState(index int) *runtime.MatrixZ8 {
 	switch index {
 	case 0:
 		return BellPhiPlus()
@@ -225,9 +217,15 @@ func BellState(index int) *runtime.Matrix {
 // Density matrices
 // ===============================
 
-// DensityMatrix computes |psi><psi| from a ket vector.
-func DensityMatrix(ket *runtime.Matrix) *runtime.Matrix {
-	return runtime.OuterProduct(ket, ket)
+// DensityMatrix computes |psi><psi| from a ket vector. The product is
+// exact in Q(zeta8); the result is nil if it does not lie in Q(i), which
+// never happens for the states in this package.
+func DensityMatrix(ket *runtime.MatrixZ8) *runtime.Matrix {
+	rho, ok := runtime.MatrixZ8ToQI(runtime.OuterProductZ8(ket, ket))
+	if !ok {
+		return nil
+	}
+	return rho
 }
 
 // RhoBellPhiPlus returns the density matrix |Phi+><Phi+|.
@@ -266,29 +264,38 @@ func BellMeasurementProjectors() [4]*runtime.Matrix {
 	}
 }
 
-// BellMeasurementUnitary returns the unitary that transforms computational basis
-// to Bell basis: U|ij> = |Bell_{ij}>
+// BellMeasurementUnitary returns the unitary that transforms Bell basis
+// to computational basis: U|Bell_{ij}> = |ij>
 // This is CNOT followed by H on first qubit.
-func BellMeasurementUnitary() *runtime.Matrix {
-	// Bell measurement circuit: CNOT then H tensor I
-	// First apply CNOT, then Hadamard on first qubit
-	cnot := CNOT()
-	h := Hadamard()
-
-	// H tensor I (Hadamard on first qubit, identity on second)
-	hI := runtime.NewMatrix(4, 4)
-	for i := 0; i < 2; i++ {
-		for j := 0; j < 2; j++ {
-			hij := h.Get(i, j)
-			for k := 0; k < 2; k++ {
-				// (H tensor I)[2*i+k, 2*j+k] = H[i,j] * I[k,k] = H[i,j] if k matches
-				hI.Set(2*i+k, 2*j+k, hij)
-			}
-		}
-	}
-
-	// Compose: (H tensor I) * CNOT
-	return runtime.MatMul(hI, cnot)
+func BellMeasurementUnitary() *runtime.MatrixZ8 {
+	return scaleZ8(runtime.MatrixToZ8(bellMeasurementUnnorm()), sqrt2Inv())
+}
+
+// bellMeasurementUnnorm returns sqrt(2) times BellMeasurementUnitary,
+// (H' tensor I) * CNOT with H' = hadamardUnnorm(). Its entries are
+// integers and U' U'^dag = 2I.
+func bellMeasurementUnnorm() *runtime.Matrix {
+	return runtime.MatMul(runtime.Kronecker(hadamardUnnorm(), Identity2()), CNOT())
+}
+
+// synthesizeBellRotation stores the channel rho -> U rho U^dag for
+// U = BellMeasurementUnitary() and returns its QGID. It is built exactly as
+// Scale(1/2, Unitary(U')) with U' = bellMeasurementUnnorm().
+func synthesizeBellRotation(store *runtime.Store) [32]byte {
+	twoQubits := runtime.Object{Blocks: []uint32{4}}
+	inner := store.Put(runtime.Circuit{
+		Domain:   twoQubits,
+		Codomain: twoQubits,
+		Prim:     runtime.PrimUnitary,
+		Data:     runtime.MatrixToValue(bellMeasurementUnnorm()),
+	})
+	return store.Put(runtime.Circuit{
+		Domain:   twoQubits,
+		Codomain: twoQubits,
+		Prim:     runtime.PrimScale,
+		Data:     runtime.MakeRat(1, 2),
+		Children: [][32]byte{inner},
+	})
 }
 
 // ===============================
@@ -332,24 +339,10 @@ func AllCorrectionOperators() [4]*runtime.Matrix {
 // BellPreparationCircuit returns the unitary that prepares |Phi+> from |00>.
 // Circuit: H on first qubit, then CNOT.
 // H|0> = |+>, then CNOT(|+>|0>) = (|00> + |11>)/sqrt(2) = |Phi+>
-func BellPreparationCircuit() *runtime.Matrix {
-	// First apply H tensor I
-	h := Hadamard()
-	hI := runtime.NewMatrix(4, 4)
-	for i := 0; i < 2; i++ {
-		for j := 0; j < 2; j++ {
-			hij := h.Get(i, j)
-			for k := 0; k < 2; k++ {
-				hI.Set(2*i+k, 2*j+k, hij)
-			}
-		}
-	}
-
-	// Then apply CNOT
-	cnot := CNOT()
-
-	// Compose: CNOT * (H tensor I)
-	return runtime.MatMul(cnot, hI)
+func BellPreparationCircuit() *runtime.MatrixZ8 {
+	// CNOT * (H tensor I), with the 1/sqrt(2) of H pulled out
+	u := runtime.MatMul(CNOT(), runtime.Kronecker(hadamardUnnorm(), Identity2()))
+	return scaleZ8(runtime.MatrixToZ8(u), sqrt2Inv())
 }
 
 // ===============================
@@ -377,12 +370,13 @@ func AllSuperdenseEncodings() [4]*runtime.Matrix {
 // ===============================
 
 // GateToValue converts a gate matrix to a runtime.Value with metadata.
-func GateToValue(name string, gate *runtime.Matrix) runtime.Value {
+// Gates over Q(i) encode as plain matrices.
+func GateToValue(name string, gate *runtime.MatrixZ8) runtime.Value {
 	return runtime.MakeTag(
 		runtime.MakeText("gate"),
 		runtime.MakeSeq(
 			runtime.MakeText(name),
-			runtime.MatrixToValue(gate),
+			runtime.MatrixZ8ToValue(gate),
 		),
 	)
 }
@@ -220,17 +220,8 @@ func (p *SuperdenseCodingProtocol) synthesizeEncode(store *runtime.Store) [32]by
 // synthesizeDecode creates the decoding circuit.
 // Bob performs Bell measurement on both qubits.
 func (p *SuperdenseCodingProtocol) synthesizeDecode(store *runtime.Store) [32]byte {
-	// Bell measurement unitary (inverse of Bell preparation)
-	bellMeasureU := BellMeasurementUnitary()
-
-	// Create unitary sub-circuit
-	unitaryCircuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{4}}, // Two qubits
-		Codomain: runtime.Object{Blocks: []uint32{4}}, // Two qubits (rotated)
-		Prim:     runtime.PrimUnitary,
-		Data:     runtime.MatrixToValue(bellMeasureU),
-	}
-	unitaryQGID := store.Put(unitaryCircuit)
+	// Bell rotation, exact over Q(i); see synthesizeBellRotation
+	unitaryQGID := synthesizeBellRotation(store)
 
 	// Computational basis projectors
 	projectors := runtime.MakeSeq(
@@ -274,10 +265,10 @@ func (p *SuperdenseCodingProtocol) encodingData() runtime.Value {
 	return runtime.MakeTag(
 		runtime.MakeText("encoding-operators"),
 		runtime.MakeSeq(
-			GateToValue("I", encodings[0]),
-			GateToValue("X", encodings[1]),
-			GateToValue("Z", encodings[2]),
-			GateToValue("XZ", encodings[3]),
+			GateToValue("I", runtime.MatrixToZ8(encodings[0])),
+			GateToValue("X", runtime.MatrixToZ8(encodings[1])),
+			GateToValue("Z", runtime.MatrixToZ8(encodings[2])),
+			GateToValue("XZ", runtime.MatrixToZ8(encodings[3])),
 		),
 	)
 }
@@ -398,7 +389,7 @@ func (v *SuperdenseVerifier) VerifyEncodingDecoding() bool {
 		// E tensor I
 		encI := tensorIdentity(enc, 2)
 		// Apply to |Phi+>
-		encoded := runtime.MatMul(encI, phiPlus)
+		encoded := runtime.MatMulZ8(runtime.MatrixToZ8(encI), phiPlus)
 
 		// Check which Bell state results
 		bellIdx := identifyBellState(encoded)
@@ -428,8 +419,8 @@ func tensorIdentity(e *runtime.Matrix, n int) *runtime.Matrix {
 
 // identifyBellState determines which Bell state a vector represents.
 // Returns 0 for |Phi+>, 1 for |Phi->, 2 for |Psi+>, 3 for |Psi->, -1 if not a Bell state.
-func identifyBellState(state *runtime.Matrix) int {
-	bellStates := [4]*runtime.Matrix{
+func identifyBellState(state *runtime.MatrixZ8) int {
+	bellStates := [4]*runtime.MatrixZ8{
 		BellPhiPlus(),
 		BellPhiMinus(),
 		BellPsiPlus(),
@@ -445,19 +436,19 @@ func identifyBellState(state *runtime.Matrix) int {
 }
 
 // statesEqual checks if two state vectors are equal (up to global phase).
-func statesEqual(a, b *runtime.Matrix) bool {
+func statesEqual(a, b *runtime.MatrixZ8) bool {
 	if a.Rows != b.Rows || a.Cols != b.Cols {
 		return false
 	}
 
 	// Find first non-zero entry to determine global phase
-	var phaseA, phaseB runtime.QI
+	var phaseA, phaseB runtime.QZ8
 	found := false
 	for i := 0; i < a.Rows && !found; i++ {
 		for j := 0; j < a.Cols && !found; j++ {
 			ai := a.Get(i, j)
 			bi := b.Get(i, j)
-			if ai.Re.Sign() != 0 || ai.Im.Sign() != 0 {
+			if !runtime.QZ8IsZero(ai) {
 				phaseA = ai
 				phaseB = bi
 				found = true
@@ -477,10 +468,10 @@ func statesEqual(a, b *runtime.Matrix) bool {
 			bi := b.Get(i, j)
 
 			// Check ai * phaseB == bi * phaseA (cross multiply to avoid division)
-			lhs := runtime.QIMul(ai, phaseB)
-			rhs := runtime.QIMul(bi, phaseA)
+			lhs := runtime.QZ8Mul(ai, phaseB)
+			rhs := runtime.QZ8Mul(bi, phaseA)
 
-			if lhs.Re.Cmp(rhs.Re) != 0 || lhs.Im.Cmp(rhs.Im) != 0 {
+			if !runtime.QZ8Equal(lhs, rhs) {
 				return false
 			}
 		}
@@ -128,7 +128,7 @@ func (p *EntanglementSwappingProtocol) Protocol() *protocol.Protocol {
 				Description: "Bob performs Bell measurement on his two qubits",
 				Actions: []protocol.Action{
 					{Actor: "Bob", Type: protocol.ActionCompute, Target: "cnot",
-						Data: GateToValue("CNOT", CNOT())},
+						Data: GateToValue("CNOT", runtime.MatrixToZ8(CNOT()))},
 					{Actor: "Bob", Type: protocol.ActionCompute, Target: "hadamard",
 						Data: GateToValue("Hadamard", Hadamard())},
 					{Actor: "Bob", Type: protocol.ActionMeasure, Target: "bell-measurement",
@@ -208,17 +208,8 @@ func (p *EntanglementSwappingProtocol) Synthesize(store *runtime.Store) ([32]byt
 
 // synthesizeBellMeasurement creates Bob's Bell measurement circuit.
 func (p *EntanglementSwappingProtocol) synthesizeBellMeasurement(store *runtime.Store) [32]byte {
-	// Bell measurement unitary on Bob's two qubits
-	bellMeasureU := BellMeasurementUnitary()
-
-	// Create unitary sub-circuit
-	unitaryCircuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{4}}, // Two qubits
-		Codomain: runtime.Object{Blocks: []uint32{4}}, // Two qubits
-		Prim:     runtime.PrimUnitary,
-		Data:     runtime.MatrixToValue(bellMeasureU),
-	}
-	unitaryQGID := store.Put(unitaryCircuit)
+	// Bell rotation, exact over Q(i); see synthesizeBellRotation
+	unitaryQGID := synthesizeBellRotation(store)
 
 	// Computational basis projectors for measurement
 	projectors := runtime.MakeSeq(
@@ -313,10 +304,10 @@ func (p *EntanglementSwappingProtocol) correctionData() runtime.Value {
 	return runtime.MakeTag(
 		runtime.MakeText("correction-operators"),
 		runtime.MakeSeq(
-			GateToValue("I", corrections[0]),
-			GateToValue("X", corrections[1]),
-			GateToValue("Z", corrections[2]),
-			GateToValue("XZ", corrections[3]),
+			GateToValue("I", runtime.MatrixToZ8(corrections[0])),
+			GateToValue("X", runtime.MatrixToZ8(corrections[1])),
+			GateToValue("Z", runtime.MatrixToZ8(corrections[2])),
+			GateToValue("XZ", runtime.MatrixToZ8(corrections[3])),
 		),
 	)
 }
@@ -423,7 +414,7 @@ func (v *SwappingVerifier) VerifySwapping() bool {
 	phiPlusAB := BellPhiPlus() // |00> + |11> (on qubits 0,1)
 	phiPlusBC := BellPhiPlus() // |00> + |11> (on qubits 2,3)
 
-	initial := runtime.Kronecker(phiPlusAB, phiPlusBC)
+	initial := runtime.KroneckerZ8(phiPlusAB, phiPlusBC)
 
 	// The state is now 16-dimensional (4 qubits)
 	// Verify it has the expected non-zero entries
@@ -433,7 +424,7 @@ func (v *SwappingVerifier) VerifySwapping() bool {
 
 	// Expected non-zero entries at indices 0, 3, 12, 15
 	// |0000> = 0, |0011> = 3, |1100> = 12, |1111> = 15
-	s := runtime.QI{Re: new(big.Rat).Set(Half), Im: new(big.Rat)} // 1/2
+	half := runtime.QZ8FromQI(runtime.NewQI(Half, new(big.Rat))) // 1/2
 
 	expectedIndices := []int{0, 3, 12, 15}
 	for i := 0; i < 16; i++ {
@@ -447,12 +438,12 @@ func (v *SwappingVerifier) VerifySwapping() bool {
 		}
 		if isExpected {
 			// Should be 1/2
-			if entry.Re.Cmp(s.Re) != 0 || entry.Im.Sign() != 0 {
+			if !runtime.QZ8Equal(entry, half) {
 				return false
 			}
 		} else {
 			// Should be 0
-			if entry.Re.Sign() != 0 || entry.Im.Sign() != 0 {
+			if !runtime.QZ8IsZero(entry) {
 				return false
 			}
 		}
@@ -92,7 +92,7 @@ func (p *TeleportationProtocol) Protocol() *protocol.Protocol {
 				Description: "Alice performs Bell measurement on |psi> and her half of Bell pair",
 				Actions: []protocol.Action{
 					{Actor: "Alice", Type: protocol.ActionCompute, Target: "cnot",
-						Data: GateToValue("CNOT", CNOT())},
+						Data: GateToValue("CNOT", runtime.MatrixToZ8(CNOT()))},
 					{Actor: "Alice", Type: protocol.ActionCompute, Target: "hadamard",
 						Data: GateToValue("Hadamard", Hadamard())},
 					{Actor: "Alice", Type: protocol.ActionMeasure, Target: "bell-measurement"},
@@ -175,9 +175,6 @@ func (p *TeleportationProtocol) Synthesize(store *runtime.Store) ([32]byte, erro
 // synthesizeBellMeasurement creates the Bell measurement circuit.
 // Alice performs: CNOT(psi, Bell_A), then H(psi), then measure both qubits.
 func (p *TeleportationProtocol) synthesizeBellMeasurement(store *runtime.Store) [32]byte {
-	// Bell measurement unitary on 2 qubits
-	bellMeasureU := BellMeasurementUnitary()
-
 	// The projectors for computational basis measurement after Bell rotation
 	projectors := runtime.MakeSeq(
 		runtime.MakeTag(runtime.MakeText("outcome-00"),
@@ -190,14 +187,8 @@ func (p *TeleportationProtocol) synthesizeBellMeasurement(store *runtime.Store)
 			runtime.MatrixToValue(DensityMatrix(Ket11()))),
 	)
 
-	// Create unitary sub-circuit for Bell rotation
-	unitaryCircuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{4}}, // Two qubits
-		Codomain: runtime.Object{Blocks: []uint32{4}}, // Two qubits
-		Prim:     runtime.PrimUnitary,
-		Data:     runtime.MatrixToValue(bellMeasureU),
-	}
-	unitaryQGID := store.Put(unitaryCircuit)
+	// Bell rotation, exact over Q(i); see synthesizeBellRotation
+	unitaryQGID := synthesizeBellRotation(store)
 
 	// Create measurement circuit
 	measureCircuit := runtime.Circuit{
@@ -266,10 +257,10 @@ func (p *TeleportationProtocol) correctionData() runtime.Value {
 	return runtime.MakeTag(
 		runtime.MakeText("correction-operators"),
 		runtime.MakeSeq(
-			GateToValue("I", corrections[0]),
-			GateToValue("X", corrections[1]),
-			GateToValue("Z", corrections[2]),
-			GateToValue("XZ", corrections[3]),
+			GateToValue("I", runtime.MatrixToZ8(corrections[0])),
+			GateToValue("X", runtime.MatrixToZ8(corrections[1])),
+			GateToValue("Z", runtime.MatrixToZ8(corrections[2])),
+			GateToValue("XZ", runtime.MatrixToZ8(corrections[3])),
 		),
 	)
 }
@@ -336,37 +327,37 @@ func TeleportationFromValue(v runtime.Value) (*TeleportationProtocol, bool) {
 // ===============================
 
 // Ket00 returns the |00> state as a column vector (4x1).
-func Ket00() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	ket.Set(0, 0, runtime.QIOne())
+func Ket00() *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(4, 1)
+	ket.Set(0, 0, runtime.QZ8One())
 	return ket
 }
 
 // Ket01 returns the |01> state as a column vector (4x1).
-func Ket01() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	ket.Set(1, 0, runtime.QIOne())
+func Ket01() *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(4, 1)
+	ket.Set(1, 0, runtime.QZ8One())
 	return ket
 }
 
 // Ket10 returns the |10> state as a column vector (4x1).
-func Ket10() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	ket.Set(2, 0, runtime.QIOne())
+func Ket10() *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(4, 1)
+	ket.Set(2, 0, runtime.QZ8One())
 	return ket
 }
 
 // Ket11 returns the |11> state as a column vector (4x1).
-func Ket11() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	ket.Set(3, 0, runtime.QIOne())
+func Ket11() *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(4, 1)
+	ket.Set(3, 0, runtime.QZ8One())
 	return ket
 }
 
 // ComputationalBasisState returns the computational basis state |ij> for i,j in {0,1}.
-func ComputationalBasisState(i, j int) *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	ket.Set(2*i+j, 0, runtime.QIOne())
+func ComputationalBasisState(i, j int) *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(4, 1)
+	ket.Set(2*i+j, 0, runtime.QZ8One())
 	return ket
 }
 
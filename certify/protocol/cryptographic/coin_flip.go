@@ -8,22 +8,15 @@ import (
 	"qbtm/runtime"
 )
 
-// Rational approximation constants for quantum coin flipping.
+// Bias constants for quantum coin flipping.
+// A protocol's Bias is a rational parameter; the Kitaev bound itself,
+// (sqrt(2) - 1)/2, is irrational and given exactly by KitaevBound. States
+// with a 1/sqrt(2) amplitude are exact matrices over Q(zeta8)
+// (runtime.MatrixZ8) whose density matrices lie in Q(i).
 var (
-	// Sqrt2Inv is a rational approximation of 1/sqrt(2) ~ 0.7071067811865475
-	// Using convergent of continued fraction: 408/577 ~ 0.7071067811865476
-	Sqrt2Inv = big.NewRat(408, 577)
-
-	// Sqrt2 is a rational approximation of sqrt(2) ~ 1.4142135623730951
-	// Using convergent: 577/408
-	Sqrt2 = big.NewRat(577, 408)
-
-	// KitaevBiasExact is the Kitaev bound: (sqrt(2) - 1) / 2
-	// This is the optimal bias achievable in any quantum coin flipping protocol.
-	// Kitaev (2003) proved this is the minimum achievable cheating probability.
-	// Approximation: (1414/1000 - 1) / 2 = 414/2000 = 207/1000
-	// Better approximation using sqrt(2) ~ 577/408:
-	// (577/408 - 1) / 2 = (577-408)/(408*2) = 169/816
+	// KitaevBiasExact is the rational bias used for Kitaev-optimal protocols.
+	// Kitaev (2003) proved (sqrt(2) - 1)/2 is the minimum achievable cheating
+	// probability; 169/816 = (577/408 - 1)/2 ~ 0.2071078 lies just above it.
 	KitaevBiasExact = big.NewRat(169, 816) // ~ 0.2071078...
 
 	// KitaevBiasSimple is a simpler approximation: 207/1000 ~ 0.207
@@ -447,46 +440,57 @@ func CoinFlipFromValue(v runtime.Value) (*CoinFlipProtocol, bool) {
 	}, true
 }
 
+// KitaevBound returns the Kitaev bound (sqrt(2) - 1)/2 exactly.
+func KitaevBound() runtime.QZ8 {
+	b := runtime.QZ8Sub(runtime.QZ8Sqrt2(), runtime.QZ8One())
+	return runtime.QZ8Scale(b, big.NewRat(1, 2))
+}
+
 // ===============================
 // Helper state functions
 // ===============================
 
 // ket0 returns the |0> state as a column vector.
-func ket0() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	ket.Set(0, 0, runtime.QIOne())
+func ket0() *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(2, 1)
+	ket.Set(0, 0, runtime.QZ8One())
 	return ket
 }
 
 // ket1 returns the |1> state as a column vector.
-func ket1() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	ket.Set(1, 0, runtime.QIOne())
+func ket1() *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(2, 1)
+	ket.Set(1, 0, runtime.QZ8One())
 	return ket
 }
 
 // ketPlus returns the |+> state: (|0> + |1>)/sqrt(2).
-func ketPlus() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
+func ketPlus() *runtime.MatrixZ8 {
+	s, _ := runtime.QZ8InvSqrt(2)
+	ket := runtime.NewMatrixZ8(2, 1)
 	ket.Set(0, 0, s)
 	ket.Set(1, 0, s)
 	return ket
 }
 
 // ketMinus returns the |-> state: (|0> - |1>)/sqrt(2).
-func ketMinus() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	negS := runtime.QINeg(s)
+func ketMinus() *runtime.MatrixZ8 {
+	s, _ := runtime.QZ8InvSqrt(2)
+	ket := runtime.NewMatrixZ8(2, 1)
 	ket.Set(0, 0, s)
-	ket.Set(1, 0, negS)
+	ket.Set(1, 0, runtime.QZ8Neg(s))
 	return ket
 }
 
-// densityMatrix computes |psi><psi| from a ket vector.
-func densityMatrix(ket *runtime.Matrix) *runtime.Matrix {
-	return runtime.OuterProduct(ket, ket)
+// densityMatrix computes |psi><psi| from a ket vector. The product is
+// exact in Q(zeta8); the result is nil if it does not lie in Q(i), which
+// never happens for the states in this package.
+func densityMatrix(ket *runtime.MatrixZ8) *runtime.Matrix {
+	rho, ok := runtime.MatrixZ8ToQI(runtime.OuterProductZ8(ket, ket))
+	if !ok {
+		return nil
+	}
+	return rho
 }
 
 // rho0 returns the density matrix |0><0|.
@@ -510,12 +514,13 @@ func rhoMinus() *runtime.Matrix {
 }
 
 // stateToValue converts a quantum state to a runtime.Value with metadata.
-func stateToValue(name string, state *runtime.Matrix) runtime.Value {
+// States over Q(i) encode as plain matrices.
+func stateToValue(name string, state *runtime.MatrixZ8) runtime.Value {
 	return runtime.MakeTag(
 		runtime.MakeText("state"),
 		runtime.MakeSeq(
 			runtime.MakeText(name),
-			runtime.MatrixToValue(state),
+			runtime.MatrixZ8ToValue(state),
 		),
 	)
 }
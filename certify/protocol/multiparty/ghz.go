@@ -178,17 +178,27 @@ func (p *GHZProtocol) Synthesize(store *runtime.Store) ([32]byte, error) {
 }
 
 // synthesizeHadamard creates the Hadamard gate circuit on the first qubit.
+// As in runtime.HadamardRule, it is Scale(1/2, Unitary(H' tensor I)) with
+// H' = sqrt(2) H, which applies H exactly.
 func (p *GHZProtocol) synthesizeHadamard(store *runtime.Store) [32]byte {
-	// H on first qubit, identity on rest
-	h := ApplyGateToQubit(Hadamard(), p.NumParties, 0)
+	// H' on first qubit, identity on rest
+	h := ApplyGateToQubit(hadamardUnnorm(), p.NumParties, 0)
 
-	circuit := runtime.Circuit{
+	inner := runtime.Circuit{
 		Domain:   p.codomainObject(),
 		Codomain: p.codomainObject(),
 		Prim:     runtime.PrimUnitary,
 		Data:     runtime.MatrixToValue(h),
 	}
 
+	circuit := runtime.Circuit{
+		Domain:   p.codomainObject(),
+		Codomain: p.codomainObject(),
+		Prim:     runtime.PrimScale,
+		Data:     runtime.MakeRat(1, 2),
+		Children: [][32]byte{store.Put(inner)},
+	}
+
 	return store.Put(circuit)
 }
 
@@ -207,7 +217,7 @@ func (p *GHZProtocol) synthesizeCNOT(store *runtime.Store, control, target int)
 }
 
 // GHZState returns the GHZ state vector for this protocol.
-func (p *GHZProtocol) GHZState() *runtime.Matrix {
+func (p *GHZProtocol) GHZState() *runtime.MatrixZ8 {
 	return GHZState(p.NumParties)
 }
 
@@ -234,12 +244,12 @@ func (p *GHZProtocol) VerifyAllSameOutcome() bool {
 		amp := ghz.Get(i, 0)
 		if i == 0 || i == dim-1 {
 			// Should be 1/sqrt(2)
-			if runtime.QIIsZero(amp) {
+			if runtime.QZ8IsZero(amp) {
 				return false
 			}
 		} else {
 			// Should be 0
-			if !runtime.QIIsZero(amp) {
+			if !runtime.QZ8IsZero(amp) {
 				return false
 			}
 		}
@@ -276,7 +286,7 @@ func (p *GHZProtocol) protocolMetadata() runtime.Value {
 			runtime.MakeText(fmt.Sprintf("GHZ-%d", p.NumParties)),
 			runtime.MakeText("Greenberger-Horne-Zeilinger state distribution"),
 			runtime.MakeInt(int64(p.NumParties)),
-			runtime.MakeBigRat(Sqrt2Inv), // Normalization factor
+			runtime.MakeBigRat(Half), // |amplitude|^2 of |0...0> and |1...1>
 		),
 	)
 }
@@ -286,8 +296,8 @@ func (p *GHZProtocol) ghzStateData() runtime.Value {
 		runtime.MakeText("ghz-state"),
 		runtime.MakeSeq(
 			runtime.MakeInt(int64(p.NumParties)),
-			runtime.MatrixToValue(p.GHZState()),
-			runtime.MatrixToValue(GHZPreparationCircuit(p.NumParties)),
+			runtime.MatrixZ8ToValue(p.GHZState()),
+			runtime.MatrixZ8ToValue(GHZPreparationCircuit(p.NumParties)),
 		),
 	)
 }
@@ -324,7 +334,7 @@ func (p *GHZProtocol) ToValue() runtime.Value {
 		runtime.MakeText("ghz-protocol"),
 		runtime.MakeSeq(
 			runtime.MakeInt(int64(p.NumParties)),
-			runtime.MatrixToValue(p.GHZState()),
+			runtime.MatrixZ8ToValue(p.GHZState()),
 			p.Protocol().ToValue(),
 		),
 	)
@@ -277,14 +277,21 @@ func (p *SecretSharingProtocol) synthesizeKNScheme(store *runtime.Store) ([32]by
 }
 
 func (p *SecretSharingProtocol) synthesizeGHZPreparation(store *runtime.Store) [32]byte {
-	// GHZ preparation circuit: H then CNOTs
-	ghzCircuit := GHZPreparationCircuit(p.Total)
+	// GHZ preparation circuit: H then CNOTs, applied exactly as
+	// Scale(1/2, Unitary(U')) with U' = sqrt(2) GHZPreparationCircuit.
+	inner := runtime.Circuit{
+		Domain:   runtime.Object{Blocks: p.qubitBlocks()},
+		Codomain: runtime.Object{Blocks: p.qubitBlocks()},
+		Prim:     runtime.PrimUnitary,
+		Data:     runtime.MatrixToValue(ghzPreparationUnnorm(p.Total)),
+	}
 
 	circuit := runtime.Circuit{
 		Domain:   runtime.Object{Blocks: p.qubitBlocks()},
 		Codomain: runtime.Object{Blocks: p.qubitBlocks()},
-		Prim:     runtime.PrimUnitary,
-		Data:     runtime.MatrixToValue(ghzCircuit),
+		Prim:     runtime.PrimScale,
+		Data:     runtime.MakeRat(1, 2),
+		Children: [][32]byte{store.Put(inner)},
 	}
 
 	return store.Put(circuit)
@@ -424,7 +431,7 @@ func (p *SecretSharingProtocol) ghzPrepareData() runtime.Value {
 		runtime.MakeText("ghz-prepare"),
 		runtime.MakeSeq(
 			runtime.MakeInt(int64(p.Total)),
-			runtime.MatrixToValue(GHZState(p.Total)),
+			runtime.MatrixZ8ToValue(GHZState(p.Total)),
 		),
 	)
 }
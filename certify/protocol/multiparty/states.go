@@ -7,43 +7,50 @@ import (
 	"qbtm/runtime"
 )
 
-// Rational approximation constants for quantum gates.
-var (
-	// Sqrt2Inv is a rational approximation of 1/sqrt(2) ~ 0.7071067811865475
-	// Using convergent of continued fraction: 408/577 ~ 0.7071067811865476
-	Sqrt2Inv = big.NewRat(408, 577)
-
-	// Sqrt2 is a rational approximation of sqrt(2) ~ 1.4142135623730951
-	// Using convergent: 577/408
-	Sqrt2 = big.NewRat(577, 408)
-
-	// Sqrt3Inv is a rational approximation of 1/sqrt(3) ~ 0.5773502691896258
-	// Using convergent: 571/989
-	Sqrt3Inv = big.NewRat(571, 989)
-
-	// Sqrt3 is a rational approximation of sqrt(3) ~ 1.7320508075688772
-	// Using convergent: 989/571
-	Sqrt3 = big.NewRat(989, 571)
-
-	// Half is 1/2
-	Half = big.NewRat(1, 2)
-)
+// Gate and state constants for multiparty protocols.
+// Gates and states with a 1/sqrt(2) entry are exact matrices over
+// Q(zeta8) = Q(i, sqrt(2)) (runtime.MatrixZ8). The W state's 1/sqrt(n) lies
+// outside Q(zeta8) for most n, so it is kept as an integer ket v with the
+// exact weight 1/n of |W_n><W_n| = (1/n) v v^dag, as ExecuteStatevector
+// keeps pure states. Every density matrix is exact over Q(i), and circuits
+// never see an approximation of a square root.
+
+// Half is 1/2
+var Half = big.NewRat(1, 2)
+
+// sqrt2Inv returns 1/sqrt(2) = sqrt(2)/2 exactly.
+func sqrt2Inv() runtime.QZ8 {
+	s, _ := runtime.QZ8InvSqrt(2)
+	return s
+}
+
+// scaleZ8 returns q*m.
+func scaleZ8(m *runtime.MatrixZ8, q runtime.QZ8) *runtime.MatrixZ8 {
+	r := runtime.NewMatrixZ8(m.Rows, m.Cols)
+	for i, x := range m.Data {
+		r.Data[i] = runtime.QZ8Mul(q, x)
+	}
+	return r
+}
 
 // ===============================
 // Single-qubit gates
 // ===============================
 
 // Hadamard returns the Hadamard gate as a 2x2 matrix.
-// H = (1/sqrt(2)) * [[1, 1], [1, -1]]
-func Hadamard() *runtime.Matrix {
-	h := runtime.NewMatrix(2, 2)
-	s := Sqrt2Inv
-
-	h.Set(0, 0, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	h.Set(0, 1, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	h.Set(1, 0, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	h.Set(1, 1, runtime.QI{Re: new(big.Rat).Neg(s), Im: new(big.Rat)})
+// H = (1/sqrt(2)) * [[1, 1], [1, -1]], exactly unitary.
+func Hadamard() *runtime.MatrixZ8 {
+	return scaleZ8(runtime.MatrixToZ8(hadamardUnnorm()), sqrt2Inv())
+}
 
+// hadamardUnnorm returns sqrt(2) H = [[1, 1], [1, -1]].
+func hadamardUnnorm() *runtime.Matrix {
+	h := runtime.NewMatrix(2, 2)
+	one := runtime.QIOne()
+	h.Set(0, 0, one)
+	h.Set(0, 1, one)
+	h.Set(1, 0, one)
+	h.Set(1, 1, runtime.QINeg(one))
 	return h
 }
 
@@ -149,16 +156,16 @@ func KetAllOnes(n int) *runtime.Matrix {
 // - Maximally entangled n-party state
 // - Single qubit loss destroys all entanglement
 // - Used for multi-party secret sharing, anonymous broadcasting
-func GHZState(n int) *runtime.Matrix {
+func GHZState(n int) *runtime.MatrixZ8 {
 	if n < 2 {
 		n = 2
 	}
 
 	dim := 1 << n // 2^n
-	ket := runtime.NewMatrix(dim, 1)
+	ket := runtime.NewMatrixZ8(dim, 1)
 
 	// Coefficient: 1/sqrt(2)
-	coeff := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
+	coeff := sqrt2Inv()
 
 	// |0...0> component (index 0)
 	ket.Set(0, 0, coeff)
@@ -171,8 +178,7 @@ func GHZState(n int) *runtime.Matrix {
 
 // GHZDensityMatrix returns the density matrix |GHZ_n><GHZ_n|.
 func GHZDensityMatrix(n int) *runtime.Matrix {
-	ghz := GHZState(n)
-	return runtime.OuterProduct(ghz, ghz)
+	return DensityMatrix(GHZState(n))
 }
 
 // GHZPreparationCircuit returns the unitary that prepares |GHZ_n> from |0...0>.
@@ -187,20 +193,21 @@ func GHZDensityMatrix(n int) *runtime.Matrix {
 //	                       |
 //	q3: -------------------X----- ...
 //	...
-func GHZPreparationCircuit(n int) *runtime.Matrix {
+func GHZPreparationCircuit(n int) *runtime.MatrixZ8 {
+	return scaleZ8(runtime.MatrixToZ8(ghzPreparationUnnorm(n)), sqrt2Inv())
+}
+
+// ghzPreparationUnnorm returns sqrt(2) times GHZPreparationCircuit(n): the
+// same circuit with H replaced by hadamardUnnorm(). Its entries are
+// integers and U' U'^dag = 2I, so (1/2) U' rho U'^dag applies the circuit
+// exactly, as runtime.HadamardRule does for H.
+func ghzPreparationUnnorm(n int) *runtime.Matrix {
 	if n < 2 {
 		n = 2
 	}
 
-	dim := 1 << n // 2^n
-
-	// Start with identity
-	U := runtime.Identity(dim)
-
-	// Apply H to first qubit: H tensor I^(n-1)
-	H := Hadamard()
-	HI := TensorWithIdentity(H, n-1, 0)
-	U = runtime.MatMul(HI, U)
+	// Apply H' to first qubit: H' tensor I^(n-1)
+	U := TensorWithIdentity(hadamardUnnorm(), n-1, 0)
 
 	// Apply cascade of CNOTs: CNOT(0,i) for i = 1, ..., n-1
 	for target := 1; target < n; target++ {
@@ -215,8 +222,10 @@ func GHZPreparationCircuit(n int) *runtime.Matrix {
 // W state preparation
 // ===============================
 
-// WState returns the n-party W state:
+// WState returns the n-party W state
 // |W_n> = (|10...0> + |01...0> + ... + |0...01>) / sqrt(n)
+// as the integer ket v = |10...0> + ... + |0...01> and the weight 1/n, so
+// that |W_n><W_n| = (1/n) v v^dag exactly.
 //
 // For n=2: (|10> + |01>) / sqrt(2) = |Psi+> (Bell state)
 // For n=3: (|100> + |010> + |001>) / sqrt(3)
@@ -225,78 +234,29 @@ func GHZPreparationCircuit(n int) *runtime.Matrix {
 // - Robust to single particle loss (n-1 party W state remains)
 // - Less entangled than GHZ but more robust
 // - Used in quantum communication complexity
-func WState(n int) *runtime.Matrix {
+func WState(n int) (ket *runtime.Matrix, weight *big.Rat) {
 	if n < 2 {
 		n = 2
 	}
 
 	dim := 1 << n // 2^n
-	ket := runtime.NewMatrix(dim, 1)
-
-	// Coefficient: 1/sqrt(n)
-	coeff := SqrtNInv(n)
-	coeffQI := runtime.QI{Re: new(big.Rat).Set(coeff), Im: new(big.Rat)}
+	ket = runtime.NewMatrix(dim, 1)
 
 	// Add each |0...010...0> component (single 1 at position i)
 	for i := 0; i < n; i++ {
 		// Position with 1 in qubit i (from left, qubit 0 is most significant)
 		// So |100...0> has index 2^(n-1), |010...0> has index 2^(n-2), etc.
 		idx := 1 << (n - 1 - i)
-		ket.Set(idx, 0, coeffQI)
+		ket.Set(idx, 0, runtime.QIOne())
 	}
 
-	return ket
+	return ket, big.NewRat(1, int64(n))
 }
 
 // WDensityMatrix returns the density matrix |W_n><W_n|.
 func WDensityMatrix(n int) *runtime.Matrix {
-	w := WState(n)
-	return runtime.OuterProduct(w, w)
-}
-
-// WPreparationCircuit returns a unitary approximation that prepares |W_n> from |10...0>.
-// The exact W state preparation requires irrational rotations.
-// This implementation uses a recursive construction with controlled rotations.
-//
-// For W_n, we use:
-// 1. Start with |10...0>
-// 2. Apply controlled rotations to distribute amplitude
-//
-// The circuit uses the recursive decomposition:
-// |W_n> can be prepared from |W_{n-1}> tensor |0> plus additional operations.
-func WPreparationCircuit(n int) *runtime.Matrix {
-	if n < 2 {
-		n = 2
-	}
-
-	dim := 1 << n
-
-	// For W state preparation, we use a sequence of controlled rotations.
-	// The exact circuit depends on n and requires Ry rotations with angles arcsin(1/sqrt(k)).
-	// Here we implement an approximate version using rational approximations.
-
-	// Build the preparation unitary explicitly by computing U such that U|10...0> = |W_n>
-	// We construct U column by column from an orthonormal basis
-
-	U := runtime.Identity(dim)
-
-	// Compute the first column to be |W_n> (corresponding to input |10...0> = index 2^(n-1))
-	w := WState(n)
-
-	// Use Gram-Schmidt to complete the unitary
-	// First, swap so that |10...0> maps to |W_n>
-	inputIdx := 1 << (n - 1) // |10...0> index
-
-	// Set column inputIdx to be |W_n>
-	for i := 0; i < dim; i++ {
-		U.Set(i, inputIdx, w.Get(i, 0))
-	}
-
-	// Complete the unitary using Gram-Schmidt orthogonalization
-	// For simplicity, we keep other columns as standard basis if orthogonal to W
-	// This is an approximation - a full implementation would use QR decomposition
-
-	return U
+	w, weight := WState(n)
+	return runtime.MatScale(runtime.OuterProduct(w, w), weight)
 }
 
 // ===============================
@@ -400,47 +360,6 @@ func CNOTExpanded(n int, control int, target int) *runtime.Matrix {
 	return cnot
 }
 
-// ===============================
-// Normalization helpers
-// ===============================
-
-// SqrtNInv returns a rational approximation of 1/sqrt(n).
-// Uses precomputed values for small n and a general approximation otherwise.
-func SqrtNInv(n int) *big.Rat {
-	switch n {
-	case 1:
-		return big.NewRat(1, 1)
-	case 2:
-		return new(big.Rat).Set(Sqrt2Inv)
-	case 3:
-		return new(big.Rat).Set(Sqrt3Inv)
-	case 4:
-		return big.NewRat(1, 2) // 1/sqrt(4) = 1/2
-	case 5:
-		// 1/sqrt(5) ~ 0.4472135954999579
-		// Approximation: 306/684 ~ 0.447368
-		return big.NewRat(306, 684)
-	case 6:
-		// 1/sqrt(6) ~ 0.4082482904638631
-		// Approximation: 289/708 ~ 0.408192
-		return big.NewRat(289, 708)
-	case 7:
-		// 1/sqrt(7) ~ 0.3779644730092272
-		// Approximation: 267/706 ~ 0.378187
-		return big.NewRat(267, 706)
-	case 8:
-		// 1/sqrt(8) = 1/(2*sqrt(2)) = sqrt(2)/4
-		return new(big.Rat).Quo(Sqrt2Inv, big.NewRat(2, 1))
-	default:
-		// General approximation using Newton's method convergent
-		// For larger n, use 1/n as crude bound and refine
-		// sqrt(n) ~ (n + 1) / 2 for n near 1, better approximation needed
-		// Use the identity: 1/sqrt(n) ~ 1000 / floor(1000*sqrt(n))
-		// For simplicity, use rational approximation tables
-		return big.NewRat(1, int64(n)) // Crude fallback
-	}
-}
-
 // ===============================
 // State conversion utilities
 // ===============================
@@ -467,7 +386,13 @@ func GateToValue(name string, gate *runtime.Matrix) runtime.Value {
 	)
 }
 
-// DensityMatrix computes |psi><psi| from a ket vector.
-func DensityMatrix(ket *runtime.Matrix) *runtime.Matrix {
-	return runtime.OuterProduct(ket, ket)
+// DensityMatrix computes |psi><psi| from a ket vector. The product is
+// exact in Q(zeta8); the result is nil if it does not lie in Q(i), which
+// never happens for the states in this package.
+func DensityMatrix(ket *runtime.MatrixZ8) *runtime.Matrix {
+	rho, ok := runtime.MatrixZ8ToQI(runtime.OuterProductZ8(ket, ket))
+	if !ok {
+		return nil
+	}
+	return rho
 }
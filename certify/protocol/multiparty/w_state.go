@@ -93,7 +93,7 @@ func (p *WStateProtocol) Protocol() *protocol.Protocol {
 		Rounds: []protocol.Round{
 			{
 				Number:      1,
-				Description: "Party 1 prepares W state locally using controlled rotations",
+				Description: "Party 1 prepares W state locally",
 				Actions: []protocol.Action{
 					{Actor: partyNames[0], Type: protocol.ActionPrepare, Target: "w-state",
 						Data: p.wStateData()},
@@ -140,192 +140,43 @@ func (p *WStateProtocol) Protocol() *protocol.Protocol {
 }
 
 // Synthesize generates the W state preparation circuit and stores it.
-// The W state circuit uses controlled rotations to spread amplitude.
+//
+// The amplitudes of |W_n> are 1/sqrt(n), which for most n lie outside
+// Q(zeta8), so no exact unitary over the executor's field maps |10...0> to
+// |W_n>. Its density matrix (1/n) v v^dag is rational, though, so the
+// circuit discards its input and prepares |W_n><W_n| exactly:
+// rho -> Tr(rho) |W_n><W_n|, which is the protocol's trivial-input
+// preparation on any normalized input.
 func (p *WStateProtocol) Synthesize(store *runtime.Store) ([32]byte, error) {
-	// Build the W state preparation circuit
-	// For W_n, we use a sequence of controlled rotations
-
-	// The W state preparation is more complex than GHZ.
-	// We use the following approach:
-	// 1. Start with |10...0>
-	// 2. Apply sequence of controlled rotations to spread the "1"
-
-	children := make([][32]byte, 0)
-
-	// For n=2: |10> -> (|10> + |01>)/sqrt(2) via single rotation
-	// For n>2: recursive construction
-
-	// Step 1: Prepare initial |10...0> state
-	initCircuit := p.synthesizeInitialState(store)
-	children = append(children, initCircuit)
+	// Step 1: Discard the input register
+	discard := store.Put(runtime.Circuit{
+		Domain:   p.domainObject(),
+		Codomain: runtime.Object{Blocks: []uint32{}}, // unit
+		Prim:     runtime.PrimDiscard,
+	})
 
-	// Step 2: Apply spreading circuit
-	spreadCircuit := p.synthesizeSpreadingCircuit(store)
-	children = append(children, spreadCircuit)
+	// Step 2: Prepare |W_n><W_n|
+	prepare := store.Put(runtime.Circuit{
+		Domain:   runtime.Object{Blocks: []uint32{}}, // unit
+		Codomain: p.codomainObject(),
+		Prim:     runtime.PrimPrepare,
+		Data:     runtime.MatrixToValue(WDensityMatrix(p.NumParties)),
+	})
 
 	mainCircuit := runtime.Circuit{
 		Domain:   p.domainObject(),
 		Codomain: p.codomainObject(),
 		Prim:     runtime.PrimCompose,
 		Data:     p.protocolMetadata(),
-		Children: children,
+		Children: [][32]byte{discard, prepare},
 	}
 
 	return store.Put(mainCircuit), nil
 }
 
-// synthesizeInitialState creates the circuit to prepare |10...0>.
-func (p *WStateProtocol) synthesizeInitialState(store *runtime.Store) [32]byte {
-	// Apply X to first qubit: |00...0> -> |10...0>
-	x := ApplyGateToQubit(PauliX(), p.NumParties, 0)
-
-	circuit := runtime.Circuit{
-		Domain:   p.domainObject(),
-		Codomain: p.codomainObject(),
-		Prim:     runtime.PrimUnitary,
-		Data:     runtime.MatrixToValue(x),
-	}
-
-	return store.Put(circuit)
-}
-
-// synthesizeSpreadingCircuit creates the circuit that spreads |10...0> to W state.
-// Uses controlled rotations: for each qubit i from 1 to n-1,
-// apply a rotation conditioned on qubit i-1 being 1.
-func (p *WStateProtocol) synthesizeSpreadingCircuit(store *runtime.Store) [32]byte {
-	// The spreading is achieved by a series of controlled operations.
-	// For the W state, we need rotations by arcsin(1/sqrt(n-k+1)) at step k.
-	// We approximate these with rational operations.
-
-	// Build the unitary that maps |10...0> -> |W_n>
-	U := p.buildWPreparationUnitary()
-
-	circuit := runtime.Circuit{
-		Domain:   p.codomainObject(),
-		Codomain: p.codomainObject(),
-		Prim:     runtime.PrimUnitary,
-		Data:     runtime.MatrixToValue(U),
-	}
-
-	return store.Put(circuit)
-}
-
-// buildWPreparationUnitary constructs the unitary for W state preparation.
-// This is done by explicitly constructing U such that U|10...0> = |W_n>.
-func (p *WStateProtocol) buildWPreparationUnitary() *runtime.Matrix {
-	n := p.NumParties
-	dim := 1 << n
-
-	// We construct U such that:
-	// - U|10...0> = |W_n>
-	// - U is unitary
-
-	// The W state preparation can be done using a sequence of
-	// controlled-Y rotations with specific angles.
-	// Here we build the matrix directly.
-
-	U := runtime.Identity(dim)
-
-	// For W_2: simple rotation
-	if n == 2 {
-		// |10> -> (|10> + |01>)/sqrt(2)
-		// This is achieved by CNOT followed by H on second qubit, but adjusted
-		// Actually, we can use a controlled-H type operation
-
-		// Direct construction: swap amplitudes correctly
-		// |10> (index 2) should map to W_2
-		// We need U[0,2]=0, U[1,2]=1/sqrt(2), U[2,2]=1/sqrt(2), U[3,2]=0
-
-		s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-
-		// Reset column 2
-		U.Set(0, 2, runtime.QIZero())
-		U.Set(1, 2, s)                 // |01>
-		U.Set(2, 2, s)                 // |10>
-		U.Set(3, 2, runtime.QIZero())
-
-		// Adjust column 1 to be orthogonal
-		U.Set(0, 1, runtime.QIZero())
-		U.Set(1, 1, s)                 // |01>
-		negS := runtime.QINeg(s)
-		U.Set(2, 1, negS)              // -|10>
-		U.Set(3, 1, runtime.QIZero())
-
-		return U
-	}
-
-	// For n > 2: use direct state construction
-	// The unitary maps |10...0> (index 2^(n-1)) to |W_n>
-
-	w := WState(n)
-	inputIdx := 1 << (n - 1) // Index of |10...0>
-
-	// Set column inputIdx to be |W_n>
-	for i := 0; i < dim; i++ {
-		U.Set(i, inputIdx, w.Get(i, 0))
-	}
-
-	// Orthogonalize remaining columns using Gram-Schmidt
-	// For simplicity, keep basis states that are orthogonal to W_n
-	p.orthogonalizeUnitary(U, inputIdx, w)
-
-	return U
-}
-
-// orthogonalizeUnitary applies Gram-Schmidt to make U unitary.
-func (p *WStateProtocol) orthogonalizeUnitary(U *runtime.Matrix, wCol int, wState *runtime.Matrix) {
-	dim := U.Rows
-
-	// For each column j != wCol, orthogonalize against wState
-	for j := 0; j < dim; j++ {
-		if j == wCol {
-			continue
-		}
-
-		// Get current column j
-		col := runtime.NewMatrix(dim, 1)
-		for i := 0; i < dim; i++ {
-			col.Set(i, 0, U.Get(i, j))
-		}
-
-		// Subtract projection onto wState: col = col - <w|col>*w
-		innerProduct := runtime.QIZero()
-		for i := 0; i < dim; i++ {
-			innerProduct = runtime.QIAdd(innerProduct,
-				runtime.QIMul(runtime.QIConj(wState.Get(i, 0)), col.Get(i, 0)))
-		}
-
-		for i := 0; i < dim; i++ {
-			adjustment := runtime.QIMul(innerProduct, wState.Get(i, 0))
-			newVal := runtime.QISub(col.Get(i, 0), adjustment)
-			U.Set(i, j, newVal)
-		}
-	}
-
-	// Normalize columns (simplified - full Gram-Schmidt would be more robust)
-	for j := 0; j < dim; j++ {
-		if j == wCol {
-			continue
-		}
-
-		normSq := big.NewRat(0, 1)
-		for i := 0; i < dim; i++ {
-			normSq.Add(normSq, runtime.QINormSq(U.Get(i, j)))
-		}
-
-		if normSq.Sign() > 0 {
-			// Normalize
-			for i := 0; i < dim; i++ {
-				entry := U.Get(i, j)
-				// Scale by 1/sqrt(normSq) - approximate
-				U.Set(i, j, entry)
-			}
-		}
-	}
-}
-
-// WState returns the W state vector for this protocol.
-func (p *WStateProtocol) WStateVector() *runtime.Matrix {
+// WStateVector returns the W state vector for this protocol as an integer
+// ket and the weight of its outer product; see WState.
+func (p *WStateProtocol) WStateVector() (*runtime.Matrix, *big.Rat) {
 	return WState(p.NumParties)
 }
 
@@ -354,7 +205,7 @@ func (p *WStateProtocol) VerifyRobustness() bool {
 // SingleExcitationSubspace returns true if the state is in the single-excitation subspace.
 // W states have exactly one qubit in |1> state across all terms.
 func (p *WStateProtocol) SingleExcitationSubspace() bool {
-	w := p.WStateVector()
+	w, _ := p.WStateVector()
 	dim := 1 << p.NumParties
 
 	for i := 0; i < dim; i++ {
@@ -398,7 +249,7 @@ func (p *WStateProtocol) protocolMetadata() runtime.Value {
 			runtime.MakeText(fmt.Sprintf("W-%d", p.NumParties)),
 			runtime.MakeText("W state distribution"),
 			runtime.MakeInt(int64(p.NumParties)),
-			runtime.MakeBigRat(SqrtNInv(p.NumParties)), // Normalization factor
+			runtime.MakeBigRat(big.NewRat(1, int64(p.NumParties))), // |amplitude|^2 of each term
 		),
 	)
 }
@@ -408,7 +259,7 @@ func (p *WStateProtocol) wStateData() runtime.Value {
 		runtime.MakeText("w-state"),
 		runtime.MakeSeq(
 			runtime.MakeInt(int64(p.NumParties)),
-			runtime.MatrixToValue(p.WStateVector()),
+			runtime.MatrixToValue(p.WDensityMatrixValue()),
 		),
 	)
 }
@@ -445,7 +296,7 @@ func (p *WStateProtocol) ToValue() runtime.Value {
 		runtime.MakeText("w-state-protocol"),
 		runtime.MakeSeq(
 			runtime.MakeInt(int64(p.NumParties)),
-			runtime.MatrixToValue(p.WStateVector()),
+			runtime.MatrixToValue(p.WDensityMatrixValue()),
 			p.Protocol().ToValue(),
 		),
 	)
@@ -159,10 +159,11 @@ func (p *B92Protocol) Protocol() *protocol.Protocol {
 	}
 }
 
-// Synthesize generates the protocol circuit and stores it.
+// Synthesize generates the circuit of a single B92 round and stores it.
+//
+// The round domain is C(2), Alice's bit. The codomain is C(8), indexed by
+// SiftIndex with keep set on conclusive rounds.
 func (p *B92Protocol) Synthesize(store *runtime.Store) ([32]byte, error) {
-	// Build the B92 circuit
-
 	// Create preparation circuit
 	prepCircuit := p.synthesizePrepare(store)
 
@@ -172,21 +173,8 @@ func (p *B92Protocol) Synthesize(store *runtime.Store) ([32]byte, error) {
 	// Create key extraction circuit
 	keyExtractCircuit := p.synthesizeKeyExtract(store)
 
-	// Compose the full protocol
-	children := make([][32]byte, 3)
-	children[0] = prepCircuit
-	children[1] = measureCircuit
-	children[2] = keyExtractCircuit
-
-	mainCircuit := runtime.Circuit{
-		Domain:   p.domainObject(),
-		Codomain: p.codomainObject(),
-		Prim:     runtime.PrimCompose,
-		Data:     p.protocolMetadata(),
-		Children: children,
-	}
-
-	return store.Put(mainCircuit), nil
+	// Compose the full round
+	return composeStages(store, p.protocolMetadata(), prepCircuit, measureCircuit, keyExtractCircuit)
 }
 
 // synthesizePrepare creates the state preparation circuit.
@@ -200,56 +188,32 @@ func (p *B92Protocol) synthesizePrepare(store *runtime.Store) [32]byte {
 		StateToValue("ketPlus", KetPlus()),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2}}, // bit
-		Codomain: runtime.Object{Blocks: []uint32{2}}, // qubit
-		Prim:     runtime.PrimPrepare,
-		Data:     states,
-	}
+	rhos := []*runtime.Matrix{Rho0(), RhoPlus()}
 
-	return store.Put(circuit)
+	return synthesizeLabelledPrepare(store, rhos, states)
 }
 
 // synthesizeUSDMeasure creates the unambiguous state discrimination measurement.
 // USD has three outcomes: 0 (conclusively |0>), 1 (conclusively |+>), ? (inconclusive)
 func (p *B92Protocol) synthesizeUSDMeasure(store *runtime.Store) [32]byte {
 	// USD POVM elements:
-	// E_0 = (1 - 1/sqrt(2)) * |1><1|  -- conclusively identifies |0>
-	// E_1 = (1 - 1/sqrt(2)) * |-><-|  -- conclusively identifies |+>
+	// E_0 = (1 - 1/sqrt(2)) * |-><-|  -- conclusively identifies |0>
+	// E_1 = (1 - 1/sqrt(2)) * |1><1|  -- conclusively identifies |+>
 	// E_? = I - E_0 - E_1             -- inconclusive
-
-	// Coefficient: 1 - 1/sqrt(2) ~ 0.2929
-	coeff := new(big.Rat).Sub(big.NewRat(1, 1), Sqrt2Inv)
-
-	// E_0: identifies that state was NOT |+>, so must be |0>
-	// Constructed from |1><1| (orthogonal to |0>)
-	e0 := runtime.MatScale(Rho1(), coeff)
-
-	// E_1: identifies that state was NOT |0>, so must be |+>
-	// Constructed from |-><-| (orthogonal to |+>)
-	e1 := runtime.MatScale(RhoMinus(), coeff)
-
-	// E_? = I - E_0 - E_1 (inconclusive)
-	id := runtime.Identity(2)
-	eInconc := runtime.MatSub(runtime.MatSub(id, e0), e1)
+	elements := B92POVMElements()
 
 	povmElements := runtime.MakeSeq(
 		runtime.MakeTag(runtime.MakeText("E0-conclusive-0"),
-			runtime.MatrixToValue(e0)),
+			runtime.MatrixZ8ToValue(elements[0])),
 		runtime.MakeTag(runtime.MakeText("E1-conclusive-1"),
-			runtime.MatrixToValue(e1)),
+			runtime.MatrixZ8ToValue(elements[1])),
 		runtime.MakeTag(runtime.MakeText("E?-inconclusive"),
-			runtime.MatrixToValue(eInconc)),
+			runtime.MatrixZ8ToValue(elements[2])),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2}}, // qubit
-		Codomain: runtime.Object{Blocks: []uint32{3}}, // ternary outcome: 0, 1, ?
-		Prim:     runtime.PrimInstrument,
-		Data:     povmElements,
-	}
-
-	return store.Put(circuit)
+	// Alice's bit rides along as the label
+	povm := elements[:]
+	return synthesizeLabelledMeasureZ8(store, [][]*runtime.MatrixZ8{povm, povm}, povmElements)
 }
 
 // synthesizeKeyExtract creates the key extraction circuit.
@@ -258,22 +222,27 @@ func (p *B92Protocol) synthesizeKeyExtract(store *runtime.Store) [32]byte {
 	// If Bob got outcome 0, Alice sent |0> (bit 0)
 	// If Bob got outcome 1, Alice sent |+> (bit 1)
 	// Inconclusive results are discarded
+	targets := make([]int, 6)
+	for bit := 0; bit < 2; bit++ {
+		for outcome := 0; outcome < 3; outcome++ {
+			if outcome == 2 {
+				targets[bit*3+outcome] = SiftIndex(false, bit, 0)
+			} else {
+				targets[bit*3+outcome] = SiftIndex(true, bit, outcome)
+			}
+		}
+	}
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{3}},    // Bob's ternary outcome
-		Codomain: runtime.Object{Blocks: []uint32{2, 2}}, // keep_flag, bit
-		Prim:     runtime.PrimBranch,
-		Data: runtime.MakeTag(
-			runtime.MakeText("b92-key-extract"),
-			runtime.MakeSeq(
-				runtime.MakeText("outcome-0 -> (keep=1, bit=0)"),
-				runtime.MakeText("outcome-1 -> (keep=1, bit=1)"),
-				runtime.MakeText("outcome-? -> (keep=0, bit=?)"),
-			),
+	data := runtime.MakeTag(
+		runtime.MakeText("b92-key-extract"),
+		runtime.MakeSeq(
+			runtime.MakeText("outcome-0 -> (keep=1, bit=0)"),
+			runtime.MakeText("outcome-1 -> (keep=1, bit=1)"),
+			runtime.MakeText("outcome-? -> (keep=0, bit=?)"),
 		),
-	}
+	)
 
-	return store.Put(circuit)
+	return synthesizeClassicalMap(store, targets, SiftOutcomes, data)
 }
 
 // KeyRate returns the theoretical key rate for B92.
@@ -407,24 +376,36 @@ func (p *B92Protocol) protocolMetadata() runtime.Value {
 }
 
 // B92States returns the two B92 states.
-func B92States() [2]*runtime.Matrix {
-	return [2]*runtime.Matrix{
+func B92States() [2]*runtime.MatrixZ8 {
+	return [2]*runtime.MatrixZ8{
 		Ket0(),
 		KetPlus(),
 	}
 }
 
-// B92POVMElements returns the USD POVM elements for B92.
-func B92POVMElements() [3]*runtime.Matrix {
-	coeff := new(big.Rat).Sub(big.NewRat(1, 1), Sqrt2Inv)
+// B92POVMElements returns the USD POVM elements for B92, exact over
+// Q(zeta8). E_0 is orthogonal to |+> and so identifies |0>; E_1 is
+// orthogonal to |0> and so identifies |+>. Both carry the optimal weight
+// 1 - 1/sqrt(2).
+func B92POVMElements() [3]*runtime.MatrixZ8 {
+	coeff := runtime.QZ8Sub(runtime.QZ8One(), sqrt2Inv())
+
+	e0 := scaleZ8(runtime.MatrixToZ8(RhoMinus()), coeff)
+	e1 := scaleZ8(runtime.MatrixToZ8(Rho1()), coeff)
 
-	e0 := runtime.MatScale(Rho1(), coeff)
-	e1 := runtime.MatScale(RhoMinus(), coeff)
+	id := runtime.IdentityZ8(2)
+	eInconc := runtime.MatSubZ8(runtime.MatSubZ8(id, e0), e1)
 
-	id := runtime.Identity(2)
-	eInconc := runtime.MatSub(runtime.MatSub(id, e0), e1)
+	return [3]*runtime.MatrixZ8{e0, e1, eInconc}
+}
 
-	return [3]*runtime.Matrix{e0, e1, eInconc}
+// scaleZ8 returns q*m.
+func scaleZ8(m *runtime.MatrixZ8, q runtime.QZ8) *runtime.MatrixZ8 {
+	r := runtime.NewMatrixZ8(m.Rows, m.Cols)
+	for i, x := range m.Data {
+		r.Data[i] = runtime.QZ8Mul(q, x)
+	}
+	return r
 }
 
 // B92VulnerableToUSD returns true, indicating B92's vulnerability to USD attacks.
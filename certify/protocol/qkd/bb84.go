@@ -154,39 +154,25 @@ func (p *BB84Protocol) Protocol() *protocol.Protocol {
 	}
 }
 
-// Synthesize generates the protocol circuit and stores it.
+// Synthesize generates the circuit of a single BB84 round and stores it.
+//
+// The round domain is C(8), Alice's bit and basis together with Bob's
+// basis, indexed by BB84RoundIndex. The codomain is C(8), indexed by
+// SiftIndex. The n-round type signature is reported by Protocol().
 func (p *BB84Protocol) Synthesize(store *runtime.Store) ([32]byte, error) {
-	// Build the BB84 circuit
-	// Domain: n copies of (C(2) x C(2)) for (bit, basis) pairs
-	// Codomain: C(2)^m for final key bits
-
-	// Create the preparation circuit for a single qubit
-	// Input: (bit, basis) -> encoded qubit state
+	// Create the preparation circuit
+	// Input: (bit, basis, bobBasis) -> labelled qubit
 	prepCircuit := p.synthesizePrepare(store)
 
 	// Create the measurement circuit
-	// Input: (qubit, basis) -> classical bit
+	// Input: labelled qubit -> (bit, basis, bobBasis, outcome)
 	measureCircuit := p.synthesizeMeasure(store)
 
 	// Create the sifting circuit
 	siftCircuit := p.synthesizeSift(store)
 
-	// Compose the full protocol
-	// For now, we create a simple tensor of preparation circuits
-	children := make([][32]byte, 3)
-	children[0] = prepCircuit
-	children[1] = measureCircuit
-	children[2] = siftCircuit
-
-	mainCircuit := runtime.Circuit{
-		Domain:   p.domainObject(),
-		Codomain: p.codomainObject(),
-		Prim:     runtime.PrimCompose,
-		Data:     p.protocolMetadata(),
-		Children: children,
-	}
-
-	return store.Put(mainCircuit), nil
+	// Compose the full round
+	return composeStages(store, p.protocolMetadata(), prepCircuit, measureCircuit, siftCircuit)
 }
 
 // synthesizePrepare creates the state preparation circuit.
@@ -205,14 +191,14 @@ func (p *BB84Protocol) synthesizePrepare(store *runtime.Store) [32]byte {
 		StateToValue("ketMinus", KetMinus()),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 2}}, // bit x basis
-		Codomain: runtime.Object{Blocks: []uint32{2}},    // qubit
-		Prim:     runtime.PrimPrepare,
-		Data:     states,
+	// Bob's basis choice rides along as part of the label
+	bb84States := BB84States()
+	rhos := make([]*runtime.Matrix, 8)
+	for r := range rhos {
+		rhos[r] = bb84States[r/2]
 	}
 
-	return store.Put(circuit)
+	return synthesizeLabelledPrepare(store, rhos, states)
 }
 
 // synthesizeMeasure creates the measurement circuit.
@@ -232,27 +218,27 @@ func (p *BB84Protocol) synthesizeMeasure(store *runtime.Store) [32]byte {
 			)),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 2}}, // qubit x basis
-		Codomain: runtime.Object{Blocks: []uint32{2}},    // classical bit
-		Prim:     runtime.PrimInstrument,
-		Data:     projectors,
+	povms := make([][]*runtime.Matrix, 8)
+	for r := range povms {
+		povms[r] = GetBasisProjectors(BasisIndex(r % 2))
 	}
 
-	return store.Put(circuit)
+	return synthesizeLabelledMeasure(store, povms, projectors)
 }
 
 // synthesizeSift creates the sifting circuit.
 func (p *BB84Protocol) synthesizeSift(store *runtime.Store) [32]byte {
 	// Sifting: compare bases and keep matching bits
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 2, 2}}, // alice_basis, bob_basis, bit
-		Codomain: runtime.Object{Blocks: []uint32{2, 2}},    // keep_flag, bit
-		Prim:     runtime.PrimBranch,
-		Data:     runtime.MakeText("sift"),
+	targets := make([]int, 16)
+	for r := 0; r < 8; r++ {
+		bit, basis := BB84Decoding(r / 2)
+		bobBasis := r % 2
+		for outcome := 0; outcome < 2; outcome++ {
+			targets[r*2+outcome] = SiftIndex(basis == bobBasis, bit, outcome)
+		}
 	}
 
-	return store.Put(circuit)
+	return synthesizeClassicalMap(store, targets, SiftOutcomes, runtime.MakeText("sift"))
 }
 
 // KeyRate computes the asymptotic key rate for a given error rate.
@@ -419,3 +405,9 @@ func BB84Encoding(bit, basis int) int {
 func BB84Decoding(stateIndex int) (bit, basis int) {
 	return stateIndex % 2, stateIndex / 2
 }
+
+// BB84RoundIndex returns the index of a round input in the C(8) domain of
+// the synthesized round circuit.
+func BB84RoundIndex(bit, basis, bobBasis int) int {
+	return BB84Encoding(bit, basis)*2 + bobBasis
+}
@@ -0,0 +1,172 @@
+package qkd
+
+import (
+	"fmt"
+
+	"qbtm/runtime"
+)
+
+// Executable round circuits.
+//
+// Every QKD protocol in this package synthesizes the circuit of a single
+// round as a chain of classically controlled stages. Classical registers
+// are C(k) objects (k one-dimensional blocks), and a quantum system of
+// dimension d that still carries a classical label from an earlier stage
+// is the labelled object [d, d, ..., d], one block per label value. Stages
+// are PrimBranch circuits over those blocks, so each child sees exactly one
+// label value:
+//
+//	prepare:  C(k)          -> [d]*k   child j prepares ρ_j into block j
+//	measure:  [d]*k         -> C(k*m)  child j measures block j with an m-outcome POVM
+//	sift:     C(k*m)        -> C(8)    child j sends its label to a sift outcome
+//
+// Every round ends in C(8), indexed by SiftIndex(keep, aliceBit, bobBit),
+// so running a round on a classical distribution over its inputs yields the
+// joint distribution of the sift flag and both parties' raw key bits.
+
+// SiftOutcomes is the number of classical outcomes of a QKD round.
+const SiftOutcomes = 8
+
+// SiftIndex returns the index of the round outcome (keep, aliceBit, bobBit)
+// in the C(8) codomain of a synthesized round.
+func SiftIndex(keep bool, aliceBit, bobBit int) int {
+	k := 0
+	if keep {
+		k = 1
+	}
+	return k*4 + aliceBit*2 + bobBit
+}
+
+// classicalObject returns C(k), the object of k classical outcomes.
+func classicalObject(k int) runtime.Object {
+	return runtime.ClassicalObject(k)
+}
+
+// labelledObject returns C(k) ⊗ Q(d) = Q(d)^⊕k, a quantum system tagged by
+// a classical label with k values. It is kept as a direct sum so that each
+// label value is one block for PrimBranch.
+func labelledObject(k int, d uint32) runtime.Object {
+	return runtime.SumObjects(runtime.TensorObjects(runtime.ClassicalObject(k), runtime.QuantumObject(int(d))))
+}
+
+// synthesizeLabelledPrepare builds C(k) -> [d]*k preparing states[j] into
+// block j. All states must be d×d density matrices.
+func synthesizeLabelledPrepare(store *runtime.Store, states []*runtime.Matrix, data runtime.Value) [32]byte {
+	k := len(states)
+	d := states[0].Rows
+	children := make([][32]byte, k)
+	for j, rho := range states {
+		blocks := make([]*runtime.Matrix, k)
+		for i := range blocks {
+			blocks[i] = runtime.NewMatrix(d, d)
+		}
+		blocks[j] = rho
+		children[j] = store.Put(runtime.Circuit{
+			Domain:   classicalObject(1),
+			Codomain: labelledObject(k, uint32(d)),
+			Prim:     runtime.PrimPrepare,
+			Data:     runtime.MatrixToValue(runtime.DirectSum(blocks...)),
+		})
+	}
+
+	return store.Put(runtime.Circuit{
+		Domain:   classicalObject(k),
+		Codomain: labelledObject(k, uint32(d)),
+		Prim:     runtime.PrimBranch,
+		Data:     data,
+		Children: children,
+	})
+}
+
+// synthesizeLabelledMeasure builds [d]*k -> C(k*m) measuring block j with
+// the POVM povms[j]. Outcome o of label j lands on index j*m+o. Every POVM
+// must have the same number m of d×d effects.
+func synthesizeLabelledMeasure(store *runtime.Store, povms [][]*runtime.Matrix, data runtime.Value) [32]byte {
+	exact := make([][]*runtime.MatrixZ8, len(povms))
+	for j, povm := range povms {
+		exact[j] = make([]*runtime.MatrixZ8, len(povm))
+		for o, E := range povm {
+			exact[j][o] = runtime.MatrixToZ8(E)
+		}
+	}
+	return synthesizeLabelledMeasureZ8(store, exact, data)
+}
+
+// synthesizeLabelledMeasureZ8 is synthesizeLabelledMeasure for effects
+// over Q(zeta8). Effects in Q(i) are encoded as plain matrices, so the
+// circuits are the same as synthesizeLabelledMeasure builds for them.
+func synthesizeLabelledMeasureZ8(store *runtime.Store, povms [][]*runtime.MatrixZ8, data runtime.Value) [32]byte {
+	k := len(povms)
+	m := len(povms[0])
+	d := povms[0][0].Rows
+	children := make([][32]byte, k)
+	for j, povm := range povms {
+		effects := make([]*runtime.MatrixZ8, k*m)
+		for i := range effects {
+			effects[i] = runtime.NewMatrixZ8(d, d)
+		}
+		copy(effects[j*m:], povm)
+		children[j] = store.Put(runtime.Circuit{
+			Domain:   labelledObject(1, uint32(d)),
+			Codomain: classicalObject(k * m),
+			Prim:     runtime.PrimInstrument,
+			Data:     runtime.POVMZ8ToValue(effects),
+		})
+	}
+
+	return store.Put(runtime.Circuit{
+		Domain:   labelledObject(k, uint32(d)),
+		Codomain: classicalObject(k * m),
+		Prim:     runtime.PrimBranch,
+		Data:     data,
+		Children: children,
+	})
+}
+
+// synthesizeClassicalMap builds the deterministic map C(len(targets)) -> C(n)
+// sending outcome j to targets[j].
+func synthesizeClassicalMap(store *runtime.Store, targets []int, n int, data runtime.Value) [32]byte {
+	children := make([][32]byte, len(targets))
+	for j, t := range targets {
+		K := runtime.NewMatrix(n, 1)
+		K.Set(t, 0, runtime.QIOne())
+		children[j] = store.Put(runtime.Circuit{
+			Domain:   classicalObject(1),
+			Codomain: classicalObject(n),
+			Prim:     runtime.PrimKraus,
+			Data:     runtime.KrausToValue([]*runtime.Matrix{K}),
+		})
+	}
+
+	return store.Put(runtime.Circuit{
+		Domain:   classicalObject(len(targets)),
+		Codomain: classicalObject(n),
+		Prim:     runtime.PrimBranch,
+		Data:     data,
+		Children: children,
+	})
+}
+
+// composeStages composes stages left to right as a single n-ary compose
+// carrying data. Adjacent stages must agree on their boundary object.
+func composeStages(store *runtime.Store, data runtime.Value, stages ...[32]byte) ([32]byte, error) {
+	circuits := make([]runtime.Circuit, len(stages))
+	for i, id := range stages {
+		c, ok := store.Get(id)
+		if !ok {
+			return [32]byte{}, fmt.Errorf("stage %d not found", i)
+		}
+		if i > 0 && !runtime.ObjectEqual(circuits[i-1].Codomain, c.Domain) {
+			return [32]byte{}, fmt.Errorf("stage %d domain does not match stage %d codomain", i, i-1)
+		}
+		circuits[i] = c
+	}
+
+	return store.Put(runtime.Circuit{
+		Domain:   circuits[0].Domain,
+		Codomain: circuits[len(stages)-1].Codomain,
+		Prim:     runtime.PrimCompose,
+		Data:     data,
+		Children: stages,
+	}), nil
+}
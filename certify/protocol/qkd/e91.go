@@ -165,40 +165,28 @@ func (p *E91Protocol) Protocol() *protocol.Protocol {
 	}
 }
 
-// Synthesize generates the protocol circuit and stores it.
+// Synthesize generates the circuit of a single E91 round and stores it.
+//
+// The round domain is C(9), Alice's and Bob's basis choices indexed by
+// E91RoundIndex. The codomain is C(8), indexed by SiftIndex with keep set
+// when both parties measured along the same axis. The CHSH test is a
+// statistic over many rounds, so it is synthesized alongside the round and
+// referenced from the key extraction data rather than composed into it.
 func (p *E91Protocol) Synthesize(store *runtime.Store) ([32]byte, error) {
-	// Build the E91 circuit
-
 	// Create Bell pair preparation circuit
 	bellPrepCircuit := p.synthesizeBellPrep(store)
 
-	// Create measurement circuits for Alice and Bob
-	aliceMeasureCircuit := p.synthesizeAliceMeasure(store)
-	bobMeasureCircuit := p.synthesizeBobMeasure(store)
+	// Create the joint measurement circuit for Alice and Bob
+	measureCircuit := p.synthesizeMeasure(store)
 
 	// Create CHSH test circuit
 	chshCircuit := p.synthesizeCHSHTest(store)
 
 	// Create key extraction circuit
-	keyExtractCircuit := p.synthesizeKeyExtract(store)
-
-	// Compose the full protocol
-	children := make([][32]byte, 5)
-	children[0] = bellPrepCircuit
-	children[1] = aliceMeasureCircuit
-	children[2] = bobMeasureCircuit
-	children[3] = chshCircuit
-	children[4] = keyExtractCircuit
-
-	mainCircuit := runtime.Circuit{
-		Domain:   p.domainObject(),
-		Codomain: p.codomainObject(),
-		Prim:     runtime.PrimCompose,
-		Data:     p.protocolMetadata(),
-		Children: children,
-	}
+	keyExtractCircuit := p.synthesizeKeyExtract(store, chshCircuit)
 
-	return store.Put(mainCircuit), nil
+	// Compose the full round
+	return composeStages(store, p.protocolMetadata(), bellPrepCircuit, measureCircuit, keyExtractCircuit)
 }
 
 // synthesizeBellPrep creates the Bell pair preparation circuit.
@@ -207,129 +195,131 @@ func (p *E91Protocol) synthesizeBellPrep(store *runtime.Store) [32]byte {
 	// Circuit: H on first qubit, then CNOT
 	bellState := BellPhiPlus()
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{1}},    // trivial input (trigger)
-		Codomain: runtime.Object{Blocks: []uint32{2, 2}}, // two qubits
-		Prim:     runtime.PrimPrepare,
-		Data: runtime.MakeSeq(
-			StateToValue("phi-plus", bellState),
-			runtime.MatrixToValue(RhoBellPhiPlus()),
-		),
+	data := runtime.MakeSeq(
+		StateToValue("phi-plus", bellState),
+		runtime.MatrixToValue(RhoBellPhiPlus()),
+	)
+
+	// Both basis choices ride along as the label
+	rhos := make([]*runtime.Matrix, 9)
+	for r := range rhos {
+		rhos[r] = RhoBellPhiPlus()
 	}
 
-	return store.Put(circuit)
+	return synthesizeLabelledPrepare(store, rhos, data)
 }
 
-// synthesizeAliceMeasure creates Alice's measurement circuit.
-// Alice measures in three bases at angles 0, pi/4, pi/2.
-func (p *E91Protocol) synthesizeAliceMeasure(store *runtime.Store) [32]byte {
-	// Alice's measurement bases
-	// a1 = 0 (Z-basis)
-	// a2 = pi/4 (diagonal)
-	// a3 = pi/2 (X-basis)
+// synthesizeMeasure creates the joint measurement circuit.
+// Alice measures in three bases at angles 0, pi/4, pi/2 and Bob in three
+// bases at angles pi/4, pi/2, 3*pi/4. Outcome (x, y) of label r lands on
+// index r*4 + x*2 + y.
+func (p *E91Protocol) synthesizeMeasure(store *runtime.Store) [32]byte {
+	alice := e91AliceProjectors()
+	bob := e91BobProjectors()
+
+	povms := make([][]*runtime.MatrixZ8, 9)
+	for r := range povms {
+		a, b := r/3, r%3
+		povm := make([]*runtime.MatrixZ8, 4)
+		for x := 0; x < 2; x++ {
+			for y := 0; y < 2; y++ {
+				povm[x*2+y] = runtime.KroneckerZ8(alice[a][x], bob[b][y])
+			}
+		}
+		povms[r] = povm
+	}
 
-	aliceBases := runtime.MakeSeq(
-		runtime.MakeTag(runtime.MakeText("a1-0"),
-			runtime.MakeSeq(
-				runtime.MatrixToValue(Rho0()),
-				runtime.MatrixToValue(Rho1()),
-			)),
-		runtime.MakeTag(runtime.MakeText("a2-pi4"),
-			runtime.MakeSeq(
-				runtime.MatrixToValue(rotatedProjector(0)),
-				runtime.MatrixToValue(rotatedProjector(1)),
-			)),
-		runtime.MakeTag(runtime.MakeText("a3-pi2"),
-			runtime.MakeSeq(
-				runtime.MatrixToValue(RhoPlus()),
-				runtime.MatrixToValue(RhoMinus()),
-			)),
-	)
+	data := runtime.MakeSeq(p.aliceBases(), p.bobBases())
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 3}}, // qubit x basis-choice
-		Codomain: runtime.Object{Blocks: []uint32{2}},    // classical bit
-		Prim:     runtime.PrimInstrument,
-		Data:     aliceBases,
+	return synthesizeLabelledMeasureZ8(store, povms, data)
+}
+
+// E91Projectors returns Alice's and Bob's measurement projectors, indexed
+// by basis then outcome. They are exact over Q(zeta8).
+func E91Projectors() (alice, bob [3][2]*runtime.MatrixZ8) {
+	return e91AliceProjectors(), e91BobProjectors()
+}
+
+// e91AliceProjectors returns Alice's projectors, indexed by basis then outcome.
+func e91AliceProjectors() [3][2]*runtime.MatrixZ8 {
+	return [3][2]*runtime.MatrixZ8{
+		{runtime.MatrixToZ8(Rho0()), runtime.MatrixToZ8(Rho1())},
+		{rotatedProjector(0), rotatedProjector(1)},
+		{runtime.MatrixToZ8(RhoPlus()), runtime.MatrixToZ8(RhoMinus())},
 	}
+}
 
-	return store.Put(circuit)
+// e91BobProjectors returns Bob's projectors, indexed by basis then outcome.
+func e91BobProjectors() [3][2]*runtime.MatrixZ8 {
+	return [3][2]*runtime.MatrixZ8{
+		{rotatedProjector(0), rotatedProjector(1)},
+		{runtime.MatrixToZ8(RhoPlus()), runtime.MatrixToZ8(RhoMinus())},
+		{rotatedProjector3Pi4(0), rotatedProjector3Pi4(1)},
+	}
+}
+
+// aliceBases returns Alice's measurement bases as annotation data.
+func (p *E91Protocol) aliceBases() runtime.Value {
+	// Alice's measurement bases
+	// a1 = 0 (Z-basis)
+	// a2 = pi/4 (diagonal)
+	// a3 = pi/2 (X-basis)
+	labels := []string{"a1-0", "a2-pi4", "a3-pi2"}
+	return e91BasesToValue(labels, e91AliceProjectors())
 }
 
-// synthesizeBobMeasure creates Bob's measurement circuit.
-// Bob measures in three bases at angles pi/4, pi/2, 3*pi/4.
-func (p *E91Protocol) synthesizeBobMeasure(store *runtime.Store) [32]byte {
+// bobBases returns Bob's measurement bases as annotation data.
+func (p *E91Protocol) bobBases() runtime.Value {
 	// Bob's measurement bases
 	// b1 = pi/4
 	// b2 = pi/2 (X-basis)
 	// b3 = 3*pi/4
+	labels := []string{"b1-pi4", "b2-pi2", "b3-3pi4"}
+	return e91BasesToValue(labels, e91BobProjectors())
+}
 
-	bobBases := runtime.MakeSeq(
-		runtime.MakeTag(runtime.MakeText("b1-pi4"),
+func e91BasesToValue(labels []string, projectors [3][2]*runtime.MatrixZ8) runtime.Value {
+	items := make([]runtime.Value, len(labels))
+	for i, label := range labels {
+		items[i] = runtime.MakeTag(runtime.MakeText(label),
 			runtime.MakeSeq(
-				runtime.MatrixToValue(rotatedProjector(0)),
-				runtime.MatrixToValue(rotatedProjector(1)),
-			)),
-		runtime.MakeTag(runtime.MakeText("b2-pi2"),
-			runtime.MakeSeq(
-				runtime.MatrixToValue(RhoPlus()),
-				runtime.MatrixToValue(RhoMinus()),
-			)),
-		runtime.MakeTag(runtime.MakeText("b3-3pi4"),
-			runtime.MakeSeq(
-				runtime.MatrixToValue(rotatedProjector3Pi4(0)),
-				runtime.MatrixToValue(rotatedProjector3Pi4(1)),
-			)),
-	)
-
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 3}}, // qubit x basis-choice
-		Codomain: runtime.Object{Blocks: []uint32{2}},    // classical bit
-		Prim:     runtime.PrimInstrument,
-		Data:     bobBases,
+				runtime.MatrixZ8ToValue(projectors[i][0]),
+				runtime.MatrixZ8ToValue(projectors[i][1]),
+			))
 	}
-
-	return store.Put(circuit)
+	return runtime.MakeSeq(items...)
 }
 
-// rotatedProjector returns a projector for angle pi/4.
-// Uses rational approximation.
-func rotatedProjector(outcome int) *runtime.Matrix {
-	// |psi> = cos(pi/8)|0> + sin(pi/8)|1> for outcome 0
-	// cos(pi/8) ~ 0.9239, sin(pi/8) ~ 0.3827
-	// Using rational approximations
-	cos := big.NewRat(9239, 10000)
-	sin := big.NewRat(3827, 10000)
-
-	ket := runtime.NewMatrix(2, 1)
-	if outcome == 0 {
-		ket.Set(0, 0, runtime.QI{Re: cos, Im: new(big.Rat)})
-		ket.Set(1, 0, runtime.QI{Re: sin, Im: new(big.Rat)})
-	} else {
-		ket.Set(0, 0, runtime.QI{Re: new(big.Rat).Neg(sin), Im: new(big.Rat)})
-		ket.Set(1, 0, runtime.QI{Re: cos, Im: new(big.Rat)})
-	}
-
-	return DensityMatrix(ket)
+// rotatedProjector returns the projector for outcome 0 or 1 of the
+// measurement at angle pi/4: (I +- (X+Z)/sqrt(2))/2, onto
+// cos(pi/8)|0> + sin(pi/8)|1> and its orthogonal complement.
+func rotatedProjector(outcome int) *runtime.MatrixZ8 {
+	return blochProjector(outcome, 1, 1)
 }
 
-// rotatedProjector3Pi4 returns a projector for angle 3*pi/4.
-func rotatedProjector3Pi4(outcome int) *runtime.Matrix {
-	// |psi> = cos(3*pi/8)|0> + sin(3*pi/8)|1>
-	// cos(3*pi/8) ~ 0.3827, sin(3*pi/8) ~ 0.9239
-	cos := big.NewRat(3827, 10000)
-	sin := big.NewRat(9239, 10000)
+// rotatedProjector3Pi4 returns the projector for outcome 0 or 1 of the
+// measurement at angle 3*pi/4: (I +- (X-Z)/sqrt(2))/2.
+func rotatedProjector3Pi4(outcome int) *runtime.MatrixZ8 {
+	return blochProjector(outcome, 1, -1)
+}
 
-	ket := runtime.NewMatrix(2, 1)
-	if outcome == 0 {
-		ket.Set(0, 0, runtime.QI{Re: cos, Im: new(big.Rat)})
-		ket.Set(1, 0, runtime.QI{Re: sin, Im: new(big.Rat)})
-	} else {
-		ket.Set(0, 0, runtime.QI{Re: new(big.Rat).Neg(sin), Im: new(big.Rat)})
-		ket.Set(1, 0, runtime.QI{Re: cos, Im: new(big.Rat)})
+// blochProjector returns (I +- (x*X + z*Z)/sqrt(2))/2, with + for outcome 0.
+// (x, z)/sqrt(2) must be a unit vector, so x and z are +-1. Its entries are
+// a/2 + (b/4)*sqrt(2), exact over Q(zeta8).
+func blochProjector(outcome int, x, z int64) *runtime.MatrixZ8 {
+	s := int64(1)
+	if outcome == 1 {
+		s = -1
 	}
-
-	return DensityMatrix(ket)
+	entry := func(a, b int64) runtime.QZ8 {
+		return runtime.NewQZ8(MakeQIReal(big.NewRat(a, 2)), MakeQIReal(big.NewRat(b, 4)))
+	}
+	p := runtime.NewMatrixZ8(2, 2)
+	p.Set(0, 0, entry(1, s*z))
+	p.Set(0, 1, entry(0, s*x))
+	p.Set(1, 0, entry(0, s*x))
+	p.Set(1, 1, entry(1, -s*z))
+	return p
 }
 
 // synthesizeCHSHTest creates the CHSH test circuit.
@@ -358,18 +348,29 @@ func (p *E91Protocol) synthesizeCHSHTest(store *runtime.Store) [32]byte {
 }
 
 // synthesizeKeyExtract creates the key extraction circuit.
-func (p *E91Protocol) synthesizeKeyExtract(store *runtime.Store) [32]byte {
-	// Extract key from rounds where Alice used a3 and Bob used b1 (matching pi/2 basis)
-	// or where angles have maximal correlation
-
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 2, 3, 3}}, // outcomes and bases
-		Codomain: runtime.Object{Blocks: []uint32{2, 2}},       // keep_flag, key_bit
-		Prim:     runtime.PrimBranch,
-		Data:     runtime.MakeText("key-extract"),
+func (p *E91Protocol) synthesizeKeyExtract(store *runtime.Store, chshCircuit [32]byte) [32]byte {
+	// Extract key from rounds where Alice and Bob measured along the same
+	// axis (a2 with b1 at pi/4, a3 with b2 at pi/2). On |Phi+> their
+	// outcomes are then perfectly correlated.
+	targets := make([]int, 36)
+	for r := 0; r < 9; r++ {
+		keep := E91KeyBases(r/3, r%3)
+		for x := 0; x < 2; x++ {
+			for y := 0; y < 2; y++ {
+				targets[r*4+x*2+y] = SiftIndex(keep, x, y)
+			}
+		}
 	}
 
-	return store.Put(circuit)
+	data := runtime.MakeTag(
+		runtime.MakeText("key-extract"),
+		runtime.MakeSeq(
+			runtime.MakeText("chsh-test"),
+			runtime.MakeBytes(chshCircuit[:]),
+		),
+	)
+
+	return synthesizeClassicalMap(store, targets, SiftOutcomes, data)
 }
 
 // KeyRate computes the key rate based on CHSH value.
@@ -507,31 +508,30 @@ func E91MeasurementAngles() ([]string, []string) {
 	return alice, bob
 }
 
-// CHSHCorrelator computes the CHSH correlator E(a,b) for given measurement settings.
-// E(a,b) = <A_a * B_b> = Tr(rho * (A_a tensor B_b))
-// For |Phi+> state with optimal angles, returns values that maximize S.
-func CHSHCorrelator(aliceBasis, bobBasis int) *big.Rat {
-	// Pre-computed correlators for |Phi+> state with E91 angles
-	// E(a1,b1) = cos(pi/4) = 1/sqrt(2) ~ 0.707
-	// E(a1,b3) = cos(3*pi/4) = -1/sqrt(2) ~ -0.707
-	// E(a3,b1) = cos(pi/4) = 1/sqrt(2) ~ 0.707
-	// E(a3,b3) = cos(pi/4) = 1/sqrt(2) ~ 0.707
-
-	correlators := map[[2]int]*big.Rat{
-		{0, 0}: Sqrt2Inv,                         // E(a1,b1)
-		{0, 2}: new(big.Rat).Neg(Sqrt2Inv),       // E(a1,b3)
-		{2, 0}: Sqrt2Inv,                         // E(a3,b1)
-		{2, 2}: Sqrt2Inv,                         // E(a3,b3)
-		{1, 1}: big.NewRat(1, 1),                 // E(a2,b2) = 1 (used for key)
-		{0, 1}: new(big.Rat).Set(Sqrt2Inv),       // Others
-		{1, 0}: new(big.Rat).Set(Sqrt2Inv),       // for completeness
-		{1, 2}: big.NewRat(0, 1),                 // orthogonal
-		{2, 1}: big.NewRat(1, 1),                 // E(a3,b2) = 1 (key basis)
+// CHSHCorrelator computes the correlator E(a,b) = Tr(rho (A_a tensor B_b))
+// of Alice's basis a and Bob's basis b on |Phi+>, where A = P_0 - P_1 for
+// the basis projectors. For measurements in the X-Z plane it is
+// cos(theta_a - theta_b), so the CHSH combination
+// E(a1,b1) - E(a1,b3) + E(a3,b1) + E(a3,b3) is exactly 2*sqrt(2).
+func CHSHCorrelator(aliceBasis, bobBasis int) runtime.QZ8 {
+	alice, bob := E91Projectors()
+	if aliceBasis < 0 || aliceBasis >= len(alice) || bobBasis < 0 || bobBasis >= len(bob) {
+		return runtime.QZ8Zero()
 	}
+	A := runtime.MatSubZ8(alice[aliceBasis][0], alice[aliceBasis][1])
+	B := runtime.MatSubZ8(bob[bobBasis][0], bob[bobBasis][1])
+	rho := runtime.MatrixToZ8(RhoBellPhiPlus())
+	return runtime.TraceZ8(runtime.MatMulZ8(rho, runtime.KroneckerZ8(A, B)))
+}
 
-	key := [2]int{aliceBasis, bobBasis}
-	if c, ok := correlators[key]; ok {
-		return c
-	}
-	return big.NewRat(0, 1)
+// E91KeyBases reports whether a round with the given basis choices
+// contributes to the key: a2 with b1 (both pi/4) and a3 with b2 (both pi/2).
+func E91KeyBases(aliceBasis, bobBasis int) bool {
+	return (aliceBasis == 1 && bobBasis == 0) || (aliceBasis == 2 && bobBasis == 1)
+}
+
+// E91RoundIndex returns the index of a round input in the C(9) domain of
+// the synthesized round circuit.
+func E91RoundIndex(aliceBasis, bobBasis int) int {
+	return aliceBasis*3 + bobBasis
 }
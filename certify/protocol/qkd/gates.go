@@ -8,39 +8,31 @@ import (
 )
 
 // Gate constants for QKD protocols.
-// All gates are represented as matrices over Q(i) (Gaussian rationals).
-// For irrational entries like 1/sqrt(2), we use high-precision rational approximations.
-
-// Rational approximation of 1/sqrt(2) with high precision.
-// 1/sqrt(2) ~ 7071067811865475244/10000000000000000000
-// For practical purposes we use a simpler approximation that preserves unitarity approximately.
-var (
-	// Sqrt2Inv is a rational approximation of 1/sqrt(2) ~ 0.7071067811865475
-	// Using convergent of continued fraction: 408/577 ~ 0.7071067811865476
-	Sqrt2Inv = big.NewRat(408, 577)
-
-	// Sqrt2 is a rational approximation of sqrt(2) ~ 1.4142135623730951
-	// Using convergent: 577/408
-	Sqrt2 = big.NewRat(577, 408)
-
-	// Half is 1/2
-	Half = big.NewRat(1, 2)
-)
+// Gates and states with a 1/sqrt(2) or e^(i*pi/4) entry are exact matrices
+// over Q(zeta8) = Q(i, sqrt(2)) (runtime.MatrixZ8); the rest are matrices
+// over Q(i). Density matrices of every state here lie in Q(i); effects with
+// a sqrt(2) part (B92's USD, E91's pi/4 bases) stay in Q(zeta8) and run on
+// Executor.ExecuteZ8, so no approximation of sqrt(2) is ever exported.
+
+// Half is 1/2
+var Half = big.NewRat(1, 2)
+
+// sqrt2Inv returns 1/sqrt(2) = sqrt(2)/2 exactly.
+func sqrt2Inv() runtime.QZ8 {
+	s, _ := runtime.QZ8InvSqrt(2)
+	return s
+}
 
 // Hadamard returns the Hadamard gate as a 2x2 matrix.
-// H = (1/sqrt(2)) * [[1, 1], [1, -1]]
-func Hadamard() *runtime.Matrix {
-	h := runtime.NewMatrix(2, 2)
-	s := Sqrt2Inv
-
-	// H[0,0] = 1/sqrt(2)
-	h.Set(0, 0, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	// H[0,1] = 1/sqrt(2)
-	h.Set(0, 1, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	// H[1,0] = 1/sqrt(2)
-	h.Set(1, 0, runtime.QI{Re: new(big.Rat).Set(s), Im: new(big.Rat)})
-	// H[1,1] = -1/sqrt(2)
-	h.Set(1, 1, runtime.QI{Re: new(big.Rat).Neg(s), Im: new(big.Rat)})
+// H = (1/sqrt(2)) * [[1, 1], [1, -1]], exactly unitary.
+func Hadamard() *runtime.MatrixZ8 {
+	h := runtime.NewMatrixZ8(2, 2)
+	s := sqrt2Inv()
+
+	h.Set(0, 0, s)
+	h.Set(0, 1, s)
+	h.Set(1, 0, s)
+	h.Set(1, 1, runtime.QZ8Neg(s))
 
 	return h
 }
@@ -126,33 +118,20 @@ func PhaseDagger() *runtime.Matrix {
 }
 
 // TGate returns the T gate (pi/8 gate).
-// T = [[1, 0], [0, e^(i*pi/4)]]
-// Using rational approximation: e^(i*pi/4) = (1+i)/sqrt(2)
-func TGate() *runtime.Matrix {
-	t := runtime.NewMatrix(2, 2)
-	one := runtime.QIOne()
-	// e^(i*pi/4) ~ (1/sqrt(2)) + (1/sqrt(2))*i
-	expIPi4 := runtime.QI{
-		Re: new(big.Rat).Set(Sqrt2Inv),
-		Im: new(big.Rat).Set(Sqrt2Inv),
-	}
-	t.Set(0, 0, one)
-	t.Set(1, 1, expIPi4)
+// T = [[1, 0], [0, e^(i*pi/4)]], with e^(i*pi/4) = zeta8 = (1+i)/sqrt(2).
+func TGate() *runtime.MatrixZ8 {
+	t := runtime.NewMatrixZ8(2, 2)
+	t.Set(0, 0, runtime.QZ8One())
+	t.Set(1, 1, runtime.QZ8Zeta())
 	return t
 }
 
 // TDagger returns the T-dagger gate.
 // T^dag = [[1, 0], [0, e^(-i*pi/4)]]
-func TDagger() *runtime.Matrix {
-	t := runtime.NewMatrix(2, 2)
-	one := runtime.QIOne()
-	// e^(-i*pi/4) ~ (1/sqrt(2)) - (1/sqrt(2))*i
-	expNegIPi4 := runtime.QI{
-		Re: new(big.Rat).Set(Sqrt2Inv),
-		Im: new(big.Rat).Neg(Sqrt2Inv),
-	}
-	t.Set(0, 0, one)
-	t.Set(1, 1, expNegIPi4)
+func TDagger() *runtime.MatrixZ8 {
+	t := runtime.NewMatrixZ8(2, 2)
+	t.Set(0, 0, runtime.QZ8One())
+	t.Set(1, 1, runtime.QZ8Conj(runtime.QZ8Zeta()))
 	return t
 }
 
@@ -168,106 +147,93 @@ func SWAP() *runtime.Matrix {
 	return swap
 }
 
-// Standard quantum states as column vectors.
+// Standard quantum states as column vectors, exact over Q(zeta8).
 
 // Ket0 returns the |0> state as a column vector.
-func Ket0() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	ket.Set(0, 0, runtime.QIOne())
+func Ket0() *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(2, 1)
+	ket.Set(0, 0, runtime.QZ8One())
 	return ket
 }
 
 // Ket1 returns the |1> state as a column vector.
-func Ket1() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	ket.Set(1, 0, runtime.QIOne())
+func Ket1() *runtime.MatrixZ8 {
+	ket := runtime.NewMatrixZ8(2, 1)
+	ket.Set(1, 0, runtime.QZ8One())
 	return ket
 }
 
-// KetPlus returns the |+> state: (|0> + |1>)/sqrt(2).
-func KetPlus() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
+// qubitKet returns (|0> + c|1>)/sqrt(2).
+func qubitKet(c runtime.QI) *runtime.MatrixZ8 {
+	s := sqrt2Inv()
+	ket := runtime.NewMatrixZ8(2, 1)
 	ket.Set(0, 0, s)
-	ket.Set(1, 0, s)
+	ket.Set(1, 0, runtime.QZ8Mul(runtime.QZ8FromQI(c), s))
 	return ket
 }
 
+// KetPlus returns the |+> state: (|0> + |1>)/sqrt(2).
+func KetPlus() *runtime.MatrixZ8 {
+	return qubitKet(runtime.QIOne())
+}
+
 // KetMinus returns the |-> state: (|0> - |1>)/sqrt(2).
-func KetMinus() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	negS := runtime.QINeg(s)
-	ket.Set(0, 0, s)
-	ket.Set(1, 0, negS)
-	return ket
+func KetMinus() *runtime.MatrixZ8 {
+	return qubitKet(runtime.QINeg(runtime.QIOne()))
 }
 
 // KetPlusI returns the |+i> state: (|0> + i|1>)/sqrt(2) (Y-basis).
-func KetPlusI() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	iS := runtime.QI{Re: new(big.Rat), Im: new(big.Rat).Set(Sqrt2Inv)}
-	ket.Set(0, 0, s)
-	ket.Set(1, 0, iS)
-	return ket
+func KetPlusI() *runtime.MatrixZ8 {
+	return qubitKet(runtime.QII())
 }
 
 // KetMinusI returns the |-i> state: (|0> - i|1>)/sqrt(2) (Y-basis).
-func KetMinusI() *runtime.Matrix {
-	ket := runtime.NewMatrix(2, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	negIS := runtime.QI{Re: new(big.Rat), Im: new(big.Rat).Neg(Sqrt2Inv)}
-	ket.Set(0, 0, s)
-	ket.Set(1, 0, negIS)
-	return ket
+func KetMinusI() *runtime.MatrixZ8 {
+	return qubitKet(runtime.QINeg(runtime.QII()))
 }
 
 // Bell states
 
-// BellPhiPlus returns the Bell state |Phi+> = (|00> + |11>)/sqrt(2).
-func BellPhiPlus() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	ket.Set(0, 0, s) // |00>
-	ket.Set(3, 0, s) // |11>
+// bellKet returns (|i> + sign*|j>)/sqrt(2) on two qubits.
+func bellKet(i, j int, sign int64) *runtime.MatrixZ8 {
+	s := sqrt2Inv()
+	ket := runtime.NewMatrixZ8(4, 1)
+	ket.Set(i, 0, s)
+	ket.Set(j, 0, runtime.QZ8Scale(s, big.NewRat(sign, 1)))
 	return ket
 }
 
+// BellPhiPlus returns the Bell state |Phi+> = (|00> + |11>)/sqrt(2).
+func BellPhiPlus() *runtime.MatrixZ8 {
+	return bellKet(0, 3, 1)
+}
+
 // BellPhiMinus returns the Bell state |Phi-> = (|00> - |11>)/sqrt(2).
-func BellPhiMinus() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	negS := runtime.QINeg(s)
-	ket.Set(0, 0, s)    // |00>
-	ket.Set(3, 0, negS) // -|11>
-	return ket
+func BellPhiMinus() *runtime.MatrixZ8 {
+	return bellKet(0, 3, -1)
 }
 
 // BellPsiPlus returns the Bell state |Psi+> = (|01> + |10>)/sqrt(2).
-func BellPsiPlus() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	ket.Set(1, 0, s) // |01>
-	ket.Set(2, 0, s) // |10>
-	return ket
+func BellPsiPlus() *runtime.MatrixZ8 {
+	return bellKet(1, 2, 1)
 }
 
 // BellPsiMinus returns the Bell state |Psi-> = (|01> - |10>)/sqrt(2).
-func BellPsiMinus() *runtime.Matrix {
-	ket := runtime.NewMatrix(4, 1)
-	s := runtime.QI{Re: new(big.Rat).Set(Sqrt2Inv), Im: new(big.Rat)}
-	negS := runtime.QINeg(s)
-	ket.Set(1, 0, s)    // |01>
-	ket.Set(2, 0, negS) // -|10>
-	return ket
+func BellPsiMinus() *runtime.MatrixZ8 {
+	return bellKet(1, 2, -1)
 }
 
 // Density matrices
 
-// DensityMatrix computes |psi><psi| from a ket vector.
-func DensityMatrix(ket *runtime.Matrix) *runtime.Matrix {
-	return runtime.OuterProduct(ket, ket)
+// DensityMatrix computes |psi><psi| from a ket vector. The product is
+// exact in Q(zeta8); the result is nil if it does not lie in Q(i), which
+// never happens for the states in this package.
+func DensityMatrix(ket *runtime.MatrixZ8) *runtime.Matrix {
+	rho, ok := runtime.MatrixZ8ToQI(runtime.OuterProductZ8(ket, ket))
+	if !ok {
+		return nil
+	}
+	return rho
 }
 
 // Rho0 returns the density matrix |0><0|.
@@ -346,37 +312,26 @@ func MakeQI(re, im *big.Rat) runtime.QI {
 	return runtime.NewQI(re, im)
 }
 
-// Symbolic representation for exact gates
-
-// SymbolicSqrt2Inv returns a tagged value representing 1/sqrt(2) symbolically.
-func SymbolicSqrt2Inv() runtime.Value {
-	return runtime.MakeTag(
-		runtime.MakeText("symbolic"),
-		runtime.MakeSeq(
-			runtime.MakeText("sqrt2-inv"),
-			runtime.MakeBigRat(Sqrt2Inv), // rational approximation for computation
-		),
-	)
-}
-
 // GateToValue converts a gate matrix to a runtime.Value with metadata.
-func GateToValue(name string, gate *runtime.Matrix) runtime.Value {
+// Gates over Q(i) encode as plain matrices.
+func GateToValue(name string, gate *runtime.MatrixZ8) runtime.Value {
 	return runtime.MakeTag(
 		runtime.MakeText("gate"),
 		runtime.MakeSeq(
 			runtime.MakeText(name),
-			runtime.MatrixToValue(gate),
+			runtime.MatrixZ8ToValue(gate),
 		),
 	)
 }
 
 // StateToValue converts a quantum state to a runtime.Value with metadata.
-func StateToValue(name string, state *runtime.Matrix) runtime.Value {
+// States over Q(i) encode as plain matrices.
+func StateToValue(name string, state *runtime.MatrixZ8) runtime.Value {
 	return runtime.MakeTag(
 		runtime.MakeText("state"),
 		runtime.MakeSeq(
 			runtime.MakeText(name),
-			runtime.MatrixToValue(state),
+			runtime.MatrixZ8ToValue(state),
 		),
 	)
 }
@@ -391,7 +346,7 @@ const (
 )
 
 // GetBasisStates returns the two states for a given basis.
-func GetBasisStates(basis BasisIndex) (*runtime.Matrix, *runtime.Matrix) {
+func GetBasisStates(basis BasisIndex) (*runtime.MatrixZ8, *runtime.MatrixZ8) {
 	switch basis {
 	case BasisZ:
 		return Ket0(), Ket1()
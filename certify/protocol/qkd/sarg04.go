@@ -171,38 +171,24 @@ func (p *SARG04Protocol) Protocol() *protocol.Protocol {
 	}
 }
 
-// Synthesize generates the protocol circuit and stores it.
+// Synthesize generates the circuit of a single SARG04 round and stores it.
+//
+// The round domain is C(8), Alice's bit and basis together with Bob's
+// basis, indexed by SARG04RoundIndex. The codomain is C(8), indexed by
+// SiftIndex with keep set on conclusive rounds.
 func (p *SARG04Protocol) Synthesize(store *runtime.Store) ([32]byte, error) {
-	// Build the SARG04 circuit
-
 	// Create preparation circuit (same as BB84)
 	prepCircuit := p.synthesizePrepare(store)
 
 	// Create measurement circuit
 	measureCircuit := p.synthesizeMeasure(store)
 
-	// Create SARG04 announcement encoding
-	announcementCircuit := p.synthesizeAnnouncement(store)
-
-	// Create decoding circuit
+	// Create decoding circuit. Alice's announced pair is determined by her
+	// label, so the announcement is folded into the decode stage.
 	decodeCircuit := p.synthesizeDecode(store)
 
-	// Compose the full protocol
-	children := make([][32]byte, 4)
-	children[0] = prepCircuit
-	children[1] = measureCircuit
-	children[2] = announcementCircuit
-	children[3] = decodeCircuit
-
-	mainCircuit := runtime.Circuit{
-		Domain:   p.domainObject(),
-		Codomain: p.codomainObject(),
-		Prim:     runtime.PrimCompose,
-		Data:     p.protocolMetadata(),
-		Children: children,
-	}
-
-	return store.Put(mainCircuit), nil
+	// Compose the full round
+	return composeStages(store, p.protocolMetadata(), prepCircuit, measureCircuit, decodeCircuit)
 }
 
 // synthesizePrepare creates the state preparation circuit (same as BB84).
@@ -214,14 +200,13 @@ func (p *SARG04Protocol) synthesizePrepare(store *runtime.Store) [32]byte {
 		StateToValue("ketMinus", KetMinus()),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 2}}, // bit x basis
-		Codomain: runtime.Object{Blocks: []uint32{2}},    // qubit
-		Prim:     runtime.PrimPrepare,
-		Data:     states,
+	bb84States := BB84States()
+	rhos := make([]*runtime.Matrix, 8)
+	for r := range rhos {
+		rhos[r] = bb84States[r/2]
 	}
 
-	return store.Put(circuit)
+	return synthesizeLabelledPrepare(store, rhos, states)
 }
 
 // synthesizeMeasure creates the measurement circuit.
@@ -239,18 +224,16 @@ func (p *SARG04Protocol) synthesizeMeasure(store *runtime.Store) [32]byte {
 			)),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 2}}, // qubit x basis
-		Codomain: runtime.Object{Blocks: []uint32{2}},    // classical bit
-		Prim:     runtime.PrimInstrument,
-		Data:     projectors,
+	povms := make([][]*runtime.Matrix, 8)
+	for r := range povms {
+		povms[r] = GetBasisProjectors(BasisIndex(r % 2))
 	}
 
-	return store.Put(circuit)
+	return synthesizeLabelledMeasure(store, povms, projectors)
 }
 
-// synthesizeAnnouncement creates the SARG04 state pair announcement circuit.
-func (p *SARG04Protocol) synthesizeAnnouncement(store *runtime.Store) [32]byte {
+// announcementData returns the SARG04 state pair announcement table.
+func (p *SARG04Protocol) announcementData() runtime.Value {
 	// SARG04 announcement: Alice announces one of four state pairs
 	// Each pair contains two non-orthogonal states from different bases
 	//
@@ -266,37 +249,30 @@ func (p *SARG04Protocol) synthesizeAnnouncement(store *runtime.Store) [32]byte {
 		runtime.MakeTag(runtime.MakeText("pair-0"),
 			runtime.MakeSeq(
 				runtime.MakeText("|0>, |+>"),
-				runtime.MatrixToValue(Ket0()),
-				runtime.MatrixToValue(KetPlus()),
+				runtime.MatrixZ8ToValue(Ket0()),
+				runtime.MatrixZ8ToValue(KetPlus()),
 			)),
 		runtime.MakeTag(runtime.MakeText("pair-1"),
 			runtime.MakeSeq(
 				runtime.MakeText("|1>, |->"),
-				runtime.MatrixToValue(Ket1()),
-				runtime.MatrixToValue(KetMinus()),
+				runtime.MatrixZ8ToValue(Ket1()),
+				runtime.MatrixZ8ToValue(KetMinus()),
 			)),
 		runtime.MakeTag(runtime.MakeText("pair-2"),
 			runtime.MakeSeq(
 				runtime.MakeText("|+>, |1>"),
-				runtime.MatrixToValue(KetPlus()),
-				runtime.MatrixToValue(Ket1()),
+				runtime.MatrixZ8ToValue(KetPlus()),
+				runtime.MatrixZ8ToValue(Ket1()),
 			)),
 		runtime.MakeTag(runtime.MakeText("pair-3"),
 			runtime.MakeSeq(
 				runtime.MakeText("|->, |0>"),
-				runtime.MatrixToValue(KetMinus()),
-				runtime.MatrixToValue(Ket0()),
+				runtime.MatrixZ8ToValue(KetMinus()),
+				runtime.MatrixZ8ToValue(Ket0()),
 			)),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 2}}, // bit, basis (Alice's encoding)
-		Codomain: runtime.Object{Blocks: []uint32{4}},    // state pair index (4 possibilities)
-		Prim:     runtime.PrimEncode,
-		Data:     statePairs,
-	}
-
-	return store.Put(circuit)
+	return statePairs
 }
 
 // synthesizeDecode creates the SARG04 decoding circuit.
@@ -346,14 +322,23 @@ func (p *SARG04Protocol) synthesizeDecode(store *runtime.Store) [32]byte {
 			)),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{4, 2, 2}}, // pair_index, bob_basis, bob_outcome
-		Codomain: runtime.Object{Blocks: []uint32{2, 2}},    // conclusive_flag, decoded_bit
-		Prim:     runtime.PrimBranch,
-		Data:     decodingRules,
+	targets := make([]int, 16)
+	for r := 0; r < 8; r++ {
+		pair := r / 2
+		bit := pair % 2
+		bobBasis := r % 2
+		for outcome := 0; outcome < 2; outcome++ {
+			conclusive, decoded := SARG04DecodingMap(pair, bobBasis, outcome)
+			targets[r*2+outcome] = SiftIndex(conclusive, bit, decoded)
+		}
 	}
 
-	return store.Put(circuit)
+	data := runtime.MakeTag(
+		runtime.MakeText("sarg04-decode"),
+		runtime.MakeSeq(p.announcementData(), decodingRules),
+	)
+
+	return synthesizeClassicalMap(store, targets, SiftOutcomes, data)
 }
 
 // KeyRate computes the key rate for SARG04.
@@ -556,3 +541,9 @@ func SARG04DecodingMap(pairIndex, bobBasis, bobOutcome int) (bool, int) {
 	}
 	return false, 0
 }
+
+// SARG04RoundIndex returns the index of a round input in the C(8) domain of
+// the synthesized round circuit.
+func SARG04RoundIndex(bit, basis, bobBasis int) int {
+	return SARG04EncodingMap(bit, basis)*2 + bobBasis
+}
@@ -160,10 +160,12 @@ func (p *SixStateProtocol) Protocol() *protocol.Protocol {
 	}
 }
 
-// Synthesize generates the protocol circuit and stores it.
+// Synthesize generates the circuit of a single six-state round and stores it.
+//
+// The round domain is C(18), Alice's bit and basis together with Bob's
+// basis, indexed by SixStateRoundIndex. The codomain is C(8), indexed by
+// SiftIndex.
 func (p *SixStateProtocol) Synthesize(store *runtime.Store) ([32]byte, error) {
-	// Build the six-state circuit
-
 	// Create preparation circuit
 	prepCircuit := p.synthesizePrepare(store)
 
@@ -173,21 +175,8 @@ func (p *SixStateProtocol) Synthesize(store *runtime.Store) ([32]byte, error) {
 	// Create sifting circuit
 	siftCircuit := p.synthesizeSift(store)
 
-	// Compose the full protocol
-	children := make([][32]byte, 3)
-	children[0] = prepCircuit
-	children[1] = measureCircuit
-	children[2] = siftCircuit
-
-	mainCircuit := runtime.Circuit{
-		Domain:   p.domainObject(),
-		Codomain: p.codomainObject(),
-		Prim:     runtime.PrimCompose,
-		Data:     p.protocolMetadata(),
-		Children: children,
-	}
-
-	return store.Put(mainCircuit), nil
+	// Compose the full round
+	return composeStages(store, p.protocolMetadata(), prepCircuit, measureCircuit, siftCircuit)
 }
 
 // synthesizePrepare creates the state preparation circuit.
@@ -209,14 +198,13 @@ func (p *SixStateProtocol) synthesizePrepare(store *runtime.Store) [32]byte {
 		StateToValue("ketMinusI", KetMinusI()),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 3}}, // bit x basis (3 bases)
-		Codomain: runtime.Object{Blocks: []uint32{2}},    // qubit
-		Prim:     runtime.PrimPrepare,
-		Data:     states,
+	kets := SixStateStates()
+	rhos := make([]*runtime.Matrix, 18)
+	for r := range rhos {
+		rhos[r] = DensityMatrix(kets[r/3])
 	}
 
-	return store.Put(circuit)
+	return synthesizeLabelledPrepare(store, rhos, states)
 }
 
 // synthesizeMeasure creates the measurement circuit.
@@ -245,29 +233,28 @@ func (p *SixStateProtocol) synthesizeMeasure(store *runtime.Store) [32]byte {
 			)),
 	)
 
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{2, 3}}, // qubit x basis
-		Codomain: runtime.Object{Blocks: []uint32{2}},    // classical bit
-		Prim:     runtime.PrimInstrument,
-		Data:     projectors,
+	povms := make([][]*runtime.Matrix, 18)
+	for r := range povms {
+		povms[r] = GetBasisProjectors(BasisIndex(r % 3))
 	}
 
-	return store.Put(circuit)
+	return synthesizeLabelledMeasure(store, povms, projectors)
 }
 
 // synthesizeSift creates the sifting circuit.
 func (p *SixStateProtocol) synthesizeSift(store *runtime.Store) [32]byte {
 	// Sifting: compare bases and keep matching ones
 	// With 3 bases, matching probability is 1/3
-
-	circuit := runtime.Circuit{
-		Domain:   runtime.Object{Blocks: []uint32{3, 3, 2}}, // alice_basis, bob_basis, bit
-		Codomain: runtime.Object{Blocks: []uint32{2, 2}},    // keep_flag, bit
-		Prim:     runtime.PrimBranch,
-		Data:     runtime.MakeText("sift"),
+	targets := make([]int, 36)
+	for r := 0; r < 18; r++ {
+		bit, basis := SixStateDecoding(r / 3)
+		bobBasis := BasisIndex(r % 3)
+		for outcome := 0; outcome < 2; outcome++ {
+			targets[r*2+outcome] = SiftIndex(basis == bobBasis, bit, outcome)
+		}
 	}
 
-	return store.Put(circuit)
+	return synthesizeClassicalMap(store, targets, SiftOutcomes, runtime.MakeText("sift"))
 }
 
 // KeyRate computes the key rate for the six-state protocol.
@@ -410,8 +397,8 @@ func (p *SixStateProtocol) protocolMetadata() runtime.Value {
 }
 
 // SixStateStates returns all six states used in the protocol.
-func SixStateStates() [6]*runtime.Matrix {
-	return [6]*runtime.Matrix{
+func SixStateStates() [6]*runtime.MatrixZ8 {
+	return [6]*runtime.MatrixZ8{
 		Ket0(),
 		Ket1(),
 		KetPlus(),
@@ -448,3 +435,9 @@ func MUBProperty() bool {
 func ComputeMUBOverlap() *big.Rat {
 	return big.NewRat(1, 2)
 }
+
+// SixStateRoundIndex returns the index of a round input in the C(18)
+// domain of the synthesized round circuit.
+func SixStateRoundIndex(bit int, basis, bobBasis BasisIndex) int {
+	return SixStateEncoding(bit, basis)*3 + int(bobBasis)
+}
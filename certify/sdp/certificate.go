@@ -0,0 +1,202 @@
+// certificate.go defines the exact certificate emitted by Solve.
+package sdp
+
+import (
+	"math/big"
+
+	"qbtm/certify/linalg"
+	"qbtm/runtime"
+)
+
+// Certificate proves Lower ≤ OPT ≤ Upper for a Problem. The primal part
+// (X, XCerts, Lower) and the dual part (Y, Shift, SCerts, Upper) are each
+// optional; a missing part is nil.
+type Certificate struct {
+	Problem *Problem
+
+	X      []*runtime.Matrix        // feasible primal point
+	XCerts []*linalg.LDLCertificate // X_j ⪰ 0
+	Lower  *big.Rat                 // Σ_j ⟨C_j, X_j⟩
+
+	Y      []*big.Rat               // dual multipliers
+	Shift  *big.Rat                 // ε ≥ 0 added to every slack block
+	SCerts []*linalg.LDLCertificate // Σ_i y_i A_ij - C_j + ε·I ⪰ 0
+	Upper  *big.Rat                 // b·y + ε·TraceBound
+}
+
+// HasLower reports whether the certificate proves a lower bound.
+func (c *Certificate) HasLower() bool {
+	return c != nil && c.X != nil
+}
+
+// HasUpper reports whether the certificate proves an upper bound.
+func (c *Certificate) HasUpper() bool {
+	return c != nil && c.Y != nil
+}
+
+// Verify rechecks every claim of the certificate with exact arithmetic.
+func (c *Certificate) Verify() bool {
+	if c == nil || c.Problem == nil || c.Problem.Validate() != nil {
+		return false
+	}
+	if !c.HasLower() && !c.HasUpper() {
+		return false
+	}
+	p := c.Problem
+
+	if c.HasLower() {
+		if len(c.X) != len(p.Blocks) || len(c.XCerts) != len(p.Blocks) || c.Lower == nil {
+			return false
+		}
+		for j, n := range p.Blocks {
+			if c.X[j] == nil || c.X[j].Rows != n || c.X[j].Cols != n {
+				return false
+			}
+			if !c.XCerts[j].Verify(c.X[j]) {
+				return false
+			}
+		}
+		for i := range p.A {
+			if p.constraint(i, c.X).Cmp(p.B[i]) != 0 {
+				return false
+			}
+		}
+		if p.objective(c.X).Cmp(c.Lower) != 0 {
+			return false
+		}
+	}
+
+	if c.HasUpper() {
+		if len(c.Y) != len(p.A) || len(c.SCerts) != len(p.Blocks) || c.Shift == nil || c.Upper == nil {
+			return false
+		}
+		for _, y := range c.Y {
+			if y == nil {
+				return false
+			}
+		}
+		if c.Shift.Sign() < 0 {
+			return false
+		}
+		upper := dot(p.B, c.Y)
+		if c.Shift.Sign() != 0 {
+			trace := p.TraceBound()
+			if trace == nil {
+				return false
+			}
+			upper.Add(upper, new(big.Rat).Mul(c.Shift, trace))
+		}
+		if upper.Cmp(c.Upper) != 0 {
+			return false
+		}
+		for j, S := range p.slack(c.Y, c.Shift) {
+			if !c.SCerts[j].Verify(S) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ToValue encodes the certificate as Tag("sdp-certificate",
+// Seq(problem, X, XCerts, lower, y, shift, SCerts, upper)), with Nil for
+// each item of a missing side.
+func (c *Certificate) ToValue() runtime.Value {
+	nils := func(k int) []runtime.Value {
+		items := make([]runtime.Value, k)
+		for i := range items {
+			items[i] = runtime.MakeNil()
+		}
+		return items
+	}
+
+	primal := nils(3)
+	if c.HasLower() {
+		xs := make([]runtime.Value, len(c.X))
+		cs := make([]runtime.Value, len(c.XCerts))
+		for j := range c.X {
+			xs[j] = runtime.MatrixToValue(c.X[j])
+			cs[j] = c.XCerts[j].ToValue()
+		}
+		primal = []runtime.Value{runtime.MakeSeq(xs...), runtime.MakeSeq(cs...), runtime.MakeBigRat(c.Lower)}
+	}
+	dual := nils(4)
+	if c.HasUpper() {
+		cs := make([]runtime.Value, len(c.SCerts))
+		for j := range c.SCerts {
+			cs[j] = c.SCerts[j].ToValue()
+		}
+		dual = []runtime.Value{ratsToValue(c.Y), runtime.MakeBigRat(c.Shift), runtime.MakeSeq(cs...), runtime.MakeBigRat(c.Upper)}
+	}
+
+	items := append([]runtime.Value{c.Problem.ToValue()}, primal...)
+	items = append(items, dual...)
+	return runtime.MakeTag(runtime.MakeText("sdp-certificate"), runtime.MakeSeq(items...))
+}
+
+// CertificateFromValue decodes an sdp-certificate Value.
+func CertificateFromValue(v runtime.Value) (*Certificate, bool) {
+	tag, ok := v.(runtime.Tag)
+	if !ok {
+		return nil, false
+	}
+	label, ok := tag.Label.(runtime.Text)
+	if !ok || label.V != "sdp-certificate" {
+		return nil, false
+	}
+	seq, ok := tag.Payload.(runtime.Seq)
+	if !ok || len(seq.Items) != 8 {
+		return nil, false
+	}
+	p, ok := ProblemFromValue(seq.Items[0])
+	if !ok {
+		return nil, false
+	}
+	c := &Certificate{Problem: p}
+
+	if _, isNil := seq.Items[1].(runtime.Nil); !isNil {
+		xs, ok1 := seq.Items[1].(runtime.Seq)
+		cs, ok2 := seq.Items[2].(runtime.Seq)
+		lower, ok3 := seq.Items[3].(runtime.Rat)
+		if !ok1 || !ok2 || !ok3 {
+			return nil, false
+		}
+		for _, item := range xs.Items {
+			X, ok := runtime.MatrixFromValue(item)
+			if !ok {
+				return nil, false
+			}
+			c.X = append(c.X, X)
+		}
+		for _, item := range cs.Items {
+			cert, ok := linalg.LDLCertificateFromValue(item)
+			if !ok {
+				return nil, false
+			}
+			c.XCerts = append(c.XCerts, cert)
+		}
+		c.Lower = new(big.Rat).Set(lower.V)
+	}
+
+	if _, isNil := seq.Items[4].(runtime.Nil); !isNil {
+		if c.Y, ok = ratsFromValue(seq.Items[4]); !ok {
+			return nil, false
+		}
+		shift, ok1 := seq.Items[5].(runtime.Rat)
+		cs, ok2 := seq.Items[6].(runtime.Seq)
+		upper, ok3 := seq.Items[7].(runtime.Rat)
+		if !ok1 || !ok2 || !ok3 {
+			return nil, false
+		}
+		for _, item := range cs.Items {
+			cert, ok := linalg.LDLCertificateFromValue(item)
+			if !ok {
+				return nil, false
+			}
+			c.SCerts = append(c.SCerts, cert)
+		}
+		c.Shift = new(big.Rat).Set(shift.V)
+		c.Upper = new(big.Rat).Set(upper.V)
+	}
+	return c, true
+}
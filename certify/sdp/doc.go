@@ -0,0 +1,19 @@
+// Package sdp solves semidefinite programs with rigorous rational bounds.
+//
+// A Problem is stated over block-diagonal Hermitian matrices with exact
+// Q(i) data. Solve runs a dependency-free primal-dual interior-point method
+// in float64 on the real embedding of the problem, rounds the primal and
+// dual iterates to rationals, and then proves what it can exactly:
+//
+//   - Primal: the rounded X is projected onto the affine constraints by an
+//     exact linear solve and each block is certified PSD by LDL†, so
+//     Lower = ⟨C, X⟩ is a lower bound on the optimum.
+//   - Dual: the rounded y gives S = Σ y_i A_i - C, each block certified
+//     PSD by LDL† (after a small shift when the problem bounds Tr X), so
+//     Upper = b·y is an upper bound.
+//
+// The floating-point solver is never trusted. A Certificate carries the
+// problem, the rational points and the LDL† factors, and Verify rechecks
+// every claim with exact arithmetic. Certificates encode as runtime.Value
+// for inclusion in evidence bundles.
+package sdp
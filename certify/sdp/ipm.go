@@ -0,0 +1,445 @@
+// ipm.go is a primal-dual interior-point method in float64.
+//
+// A complex Hermitian block of size n is replaced by its real embedding
+// φ(M) = [[Re M, -Im M], [Im M, Re M]] of size 2n. Since Tr(φ(A)φ(X)) =
+// 2⟨A, X⟩ and every real feasible point can be averaged into the image of
+// φ, the real program with data φ(C)/2, φ(A_i)/2 and b has the same value.
+//
+// Iterations follow the HKM search direction from an infeasible start
+// X = Z = γI, y = 0: the Schur complement M_ij = Tr(A_i X A_j Z⁻¹) gives
+// Δy, then ΔZ = Σ Δy_i A_i + R_d and ΔX = σμZ⁻¹ - X - X ΔZ Z⁻¹,
+// symmetrized. Step lengths keep X and Z strictly positive definite.
+package sdp
+
+import (
+	"fmt"
+	"math"
+
+	"qbtm/certify/linalg"
+	"qbtm/runtime"
+)
+
+// dense is a real square matrix stored by rows.
+type dense [][]float64
+
+func newDense(n int) dense {
+	m := make(dense, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+	}
+	return m
+}
+
+func identityDense(n int, scale float64) dense {
+	m := newDense(n)
+	for i := range m {
+		m[i][i] = scale
+	}
+	return m
+}
+
+func (a dense) mul(b dense) dense {
+	n := len(a)
+	c := newDense(n)
+	for i := 0; i < n; i++ {
+		for k := 0; k < n; k++ {
+			if a[i][k] == 0 {
+				continue
+			}
+			aik := a[i][k]
+			for j := 0; j < n; j++ {
+				c[i][j] += aik * b[k][j]
+			}
+		}
+	}
+	return c
+}
+
+// axpy returns a + s·b.
+func (a dense) axpy(s float64, b dense) dense {
+	c := newDense(len(a))
+	for i := range a {
+		for j := range a[i] {
+			c[i][j] = a[i][j] + s*b[i][j]
+		}
+	}
+	return c
+}
+
+// scaled returns s·a.
+func (a dense) scaled(s float64) dense {
+	c := newDense(len(a))
+	for i := range a {
+		for j := range a[i] {
+			c[i][j] = s * a[i][j]
+		}
+	}
+	return c
+}
+
+func (a dense) symmetrize() dense {
+	c := newDense(len(a))
+	for i := range a {
+		for j := range a[i] {
+			c[i][j] = (a[i][j] + a[j][i]) / 2
+		}
+	}
+	return c
+}
+
+// innerDense returns Tr(aᵀb).
+func innerDense(a, b dense) float64 {
+	sum := 0.0
+	for i := range a {
+		for j := range a[i] {
+			sum += a[i][j] * b[i][j]
+		}
+	}
+	return sum
+}
+
+// cholesky returns lower-triangular L with L·Lᵀ = a, or false if a is not
+// numerically positive definite.
+func cholesky(a dense) (dense, bool) {
+	n := len(a)
+	L := newDense(n)
+	for j := 0; j < n; j++ {
+		d := a[j][j]
+		for k := 0; k < j; k++ {
+			d -= L[j][k] * L[j][k]
+		}
+		if !(d > 0) {
+			return nil, false
+		}
+		L[j][j] = math.Sqrt(d)
+		for i := j + 1; i < n; i++ {
+			s := a[i][j]
+			for k := 0; k < j; k++ {
+				s -= L[i][k] * L[j][k]
+			}
+			L[i][j] = s / L[j][j]
+		}
+	}
+	return L, true
+}
+
+// lowerInverse inverts a lower-triangular matrix.
+func lowerInverse(L dense) dense {
+	n := len(L)
+	inv := newDense(n)
+	for j := 0; j < n; j++ {
+		inv[j][j] = 1 / L[j][j]
+		for i := j + 1; i < n; i++ {
+			s := 0.0
+			for k := j; k < i; k++ {
+				s -= L[i][k] * inv[k][j]
+			}
+			inv[i][j] = s / L[i][i]
+		}
+	}
+	return inv
+}
+
+// transpose returns aᵀ.
+func (a dense) transpose() dense {
+	c := newDense(len(a))
+	for i := range a {
+		for j := range a[i] {
+			c[j][i] = a[i][j]
+		}
+	}
+	return c
+}
+
+// maxStep returns the largest α ≤ 1/0.95 with x + α·d ⪰ 0, given x ≻ 0 with
+// inverse Cholesky factor linv.
+func maxStep(linv, d dense) float64 {
+	eigs, _ := linalg.SymmetricEigen(linv.mul(d).mul(linv.transpose()))
+	lmin := math.Inf(1)
+	for _, e := range eigs {
+		lmin = math.Min(lmin, e)
+	}
+	if lmin >= -0.95 {
+		return 1 / 0.95
+	}
+	return -1 / lmin
+}
+
+// solveSPD solves m·x = r for symmetric positive definite m, adding a
+// little diagonal regularization if the factorization fails.
+func solveSPD(m dense, r []float64) ([]float64, bool) {
+	n := len(m)
+	reg := 0.0
+	for attempt := 0; attempt < 8; attempt++ {
+		a := m
+		if reg > 0 {
+			a = m.axpy(reg, identityDense(n, 1))
+		}
+		L, ok := cholesky(a)
+		if ok {
+			// Forward then back substitution.
+			z := make([]float64, n)
+			for i := 0; i < n; i++ {
+				s := r[i]
+				for k := 0; k < i; k++ {
+					s -= L[i][k] * z[k]
+				}
+				z[i] = s / L[i][i]
+			}
+			x := make([]float64, n)
+			for i := n - 1; i >= 0; i-- {
+				s := z[i]
+				for k := i + 1; k < n; k++ {
+					s -= L[k][i] * x[k]
+				}
+				x[i] = s / L[i][i]
+			}
+			return x, true
+		}
+		scale := 0.0
+		for i := range m {
+			scale = math.Max(scale, math.Abs(m[i][i]))
+		}
+		if reg == 0 {
+			reg = 1e-14 * math.Max(scale, 1)
+		} else {
+			reg *= 100
+		}
+	}
+	return nil, false
+}
+
+// embed returns φ(M)/2 for an exact Hermitian matrix, or nil for nil.
+func embed(M *runtime.Matrix) dense {
+	if M == nil {
+		return nil
+	}
+	n := M.Rows
+	e := newDense(2 * n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			q := M.Get(i, j)
+			re, _ := q.Re.Float64()
+			im, _ := q.Im.Float64()
+			e[i][j], e[i][n+j] = re/2, -im/2
+			e[n+i][j], e[n+i][n+j] = im/2, re/2
+		}
+	}
+	return e
+}
+
+// realProblem is the real embedding of a Problem.
+type realProblem struct {
+	sizes []int     // 2n per block
+	c     []dense   // per block; nil is zero
+	a     [][]dense // a[i][j]; nil is zero
+	b     []float64
+}
+
+func embedProblem(p *Problem) *realProblem {
+	rp := &realProblem{
+		sizes: make([]int, len(p.Blocks)),
+		c:     make([]dense, len(p.Blocks)),
+		a:     make([][]dense, len(p.A)),
+		b:     make([]float64, len(p.B)),
+	}
+	for j, n := range p.Blocks {
+		rp.sizes[j] = 2 * n
+		rp.c[j] = embed(p.C[j])
+	}
+	for i, row := range p.A {
+		rp.a[i] = make([]dense, len(row))
+		for j, A := range row {
+			rp.a[i][j] = embed(A)
+		}
+		rp.b[i], _ = p.B[i].Float64()
+	}
+	return rp
+}
+
+// iterate is a float64 primal-dual point.
+type iterate struct {
+	x, z []dense
+	y    []float64
+
+	// path holds the primal iterates in order, ending with x. Earlier
+	// points are further from optimal but also further from the boundary
+	// of the cone, which helps when rounding breaks positivity.
+	path [][]dense
+}
+
+// solveFloat runs the interior-point method and returns the final iterate.
+func solveFloat(p *Problem, opts *Options) (*iterate, error) {
+	rp := embedProblem(p)
+	m, nb := len(rp.b), len(rp.sizes)
+
+	// Scale the starting point with the data.
+	gamma := 1.0
+	for _, bi := range rp.b {
+		gamma = math.Max(gamma, math.Abs(bi))
+	}
+	for _, c := range rp.c {
+		if c != nil {
+			gamma = math.Max(gamma, math.Sqrt(innerDense(c, c)))
+		}
+	}
+	it := &iterate{x: make([]dense, nb), z: make([]dense, nb), y: make([]float64, m)}
+	total := 0
+	for j, n := range rp.sizes {
+		it.x[j] = identityDense(n, gamma)
+		it.z[j] = identityDense(n, gamma)
+		total += n
+	}
+
+	for iter := 0; iter < opts.MaxIterations; iter++ {
+		// Residuals: r_p = b - A(X), R_d = Σ y_i A_i - C - Z.
+		rpv := make([]float64, m)
+		pinf := 0.0
+		for i := 0; i < m; i++ {
+			s := rp.b[i]
+			for j := range rp.sizes {
+				if rp.a[i][j] != nil {
+					s -= innerDense(rp.a[i][j], it.x[j])
+				}
+			}
+			rpv[i] = s
+			pinf = math.Max(pinf, math.Abs(s))
+		}
+		rd := make([]dense, nb)
+		dinf := 0.0
+		gap, pobj, dobj := 0.0, 0.0, 0.0
+		for j, n := range rp.sizes {
+			r := newDense(n).axpy(-1, it.z[j])
+			if rp.c[j] != nil {
+				r = r.axpy(-1, rp.c[j])
+				pobj += innerDense(rp.c[j], it.x[j])
+			}
+			for i := 0; i < m; i++ {
+				if rp.a[i][j] != nil && it.y[i] != 0 {
+					r = r.axpy(it.y[i], rp.a[i][j])
+				}
+			}
+			rd[j] = r
+			for _, row := range r {
+				for _, v := range row {
+					dinf = math.Max(dinf, math.Abs(v))
+				}
+			}
+			gap += innerDense(it.x[j], it.z[j])
+		}
+		for i := 0; i < m; i++ {
+			dobj += rp.b[i] * it.y[i]
+		}
+		if pinf < opts.Tolerance && dinf < opts.Tolerance &&
+			math.Abs(pobj-dobj) < opts.Tolerance*(1+math.Abs(pobj)+math.Abs(dobj)) {
+			return it, nil
+		}
+		mu := gap / float64(total)
+		sigma := 0.1
+		if pinf < 1e-3 && dinf < 1e-3 {
+			sigma = 0.05
+		}
+
+		// Schur complement and right-hand side.
+		zinv := make([]dense, nb)
+		for j := range rp.sizes {
+			L, ok := cholesky(it.z[j])
+			if !ok {
+				return nil, fmt.Errorf("sdp: dual iterate lost definiteness")
+			}
+			li := lowerInverse(L)
+			zinv[j] = li.transpose().mul(li)
+		}
+		xaz := make([][]dense, m) // X A_i Z⁻¹ per block
+		for i := 0; i < m; i++ {
+			xaz[i] = make([]dense, nb)
+			for j := range rp.sizes {
+				if rp.a[i][j] != nil {
+					xaz[i][j] = it.x[j].mul(rp.a[i][j]).mul(zinv[j])
+				}
+			}
+		}
+		schur := newDense(m)
+		for i := 0; i < m; i++ {
+			for k := i; k < m; k++ {
+				s := 0.0
+				for j := range rp.sizes {
+					if rp.a[i][j] != nil && xaz[k][j] != nil {
+						s += innerDense(rp.a[i][j], xaz[k][j])
+					}
+				}
+				schur[i][k], schur[k][i] = s, s
+			}
+		}
+		base := make([]dense, nb) // σμZ⁻¹ - X - X R_d Z⁻¹
+		for j := range rp.sizes {
+			base[j] = zinv[j].scaled(sigma*mu).axpy(-1, it.x[j]).axpy(-1, it.x[j].mul(rd[j]).mul(zinv[j]))
+		}
+		rhs := make([]float64, m)
+		for i := 0; i < m; i++ {
+			s := -rpv[i]
+			for j := range rp.sizes {
+				if rp.a[i][j] != nil {
+					s += innerDense(rp.a[i][j], base[j])
+				}
+			}
+			rhs[i] = s
+		}
+		dy, ok := solveSPD(schur, rhs)
+		if !ok {
+			return nil, fmt.Errorf("sdp: Schur complement is singular")
+		}
+
+		// Directions and step lengths.
+		dx := make([]dense, nb)
+		dz := make([]dense, nb)
+		alphaP, alphaD := 1/0.95, 1/0.95
+		for j := range rp.sizes {
+			d := rd[j]
+			for i := 0; i < m; i++ {
+				if rp.a[i][j] != nil && dy[i] != 0 {
+					d = d.axpy(dy[i], rp.a[i][j])
+				}
+			}
+			dz[j] = d
+			dx[j] = base[j].axpy(-1, it.x[j].mul(d).mul(zinv[j])).symmetrize()
+
+			Lx, ok := cholesky(it.x[j])
+			if !ok {
+				return nil, fmt.Errorf("sdp: primal iterate lost definiteness")
+			}
+			alphaP = math.Min(alphaP, maxStep(lowerInverse(Lx), dx[j]))
+			Lz, _ := cholesky(it.z[j])
+			alphaD = math.Min(alphaD, maxStep(lowerInverse(Lz), dz[j]))
+		}
+		alphaP *= 0.95
+		alphaD *= 0.95
+		x := make([]dense, nb)
+		for j := range rp.sizes {
+			x[j] = it.x[j].axpy(alphaP, dx[j])
+			it.z[j] = it.z[j].axpy(alphaD, dz[j])
+		}
+		it.x = x
+		it.path = append(it.path, x)
+		for i := range it.y {
+			it.y[i] += alphaD * dy[i]
+		}
+	}
+	return it, fmt.Errorf("sdp: no convergence in %d iterations", opts.MaxIterations)
+}
+
+// complexBlock reads a complex Hermitian matrix back from a real block,
+// averaging it into the image of the embedding.
+func complexBlock(x dense) [][]complex128 {
+	n := len(x) / 2
+	out := make([][]complex128, n)
+	for k := 0; k < n; k++ {
+		out[k] = make([]complex128, n)
+		for l := 0; l < n; l++ {
+			re := (x[k][l] + x[n+k][n+l]) / 2
+			im := (x[n+k][l] - x[k][n+l]) / 2
+			out[k][l] = complex(re, im)
+		}
+	}
+	return out
+}
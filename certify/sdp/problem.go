@@ -0,0 +1,315 @@
+// problem.go defines semidefinite programs in standard form.
+//
+// Primal and dual are
+//
+//	maximize   Σ_j ⟨C_j, X_j⟩   s.t. Σ_j ⟨A_ij, X_j⟩ = b_i,  X_j ⪰ 0
+//	minimize   b·y              s.t. S_j = Σ_i y_i A_ij - C_j ⪰ 0
+//
+// with ⟨A, X⟩ = Re Tr(A X) on Hermitian matrices. Weak duality gives
+// ⟨C, X⟩ ≤ b·y for every feasible pair, which is all a certificate needs.
+package sdp
+
+import (
+	"fmt"
+	"math/big"
+
+	"qbtm/certify/linalg"
+	"qbtm/runtime"
+)
+
+// Problem is a semidefinite program over block-diagonal Hermitian X.
+type Problem struct {
+	Blocks []int               // size of each diagonal block
+	C      []*runtime.Matrix   // objective, one matrix per block
+	A      [][]*runtime.Matrix // A[i][j]: constraint i on block j; nil means zero
+	B      []*big.Rat          // right-hand sides
+
+	// Identity optionally gives weights with Σ_i Identity_i A_ij = I in
+	// every block, which proves Σ_j Tr X_j = b·Identity for every feasible
+	// X. A bounded trace lets a slightly infeasible dual point be repaired.
+	Identity []*big.Rat
+}
+
+// Validate checks shapes and Hermiticity.
+func (p *Problem) Validate() error {
+	if p == nil || len(p.Blocks) == 0 {
+		return fmt.Errorf("sdp: problem has no blocks")
+	}
+	if len(p.C) != len(p.Blocks) {
+		return fmt.Errorf("sdp: %d objective blocks for %d blocks", len(p.C), len(p.Blocks))
+	}
+	if len(p.A) != len(p.B) {
+		return fmt.Errorf("sdp: %d constraints with %d right-hand sides", len(p.A), len(p.B))
+	}
+	if p.Identity != nil && len(p.Identity) != len(p.A) {
+		return fmt.Errorf("sdp: %d identity weights for %d constraints", len(p.Identity), len(p.A))
+	}
+	check := func(M *runtime.Matrix, n int, what string) error {
+		if M == nil {
+			return nil
+		}
+		if M.Rows != n || M.Cols != n {
+			return fmt.Errorf("sdp: %s is %dx%d, want %dx%d", what, M.Rows, M.Cols, n, n)
+		}
+		if !linalg.IsHermitian(M) {
+			return fmt.Errorf("sdp: %s is not Hermitian", what)
+		}
+		return nil
+	}
+	for j, n := range p.Blocks {
+		if n <= 0 {
+			return fmt.Errorf("sdp: block %d has size %d", j, n)
+		}
+		if err := check(p.C[j], n, fmt.Sprintf("objective block %d", j)); err != nil {
+			return err
+		}
+	}
+	for i, row := range p.A {
+		if len(row) != len(p.Blocks) {
+			return fmt.Errorf("sdp: constraint %d has %d blocks, want %d", i, len(row), len(p.Blocks))
+		}
+		if p.B[i] == nil {
+			return fmt.Errorf("sdp: constraint %d has no right-hand side", i)
+		}
+		for j, M := range row {
+			if err := check(M, p.Blocks[j], fmt.Sprintf("constraint %d block %d", i, j)); err != nil {
+				return err
+			}
+		}
+	}
+	if p.Identity != nil && !p.identityHolds() {
+		return fmt.Errorf("sdp: identity weights do not sum to the identity")
+	}
+	return nil
+}
+
+// identityHolds checks Σ_i Identity_i A_ij = I in every block.
+func (p *Problem) identityHolds() bool {
+	for j, n := range p.Blocks {
+		sum := runtime.NewMatrix(n, n)
+		for i, w := range p.Identity {
+			if p.A[i][j] != nil && w != nil && w.Sign() != 0 {
+				sum = runtime.MatAdd(sum, runtime.MatScale(p.A[i][j], w))
+			}
+		}
+		if !runtime.MatrixEqual(sum, runtime.Identity(n)) {
+			return false
+		}
+	}
+	return true
+}
+
+// TraceBound returns b·Identity, the common trace of every feasible X, or
+// nil if the problem gives no identity weights.
+func (p *Problem) TraceBound() *big.Rat {
+	if p.Identity == nil {
+		return nil
+	}
+	return dot(p.B, p.Identity)
+}
+
+// inner returns ⟨A, X⟩ = Re Tr(A X) exactly; a nil A is zero.
+func inner(A, X *runtime.Matrix) *big.Rat {
+	sum := new(big.Rat)
+	if A == nil || X == nil {
+		return sum
+	}
+	for k := 0; k < A.Rows; k++ {
+		for l := 0; l < A.Cols; l++ {
+			a, x := A.Get(k, l), X.Get(l, k)
+			sum.Add(sum, new(big.Rat).Mul(a.Re, x.Re))
+			sum.Sub(sum, new(big.Rat).Mul(a.Im, x.Im))
+		}
+	}
+	return sum
+}
+
+// dot returns Σ a_i b_i.
+func dot(a, b []*big.Rat) *big.Rat {
+	sum := new(big.Rat)
+	for i := range a {
+		sum.Add(sum, new(big.Rat).Mul(a[i], b[i]))
+	}
+	return sum
+}
+
+// objective returns Σ_j ⟨C_j, X_j⟩.
+func (p *Problem) objective(X []*runtime.Matrix) *big.Rat {
+	sum := new(big.Rat)
+	for j := range p.Blocks {
+		sum.Add(sum, inner(p.C[j], X[j]))
+	}
+	return sum
+}
+
+// constraint returns Σ_j ⟨A_ij, X_j⟩.
+func (p *Problem) constraint(i int, X []*runtime.Matrix) *big.Rat {
+	sum := new(big.Rat)
+	for j := range p.Blocks {
+		sum.Add(sum, inner(p.A[i][j], X[j]))
+	}
+	return sum
+}
+
+// slack returns S_j = Σ_i y_i A_ij - C_j + shift·I for every block.
+func (p *Problem) slack(y []*big.Rat, shift *big.Rat) []*runtime.Matrix {
+	S := make([]*runtime.Matrix, len(p.Blocks))
+	for j, n := range p.Blocks {
+		s := runtime.NewMatrix(n, n)
+		for i := range p.A {
+			if p.A[i][j] != nil && y[i].Sign() != 0 {
+				s = runtime.MatAdd(s, runtime.MatScale(p.A[i][j], y[i]))
+			}
+		}
+		if p.C[j] != nil {
+			s = runtime.MatSub(s, p.C[j])
+		}
+		if shift != nil && shift.Sign() != 0 {
+			s = runtime.MatAdd(s, runtime.MatScale(runtime.Identity(n), shift))
+		}
+		S[j] = s
+	}
+	return S
+}
+
+// matrixOrNil encodes a possibly nil matrix.
+func matrixOrNil(M *runtime.Matrix) runtime.Value {
+	if M == nil {
+		return runtime.MakeNil()
+	}
+	return runtime.MatrixToValue(M)
+}
+
+// matrixFromValueOrNil decodes a possibly nil matrix.
+func matrixFromValueOrNil(v runtime.Value) (*runtime.Matrix, bool) {
+	if _, ok := v.(runtime.Nil); ok {
+		return nil, true
+	}
+	return runtime.MatrixFromValue(v)
+}
+
+// ratsToValue encodes a rational vector.
+func ratsToValue(rs []*big.Rat) runtime.Value {
+	items := make([]runtime.Value, len(rs))
+	for i, r := range rs {
+		items[i] = runtime.MakeBigRat(r)
+	}
+	return runtime.MakeSeq(items...)
+}
+
+// ratsFromValue decodes a rational vector.
+func ratsFromValue(v runtime.Value) ([]*big.Rat, bool) {
+	seq, ok := v.(runtime.Seq)
+	if !ok {
+		return nil, false
+	}
+	rs := make([]*big.Rat, len(seq.Items))
+	for i, item := range seq.Items {
+		r, ok := item.(runtime.Rat)
+		if !ok {
+			return nil, false
+		}
+		rs[i] = new(big.Rat).Set(r.V)
+	}
+	return rs, true
+}
+
+// ToValue encodes the problem as
+// Tag("sdp-problem", Seq(blocks, C, A, b, identity-or-Nil)).
+func (p *Problem) ToValue() runtime.Value {
+	blocks := make([]runtime.Value, len(p.Blocks))
+	for j, n := range p.Blocks {
+		blocks[j] = runtime.MakeInt(int64(n))
+	}
+	cs := make([]runtime.Value, len(p.C))
+	for j, C := range p.C {
+		cs[j] = matrixOrNil(C)
+	}
+	as := make([]runtime.Value, len(p.A))
+	for i, row := range p.A {
+		items := make([]runtime.Value, len(row))
+		for j, A := range row {
+			items[j] = matrixOrNil(A)
+		}
+		as[i] = runtime.MakeSeq(items...)
+	}
+	var identity runtime.Value = runtime.MakeNil()
+	if p.Identity != nil {
+		identity = ratsToValue(p.Identity)
+	}
+	return runtime.MakeTag(
+		runtime.MakeText("sdp-problem"),
+		runtime.MakeSeq(
+			runtime.MakeSeq(blocks...),
+			runtime.MakeSeq(cs...),
+			runtime.MakeSeq(as...),
+			ratsToValue(p.B),
+			identity,
+		),
+	)
+}
+
+// ProblemFromValue decodes and validates an sdp-problem Value.
+func ProblemFromValue(v runtime.Value) (*Problem, bool) {
+	tag, ok := v.(runtime.Tag)
+	if !ok {
+		return nil, false
+	}
+	label, ok := tag.Label.(runtime.Text)
+	if !ok || label.V != "sdp-problem" {
+		return nil, false
+	}
+	seq, ok := tag.Payload.(runtime.Seq)
+	if !ok || len(seq.Items) != 5 {
+		return nil, false
+	}
+	blocks, ok1 := seq.Items[0].(runtime.Seq)
+	cs, ok2 := seq.Items[1].(runtime.Seq)
+	as, ok3 := seq.Items[2].(runtime.Seq)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, false
+	}
+
+	p := &Problem{}
+	for _, item := range blocks.Items {
+		n, ok := item.(runtime.Int)
+		if !ok || !n.V.IsInt64() {
+			return nil, false
+		}
+		p.Blocks = append(p.Blocks, int(n.V.Int64()))
+	}
+	for _, item := range cs.Items {
+		C, ok := matrixFromValueOrNil(item)
+		if !ok {
+			return nil, false
+		}
+		p.C = append(p.C, C)
+	}
+	for _, item := range as.Items {
+		row, ok := item.(runtime.Seq)
+		if !ok {
+			return nil, false
+		}
+		var mats []*runtime.Matrix
+		for _, m := range row.Items {
+			A, ok := matrixFromValueOrNil(m)
+			if !ok {
+				return nil, false
+			}
+			mats = append(mats, A)
+		}
+		p.A = append(p.A, mats)
+	}
+	if p.B, ok = ratsFromValue(seq.Items[3]); !ok {
+		return nil, false
+	}
+	if _, isNil := seq.Items[4].(runtime.Nil); !isNil {
+		if p.Identity, ok = ratsFromValue(seq.Items[4]); !ok {
+			return nil, false
+		}
+	}
+	if p.Validate() != nil {
+		return nil, false
+	}
+	return p, true
+}
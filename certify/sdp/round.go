@@ -0,0 +1,230 @@
+// round.go turns a floating-point solution into exact bounds.
+//
+// The primal iterate is rounded to a dyadic Hermitian matrix per block and
+// then moved back onto the affine constraints exactly: with the Gram matrix
+// G_ik = Σ_j ⟨A_ij, A_kj⟩ and residual r_i = Σ_j ⟨A_ij, X_j⟩ - b_i, the
+// point X - Σ z_i A_i with G z = r satisfies every constraint. The
+// correction is as small as the solver's residual, so an interior iterate
+// stays PSD; when the last iterate is too close to the boundary, earlier
+// ones along the solver's path are tried.
+//
+// The dual multipliers are rounded directly. When S is not quite PSD and
+// Tr X is fixed by the constraints, adding ε·I to every block costs
+// ε·Tr X in the bound and restores positivity.
+package sdp
+
+import (
+	"fmt"
+	"math/big"
+
+	"qbtm/certify/linalg"
+	"qbtm/runtime"
+)
+
+// Options controls the floating-point solver and the rounding.
+type Options struct {
+	Tolerance     float64 // stop when residuals and gap fall below this
+	MaxIterations int     // iteration limit for the interior-point method
+	Bits          uint    // round to multiples of 2^-Bits
+}
+
+// DefaultOptions returns the settings used by Solve when opts is nil.
+func DefaultOptions() *Options {
+	return &Options{Tolerance: 1e-10, MaxIterations: 100, Bits: 40}
+}
+
+// maxShiftDoublings bounds the search for a dual shift.
+const maxShiftDoublings = 96
+
+// maxPrimalRetries bounds how many primal iterates, walking back from the
+// last, are rounded before the primal side is given up.
+const maxPrimalRetries = 32
+
+// Solve solves p numerically and returns a certificate for whatever bounds
+// can be proven exactly. Either side of the certificate may be missing if
+// rounding could not be repaired; an error means p is malformed or the
+// floating-point solver broke down.
+func Solve(p *Problem, opts *Options) (*Certificate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	// A solver that stalls near the optimum still yields usable points, so
+	// non-convergence only matters if nothing can be certified.
+	it, err := solveFloat(p, opts)
+	if it == nil {
+		return nil, err
+	}
+
+	cert := &Certificate{Problem: p}
+	for k := len(it.path) - 1; k >= 0 && k >= len(it.path)-maxPrimalRetries; k-- {
+		if cert.X, cert.XCerts, cert.Lower = roundPrimal(p, it.path[k], opts.Bits); cert.X != nil {
+			break
+		}
+	}
+	cert.Y, cert.Shift, cert.SCerts, cert.Upper = roundDual(p, it, opts.Bits)
+	if cert.X == nil && cert.Y == nil {
+		if err == nil {
+			err = fmt.Errorf("sdp: rounded solution could not be certified")
+		}
+		return nil, err
+	}
+	return cert, nil
+}
+
+// roundPrimal rounds and projects a primal iterate, returning nils if a
+// block is not PSD afterwards or the constraints cannot be met.
+func roundPrimal(p *Problem, x []dense, bits uint) ([]*runtime.Matrix, []*linalg.LDLCertificate, *big.Rat) {
+	X := make([]*runtime.Matrix, len(p.Blocks))
+	for j := range p.Blocks {
+		X[j] = linalg.RoundHermitian(complexBlock(x[j]), bits)
+	}
+
+	m := len(p.A)
+	G := make([][]*big.Rat, m)
+	r := make([]*big.Rat, m)
+	for i := 0; i < m; i++ {
+		G[i] = make([]*big.Rat, m)
+		for k := 0; k < m; k++ {
+			g := new(big.Rat)
+			for j := range p.Blocks {
+				g.Add(g, inner(p.A[i][j], p.A[k][j]))
+			}
+			G[i][k] = g
+		}
+		r[i] = new(big.Rat).Sub(p.constraint(i, X), p.B[i])
+	}
+	z, ok := solveExact(G, r)
+	if !ok {
+		return nil, nil, nil
+	}
+	for j := range p.Blocks {
+		for i := 0; i < m; i++ {
+			if p.A[i][j] != nil && z[i].Sign() != 0 {
+				X[j] = runtime.MatSub(X[j], runtime.MatScale(p.A[i][j], z[i]))
+			}
+		}
+	}
+
+	certs := make([]*linalg.LDLCertificate, len(p.Blocks))
+	for j := range p.Blocks {
+		c, _, err := linalg.CertifyPSD(X[j])
+		if err != nil || c == nil {
+			return nil, nil, nil
+		}
+		certs[j] = c
+	}
+	return X, certs, p.objective(X)
+}
+
+// roundDual rounds the dual multipliers and finds the smallest dyadic shift
+// that makes every slack block PSD, returning nils if none is found.
+func roundDual(p *Problem, it *iterate, bits uint) ([]*big.Rat, *big.Rat, []*linalg.LDLCertificate, *big.Rat) {
+	y := make([]*big.Rat, len(p.A))
+	for i := range y {
+		y[i] = roundFloat(it.y[i], bits)
+	}
+	trace := p.TraceBound()
+
+	shift := new(big.Rat)
+	for attempt := 0; attempt <= maxShiftDoublings; attempt++ {
+		if certs := slackCerts(p, y, shift); certs != nil {
+			upper := dot(p.B, y)
+			if shift.Sign() != 0 {
+				upper.Add(upper, new(big.Rat).Mul(shift, trace))
+			}
+			return y, shift, certs, upper
+		}
+		if trace == nil {
+			break
+		}
+		if shift.Sign() == 0 {
+			shift = new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), bits))
+		} else {
+			shift = new(big.Rat).Add(shift, shift)
+		}
+	}
+	return nil, nil, nil, nil
+}
+
+// slackCerts certifies every block of S(y) + shift·I, or returns nil.
+func slackCerts(p *Problem, y []*big.Rat, shift *big.Rat) []*linalg.LDLCertificate {
+	S := p.slack(y, shift)
+	certs := make([]*linalg.LDLCertificate, len(S))
+	for j, s := range S {
+		c, _, err := linalg.CertifyPSD(s)
+		if err != nil || c == nil {
+			return nil
+		}
+		certs[j] = c
+	}
+	return certs
+}
+
+// roundFloat returns the multiple of 2^-bits nearest to x.
+func roundFloat(x float64, bits uint) *big.Rat {
+	M := linalg.RoundHermitian([][]complex128{{complex(x, 0)}}, bits)
+	return new(big.Rat).Set(M.Get(0, 0).Re)
+}
+
+// solveExact finds some solution of the symmetric system G z = r by
+// Gauss-Jordan elimination, setting free variables to zero. It reports
+// false if the system is inconsistent.
+func solveExact(G [][]*big.Rat, r []*big.Rat) ([]*big.Rat, bool) {
+	m := len(G)
+	a := make([][]*big.Rat, m)
+	for i := range a {
+		a[i] = make([]*big.Rat, m+1)
+		for k := 0; k < m; k++ {
+			a[i][k] = new(big.Rat).Set(G[i][k])
+		}
+		a[i][m] = new(big.Rat).Set(r[i])
+	}
+
+	pivots := make([]int, 0, m) // pivot column of each reduced row
+	row := 0
+	for col := 0; col < m && row < m; col++ {
+		p := -1
+		for i := row; i < m; i++ {
+			if a[i][col].Sign() != 0 {
+				p = i
+				break
+			}
+		}
+		if p < 0 {
+			continue
+		}
+		a[row], a[p] = a[p], a[row]
+		inv := new(big.Rat).Inv(a[row][col])
+		for k := col; k <= m; k++ {
+			a[row][k].Mul(a[row][k], inv)
+		}
+		for i := 0; i < m; i++ {
+			if i == row || a[i][col].Sign() == 0 {
+				continue
+			}
+			f := new(big.Rat).Set(a[i][col])
+			for k := col; k <= m; k++ {
+				a[i][k].Sub(a[i][k], new(big.Rat).Mul(f, a[row][k]))
+			}
+		}
+		pivots = append(pivots, col)
+		row++
+	}
+	for i := row; i < m; i++ {
+		if a[i][m].Sign() != 0 {
+			return nil, false
+		}
+	}
+
+	z := make([]*big.Rat, m)
+	for i := range z {
+		z[i] = new(big.Rat)
+	}
+	for i, col := range pivots {
+		z[col].Set(a[i][m])
+	}
+	return z, true
+}
@@ -45,6 +45,11 @@ func main() {
 			continue
 		}
 		fmt.Printf("  %s: %s\n", name, hex.EncodeToString(qgid[:8]))
+
+		// Type check before the circuit is emitted
+		for _, d := range runtime.TypeCheck(store, qgid) {
+			fmt.Printf("    Warning: %s\n", d)
+		}
 	}
 
 	// 3. Register attack library
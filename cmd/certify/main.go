@@ -15,22 +15,29 @@
 //	full-analysis  Run complete certification
 //	list           List available protocols
 //	info           Show protocol information
+//	emit           Emit a certified model (signed with --sign-key)
+//	check          Load a certificate, checking its signature with --trust or $QBTM_TRUST
 //
 // Examples:
 //
 //	certify synth BB84
 //	certify security --attack=coherent BB84
 //	certify full-analysis --output=cert.qmb BB84
+//	certify --format=qmb --sign-key=alice.key -o bb84.qmb emit BB84
+//	certify --trust=trusted.keys check bb84.qmb
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"math/big"
 	"os"
 	"strings"
+	"time"
 
 	"qbtm/certify"
+	"qbtm/runtime"
 )
 
 const (
@@ -50,6 +57,8 @@ Commands:
   full-analysis  Run complete certification pipeline
   list           List all available protocols
   info           Show detailed protocol information
+  emit           Emit a certified model (.qmb with --format=qmb)
+  check          Load a .qmb certificate and re-verify its evidence
 
 Options:
   -h, --help        Show this help message
@@ -61,6 +70,9 @@ Options:
   --error-rate      QBER for analysis (e.g., "1/100" for 1%)
   --self-verify     Verify correctness before emitting (default: true)
   --verbose         Include detailed derivations
+  --sign-key        Sign .qmb output with this key (from qbtm keygen)
+  --trust           Trust store; check requires a signature from one of its keys
+                    (default: $QBTM_TRUST, then ~/.config/qbtm/trusted.keys)
 
 Examples:
   certify synth BB84
@@ -71,6 +83,8 @@ Examples:
   certify full-analysis --output=bb84_cert.qmb BB84
   certify list
   certify info BB84
+  certify --format=qmb --sign-key=alice.key -o bb84.qmb emit BB84
+  certify --trust=trusted.keys check bb84.qmb
 
 Supported Protocols:
   QKD:            BB84, E91, B92, Six-State, SARG04
@@ -94,6 +108,8 @@ func main() {
 	errorRateFlag := flag.String("error-rate", "0", "QBER for analysis (e.g., '1/100')")
 	selfVerifyFlag := flag.Bool("self-verify", true, "Verify correctness before emitting")
 	verboseFlag := flag.Bool("verbose", false, "Include detailed derivations")
+	signKeyFlag := flag.String("sign-key", "", "Sign .qmb output with this key file")
+	trustFlag := flag.String("trust", "", "Trust store file for check")
 
 	flag.Usage = func() {
 		fmt.Print(usage)
@@ -179,7 +195,7 @@ func main() {
 	case "compose":
 		result, err = certify.DispatchWithOptions(certify.CmdCompose, cmdArgs, opts)
 	case "full-analysis":
-		err = cmdFullAnalysis(cmdArgs, output, *attackFlag, *noiseFlag, *formatFlag, errorRate, *selfVerifyFlag, *verboseFlag)
+		err = cmdFullAnalysis(cmdArgs, output, *attackFlag, *noiseFlag, *formatFlag, errorRate, *selfVerifyFlag, *verboseFlag, *signKeyFlag)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -190,7 +206,14 @@ func main() {
 	case "info":
 		result, err = certify.DispatchWithOptions(certify.CmdInfo, cmdArgs, opts)
 	case "emit":
-		err = cmdEmit(cmdArgs, output, format, *selfVerifyFlag)
+		err = cmdEmit(cmdArgs, output, format, *selfVerifyFlag, *signKeyFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "check":
+		err = cmdCheck(cmdArgs, *trustFlag)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -223,7 +246,7 @@ func main() {
 }
 
 // cmdFullAnalysis runs the complete certification pipeline.
-func cmdFullAnalysis(args []string, output, attack, noise, formatStr string, errorRate *big.Rat, selfVerify, verbose bool) error {
+func cmdFullAnalysis(args []string, output, attack, noise, formatStr string, errorRate *big.Rat, selfVerify, verbose bool, signKey string) error {
 	if len(args) < 1 {
 		return fmt.Errorf("full-analysis requires a protocol name")
 	}
@@ -248,6 +271,10 @@ func cmdFullAnalysis(args []string, output, attack, noise, formatStr string, err
 
 	// If output is specified and format is qmb, emit as .qmb file
 	if output != "" && format == certify.FormatQMB {
+		key, err := loadSigningKey(signKey)
+		if err != nil {
+			return err
+		}
 		emitOpts := &certify.EmitOptions{
 			Format:             format,
 			ComputeChoi:        true,
@@ -261,6 +288,7 @@ func cmdFullAnalysis(args []string, output, attack, noise, formatStr string, err
 			ErrorRate:          errorRate,
 			AdversaryModel:     attack,
 			NoiseModels:        []string{noise, "amplitude_damping", "phase_damping"},
+			SigningKey:         key,
 		}
 		return certify.EmitModel(protocolName, output, emitOpts)
 	}
@@ -270,12 +298,17 @@ func cmdFullAnalysis(args []string, output, attack, noise, formatStr string, err
 }
 
 // cmdEmit emits a certified model to a file.
-func cmdEmit(args []string, output string, format certify.OutputFormat, selfVerify bool) error {
+func cmdEmit(args []string, output string, format certify.OutputFormat, selfVerify bool, signKey string) error {
 	if len(args) < 1 {
 		return fmt.Errorf("emit requires a protocol name")
 	}
 	protocolName := args[0]
 
+	key, err := loadSigningKey(signKey)
+	if err != nil {
+		return err
+	}
+
 	if output == "" {
 		output = protocolName + ".qmb"
 	}
@@ -293,9 +326,10 @@ func cmdEmit(args []string, output string, format certify.OutputFormat, selfVeri
 		ErrorRate:          nil,
 		AdversaryModel:     "coherent",
 		NoiseModels:        []string{"depolarizing"},
+		SigningKey:         key,
 	}
 
-	err := certify.EmitModel(protocolName, output, opts)
+	err = certify.EmitModel(protocolName, output, opts)
 	if err != nil {
 		return err
 	}
@@ -303,3 +337,69 @@ func cmdEmit(args []string, output string, format certify.OutputFormat, selfVeri
 	fmt.Printf("Emitted certified model to: %s\n", output)
 	return nil
 }
+
+// cmdCheck loads a .qmb certificate bundle or certified model, requiring
+// a trusted signature if a trust store is given or configured (see
+// certify.DefaultTrustStore), and re-verifies bundle evidence.
+func cmdCheck(args []string, trustFile string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("check requires a .qmb file")
+	}
+	path := args[0]
+
+	var trust *runtime.TrustStore
+	var err error
+	if trustFile != "" {
+		trust, err = runtime.LoadTrustStore(trustFile)
+	} else {
+		trust, err = certify.DefaultTrustStore()
+	}
+	if err != nil {
+		return err
+	}
+	if trust == nil {
+		fmt.Fprintf(os.Stderr, "Warning: no trust store (--trust or $%s); signature not verified\n", certify.TrustEnv)
+	}
+
+	bundle, sig, err := certify.LoadBundleTrusted(path, trust)
+	if err == nil {
+		if trust != nil {
+			fmt.Printf("Signed by %s (key %x) at %s\n", sig.Signer, sig.KeyID, sig.Time.Format(time.RFC3339))
+		}
+		ok, msg := certify.VerifyLoadedBundle(bundle)
+		fmt.Printf("Bundle for %s: secure=%v, %s\n", bundle.Protocol, bundle.IsSecure, msg)
+		if !ok {
+			return fmt.Errorf("bundle evidence failed verification")
+		}
+		return nil
+	}
+	if errors.Is(err, runtime.ErrUnsigned) || errors.Is(err, runtime.ErrUntrusted) {
+		return err
+	}
+
+	model, sig, err := certify.LoadModelTrusted(path, trust)
+	if err != nil {
+		return err
+	}
+	if trust != nil {
+		fmt.Printf("Signed by %s (key %x) at %s\n", sig.Signer, sig.KeyID, sig.Time.Format(time.RFC3339))
+	}
+	fmt.Printf("Model for %s: secure=%v\n", model.Protocol.Name, model.IsSecure())
+	return nil
+}
+
+// loadSigningKey reads a signing key file, or returns nil for "".
+func loadSigningKey(path string) (*runtime.SigningKey, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	key, err := runtime.ParseSigningKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
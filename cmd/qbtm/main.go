@@ -6,11 +6,15 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"qbtm/runtime"
 )
@@ -42,6 +46,18 @@ func main() {
 		err = synthesizeGate(args)
 	case "verify":
 		err = verifyFixpoint(args)
+	case "check":
+		err = checkQMB(args)
+	case "gc":
+		err = gcQMB(args)
+	case "link":
+		err = linkQMB(args)
+	case "keygen":
+		err = keygen(args)
+	case "sign":
+		err = signQMB(args)
+	case "verify-sig":
+		err = verifySig(args)
 	case "info":
 		err = showInfo(args)
 	default:
@@ -71,6 +87,14 @@ COMMANDS:
     bootstrap                   Demonstrate the self-reproducing fixpoint
     synthesize <gate>            Synthesize a gate circuit and emit .qmb
     verify <a.qmb> <b.qmb>     Verify two binaries are identical (fixpoint check)
+    check <file.qmb>            Type check every circuit in a .qmb binary
+    gc <in.qmb> -o <out.qmb>    Drop store entries the entrypoint does not reach
+    link <in.qmb> <lib.qmb>... -o <out.qmb>
+                                Inline circuits referenced from other binaries
+    keygen <name>               Write an Ed25519 signing key and its trust store line
+    sign <in.qmb> --key <k>     Sign a binary (embedded, or detached with --detached)
+    verify-sig <in.qmb> --trust <file>
+                                Check a binary's signatures against trusted keys
     info                        Show runtime architecture information
 
 GATES (for synthesize):
@@ -79,6 +103,21 @@ GATES (for synthesize):
 
 OPTIONS:
     -o <file>       Output file for synthesize (default: stdout summary)
+    --format <f>    v1 (default) or v2: sectioned, indexed and checksummed .qmb (synthesize)
+    --statevector   Run pure inputs on kets through unitary subcircuits (run)
+    --backend <b>   exact (default) or interval: float64 enclosures with rigorous bounds (run)
+    --cache-dir <d> Memoize circuit executions in directory d across runs (run)
+    --timeout <t>   Stop execution after duration t, e.g. 30s (run)
+    --max-dim <n>   Reject any input or codomain of dimension above n (run)
+    --max-visits <n> Stop after n circuit node executions (run)
+    --max-depth <n> Reject circuits nested more than n levels deep (run)
+    --workers <n>   Evaluate independent children on n goroutines (run; default: all CPUs)
+    --lib <dir>     Resolve external references from the .qmb files in dir (run, link)
+    --signer <s>    Signer identity recorded in the key (keygen; default: name)
+    --key <file>    Signing key written by keygen (sign)
+    --detached <f>  Write the signature to f instead of embedding it (sign)
+    --sig <file>    Also check a detached signature file (verify-sig)
+    --trust <file>  Trust store: one "<hex public key> <signer>" per line (verify-sig)
     --help, -h      Show this help message
     --version, -v   Show version information
 
@@ -88,6 +127,13 @@ EXAMPLES:
     qbtm run hadamard.qmb
     qbtm inspect examples/qbtm_generator_v3.qmb
     qbtm verify v2.qmb v3.qmb
+    qbtm check qbtm_certify.qmb
+    qbtm gc synth.qmb -o synth.min.qmb
+    qbtm link model.qmb bell.qmb -o model.linked.qmb
+    qbtm run --lib ./lib model.qmb
+    qbtm keygen alice && cat alice.pub >> trusted.keys
+    qbtm sign cert.qmb --key alice.key -o cert.qmb
+    qbtm verify-sig cert.qmb --trust trusted.keys
 
 LICENSE:
     AGPL-3.0 - See LICENSE file for details
@@ -103,16 +149,73 @@ func printVersion() {
 
 // runQMB executes a .qmb binary and displays the result.
 func runQMB(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: qbtm run <file.qmb>")
+	var opts []runtime.RunnerOption
+	var files []string
+	var cache *runtime.ExecCache
+	var limits runtime.Limits
+	var timeout time.Duration
+	backend := runtime.BackendExact
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--timeout" && i+1 < len(args):
+			d, err := time.ParseDuration(args[i+1])
+			if err != nil {
+				return fmt.Errorf("--timeout: %w", err)
+			}
+			timeout = d
+			i++
+		case (args[i] == "--max-dim" || args[i] == "--max-visits" || args[i] == "--max-depth" || args[i] == "--workers") && i+1 < len(args):
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n < 0 {
+				return fmt.Errorf("%s: want a non-negative integer, got %q", args[i], args[i+1])
+			}
+			switch args[i] {
+			case "--max-dim":
+				limits.MaxDim = int(n)
+			case "--max-visits":
+				limits.MaxVisits = n
+			case "--max-depth":
+				limits.MaxDepth = int(n)
+			case "--workers":
+				limits.Workers = int(n)
+			}
+			i++
+		case args[i] == "--statevector":
+			opts = append(opts, runtime.WithStatevector())
+		case args[i] == "--backend" && i+1 < len(args):
+			b, err := runtime.ParseBackend(args[i+1])
+			if err != nil {
+				return err
+			}
+			backend = b
+			opts = append(opts, runtime.WithBackend(b))
+			i++
+		case args[i] == "--cache-dir" && i+1 < len(args):
+			c, err := runtime.NewExecCache(runtime.CacheConfig{Dir: args[i+1]})
+			if err != nil {
+				return err
+			}
+			cache = c
+			opts = append(opts, runtime.WithCache(c))
+			i++
+		case args[i] == "--lib" && i+1 < len(args):
+			opts = append(opts, runtime.WithResolver(runtime.DirResolver(args[i+1])))
+			i++
+		default:
+			files = append(files, args[i])
+		}
 	}
+	if len(files) < 1 {
+		return fmt.Errorf("usage: qbtm run [--statevector] [--backend exact|interval] [--cache-dir <dir>] [--timeout <t>] [--max-dim <n>] [--max-visits <n>] [--max-depth <n>] [--workers <n>] [--lib <dir>] <file.qmb>")
+	}
+	opts = append(opts, runtime.WithLimits(limits))
 
-	data, err := os.ReadFile(args[0])
+	data, err := os.ReadFile(files[0])
 	if err != nil {
 		return fmt.Errorf("read failed: %w", err)
 	}
 
-	runner, err := runtime.NewRunner(data)
+	runner, err := runtime.NewRunner(data, opts...)
 	if err != nil {
 		return fmt.Errorf("load failed: %w", err)
 	}
@@ -129,7 +232,32 @@ func runQMB(args []string) error {
 
 	// Execute with identity input of the right dimension
 	input := runtime.Identity(dim)
-	result, err := runner.Run(input)
+	if backend == runtime.BackendInterval {
+		result, err := runner.RunInterval(input)
+		if err != nil {
+			return fmt.Errorf("execution failed: %w", err)
+		}
+		fmt.Println()
+		printIntervalMatrix("Output", result)
+		return nil
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result, err := runner.RunContext(ctx, input)
+	if errors.Is(err, runtime.ErrNotQI) {
+		// The output has a √2 part; print it exactly over Q(ζ8).
+		exact, err := runner.RunZ8(input)
+		if err != nil {
+			return fmt.Errorf("execution failed: %w", err)
+		}
+		fmt.Println()
+		printMatrixZ8("Output", exact)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("execution failed: %w", err)
 	}
@@ -137,6 +265,15 @@ func runQMB(args []string) error {
 	fmt.Println()
 	printMatrix("Output", result)
 
+	if cache != nil {
+		s := cache.Stats()
+		fmt.Printf("\nCache: %d hits, %d disk hits, %d misses, %d entries (%d bytes)\n",
+			s.Hits, s.DiskHits, s.Misses, s.Entries, s.Bytes)
+		if err := cache.WriteErr(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %d results not written to the cache directory: %v\n", s.WriteErrors, err)
+		}
+	}
+
 	return nil
 }
 
@@ -164,8 +301,24 @@ func inspectQMB(args []string) error {
 	fmt.Printf("  Version:     %s\n", binary.Version)
 	fmt.Printf("  Entrypoint:  %s\n", hex.EncodeToString(binary.Entrypoint[:]))
 	fmt.Printf("  SHA-256:     %s\n", hex.EncodeToString(hash[:]))
-	fmt.Printf("  Store:       %d bytes\n", len(binary.StoreData))
+	if binary.Sections != nil {
+		fmt.Printf("  Format:      v2, %d indexed entries\n", len(binary.EntryIDs()))
+		for _, s := range binary.Sections {
+			fmt.Printf("    %-10s  %8d bytes at %-8d SHA-256: %s\n",
+				s.Kind, s.Length, s.Offset, hex.EncodeToString(s.Sum[:8]))
+		}
+	} else {
+		fmt.Printf("  Format:      v1\n")
+		fmt.Printf("  Store:       %d bytes\n", len(binary.StoreData))
+	}
 	fmt.Printf("  Total:       %d bytes\n", len(data))
+	if sigs, err := binary.EmbeddedSignatures(); err != nil {
+		fmt.Printf("  Signatures:  %v\n", err)
+	} else {
+		for _, sig := range sigs {
+			fmt.Printf("  Signed by:   %s (key %x) at %s\n", sig.Signer, sig.KeyID, sig.Time.Format(time.RFC3339))
+		}
+	}
 
 	// Try to load the store and show contents
 	runner, err := runtime.NewRunner(data)
@@ -241,7 +394,19 @@ func runBootstrap(args []string) error {
 // synthesizeGate creates a circuit for a named gate and optionally writes a .qmb file.
 func synthesizeGate(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: qbtm synthesize <gate> [-o output.qmb]")
+		return fmt.Errorf("usage: qbtm synthesize <gate> [-o output.qmb] [--format v1|v2]")
+	}
+	embed := runtime.Embed
+	for i, arg := range args {
+		if arg == "--format" && i+1 < len(args) {
+			switch args[i+1] {
+			case "v1":
+			case "v2":
+				embed = runtime.EmbedV2
+			default:
+				return fmt.Errorf("unknown format %q (want v1 or v2)", args[i+1])
+			}
+		}
 	}
 
 	gateName := args[0]
@@ -285,7 +450,7 @@ func synthesizeGate(args []string) error {
 	for i, arg := range args {
 		if arg == "-o" && i+1 < len(args) {
 			outFile := args[i+1]
-			binary := runtime.Embed(store, id, gateName, version)
+			binary := embed(store, id, gateName, version)
 			data := binary.Encode()
 			if err := os.WriteFile(outFile, data, 0644); err != nil {
 				return fmt.Errorf("write failed: %w", err)
@@ -346,6 +511,315 @@ func verifyFixpoint(args []string) error {
 	return nil
 }
 
+// checkQMB type checks every circuit in a .qmb binary and fails if any
+// diagnostics are reported.
+func checkQMB(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: qbtm check <file.qmb>")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("read failed: %w", err)
+	}
+
+	runner, err := runtime.NewRunner(data)
+	if err != nil {
+		return fmt.Errorf("load failed: %w", err)
+	}
+
+	diags := runner.TypeCheck()
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	if len(diags) > 0 {
+		return fmt.Errorf("%s: %d type errors", args[0], len(diags))
+	}
+
+	fmt.Printf("%s: OK (%d entries)\n", args[0], runner.StoreSize())
+	return nil
+}
+
+// gcQMB rewrites a .qmb binary without the store entries its entrypoint
+// does not reach, keeping its format.
+func gcQMB(args []string) error {
+	var in, out string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-o" && i+1 < len(args) {
+			out = args[i+1]
+			i++
+		} else {
+			in = args[i]
+		}
+	}
+	if in == "" || out == "" {
+		return fmt.Errorf("usage: qbtm gc <in.qmb> -o <out.qmb>")
+	}
+
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return fmt.Errorf("read failed: %w", err)
+	}
+	binary, err := runtime.Decode(data)
+	if err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+	store, err := binary.LoadStore()
+	if err != nil {
+		return fmt.Errorf("load failed: %w", err)
+	}
+
+	before := store.StoreSize()
+	removed := store.Prune(binary.Entrypoint)
+	result := reembed(binary, store)
+	if err := os.WriteFile(out, result, 0644); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	fmt.Printf("%s: kept %d of %d entries, removed %d\n", in, before-removed, before, removed)
+	fmt.Printf("Written: %s (%d bytes, %d bytes reclaimed)\n", out, len(result), len(data)-len(result))
+	return nil
+}
+
+// reembed encodes store under binary's entrypoint and metadata, in
+// binary's format.
+func reembed(binary *runtime.EmbeddedBinary, store *runtime.Store) []byte {
+	embed := runtime.Embed
+	if binary.Sections != nil {
+		embed = runtime.EmbedV2
+	}
+	return embed(store, binary.Entrypoint, binary.Name, binary.Version).Encode()
+}
+
+// linkQMB inlines the circuits a binary references from library binaries,
+// given as files or found in a --lib directory.
+func linkQMB(args []string) error {
+	var out, dir string
+	var files []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-o" && i+1 < len(args):
+			out = args[i+1]
+			i++
+		case args[i] == "--lib" && i+1 < len(args):
+			dir = args[i+1]
+			i++
+		default:
+			files = append(files, args[i])
+		}
+	}
+	if len(files) == 0 || out == "" {
+		return fmt.Errorf("usage: qbtm link <in.qmb> [<lib.qmb>...] [--lib <dir>] -o <out.qmb>")
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return fmt.Errorf("read failed: %w", err)
+	}
+	var libs [][]byte
+	for _, f := range files[1:] {
+		lib, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+		libs = append(libs, lib)
+	}
+	resolve := runtime.LibraryResolver(libs...)
+	if dir != "" {
+		fromFiles, fromDir := resolve, runtime.DirResolver(dir)
+		resolve = func(hash [32]byte) ([]byte, error) {
+			if lib, err := fromFiles(hash); err == nil {
+				return lib, nil
+			}
+			return fromDir(hash)
+		}
+	}
+
+	binary, err := runtime.Decode(data)
+	if err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+	store, err := binary.LoadStore()
+	if err != nil {
+		return fmt.Errorf("load failed: %w", err)
+	}
+	refs := len(store.Unresolved())
+	imported, err := runtime.Link(store, resolve)
+	if err != nil {
+		return err
+	}
+	result := reembed(binary, store)
+	if err := os.WriteFile(out, result, 0644); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	fmt.Printf("%s: resolved %d external references, imported %d entries\n", files[0], refs, imported)
+	fmt.Printf("Written: %s (%d bytes)\n", out, len(result))
+	return nil
+}
+
+// keygen writes <name>.key and <name>.pub.
+func keygen(args []string) error {
+	var name, signer string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--signer" && i+1 < len(args) {
+			signer = args[i+1]
+			i++
+		} else {
+			name = args[i]
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("usage: qbtm keygen [--signer <identity>] <name>")
+	}
+	if signer == "" {
+		signer = name
+	}
+
+	key, err := runtime.GenerateSigningKey(signer)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(name+".key", key.Marshal(), 0600); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	if err := os.WriteFile(name+".pub", key.MarshalPublic(), 0644); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	fmt.Printf("Key %x for %s: %s.key (secret), %s.pub (add to a trust store)\n",
+		runtime.KeyID(key.Public()), signer, name, name)
+	return nil
+}
+
+// signQMB signs a binary, embedding the signature in a v2 output file or
+// writing it to a detached signature file.
+func signQMB(args []string) error {
+	var in, out, keyFile, detached string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-o" && i+1 < len(args):
+			out = args[i+1]
+			i++
+		case args[i] == "--key" && i+1 < len(args):
+			keyFile = args[i+1]
+			i++
+		case args[i] == "--detached" && i+1 < len(args):
+			detached = args[i+1]
+			i++
+		default:
+			in = args[i]
+		}
+	}
+	if in == "" || keyFile == "" || (out == "") == (detached == "") {
+		return fmt.Errorf("usage: qbtm sign <in.qmb> --key <file.key> (-o <out.qmb> | --detached <out.sig>)")
+	}
+
+	keyData, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("read failed: %w", err)
+	}
+	key, err := runtime.ParseSigningKey(keyData)
+	if err != nil {
+		return fmt.Errorf("%s: %w", keyFile, err)
+	}
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return fmt.Errorf("read failed: %w", err)
+	}
+	binary, err := runtime.Decode(data)
+	if err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+
+	sig := binary.Sign(key, time.Now())
+	if detached != "" {
+		out, data = detached, runtime.EncodeSignatures([]runtime.Signature{sig})
+	} else {
+		if err := binary.AddSignature(sig); err != nil {
+			return err
+		}
+		data = binary.Encode()
+	}
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	fmt.Printf("Signed %s as %s (key %x)\n", in, sig.Signer, sig.KeyID)
+	fmt.Printf("Written: %s (%d bytes)\n", out, len(data))
+	return nil
+}
+
+// verifySig checks every embedded and detached signature of a binary and
+// fails unless one is valid under a trusted key.
+func verifySig(args []string) error {
+	var in, trustFile string
+	var sigFiles []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--trust" && i+1 < len(args):
+			trustFile = args[i+1]
+			i++
+		case args[i] == "--sig" && i+1 < len(args):
+			sigFiles = append(sigFiles, args[i+1])
+			i++
+		default:
+			in = args[i]
+		}
+	}
+	if in == "" || trustFile == "" {
+		return fmt.Errorf("usage: qbtm verify-sig <in.qmb> --trust <file> [--sig <file.sig>]...")
+	}
+
+	trust, err := runtime.LoadTrustStore(trustFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return fmt.Errorf("read failed: %w", err)
+	}
+	binary, err := runtime.Decode(data)
+	if err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+	sigs, err := binary.EmbeddedSignatures()
+	if err != nil {
+		return err
+	}
+	for _, f := range sigFiles {
+		sigData, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+		detached, err := runtime.DecodeSignatures(sigData)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+		sigs = append(sigs, detached...)
+	}
+	if len(sigs) == 0 {
+		return runtime.ErrUnsigned
+	}
+
+	store := binary.StoreHash()
+	fmt.Printf("%s: entrypoint %s, store %s\n", in,
+		hex.EncodeToString(binary.Entrypoint[:8]), hex.EncodeToString(store[:8]))
+	valid := 0
+	for _, sig := range sigs {
+		status := "OK"
+		if name, err := trust.Check(binary, sig); err != nil {
+			status = err.Error()
+		} else {
+			status += " (trusted as " + name + ")"
+			valid++
+		}
+		fmt.Printf("  %s, key %x, %s: %s\n", sig.Signer, sig.KeyID, sig.Time.Format(time.RFC3339), status)
+	}
+	if valid == 0 {
+		return runtime.ErrUntrusted
+	}
+	return nil
+}
+
 func showInfo(args []string) error {
 	fmt.Printf("QBTM Runtime v%s\n", version)
 	fmt.Println(strings.Repeat("=", 60))
@@ -376,7 +850,7 @@ func showInfo(args []string) error {
 		fmt.Printf("  %s\n", r.Name)
 	}
 	fmt.Println()
-	fmt.Println("Rewrite Rules (4):")
+	fmt.Println("Rewrite Rules (6):")
 	for _, r := range runtime.AllRewriteRules() {
 		fmt.Printf("  %s\n", r.Name)
 	}
@@ -410,6 +884,51 @@ func printMatrix(label string, m *runtime.Matrix) {
 	fmt.Printf("  Trace: %s\n", formatQI(tr))
 }
 
+func printMatrixZ8(label string, m *runtime.MatrixZ8) {
+	fmt.Printf("%s: %dx%d matrix over Q(ζ8)\n", label, m.Rows, m.Cols)
+
+	if m.Rows <= 8 && m.Cols <= 8 {
+		for i := 0; i < m.Rows; i++ {
+			fmt.Print("  [")
+			for j := 0; j < m.Cols; j++ {
+				if j > 0 {
+					fmt.Print("  ")
+				}
+				fmt.Print(formatQZ8(m.Get(i, j)))
+			}
+			fmt.Println("]")
+		}
+	}
+	fmt.Printf("  Trace: %s\n", formatQZ8(runtime.TraceZ8(m)))
+}
+
+func printIntervalMatrix(label string, m *runtime.IntervalMatrix) {
+	fmt.Printf("%s: %dx%d enclosure\n", label, m.Rows, m.Cols)
+
+	if m.Rows <= 8 && m.Cols <= 8 {
+		for i := 0; i < m.Rows; i++ {
+			fmt.Print("  [")
+			for j := 0; j < m.Cols; j++ {
+				if j > 0 {
+					fmt.Print("  ")
+				}
+				fmt.Print(formatCInterval(m.Get(i, j)))
+			}
+			fmt.Println("]")
+		}
+	}
+	fmt.Printf("  Trace: %s\n", formatCInterval(runtime.IntervalTrace(m)))
+	fmt.Printf("  Max width: %.3g\n", m.MaxWidth())
+}
+
+func formatCInterval(c runtime.CInterval) string {
+	re := fmt.Sprintf("[%.17g, %.17g]", c.Re.Lo, c.Re.Hi)
+	if c.Im.Lo == 0 && c.Im.Hi == 0 {
+		return re
+	}
+	return fmt.Sprintf("%s+[%.17g, %.17g]i", re, c.Im.Lo, c.Im.Hi)
+}
+
 func formatQI(q runtime.QI) string {
 	re := q.Re.RatString()
 	im := q.Im.RatString()
@@ -432,10 +951,44 @@ func formatQI(q runtime.QI) string {
 	return re + im + "i"
 }
 
+// formatQZ8 writes a + b√2, leaving out a zero part.
+func formatQZ8(q runtime.QZ8) string {
+	if runtime.QIIsZero(q.B) {
+		return formatQI(q.A)
+	}
+	b := formatQI(q.B)
+	switch {
+	case b == "1":
+		b = ""
+	case b == "-1":
+		b = "-"
+	case q.B.Re.Sign() != 0 && q.B.Im.Sign() != 0:
+		b = "(" + b + ")"
+	}
+	b += "√2"
+	if runtime.QIIsZero(q.A) {
+		return b
+	}
+	if strings.HasPrefix(b, "-") {
+		return formatQI(q.A) + b
+	}
+	return formatQI(q.A) + "+" + b
+}
+
 func formatObject(obj runtime.Object) string {
 	if len(obj.Blocks) == 0 {
 		return "I"
 	}
+	if len(obj.Factors) > 0 {
+		parts := make([]string, len(obj.Factors))
+		for i, f := range obj.Factors {
+			parts[i] = formatObject(f)
+			if len(f.Blocks) > 1 && len(f.Factors) == 0 {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+		return strings.Join(parts, " ⊗ ")
+	}
 	parts := make([]string, len(obj.Blocks))
 	for i, b := range obj.Blocks {
 		if b == 1 {
@@ -473,7 +1026,7 @@ func printCircuit(indent string, c runtime.Circuit, runner *runtime.Runner) {
 
 func gateSpec(name string) *runtime.SynthesisSpec {
 	qubit := runtime.Object{Blocks: []uint32{2}}
-	twoQubit := runtime.Object{Blocks: []uint32{2, 2}}
+	twoQubit := runtime.TensorObjects(qubit, qubit)
 	unit := runtime.Object{}
 
 	switch name {
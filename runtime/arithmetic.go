@@ -142,11 +142,13 @@ type Matrix struct {
 	Data []QI
 }
 
-// NewMatrix creates a zero matrix.
+// NewMatrix creates a zero matrix. The entries' big.Rats are allocated
+// as one block rather than two allocations per entry.
 func NewMatrix(rows, cols int) *Matrix {
 	data := make([]QI, rows*cols)
+	rats := make([]big.Rat, 2*len(data))
 	for i := range data {
-		data[i] = QIZero()
+		data[i] = QI{Re: &rats[2*i], Im: &rats[2*i+1]}
 	}
 	return &Matrix{
 		Rows: rows,
@@ -179,23 +181,12 @@ func Zero(rows, cols int) *Matrix {
 	return NewMatrix(rows, cols)
 }
 
-// MatMul computes A * B.
+// MatMul computes A * B with the common-denominator kernel IntMatMul.
 func MatMul(A, B *Matrix) *Matrix {
 	if A.Cols != B.Rows {
 		return nil
 	}
-	C := NewMatrix(A.Rows, B.Cols)
-	for i := 0; i < A.Rows; i++ {
-		for j := 0; j < B.Cols; j++ {
-			sum := QIZero()
-			for k := 0; k < A.Cols; k++ {
-				product := QIMul(A.Get(i, k), B.Get(k, j))
-				sum = QIAdd(sum, product)
-			}
-			C.Set(i, j, sum)
-		}
-	}
-	return C
+	return IntMatMul(ToIntMatrix(A), ToIntMatrix(B)).ToMatrix()
 }
 
 // MatAdd computes A + B.
@@ -254,26 +245,47 @@ func Trace(A *Matrix) QI {
 	return sum
 }
 
-// Kronecker computes the Kronecker product A ⊗ B.
+// Kronecker computes the Kronecker product A ⊗ B with IntKronecker.
 func Kronecker(A, B *Matrix) *Matrix {
-	rows := A.Rows * B.Rows
-	cols := A.Cols * B.Cols
-	C := NewMatrix(rows, cols)
+	return IntKronecker(ToIntMatrix(A), ToIntMatrix(B)).ToMatrix()
+}
 
-	for i := 0; i < A.Rows; i++ {
-		for j := 0; j < A.Cols; j++ {
-			for k := 0; k < B.Rows; k++ {
-				for l := 0; l < B.Cols; l++ {
-					row := i*B.Rows + k
-					col := j*B.Cols + l
-					C.Set(row, col, QIMul(A.Get(i, j), B.Get(k, l)))
-				}
+// DirectSum computes the block-diagonal matrix A_1 ⊕ A_2 ⊕ ... ⊕ A_k.
+func DirectSum(blocks ...*Matrix) *Matrix {
+	rows, cols := 0, 0
+	for _, b := range blocks {
+		rows += b.Rows
+		cols += b.Cols
+	}
+	C := NewMatrix(rows, cols)
+	r, c := 0, 0
+	for _, b := range blocks {
+		for i := 0; i < b.Rows; i++ {
+			for j := 0; j < b.Cols; j++ {
+				C.Set(r+i, c+j, b.Get(i, j))
 			}
 		}
+		r += b.Rows
+		c += b.Cols
 	}
 	return C
 }
 
+// SubMatrix extracts the rows×cols block of A whose top-left corner is at
+// (row, col). Returns nil if the block does not fit inside A.
+func SubMatrix(A *Matrix, row, col, rows, cols int) *Matrix {
+	if row < 0 || col < 0 || row+rows > A.Rows || col+cols > A.Cols {
+		return nil
+	}
+	B := NewMatrix(rows, cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			B.Set(i, j, A.Get(row+i, col+j))
+		}
+	}
+	return B
+}
+
 // OuterProduct computes |u⟩⟨v| (u * v†).
 func OuterProduct(u, v *Matrix) *Matrix {
 	if u.Cols != 1 || v.Cols != 1 {
@@ -307,8 +319,17 @@ func (m *Matrix) Clone() *Matrix {
 
 // Encoding for matrices
 
-// MatrixToValue converts a matrix to a Value.
+// MatrixToValue converts a matrix to a Value. Sparse matrices (see
+// isSparse) get the "matrix-sparse" encoding.
 func MatrixToValue(m *Matrix) Value {
+	if isSparse(m) {
+		return SparseMatrixToValue(ToSparse(m))
+	}
+	return denseMatrixToValue(m)
+}
+
+// denseMatrixToValue writes the row-major Tag("matrix", ...) encoding.
+func denseMatrixToValue(m *Matrix) Value {
 	items := make([]Value, len(m.Data))
 	for i, q := range m.Data {
 		items[i] = MakeTag(
@@ -329,13 +350,21 @@ func MatrixToValue(m *Matrix) Value {
 	)
 }
 
-// MatrixFromValue parses a matrix from a Value.
+// MatrixFromValue parses a matrix from a Value in either encoding. The
+// result is always dense; SparseMatrixFromValue keeps CSR form.
 func MatrixFromValue(v Value) (*Matrix, bool) {
 	tag, ok := v.(Tag)
 	if !ok {
 		return nil, false
 	}
 	label, ok := tag.Label.(Text)
+	if ok && label.V == sparseLabel {
+		s, ok := sparseFromPayload(tag.Payload)
+		if !ok {
+			return nil, false
+		}
+		return s.ToDense(), true
+	}
 	if !ok || label.V != "matrix" {
 		return nil, false
 	}
@@ -0,0 +1,162 @@
+package runtime
+
+import (
+	"fmt"
+)
+
+// Direct sums.
+//
+// A state on A⊕B is block diagonal: the Hilbert space is the concatenation
+// of the blocks of A and B and coherences between summands are not part of
+// the algebra. A Bisum(f_1, ..., f_n) circuit runs f_i on the i-th summand
+// of its domain:
+//
+//	(f_1 ⊕ ... ⊕ f_n)(ρ_1 ⊕ ... ⊕ ρ_n) = f_1(ρ_1) ⊕ ... ⊕ f_n(ρ_n)
+//
+// Inject and Project move one summand in and out of a direct sum. With Nil
+// data they keep their original behaviour, padding or truncating the
+// top-left corner. With
+//
+//	Tag("summand", Int k)
+//
+// the summand starts at block k of the direct sum (the codomain of Inject,
+// the domain of Project) and covers as many blocks as the other side has.
+// Inject places the input on those diagonal blocks with zeros elsewhere;
+// Project extracts them. Both index the Hilbert space by BlockDim, as
+// Instrument and Branch do, so Project(k) after an instrument selects the
+// post-measurement state of outcome k.
+
+const summandLabel = "summand"
+
+// SummandToValue encodes the block index of a summand as
+// Tag("summand", Int k).
+func SummandToValue(k int) Value {
+	return MakeTag(MakeText(summandLabel), MakeInt(int64(k)))
+}
+
+// SummandFromValue parses a Tag("summand", Int k) value with k >= 0.
+func SummandFromValue(v Value) (int, bool) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return 0, false
+	}
+	label, ok := tag.Label.(Text)
+	if !ok || label.V != summandLabel {
+		return 0, false
+	}
+	n, ok := tag.Payload.(Int)
+	if !ok || n.V.Sign() < 0 || n.V.BitLen() > 31 {
+		return 0, false
+	}
+	return int(n.V.Int64()), true
+}
+
+// summandSpan locates part as the summand of whole starting at block k and
+// returns its Hilbert space offset and dimension within whole.
+func summandSpan(whole, part Object, k int) (int, int, error) {
+	if len(part.Blocks) == 0 {
+		return 0, 0, fmt.Errorf("summand %s has no blocks", formatObjectDiag(part))
+	}
+	if k+len(part.Blocks) > len(whole.Blocks) {
+		return 0, 0, fmt.Errorf("summand at block %d of %s does not fit %d blocks",
+			k, formatObjectDiag(whole), len(part.Blocks))
+	}
+	if !blocksEqual(whole.Blocks[k:k+len(part.Blocks)], part.Blocks) {
+		return 0, 0, fmt.Errorf("blocks %d..%d of %s are not %s",
+			k, k+len(part.Blocks)-1, formatObjectDiag(whole), formatObjectDiag(part))
+	}
+	offset := 0
+	for _, n := range whole.Blocks[:k] {
+		offset += int(n)
+	}
+	return offset, BlockDim(part), nil
+}
+
+// applyBisum runs each child on its diagonal block of the input and
+// returns the direct sum of the outputs.
+func (e *Executor) applyBisum(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	if len(c.Children) == 0 {
+		return nil, fmt.Errorf("bisum requires at least 1 child")
+	}
+	if dim := BlockDim(c.Domain); input.Rows != dim || input.Cols != dim {
+		return nil, fmt.Errorf("bisum: input is %dx%d, domain dim is %d",
+			input.Rows, input.Cols, dim)
+	}
+
+	children := make([]Circuit, len(c.Children))
+	offsets := make([]int, len(c.Children))
+	offset := 0
+	for i, childID := range c.Children {
+		child, ok := e.store.Get(childID)
+		if !ok {
+			return nil, fmt.Errorf("child %d not found", i)
+		}
+		if len(child.Domain.Blocks) == 0 {
+			return nil, fmt.Errorf("bisum: child %d domain has no blocks", i)
+		}
+		n := BlockDim(child.Domain)
+		if offset+n > input.Rows {
+			return nil, fmt.Errorf("bisum: child %d overruns the %d-dimensional input", i, input.Rows)
+		}
+		children[i] = child
+		offsets[i] = offset
+		offset += n
+	}
+	if offset != input.Rows {
+		return nil, fmt.Errorf("bisum: children cover %d of %d dimensions", offset, input.Rows)
+	}
+
+	outputs := make([]*IntMatrix, len(children))
+	err := e.forEach(len(children), func(i int) error {
+		n := BlockDim(children[i].Domain)
+		out, err := e.executeInt(children[i], IntSubMatrix(input, offsets[i], offsets[i], n, n))
+		outputs[i] = out
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return IntDirectSum(outputs...), nil
+}
+
+// applySummandInject places the input on the summand of the codomain named
+// by c.Data.
+func (e *Executor) applySummandInject(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	k, ok := SummandFromValue(c.Data)
+	if !ok {
+		return nil, fmt.Errorf("inject: data must be Nil or Tag(\"summand\", Int k)")
+	}
+	offset, n, err := summandSpan(c.Codomain, c.Domain, k)
+	if err != nil {
+		return nil, fmt.Errorf("inject: %w", err)
+	}
+	if input.Rows != n || input.Cols != n {
+		return nil, fmt.Errorf("inject: input is %dx%d, domain dim is %d", input.Rows, input.Cols, n)
+	}
+	dim := BlockDim(c.Codomain)
+	result := NewIntMatrix(dim, dim)
+	result.Den.Set(&input.Den)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			result.Re[(offset+i)*dim+offset+j].Set(&input.Re[i*n+j])
+			result.Im[(offset+i)*dim+offset+j].Set(&input.Im[i*n+j])
+		}
+	}
+	return result, nil
+}
+
+// applySummandProject extracts the summand of the domain named by c.Data.
+func (e *Executor) applySummandProject(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	k, ok := SummandFromValue(c.Data)
+	if !ok {
+		return nil, fmt.Errorf("project: data must be Nil or Tag(\"summand\", Int k)")
+	}
+	offset, n, err := summandSpan(c.Domain, c.Codomain, k)
+	if err != nil {
+		return nil, fmt.Errorf("project: %w", err)
+	}
+	if dim := BlockDim(c.Domain); input.Rows != dim || input.Cols != dim {
+		return nil, fmt.Errorf("project: input is %dx%d, domain dim is %d", input.Rows, input.Cols, dim)
+	}
+	return IntSubMatrix(input, offset, offset, n, n), nil
+}
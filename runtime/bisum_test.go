@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBisumBlockwise(t *testing.T) {
+	r := rand.New(rand.NewSource(18))
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Q(2) ⊕ Q(3) → Q(3) ⊕ Q(2): a Kraus map on each summand.
+	f := putKraus(store, randKraus(r, 2, 2, 3))
+	g := putKraus(store, randKraus(r, 2, 3, 2))
+	fc, _ := store.Get(f)
+	gc, _ := store.Get(g)
+	bisum := store.Put(Circuit{
+		Domain:   SumObjects(QuantumObject(2), QuantumObject(3)),
+		Codomain: SumObjects(QuantumObject(3), QuantumObject(2)),
+		Prim:     PrimBisum,
+		Children: [][32]byte{f, g},
+	})
+	if diags := TypeCheck(store, bisum); diags != nil {
+		t.Fatalf("valid bisum reported %v", diags)
+	}
+	c, _ := store.Get(bisum)
+
+	for trial := 0; trial < 3; trial++ {
+		rho0, rho1 := randDensity(r, 2), randDensity(r, 3)
+		want0, _ := exec.Execute(fc, rho0)
+		want1, _ := exec.Execute(gc, rho1)
+
+		// Off-diagonal coherences between summands are dropped.
+		input := randDensity(r, 5)
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 2; j++ {
+				input.Set(i, j, rho0.Get(i, j))
+			}
+		}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				input.Set(2+i, 2+j, rho1.Get(i, j))
+			}
+		}
+		got, err := exec.Execute(c, input)
+		if err != nil {
+			t.Fatalf("trial %d: Execute bisum failed: %v", trial, err)
+		}
+		if !MatrixEqual(got, DirectSum(want0, want1)) {
+			t.Errorf("trial %d: bisum differs from the direct sum of its children", trial)
+		}
+	}
+
+	if _, err := exec.Execute(c, Identity(4)); err == nil {
+		t.Error("bisum on a wrongly sized input should fail")
+	}
+}
+
+func TestSummandInjectProject(t *testing.T) {
+	r := rand.New(rand.NewSource(19))
+	exec := NewExecutor(NewStore())
+	sum := SumObjects(QuantumObject(2), ClassicalObject(2), QuantumObject(3))
+
+	for _, tc := range []struct {
+		k    int
+		part Object
+		off  int
+	}{
+		{0, QuantumObject(2), 0},
+		{1, ClassicalObject(2), 2},
+		{3, QuantumObject(3), 4},
+		{2, SumObjects(ClassicalObject(1), QuantumObject(3)), 3},
+	} {
+		n := BlockDim(tc.part)
+		rho := randDensity(r, n)
+		inject := Circuit{Domain: tc.part, Codomain: sum, Prim: PrimInject, Data: SummandToValue(tc.k)}
+		project := Circuit{Domain: sum, Codomain: tc.part, Prim: PrimProject, Data: SummandToValue(tc.k)}
+
+		embedded, err := exec.Execute(inject, rho)
+		if err != nil {
+			t.Fatalf("summand %d: inject failed: %v", tc.k, err)
+		}
+		want := NewMatrix(7, 7)
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				want.Set(tc.off+i, tc.off+j, rho.Get(i, j))
+			}
+		}
+		if !MatrixEqual(embedded, want) {
+			t.Errorf("summand %d: inject placed the input at the wrong block", tc.k)
+		}
+
+		back, err := exec.Execute(project, embedded)
+		if err != nil {
+			t.Fatalf("summand %d: project failed: %v", tc.k, err)
+		}
+		if !MatrixEqual(back, rho) {
+			t.Errorf("summand %d: project(inject(ρ)) != ρ", tc.k)
+		}
+	}
+}
+
+func TestProjectInstrumentOutcome(t *testing.T) {
+	r := rand.New(rand.NewSource(20))
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// A two-outcome instrument on Q(2), followed by Project onto outcome 1.
+	outcomes := [][]*Matrix{randKraus(r, 1, 2, 2), randKraus(r, 2, 2, 2)}
+	cod := SumObjects(QuantumObject(2), QuantumObject(2))
+	inst := store.Put(Circuit{Domain: qubit(), Codomain: cod, Prim: PrimInstrument, Data: InstrumentToValue(outcomes)})
+	proj := store.Put(Circuit{Domain: cod, Codomain: qubit(), Prim: PrimProject, Data: SummandToValue(1)})
+	root := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{inst, proj}})
+	if diags := TypeCheck(store, root); diags != nil {
+		t.Fatalf("instrument;project reported %v", diags)
+	}
+
+	rho := randDensity(r, 2)
+	c, _ := store.Get(root)
+	got, err := exec.Execute(c, rho)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want, _ := krausSum(outcomes[1], rho, 2)
+	if !MatrixEqual(got, want) {
+		t.Error("Project(1) after an instrument should give the outcome-1 state")
+	}
+}
+
+func TestSummandTypeCheck(t *testing.T) {
+	store := NewStore()
+	sum := SumObjects(QuantumObject(2), QuantumObject(3))
+
+	ok := store.Put(Circuit{Domain: QuantumObject(3), Codomain: sum, Prim: PrimInject, Data: SummandToValue(1)})
+	if diags := TypeCheck(store, ok); diags != nil {
+		t.Errorf("valid inject reported %v", diags)
+	}
+
+	wrongBlock := store.Put(Circuit{Domain: QuantumObject(3), Codomain: sum, Prim: PrimInject, Data: SummandToValue(0)})
+	expectDiag(t, TypeCheck(store, wrongBlock), DiagType)
+
+	outOfRange := store.Put(Circuit{Domain: sum, Codomain: QuantumObject(3), Prim: PrimProject, Data: SummandToValue(2)})
+	expectDiag(t, TypeCheck(store, outOfRange), DiagType)
+
+	bad := store.Put(Circuit{Domain: sum, Codomain: QuantumObject(3), Prim: PrimProject, Data: MakeText("second")})
+	expectDiag(t, TypeCheck(store, bad), DiagData)
+
+	badBisum := store.Put(Circuit{
+		Domain:   sum,
+		Codomain: QuantumObject(3),
+		Prim:     PrimBisum,
+		Children: [][32]byte{store.Put(Circuit{Domain: QuantumObject(3), Codomain: QuantumObject(3), Prim: PrimId})},
+	})
+	expectDiag(t, TypeCheck(store, badBisum), DiagType)
+}
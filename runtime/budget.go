@@ -0,0 +1,207 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goruntime "runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Cancellable execution with resource budgets.
+//
+// Execute trusts the circuit: a malformed or hostile .qmb can nest deeply,
+// declare huge objects or revisit a shared subcircuit exponentially often.
+// ExecuteContext runs the same interpreter under a context and the
+// executor's Limits:
+//
+//	MaxDim     largest Hilbert space dimension of any input or codomain
+//	MaxVisits  number of Execute calls over the whole run
+//	MaxDepth   nesting depth of Execute calls
+//
+// Each is checked when a node is entered, before any work is done for it,
+// and exceeding one returns a *BudgetError. Cancelling the context stops
+// the run at the next node with an error wrapping ctx.Err().
+//
+// Under ExecuteContext, independent children are evaluated in parallel on
+// a pool of Limits.Workers goroutines shared by the whole run: the slices
+// of each Tensor factor, the two terms of Add and the blocks of Bisum and
+// Branch. When the pool is busy the caller runs the task itself, so nested
+// parallelism never blocks. The first error cancels the remaining work and
+// is the one returned. Results are identical to Execute.
+
+// Limits bounds an ExecuteContext run. Zero values mean unlimited; zero
+// Workers means GOMAXPROCS.
+type Limits struct {
+	MaxDim    int
+	MaxVisits int64
+	MaxDepth  int
+	Workers   int
+}
+
+// ErrBudgetExceeded is matched by every *BudgetError.
+var ErrBudgetExceeded = errors.New("execution budget exceeded")
+
+// BudgetError reports which limit an ExecuteContext run exceeded.
+type BudgetError struct {
+	Resource string // "dimension", "visits" or "depth"
+	Limit    int64
+	Value    int64 // the value that exceeded Limit
+	Prim     Prim  // the primitive being entered
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("budget: %s %d exceeds limit %d at %s",
+		e.Resource, e.Value, e.Limit, PrimName(e.Prim))
+}
+
+// Is makes errors.Is(err, ErrBudgetExceeded) hold.
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// execRun is the state shared by every node of one ExecuteContext call.
+type execRun struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	limits Limits
+	visits atomic.Int64
+	slots  chan struct{} // tokens for workers beyond the calling goroutine
+
+	mu  sync.Mutex
+	err error // first failure, which cancelled ctx
+}
+
+// fail records the first error of the run and cancels the rest.
+func (r *execRun) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// stopped returns the error the run stopped with, or nil while it is
+// still going.
+func (r *execRun) stopped() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.ctx.Err(); err != nil {
+		return fmt.Errorf("execute: %w", err)
+	}
+	return nil
+}
+
+// SetLimits sets the budgets enforced by ExecuteContext.
+func (e *Executor) SetLimits(l Limits) {
+	e.limits = l
+}
+
+// Limits returns the budgets enforced by ExecuteContext.
+func (e *Executor) Limits() Limits {
+	return e.limits
+}
+
+// ExecuteContext executes c on input like Execute, evaluating independent
+// children in parallel and enforcing the executor's Limits. It returns a
+// *BudgetError when a limit is exceeded and an error wrapping ctx.Err()
+// when ctx is done first.
+func (e *Executor) ExecuteContext(ctx context.Context, c Circuit, input *Matrix) (*Matrix, error) {
+	return e.withRun(ctx, func(r *Executor) (*Matrix, error) {
+		return r.Execute(c, input)
+	})
+}
+
+// withRun calls fn with a copy of e bound to a fresh run under ctx.
+func (e *Executor) withRun(ctx context.Context, fn func(*Executor) (*Matrix, error)) (*Matrix, error) {
+	workers := e.limits.Workers
+	if workers <= 0 {
+		workers = goruntime.GOMAXPROCS(0)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run := &execRun{ctx: ctx, cancel: cancel, limits: e.limits, slots: make(chan struct{}, workers-1)}
+
+	r := *e
+	r.run = run
+	r.depth = 0
+	out, err := fn(&r)
+	if err != nil {
+		run.fail(err)
+		return nil, run.stopped()
+	}
+	return out, nil
+}
+
+// enter checks the run's budgets for executing c on input and returns the
+// executor for c's children, one level deeper.
+func (e *Executor) enter(c Circuit, input *IntMatrix) (*Executor, error) {
+	run := e.run
+	if err := run.stopped(); err != nil {
+		return nil, err
+	}
+	l := run.limits
+	if l.MaxDepth > 0 && e.depth+1 > l.MaxDepth {
+		return nil, &BudgetError{Resource: "depth", Limit: int64(l.MaxDepth), Value: int64(e.depth + 1), Prim: c.Prim}
+	}
+	if n := run.visits.Add(1); l.MaxVisits > 0 && n > l.MaxVisits {
+		return nil, &BudgetError{Resource: "visits", Limit: l.MaxVisits, Value: n, Prim: c.Prim}
+	}
+	if l.MaxDim > 0 {
+		dim := BlockDim(c.Codomain)
+		if input.Rows > dim {
+			dim = input.Rows
+		}
+		if dim > l.MaxDim {
+			return nil, &BudgetError{Resource: "dimension", Limit: int64(l.MaxDim), Value: int64(dim), Prim: c.Prim}
+		}
+	}
+	sub := *e
+	sub.depth++
+	return &sub, nil
+}
+
+// forEach calls fn(0), ..., fn(n-1) and returns the first error. Under
+// ExecuteContext the calls run on the run's worker pool; otherwise they
+// run in order and stop at the first error.
+func (e *Executor) forEach(n int, fn func(i int) error) error {
+	if e.run == nil || n < 2 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	run := e.run
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if run.ctx.Err() != nil {
+			break
+		}
+		select {
+		case run.slots <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-run.slots
+					wg.Done()
+				}()
+				if err := fn(i); err != nil {
+					run.fail(err)
+				}
+			}(i)
+		default:
+			if err := fn(i); err != nil {
+				run.fail(err)
+			}
+		}
+	}
+	wg.Wait()
+	return run.stopped()
+}
@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// budgetFixture builds Add(Scale(1/3, f), Scale(2/3, f;f)) with
+// f = Kraus ⊗ U on Q(2)⊗Q(3), a Bisum and a Branch, and returns them with
+// matching inputs.
+func budgetFixture(r *rand.Rand, store *Store) (ids [][32]byte, inputs []*Matrix) {
+	ab := tensorObject(qubit(), QuantumObject(3))
+	f := store.Put(Circuit{Domain: ab, Codomain: ab, Prim: PrimTensor, Children: [][32]byte{
+		putKraus(store, randKraus(r, 2, 2, 2)),
+		putUnitary(store, randMatrix(r, 3, 3)),
+	}})
+	ff := store.Put(Circuit{Domain: ab, Codomain: ab, Prim: PrimCompose, Children: [][32]byte{f, f}})
+	add := store.Put(Circuit{Domain: ab, Codomain: ab, Prim: PrimAdd, Children: [][32]byte{
+		store.Put(Circuit{Domain: ab, Codomain: ab, Prim: PrimScale, Children: [][32]byte{f}, Data: MakeRat(1, 3)}),
+		store.Put(Circuit{Domain: ab, Codomain: ab, Prim: PrimScale, Children: [][32]byte{ff}, Data: MakeRat(2, 3)}),
+	}})
+
+	bisum := store.Put(Circuit{
+		Domain:   SumObjects(QuantumObject(2), QuantumObject(3)),
+		Codomain: SumObjects(QuantumObject(3), QuantumObject(2)),
+		Prim:     PrimBisum,
+		Children: [][32]byte{putKraus(store, randKraus(r, 2, 2, 3)), putKraus(store, randKraus(r, 2, 3, 2))},
+	})
+
+	one := Object{Blocks: []uint32{1}}
+	branch := store.Put(Circuit{Domain: ClassicalObject(2), Codomain: qubit(), Prim: PrimBranch, Children: [][32]byte{
+		store.Put(Circuit{Domain: one, Codomain: qubit(), Prim: PrimPrepare, Data: MatrixToValue(rhoYExact())}),
+		store.Put(Circuit{Domain: one, Codomain: qubit(), Prim: PrimPrepare, Data: MatrixToValue(ket1bra1())}),
+	}})
+
+	return [][32]byte{add, bisum, branch}, []*Matrix{randDensity(r, 6), randDensity(r, 5), randDensity(r, 2)}
+}
+
+func TestExecuteContextMatchesExecute(t *testing.T) {
+	r := rand.New(rand.NewSource(20))
+	store := NewStore()
+	ids, inputs := budgetFixture(r, store)
+	exec := NewExecutor(store)
+
+	for _, workers := range []int{1, 4} {
+		exec.SetLimits(Limits{Workers: workers})
+		for k, id := range ids {
+			c, _ := store.Get(id)
+			want, err := exec.Execute(c, inputs[k])
+			if err != nil {
+				t.Fatalf("circuit %d: Execute: %v", k, err)
+			}
+			got, err := exec.ExecuteContext(context.Background(), c, inputs[k])
+			if err != nil {
+				t.Fatalf("circuit %d, %d workers: ExecuteContext: %v", k, workers, err)
+			}
+			if !MatrixEqual(got, want) {
+				t.Errorf("circuit %d, %d workers: ExecuteContext differs from Execute", k, workers)
+			}
+		}
+	}
+}
+
+func TestExecuteContextBudgets(t *testing.T) {
+	r := rand.New(rand.NewSource(20))
+	store := NewStore()
+	ids, inputs := budgetFixture(r, store)
+	c, _ := store.Get(ids[0])
+	exec := NewExecutor(store)
+
+	// Add → Scale → Compose → Tensor → Kraus is 5 levels deep.
+	for _, tc := range []struct {
+		limits   Limits
+		resource string
+	}{
+		{Limits{MaxDepth: 4}, "depth"},
+		{Limits{MaxVisits: 10}, "visits"},
+		{Limits{MaxDim: 5}, "dimension"},
+	} {
+		tc.limits.Workers = 4
+		exec.SetLimits(tc.limits)
+		_, err := exec.ExecuteContext(context.Background(), c, inputs[0])
+		var be *BudgetError
+		if !errors.As(err, &be) || be.Resource != tc.resource || !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("%+v: got %v, want a %s budget error", tc.limits, err, tc.resource)
+		}
+	}
+
+	exec.SetLimits(Limits{MaxDepth: 5, MaxVisits: 1000, MaxDim: 6})
+	if _, err := exec.ExecuteContext(context.Background(), c, inputs[0]); err != nil {
+		t.Errorf("run within budget failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := exec.ExecuteContext(ctx, c, inputs[0]); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled run returned %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package runtime
+
+import (
+	"container/list"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Execution cache.
+//
+// Circuits are content-addressed, so a circuit applied to the same input
+// always produces the same output. An ExecCache attached to an Executor
+// memoizes Execute on the key
+//
+//	(QGID(CircuitToValue(c)), QGID(MatrixToValue(input)))
+//
+// so subcircuits shared across a DAG, such as one Hadamard reused by many
+// stages, are computed once per distinct input. Entries are kept in memory
+// in least-recently-used order within MaxEntries and MaxBytes, where the
+// size of an entry is the length of its encoded output. With Dir set,
+// every computed result is also written to Dir as
+//
+//	<circuit hex>-<input hex>.qv   Tag("exec-result", Seq(Bytes circuit, Bytes input, matrix))
+//
+// and read back on a memory miss, so results persist across runs. The
+// directory is not size limited. Unreadable or mismatched files are
+// treated as misses. A result that cannot be written is still kept in
+// memory and returned; the failure is counted in Stats and the last one is
+// available from WriteErr. PrimId is never cached.
+
+const execResultLabel = "exec-result"
+
+// CacheConfig configures an ExecCache. Zero limits mean unlimited; an
+// empty Dir keeps the cache in memory only.
+type CacheConfig struct {
+	MaxEntries int
+	MaxBytes   int64
+	Dir        string
+}
+
+// CacheStats reports ExecCache activity.
+type CacheStats struct {
+	Hits        uint64 // lookups answered from memory
+	DiskHits    uint64 // lookups answered from Dir
+	Misses      uint64 // lookups that ran the circuit
+	Evictions   uint64 // entries dropped to respect the limits
+	WriteErrors uint64 // results that could not be written to Dir
+	Entries     int    // entries in memory
+	Bytes       int64  // encoded size of the entries in memory
+}
+
+// cacheKey identifies one execution.
+type cacheKey struct {
+	circuit [32]byte
+	input   [32]byte
+}
+
+// cacheEntry is one memoized result.
+type cacheEntry struct {
+	key    cacheKey
+	output *Matrix
+	size   int64
+}
+
+// ExecCache memoizes circuit executions. It is safe for concurrent use.
+type ExecCache struct {
+	mu      sync.Mutex
+	cfg     CacheConfig
+	entries map[cacheKey]*list.Element
+	lru     *list.List // front is most recently used
+	stats   CacheStats
+	lastErr error // most recent failure to write to Dir
+}
+
+// NewExecCache creates a cache, creating cfg.Dir if it is set.
+func NewExecCache(cfg CacheConfig) (*ExecCache, error) {
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+			return nil, fmt.Errorf("cache: %w", err)
+		}
+	}
+	return &ExecCache{
+		cfg:     cfg,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+	}, nil
+}
+
+// Stats returns a snapshot of the cache statistics.
+func (c *ExecCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// WriteErr returns the most recent failure to write a result to Dir, or
+// nil if every write succeeded.
+func (c *ExecCache) WriteErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr
+}
+
+// get returns a copy of the cached output for key, from memory or disk.
+func (c *ExecCache) get(key cacheKey) (*Matrix, bool) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(el)
+		c.stats.Hits++
+		out := el.Value.(*cacheEntry).output.Clone()
+		c.mu.Unlock()
+		return out, true
+	}
+	c.mu.Unlock()
+
+	if c.cfg.Dir != "" {
+		if out, size, ok := c.readFile(key); ok {
+			c.mu.Lock()
+			c.stats.DiskHits++
+			c.insert(key, out, size)
+			c.mu.Unlock()
+			return out.Clone(), true
+		}
+	}
+
+	c.mu.Lock()
+	c.stats.Misses++
+	c.mu.Unlock()
+	return nil, false
+}
+
+// put records output for key in memory and, with Dir set, on disk. A
+// failed write is recorded in the stats; the entry stays in memory.
+func (c *ExecCache) put(key cacheKey, output *Matrix) {
+	encoded := execResultValue(key, output).Encode()
+	c.mu.Lock()
+	c.insert(key, output.Clone(), int64(len(encoded)))
+	c.mu.Unlock()
+
+	if c.cfg.Dir == "" {
+		return
+	}
+	if err := c.writeFile(key, encoded); err != nil {
+		c.mu.Lock()
+		c.stats.WriteErrors++
+		c.lastErr = err
+		c.mu.Unlock()
+	}
+}
+
+// writeFile atomically writes an encoded result to key's file.
+func (c *ExecCache) writeFile(key cacheKey, encoded []byte) error {
+	path := c.path(key)
+	tmp, err := os.CreateTemp(c.cfg.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// insert adds an entry and evicts from the back until the limits hold.
+// The caller holds c.mu.
+func (c *ExecCache) insert(key cacheKey, output *Matrix, size int64) {
+	if el, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, output: output, size: size})
+	c.stats.Entries++
+	c.stats.Bytes += size
+
+	for c.lru.Len() > 0 && c.overLimit() {
+		el := c.lru.Back()
+		e := el.Value.(*cacheEntry)
+		c.lru.Remove(el)
+		delete(c.entries, e.key)
+		c.stats.Entries--
+		c.stats.Bytes -= e.size
+		c.stats.Evictions++
+	}
+}
+
+func (c *ExecCache) overLimit() bool {
+	return (c.cfg.MaxEntries > 0 && c.stats.Entries > c.cfg.MaxEntries) ||
+		(c.cfg.MaxBytes > 0 && c.stats.Bytes > c.cfg.MaxBytes)
+}
+
+// path returns the file holding key's result.
+func (c *ExecCache) path(key cacheKey) string {
+	return filepath.Join(c.cfg.Dir, hex.EncodeToString(key.circuit[:])+"-"+hex.EncodeToString(key.input[:])+".qv")
+}
+
+// readFile loads key's result from disk, returning it with its encoded size.
+func (c *ExecCache) readFile(key cacheKey) (*Matrix, int64, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, 0, false
+	}
+	v, err := DecodeCanonical(data, DefaultDecodeLimits)
+	if err != nil {
+		return nil, 0, false
+	}
+	tag, ok := v.(Tag)
+	if !ok {
+		return nil, 0, false
+	}
+	label, ok := tag.Label.(Text)
+	if !ok || label.V != execResultLabel {
+		return nil, 0, false
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok || len(seq.Items) != 3 {
+		return nil, 0, false
+	}
+	circuit, ok1 := seq.Items[0].(Bytes)
+	input, ok2 := seq.Items[1].(Bytes)
+	if !ok1 || !ok2 || string(circuit.V) != string(key.circuit[:]) || string(input.V) != string(key.input[:]) {
+		return nil, 0, false
+	}
+	out, ok := MatrixFromValue(seq.Items[2])
+	if !ok {
+		return nil, 0, false
+	}
+	return out, int64(len(data)), true
+}
+
+// execResultValue is the on-disk record for one cached execution.
+func execResultValue(key cacheKey, output *Matrix) Value {
+	return MakeTag(
+		MakeText(execResultLabel),
+		MakeSeq(MakeBytes(key.circuit[:]), MakeBytes(key.input[:]), MatrixToValue(output)),
+	)
+}
+
+// SetCache attaches an execution cache to e. A nil cache disables caching.
+func (e *Executor) SetCache(c *ExecCache) {
+	e.cache = c
+}
+
+// Cache returns the executor's cache, or nil.
+func (e *Executor) Cache() *ExecCache {
+	return e.cache
+}
+
+// executeCached looks c and input up in the cache before executing. The
+// cache holds reduced matrices, so keys and entries do not depend on the
+// denominators the dataflow happens to carry.
+func (e *Executor) executeCached(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	key := cacheKey{circuit: QGID(CircuitToValue(c)), input: QGID(MatrixToValue(input.ToMatrix()))}
+	if out, ok := e.cache.get(key); ok {
+		return ToIntMatrix(out), nil
+	}
+	out, err := e.execute(c, input)
+	if err != nil {
+		return nil, err
+	}
+	e.cache.put(key, out.ToMatrix())
+	return out, nil
+}
@@ -0,0 +1,191 @@
+package runtime
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// cacheFixture stores Tensor(U, U) on Q(3)⊗Q(3) and returns it.
+func cacheFixture(r *rand.Rand, store *Store) Circuit {
+	u := putUnitary(store, randMatrix(r, 3, 3))
+	joint := tensorObject(QuantumObject(3), QuantumObject(3))
+	c, _ := store.Get(store.Put(Circuit{Domain: joint, Codomain: joint, Prim: PrimTensor, Children: [][32]byte{u, u}}))
+	return c
+}
+
+func TestExecCacheMemoizes(t *testing.T) {
+	r := rand.New(rand.NewSource(15))
+	store := NewStore()
+	root := cacheFixture(r, store)
+	rho := Kronecker(ket0bra0Dim(3), ket0bra0Dim(3))
+
+	want, err := NewExecutor(store).Execute(root, rho)
+	if err != nil {
+		t.Fatalf("uncached Execute failed: %v", err)
+	}
+
+	cache, err := NewExecCache(CacheConfig{})
+	if err != nil {
+		t.Fatalf("NewExecCache failed: %v", err)
+	}
+	exec := NewExecutor(store)
+	exec.SetCache(cache)
+
+	got, err := exec.Execute(root, rho)
+	if err != nil {
+		t.Fatalf("cached Execute failed: %v", err)
+	}
+	if !MatrixEqual(got, want) {
+		t.Fatal("cached result differs from uncached result")
+	}
+	// Most slices of |00⟩⟨00| are zero, so U sees repeated inputs.
+	first := cache.Stats()
+	if first.Hits == 0 || first.Misses == 0 {
+		t.Errorf("first run: got %+v, want both hits and misses", first)
+	}
+
+	got, err = exec.Execute(root, rho)
+	if err != nil || !MatrixEqual(got, want) {
+		t.Fatal("second run should return the cached result")
+	}
+	second := cache.Stats()
+	if second.Hits != first.Hits+1 || second.Misses != first.Misses {
+		t.Errorf("second run should be a single hit: before %+v, after %+v", first, second)
+	}
+
+	// Returned matrices are copies.
+	got.Set(0, 0, QIZero())
+	again, _ := exec.Execute(root, rho)
+	if !MatrixEqual(again, want) {
+		t.Error("mutating a returned result should not change the cache")
+	}
+}
+
+func TestExecCacheLimits(t *testing.T) {
+	r := rand.New(rand.NewSource(16))
+	store := NewStore()
+	root := cacheFixture(r, store)
+
+	cache, _ := NewExecCache(CacheConfig{MaxEntries: 3})
+	exec := NewExecutor(store)
+	exec.SetCache(cache)
+	for trial := 0; trial < 4; trial++ {
+		if _, err := exec.Execute(root, randDensity(r, 9)); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	s := cache.Stats()
+	if s.Entries > 3 || s.Evictions == 0 {
+		t.Errorf("got %+v, want at most 3 entries and some evictions", s)
+	}
+
+	small, _ := NewExecCache(CacheConfig{MaxBytes: 1})
+	exec.SetCache(small)
+	if _, err := exec.Execute(root, randDensity(r, 9)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if s := small.Stats(); s.Entries != 0 || s.Bytes != 0 {
+		t.Errorf("a 1-byte cache should hold nothing, got %+v", s)
+	}
+}
+
+func TestExecCacheDisk(t *testing.T) {
+	r := rand.New(rand.NewSource(17))
+	dir := t.TempDir()
+	store := NewStore()
+	root := cacheFixture(r, store)
+	rho := randDensity(r, 9)
+
+	first, _ := NewExecCache(CacheConfig{Dir: dir})
+	exec := NewExecutor(store)
+	exec.SetCache(first)
+	want, err := exec.Execute(root, rho)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// A fresh cache over the same directory answers the root from disk.
+	second, _ := NewExecCache(CacheConfig{Dir: dir})
+	exec = NewExecutor(store)
+	exec.SetCache(second)
+	got, err := exec.Execute(root, rho)
+	if err != nil || !MatrixEqual(got, want) {
+		t.Fatal("disk-cached result differs")
+	}
+	if s := second.Stats(); s.DiskHits != 1 || s.Misses != 0 {
+		t.Errorf("got %+v, want exactly one disk hit", s)
+	}
+
+	// Corrupt files are misses, not errors.
+	files, _ := filepath.Glob(filepath.Join(dir, "*.qv"))
+	for _, f := range files {
+		if err := os.WriteFile(f, []byte{0xFF}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	third, _ := NewExecCache(CacheConfig{Dir: dir})
+	exec = NewExecutor(store)
+	exec.SetCache(third)
+	got, err = exec.Execute(root, rho)
+	if err != nil || !MatrixEqual(got, want) {
+		t.Fatal("result after corrupting the cache differs")
+	}
+	if s := third.Stats(); s.DiskHits != 0 || s.Misses == 0 {
+		t.Errorf("corrupt files should miss, got %+v", s)
+	}
+}
+
+func TestExecCacheWriteFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache, err := NewExecCache(CacheConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replace the directory with a regular file so every write fails,
+	// even when the test runs as root.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	x := Circuit{
+		Domain:   Object{Blocks: []uint32{2}},
+		Codomain: Object{Blocks: []uint32{2}},
+		Prim:     PrimUnitary,
+		Data:     MatrixToValue(pauliX()),
+	}
+	exec := NewExecutor(NewStore())
+	exec.SetCache(cache)
+	got, err := exec.Execute(x, ket0bra0())
+	if err != nil {
+		t.Fatalf("a failed cache write should not fail Execute: %v", err)
+	}
+	if !MatrixEqual(got, ket1bra1()) {
+		t.Error("X|0⟩⟨0|X should be |1⟩⟨1|")
+	}
+	if s := cache.Stats(); s.WriteErrors != 1 || s.Entries != 1 {
+		t.Errorf("got %+v, want one write error and one memory entry", s)
+	}
+	if cache.WriteErr() == nil {
+		t.Error("WriteErr should report the failed write")
+	}
+
+	// The result is still served from memory.
+	if _, err := exec.Execute(x, ket0bra0()); err != nil {
+		t.Fatal(err)
+	}
+	if s := cache.Stats(); s.Hits != 1 {
+		t.Errorf("got %+v, want one memory hit", s)
+	}
+}
+
+// ket0bra0Dim returns |0⟩⟨0| on C^n.
+func ket0bra0Dim(n int) *Matrix {
+	m := NewMatrix(n, n)
+	m.Set(0, 0, QIOne())
+	return m
+}
@@ -0,0 +1,501 @@
+package runtime
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Channel representations.
+//
+// A channel Φ: Q(d_in) → Q(d_out) has several exact representations, all
+// over Q(i) and all interconvertible here:
+//
+//	Kraus        Φ(ρ) = Σ_k K_k ρ K_k†                 K_k is d_out×d_in
+//	Choi         J = Σ_ij |i⟩⟨j| ⊗ Φ(|i⟩⟨j|)          (d_in·d_out)², input factor first
+//	Liouville    vec(Φ(ρ)) = N·vec(ρ)                  d_out²×d_in², vec stacks rows
+//	Pauli (PTM)  Φ(P_β) = Σ_α R[α,β] P_α               4^m×4^n for qubit registers
+//	Stinespring  Φ(ρ) = Tr_env(V ρ V†)                 V is (d_out·r)×d_in, environment last
+//
+// The Choi matrix is unnormalized, matching PrimChoi, so J[(i,a),(j,b)] =
+// Φ(|i⟩⟨j|)[a,b]; it is PSD exactly when Φ is completely positive and its
+// rank is the minimal number of Kraus operators. With row stacking,
+// vec(KρK†) = (K ⊗ K̄)·vec(ρ), and N is the realignment of J.
+//
+// Kraus operators are recovered from a Choi matrix by LDL† elimination,
+// J = Σ_k w_k l_k l_k† with w_k > 0 rational, which always gives an exact
+// weighted family of minimal size. Absorbing √w_k into l_k stays inside
+// Q(i) exactly when w_k is a sum of two rational squares, |c|² = w_k with
+// c ∈ Q(i); ChoiToKraus reports an error otherwise.
+
+// KrausToChoi returns the Choi matrix Σ_k |K_k⟩⟩⟨⟨K_k| of the map
+// ρ ↦ Σ_k K_k ρ K_k†.
+func KrausToChoi(ops []*Matrix) (*Matrix, error) {
+	outDim, inDim, err := krausShape(ops)
+	if err != nil {
+		return nil, err
+	}
+	J := NewMatrix(inDim*outDim, inDim*outDim)
+	for _, K := range ops {
+		v := krausVector(K)
+		J = MatAdd(J, OuterProduct(v, v))
+	}
+	return J, nil
+}
+
+// WeightedKrausToChoi returns the Choi matrix of ρ ↦ Σ_k w_k K_k ρ K_k†,
+// the form produced by ChoiToWeightedKraus.
+func WeightedKrausToChoi(ops []*Matrix, weights []*big.Rat) (*Matrix, error) {
+	if len(weights) != len(ops) {
+		return nil, fmt.Errorf("channel: %d weights for %d Kraus operators", len(weights), len(ops))
+	}
+	outDim, inDim, err := krausShape(ops)
+	if err != nil {
+		return nil, err
+	}
+	J := NewMatrix(inDim*outDim, inDim*outDim)
+	for k, K := range ops {
+		if weights[k] == nil {
+			return nil, fmt.Errorf("channel: Kraus operator %d has no weight", k)
+		}
+		v := krausVector(K)
+		J = MatAdd(J, MatScale(OuterProduct(v, v), weights[k]))
+	}
+	return J, nil
+}
+
+// KrausToLiouville returns the natural representation Σ_k K_k ⊗ K̄_k.
+func KrausToLiouville(ops []*Matrix) (*Matrix, error) {
+	outDim, inDim, err := krausShape(ops)
+	if err != nil {
+		return nil, err
+	}
+	N := NewMatrix(outDim*outDim, inDim*inDim)
+	for _, K := range ops {
+		N = MatAdd(N, Kronecker(K, matConj(K)))
+	}
+	return N, nil
+}
+
+// KrausToStinespring returns the isometry V = Σ_k K_k ⊗ |k⟩ with the
+// environment as the last tensor factor, so V[a·r+k, i] = K_k[a,i].
+func KrausToStinespring(ops []*Matrix) (*Matrix, error) {
+	outDim, inDim, err := krausShape(ops)
+	if err != nil {
+		return nil, err
+	}
+	r := len(ops)
+	V := NewMatrix(outDim*r, inDim)
+	for k, K := range ops {
+		for a := 0; a < outDim; a++ {
+			for i := 0; i < inDim; i++ {
+				V.Set(a*r+k, i, K.Get(a, i))
+			}
+		}
+	}
+	return V, nil
+}
+
+// StinespringToKraus splits an isometry V: d_in → d_out ⊗ env back into
+// its Kraus operators K_k = (I ⊗ ⟨k|)V.
+func StinespringToKraus(V *Matrix, outDim int) ([]*Matrix, error) {
+	if V == nil || outDim <= 0 || V.Rows == 0 || V.Rows%outDim != 0 {
+		return nil, fmt.Errorf("channel: Stinespring operator does not factor through output dimension %d", outDim)
+	}
+	r := V.Rows / outDim
+	ops := make([]*Matrix, r)
+	for k := range ops {
+		K := NewMatrix(outDim, V.Cols)
+		for a := 0; a < outDim; a++ {
+			for i := 0; i < V.Cols; i++ {
+				K.Set(a, i, V.Get(a*r+k, i))
+			}
+		}
+		ops[k] = K
+	}
+	return ops, nil
+}
+
+// ChoiToLiouville realigns a Choi matrix into the natural representation,
+// N[(a,b),(i,j)] = J[(i,a),(j,b)].
+func ChoiToLiouville(J *Matrix, inDim, outDim int) (*Matrix, error) {
+	if err := checkChoi(J, inDim, outDim); err != nil {
+		return nil, err
+	}
+	N := NewMatrix(outDim*outDim, inDim*inDim)
+	for i := 0; i < inDim; i++ {
+		for j := 0; j < inDim; j++ {
+			for a := 0; a < outDim; a++ {
+				for b := 0; b < outDim; b++ {
+					N.Set(a*outDim+b, i*inDim+j, J.Get(i*outDim+a, j*outDim+b))
+				}
+			}
+		}
+	}
+	return N, nil
+}
+
+// LiouvilleToChoi inverts ChoiToLiouville.
+func LiouvilleToChoi(N *Matrix, inDim, outDim int) (*Matrix, error) {
+	if N == nil || inDim <= 0 || outDim <= 0 || N.Rows != outDim*outDim || N.Cols != inDim*inDim {
+		return nil, fmt.Errorf("channel: Liouville matrix must be %dx%d", outDim*outDim, inDim*inDim)
+	}
+	J := NewMatrix(inDim*outDim, inDim*outDim)
+	for i := 0; i < inDim; i++ {
+		for j := 0; j < inDim; j++ {
+			for a := 0; a < outDim; a++ {
+				for b := 0; b < outDim; b++ {
+					J.Set(i*outDim+a, j*outDim+b, N.Get(a*outDim+b, i*inDim+j))
+				}
+			}
+		}
+	}
+	return J, nil
+}
+
+// PauliBasis returns the 4^n Pauli operators on n qubits. Index α reads
+// its base-4 digits as I, X, Y, Z on each qubit, first qubit most
+// significant, matching the Kronecker order of the factors.
+func PauliBasis(n int) []*Matrix {
+	one, zero := big.NewRat(1, 1), new(big.Rat)
+	minus := big.NewRat(-1, 1)
+	single := [4]*Matrix{Identity(2), NewMatrix(2, 2), NewMatrix(2, 2), NewMatrix(2, 2)}
+	single[1].Set(0, 1, QIOne())
+	single[1].Set(1, 0, QIOne())
+	single[2].Set(0, 1, NewQI(zero, minus))
+	single[2].Set(1, 0, NewQI(zero, one))
+	single[3].Set(0, 0, QIOne())
+	single[3].Set(1, 1, NewQI(minus, zero))
+
+	basis := []*Matrix{Identity(1)}
+	for q := 0; q < n; q++ {
+		next := make([]*Matrix, 0, 4*len(basis))
+		for _, P := range basis {
+			for _, s := range single {
+				next = append(next, Kronecker(P, s))
+			}
+		}
+		basis = next
+	}
+	return basis
+}
+
+// ChoiToPTM returns the Pauli transfer matrix R[α,β] = Tr(P_α Φ(P_β))/d_out
+// of a channel between qubit registers. R is real exactly when Φ preserves
+// Hermiticity.
+func ChoiToPTM(J *Matrix, inDim, outDim int) (*Matrix, error) {
+	if err := checkChoi(J, inDim, outDim); err != nil {
+		return nil, err
+	}
+	inQubits, ok1 := qubitCount(inDim)
+	outQubits, ok2 := qubitCount(outDim)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("channel: Pauli transfer matrix needs qubit registers, got %d → %d", inDim, outDim)
+	}
+	inBasis, outBasis := PauliBasis(inQubits), PauliBasis(outQubits)
+	scale := big.NewRat(1, int64(outDim))
+	R := NewMatrix(len(outBasis), len(inBasis))
+	for beta, Pb := range inBasis {
+		image := choiApply(J, Pb, inDim, outDim)
+		for alpha, Pa := range outBasis {
+			R.Set(alpha, beta, QIScale(Trace(MatMul(Pa, image)), scale))
+		}
+	}
+	return R, nil
+}
+
+// PTMToChoi inverts ChoiToPTM using Φ(|i⟩⟨j|) = Σ_β P_β[j,i] Φ(P_β)/d_in.
+func PTMToChoi(R *Matrix, inDim, outDim int) (*Matrix, error) {
+	inQubits, ok1 := qubitCount(inDim)
+	outQubits, ok2 := qubitCount(outDim)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("channel: Pauli transfer matrix needs qubit registers, got %d → %d", inDim, outDim)
+	}
+	inBasis, outBasis := PauliBasis(inQubits), PauliBasis(outQubits)
+	if R == nil || R.Rows != len(outBasis) || R.Cols != len(inBasis) {
+		return nil, fmt.Errorf("channel: Pauli transfer matrix must be %dx%d", len(outBasis), len(inBasis))
+	}
+	images := make([]*Matrix, len(inBasis))
+	for beta := range inBasis {
+		image := NewMatrix(outDim, outDim)
+		for alpha, Pa := range outBasis {
+			if r := R.Get(alpha, beta); !QIIsZero(r) {
+				image = MatAdd(image, matScaleQI(Pa, r))
+			}
+		}
+		images[beta] = image
+	}
+	scale := big.NewRat(1, int64(inDim))
+	J := NewMatrix(inDim*outDim, inDim*outDim)
+	for i := 0; i < inDim; i++ {
+		for j := 0; j < inDim; j++ {
+			block := NewMatrix(outDim, outDim)
+			for beta, Pb := range inBasis {
+				if c := Pb.Get(j, i); !QIIsZero(c) {
+					block = MatAdd(block, matScaleQI(images[beta], c))
+				}
+			}
+			for a := 0; a < outDim; a++ {
+				for b := 0; b < outDim; b++ {
+					J.Set(i*outDim+a, j*outDim+b, QIScale(block.Get(a, b), scale))
+				}
+			}
+		}
+	}
+	return J, nil
+}
+
+// KrausRank returns the rank of a Choi matrix, the minimal number of Kraus
+// operators of the channel.
+func KrausRank(J *Matrix) int {
+	if J == nil {
+		return 0
+	}
+	a := J.Clone()
+	rank := 0
+	for col := 0; col < a.Cols && rank < a.Rows; col++ {
+		pivot := -1
+		for i := rank; i < a.Rows; i++ {
+			if !QIIsZero(a.Get(i, col)) {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		for k := 0; k < a.Cols; k++ {
+			x, y := a.Get(rank, k), a.Get(pivot, k)
+			a.Set(rank, k, y)
+			a.Set(pivot, k, x)
+		}
+		inv, _ := QIInv(a.Get(rank, col))
+		for i := rank + 1; i < a.Rows; i++ {
+			f := QIMul(a.Get(i, col), inv)
+			if QIIsZero(f) {
+				continue
+			}
+			for k := col; k < a.Cols; k++ {
+				a.Set(i, k, QISub(a.Get(i, k), QIMul(f, a.Get(rank, k))))
+			}
+		}
+		rank++
+	}
+	return rank
+}
+
+// ChoiToWeightedKraus decomposes the Choi matrix of a completely positive
+// map as Φ(ρ) = Σ_k w_k K_k ρ K_k† with rational w_k > 0. The number of
+// operators is KrausRank(J). It fails if J is not Hermitian PSD.
+func ChoiToWeightedKraus(J *Matrix, inDim, outDim int) ([]*Matrix, []*big.Rat, error) {
+	if err := checkChoi(J, inDim, outDim); err != nil {
+		return nil, nil, err
+	}
+	if !MatrixEqual(J, Dagger(J)) {
+		return nil, nil, fmt.Errorf("channel: Choi matrix is not Hermitian")
+	}
+
+	// Outer-product LDL†: peel w·l·l† off the remainder column by column.
+	// A PSD matrix never has a negative pivot, and a zero pivot has a zero
+	// column below it.
+	n := J.Rows
+	S := J.Clone()
+	var ops []*Matrix
+	var weights []*big.Rat
+	for k := 0; k < n; k++ {
+		d := S.Get(k, k)
+		if d.Re.Sign() < 0 {
+			return nil, nil, fmt.Errorf("channel: Choi matrix is not positive semidefinite")
+		}
+		if d.Re.Sign() == 0 {
+			for i := k + 1; i < n; i++ {
+				if !QIIsZero(S.Get(i, k)) {
+					return nil, nil, fmt.Errorf("channel: Choi matrix is not positive semidefinite")
+				}
+			}
+			continue
+		}
+		inv := new(big.Rat).Inv(d.Re)
+		l := NewMatrix(n, 1)
+		for i := k; i < n; i++ {
+			l.Set(i, 0, QIScale(S.Get(i, k), inv))
+		}
+		S = MatSub(S, MatScale(OuterProduct(l, l), d.Re))
+		ops = append(ops, krausFromVector(l, inDim, outDim))
+		weights = append(weights, new(big.Rat).Set(d.Re))
+	}
+	if len(ops) == 0 {
+		return nil, nil, fmt.Errorf("channel: Choi matrix is zero")
+	}
+	return ops, weights, nil
+}
+
+// ChoiToKraus returns Kraus operators over Q(i) for the Choi matrix of a
+// completely positive map, or an error if some LDL† weight is not a sum
+// of two rational squares; ChoiToWeightedKraus always succeeds for such a
+// map.
+func ChoiToKraus(J *Matrix, inDim, outDim int) ([]*Matrix, error) {
+	ops, weights, err := ChoiToWeightedKraus(J, inDim, outDim)
+	if err != nil {
+		return nil, err
+	}
+	for k, w := range weights {
+		c, ok := gaussianSqrt(w)
+		if !ok {
+			return nil, fmt.Errorf("channel: weight %s has no square root |c|² in Q(i)", w.RatString())
+		}
+		ops[k] = matScaleQI(ops[k], c)
+	}
+	return ops, nil
+}
+
+// ChannelKraus returns Kraus operators for a PrimKraus, PrimUnitary or
+// PrimChoi circuit.
+func ChannelKraus(c Circuit) ([]*Matrix, error) {
+	switch c.Prim {
+	case PrimKraus:
+		return KrausFromValue(c.Data)
+	case PrimUnitary:
+		U, ok := MatrixFromValue(c.Data)
+		if !ok {
+			return nil, fmt.Errorf("unitary data must be matrix")
+		}
+		return []*Matrix{U}, nil
+	case PrimChoi:
+		J, ok := MatrixFromValue(c.Data)
+		if !ok {
+			return nil, fmt.Errorf("choi data must be matrix")
+		}
+		return ChoiToKraus(J, BlockDim(c.Domain), BlockDim(c.Codomain))
+	}
+	return nil, fmt.Errorf("channel: %s is not a channel primitive", PrimName(c.Prim))
+}
+
+// ChannelChoi returns the Choi matrix of a PrimKraus, PrimUnitary or
+// PrimChoi circuit.
+func ChannelChoi(c Circuit) (*Matrix, error) {
+	if c.Prim == PrimChoi {
+		J, ok := MatrixFromValue(c.Data)
+		if !ok {
+			return nil, fmt.Errorf("choi data must be matrix")
+		}
+		return J, nil
+	}
+	ops, err := ChannelKraus(c)
+	if err != nil {
+		return nil, err
+	}
+	return KrausToChoi(ops)
+}
+
+// krausShape checks that every operator has the shape of the first.
+func krausShape(ops []*Matrix) (outDim, inDim int, err error) {
+	if len(ops) == 0 || ops[0] == nil || ops[0].Rows == 0 || ops[0].Cols == 0 {
+		return 0, 0, fmt.Errorf("channel: no Kraus operators")
+	}
+	outDim, inDim = ops[0].Rows, ops[0].Cols
+	for k, K := range ops {
+		if K == nil || K.Rows != outDim || K.Cols != inDim {
+			return 0, 0, fmt.Errorf("channel: Kraus operator %d is not %dx%d", k, outDim, inDim)
+		}
+	}
+	return outDim, inDim, nil
+}
+
+// checkChoi checks that J is square of side d_in·d_out.
+func checkChoi(J *Matrix, inDim, outDim int) error {
+	n := inDim * outDim
+	if J == nil || inDim <= 0 || outDim <= 0 || J.Rows != n || J.Cols != n {
+		return fmt.Errorf("channel: Choi matrix must be %dx%d", n, n)
+	}
+	return nil
+}
+
+// krausVector returns |K⟩⟩ with entry K[a,i] at index i·d_out+a.
+func krausVector(K *Matrix) *Matrix {
+	v := NewMatrix(K.Rows*K.Cols, 1)
+	for i := 0; i < K.Cols; i++ {
+		for a := 0; a < K.Rows; a++ {
+			v.Set(i*K.Rows+a, 0, K.Get(a, i))
+		}
+	}
+	return v
+}
+
+// krausFromVector inverts krausVector.
+func krausFromVector(v *Matrix, inDim, outDim int) *Matrix {
+	K := NewMatrix(outDim, inDim)
+	for i := 0; i < inDim; i++ {
+		for a := 0; a < outDim; a++ {
+			K.Set(a, i, v.Get(i*outDim+a, 0))
+		}
+	}
+	return K
+}
+
+// choiApply returns Φ(X) = Σ_ij X[i,j] Φ(|i⟩⟨j|) for any d_in×d_in X.
+func choiApply(J, X *Matrix, inDim, outDim int) *Matrix {
+	out := NewMatrix(outDim, outDim)
+	for i := 0; i < inDim; i++ {
+		for j := 0; j < inDim; j++ {
+			x := X.Get(i, j)
+			if QIIsZero(x) {
+				continue
+			}
+			for a := 0; a < outDim; a++ {
+				for b := 0; b < outDim; b++ {
+					out.Set(a, b, QIAdd(out.Get(a, b), QIMul(x, J.Get(i*outDim+a, j*outDim+b))))
+				}
+			}
+		}
+	}
+	return out
+}
+
+// matConj returns the entrywise complex conjugate.
+func matConj(A *Matrix) *Matrix {
+	C := NewMatrix(A.Rows, A.Cols)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			C.Set(i, j, QIConj(A.Get(i, j)))
+		}
+	}
+	return C
+}
+
+// qubitCount returns n with d = 2^n.
+func qubitCount(d int) (int, bool) {
+	n := 0
+	for d > 1 && d%2 == 0 {
+		d /= 2
+		n++
+	}
+	return n, d == 1
+}
+
+// maxTwoSquares bounds the integers searched for a sum of two squares.
+const maxTwoSquares = 1 << 40
+
+// gaussianSqrt finds c ∈ Q(i) with |c|² = w for rational w > 0. Writing
+// w = p/q in lowest terms, |c|² = w with c = (x + iy)/q needs
+// x² + y² = p·q, which is searched directly for moderate p·q.
+func gaussianSqrt(w *big.Rat) (QI, bool) {
+	if w.Sign() <= 0 {
+		return QI{}, false
+	}
+	q := w.Denom()
+	n := new(big.Int).Mul(w.Num(), q)
+	if n.Cmp(big.NewInt(maxTwoSquares)) > 0 {
+		return QI{}, false
+	}
+	target := n.Int64()
+	for x := int64(0); x*x <= target; x++ {
+		rest := target - x*x
+		y := new(big.Int).Sqrt(big.NewInt(rest)).Int64()
+		if y*y == rest {
+			re := new(big.Rat).SetFrac(big.NewInt(x), q)
+			im := new(big.Rat).SetFrac(big.NewInt(y), q)
+			return NewQI(re, im), true
+		}
+	}
+	return QI{}, false
+}
@@ -0,0 +1,220 @@
+package runtime
+
+import (
+	"math/big"
+	"testing"
+)
+
+// ratMatrix builds a rows×cols real matrix from row-major rationals.
+func ratMatrix(rows, cols int, entries ...*big.Rat) *Matrix {
+	m := NewMatrix(rows, cols)
+	for k, r := range entries {
+		m.Set(k/cols, k%cols, NewQI(r, new(big.Rat)))
+	}
+	return m
+}
+
+// dampingKraus returns amplitude damping with γ = 9/25.
+func dampingKraus() []*Matrix {
+	r := big.NewRat
+	return []*Matrix{
+		ratMatrix(2, 2, r(1, 1), r(0, 1), r(0, 1), r(4, 5)),
+		ratMatrix(2, 2, r(0, 1), r(3, 5), r(0, 1), r(0, 1)),
+	}
+}
+
+// rhoYExact returns (I + Y)/2, a state with imaginary off-diagonals.
+func rhoYExact() *Matrix {
+	m := NewMatrix(2, 2)
+	m.Set(0, 0, qiHalf())
+	m.Set(1, 1, qiHalf())
+	m.Set(0, 1, NewQI(new(big.Rat), big.NewRat(-1, 2)))
+	m.Set(1, 0, NewQI(new(big.Rat), big.NewRat(1, 2)))
+	return m
+}
+
+// rowVec stacks the rows of A into a column vector.
+func rowVec(A *Matrix) *Matrix {
+	v := NewMatrix(A.Rows*A.Cols, 1)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			v.Set(i*A.Cols+j, 0, A.Get(i, j))
+		}
+	}
+	return v
+}
+
+func TestChoiExecutionMatchesKraus(t *testing.T) {
+	exec := NewExecutor(NewStore())
+	qubit := Object{Blocks: []uint32{2}}
+	ops := dampingKraus()
+	J, err := KrausToChoi(ops)
+	if err != nil {
+		t.Fatalf("KrausToChoi: %v", err)
+	}
+
+	kraus := Circuit{Domain: qubit, Codomain: qubit, Prim: PrimKraus, Data: KrausToValue(ops)}
+	choi := Circuit{Domain: qubit, Codomain: qubit, Prim: PrimChoi, Data: MatrixToValue(J)}
+	for _, rho := range []*Matrix{rhoPlusExact(), rhoYExact(), ket1bra1()} {
+		want, err := exec.Execute(kraus, rho)
+		if err != nil {
+			t.Fatalf("Execute Kraus: %v", err)
+		}
+		got, err := exec.Execute(choi, rho)
+		if err != nil {
+			t.Fatalf("Execute Choi: %v", err)
+		}
+		if !MatrixEqual(got, want) {
+			t.Errorf("PrimChoi and PrimKraus disagree on input %v", rho.Data)
+		}
+	}
+
+	fromCircuit, err := ChannelChoi(kraus)
+	if err != nil || !MatrixEqual(fromCircuit, J) {
+		t.Errorf("ChannelChoi(PrimKraus) = %v, %v", fromCircuit, err)
+	}
+}
+
+func TestLiouvilleAndPTM(t *testing.T) {
+	ops := dampingKraus()
+	J, _ := KrausToChoi(ops)
+
+	N, err := ChoiToLiouville(J, 2, 2)
+	if err != nil {
+		t.Fatalf("ChoiToLiouville: %v", err)
+	}
+	direct, _ := KrausToLiouville(ops)
+	if !MatrixEqual(N, direct) {
+		t.Error("realigned Choi matrix differs from Σ K ⊗ K̄")
+	}
+	rho := rhoYExact()
+	image, _ := krausSum(ops, rho, 2)
+	if !MatrixEqual(MatMul(N, rowVec(rho)), rowVec(image)) {
+		t.Error("Liouville matrix does not act as vec(ρ) ↦ vec(Φ(ρ))")
+	}
+	back, err := LiouvilleToChoi(N, 2, 2)
+	if err != nil || !MatrixEqual(back, J) {
+		t.Errorf("LiouvilleToChoi round trip failed: %v", err)
+	}
+
+	// Amplitude damping has PTM [[1,0,0,0],[0,√(1-γ),0,0],[0,0,√(1-γ),0],[γ,0,0,1-γ]].
+	r := big.NewRat
+	want := ratMatrix(4, 4,
+		r(1, 1), r(0, 1), r(0, 1), r(0, 1),
+		r(0, 1), r(4, 5), r(0, 1), r(0, 1),
+		r(0, 1), r(0, 1), r(4, 5), r(0, 1),
+		r(9, 25), r(0, 1), r(0, 1), r(16, 25),
+	)
+	R, err := ChoiToPTM(J, 2, 2)
+	if err != nil {
+		t.Fatalf("ChoiToPTM: %v", err)
+	}
+	if !MatrixEqual(R, want) {
+		t.Errorf("amplitude damping PTM = %v", R.Data)
+	}
+	back, err = PTMToChoi(R, 2, 2)
+	if err != nil || !MatrixEqual(back, J) {
+		t.Errorf("PTMToChoi round trip failed: %v", err)
+	}
+	if _, err := ChoiToPTM(NewMatrix(9, 9), 3, 3); err == nil {
+		t.Error("ChoiToPTM accepted a qutrit channel")
+	}
+}
+
+func TestKrausFromChoi(t *testing.T) {
+	ops := dampingKraus()
+	J, _ := KrausToChoi(ops)
+	if rank := KrausRank(J); rank != 2 {
+		t.Errorf("amplitude damping Kraus rank = %d, want 2", rank)
+	}
+
+	// The redundant family {K_0, K_1, K_1} has the same minimal rank.
+	redundant, _ := KrausToChoi(append(ops, ops[1]))
+	if rank := KrausRank(redundant); rank != 2 {
+		t.Errorf("redundant family Kraus rank = %d, want 2", rank)
+	}
+	extracted, err := ChoiToKraus(redundant, 2, 2)
+	if err != nil {
+		t.Fatalf("ChoiToKraus: %v", err)
+	}
+	if len(extracted) != 2 {
+		t.Errorf("ChoiToKraus returned %d operators, want 2", len(extracted))
+	}
+	if rebuilt, _ := KrausToChoi(extracted); !MatrixEqual(rebuilt, redundant) {
+		t.Error("extracted Kraus operators do not reproduce the Choi matrix")
+	}
+
+	// This depolarizing channel has LDL† weights 3/4, 1/4, 1/4, 5/12, and
+	// 3/4 is not |c|² for any c in Q(i); the weighted form stays exact.
+	r := big.NewRat
+	paulis := PauliBasis(1)
+	depol := MatScale(krausToChoiMust(t, paulis[:1]), r(5, 8))
+	for _, P := range paulis[1:] {
+		depol = MatAdd(depol, MatScale(krausToChoiMust(t, []*Matrix{P}), r(1, 8)))
+	}
+	weighted, weights, err := ChoiToWeightedKraus(depol, 2, 2)
+	if err != nil {
+		t.Fatalf("ChoiToWeightedKraus: %v", err)
+	}
+	sum := NewMatrix(4, 4)
+	for k, K := range weighted {
+		sum = MatAdd(sum, MatScale(krausToChoiMust(t, []*Matrix{K}), weights[k]))
+	}
+	if !MatrixEqual(sum, depol) || len(weighted) != 4 {
+		t.Error("weighted Kraus operators do not reproduce the depolarizing channel")
+	}
+	if _, err := ChoiToKraus(depol, 2, 2); err == nil {
+		t.Error("ChoiToKraus found an exact family for a weight of 3/4")
+	}
+
+	// A non-CP map has no Kraus form.
+	transpose := NewMatrix(4, 4)
+	transpose.Set(0, 0, QIOne())
+	transpose.Set(1, 2, QIOne())
+	transpose.Set(2, 1, QIOne())
+	transpose.Set(3, 3, QIOne())
+	if _, _, err := ChoiToWeightedKraus(transpose, 2, 2); err == nil {
+		t.Error("ChoiToWeightedKraus accepted the transpose map")
+	}
+}
+
+func TestStinespring(t *testing.T) {
+	ops := dampingKraus()
+	V, err := KrausToStinespring(ops)
+	if err != nil {
+		t.Fatalf("KrausToStinespring: %v", err)
+	}
+	if V.Rows != 4 || V.Cols != 2 || !MatrixEqual(MatMul(Dagger(V), V), Identity(2)) {
+		t.Error("Stinespring operator of a channel is not an isometry")
+	}
+	back, err := StinespringToKraus(V, 2)
+	if err != nil || len(back) != 2 || !MatrixEqual(back[0], ops[0]) || !MatrixEqual(back[1], ops[1]) {
+		t.Errorf("StinespringToKraus round trip failed: %v", err)
+	}
+
+	// Tracing out the environment of V ρ V† gives Φ(ρ).
+	rho := rhoYExact()
+	dilated := MatMul(MatMul(V, rho), Dagger(V))
+	reduced := NewMatrix(2, 2)
+	for a := 0; a < 2; a++ {
+		for b := 0; b < 2; b++ {
+			for k := 0; k < 2; k++ {
+				reduced.Set(a, b, QIAdd(reduced.Get(a, b), dilated.Get(a*2+k, b*2+k)))
+			}
+		}
+	}
+	want, _ := krausSum(ops, rho, 2)
+	if !MatrixEqual(reduced, want) {
+		t.Error("Tr_env(V ρ V†) differs from Φ(ρ)")
+	}
+}
+
+// krausToChoiMust is KrausToChoi for operators known to be well formed.
+func krausToChoiMust(t *testing.T, ops []*Matrix) *Matrix {
+	t.Helper()
+	J, err := KrausToChoi(ops)
+	if err != nil {
+		t.Fatalf("KrausToChoi: %v", err)
+	}
+	return J
+}
@@ -0,0 +1,264 @@
+package runtime
+
+import (
+	"fmt"
+)
+
+// Structural Choi evaluation.
+//
+// The Choi matrix of a circuit (unnormalized, input factor first, as for
+// PrimChoi; see channel.go) can be found by probing: execute the circuit
+// on every |i⟩⟨j| of its domain, d_in² full executions. Choi evaluates it
+// bottom-up instead:
+//
+//	Compose(f, g)   link product, N_{g∘f} = N_g·N_f on the realignments
+//	Tensor(f, g)    J_f ⊗ J_g with the factors reordered to (in_f, in_g, out_f, out_g)
+//	Add(f, g)       J_f + J_g
+//	Scale(r, f)     r·J_f
+//	Id              Σ_ij |ii⟩⟨jj|
+//	Kraus, Unitary  Σ_k |K_k⟩⟩⟨⟨K_k|
+//	Choi            the stored matrix
+//
+// Every other primitive is a leaf and is probed on its own domain, which
+// is usually small. Input dimensions are BlockDim(Domain), as in
+// execution; output dimensions are read off each child's Choi matrix, so
+// they always agree with what Execute produces.
+
+// Choi returns the Choi matrix of c, computed structurally.
+func (e *Executor) Choi(c Circuit) (*Matrix, error) {
+	J, _, _, err := e.choi(c)
+	return J, err
+}
+
+// ProbeChoi returns the Choi matrix of c by executing it on every basis
+// operator |i⟩⟨j| of its domain.
+func (e *Executor) ProbeChoi(c Circuit) (*Matrix, error) {
+	J, _, _, err := e.probeChoi(c)
+	return J, err
+}
+
+// choi returns the Choi matrix of c with its input and output dimensions.
+func (e *Executor) choi(c Circuit) (*Matrix, int, int, error) {
+	inDim := BlockDim(c.Domain)
+	switch c.Prim {
+	case PrimId:
+		J := NewMatrix(inDim*inDim, inDim*inDim)
+		for i := 0; i < inDim; i++ {
+			for j := 0; j < inDim; j++ {
+				J.Set(i*inDim+i, j*inDim+j, QIOne())
+			}
+		}
+		return J, inDim, inDim, nil
+
+	case PrimCompose:
+		if len(c.Children) < 2 {
+			return nil, 0, 0, fmt.Errorf("compose requires at least 2 children")
+		}
+		var J *Matrix
+		var first, mid int
+		for i, childID := range c.Children {
+			child, ok := e.store.Get(childID)
+			if !ok {
+				return nil, 0, 0, fmt.Errorf("child %d not found", i)
+			}
+			Jc, in, out, err := e.choi(child)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			if i == 0 {
+				J, first, mid = Jc, in, out
+				continue
+			}
+			if in != mid {
+				return nil, 0, 0, fmt.Errorf("choi: compose child %d expects dimension %d, got %d", i, in, mid)
+			}
+			if J, err = LinkProduct(J, Jc, first, mid, out); err != nil {
+				return nil, 0, 0, err
+			}
+			mid = out
+		}
+		return J, first, mid, nil
+
+	case PrimTensor:
+		if len(c.Children) < 2 {
+			return nil, 0, 0, fmt.Errorf("tensor requires at least 2 children")
+		}
+		var J *Matrix
+		var in, out int
+		for i, childID := range c.Children {
+			child, ok := e.store.Get(childID)
+			if !ok {
+				return nil, 0, 0, fmt.Errorf("child %d not found", i)
+			}
+			Jc, inC, outC, err := e.choi(child)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			if i == 0 {
+				J, in, out = Jc, inC, outC
+				continue
+			}
+			J = tensorChoi(J, in, out, Jc, inC, outC)
+			in, out = in*inC, out*outC
+		}
+		if in != inDim {
+			return nil, 0, 0, fmt.Errorf("tensor: children act on dimension %d, domain has %d", in, inDim)
+		}
+		return J, in, out, nil
+
+	case PrimAdd:
+		if len(c.Children) != 2 {
+			return nil, 0, 0, fmt.Errorf("add requires 2 children")
+		}
+		var parts [2]*Matrix
+		var outs [2]int
+		for i, childID := range c.Children {
+			child, ok := e.store.Get(childID)
+			if !ok {
+				return nil, 0, 0, fmt.Errorf("child %d not found", i)
+			}
+			Jc, _, out, err := e.choi(child)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			parts[i], outs[i] = Jc, out
+		}
+		if parts[0].Rows != parts[1].Rows || outs[0] != outs[1] {
+			return nil, 0, 0, fmt.Errorf("add: children have different shapes")
+		}
+		return MatAdd(parts[0], parts[1]), inDim, outs[0], nil
+
+	case PrimScale:
+		if len(c.Children) != 1 {
+			return nil, 0, 0, fmt.Errorf("scale requires 1 child")
+		}
+		r, ok := c.Data.(Rat)
+		if !ok {
+			return nil, 0, 0, fmt.Errorf("scale data must be Rat")
+		}
+		child, ok := e.store.Get(c.Children[0])
+		if !ok {
+			return nil, 0, 0, fmt.Errorf("child not found")
+		}
+		J, in, out, err := e.choi(child)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		return MatScale(J, r.V), in, out, nil
+
+	case PrimKraus, PrimUnitary:
+		ops, err := ChannelKraus(c)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if len(ops) == 0 {
+			// The empty family is the zero map.
+			outDim := BlockDim(c.Codomain)
+			return NewMatrix(inDim*outDim, inDim*outDim), inDim, outDim, nil
+		}
+		if ops[0].Cols != inDim {
+			return nil, 0, 0, fmt.Errorf("%s: operators act on dimension %d, domain has %d",
+				PrimName(c.Prim), ops[0].Cols, inDim)
+		}
+		J, err := KrausToChoi(ops)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		return J, inDim, ops[0].Rows, nil
+
+	case PrimChoi:
+		J, ok := MatrixFromValue(c.Data)
+		if !ok {
+			return nil, 0, 0, fmt.Errorf("choi data must be matrix")
+		}
+		if J.Rows != J.Cols || J.Rows%inDim != 0 {
+			return nil, 0, 0, fmt.Errorf("choi: %dx%d matrix does not fit input dimension %d", J.Rows, J.Cols, inDim)
+		}
+		return J, inDim, J.Rows / inDim, nil
+	}
+	return e.probeChoi(c)
+}
+
+// probeChoi executes c on each |i⟩⟨j| of its domain.
+func (e *Executor) probeChoi(c Circuit) (*Matrix, int, int, error) {
+	inDim := BlockDim(c.Domain)
+	var J *Matrix
+	outDim := 0
+	for i := 0; i < inDim; i++ {
+		for j := 0; j < inDim; j++ {
+			basis := NewMatrix(inDim, inDim)
+			basis.Set(i, j, QIOne())
+			out, err := e.Execute(c, basis)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			if J == nil {
+				outDim = out.Rows
+				J = NewMatrix(inDim*outDim, inDim*outDim)
+			}
+			if out.Rows != outDim || out.Cols != outDim {
+				return nil, 0, 0, fmt.Errorf("choi: %s output on |%d⟩⟨%d| is %dx%d, want %dx%d",
+					PrimName(c.Prim), i, j, out.Rows, out.Cols, outDim, outDim)
+			}
+			for a := 0; a < outDim; a++ {
+				for b := 0; b < outDim; b++ {
+					J.Set(i*outDim+a, j*outDim+b, out.Get(a, b))
+				}
+			}
+		}
+	}
+	return J, inDim, outDim, nil
+}
+
+// LinkProduct returns the Choi matrix of g∘f from the Choi matrices of
+// f: A → B and g: B → C,
+//
+//	J[(i,c),(j,d)] = Σ_ab J_f[(i,a),(j,b)]·J_g[(a,c),(b,d)],
+//
+// computed as the product of their natural representations.
+func LinkProduct(Jf, Jg *Matrix, dimA, dimB, dimC int) (*Matrix, error) {
+	Nf, err := ChoiToLiouville(Jf, dimA, dimB)
+	if err != nil {
+		return nil, err
+	}
+	Ng, err := ChoiToLiouville(Jg, dimB, dimC)
+	if err != nil {
+		return nil, err
+	}
+	return LiouvilleToChoi(MatMul(Ng, Nf), dimA, dimC)
+}
+
+// tensorChoi returns the Choi matrix of f ⊗ g on (A⊗C) → (B⊗D),
+// J[((i,k),(a,c)), ((j,l),(b,d))] = J_f[(i,a),(j,b)]·J_g[(k,c),(l,d)].
+func tensorChoi(Jf *Matrix, inF, outF int, Jg *Matrix, inG, outG int) *Matrix {
+	in, out := inF*inG, outF*outG
+	J := NewMatrix(in*out, in*out)
+	for i := 0; i < inF; i++ {
+		for a := 0; a < outF; a++ {
+			for j := 0; j < inF; j++ {
+				for b := 0; b < outF; b++ {
+					f := Jf.Get(i*outF+a, j*outF+b)
+					if QIIsZero(f) {
+						continue
+					}
+					for k := 0; k < inG; k++ {
+						for c := 0; c < outG; c++ {
+							row := (i*inG+k)*out + a*outG + c
+							for l := 0; l < inG; l++ {
+								for d := 0; d < outG; d++ {
+									g := Jg.Get(k*outG+c, l*outG+d)
+									if QIIsZero(g) {
+										continue
+									}
+									col := (j*inG+l)*out + b*outG + d
+									J.Set(row, col, QIMul(f, g))
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return J
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestStructuralChoiMatchesProbe(t *testing.T) {
+	r := rand.New(rand.NewSource(16))
+	store := NewStore()
+	exec := NewExecutor(store)
+	qutrit := QuantumObject(3)
+	ab := tensorObject(qubit(), qutrit)
+	ba := tensorObject(qutrit, qubit())
+
+	damp := putKraus(store, dampingKraus())
+	u := putUnitary(store, randMatrix(r, 3, 3))
+	swap := store.Put(Circuit{Domain: ab, Codomain: ba, Prim: PrimSwap})
+	shrink := putKraus(store, randKraus(r, 2, 3, 2))
+	discard := store.Put(Circuit{Domain: qubit(), Codomain: unitObject(), Prim: PrimDiscard})
+	prep := store.Put(Circuit{Domain: unitObject(), Codomain: qubit(), Prim: PrimPrepare, Data: MatrixToValue(rhoYExact())})
+
+	// Q(2)⊗Q(3) → Q(2): (damp ⊗ U) ; swap ; (shrink ⊗ discard).
+	layer := store.Put(Circuit{Domain: ab, Codomain: ab, Prim: PrimTensor, Children: [][32]byte{damp, u}})
+	reduce := store.Put(Circuit{Domain: ba, Codomain: qubit(), Prim: PrimTensor, Children: [][32]byte{shrink, discard}})
+	pipeline := store.Put(Circuit{Domain: ab, Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{layer, swap, reduce}})
+
+	// A mixture of the pipeline with "discard everything, prepare (I+Y)/2".
+	reset := store.Put(Circuit{Domain: ab, Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{
+		store.Put(Circuit{Domain: ab, Codomain: unitObject(), Prim: PrimDiscard}), prep,
+	}})
+	scaled := store.Put(Circuit{Domain: ab, Codomain: qubit(), Prim: PrimScale, Children: [][32]byte{pipeline}, Data: MakeRat(2, 3)})
+	mixture := store.Put(Circuit{Domain: ab, Codomain: qubit(), Prim: PrimAdd, Children: [][32]byte{scaled, reset}})
+
+	// The empty Kraus family is the zero map, alone and as a summand.
+	empty := store.Put(Circuit{Domain: ab, Codomain: qubit(), Prim: PrimKraus, Data: KrausToValue(nil)})
+	padded := store.Put(Circuit{Domain: ab, Codomain: qubit(), Prim: PrimAdd, Children: [][32]byte{mixture, empty}})
+
+	for name, id := range map[string][32]byte{"pipeline": pipeline, "reset": reset, "mixture": mixture,
+		"empty": empty, "padded": padded} {
+		c, _ := store.Get(id)
+		want, err := exec.ProbeChoi(c)
+		if err != nil {
+			t.Fatalf("%s: ProbeChoi: %v", name, err)
+		}
+		got, err := exec.Choi(c)
+		if err != nil {
+			t.Fatalf("%s: Choi: %v", name, err)
+		}
+		if got.Rows != 12 || !MatrixEqual(got, want) {
+			t.Errorf("%s: structural Choi matrix differs from probing", name)
+		}
+	}
+}
+
+func TestLinkProduct(t *testing.T) {
+	ops := dampingKraus()
+	J := krausToChoiMust(t, ops)
+
+	// Damping twice with γ = 9/25 is damping with γ = 1 - (16/25)².
+	twice, err := LinkProduct(J, J, 2, 2, 2)
+	if err != nil {
+		t.Fatalf("LinkProduct: %v", err)
+	}
+	var composed []*Matrix
+	for _, A := range ops {
+		for _, B := range ops {
+			composed = append(composed, MatMul(B, A))
+		}
+	}
+	if !MatrixEqual(twice, krausToChoiMust(t, composed)) {
+		t.Error("link product differs from the composed Kraus family")
+	}
+	if twice.Get(3, 3).Re.Cmp(big.NewRat(256, 625)) != 0 {
+		t.Errorf("J[(1,1),(1,1)] = %v, want 256/625", twice.Get(3, 3).Re)
+	}
+
+	if _, err := LinkProduct(J, NewMatrix(9, 9), 2, 2, 2); err == nil {
+		t.Error("LinkProduct accepted a 9x9 matrix as a qubit channel")
+	}
+}
@@ -0,0 +1,457 @@
+package runtime
+
+import (
+	"math/big"
+)
+
+// Exact arithmetic in the cyclotomic field Q(ζ8).
+//
+// Q(ζ8) with ζ8 = e^{iπ/4} = (1+i)/√2 is the same field as Q(i, √2). It is
+// the smallest field containing the entries of the Hadamard and T gates and
+// the amplitudes of |±⟩, |±i⟩ and the Bell states, none of which lie in
+// Q(i). Every element has a unique form a + b√2 with a, b ∈ Q(i), so QZ8 is
+// a degree-2 extension built on QI:
+//
+//	(a + b√2)(c + d√2) = (ac + 2bd) + (ad + bc)√2
+//	(a + b√2)⁻¹       = (a - b√2) / (a² - 2b²)
+//
+// a² - 2b² vanishes only at zero because √2 ∉ Q(i). Complex conjugation
+// fixes √2, so it acts on a and b separately.
+//
+// The executor works over Q(i). Values that are needed there, such as the
+// density matrix |+⟩⟨+| = ½[[1, 1], [1, 1]], are computed exactly in Q(ζ8)
+// and brought back with MatrixZ8ToQI, which fails rather than round.
+// Circuit data over Q(ζ8) that does not lie in Q(i), such as the effects of
+// a measurement at angle π/4, is kept exact; ExecuteZ8 runs it exactly
+// (see cyclotomic_exec.go) and the interval backend encloses it.
+//
+// Encoding:
+//
+//	element: Tag("qz8", Seq(Rat re(a), Rat im(a), Rat re(b), Rat im(b)))
+//	matrix:  Tag("matrix-qz8", Seq(Int rows, Int cols, Seq(element...)))
+//
+// A matrix whose entries all lie in Q(i) is written with MatrixToValue
+// instead, so it has the same QGID as the equal *Matrix.
+
+// QZ8 represents an element a + b√2 of Q(ζ8) = Q(i, √2), with a, b ∈ Q(i).
+type QZ8 struct {
+	A QI
+	B QI
+}
+
+// NewQZ8 creates a + b√2.
+func NewQZ8(a, b QI) QZ8 {
+	return QZ8{
+		A: NewQI(a.Re, a.Im),
+		B: NewQI(b.Re, b.Im),
+	}
+}
+
+// QZ8FromQI embeds a Gaussian rational in Q(ζ8).
+func QZ8FromQI(q QI) QZ8 {
+	return QZ8{A: NewQI(q.Re, q.Im), B: QIZero()}
+}
+
+// QZ8Zero returns 0.
+func QZ8Zero() QZ8 {
+	return QZ8{A: QIZero(), B: QIZero()}
+}
+
+// QZ8One returns 1.
+func QZ8One() QZ8 {
+	return QZ8{A: QIOne(), B: QIZero()}
+}
+
+// QZ8Sqrt2 returns √2.
+func QZ8Sqrt2() QZ8 {
+	return QZ8{A: QIZero(), B: QIOne()}
+}
+
+// QZ8Zeta returns the primitive eighth root of unity ζ8 = (1+i)/√2 = (1+i)√2/2.
+func QZ8Zeta() QZ8 {
+	half := big.NewRat(1, 2)
+	return QZ8{A: QIZero(), B: NewQI(half, half)}
+}
+
+// QZ8InvSqrt returns 1/√n. It is in Q(ζ8) exactly when n = m² or n = 2m²,
+// and ok is false otherwise.
+func QZ8InvSqrt(n int) (QZ8, bool) {
+	if n <= 0 {
+		return QZ8Zero(), false
+	}
+	if m := intSqrtExact(n); m > 0 {
+		return QZ8FromQI(NewQI(big.NewRat(1, int64(m)), new(big.Rat))), true
+	}
+	if n%2 == 0 {
+		if m := intSqrtExact(n / 2); m > 0 {
+			// 1/(m√2) = √2/(2m)
+			return QZ8{A: QIZero(), B: NewQI(big.NewRat(1, int64(2*m)), new(big.Rat))}, true
+		}
+	}
+	return QZ8Zero(), false
+}
+
+// intSqrtExact returns m with m² = n, or 0 if n is not a perfect square.
+func intSqrtExact(n int) int {
+	m := int(new(big.Int).Sqrt(big.NewInt(int64(n))).Int64())
+	if m*m == n {
+		return m
+	}
+	return 0
+}
+
+// QZ8Neg returns -q.
+func QZ8Neg(q QZ8) QZ8 {
+	return QZ8{A: QINeg(q.A), B: QINeg(q.B)}
+}
+
+// QZ8Add returns x + y.
+func QZ8Add(x, y QZ8) QZ8 {
+	return QZ8{A: QIAdd(x.A, y.A), B: QIAdd(x.B, y.B)}
+}
+
+// QZ8Sub returns x - y.
+func QZ8Sub(x, y QZ8) QZ8 {
+	return QZ8{A: QISub(x.A, y.A), B: QISub(x.B, y.B)}
+}
+
+// QZ8Mul returns x * y.
+// (a + b√2)(c + d√2) = (ac + 2bd) + (ad + bc)√2
+func QZ8Mul(x, y QZ8) QZ8 {
+	bd := QIMul(x.B, y.B)
+	return QZ8{
+		A: QIAdd(QIMul(x.A, y.A), QIAdd(bd, bd)),
+		B: QIAdd(QIMul(x.A, y.B), QIMul(x.B, y.A)),
+	}
+}
+
+// QZ8Conj returns the complex conjugate of q.
+func QZ8Conj(q QZ8) QZ8 {
+	return QZ8{A: QIConj(q.A), B: QIConj(q.B)}
+}
+
+// QZ8Inv returns 1/q = (a - b√2)/(a² - 2b²).
+func QZ8Inv(q QZ8) (QZ8, bool) {
+	b2 := QIMul(q.B, q.B)
+	norm := QISub(QIMul(q.A, q.A), QIAdd(b2, b2))
+	inv, ok := QIInv(norm)
+	if !ok {
+		return QZ8Zero(), false
+	}
+	return QZ8{A: QIMul(q.A, inv), B: QINeg(QIMul(q.B, inv))}, true
+}
+
+// QZ8Div returns x/y.
+func QZ8Div(x, y QZ8) (QZ8, bool) {
+	inv, ok := QZ8Inv(y)
+	if !ok {
+		return QZ8Zero(), false
+	}
+	return QZ8Mul(x, inv), true
+}
+
+// QZ8Equal checks if two elements are equal.
+func QZ8Equal(x, y QZ8) bool {
+	return QIEqual(x.A, y.A) && QIEqual(x.B, y.B)
+}
+
+// QZ8IsZero checks if q is zero.
+func QZ8IsZero(q QZ8) bool {
+	return QIIsZero(q.A) && QIIsZero(q.B)
+}
+
+// QZ8Scale multiplies q by a rational.
+func QZ8Scale(q QZ8, r *big.Rat) QZ8 {
+	return QZ8{A: QIScale(q.A, r), B: QIScale(q.B, r)}
+}
+
+// QZ8ToQI returns q as a Gaussian rational if its √2 part is zero.
+func QZ8ToQI(q QZ8) (QI, bool) {
+	if !QIIsZero(q.B) {
+		return QIZero(), false
+	}
+	return NewQI(q.A.Re, q.A.Im), true
+}
+
+// MatrixZ8 represents a matrix over Q(ζ8).
+type MatrixZ8 struct {
+	Rows int
+	Cols int
+	Data []QZ8 // row-major
+}
+
+// NewMatrixZ8 creates a zero matrix.
+func NewMatrixZ8(rows, cols int) *MatrixZ8 {
+	data := make([]QZ8, rows*cols)
+	for i := range data {
+		data[i] = QZ8Zero()
+	}
+	return &MatrixZ8{Rows: rows, Cols: cols, Data: data}
+}
+
+// Get returns the element at (i, j).
+func (m *MatrixZ8) Get(i, j int) QZ8 {
+	return m.Data[i*m.Cols+j]
+}
+
+// Set sets the element at (i, j).
+func (m *MatrixZ8) Set(i, j int, v QZ8) {
+	m.Data[i*m.Cols+j] = v
+}
+
+// Clone creates a copy of the matrix.
+func (m *MatrixZ8) Clone() *MatrixZ8 {
+	c := NewMatrixZ8(m.Rows, m.Cols)
+	for i, q := range m.Data {
+		c.Data[i] = NewQZ8(q.A, q.B)
+	}
+	return c
+}
+
+// IdentityZ8 creates an identity matrix.
+func IdentityZ8(n int) *MatrixZ8 {
+	m := NewMatrixZ8(n, n)
+	for i := 0; i < n; i++ {
+		m.Set(i, i, QZ8One())
+	}
+	return m
+}
+
+// MatrixToZ8 embeds a matrix over Q(i) in Q(ζ8).
+func MatrixToZ8(m *Matrix) *MatrixZ8 {
+	r := NewMatrixZ8(m.Rows, m.Cols)
+	for i, q := range m.Data {
+		r.Data[i] = QZ8FromQI(q)
+	}
+	return r
+}
+
+// MatrixZ8ToQI returns m as a matrix over Q(i) if every entry has zero √2
+// part.
+func MatrixZ8ToQI(m *MatrixZ8) (*Matrix, bool) {
+	r := NewMatrix(m.Rows, m.Cols)
+	for i, q := range m.Data {
+		qi, ok := QZ8ToQI(q)
+		if !ok {
+			return nil, false
+		}
+		r.Data[i] = qi
+	}
+	return r, true
+}
+
+// MatMulZ8 computes A * B.
+func MatMulZ8(A, B *MatrixZ8) *MatrixZ8 {
+	if A.Cols != B.Rows {
+		return nil
+	}
+	C := NewMatrixZ8(A.Rows, B.Cols)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < B.Cols; j++ {
+			sum := QZ8Zero()
+			for k := 0; k < A.Cols; k++ {
+				sum = QZ8Add(sum, QZ8Mul(A.Get(i, k), B.Get(k, j)))
+			}
+			C.Set(i, j, sum)
+		}
+	}
+	return C
+}
+
+// MatAddZ8 computes A + B.
+func MatAddZ8(A, B *MatrixZ8) *MatrixZ8 {
+	if A.Rows != B.Rows || A.Cols != B.Cols {
+		return nil
+	}
+	C := NewMatrixZ8(A.Rows, A.Cols)
+	for i := range A.Data {
+		C.Data[i] = QZ8Add(A.Data[i], B.Data[i])
+	}
+	return C
+}
+
+// MatSubZ8 computes A - B.
+func MatSubZ8(A, B *MatrixZ8) *MatrixZ8 {
+	if A.Rows != B.Rows || A.Cols != B.Cols {
+		return nil
+	}
+	C := NewMatrixZ8(A.Rows, A.Cols)
+	for i := range A.Data {
+		C.Data[i] = QZ8Sub(A.Data[i], B.Data[i])
+	}
+	return C
+}
+
+// MatScaleZ8 computes r * A.
+func MatScaleZ8(A *MatrixZ8, r *big.Rat) *MatrixZ8 {
+	C := NewMatrixZ8(A.Rows, A.Cols)
+	for i := range A.Data {
+		C.Data[i] = QZ8Scale(A.Data[i], r)
+	}
+	return C
+}
+
+// DaggerZ8 computes the conjugate transpose.
+func DaggerZ8(A *MatrixZ8) *MatrixZ8 {
+	C := NewMatrixZ8(A.Cols, A.Rows)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			C.Set(j, i, QZ8Conj(A.Get(i, j)))
+		}
+	}
+	return C
+}
+
+// TraceZ8 computes the trace of a square matrix.
+func TraceZ8(A *MatrixZ8) QZ8 {
+	sum := QZ8Zero()
+	n := A.Rows
+	if A.Cols < n {
+		n = A.Cols
+	}
+	for i := 0; i < n; i++ {
+		sum = QZ8Add(sum, A.Get(i, i))
+	}
+	return sum
+}
+
+// KroneckerZ8 computes the Kronecker product A ⊗ B.
+func KroneckerZ8(A, B *MatrixZ8) *MatrixZ8 {
+	C := NewMatrixZ8(A.Rows*B.Rows, A.Cols*B.Cols)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			a := A.Get(i, j)
+			for k := 0; k < B.Rows; k++ {
+				for l := 0; l < B.Cols; l++ {
+					C.Set(i*B.Rows+k, j*B.Cols+l, QZ8Mul(a, B.Get(k, l)))
+				}
+			}
+		}
+	}
+	return C
+}
+
+// OuterProductZ8 computes |u⟩⟨v| (u * v†).
+func OuterProductZ8(u, v *MatrixZ8) *MatrixZ8 {
+	return MatMulZ8(u, DaggerZ8(v))
+}
+
+// MatrixEqualZ8 checks if two matrices are equal.
+func MatrixEqualZ8(A, B *MatrixZ8) bool {
+	if A.Rows != B.Rows || A.Cols != B.Cols {
+		return false
+	}
+	for i := range A.Data {
+		if !QZ8Equal(A.Data[i], B.Data[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encoding for Q(ζ8) matrices
+
+// MatrixZ8ToValue converts a matrix to a Value. A matrix with entries in
+// Q(i) is encoded exactly as MatrixToValue would encode it.
+func MatrixZ8ToValue(m *MatrixZ8) Value {
+	if qi, ok := MatrixZ8ToQI(m); ok {
+		return MatrixToValue(qi)
+	}
+	items := make([]Value, len(m.Data))
+	for i, q := range m.Data {
+		items[i] = MakeTag(
+			MakeText("qz8"),
+			MakeSeq(
+				MakeBigRat(q.A.Re),
+				MakeBigRat(q.A.Im),
+				MakeBigRat(q.B.Re),
+				MakeBigRat(q.B.Im),
+			),
+		)
+	}
+	return MakeTag(
+		MakeText("matrix-qz8"),
+		MakeSeq(
+			MakeInt(int64(m.Rows)),
+			MakeInt(int64(m.Cols)),
+			MakeSeq(items...),
+		),
+	)
+}
+
+// MatrixZ8FromValue parses a matrix from a Value written by MatrixZ8ToValue
+// or MatrixToValue.
+func MatrixZ8FromValue(v Value) (*MatrixZ8, bool) {
+	if m, ok := MatrixFromValue(v); ok {
+		return MatrixToZ8(m), true
+	}
+	tag, ok := v.(Tag)
+	if !ok {
+		return nil, false
+	}
+	label, ok := tag.Label.(Text)
+	if !ok || label.V != "matrix-qz8" {
+		return nil, false
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok || len(seq.Items) != 3 {
+		return nil, false
+	}
+	rows, ok := seq.Items[0].(Int)
+	if !ok || !rows.V.IsInt64() || rows.V.Sign() < 0 {
+		return nil, false
+	}
+	cols, ok := seq.Items[1].(Int)
+	if !ok || !cols.V.IsInt64() || cols.V.Sign() < 0 {
+		return nil, false
+	}
+	data, ok := seq.Items[2].(Seq)
+	if !ok || int64(len(data.Items)) != rows.V.Int64()*cols.V.Int64() {
+		return nil, false
+	}
+
+	m := NewMatrixZ8(int(rows.V.Int64()), int(cols.V.Int64()))
+	for i, item := range data.Items {
+		q, ok := qz8FromValue(item)
+		if !ok {
+			return nil, false
+		}
+		m.Data[i] = q
+	}
+	return m, true
+}
+
+// POVMZ8ToValue encodes POVM effects over Q(ζ8) as POVMToValue does,
+// writing each effect with MatrixZ8ToValue.
+func POVMZ8ToValue(effects []*MatrixZ8) Value {
+	items := make([]Value, len(effects))
+	for i, E := range effects {
+		items[i] = MatrixZ8ToValue(E)
+	}
+	return MakeTag(MakeText("povm"), MakeSeq(items...))
+}
+
+// qz8FromValue parses a QZ8 from a Value.
+func qz8FromValue(v Value) (QZ8, bool) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return QZ8Zero(), false
+	}
+	label, ok := tag.Label.(Text)
+	if !ok || label.V != "qz8" {
+		return QZ8Zero(), false
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok || len(seq.Items) != 4 {
+		return QZ8Zero(), false
+	}
+	var parts [4]*big.Rat
+	for i, item := range seq.Items {
+		r, ok := item.(Rat)
+		if !ok {
+			return QZ8Zero(), false
+		}
+		parts[i] = r.V
+	}
+	return NewQZ8(NewQI(parts[0], parts[1]), NewQI(parts[2], parts[3])), true
+}
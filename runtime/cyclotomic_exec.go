@@ -0,0 +1,420 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exact execution over Q(ζ8).
+//
+// Execute works over Q(i). It applies POVM effects over Q(ζ8), such as the
+// projectors of a measurement at angle π/4, whenever the outcome
+// probabilities they give lie in Q(i), and fails with ErrNotQI otherwise.
+// ExecuteZ8 runs the same circuits on states over Q(ζ8) and returns the
+// exact result.
+//
+// Every primitive except Prepare and Witness on a non-scalar input is
+// linear in its input, so a state ρ = A + √2·B is carried as its two Q(i)
+// parts. A subtree whose data lies in Q(i), run on a state in Q(i), goes
+// through Execute unchanged, with its caching and parallelism. Above data
+// over Q(ζ8), Compose, Tensor (slice by slice, as applyFactor does), Bisum,
+// Branch, Add and Scale are evaluated here; POVM effects are applied as
+// Tr(E ρ), Prepare and Witness scale their state by the scalar input, and
+// every other primitive runs through Execute on A and B separately.
+
+// ErrNotQI is returned by Execute when a result does not lie in Q(i).
+var ErrNotQI = errors.New("result is not over Q(i)")
+
+// ExecuteZ8 executes c on a state over Q(ζ8).
+func (e *Executor) ExecuteZ8(c Circuit, input *MatrixZ8) (*MatrixZ8, error) {
+	z := &z8Exec{e: e, plain: make(map[[32]byte]bool)}
+	return z.run(c, input)
+}
+
+// z8Exec holds the state of one ExecuteZ8 call.
+type z8Exec struct {
+	e     *Executor
+	plain map[[32]byte]bool // whether a child's data all lies in Q(i), by QGID
+}
+
+// run dispatches on c.Prim.
+func (z *z8Exec) run(c Circuit, input *MatrixZ8) (*MatrixZ8, error) {
+	a, b := splitZ8(input)
+	if b == nil && z.isPlain(c) {
+		out, err := z.e.Execute(c, a)
+		if err != nil {
+			return nil, err
+		}
+		return MatrixToZ8(out), nil
+	}
+
+	switch c.Prim {
+	case PrimCompose:
+		if len(c.Children) < 2 {
+			return nil, fmt.Errorf("compose requires at least 2 children")
+		}
+		current := input
+		for i, childID := range c.Children {
+			child, ok := z.e.store.Get(childID)
+			if !ok {
+				return nil, fmt.Errorf("child %d not found", i)
+			}
+			next, err := z.run(child, current)
+			if err != nil {
+				return nil, err
+			}
+			current = next
+		}
+		return current, nil
+
+	case PrimTensor:
+		return z.tensor(c, input)
+
+	case PrimBisum, PrimBranch:
+		return z.blocks(c, input)
+
+	case PrimAdd:
+		if len(c.Children) != 2 {
+			return nil, fmt.Errorf("add requires 2 children")
+		}
+		var results [2]*MatrixZ8
+		for i, childID := range c.Children {
+			child, ok := z.e.store.Get(childID)
+			if !ok {
+				return nil, fmt.Errorf("child %d not found", i)
+			}
+			out, err := z.run(child, input)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = out
+		}
+		sum := MatAddZ8(results[0], results[1])
+		if sum == nil {
+			return nil, fmt.Errorf("add: children have different output dimensions")
+		}
+		return sum, nil
+
+	case PrimScale:
+		if len(c.Children) != 1 {
+			return nil, fmt.Errorf("scale requires 1 child")
+		}
+		r, ok := c.Data.(Rat)
+		if !ok {
+			return nil, fmt.Errorf("scale data must be Rat")
+		}
+		child, ok := z.e.store.Get(c.Children[0])
+		if !ok {
+			return nil, fmt.Errorf("child not found")
+		}
+		out, err := z.run(child, input)
+		if err != nil {
+			return nil, err
+		}
+		return MatScaleZ8(out, r.V), nil
+
+	case PrimInstrument:
+		if label, ok := instrumentLabel(c.Data); ok && label == "povm" {
+			return z.povm(c, input)
+		}
+
+	case PrimPrepare, PrimWitness:
+		rho, ok := MatrixZ8FromValue(c.Data)
+		if !ok {
+			return nil, fmt.Errorf("prepare data must be matrix")
+		}
+		if input.Rows == 1 && input.Cols == 1 {
+			w := input.Get(0, 0)
+			for i, q := range rho.Data {
+				rho.Data[i] = QZ8Mul(q, w)
+			}
+		}
+		return rho, nil
+	}
+
+	// The remaining primitives carry data over Q(i) and are linear.
+	outA, err := z.e.Execute(c, a)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return MatrixToZ8(outA), nil
+	}
+	outB, err := z.e.Execute(c, b)
+	if err != nil {
+		return nil, err
+	}
+	return joinZ8(outA, outB), nil
+}
+
+// isPlain reports whether the data of c and of every circuit below it lies
+// in Q(i). A missing child counts as plain; Execute reports it.
+func (z *z8Exec) isPlain(c Circuit) bool {
+	if valueHasZ8(c.Data) {
+		return false
+	}
+	for _, id := range c.Children {
+		plain, ok := z.plain[id]
+		if !ok {
+			plain = true
+			if child, found := z.e.store.Get(id); found {
+				plain = z.isPlain(child)
+			}
+			z.plain[id] = plain
+		}
+		if !plain {
+			return false
+		}
+	}
+	return true
+}
+
+// valueHasZ8 reports whether v contains a matrix written with the Q(ζ8)
+// encoding.
+func valueHasZ8(v Value) bool {
+	switch v := v.(type) {
+	case Tag:
+		if label, ok := v.Label.(Text); ok && label.V == "matrix-qz8" {
+			return true
+		}
+		return valueHasZ8(v.Label) || valueHasZ8(v.Payload)
+	case Seq:
+		for _, item := range v.Items {
+			if valueHasZ8(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// instrumentLabel returns the label of an instrument payload.
+func instrumentLabel(v Value) (string, bool) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return "", false
+	}
+	label, ok := tag.Label.(Text)
+	return label.V, ok
+}
+
+// povm applies a "povm" instrument whose effects may lie in Q(ζ8).
+func (z *z8Exec) povm(c Circuit, input *MatrixZ8) (*MatrixZ8, error) {
+	seq, ok := c.Data.(Tag).Payload.(Seq)
+	if !ok {
+		return nil, fmt.Errorf("instrument: payload must be a Seq")
+	}
+	if len(c.Codomain.Blocks) != len(seq.Items) {
+		return nil, fmt.Errorf("instrument: codomain has %d blocks, data has %d outcomes",
+			len(c.Codomain.Blocks), len(seq.Items))
+	}
+	result := NewMatrixZ8(len(seq.Items), len(seq.Items))
+	for i, item := range seq.Items {
+		if c.Codomain.Blocks[i] != 1 {
+			return nil, fmt.Errorf("instrument: povm outcome %d has block size %d, want 1",
+				i, c.Codomain.Blocks[i])
+		}
+		E, ok := MatrixZ8FromValue(item)
+		if !ok {
+			return nil, fmt.Errorf("instrument: effect %d is not a valid matrix", i)
+		}
+		p := MatMulZ8(E, input)
+		if p == nil || p.Rows != p.Cols {
+			return nil, fmt.Errorf("instrument: dimension mismatch for effect %d", i)
+		}
+		result.Set(i, i, TraceZ8(p))
+	}
+	return result, nil
+}
+
+// tensor applies each child on its own factor, as applyTensor does.
+func (z *z8Exec) tensor(c Circuit, input *MatrixZ8) (*MatrixZ8, error) {
+	if len(c.Children) < 2 {
+		return nil, fmt.Errorf("tensor requires at least 2 children")
+	}
+	children := make([]Circuit, len(c.Children))
+	dims := make([]int, len(c.Children))
+	total := 1
+	for i, childID := range c.Children {
+		child, ok := z.e.store.Get(childID)
+		if !ok {
+			return nil, fmt.Errorf("child %d not found", i)
+		}
+		children[i] = child
+		dims[i] = BlockDim(child.Domain)
+		total *= dims[i]
+	}
+	if input.Rows != total || input.Cols != total {
+		return nil, fmt.Errorf("tensor: input is %dx%d, want %dx%d",
+			input.Rows, input.Cols, total, total)
+	}
+
+	current := input
+	for k, child := range children {
+		pre := 1
+		for _, d := range dims[:k] {
+			pre *= d
+		}
+		post := 1
+		for _, d := range dims[k+1:] {
+			post *= d
+		}
+		out, err := z.factor(child, k, current, pre, dims[k], post)
+		if err != nil {
+			return nil, err
+		}
+		dims[k] = out.Rows / (pre * post)
+		current = out
+	}
+	return current, nil
+}
+
+// factor computes (id_pre ⊗ f ⊗ id_post)(ρ) slice by slice.
+func (z *z8Exec) factor(f Circuit, k int, input *MatrixZ8, pre, d, post int) (*MatrixZ8, error) {
+	var result *MatrixZ8
+	eF := 0
+	for a := 0; a < pre; a++ {
+		for b := 0; b < pre; b++ {
+			for p := 0; p < post; p++ {
+				for q := 0; q < post; q++ {
+					slice := NewMatrixZ8(d, d)
+					for i := 0; i < d; i++ {
+						for j := 0; j < d; j++ {
+							slice.Set(i, j, input.Get((a*d+i)*post+p, (b*d+j)*post+q))
+						}
+					}
+					out, err := z.run(f, slice)
+					if err != nil {
+						return nil, err
+					}
+					if result == nil {
+						eF = out.Rows
+						n := pre * eF * post
+						result = NewMatrixZ8(n, n)
+					}
+					if out.Rows != eF || out.Cols != eF {
+						return nil, fmt.Errorf("tensor: child %d output is %dx%d, want %dx%d",
+							k, out.Rows, out.Cols, eF, eF)
+					}
+					for i := 0; i < eF; i++ {
+						for j := 0; j < eF; j++ {
+							result.Set((a*eF+i)*post+p, (b*eF+j)*post+q, out.Get(i, j))
+						}
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// blocks runs child i on the i-th diagonal block of the input and returns
+// the direct sum of the outputs (Bisum) or their sum (Branch).
+func (z *z8Exec) blocks(c Circuit, input *MatrixZ8) (*MatrixZ8, error) {
+	name := "bisum"
+	if c.Prim == PrimBranch {
+		name = "branch"
+	}
+	if len(c.Children) == 0 {
+		return nil, fmt.Errorf("%s requires at least 1 child", name)
+	}
+	if dim := BlockDim(c.Domain); input.Rows != dim || input.Cols != dim {
+		return nil, fmt.Errorf("%s: input is %dx%d, domain dim is %d", name, input.Rows, input.Cols, dim)
+	}
+	if c.Prim == PrimBranch && len(c.Children) != len(c.Domain.Blocks) {
+		return nil, fmt.Errorf("branch: domain has %d blocks, got %d children",
+			len(c.Domain.Blocks), len(c.Children))
+	}
+
+	outputs := make([]*MatrixZ8, len(c.Children))
+	offset := 0
+	for i, childID := range c.Children {
+		child, ok := z.e.store.Get(childID)
+		if !ok {
+			return nil, fmt.Errorf("child %d not found", i)
+		}
+		if c.Prim == PrimBisum && len(child.Domain.Blocks) == 0 {
+			return nil, fmt.Errorf("%s: child %d domain has no blocks", name, i)
+		}
+		n := BlockDim(child.Domain)
+		if c.Prim == PrimBranch && n != int(c.Domain.Blocks[i]) {
+			return nil, fmt.Errorf("branch: child %d domain dim %d does not match block size %d",
+				i, n, c.Domain.Blocks[i])
+		}
+		if offset+n > input.Rows {
+			return nil, fmt.Errorf("%s: child %d overruns the %d-dimensional input", name, i, input.Rows)
+		}
+		block := NewMatrixZ8(n, n)
+		for r := 0; r < n; r++ {
+			for s := 0; s < n; s++ {
+				block.Set(r, s, input.Get(offset+r, offset+s))
+			}
+		}
+		out, err := z.run(child, block)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = out
+		offset += n
+	}
+	if offset != input.Rows {
+		return nil, fmt.Errorf("%s: children cover %d of %d dimensions", name, offset, input.Rows)
+	}
+	if c.Prim == PrimBisum {
+		return directSumZ8(outputs...), nil
+	}
+	result := outputs[0]
+	for i, out := range outputs[1:] {
+		if result = MatAddZ8(result, out); result == nil {
+			return nil, fmt.Errorf("branch: child %d output dimension mismatch", i+1)
+		}
+	}
+	return result, nil
+}
+
+// splitZ8 returns the parts A and B of m = A + √2·B, with B nil when it is
+// zero.
+func splitZ8(m *MatrixZ8) (a, b *Matrix) {
+	a = NewMatrix(m.Rows, m.Cols)
+	b = NewMatrix(m.Rows, m.Cols)
+	zero := true
+	for i, q := range m.Data {
+		a.Data[i] = NewQI(q.A.Re, q.A.Im)
+		if !QIIsZero(q.B) {
+			b.Data[i] = NewQI(q.B.Re, q.B.Im)
+			zero = false
+		}
+	}
+	if zero {
+		return a, nil
+	}
+	return a, b
+}
+
+// joinZ8 returns A + √2·B.
+func joinZ8(a, b *Matrix) *MatrixZ8 {
+	m := NewMatrixZ8(a.Rows, a.Cols)
+	for i := range m.Data {
+		m.Data[i] = NewQZ8(a.Data[i], b.Data[i])
+	}
+	return m
+}
+
+// directSumZ8 is DirectSum over Q(ζ8).
+func directSumZ8(blocks ...*MatrixZ8) *MatrixZ8 {
+	n := 0
+	for _, B := range blocks {
+		n += B.Rows
+	}
+	m := NewMatrixZ8(n, n)
+	offset := 0
+	for _, B := range blocks {
+		for i := 0; i < B.Rows; i++ {
+			for j := 0; j < B.Cols; j++ {
+				m.Set(offset+i, offset+j, B.Get(i, j))
+			}
+		}
+		offset += B.Rows
+	}
+	return m
+}
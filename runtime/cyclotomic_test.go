@@ -0,0 +1,288 @@
+package runtime
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randQZ8 returns a random element of Q(ζ8) with small coefficients.
+func randQZ8(r *rand.Rand) QZ8 {
+	return NewQZ8(randQI(r), randQI(r))
+}
+
+func TestQZ8RootsOfUnity(t *testing.T) {
+	zeta := QZ8Zeta()
+
+	z2 := QZ8Mul(zeta, zeta)
+	if !QZ8Equal(z2, QZ8FromQI(QII())) {
+		t.Error("ζ8² should be i")
+	}
+	z4 := QZ8Mul(z2, z2)
+	if !QZ8Equal(z4, QZ8Neg(QZ8One())) {
+		t.Error("ζ8⁴ should be -1")
+	}
+	if !QZ8Equal(QZ8Mul(zeta, QZ8Conj(zeta)), QZ8One()) {
+		t.Error("ζ8 ζ̄8 should be 1")
+	}
+
+	sqrt2 := QZ8Sqrt2()
+	if !QZ8Equal(QZ8Mul(sqrt2, sqrt2), QZ8FromQI(NewQI(big.NewRat(2, 1), new(big.Rat)))) {
+		t.Error("√2² should be 2")
+	}
+	// ζ8 + ζ̄8 = √2
+	if !QZ8Equal(QZ8Add(zeta, QZ8Conj(zeta)), sqrt2) {
+		t.Error("ζ8 + ζ̄8 should be √2")
+	}
+}
+
+func TestQZ8FieldOperations(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+
+	for trial := 0; trial < 50; trial++ {
+		x, y, z := randQZ8(r), randQZ8(r), randQZ8(r)
+
+		// Distributivity and commutativity
+		lhs := QZ8Mul(x, QZ8Add(y, z))
+		rhs := QZ8Add(QZ8Mul(x, y), QZ8Mul(x, z))
+		if !QZ8Equal(lhs, rhs) {
+			t.Fatalf("trial %d: x(y+z) != xy + xz", trial)
+		}
+		if !QZ8Equal(QZ8Mul(x, y), QZ8Mul(y, x)) {
+			t.Fatalf("trial %d: xy != yx", trial)
+		}
+
+		// Conjugation is multiplicative
+		if !QZ8Equal(QZ8Conj(QZ8Mul(x, y)), QZ8Mul(QZ8Conj(x), QZ8Conj(y))) {
+			t.Fatalf("trial %d: conj(xy) != conj(x)conj(y)", trial)
+		}
+
+		if QZ8IsZero(x) {
+			continue
+		}
+		inv, ok := QZ8Inv(x)
+		if !ok {
+			t.Fatalf("trial %d: nonzero element has no inverse", trial)
+		}
+		if !QZ8Equal(QZ8Mul(x, inv), QZ8One()) {
+			t.Fatalf("trial %d: x x⁻¹ != 1", trial)
+		}
+		q, ok := QZ8Div(y, x)
+		if !ok || !QZ8Equal(QZ8Mul(q, x), y) {
+			t.Fatalf("trial %d: (y/x) x != y", trial)
+		}
+	}
+
+	if _, ok := QZ8Inv(QZ8Zero()); ok {
+		t.Error("zero should have no inverse")
+	}
+}
+
+func TestQZ8InvSqrt(t *testing.T) {
+	for _, n := range []int{1, 2, 4, 8, 9, 18, 50} {
+		s, ok := QZ8InvSqrt(n)
+		if !ok {
+			t.Errorf("1/√%d should be in Q(ζ8)", n)
+			continue
+		}
+		// n · s² = 1
+		sq := QZ8Scale(QZ8Mul(s, s), big.NewRat(int64(n), 1))
+		if !QZ8Equal(sq, QZ8One()) {
+			t.Errorf("n·(1/√%d)² != 1", n)
+		}
+	}
+	for _, n := range []int{0, 3, 5, 6, 12} {
+		if _, ok := QZ8InvSqrt(n); ok {
+			t.Errorf("1/√%d should not be in Q(ζ8)", n)
+		}
+	}
+}
+
+func TestMatrixZ8Hadamard(t *testing.T) {
+	s, _ := QZ8InvSqrt(2)
+	H := NewMatrixZ8(2, 2)
+	H.Set(0, 0, s)
+	H.Set(0, 1, s)
+	H.Set(1, 0, s)
+	H.Set(1, 1, QZ8Neg(s))
+
+	if !MatrixEqualZ8(MatMulZ8(DaggerZ8(H), H), IdentityZ8(2)) {
+		t.Error("H†H should be exactly I")
+	}
+
+	// |+⟩⟨+| = H|0⟩⟨0|H† has rational entries 1/2.
+	ket0 := NewMatrixZ8(2, 1)
+	ket0.Set(0, 0, QZ8One())
+	plus := MatMulZ8(H, ket0)
+	rho, ok := MatrixZ8ToQI(OuterProductZ8(plus, plus))
+	if !ok {
+		t.Fatal("|+⟩⟨+| should lie in Q(i)")
+	}
+	want := NewMatrix(2, 2)
+	for i := range want.Data {
+		want.Data[i] = NewQI(big.NewRat(1, 2), new(big.Rat))
+	}
+	if !MatrixEqual(rho, want) {
+		t.Error("|+⟩⟨+| should be exactly ½[[1,1],[1,1]]")
+	}
+
+	if _, ok := MatrixZ8ToQI(H); ok {
+		t.Error("H should not lie in Q(i)")
+	}
+
+	// (H ⊗ H)† (H ⊗ H) = I
+	HH := KroneckerZ8(H, H)
+	if !MatrixEqualZ8(MatMulZ8(DaggerZ8(HH), HH), IdentityZ8(4)) {
+		t.Error("(H⊗H)†(H⊗H) should be exactly I")
+	}
+	if !QZ8IsZero(TraceZ8(H)) {
+		t.Error("Tr H should be 0")
+	}
+}
+
+func TestMatrixZ8Encoding(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+
+	m := NewMatrixZ8(2, 3)
+	for i := range m.Data {
+		m.Data[i] = randQZ8(r)
+	}
+	m.Set(0, 0, QZ8Zeta())
+
+	back, ok := MatrixZ8FromValue(MatrixZ8ToValue(m))
+	if !ok || !MatrixEqualZ8(back, m) {
+		t.Fatal("MatrixZ8 should round-trip through its Value encoding")
+	}
+
+	// Matrices over Q(i) keep their QGID.
+	q := randMatrix(r, 2, 2)
+	if QGID(MatrixZ8ToValue(MatrixToZ8(q))) != QGID(MatrixToValue(q)) {
+		t.Error("Q(i) matrix should encode exactly as MatrixToValue")
+	}
+	if back, ok := MatrixZ8FromValue(MatrixToValue(q)); !ok || !MatrixEqualZ8(back, MatrixToZ8(q)) {
+		t.Error("MatrixZ8FromValue should accept plain matrices")
+	}
+
+	// Round trip through a .qmb binary.
+	store := NewStore()
+	id := store.PutValue(MatrixZ8ToValue(m))
+	bin := Embed(store, id, "qz8", "1")
+	runner, err := NewRunner(bin.Encode())
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	v, ok := runner.GetValue(id)
+	if !ok {
+		t.Fatal("value not found after .qmb round trip")
+	}
+	loaded, ok := MatrixZ8FromValue(v)
+	if !ok || !MatrixEqualZ8(loaded, m) {
+		t.Error("MatrixZ8 should round-trip through .qmb")
+	}
+}
+
+// rotatedPOVM returns the projectors (I ± (X+Z)/√2)/2 of a measurement at
+// angle π/4, as a qubit → C(2) instrument.
+func rotatedPOVM() Circuit {
+	entry := func(a, b int64) QZ8 {
+		return NewQZ8(qiRat(a, 2), qiRat(b, 4))
+	}
+	plus, minus := NewMatrixZ8(2, 2), NewMatrixZ8(2, 2)
+	for i, s := range []int64{1, -1} {
+		P := plus
+		if i == 1 {
+			P = minus
+		}
+		P.Set(0, 0, entry(1, s))
+		P.Set(0, 1, entry(0, s))
+		P.Set(1, 0, entry(0, s))
+		P.Set(1, 1, entry(1, -s))
+	}
+	return Circuit{
+		Domain:   qubit(),
+		Codomain: ClassicalObject(2),
+		Prim:     PrimInstrument,
+		Data:     POVMZ8ToValue([]*MatrixZ8{plus, minus}),
+	}
+}
+
+// diagZ8 returns the diagonal matrix with entries a_k + b_k√2 for
+// consecutive pairs (a_k, b_k) of quarters.
+func diagZ8(quarters ...int64) *MatrixZ8 {
+	n := len(quarters) / 2
+	m := NewMatrixZ8(n, n)
+	for k := 0; k < n; k++ {
+		m.Set(k, k, NewQZ8(qiRat(quarters[2*k], 4), qiRat(quarters[2*k+1], 4)))
+	}
+	return m
+}
+
+func TestExecuteZ8POVM(t *testing.T) {
+	exec := NewExecutor(NewStore())
+	measure := rotatedPOVM()
+
+	// On |0⟩ the outcomes have probabilities (2 ± √2)/4.
+	if _, err := exec.Execute(measure, ket0bra0()); !errors.Is(err, ErrNotQI) {
+		t.Errorf("Execute on |0⟩ = %v, want ErrNotQI", err)
+	}
+	got, err := exec.ExecuteZ8(measure, MatrixToZ8(ket0bra0()))
+	if err != nil {
+		t.Fatalf("ExecuteZ8 failed: %v", err)
+	}
+	if want := diagZ8(2, 1, 2, -1); !MatrixEqualZ8(got, want) {
+		t.Errorf("ExecuteZ8 = %v, want %v", got, want)
+	}
+
+	// On I/2 they are rational, and Execute applies the effects itself.
+	mixed, err := exec.Execute(measure, MatScale(Identity(2), big.NewRat(1, 2)))
+	if err != nil {
+		t.Fatalf("Execute on I/2 failed: %v", err)
+	}
+	if !MatrixEqual(mixed, MatScale(Identity(2), big.NewRat(1, 2))) {
+		t.Errorf("Execute on I/2 = %v, want I/2", mixed)
+	}
+}
+
+func TestExecuteZ8Composite(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+	measure := store.Put(rotatedPOVM())
+	flip := store.Put(Circuit{
+		Domain:   ClassicalObject(2),
+		Codomain: ClassicalObject(2),
+		Prim:     PrimKraus,
+		Data:     KrausToValue([]*Matrix{pauliX()}),
+	})
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+
+	// The √2 part passes through the Q(i) relabelling.
+	compose := Circuit{
+		Domain:   qubit(),
+		Codomain: ClassicalObject(2),
+		Prim:     PrimCompose,
+		Children: [][32]byte{measure, flip},
+	}
+	got, err := exec.ExecuteZ8(compose, MatrixToZ8(ket0bra0()))
+	if err != nil {
+		t.Fatalf("ExecuteZ8 compose failed: %v", err)
+	}
+	if want := diagZ8(2, -1, 2, 1); !MatrixEqualZ8(got, want) {
+		t.Errorf("measure ; flip = %v, want %v", got, want)
+	}
+
+	// measure ⊗ id on |0⟩⟨0| ⊗ |1⟩⟨1|.
+	tensor := Circuit{
+		Domain:   tensorObject(qubit(), qubit()),
+		Codomain: TensorObjects(ClassicalObject(2), qubit()),
+		Prim:     PrimTensor,
+		Children: [][32]byte{measure, id},
+	}
+	got, err = exec.ExecuteZ8(tensor, MatrixToZ8(Kronecker(ket0bra0(), ket1bra1())))
+	if err != nil {
+		t.Fatalf("ExecuteZ8 tensor failed: %v", err)
+	}
+	if want := KroneckerZ8(diagZ8(2, 1, 2, -1), MatrixToZ8(ket1bra1())); !MatrixEqualZ8(got, want) {
+		t.Errorf("measure ⊗ id = %v, want %v", got, want)
+	}
+}
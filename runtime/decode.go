@@ -0,0 +1,311 @@
+package runtime
+
+import (
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+)
+
+// Value decoding.
+//
+// decodeValue is the lenient inverse of the Encode methods used for v1
+// store data: it accepts any well-formed bytes, including encodings Encode
+// never produces (a small Int in the long form, leading zero bytes, a
+// Rat that is not in lowest terms). Two such encodings of one value have
+// different QGIDs, so content addressing breaks on them.
+//
+// DecodeCanonical accepts exactly the bytes Encode produces:
+//
+//	Int     0x00 for zero, one byte for 1..63, otherwise the long form
+//	        with a nonzero leading magnitude byte
+//	Rat     0x90 00 00 00 for zero, otherwise sign 0x00 or 0x80 and
+//	        nonempty numerator and denominator magnitudes without leading
+//	        zeros, in lowest terms
+//	varint  the shortest form, at most 64 bits
+//	Text    valid UTF-8
+//
+// so for every accepted b, Encode(DecodeCanonical(b)) == b, and for every
+// value v that Encode can represent, DecodeCanonical(Encode(v)) equals v.
+// It also enforces DecodeLimits and requires the value to fill the input.
+// Errors from both modes are *DecodeError values carrying the byte offset
+// of the problem.
+
+// DecodeLimits bounds DecodeCanonical. Zero fields are unlimited.
+type DecodeLimits struct {
+	MaxDepth  int // nesting of Seq and Tag
+	MaxLength int // bytes in one Bytes or Text, items in one Seq
+	MaxValues int // values in the whole input
+}
+
+// DefaultDecodeLimits are the limits used for .qmb v2 entries and the
+// execution cache.
+var DefaultDecodeLimits = DecodeLimits{MaxDepth: 256, MaxLength: 1 << 26, MaxValues: 1 << 22}
+
+// DecodeError reports malformed, non-canonical or over-limit input.
+type DecodeError struct {
+	Offset int // byte offset in the input
+	Msg    string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode: offset %d: %s", e.Offset, e.Msg)
+}
+
+// DecodeCanonical decodes the single value encoded by data, rejecting
+// non-canonical encodings, trailing bytes and input beyond limits.
+func DecodeCanonical(data []byte, limits DecodeLimits) (Value, error) {
+	d := &decoder{data: data, canonical: true, limits: limits}
+	v, end, err := d.value(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if end != len(data) {
+		return nil, d.fail(end, "%d trailing bytes", len(data)-end)
+	}
+	return v, nil
+}
+
+// decodeValue decodes a Value from bytes, returning the value and the number
+// of bytes consumed. This is the complete inverse of the Encode() methods
+// defined on each value type in value.go.
+func decodeValue(data []byte) (Value, int, error) {
+	d := &decoder{data: data}
+	return d.value(0, 0)
+}
+
+// decodeVarint decodes a variable-length integer from data, returning the
+// value and the number of bytes consumed. This is the inverse of
+// encodeVarint() in value.go.
+func decodeVarint(data []byte) (uint64, int, error) {
+	d := &decoder{data: data}
+	return d.varint(0)
+}
+
+// decoder reads values from data. Offsets are into data.
+type decoder struct {
+	data      []byte
+	canonical bool
+	limits    DecodeLimits
+	values    int
+}
+
+func (d *decoder) fail(off int, format string, args ...any) error {
+	return &DecodeError{Offset: off, Msg: fmt.Sprintf(format, args...)}
+}
+
+// varint reads a varint at off and returns it with the offset after it.
+func (d *decoder) varint(off int) (uint64, int, error) {
+	var n uint64
+	var shift uint
+	for i := off; i < len(d.data); i++ {
+		b := d.data[i]
+		if d.canonical && shift == 63 && b > 1 {
+			return 0, 0, d.fail(off, "varint overflow")
+		}
+		n |= uint64(b&0x7F) << shift
+		if b&0x80 == 0 {
+			if d.canonical && b == 0 && i > off {
+				return 0, 0, d.fail(off, "non-minimal varint")
+			}
+			return n, i + 1, nil
+		}
+		shift += 7
+		if shift >= 64 {
+			return 0, 0, d.fail(off, "varint overflow")
+		}
+	}
+	return 0, 0, d.fail(off, "varint truncated")
+}
+
+// length reads the varint length of a Bytes, Text or Seq at off, checking
+// it against MaxLength and against the unit-sized items left after it.
+func (d *decoder) length(off int, what string) (int, int, error) {
+	n, end, err := d.varint(off)
+	if err != nil {
+		return 0, 0, err
+	}
+	if d.limits.MaxLength > 0 && n > uint64(d.limits.MaxLength) {
+		return 0, 0, d.fail(off, "%s length %d exceeds limit %d", what, n, d.limits.MaxLength)
+	}
+	if n > uint64(len(d.data)-end) {
+		return 0, 0, d.fail(off, "%s length %d exceeds the %d bytes left", what, n, len(d.data)-end)
+	}
+	return int(n), end, nil
+}
+
+// magnitude reads a one-byte length and that many big-endian bytes at off.
+func (d *decoder) magnitude(off int, what string) ([]byte, int, error) {
+	if off >= len(d.data) {
+		return nil, 0, d.fail(off, "%s: missing length byte", what)
+	}
+	n := int(d.data[off])
+	if len(d.data) < off+1+n {
+		return nil, 0, d.fail(off, "%s: data too short, need %d bytes", what, n)
+	}
+	mag := d.data[off+1 : off+1+n]
+	if d.canonical && n > 0 && mag[0] == 0 {
+		return nil, 0, d.fail(off+1, "%s: leading zero byte", what)
+	}
+	return mag, off + 1 + n, nil
+}
+
+// value decodes the value at off, nested depth containers deep, and
+// returns it with the offset after it.
+func (d *decoder) value(off, depth int) (Value, int, error) {
+	if off >= len(d.data) {
+		return nil, 0, d.fail(off, "unexpected end of data")
+	}
+	d.values++
+	if d.limits.MaxValues > 0 && d.values > d.limits.MaxValues {
+		return nil, 0, d.fail(off, "more than %d values", d.limits.MaxValues)
+	}
+
+	tag := d.data[off]
+	switch {
+	// 0x00 → Int(0)
+	case tag == 0x00:
+		return MakeInt(0), off + 1, nil
+
+	// 0x01-0x3F → small positive Int (value = byte itself)
+	case tag >= 0x01 && tag <= 0x3F:
+		return MakeInt(int64(tag)), off + 1, nil
+
+	// 0x40 → positive Int, 0x80 → negative Int (length byte, then
+	// big-endian magnitude)
+	case tag == 0x40 || tag == 0x80:
+		mag, end, err := d.magnitude(off+1, "int")
+		if err != nil {
+			return nil, 0, err
+		}
+		if d.canonical && len(mag) == 0 {
+			return nil, 0, d.fail(off, "int: zero in the long form")
+		}
+		v := new(big.Int).SetBytes(mag)
+		if d.canonical && tag == 0x40 && v.BitLen() <= 6 {
+			return nil, 0, d.fail(off, "int: %d in the long form", v)
+		}
+		if tag == 0x80 {
+			v.Neg(v)
+		}
+		return Int{V: v}, end, nil
+
+	// 0x90 → Rat (sign byte, numLen, numBytes..., denomLen, denomBytes...)
+	// Zero rational: 0x90, 0x00, 0x00, 0x00 (sign=0, numLen=0, denomLen=0).
+	case tag == 0x90:
+		if off+1 >= len(d.data) {
+			return nil, 0, d.fail(off, "rat: missing sign byte")
+		}
+		sign := d.data[off+1]
+		numBytes, end, err := d.magnitude(off+2, "rat numerator")
+		if err != nil {
+			return nil, 0, err
+		}
+		denomBytes, end, err := d.magnitude(end, "rat denominator")
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(numBytes) == 0 && len(denomBytes) == 0 {
+			if d.canonical && sign != 0x00 {
+				return nil, 0, d.fail(off+1, "rat: zero with sign byte 0x%02x", sign)
+			}
+			return MakeRat(0, 1), end, nil
+		}
+		num := new(big.Int).SetBytes(numBytes)
+		denom := new(big.Int).SetBytes(denomBytes)
+		if d.canonical {
+			if sign != 0x00 && sign != 0x80 {
+				return nil, 0, d.fail(off+1, "rat: sign byte 0x%02x", sign)
+			}
+			if num.Sign() == 0 || denom.Sign() == 0 {
+				return nil, 0, d.fail(off, "rat: empty numerator or denominator")
+			}
+			if new(big.Int).GCD(nil, nil, num, denom).BitLen() != 1 {
+				return nil, 0, d.fail(off, "rat: %v/%v is not in lowest terms", num, denom)
+			}
+		}
+		if denom.Sign() == 0 {
+			denom.SetInt64(1)
+		}
+		if sign == 0x80 {
+			num.Neg(num)
+		}
+		return Rat{V: new(big.Rat).SetFrac(num, denom)}, end, nil
+
+	// 0xA0 → Bytes (varint length, then raw bytes)
+	case tag == 0xA0:
+		n, start, err := d.length(off+1, "bytes")
+		if err != nil {
+			return nil, 0, err
+		}
+		return MakeBytes(d.data[start : start+n]), start + n, nil
+
+	// 0xB0 → Text (varint length, then UTF-8 bytes)
+	case tag == 0xB0:
+		n, start, err := d.length(off+1, "text")
+		if err != nil {
+			return nil, 0, err
+		}
+		s := d.data[start : start+n]
+		if d.canonical && !utf8.Valid(s) {
+			return nil, 0, d.fail(start, "text: invalid UTF-8")
+		}
+		return MakeText(string(s)), start + n, nil
+
+	// 0xC0 → Seq (varint count, then each item recursively)
+	case tag == 0xC0:
+		if err := d.nest(off, depth); err != nil {
+			return nil, 0, err
+		}
+		count, end, err := d.length(off+1, "seq")
+		if err != nil {
+			return nil, 0, err
+		}
+		items := make([]Value, count)
+		for i := range items {
+			items[i], end, err = d.value(end, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return MakeSeq(items...), end, nil
+
+	// 0xD0 → Tag (label then payload, both recursively)
+	case tag == 0xD0:
+		if err := d.nest(off, depth); err != nil {
+			return nil, 0, err
+		}
+		label, end, err := d.value(off+1, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		payload, end, err := d.value(end, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return MakeTag(label, payload), end, nil
+
+	// 0xE0 → Bool(false)
+	case tag == 0xE0:
+		return MakeBool(false), off + 1, nil
+
+	// 0xE1 → Bool(true)
+	case tag == 0xE1:
+		return MakeBool(true), off + 1, nil
+
+	// 0xF0 → Nil
+	case tag == 0xF0:
+		return MakeNil(), off + 1, nil
+
+	default:
+		return nil, 0, d.fail(off, "unknown value tag: 0x%02x", tag)
+	}
+}
+
+// nest checks MaxDepth before entering the container at off.
+func (d *decoder) nest(off, depth int) error {
+	if d.limits.MaxDepth > 0 && depth+1 > d.limits.MaxDepth {
+		return d.fail(off, "nesting deeper than %d", d.limits.MaxDepth)
+	}
+	return nil
+}
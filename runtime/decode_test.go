@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// decodeSamples covers every value type and the Int form boundaries.
+func decodeSamples() []Value {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	return []Value{
+		MakeInt(0), MakeInt(1), MakeInt(63), MakeInt(64), MakeInt(-1), MakeInt(1 << 40), MakeBigInt(huge),
+		MakeRat(0, 1), MakeRat(1, 2), MakeRat(-7, 3), MakeRat(5, 1), MakeBigRat(new(big.Rat).SetFrac(huge, big.NewInt(7))),
+		MakeBytes(nil), MakeBytes(make([]byte, 300)), MakeText(""), MakeText("ρ ↦ U ρ U†"),
+		MakeBool(true), MakeBool(false), MakeNil(),
+		MakeSeq(), MakeTag(MakeText("entry"), MakeSeq(MakeBytes([]byte{1, 2}), MakeSeq(MakeInt(3), MakeNil()))),
+		CircuitToValue(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixToValue(rhoYExact())}),
+	}
+}
+
+func TestDecodeCanonicalRoundTrip(t *testing.T) {
+	for _, v := range decodeSamples() {
+		enc := v.Encode()
+		got, err := DecodeCanonical(enc, DefaultDecodeLimits)
+		if err != nil {
+			t.Errorf("%x: %v", enc, err)
+			continue
+		}
+		if !Equal(got, v) || !bytes.Equal(got.Encode(), enc) {
+			t.Errorf("%x does not round-trip", enc)
+		}
+	}
+}
+
+func TestDecodeCanonicalRejects(t *testing.T) {
+	deep := append(bytes.Repeat([]byte{0xC0, 0x01}, 300), 0xF0)
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		limits DecodeLimits
+		offset int
+		msg    string
+	}{
+		{"small int in long form", []byte{0x40, 0x01, 0x05}, DecodeLimits{}, 0, "5 in the long form"},
+		{"leading zero", []byte{0x80, 0x02, 0x00, 0x80}, DecodeLimits{}, 2, "leading zero"},
+		{"unreduced rat", []byte{0x90, 0x00, 0x01, 0x02, 0x01, 0x04}, DecodeLimits{}, 0, "not in lowest terms"},
+		{"negative zero rat", []byte{0x90, 0x80, 0x00, 0x00}, DecodeLimits{}, 1, "zero with sign"},
+		{"long varint", []byte{0xA0, 0x80, 0x00}, DecodeLimits{}, 1, "non-minimal varint"},
+		{"invalid utf-8", []byte{0xB0, 0x01, 0xFF}, DecodeLimits{}, 2, "invalid UTF-8"},
+		{"trailing bytes", []byte{0xE0, 0xF0}, DecodeLimits{}, 1, "1 trailing bytes"},
+		{"huge seq", []byte{0xC0, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, DecodeLimits{}, 1, "exceeds the 0 bytes left"},
+		{"deep nesting", deep, DefaultDecodeLimits, 512, "nesting deeper than 256"},
+		{"long text", MakeText("abcdef").Encode(), DecodeLimits{MaxLength: 4}, 1, "text length 6 exceeds limit 4"},
+		{"many values", MakeSeq(MakeInt(1), MakeInt(2), MakeInt(3)).Encode(), DecodeLimits{MaxValues: 3}, 4, "more than 3 values"},
+	} {
+		_, err := DecodeCanonical(tc.data, tc.limits)
+		var de *DecodeError
+		if !errors.As(err, &de) || de.Offset != tc.offset || !strings.Contains(de.Msg, tc.msg) {
+			t.Errorf("%s: got %v, want %q at offset %d", tc.name, err, tc.msg, tc.offset)
+		}
+	}
+
+	// The lenient v1 decoder still accepts non-canonical forms.
+	if v, _, err := decodeValue([]byte{0x40, 0x01, 0x05}); err != nil || !Equal(v, MakeInt(5)) {
+		t.Errorf("decodeValue(40 01 05) = %v, %v", v, err)
+	}
+}
+
+func FuzzDecodeCanonical(f *testing.F) {
+	for _, v := range decodeSamples() {
+		f.Add(v.Encode())
+	}
+	f.Add([]byte{0x40, 0x01, 0x05})
+	f.Add([]byte{0x90, 0x00, 0x01, 0x02, 0x01, 0x04})
+	f.Add([]byte{0xC0, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeValue(data) // must not panic
+		v, err := DecodeCanonical(data, DefaultDecodeLimits)
+		if err != nil {
+			var de *DecodeError
+			if !errors.As(err, &de) || de.Offset < 0 || de.Offset > len(data) {
+				t.Fatalf("error %v has no valid offset", err)
+			}
+			return
+		}
+		enc := v.Encode()
+		if !bytes.Equal(enc, data) {
+			t.Fatalf("encode(decode(%x)) = %x", data, enc)
+		}
+		again, err := DecodeCanonical(enc, DefaultDecodeLimits)
+		if err != nil || !Equal(again, v) {
+			t.Fatalf("decode(encode(v)) != v for %x: %v", data, err)
+		}
+	})
+}
@@ -1,21 +1,31 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
-	"math/big"
 )
 
 // EmbeddedBinary represents a self-contained qmb binary.
 type EmbeddedBinary struct {
-	Magic      [4]byte  // "QMB\x01"
+	Magic      [4]byte  // "QMB\x01" or "QMB\x02"
 	Entrypoint [32]byte // Main circuit QGID
 	Name       string   // Binary name
 	Version    string   // Version string
-	StoreData  []byte   // Serialized store
+	StoreData  []byte   // Serialized store (v1)
+
+	Sections   []Section // Section table as decoded (v2)
+	Signatures []byte    // Optional signatures section (v2)
+
+	entries []storeEntry // Store index, ascending by QGID (v2)
+	blobs   []byte       // Encoded store entries (v2)
 }
 
-// Encode serializes the embedded binary to bytes.
+// Encode serializes the embedded binary to bytes, in the v2 layout when
+// Magic is "QMB\x02". See sections.go.
 func (e *EmbeddedBinary) Encode() []byte {
+	if e.Magic == magicV2 {
+		return e.encodeV2()
+	}
 	nameBytes := []byte(e.Name)
 	versionBytes := []byte(e.Version)
 
@@ -63,8 +73,11 @@ func Decode(data []byte) (*EmbeddedBinary, error) {
 	}
 
 	// Check magic
-	if data[0] != 'Q' || data[1] != 'M' || data[2] != 'B' || data[3] != 0x01 {
-		return nil, fmt.Errorf("invalid magic: expected QMB\\x01")
+	if [4]byte(data[:4]) == magicV2 {
+		return decodeV2(data)
+	}
+	if [4]byte(data[:4]) != magicV1 {
+		return nil, fmt.Errorf("invalid magic: expected QMB\\x01 or QMB\\x02")
 	}
 
 	if len(data) < 36 {
@@ -107,34 +120,171 @@ func Decode(data []byte) (*EmbeddedBinary, error) {
 	return result, nil
 }
 
+// LoadStore decodes every entry of the binary into a new store, including
+// entries the entrypoint does not reach.
+func (e *EmbeddedBinary) LoadStore() (*Store, error) {
+	store := NewStore()
+	if e.Magic != magicV2 {
+		if err := loadStoreData(store, e.StoreData); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	for _, ent := range e.entries {
+		v, _, err := e.Entry(ent.id)
+		if err != nil {
+			return nil, err
+		}
+		store.values[ent.id] = v
+		if c, ok := CircuitFromValue(v); ok {
+			store.circuits[ent.id] = c
+		}
+	}
+	return store, nil
+}
+
 // Runner executes an embedded binary.
 type Runner struct {
-	binary   *EmbeddedBinary
-	store    *Store
-	executor *Executor
+	binary       *EmbeddedBinary
+	store        *Store
+	executor     *Executor
+	statevector  bool
+	backend      Backend
+	decodeLimits *DecodeLimits // nil: lenient v1 decoding; see WithDecodeLimits
+	resolver     Resolver
+}
+
+// Backend selects the arithmetic a Runner executes with.
+type Backend int
+
+const (
+	// BackendExact executes over Q(i) with big.Rat entries (the default).
+	BackendExact Backend = iota
+	// BackendInterval executes on float64 interval enclosures. See
+	// ExecuteInterval.
+	BackendInterval
+)
+
+// String returns the name of the backend.
+func (b Backend) String() string {
+	switch b {
+	case BackendExact:
+		return "exact"
+	case BackendInterval:
+		return "interval"
+	default:
+		return fmt.Sprintf("Backend(%d)", int(b))
+	}
+}
+
+// ParseBackend returns the backend named s.
+func ParseBackend(s string) (Backend, error) {
+	switch s {
+	case "exact":
+		return BackendExact, nil
+	case "interval":
+		return BackendInterval, nil
+	}
+	return 0, fmt.Errorf("unknown backend %q (want exact or interval)", s)
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(*Runner)
+
+// WithStatevector makes Run evolve rank-1 inputs as kets through the
+// unitary part of the circuit. See ExecuteStatevector.
+func WithStatevector() RunnerOption {
+	return func(r *Runner) {
+		r.statevector = true
+	}
+}
+
+// WithBackend selects the arithmetic used by Run and RunInterval. The
+// interval backend always evolves density matrices, so WithStatevector has
+// no effect on it.
+func WithBackend(b Backend) RunnerOption {
+	return func(r *Runner) {
+		r.backend = b
+	}
+}
+
+// WithCache attaches an execution cache to the runner's executor. See
+// ExecCache.
+func WithCache(c *ExecCache) RunnerOption {
+	return func(r *Runner) {
+		r.executor.SetCache(c)
+	}
+}
+
+// WithLimits sets the budgets RunContext enforces. See ExecuteContext.
+func WithLimits(l Limits) RunnerOption {
+	return func(r *Runner) {
+		r.executor.SetLimits(l)
+	}
+}
+
+// WithDecodeLimits decodes the store with DecodeCanonical under l. v2
+// binaries are always decoded canonically, with DefaultDecodeLimits unless
+// this option is given; v1 store data is otherwise decoded leniently.
+func WithDecodeLimits(l DecodeLimits) RunnerOption {
+	return func(r *Runner) {
+		r.decodeLimits = &l
+	}
+}
+
+// WithResolver links the loaded store with resolve, so that circuits from
+// other binaries named by external references can run. See Link and
+// DirResolver.
+func WithResolver(resolve Resolver) RunnerOption {
+	return func(r *Runner) {
+		r.resolver = resolve
+	}
 }
 
 // NewRunner creates a runner from binary data.
-func NewRunner(data []byte) (*Runner, error) {
+func NewRunner(data []byte, opts ...RunnerOption) (*Runner, error) {
 	binary, err := Decode(data)
 	if err != nil {
 		return nil, fmt.Errorf("decode failed: %w", err)
 	}
 
 	store := NewStore()
-
-	// Load store data
-	if err := loadStoreData(store, binary.StoreData); err != nil {
-		return nil, fmt.Errorf("load store failed: %w", err)
-	}
-
 	executor := NewExecutor(store)
 
-	return &Runner{
+	r := &Runner{
 		binary:   binary,
 		store:    store,
 		executor: executor,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	// Load store data; v2 binaries load only what the entrypoint reaches
+	switch {
+	case binary.Magic == magicV2:
+		limits := DefaultDecodeLimits
+		if r.decodeLimits != nil {
+			limits = *r.decodeLimits
+		}
+		err = loadReachable(store, binary, limits)
+	case r.decodeLimits != nil && len(binary.StoreData) > 0:
+		var v Value
+		if v, err = DecodeCanonical(binary.StoreData, *r.decodeLimits); err == nil {
+			err = importValues(store, v)
+		}
+	default:
+		err = loadStoreData(store, binary.StoreData)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load store failed: %w", err)
+	}
+	if r.resolver != nil {
+		if _, err := Link(store, r.resolver); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
 }
 
 // loadStoreData loads serialized store data.
@@ -153,208 +303,6 @@ func loadStoreData(store *Store, data []byte) error {
 	return importValues(store, v)
 }
 
-// decodeVarint decodes a variable-length integer from data, returning the
-// value and the number of bytes consumed. This is the inverse of
-// encodeVarint() in value.go.
-func decodeVarint(data []byte) (uint64, int, error) {
-	var n uint64
-	var shift uint
-	for i := 0; i < len(data); i++ {
-		b := data[i]
-		n |= uint64(b&0x7F) << shift
-		if b&0x80 == 0 {
-			return n, i + 1, nil
-		}
-		shift += 7
-		if shift >= 64 {
-			return 0, 0, fmt.Errorf("varint overflow")
-		}
-	}
-	return 0, 0, fmt.Errorf("varint truncated")
-}
-
-// decodeValue decodes a Value from bytes, returning the value and the number
-// of bytes consumed. This is the complete inverse of the Encode() methods
-// defined on each value type in value.go.
-func decodeValue(data []byte) (Value, int, error) {
-	if len(data) == 0 {
-		return nil, 0, fmt.Errorf("empty data")
-	}
-
-	tag := data[0]
-
-	switch {
-	// 0x00 → Int(0)
-	case tag == 0x00:
-		return MakeInt(0), 1, nil
-
-	// 0x01-0x3F → small positive Int (value = byte itself)
-	case tag >= 0x01 && tag <= 0x3F:
-		return MakeInt(int64(tag)), 1, nil
-
-	// 0x40 → positive Int (next byte = length, then big-endian bytes)
-	case tag == 0x40:
-		if len(data) < 2 {
-			return nil, 0, fmt.Errorf("positive int: missing length byte")
-		}
-		byteLen := int(data[1])
-		if len(data) < 2+byteLen {
-			return nil, 0, fmt.Errorf("positive int: data too short, need %d bytes", byteLen)
-		}
-		v := new(big.Int).SetBytes(data[2 : 2+byteLen])
-		return MakeBigInt(v), 2 + byteLen, nil
-
-	// 0x80 → negative Int (next byte = length, then big-endian bytes)
-	case tag == 0x80:
-		if len(data) < 2 {
-			return nil, 0, fmt.Errorf("negative int: missing length byte")
-		}
-		byteLen := int(data[1])
-		if len(data) < 2+byteLen {
-			return nil, 0, fmt.Errorf("negative int: data too short, need %d bytes", byteLen)
-		}
-		v := new(big.Int).SetBytes(data[2 : 2+byteLen])
-		v.Neg(v)
-		return MakeBigInt(v), 2 + byteLen, nil
-
-	// 0x90 → Rat (sign byte, numLen, numBytes..., denomLen, denomBytes...)
-	// Format is always: 0x90, sign, numLen, numBytes..., denomLen, denomBytes...
-	// Zero rational: 0x90, 0x00, 0x00, 0x00 (sign=0, numLen=0, denomLen=0).
-	case tag == 0x90:
-		if len(data) < 2 {
-			return nil, 0, fmt.Errorf("rat: missing sign byte")
-		}
-		signByte := data[1]
-		offset := 2
-
-		// Numerator length
-		if offset >= len(data) {
-			return nil, 0, fmt.Errorf("rat: missing numerator length")
-		}
-		numLen := int(data[offset])
-		offset++
-		if len(data) < offset+numLen {
-			return nil, 0, fmt.Errorf("rat: data too short for numerator")
-		}
-		numBytes := data[offset : offset+numLen]
-		offset += numLen
-
-		// Denominator length
-		if offset >= len(data) {
-			return nil, 0, fmt.Errorf("rat: missing denominator length")
-		}
-		denomLen := int(data[offset])
-		offset++
-		if len(data) < offset+denomLen {
-			return nil, 0, fmt.Errorf("rat: data too short for denominator")
-		}
-		denomBytes := data[offset : offset+denomLen]
-		offset += denomLen
-
-		// If both numerator and denominator are empty, this is zero
-		if numLen == 0 && denomLen == 0 {
-			return MakeRat(0, 1), offset, nil
-		}
-
-		num := new(big.Int).SetBytes(numBytes)
-		denom := new(big.Int).SetBytes(denomBytes)
-		if denom.Sign() == 0 {
-			denom.SetInt64(1)
-		}
-
-		if signByte == 0x80 {
-			num.Neg(num)
-		}
-
-		r := new(big.Rat).SetFrac(num, denom)
-		return MakeBigRat(r), offset, nil
-
-	// 0xA0 → Bytes (varint length, then raw bytes)
-	case tag == 0xA0:
-		length, vLen, err := decodeVarint(data[1:])
-		if err != nil {
-			return nil, 0, fmt.Errorf("bytes: %w", err)
-		}
-		offset := 1 + vLen
-		if len(data) < offset+int(length) {
-			return nil, 0, fmt.Errorf("bytes: data too short, need %d bytes", length)
-		}
-		v := make([]byte, length)
-		copy(v, data[offset:offset+int(length)])
-		return MakeBytes(v), offset + int(length), nil
-
-	// 0xB0 → Text (varint length, then UTF-8 bytes)
-	case tag == 0xB0:
-		length, vLen, err := decodeVarint(data[1:])
-		if err != nil {
-			return nil, 0, fmt.Errorf("text: %w", err)
-		}
-		offset := 1 + vLen
-		if len(data) < offset+int(length) {
-			return nil, 0, fmt.Errorf("text: data too short, need %d bytes", length)
-		}
-		s := string(data[offset : offset+int(length)])
-		return MakeText(s), offset + int(length), nil
-
-	// 0xC0 → Seq (varint count, then each item recursively)
-	case tag == 0xC0:
-		count, vLen, err := decodeVarint(data[1:])
-		if err != nil {
-			return nil, 0, fmt.Errorf("seq: %w", err)
-		}
-		offset := 1 + vLen
-		items := make([]Value, count)
-		for i := uint64(0); i < count; i++ {
-			if offset >= len(data) {
-				return nil, 0, fmt.Errorf("seq: truncated at item %d", i)
-			}
-			item, consumed, err := decodeValue(data[offset:])
-			if err != nil {
-				return nil, 0, fmt.Errorf("seq item %d: %w", i, err)
-			}
-			items[i] = item
-			offset += consumed
-		}
-		return MakeSeq(items...), offset, nil
-
-	// 0xD0 → Tag (label then payload, both recursively)
-	case tag == 0xD0:
-		offset := 1
-		if offset >= len(data) {
-			return nil, 0, fmt.Errorf("tag: missing label")
-		}
-		label, consumed, err := decodeValue(data[offset:])
-		if err != nil {
-			return nil, 0, fmt.Errorf("tag label: %w", err)
-		}
-		offset += consumed
-		if offset >= len(data) {
-			return nil, 0, fmt.Errorf("tag: missing payload")
-		}
-		payload, consumed, err := decodeValue(data[offset:])
-		if err != nil {
-			return nil, 0, fmt.Errorf("tag payload: %w", err)
-		}
-		offset += consumed
-		return MakeTag(label, payload), offset, nil
-
-	// 0xE0 → Bool(false)
-	case tag == 0xE0:
-		return MakeBool(false), 1, nil
-
-	// 0xE1 → Bool(true)
-	case tag == 0xE1:
-		return MakeBool(true), 1, nil
-
-	// 0xF0 → Nil
-	case tag == 0xF0:
-		return MakeNil(), 1, nil
-
-	default:
-		return nil, 0, fmt.Errorf("unknown value tag: 0x%02x", tag)
-	}
-}
-
 // importValues imports values into the store. The store data is expected to
 // be a Seq of Tag("entry", Seq(Bytes(qgid), value)) pairs. Each entry is
 // tested as a circuit; if it parses, it is stored as a circuit, otherwise
@@ -405,15 +353,83 @@ func importValues(store *Store, v Value) error {
 	return nil
 }
 
-// Run executes the binary's entrypoint circuit.
+// Run executes the binary's entrypoint circuit. With the interval backend
+// it returns the midpoints of the enclosures RunInterval computes, which
+// approximate the exact output; use RunInterval for the error bounds.
 func (r *Runner) Run(input *Matrix) (*Matrix, error) {
 	c, ok := r.store.Get(r.binary.Entrypoint)
 	if !ok {
 		return nil, fmt.Errorf("entrypoint circuit not found")
 	}
+	if r.backend == BackendInterval {
+		out, err := r.executor.ExecuteInterval(c, IntervalMatrixFromMatrix(input))
+		if err != nil {
+			return nil, err
+		}
+		return out.Midpoint()
+	}
+	if r.statevector {
+		return r.executor.ExecuteStatevector(c, input)
+	}
 	return r.executor.Execute(c, input)
 }
 
+// RunContext is Run under ctx and the runner's Limits, with independent
+// children evaluated in parallel. See ExecuteContext. The statevector path
+// counts only the density-matrix nodes it falls back to; the interval
+// backend checks ctx once before running.
+func (r *Runner) RunContext(ctx context.Context, input *Matrix) (*Matrix, error) {
+	c, ok := r.store.Get(r.binary.Entrypoint)
+	if !ok {
+		return nil, fmt.Errorf("entrypoint circuit not found")
+	}
+	if r.backend == BackendInterval {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("execute: %w", err)
+		}
+		return r.Run(input)
+	}
+	return r.executor.withRun(ctx, func(e *Executor) (*Matrix, error) {
+		if r.statevector {
+			return e.ExecuteStatevector(c, input)
+		}
+		return e.Execute(c, input)
+	})
+}
+
+// RunZ8 executes the entrypoint circuit exactly over Q(ζ8); see
+// ExecuteZ8. Run fails with ErrNotQI where RunZ8 is needed. It requires
+// the exact backend.
+func (r *Runner) RunZ8(input *Matrix) (*MatrixZ8, error) {
+	c, ok := r.store.Get(r.binary.Entrypoint)
+	if !ok {
+		return nil, fmt.Errorf("entrypoint circuit not found")
+	}
+	if r.backend != BackendExact {
+		return nil, fmt.Errorf("RunZ8 needs the exact backend, not %s", r.backend)
+	}
+	return r.executor.ExecuteZ8(c, MatrixToZ8(input))
+}
+
+// RunInterval executes the entrypoint circuit and returns enclosures of
+// the exact output. With the interval backend the enclosures come from
+// ExecuteInterval; with the exact backend they are the exact result as
+// point intervals, rounded outward to float64.
+func (r *Runner) RunInterval(input *Matrix) (*IntervalMatrix, error) {
+	c, ok := r.store.Get(r.binary.Entrypoint)
+	if !ok {
+		return nil, fmt.Errorf("entrypoint circuit not found")
+	}
+	if r.backend == BackendInterval {
+		return r.executor.ExecuteInterval(c, IntervalMatrixFromMatrix(input))
+	}
+	out, err := r.Run(input)
+	if err != nil {
+		return nil, err
+	}
+	return IntervalMatrixFromMatrix(out), nil
+}
+
 // RunWithValue executes with a Value input.
 func (r *Runner) RunWithValue(input Value) (Value, error) {
 	// Convert value to matrix if needed
@@ -446,21 +462,40 @@ func (r *Runner) Entrypoint() [32]byte {
 	return r.binary.Entrypoint
 }
 
-// GetCircuit retrieves a circuit by QGID.
+// GetCircuit retrieves a circuit by QGID. Entries of a v2 binary that the
+// entrypoint does not reach are decoded on demand.
 func (r *Runner) GetCircuit(id [32]byte) (Circuit, bool) {
-	return r.store.Get(id)
+	if c, ok := r.store.Get(id); ok {
+		return c, true
+	}
+	v, ok := r.GetValue(id)
+	if !ok {
+		return Circuit{}, false
+	}
+	return CircuitFromValue(v)
 }
 
-// GetValue retrieves a value by QGID.
+// GetValue retrieves a value by QGID. Entries of a v2 binary that the
+// entrypoint does not reach are decoded on demand.
 func (r *Runner) GetValue(id [32]byte) (Value, bool) {
-	return r.store.GetValue(id)
+	if v, ok := r.store.GetValue(id); ok {
+		return v, true
+	}
+	v, ok, err := r.binary.Entry(id)
+	return v, ok && err == nil
 }
 
-// StoreSize returns the number of entries in the store.
+// StoreSize returns the number of entries in the store. For a v2 binary
+// these are the entries loaded from the entrypoint.
 func (r *Runner) StoreSize() int {
 	return r.store.StoreSize()
 }
 
+// TypeCheck type checks every circuit in the loaded store.
+func (r *Runner) TypeCheck() []Diagnostic {
+	return TypeCheckAll(r.store)
+}
+
 // Embed creates an embedded binary from a store and entrypoint. It
 // serializes every circuit and value in the store as a Seq of
 // Tag("entry", Seq(Bytes(qgid), value)) pairs.
@@ -485,7 +520,7 @@ func Embed(store *Store, entrypoint [32]byte, name, version string) *EmbeddedBin
 	}
 
 	return &EmbeddedBinary{
-		Magic:      [4]byte{'Q', 'M', 'B', 0x01},
+		Magic:      magicV1,
 		Entrypoint: entrypoint,
 		Name:       name,
 		Version:    version,
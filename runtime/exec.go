@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"fmt"
+	"math/big"
 )
 
 // Prim is the primitive type for circuits.
@@ -99,9 +100,14 @@ type Circuit struct {
 	Children [][32]byte
 }
 
-// Object represents a C*-algebra type (block signature).
+// Object represents a C*-algebra type ⊕_i M_{n_i}.
+//
+// Blocks is the canonical normal form: the sizes n_i of the matrix blocks.
+// Factors records the tensor structure of objects built with TensorObjects
+// and is nil otherwise. See object.go.
 type Object struct {
-	Blocks []uint32
+	Blocks  []uint32
+	Factors []Object
 }
 
 // Store holds circuits by their QGID.
@@ -148,7 +154,12 @@ func (s *Store) PutValue(v Value) [32]byte {
 
 // Executor executes circuits.
 type Executor struct {
-	store *Store
+	store  *Store
+	cache  *ExecCache // optional; see SetCache
+	limits Limits     // enforced by ExecuteContext; see SetLimits
+
+	run   *execRun // set inside ExecuteContext
+	depth int      // nesting depth within run
 }
 
 // NewExecutor creates a new executor.
@@ -158,57 +169,69 @@ func NewExecutor(store *Store) *Executor {
 
 // Execute executes a circuit on an input state.
 // For quantum circuits, input is a density matrix.
+//
+// The input is converted to common-denominator form once (see IntMatrix),
+// every gate works on numerators over a shared denominator, and the result
+// is reduced once on the way out.
 func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
+	var in *IntMatrix // nil stays nil for Prepare on the unit object
+	if input != nil {
+		in = ToIntMatrix(input)
+	}
+	out, err := e.executeInt(c, in)
+	if err != nil {
+		return nil, err
+	}
+	return out.ToMatrix(), nil
+}
+
+// executeInt runs c on input in common-denominator form, checking the run's
+// budgets and the cache. Every result is a new matrix owned by the caller.
+func (e *Executor) executeInt(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	if e.run != nil {
+		sub, err := e.enter(c, input)
+		if err != nil {
+			return nil, err
+		}
+		e = sub
+	}
+	if e.cache != nil && c.Prim != PrimId {
+		return e.executeCached(c, input)
+	}
+	return e.execute(c, input)
+}
+
+// execute dispatches on c.Prim. Children go back through executeInt so
+// that they are cached too.
+func (e *Executor) execute(c Circuit, input *IntMatrix) (*IntMatrix, error) {
 	switch c.Prim {
 	case PrimId:
 		return input.Clone(), nil
 
 	case PrimCompose:
-		if len(c.Children) != 2 {
-			return nil, fmt.Errorf("compose requires 2 children")
+		// Compose(f_1, ..., f_n) runs f_1 first.
+		if len(c.Children) < 2 {
+			return nil, fmt.Errorf("compose requires at least 2 children")
 		}
-		f, ok := e.store.Get(c.Children[0])
-		if !ok {
-			return nil, fmt.Errorf("child 0 not found")
-		}
-		g, ok := e.store.Get(c.Children[1])
-		if !ok {
-			return nil, fmt.Errorf("child 1 not found")
-		}
-		intermediate, err := e.Execute(f, input)
-		if err != nil {
-			return nil, err
+		current := input
+		for i, childID := range c.Children {
+			child, ok := e.store.Get(childID)
+			if !ok {
+				return nil, fmt.Errorf("child %d not found", i)
+			}
+			next, err := e.executeInt(child, current)
+			if err != nil {
+				return nil, err
+			}
+			// Keep the numerators of long chains from growing.
+			next.Reduce()
+			current = next
 		}
-		return e.Execute(g, intermediate)
+		return current, nil
 
 	case PrimTensor:
-		if len(c.Children) != 2 {
-			return nil, fmt.Errorf("tensor requires 2 children")
-		}
-		// For tensor product, apply each subcircuit to its portion
-		// Simplified: just return Kronecker of results applied to identity
-		f, ok := e.store.Get(c.Children[0])
-		if !ok {
-			return nil, fmt.Errorf("child 0 not found")
-		}
-		g, ok := e.store.Get(c.Children[1])
-		if !ok {
-			return nil, fmt.Errorf("child 1 not found")
-		}
-		// Compute f on identity and g on identity, then Kronecker
-		fDim := objectDim(f.Domain)
-		gDim := objectDim(g.Domain)
-		fId := Identity(fDim)
-		gId := Identity(gDim)
-		fResult, err := e.Execute(f, fId)
-		if err != nil {
-			return nil, err
-		}
-		gResult, err := e.Execute(g, gId)
-		if err != nil {
-			return nil, err
-		}
-		return Kronecker(fResult, gResult), nil
+		// (f_1⊗...⊗f_n)(ρ) on the Kronecker-ordered joint space of the children.
+		return e.applyTensor(c, input)
 
 	case PrimSwap:
 		// Swap acts by permutation: A⊗B → B⊗A
@@ -216,16 +239,30 @@ func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
 
 	case PrimDiscard:
 		// Discard traces out the entire system, returning Tr(ρ) as a 1x1 matrix.
-		// This is the trace-out-everything case. For partial trace over a
-		// subsystem, use a composition of discard with tensor.
+		// A "factors" payload traces out only the named tensor factors.
+		if !isNilData(c.Data) {
+			return e.applyPartialTrace(c, input)
+		}
 		return e.applyDiscard(c.Domain, input)
 
+	case PrimBisum:
+		// (f_1⊕...⊕f_n)(ρ_1⊕...⊕ρ_n): child i runs on the i-th diagonal block.
+		return e.applyBisum(c, input)
+
 	case PrimInject:
 		// Inclusion into biproduct: maps A → A⊕B by padding with zeros.
+		// A "summand" payload names the blocks A occupies.
+		if !isNilData(c.Data) {
+			return e.applySummandInject(c, input)
+		}
 		return e.applyInject(c.Domain, c.Codomain, input)
 
 	case PrimProject:
 		// Projection from biproduct: maps A⊕B → A by truncation.
+		// A "summand" payload names the blocks A occupies.
+		if !isNilData(c.Data) {
+			return e.applySummandProject(c, input)
+		}
 		return e.applyProject(c.Domain, c.Codomain, input)
 
 	case PrimCopy:
@@ -251,6 +288,10 @@ func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
 
 	case PrimTrace:
 		// Quantum trace: maps Q(n) → I, returns Tr(ρ) as a 1x1 matrix.
+		// A "factors" payload traces out only the named tensor factors.
+		if !isNilData(c.Data) {
+			return e.applyPartialTrace(c, input)
+		}
 		return e.applyTrace(input)
 
 	case PrimKraus:
@@ -259,8 +300,8 @@ func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
 
 	case PrimZero:
 		// Zero map returns zero matrix
-		outDim := objectDim(c.Codomain)
-		return NewMatrix(outDim, outDim), nil
+		outDim := BlockDim(c.Codomain)
+		return NewIntMatrix(outDim, outDim), nil
 
 	case PrimUnitary:
 		// Apply unitary: U ρ U†
@@ -272,7 +313,15 @@ func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
 
 	case PrimPrepare:
 		// Prepare a fixed state
-		return e.applyPrepare(c)
+		return e.applyPrepare(c, input)
+
+	case PrimInstrument:
+		// Instrument: ρ ↦ Φ_0(ρ) ⊕ ... ⊕ Φ_{k-1}(ρ), one block per outcome.
+		return e.applyInstrument(c, input)
+
+	case PrimBranch:
+		// Classical control: child i acts on the i-th block of the domain.
+		return e.applyBranch(c, input)
 
 	case PrimAdd:
 		if len(c.Children) != 2 {
@@ -286,15 +335,22 @@ func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
 		if !ok {
 			return nil, fmt.Errorf("child 1 not found")
 		}
-		fResult, err := e.Execute(f, input)
+		children := [2]Circuit{f, g}
+		var results [2]*IntMatrix
+		err := e.forEach(2, func(i int) error {
+			var err error
+			results[i], err = e.executeInt(children[i], input)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
-		gResult, err := e.Execute(g, input)
-		if err != nil {
-			return nil, err
+		sum := IntMatAdd(results[0], results[1])
+		if sum == nil {
+			return nil, fmt.Errorf("add: outputs are %dx%d and %dx%d",
+				results[0].Rows, results[0].Cols, results[1].Rows, results[1].Cols)
 		}
-		return MatAdd(fResult, gResult), nil
+		return sum, nil
 
 	case PrimScale:
 		if len(c.Children) != 1 {
@@ -309,11 +365,11 @@ func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
 		if !ok {
 			return nil, fmt.Errorf("child not found")
 		}
-		result, err := e.Execute(child, input)
+		result, err := e.executeInt(child, input)
 		if err != nil {
 			return nil, err
 		}
-		return MatScale(result, r.V), nil
+		return IntMatScale(result, r.V), nil
 
 	case PrimAssert:
 		// Type assertion: returns input unchanged if domain matches codomain.
@@ -326,7 +382,7 @@ func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
 	case PrimWitness:
 		// Witness: returns a prepared state, like Prepare.
 		// The witness state is stored in Data.
-		return e.applyPrepare(c)
+		return e.applyPrepare(c, input)
 
 	default:
 		return nil, fmt.Errorf("unsupported primitive: %s (%d)", PrimName(c.Prim), int(c.Prim))
@@ -341,75 +397,170 @@ func (e *Executor) Execute(c Circuit, input *Matrix) (*Matrix, error) {
 //
 // The matrix element S[j*dimA+i, i*dimB+j] = 1 for all valid i,j.
 // The channel maps ρ ↦ S ρ S†.
-func (e *Executor) applySwap(domain, codomain Object, input *Matrix) (*Matrix, error) {
+func (e *Executor) applySwap(domain, codomain Object, input *IntMatrix) (*IntMatrix, error) {
 	dimA, dimB, err := bipartiteDims(domain, codomain)
 	if err != nil {
-		// Fallback: if we cannot determine the split, return identity
-		n := objectDim(domain)
-		return MatMul(MatMul(Identity(n), input), Identity(n)), nil
+		return nil, err
 	}
 
 	totalDim := dimA * dimB
 
-	// Build swap permutation matrix S where S|i,j⟩ = |j,i⟩.
+	// Build swap permutation matrix S where S|i,j⟩ = |j,i⟩, in CSR form.
 	// Input basis ordering: |i⟩⊗|j⟩ has index i*dimB + j  (A⊗B)
 	// Output basis ordering: |j⟩⊗|i⟩ has index j*dimA + i  (B⊗A)
-	// So S[j*dimA+i, i*dimB+j] = 1
-	S := NewMatrix(totalDim, totalDim)
-	for i := 0; i < dimA; i++ {
-		for j := 0; j < dimB; j++ {
-			outIdx := j*dimA + i
-			inIdx := i*dimB + j
-			S.Set(outIdx, inIdx, QIOne())
-		}
+	// So row j*dimA+i of S holds a single 1 in column i*dimB+j.
+	S := SparseIdentity(totalDim)
+	for out := 0; out < totalDim; out++ {
+		j, i := out/dimA, out%dimA
+		S.ColIdx[out] = i*dimB + j
 	}
 
 	// Apply as S ρ S†
-	Sdag := Dagger(S)
-	return MatMul(MatMul(S, input), Sdag), nil
+	out := conjugateSparseInt(S, input)
+	if out == nil {
+		return nil, fmt.Errorf("swap: %dx%d input, want %d", input.Rows, input.Cols, totalDim)
+	}
+	return out, nil
+}
+
+// applyTensor applies Tensor(f_1, ..., f_n) to an arbitrary joint input ρ
+// on C^d_1 ⊗ ... ⊗ C^d_n, where d_k is the Hilbert dimension of child k's
+// domain and ρ is indexed in Kronecker order.
+//
+// Writing ρ = Σ_{k,l} ρ_kl ⊗ |k⟩⟨l| with ρ_kl the d_1×d_1 slices across the
+// first split, (f_1⊗id)(ρ) = Σ_{k,l} f_1(ρ_kl) ⊗ |k⟩⟨l|. Applying each
+// child the same way on its own factor gives the tensor by linearity, so
+// entangled inputs are handled exactly.
+func (e *Executor) applyTensor(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	if len(c.Children) < 2 {
+		return nil, fmt.Errorf("tensor requires at least 2 children")
+	}
+	children := make([]Circuit, len(c.Children))
+	dims := make([]int, len(c.Children))
+	total := 1
+	for i, childID := range c.Children {
+		child, ok := e.store.Get(childID)
+		if !ok {
+			return nil, fmt.Errorf("child %d not found", i)
+		}
+		children[i] = child
+		dims[i] = BlockDim(child.Domain)
+		total *= dims[i]
+	}
+	if input.Rows != total || input.Cols != total {
+		return nil, fmt.Errorf("tensor: input is %dx%d, want %dx%d",
+			input.Rows, input.Cols, total, total)
+	}
+
+	// Factors left of child k already carry their output dimensions.
+	current := input
+	for k, child := range children {
+		pre := 1
+		for _, d := range dims[:k] {
+			pre *= d
+		}
+		post := 1
+		for _, d := range dims[k+1:] {
+			post *= d
+		}
+		out, err := e.applyFactor(child, k, current, pre, dims[k], post)
+		if err != nil {
+			return nil, err
+		}
+		out.Reduce()
+		dims[k] = out.Rows / (pre * post)
+		current = out
+	}
+	return current, nil
+}
+
+// applyFactor computes (id_pre ⊗ f ⊗ id_post)(ρ) for ρ on
+// C^pre ⊗ C^d ⊗ C^post. k is f's child index, for error messages.
+func (e *Executor) applyFactor(f Circuit, k int, input *IntMatrix, pre, d, post int) (*IntMatrix, error) {
+	// The (a, b, p, q) slices are independent; run them, then assemble.
+	n := input.Cols
+	outs := make([]*IntMatrix, pre*pre*post*post)
+	err := e.forEach(len(outs), func(s int) error {
+		a, b, p, q := s/(pre*post*post), s/(post*post)%pre, s/post%post, s%post
+		slice := NewIntMatrix(d, d)
+		slice.Den.Set(&input.Den)
+		for i := 0; i < d; i++ {
+			for j := 0; j < d; j++ {
+				at := ((a*d+i)*post+p)*n + (b*d+j)*post + q
+				slice.Re[i*d+j].Set(&input.Re[at])
+				slice.Im[i*d+j].Set(&input.Im[at])
+			}
+		}
+		out, err := e.executeInt(f, slice)
+		outs[s] = out
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The slices' outputs may carry different denominators.
+	eF := outs[0].Rows
+	m := pre * eF * post
+	result := NewIntMatrix(m, m)
+	lcmDen(&result.Den, outs)
+	var scale big.Int
+	for s, out := range outs {
+		a, b, p, q := s/(pre*post*post), s/(post*post)%pre, s/post%post, s%post
+		if out.Rows != eF || out.Cols != eF {
+			return nil, fmt.Errorf("tensor: child %d output is %dx%d, want %dx%d",
+				k, out.Rows, out.Cols, eF, eF)
+		}
+		scale.Quo(&result.Den, &out.Den)
+		for i := 0; i < eF; i++ {
+			for j := 0; j < eF; j++ {
+				result.setScaled(((a*eF+i)*post+p)*m+(b*eF+j)*post+q, out, i*eF+j, &scale)
+			}
+		}
+	}
+	return result, nil
 }
 
 // bipartiteDims extracts the dimensions of the two subsystems from a
-// bipartite domain. The domain should have exactly 2 blocks [a, b],
-// giving dimA = a and dimB = b. If it does not, the codomain is used
-// as a hint (it should have blocks [b, a]).
+// bipartite domain. A domain built as A ⊗ B gives dimA and dimB from its
+// factors. Otherwise the codomain's factors (B ⊗ A) are used as a hint.
+// Legacy two-block objects reach here already migrated to products by
+// ObjectFromValue.
 func bipartiteDims(domain, codomain Object) (dimA, dimB int, err error) {
-	if len(domain.Blocks) == 2 {
-		return int(domain.Blocks[0]), int(domain.Blocks[1]), nil
+	if len(domain.Factors) == 2 {
+		return BlockDim(domain.Factors[0]), BlockDim(domain.Factors[1]), nil
 	}
-	// Try codomain as hint: codomain should be [b, a]
-	if len(codomain.Blocks) == 2 {
-		return int(codomain.Blocks[1]), int(codomain.Blocks[0]), nil
+	if len(codomain.Factors) == 2 {
+		return BlockDim(codomain.Factors[1]), BlockDim(codomain.Factors[0]), nil
 	}
-	return 0, 0, fmt.Errorf("swap: cannot determine bipartite split from blocks %v", domain.Blocks)
+	return 0, 0, fmt.Errorf("swap: domain %v is not a bipartite product", domain.Blocks)
 }
 
 // applyDiscard applies a discard operation (full trace).
 // This is the trace-out-everything case: maps Q(n) → I by returning
 // Tr(ρ) as a 1x1 matrix (scalar channel).
-func (e *Executor) applyDiscard(domain Object, input *Matrix) (*Matrix, error) {
-	tr := Trace(input)
-	result := NewMatrix(1, 1)
-	result.Set(0, 0, tr)
-	return result, nil
+func (e *Executor) applyDiscard(domain Object, input *IntMatrix) (*IntMatrix, error) {
+	return IntTrace(input), nil
 }
 
 // applyInject applies injection into a biproduct: A → A⊕B.
 // The output is a block-diagonal matrix with the input in the top-left
 // block and zeros in the bottom-right block.
-func (e *Executor) applyInject(domain, codomain Object, input *Matrix) (*Matrix, error) {
-	inDim := objectDim(domain)
-	outDim := objectDim(codomain)
+func (e *Executor) applyInject(domain, codomain Object, input *IntMatrix) (*IntMatrix, error) {
+	inDim := BlockDim(domain)
+	outDim := BlockDim(codomain)
 
 	if outDim < inDim {
 		return nil, fmt.Errorf("inject: codomain dim %d < domain dim %d", outDim, inDim)
 	}
 
 	// Embed input into top-left corner of larger matrix
-	result := NewMatrix(outDim, outDim)
+	result := NewIntMatrix(outDim, outDim)
+	result.Den.Set(&input.Den)
 	for i := 0; i < inDim && i < input.Rows; i++ {
 		for j := 0; j < inDim && j < input.Cols; j++ {
-			result.Set(i, j, input.Get(i, j))
+			result.Re[i*outDim+j].Set(&input.Re[i*input.Cols+j])
+			result.Im[i*outDim+j].Set(&input.Im[i*input.Cols+j])
 		}
 	}
 	return result, nil
@@ -418,21 +569,15 @@ func (e *Executor) applyInject(domain, codomain Object, input *Matrix) (*Matrix,
 // applyProject applies projection from a biproduct: A⊕B → A.
 // The output is the top-left block of the input matrix, truncated
 // to the codomain dimension.
-func (e *Executor) applyProject(domain, codomain Object, input *Matrix) (*Matrix, error) {
-	outDim := objectDim(codomain)
+func (e *Executor) applyProject(domain, codomain Object, input *IntMatrix) (*IntMatrix, error) {
+	outDim := BlockDim(codomain)
 
 	if outDim > input.Rows || outDim > input.Cols {
 		return nil, fmt.Errorf("project: codomain dim %d > input dim %dx%d", outDim, input.Rows, input.Cols)
 	}
 
 	// Extract top-left block
-	result := NewMatrix(outDim, outDim)
-	for i := 0; i < outDim; i++ {
-		for j := 0; j < outDim; j++ {
-			result.Set(i, j, input.Get(i, j))
-		}
-	}
-	return result, nil
+	return IntSubMatrix(input, 0, 0, outDim, outDim), nil
 }
 
 // applyCopy applies classical copying: C(k) → C(k)⊗C(k).
@@ -441,15 +586,17 @@ func (e *Executor) applyProject(domain, codomain Object, input *Matrix) (*Matrix
 // diag(p_0, 0, ..., 0, p_1, 0, ..., 0, p_{k-1}).
 // Specifically, the output has non-zero entries only at positions
 // (i*k+i, i*k+i) = p_i, implementing |i⟩ → |i,i⟩.
-func (e *Executor) applyCopy(domain Object, input *Matrix) (*Matrix, error) {
+func (e *Executor) applyCopy(domain Object, input *IntMatrix) (*IntMatrix, error) {
 	k := input.Rows
 	outDim := k * k
 
-	result := NewMatrix(outDim, outDim)
+	result := NewIntMatrix(outDim, outDim)
+	result.Den.Set(&input.Den)
 	for i := 0; i < k; i++ {
 		// |i⟩ maps to |i,i⟩ which has index i*k + i
 		idx := i*k + i
-		result.Set(idx, idx, input.Get(i, i))
+		result.Re[idx*outDim+idx].Set(&input.Re[i*input.Cols+i])
+		result.Im[idx*outDim+idx].Set(&input.Im[i*input.Cols+i])
 	}
 	return result, nil
 }
@@ -457,97 +604,65 @@ func (e *Executor) applyCopy(domain Object, input *Matrix) (*Matrix, error) {
 // applyDelete applies classical deletion: C(k) → I.
 // Returns the trace of the input as a 1x1 matrix. For a classical
 // (diagonal) density matrix, this sums the probabilities.
-func (e *Executor) applyDelete(input *Matrix) (*Matrix, error) {
-	tr := Trace(input)
-	result := NewMatrix(1, 1)
-	result.Set(0, 0, tr)
-	return result, nil
+func (e *Executor) applyDelete(input *IntMatrix) (*IntMatrix, error) {
+	return IntTrace(input), nil
 }
 
 // applyEncode applies classical-to-quantum encoding: C(k) → Q(k).
 // A diagonal classical density matrix is already a valid quantum density
 // matrix, so this returns the input unchanged.
-func (e *Executor) applyEncode(domain Object, input *Matrix) (*Matrix, error) {
+func (e *Executor) applyEncode(domain Object, input *IntMatrix) (*IntMatrix, error) {
 	return input.Clone(), nil
 }
 
 // applyDecode applies quantum-to-classical decoding: Q(k) → C(k).
 // Measures in the computational basis by extracting the diagonal of the
 // density matrix, producing a diagonal (classical) density matrix.
-func (e *Executor) applyDecode(domain Object, input *Matrix) (*Matrix, error) {
+func (e *Executor) applyDecode(domain Object, input *IntMatrix) (*IntMatrix, error) {
 	n := input.Rows
-	result := NewMatrix(n, n)
+	result := NewIntMatrix(n, n)
+	result.Den.Set(&input.Den)
 	for i := 0; i < n; i++ {
-		result.Set(i, i, input.Get(i, i))
+		result.Re[i*n+i].Set(&input.Re[i*input.Cols+i])
+		result.Im[i*n+i].Set(&input.Im[i*input.Cols+i])
 	}
 	return result, nil
 }
 
 // applyTrace applies the quantum trace: Q(n) → I.
 // Returns Tr(ρ) as a 1x1 matrix.
-func (e *Executor) applyTrace(input *Matrix) (*Matrix, error) {
-	tr := Trace(input)
-	result := NewMatrix(1, 1)
-	result.Set(0, 0, tr)
-	return result, nil
+func (e *Executor) applyTrace(input *IntMatrix) (*IntMatrix, error) {
+	return IntTrace(input), nil
 }
 
 // applyKraus applies a Kraus representation: Φ(ρ) = Σ_k K_k ρ K_k†.
 // The Kraus operators are stored in the circuit's Data field as a
 // Tag("kraus", Seq(matrix_1, matrix_2, ...)).
-func (e *Executor) applyKraus(c Circuit, input *Matrix) (*Matrix, error) {
-	tag, ok := c.Data.(Tag)
-	if !ok {
-		return nil, fmt.Errorf("kraus: data must be a Tag")
-	}
-	label, ok := tag.Label.(Text)
-	if !ok || label.V != "kraus" {
-		return nil, fmt.Errorf("kraus: data must be Tag(\"kraus\", ...)")
-	}
-	seq, ok := tag.Payload.(Seq)
-	if !ok {
-		return nil, fmt.Errorf("kraus: payload must be a Seq of matrices")
-	}
-
-	if len(seq.Items) == 0 {
-		outDim := objectDim(c.Codomain)
-		return NewMatrix(outDim, outDim), nil
-	}
+func (e *Executor) applyKraus(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	return krausValueSum(c.Data, input, BlockDim(c.Codomain))
+}
 
-	var result *Matrix
-	for i, item := range seq.Items {
-		K, ok := MatrixFromValue(item)
-		if !ok {
-			return nil, fmt.Errorf("kraus: operator %d is not a valid matrix", i)
-		}
-		// Compute K ρ K†
-		Kdag := Dagger(K)
-		term := MatMul(MatMul(K, input), Kdag)
-		if term == nil {
-			return nil, fmt.Errorf("kraus: dimension mismatch for operator %d", i)
-		}
-		if result == nil {
-			result = term
-		} else {
-			result = MatAdd(result, term)
-			if result == nil {
-				return nil, fmt.Errorf("kraus: dimension mismatch in sum at operator %d", i)
-			}
+// applyUnitary applies a unitary operation: U ρ U†. A gate written in the
+// sparse encoding is applied in CSR form.
+func (e *Executor) applyUnitary(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	if S, ok := sparseOperatorFromValue(c.Data); ok {
+		out := conjugateSparseInt(S, input)
+		if out == nil {
+			return nil, fmt.Errorf("unitary: %dx%d matrix on %dx%d input", S.Rows, S.Cols, input.Rows, input.Cols)
 		}
+		return out, nil
 	}
-	return result, nil
-}
-
-// applyUnitary applies a unitary operation: U ρ U†.
-func (e *Executor) applyUnitary(c Circuit, input *Matrix) (*Matrix, error) {
 	// Get unitary matrix from data
-	U, ok := MatrixFromValue(c.Data)
+	U, ok := intMatrixFromValue(c.Data)
 	if !ok {
 		return nil, fmt.Errorf("unitary data must be matrix")
 	}
-	// Compute U ρ U†
-	Udag := Dagger(U)
-	return MatMul(MatMul(U, input), Udag), nil
+	// Compute U ρ U† over a common denominator
+	out := conjugateInt(U, input)
+	if out == nil {
+		return nil, fmt.Errorf("unitary: %dx%d matrix on %dx%d input", U.Rows, U.Cols, input.Rows, input.Cols)
+	}
+	return out, nil
 }
 
 // applyChoi applies a channel via its Choi matrix.
@@ -555,9 +670,9 @@ func (e *Executor) applyUnitary(c Circuit, input *Matrix) (*Matrix, error) {
 // (n_in * n_out) × (n_in * n_out) matrix. The channel is reconstructed as:
 //   Φ(ρ) = Tr_in[(ρ^T ⊗ I_out) J]
 // where the partial trace is over the input subsystem.
-func (e *Executor) applyChoi(c Circuit, input *Matrix) (*Matrix, error) {
+func (e *Executor) applyChoi(c Circuit, input *IntMatrix) (*IntMatrix, error) {
 	// Get Choi matrix from data
-	J, ok := MatrixFromValue(c.Data)
+	J, ok := intMatrixFromValue(c.Data)
 	if !ok {
 		return nil, fmt.Errorf("choi data must be matrix")
 	}
@@ -568,24 +683,25 @@ func (e *Executor) applyChoi(c Circuit, input *Matrix) (*Matrix, error) {
 
 	// Apply Choi-Jamiolkowski isomorphism
 	// Φ(ρ) = Tr_in[(ρ^T ⊗ I_out) J]
-	result := NewMatrix(outDim, outDim)
+	result := NewIntMatrix(outDim, outDim)
+	result.Den.Mul(&input.Den, &J.Den)
+	var t big.Int
 
 	for i := 0; i < outDim; i++ {
 		for j := 0; j < outDim; j++ {
-			sum := QIZero()
+			r := i*outDim + j
 			for k := 0; k < inDim; k++ {
 				for l := 0; l < inDim; l++ {
-					// ρ^T[k,l] * J[k*outDim+i, l*outDim+j]
-					rhoEntry := input.Get(l, k) // Transpose
+					// ρ^T[l,k] * J[k*outDim+i, l*outDim+j]
+					a := k*input.Cols + l
 					jRow := k*outDim + i
 					jCol := l*outDim + j
-					if jRow < J.Rows && jCol < J.Cols {
-						jEntry := J.Get(jRow, jCol)
-						sum = QIAdd(sum, QIMul(rhoEntry, jEntry))
+					if jRow < J.Rows && jCol < J.Cols && !input.isZero(a) {
+						b := jRow*J.Cols + jCol
+						mulAcc(&result.Re[r], &result.Im[r], &input.Re[a], &input.Im[a], &J.Re[b], &J.Im[b], input.Im[a].Sign() == 0, &t)
 					}
 				}
 			}
-			result.Set(i, j, sum)
 		}
 	}
 
@@ -593,13 +709,21 @@ func (e *Executor) applyChoi(c Circuit, input *Matrix) (*Matrix, error) {
 }
 
 // applyPrepare prepares a fixed state.
-func (e *Executor) applyPrepare(c Circuit) (*Matrix, error) {
+// Preparation is a map I → A, so a 1×1 input is the weight of the unit
+// input and scales the prepared state. This keeps Prepare linear when it
+// runs on a single classical outcome inside a Branch.
+func (e *Executor) applyPrepare(c Circuit, input *IntMatrix) (*IntMatrix, error) {
 	// Get prepared state from data
-	rho, ok := MatrixFromValue(c.Data)
+	rho, ok := intMatrixFromValue(c.Data)
 	if !ok {
 		return nil, fmt.Errorf("prepare data must be matrix")
 	}
-	return rho.Clone(), nil
+	// w·ρ is the Kronecker product of the 1×1 weight w with ρ. Any other
+	// input carries weight 1.
+	if input != nil && input.Rows == 1 && input.Cols == 1 {
+		return IntKronecker(input, rho), nil
+	}
+	return rho, nil
 }
 
 // objectDim computes the dimension of an object.
@@ -700,54 +824,3 @@ func CircuitFromValue(v Value) (Circuit, bool) {
 		Children: children,
 	}, true
 }
-
-// ObjectToValue converts an object to a Value.
-func ObjectToValue(obj Object) Value {
-	blocks := make([]Value, len(obj.Blocks))
-	for i, n := range obj.Blocks {
-		blocks[i] = MakeInt(int64(n))
-	}
-	return MakeTag(
-		MakeText("object"),
-		MakeSeq(blocks...),
-	)
-}
-
-// ObjectFromValue parses an object from a Value.
-func ObjectFromValue(v Value) (Object, bool) {
-	tag, ok := v.(Tag)
-	if !ok {
-		return Object{}, false
-	}
-	label, ok := tag.Label.(Text)
-	if !ok || label.V != "object" {
-		return Object{}, false
-	}
-	seq, ok := tag.Payload.(Seq)
-	if !ok {
-		return Object{}, false
-	}
-
-	blocks := make([]uint32, len(seq.Items))
-	for i, item := range seq.Items {
-		n, ok := item.(Int)
-		if !ok {
-			return Object{}, false
-		}
-		blocks[i] = uint32(n.V.Int64())
-	}
-	return Object{Blocks: blocks}, true
-}
-
-// ObjectEqual checks if two objects are equal.
-func ObjectEqual(a, b Object) bool {
-	if len(a.Blocks) != len(b.Blocks) {
-		return false
-	}
-	for i := range a.Blocks {
-		if a.Blocks[i] != b.Blocks[i] {
-			return false
-		}
-	}
-	return true
-}
@@ -0,0 +1,97 @@
+package runtime
+
+// Store reachability.
+//
+// A store accumulates every circuit put into it, including intermediate
+// circuits from synthesis and rewriting that the final circuit no longer
+// uses, and Embed writes all of them. An entry references another entry
+// through
+//
+//	Circuit.Children                   the QGIDs of its subcircuits
+//	Bytes of length 32 in Data         e.g. the circuit of a model or the
+//	                                   channels of a witness
+//
+// and a plain value through any 32-byte Bytes it contains. A 32-byte Bytes
+// counts as a reference only if the store holds an entry with that QGID;
+// other byte strings of that length are data. A QGID missing from the
+// store reaches the external reference that names it, if any (see
+// link.go). Reachable follows these edges from a set of roots and Prune
+// deletes everything else.
+
+// Reachable returns the QGIDs of the entries reachable from roots. Roots
+// not in the store are omitted.
+func (s *Store) Reachable(roots ...[32]byte) map[[32]byte]bool {
+	seen := make(map[[32]byte]bool)
+	queue := make([][32]byte, 0, len(roots))
+	var externs map[[32]byte][32]byte
+	visit := func(id [32]byte) {
+		if _, ok := s.values[id]; !ok {
+			if externs == nil {
+				externs = s.externs()
+			}
+			ref, ok := externs[id]
+			if !ok {
+				return
+			}
+			id = ref
+		}
+		if !seen[id] {
+			seen[id] = true
+			queue = append(queue, id)
+		}
+	}
+	for _, id := range roots {
+		visit(id)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if c, ok := s.circuits[id]; ok {
+			circuitRefs(c, visit)
+		} else {
+			valueRefs(s.values[id], visit)
+		}
+	}
+	return seen
+}
+
+// Prune deletes every entry not reachable from roots and returns the number
+// deleted.
+func (s *Store) Prune(roots ...[32]byte) int {
+	keep := s.Reachable(roots...)
+	removed := 0
+	for id := range s.values {
+		if !keep[id] {
+			delete(s.values, id)
+			delete(s.circuits, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// circuitRefs calls visit with the children of c and the references in its
+// Data. visit decides which of them exist.
+func circuitRefs(c Circuit, visit func([32]byte)) {
+	for _, child := range c.Children {
+		visit(child)
+	}
+	valueRefs(c.Data, visit)
+}
+
+// valueRefs calls visit with every 32-byte Bytes inside v.
+func valueRefs(v Value, visit func([32]byte)) {
+	switch v := v.(type) {
+	case Bytes:
+		if len(v.V) == 32 {
+			visit([32]byte(v.V))
+		}
+	case Seq:
+		for _, item := range v.Items {
+			valueRefs(item, visit)
+		}
+	case Tag:
+		valueRefs(v.Label, visit)
+		valueRefs(v.Payload, visit)
+	}
+}
@@ -0,0 +1,43 @@
+package runtime
+
+import "testing"
+
+func TestStoreReachablePrune(t *testing.T) {
+	// root tensors one unitary with itself, so it reaches two entries.
+	store := NewStore()
+	h := putUnitary(store, hadamardUnnorm())
+	two := tensorObject(qubit(), qubit())
+	root := store.Put(Circuit{Domain: two, Codomain: two, Prim: PrimTensor, Children: [][32]byte{h, h}})
+	putUnitary(store, pauliX()) // discarded intermediate
+	store.PutValue(MakeText("orphan"))
+
+	// A model-like value naming root, and a circuit whose Data names that
+	// value next to a 32-byte string that is not a QGID.
+	model := store.PutValue(MakeTag(MakeText("model"), MakeBytes(root[:])))
+	noise := make([]byte, 32)
+	prep := store.Put(Circuit{Domain: unitObject(), Codomain: unitObject(), Prim: PrimWitness,
+		Data: MakeSeq(MakeBytes(model[:]), MakeBytes(noise))})
+
+	if got := store.Reachable(root); len(got) != 2 {
+		t.Errorf("Reachable(root) has %d entries, want the tensor and its unitary", len(got))
+	}
+	if got := store.Reachable(prep); len(got) != 4 || !got[model] || !got[root] {
+		t.Errorf("Reachable(prep) = %d entries, want prep, model, root and its unitary", len(got))
+	}
+
+	// v2 lazy loading follows the same references.
+	runner, err := NewRunner(EmbedV2(store, prep, "gc", "1").Encode())
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	if runner.StoreSize() != 4 {
+		t.Errorf("v2 runner loaded %d entries, want 4", runner.StoreSize())
+	}
+
+	if removed := store.Prune(prep); removed != 2 || store.StoreSize() != 4 {
+		t.Errorf("Prune removed %d, kept %d; want 2 and 4", removed, store.StoreSize())
+	}
+	if _, ok := store.Get(root); !ok {
+		t.Error("Prune dropped a reachable circuit")
+	}
+}
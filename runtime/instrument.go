@@ -0,0 +1,306 @@
+package runtime
+
+import (
+	"fmt"
+)
+
+// Quantum instruments and classical branching.
+//
+// An instrument is a family of CP maps {Φ_0, ..., Φ_{k-1}} indexed by a
+// classical outcome. Applied to ρ it produces the block-diagonal state
+//
+//	Φ_0(ρ) ⊕ Φ_1(ρ) ⊕ ... ⊕ Φ_{k-1}(ρ)
+//
+// whose i-th block carries outcome i together with its post-measurement
+// state. The codomain therefore has one block per outcome. Two data
+// payloads are accepted on PrimInstrument circuits:
+//
+//	Tag("instrument", Seq(Tag("kraus", Seq(K...)), ...))  one Kraus family per outcome
+//	Tag("povm", Seq(E_0, ..., E_{k-1}))                    effects of a destructive measurement
+//
+// The "povm" form is the instrument whose outcome maps are ρ ↦ Tr(E_i ρ),
+// so its codomain is C(k). It lets measurements whose Kraus operators
+// would be √E_i (usually irrational) be stated exactly.
+//
+// A branch is classical control over the blocks of its domain. A
+// PrimBranch circuit has one child per domain block; child i runs on the
+// i-th diagonal block of the input and the results are summed:
+//
+//	Branch(f_0, ..., f_{k-1})(ρ_0 ⊕ ... ⊕ ρ_{k-1}) = f_0(ρ_0) + ... + f_{k-1}(ρ_{k-1})
+//
+// Every child shares the branch's codomain. Branch data is free-form
+// annotation and is ignored by the executor.
+
+// KrausToValue encodes a family of Kraus operators as
+// Tag("kraus", Seq(matrix_1, matrix_2, ...)).
+func KrausToValue(ops []*Matrix) Value {
+	items := make([]Value, len(ops))
+	for i, K := range ops {
+		items[i] = MatrixToValue(K)
+	}
+	return MakeTag(MakeText("kraus"), MakeSeq(items...))
+}
+
+// KrausFromValue parses a Tag("kraus", Seq(matrix, ...)) value.
+func KrausFromValue(v Value) ([]*Matrix, error) {
+	items, err := krausItems(v)
+	if err != nil {
+		return nil, err
+	}
+	ops := make([]*Matrix, len(items))
+	for i, item := range items {
+		K, ok := MatrixFromValue(item)
+		if !ok {
+			return nil, fmt.Errorf("kraus: operator %d is not a valid matrix", i)
+		}
+		ops[i] = K
+	}
+	return ops, nil
+}
+
+// krausItems returns the operator values of a Tag("kraus", Seq(...)).
+func krausItems(v Value) ([]Value, error) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return nil, fmt.Errorf("kraus: data must be a Tag")
+	}
+	label, ok := tag.Label.(Text)
+	if !ok || label.V != "kraus" {
+		return nil, fmt.Errorf("kraus: data must be Tag(\"kraus\", ...)")
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok {
+		return nil, fmt.Errorf("kraus: payload must be a Seq of matrices")
+	}
+	return seq.Items, nil
+}
+
+// InstrumentToValue encodes an instrument given as one Kraus family per
+// outcome. An empty family encodes the zero map for that outcome.
+func InstrumentToValue(outcomes [][]*Matrix) Value {
+	items := make([]Value, len(outcomes))
+	for i, ops := range outcomes {
+		items[i] = KrausToValue(ops)
+	}
+	return MakeTag(MakeText("instrument"), MakeSeq(items...))
+}
+
+// POVMToValue encodes the effects of a destructive measurement as
+// Tag("povm", Seq(E_0, ..., E_{k-1})).
+func POVMToValue(effects []*Matrix) Value {
+	items := make([]Value, len(effects))
+	for i, E := range effects {
+		items[i] = MatrixToValue(E)
+	}
+	return MakeTag(MakeText("povm"), MakeSeq(items...))
+}
+
+// applyInstrument applies a quantum instrument, producing the direct sum
+// of its outcome maps applied to the input.
+func (e *Executor) applyInstrument(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	tag, ok := c.Data.(Tag)
+	if !ok {
+		return nil, fmt.Errorf("instrument: data must be a Tag")
+	}
+	label, ok := tag.Label.(Text)
+	if !ok {
+		return nil, fmt.Errorf("instrument: data label must be Text")
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok {
+		return nil, fmt.Errorf("instrument: payload must be a Seq")
+	}
+	if len(c.Codomain.Blocks) != len(seq.Items) {
+		return nil, fmt.Errorf("instrument: codomain has %d blocks, data has %d outcomes",
+			len(c.Codomain.Blocks), len(seq.Items))
+	}
+
+	blocks := make([]*IntMatrix, len(seq.Items))
+	switch label.V {
+	case "instrument":
+		for i, item := range seq.Items {
+			n := int(c.Codomain.Blocks[i])
+			block, err := krausValueSum(item, input, n)
+			if err != nil {
+				return nil, fmt.Errorf("instrument: outcome %d: %w", i, err)
+			}
+			if block.Rows != n || block.Cols != n {
+				return nil, fmt.Errorf("instrument: outcome %d is %dx%d, codomain block is %d",
+					i, block.Rows, block.Cols, n)
+			}
+			blocks[i] = block
+		}
+
+	case "povm":
+		for i, item := range seq.Items {
+			if c.Codomain.Blocks[i] != 1 {
+				return nil, fmt.Errorf("instrument: povm outcome %d has block size %d, want 1",
+					i, c.Codomain.Blocks[i])
+			}
+			if E, ok := intMatrixFromValue(item); ok {
+				blocks[i] = intTraceMul(E, input)
+				if blocks[i] == nil {
+					return nil, fmt.Errorf("instrument: dimension mismatch for effect %d", i)
+				}
+				continue
+			}
+			// An effect over Q(ζ8) can still give a probability in Q(i).
+			E, ok := MatrixZ8FromValue(item)
+			if !ok {
+				return nil, fmt.Errorf("instrument: effect %d is not a valid matrix", i)
+			}
+			p := MatMulZ8(E, MatrixToZ8(input.ToMatrix()))
+			if p == nil || p.Rows != p.Cols {
+				return nil, fmt.Errorf("instrument: dimension mismatch for effect %d", i)
+			}
+			q, ok := QZ8ToQI(TraceZ8(p))
+			if !ok {
+				return nil, fmt.Errorf("instrument: probability of effect %d: %w; use ExecuteZ8", i, ErrNotQI)
+			}
+			block := NewMatrix(1, 1)
+			block.Set(0, 0, q)
+			blocks[i] = ToIntMatrix(block)
+		}
+
+	default:
+		return nil, fmt.Errorf("instrument: unknown data label %q", label.V)
+	}
+
+	return IntDirectSum(blocks...), nil
+}
+
+// krausSum computes Σ_k K_k ρ K_k† over a common denominator, reducing
+// the entries once at the end. An empty family is the zero map onto an
+// n×n output.
+func krausSum(ops []*Matrix, input *Matrix, n int) (*Matrix, error) {
+	terms := make([]conjugator, len(ops))
+	for i, K := range ops {
+		terms[i] = denseConjugator(K)
+	}
+	out, err := sumConjugates(terms, ToIntMatrix(input), n)
+	if err != nil {
+		return nil, err
+	}
+	return out.ToMatrix(), nil
+}
+
+// krausValueSum is krausSum for the Kraus family encoded in v. Operators
+// written in the sparse encoding stay in CSR form.
+func krausValueSum(v Value, input *IntMatrix, n int) (*IntMatrix, error) {
+	items, err := krausItems(v)
+	if err != nil {
+		return nil, err
+	}
+	terms := make([]conjugator, len(items))
+	for i, item := range items {
+		if S, ok := sparseOperatorFromValue(item); ok {
+			terms[i] = func(rho *IntMatrix) *IntMatrix { return conjugateSparseInt(S, rho) }
+			continue
+		}
+		K, ok := MatrixFromValue(item)
+		if !ok {
+			return nil, fmt.Errorf("kraus: operator %d is not a valid matrix", i)
+		}
+		terms[i] = denseConjugator(K)
+	}
+	result, err := sumConjugates(terms, input, n)
+	if err != nil {
+		return nil, fmt.Errorf("kraus: %w", err)
+	}
+	return result, nil
+}
+
+// A conjugator computes K ρ K† for one Kraus operator K, or nil on a
+// shape mismatch.
+type conjugator func(rho *IntMatrix) *IntMatrix
+
+func denseConjugator(K *Matrix) conjugator {
+	return func(rho *IntMatrix) *IntMatrix { return conjugateInt(ToIntMatrix(K), rho) }
+}
+
+// sumConjugates sums the terms applied to rho, onto an n×n output when
+// there are none.
+func sumConjugates(terms []conjugator, rho *IntMatrix, n int) (*IntMatrix, error) {
+	if len(terms) == 0 {
+		return NewIntMatrix(n, n), nil
+	}
+	var result *IntMatrix
+	for i, conj := range terms {
+		term := conj(rho)
+		if term == nil {
+			return nil, fmt.Errorf("dimension mismatch for operator %d", i)
+		}
+		if result == nil {
+			result = term
+			continue
+		}
+		result = IntMatAdd(result, term)
+		if result == nil {
+			return nil, fmt.Errorf("dimension mismatch in sum at operator %d", i)
+		}
+	}
+	return result, nil
+}
+
+// applyBranch applies classical control over the blocks of the domain.
+// Child i runs on the i-th diagonal block of the input and the children's
+// outputs are summed.
+func (e *Executor) applyBranch(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	if len(c.Domain.Blocks) == 0 {
+		return nil, fmt.Errorf("branch: domain has no blocks")
+	}
+	if len(c.Children) != len(c.Domain.Blocks) {
+		return nil, fmt.Errorf("branch: domain has %d blocks, got %d children",
+			len(c.Domain.Blocks), len(c.Children))
+	}
+	if dim := BlockDim(c.Domain); input.Rows != dim || input.Cols != dim {
+		return nil, fmt.Errorf("branch: input is %dx%d, domain dim is %d",
+			input.Rows, input.Cols, dim)
+	}
+
+	children := make([]Circuit, len(c.Children))
+	offsets := make([]int, len(c.Children))
+	offset := 0
+	for i, n := range c.Domain.Blocks {
+		child, ok := e.store.Get(c.Children[i])
+		if !ok {
+			return nil, fmt.Errorf("child %d not found", i)
+		}
+		if BlockDim(child.Domain) != int(n) {
+			return nil, fmt.Errorf("branch: child %d domain dim %d does not match block size %d",
+				i, BlockDim(child.Domain), n)
+		}
+		children[i] = child
+		offsets[i] = offset
+		offset += int(n)
+	}
+
+	outputs := make([]*IntMatrix, len(children))
+	err := e.forEach(len(children), func(i int) error {
+		n := int(c.Domain.Blocks[i])
+		out, err := e.executeInt(children[i], IntSubMatrix(input, offsets[i], offsets[i], n, n))
+		outputs[i] = out
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := outputs[0]
+	for i, out := range outputs[1:] {
+		result = IntMatAdd(result, out)
+		if result == nil {
+			return nil, fmt.Errorf("branch: child %d output dimension mismatch", i+1)
+		}
+	}
+	return result, nil
+}
+
+// matScaleQI computes q * A for a Gaussian rational q.
+func matScaleQI(A *Matrix, q QI) *Matrix {
+	C := NewMatrix(A.Rows, A.Cols)
+	for i := range A.Data {
+		C.Data[i] = QIMul(A.Data[i], q)
+	}
+	return C
+}
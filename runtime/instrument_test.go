@@ -0,0 +1,395 @@
+package runtime
+
+import (
+	"math/big"
+	"testing"
+)
+
+// qiHalf returns the Gaussian rational 1/2.
+func qiHalf() QI {
+	return NewQI(big.NewRat(1, 2), new(big.Rat))
+}
+
+// rhoPlusExact returns |+⟩⟨+| = (I + X)/2, which is exact in Q(i).
+func rhoPlusExact() *Matrix {
+	m := NewMatrix(2, 2)
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			m.Set(i, j, qiHalf())
+		}
+	}
+	return m
+}
+
+// ket1bra1 returns |1⟩⟨1|.
+func ket1bra1() *Matrix {
+	m := NewMatrix(2, 2)
+	m.Set(1, 1, QIOne())
+	return m
+}
+
+func TestExecuteInstrumentPOVM(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Z-basis measurement Q(2) → C(2)
+	c := Circuit{
+		Domain:   Object{Blocks: []uint32{2}},
+		Codomain: Object{Blocks: []uint32{1, 1}},
+		Prim:     PrimInstrument,
+		Data:     POVMToValue([]*Matrix{ket0bra0(), ket1bra1()}),
+	}
+
+	input := NewMatrix(2, 2)
+	input.Set(0, 0, NewQI(big.NewRat(1, 3), new(big.Rat)))
+	input.Set(0, 1, NewQI(big.NewRat(1, 5), big.NewRat(1, 7)))
+	input.Set(1, 0, NewQI(big.NewRat(1, 5), big.NewRat(-1, 7)))
+	input.Set(1, 1, NewQI(big.NewRat(2, 3), new(big.Rat)))
+
+	result, err := exec.Execute(c, input)
+	if err != nil {
+		t.Fatalf("Execute instrument failed: %v", err)
+	}
+
+	expected := NewMatrix(2, 2)
+	expected.Set(0, 0, NewQI(big.NewRat(1, 3), new(big.Rat)))
+	expected.Set(1, 1, NewQI(big.NewRat(2, 3), new(big.Rat)))
+	if !MatrixEqual(result, expected) {
+		t.Errorf("povm(ρ) should be diag(1/3, 2/3)")
+	}
+}
+
+func TestExecuteInstrumentKraus(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Lüders Z measurement Q(2) → Q(2)⊕Q(2): outcome i keeps P_i ρ P_i.
+	c := Circuit{
+		Domain:   Object{Blocks: []uint32{2}},
+		Codomain: Object{Blocks: []uint32{2, 2}},
+		Prim:     PrimInstrument,
+		Data: InstrumentToValue([][]*Matrix{
+			{ket0bra0()},
+			{ket1bra1()},
+		}),
+	}
+
+	result, err := exec.Execute(c, rhoPlusExact())
+	if err != nil {
+		t.Fatalf("Execute instrument failed: %v", err)
+	}
+
+	if result.Rows != 4 || result.Cols != 4 {
+		t.Fatalf("instrument should produce 4x4, got %dx%d", result.Rows, result.Cols)
+	}
+
+	// (1/2)|0⟩⟨0| ⊕ (1/2)|1⟩⟨1|
+	expected := NewMatrix(4, 4)
+	expected.Set(0, 0, qiHalf())
+	expected.Set(3, 3, qiHalf())
+	if !MatrixEqual(result, expected) {
+		t.Error("Lüders measurement of |+⟩ should give (1/2)|0⟩⟨0| ⊕ (1/2)|1⟩⟨1|")
+	}
+}
+
+func TestExecuteInstrumentZeroOutcome(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Outcome 1 is the zero map onto Q(3).
+	c := Circuit{
+		Domain:   Object{Blocks: []uint32{2}},
+		Codomain: Object{Blocks: []uint32{2, 3}},
+		Prim:     PrimInstrument,
+		Data:     InstrumentToValue([][]*Matrix{{Identity(2)}, {}}),
+	}
+
+	input := ket1bra1()
+	result, err := exec.Execute(c, input)
+	if err != nil {
+		t.Fatalf("Execute instrument failed: %v", err)
+	}
+
+	expected := DirectSum(input, NewMatrix(3, 3))
+	if !MatrixEqual(result, expected) {
+		t.Error("empty Kraus family should contribute a zero block")
+	}
+}
+
+func TestExecuteEmptyKrausShape(t *testing.T) {
+	exec := NewExecutor(NewStore())
+
+	// The empty family and PrimZero are the zero map onto the codomain's
+	// Hilbert space: 3x3 for Q(2)⊕Q(1), not one entry per block element.
+	codomain := Object{Blocks: []uint32{2, 1}}
+	for _, c := range []Circuit{
+		{Domain: qubit(), Codomain: codomain, Prim: PrimKraus, Data: KrausToValue(nil)},
+		{Domain: qubit(), Codomain: codomain, Prim: PrimZero},
+	} {
+		result, err := exec.Execute(c, ket1bra1())
+		if err != nil {
+			t.Fatalf("Execute %s failed: %v", PrimName(c.Prim), err)
+		}
+		if !MatrixEqual(result, NewMatrix(3, 3)) {
+			t.Errorf("%s: got a %dx%d matrix, want the 3x3 zero", PrimName(c.Prim), result.Rows, result.Cols)
+		}
+	}
+}
+
+func TestExecuteInstrumentErrors(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	tests := []struct {
+		name string
+		c    Circuit
+	}{
+		{"nil data", Circuit{
+			Domain:   Object{Blocks: []uint32{2}},
+			Codomain: Object{Blocks: []uint32{1, 1}},
+			Prim:     PrimInstrument,
+		}},
+		{"unknown label", Circuit{
+			Domain:   Object{Blocks: []uint32{2}},
+			Codomain: Object{Blocks: []uint32{1, 1}},
+			Prim:     PrimInstrument,
+			Data:     MakeTag(MakeText("z-basis"), MakeSeq()),
+		}},
+		{"outcome count", Circuit{
+			Domain:   Object{Blocks: []uint32{2}},
+			Codomain: Object{Blocks: []uint32{1, 1, 1}},
+			Prim:     PrimInstrument,
+			Data:     POVMToValue([]*Matrix{ket0bra0(), ket1bra1()}),
+		}},
+		{"outcome block size", Circuit{
+			Domain:   Object{Blocks: []uint32{2}},
+			Codomain: Object{Blocks: []uint32{1, 2}},
+			Prim:     PrimInstrument,
+			Data:     InstrumentToValue([][]*Matrix{{Identity(2)}, {Identity(2)}}),
+		}},
+		{"effect dimension", Circuit{
+			Domain:   Object{Blocks: []uint32{2}},
+			Codomain: Object{Blocks: []uint32{1}},
+			Prim:     PrimInstrument,
+			Data:     POVMToValue([]*Matrix{Identity(3)}),
+		}},
+	}
+
+	for _, tt := range tests {
+		if _, err := exec.Execute(tt.c, ket0bra0()); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestExecuteBranchPrepare(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Branch over C(2): outcome 0 prepares |0⟩, outcome 1 prepares |+⟩.
+	prep0 := store.Put(Circuit{
+		Domain:   Object{Blocks: []uint32{1}},
+		Codomain: qubit(),
+		Prim:     PrimPrepare,
+		Data:     MatrixToValue(ket0bra0()),
+	})
+	prepPlus := store.Put(Circuit{
+		Domain:   Object{Blocks: []uint32{1}},
+		Codomain: qubit(),
+		Prim:     PrimPrepare,
+		Data:     MatrixToValue(rhoPlusExact()),
+	})
+
+	c := Circuit{
+		Domain:   Object{Blocks: []uint32{1, 1}},
+		Codomain: qubit(),
+		Prim:     PrimBranch,
+		Children: [][32]byte{prep0, prepPlus},
+	}
+
+	// Classical input diag(1/4, 3/4)
+	input := NewMatrix(2, 2)
+	input.Set(0, 0, NewQI(big.NewRat(1, 4), new(big.Rat)))
+	input.Set(1, 1, NewQI(big.NewRat(3, 4), new(big.Rat)))
+
+	result, err := exec.Execute(c, input)
+	if err != nil {
+		t.Fatalf("Execute branch failed: %v", err)
+	}
+
+	// (1/4)|0⟩⟨0| + (3/4)|+⟩⟨+|
+	expected := MatAdd(
+		MatScale(ket0bra0(), big.NewRat(1, 4)),
+		MatScale(rhoPlusExact(), big.NewRat(3, 4)),
+	)
+	if !MatrixEqual(result, expected) {
+		t.Error("branch should mix the prepared states with the classical weights")
+	}
+}
+
+func TestExecuteBranchControlledUnitary(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Controlled-X on C(2)⊗Q(2) = Q(2)⊕Q(2), keeping only the target.
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	x := store.Put(Circuit{
+		Domain:   qubit(),
+		Codomain: qubit(),
+		Prim:     PrimUnitary,
+		Data:     MatrixToValue(pauliX()),
+	})
+
+	c := Circuit{
+		Domain:   Object{Blocks: []uint32{2, 2}},
+		Codomain: qubit(),
+		Prim:     PrimBranch,
+		Children: [][32]byte{id, x},
+	}
+
+	// (1/2)|0⟩⟨0| on control 0 and (1/2)|0⟩⟨0| on control 1
+	input := DirectSum(MatScale(ket0bra0(), big.NewRat(1, 2)), MatScale(ket0bra0(), big.NewRat(1, 2)))
+	result, err := exec.Execute(c, input)
+	if err != nil {
+		t.Fatalf("Execute branch failed: %v", err)
+	}
+
+	expected := NewMatrix(2, 2)
+	expected.Set(0, 0, qiHalf())
+	expected.Set(1, 1, qiHalf())
+	if !MatrixEqual(result, expected) {
+		t.Error("controlled X should flip only the control-1 block")
+	}
+}
+
+func TestExecuteMeasureThenBranch(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Measure in Z and re-prepare the opposite basis state: a classical
+	// feed-forward that maps |0⟩ → |1⟩ and |1⟩ → |0⟩ on diagonal inputs.
+	measure := store.Put(Circuit{
+		Domain:   qubit(),
+		Codomain: Object{Blocks: []uint32{1, 1}},
+		Prim:     PrimInstrument,
+		Data:     POVMToValue([]*Matrix{ket0bra0(), ket1bra1()}),
+	})
+	prep1 := store.Put(Circuit{
+		Domain:   Object{Blocks: []uint32{1}},
+		Codomain: qubit(),
+		Prim:     PrimPrepare,
+		Data:     MatrixToValue(ket1bra1()),
+	})
+	prep0 := store.Put(Circuit{
+		Domain:   Object{Blocks: []uint32{1}},
+		Codomain: qubit(),
+		Prim:     PrimPrepare,
+		Data:     MatrixToValue(ket0bra0()),
+	})
+	branch := store.Put(Circuit{
+		Domain:   Object{Blocks: []uint32{1, 1}},
+		Codomain: qubit(),
+		Prim:     PrimBranch,
+		Children: [][32]byte{prep1, prep0},
+	})
+
+	c := Circuit{
+		Domain:   qubit(),
+		Codomain: qubit(),
+		Prim:     PrimCompose,
+		Children: [][32]byte{measure, branch},
+	}
+
+	// |+⟩ measures to 0 or 1 with probability 1/2 each.
+	result, err := exec.Execute(c, rhoPlusExact())
+	if err != nil {
+		t.Fatalf("Execute measure;branch failed: %v", err)
+	}
+	expected := NewMatrix(2, 2)
+	expected.Set(0, 0, qiHalf())
+	expected.Set(1, 1, qiHalf())
+	if !MatrixEqual(result, expected) {
+		t.Error("measure;branch on |+⟩ should give I/2")
+	}
+
+	result, err = exec.Execute(c, ket0bra0())
+	if err != nil {
+		t.Fatalf("Execute measure;branch failed: %v", err)
+	}
+	if !MatrixEqual(result, ket1bra1()) {
+		t.Error("measure;branch should map |0⟩ to |1⟩")
+	}
+}
+
+func TestExecuteBranchErrors(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+
+	// Too few children for the domain blocks.
+	c := Circuit{
+		Domain:   Object{Blocks: []uint32{2, 2}},
+		Codomain: qubit(),
+		Prim:     PrimBranch,
+		Children: [][32]byte{id},
+	}
+	if _, err := exec.Execute(c, Identity(4)); err == nil {
+		t.Error("branch with too few children should fail")
+	}
+
+	// Child domain does not match its block.
+	c = Circuit{
+		Domain:   Object{Blocks: []uint32{1, 1}},
+		Codomain: qubit(),
+		Prim:     PrimBranch,
+		Children: [][32]byte{id, id},
+	}
+	if _, err := exec.Execute(c, Identity(2)); err == nil {
+		t.Error("branch with mismatched child domain should fail")
+	}
+}
+
+func TestPrepareScalesByUnitInput(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	c := Circuit{
+		Domain:   unitObject(),
+		Codomain: qubit(),
+		Prim:     PrimPrepare,
+		Data:     MatrixToValue(ket0bra0()),
+	}
+
+	input := NewMatrix(1, 1)
+	input.Set(0, 0, NewQI(big.NewRat(2, 5), new(big.Rat)))
+	result, err := exec.Execute(c, input)
+	if err != nil {
+		t.Fatalf("Execute prepare failed: %v", err)
+	}
+	if !MatrixEqual(result, MatScale(ket0bra0(), big.NewRat(2, 5))) {
+		t.Error("prepare should scale its state by the unit input")
+	}
+}
+
+func TestDirectSumSubMatrix(t *testing.T) {
+	A := Identity(2)
+	B := MatScale(Identity(3), big.NewRat(1, 3))
+	S := DirectSum(A, B)
+
+	if S.Rows != 5 || S.Cols != 5 {
+		t.Fatalf("DirectSum size = %dx%d, want 5x5", S.Rows, S.Cols)
+	}
+	if !MatrixEqual(SubMatrix(S, 0, 0, 2, 2), A) {
+		t.Error("first block should be A")
+	}
+	if !MatrixEqual(SubMatrix(S, 2, 2, 3, 3), B) {
+		t.Error("second block should be B")
+	}
+	if !MatrixEqual(SubMatrix(S, 0, 2, 2, 3), NewMatrix(2, 3)) {
+		t.Error("off-diagonal block should be zero")
+	}
+	if SubMatrix(S, 4, 4, 2, 2) != nil {
+		t.Error("out-of-range SubMatrix should return nil")
+	}
+}
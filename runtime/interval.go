@@ -0,0 +1,410 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Interval arithmetic.
+//
+// Exact Q(i) arithmetic is the reference semantics, but entry sizes grow
+// with circuit depth and a dense 2^n×2^n density matrix of big.Rat pairs
+// stops being practical past a handful of qubits. Interval arithmetic
+// trades exactness for speed while keeping a guarantee: every float64
+// operation is rounded outward, so an Interval [Lo, Hi] computed from
+// enclosures of the inputs always contains the exact result.
+//
+// Outward rounding is emulated under Go's round-to-nearest with
+// error-free transformations. For s = a + b the rounding error
+// (a - (s - b')) + (b - b') with b' = s - a is exact, and for p = a·b the
+// error is math.FMA(a, b, -p). The bound is moved by one ulp only in the
+// direction the error points, so operations that happen to be exact, such
+// as sums of dyadic rationals, keep point intervals. An overflow widens the
+// bound to ±Inf rather than losing containment.
+//
+// A complex enclosure is a rectangle CInterval{Re, Im}. Q(ζ8) entries
+// (matrix-qz8 data) are enclosed via an interval for √2, so circuits with
+// Hadamard and T gates run on this backend without rational approximation.
+
+// Interval is the closed real interval [Lo, Hi].
+type Interval struct {
+	Lo, Hi float64
+}
+
+// CInterval is the complex rectangle Re + i·Im.
+type CInterval struct {
+	Re, Im Interval
+}
+
+// IntervalMatrix is a row-major matrix of complex enclosures.
+type IntervalMatrix struct {
+	Rows, Cols int
+	Data       []CInterval
+}
+
+var (
+	negInf = math.Inf(-1)
+	posInf = math.Inf(1)
+)
+
+// tinyProduct bounds products below which math.FMA may lose the sign of
+// the rounding error to underflow; such products are widened outright.
+const tinyProduct = 0x1p-960
+
+// addDown returns a lower bound for a + b.
+func addDown(a, b float64) float64 {
+	s := a + b
+	if math.IsInf(s, 0) || math.IsNaN(s) {
+		if math.IsInf(a, -1) || math.IsInf(b, -1) || math.IsNaN(s) {
+			return negInf
+		}
+		if math.IsInf(s, 1) && !math.IsInf(a, 1) && !math.IsInf(b, 1) {
+			return math.MaxFloat64
+		}
+		return s
+	}
+	bb := s - a
+	if (a-(s-bb))+(b-bb) < 0 {
+		return math.Nextafter(s, negInf)
+	}
+	return s
+}
+
+// addUp returns an upper bound for a + b.
+func addUp(a, b float64) float64 {
+	return -addDown(-a, -b)
+}
+
+// mulDown returns a lower bound for a·b, with 0·∞ = 0.
+func mulDown(a, b float64) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	p := a * b
+	if math.IsInf(p, 0) || math.IsNaN(p) {
+		if p > 0 && !math.IsInf(a, 0) && !math.IsInf(b, 0) {
+			return math.MaxFloat64
+		}
+		return negInf
+	}
+	if math.Abs(p) < tinyProduct {
+		return math.Nextafter(p, negInf)
+	}
+	if math.FMA(a, b, -p) < 0 {
+		return math.Nextafter(p, negInf)
+	}
+	return p
+}
+
+// mulUp returns an upper bound for a·b, with 0·∞ = 0.
+func mulUp(a, b float64) float64 {
+	return -mulDown(-a, b)
+}
+
+// PointInterval returns [x, x].
+func PointInterval(x float64) Interval {
+	return Interval{Lo: x, Hi: x}
+}
+
+// IntervalFromRat returns the tightest float64 interval containing r.
+func IntervalFromRat(r *big.Rat) Interval {
+	f, exact := r.Float64()
+	if exact {
+		return PointInterval(f)
+	}
+	return Interval{Lo: math.Nextafter(f, negInf), Hi: math.Nextafter(f, posInf)}
+}
+
+// IntervalAdd returns an enclosure of {x + y : x ∈ a, y ∈ b}.
+func IntervalAdd(a, b Interval) Interval {
+	return Interval{Lo: addDown(a.Lo, b.Lo), Hi: addUp(a.Hi, b.Hi)}
+}
+
+// IntervalNeg returns -a.
+func IntervalNeg(a Interval) Interval {
+	return Interval{Lo: -a.Hi, Hi: -a.Lo}
+}
+
+// IntervalSub returns an enclosure of {x - y : x ∈ a, y ∈ b}.
+func IntervalSub(a, b Interval) Interval {
+	return IntervalAdd(a, IntervalNeg(b))
+}
+
+// IntervalMul returns an enclosure of {x·y : x ∈ a, y ∈ b}.
+func IntervalMul(a, b Interval) Interval {
+	lo, hi := posInf, negInf
+	for _, x := range [2]float64{a.Lo, a.Hi} {
+		for _, y := range [2]float64{b.Lo, b.Hi} {
+			lo = math.Min(lo, mulDown(x, y))
+			hi = math.Max(hi, mulUp(x, y))
+		}
+	}
+	return Interval{Lo: lo, Hi: hi}
+}
+
+// Contains reports whether r lies in a.
+func (a Interval) Contains(r *big.Rat) bool {
+	return !ratBelow(r, a.Lo) && !ratAbove(r, a.Hi)
+}
+
+// Width returns an upper bound for Hi - Lo.
+func (a Interval) Width() float64 {
+	return addUp(a.Hi, -a.Lo)
+}
+
+// ratBelow reports whether r < x.
+func ratBelow(r *big.Rat, x float64) bool {
+	if math.IsInf(x, 0) {
+		return x > 0
+	}
+	return r.Cmp(new(big.Rat).SetFloat64(x)) < 0
+}
+
+// ratAbove reports whether r > x.
+func ratAbove(r *big.Rat, x float64) bool {
+	if math.IsInf(x, 0) {
+		return x < 0
+	}
+	return r.Cmp(new(big.Rat).SetFloat64(x)) > 0
+}
+
+// CIntervalFromQI returns the tightest enclosure of q.
+func CIntervalFromQI(q QI) CInterval {
+	return CInterval{Re: IntervalFromRat(q.Re), Im: IntervalFromRat(q.Im)}
+}
+
+// sqrt2Interval encloses √2; math.Sqrt is correctly rounded.
+func sqrt2Interval() Interval {
+	s := math.Sqrt(2)
+	return Interval{Lo: math.Nextafter(s, negInf), Hi: math.Nextafter(s, posInf)}
+}
+
+// CIntervalFromQZ8 returns an enclosure of a + b√2.
+func CIntervalFromQZ8(q QZ8) CInterval {
+	b := CIntervalFromQI(q.B)
+	r := sqrt2Interval()
+	return CIAdd(CIntervalFromQI(q.A), CInterval{Re: IntervalMul(b.Re, r), Im: IntervalMul(b.Im, r)})
+}
+
+// CIAdd returns an enclosure of a + b.
+func CIAdd(a, b CInterval) CInterval {
+	return CInterval{Re: IntervalAdd(a.Re, b.Re), Im: IntervalAdd(a.Im, b.Im)}
+}
+
+// CISub returns an enclosure of a - b.
+func CISub(a, b CInterval) CInterval {
+	return CInterval{Re: IntervalSub(a.Re, b.Re), Im: IntervalSub(a.Im, b.Im)}
+}
+
+// CIMul returns an enclosure of a·b.
+func CIMul(a, b CInterval) CInterval {
+	return CInterval{
+		Re: IntervalSub(IntervalMul(a.Re, b.Re), IntervalMul(a.Im, b.Im)),
+		Im: IntervalAdd(IntervalMul(a.Re, b.Im), IntervalMul(a.Im, b.Re)),
+	}
+}
+
+// CIConj returns the complex conjugate of a.
+func CIConj(a CInterval) CInterval {
+	return CInterval{Re: a.Re, Im: IntervalNeg(a.Im)}
+}
+
+// CIIsZero reports whether a is the point 0.
+func CIIsZero(a CInterval) bool {
+	return a.Re.Lo == 0 && a.Re.Hi == 0 && a.Im.Lo == 0 && a.Im.Hi == 0
+}
+
+// Contains reports whether q lies in a.
+func (a CInterval) Contains(q QI) bool {
+	return a.Re.Contains(q.Re) && a.Im.Contains(q.Im)
+}
+
+// NewIntervalMatrix creates a rows×cols matrix of point zeros.
+func NewIntervalMatrix(rows, cols int) *IntervalMatrix {
+	return &IntervalMatrix{Rows: rows, Cols: cols, Data: make([]CInterval, rows*cols)}
+}
+
+// Get returns the enclosure at (i, j).
+func (m *IntervalMatrix) Get(i, j int) CInterval {
+	return m.Data[i*m.Cols+j]
+}
+
+// Set sets the enclosure at (i, j).
+func (m *IntervalMatrix) Set(i, j int, v CInterval) {
+	m.Data[i*m.Cols+j] = v
+}
+
+// Clone returns a copy of m.
+func (m *IntervalMatrix) Clone() *IntervalMatrix {
+	c := NewIntervalMatrix(m.Rows, m.Cols)
+	copy(c.Data, m.Data)
+	return c
+}
+
+// IntervalMatrixFromMatrix encloses every entry of an exact matrix.
+func IntervalMatrixFromMatrix(m *Matrix) *IntervalMatrix {
+	r := NewIntervalMatrix(m.Rows, m.Cols)
+	for i, q := range m.Data {
+		r.Data[i] = CIntervalFromQI(q)
+	}
+	return r
+}
+
+// IntervalMatrixFromZ8 encloses every entry of a Q(ζ8) matrix.
+func IntervalMatrixFromZ8(m *MatrixZ8) *IntervalMatrix {
+	r := NewIntervalMatrix(m.Rows, m.Cols)
+	for i, q := range m.Data {
+		r.Data[i] = CIntervalFromQZ8(q)
+	}
+	return r
+}
+
+// intervalMatrixFromValue encloses matrix data in any of the exact
+// encodings: dense or sparse over Q(i), or matrix-qz8.
+func intervalMatrixFromValue(v Value) (*IntervalMatrix, bool) {
+	if m, ok := MatrixFromValue(v); ok {
+		return IntervalMatrixFromMatrix(m), true
+	}
+	if m, ok := MatrixZ8FromValue(v); ok {
+		return IntervalMatrixFromZ8(m), true
+	}
+	return nil, false
+}
+
+// Contains reports whether every entry of the exact matrix x lies in the
+// corresponding enclosure of m.
+func (m *IntervalMatrix) Contains(x *Matrix) bool {
+	if x == nil || x.Rows != m.Rows || x.Cols != m.Cols {
+		return false
+	}
+	for i, q := range x.Data {
+		if !m.Data[i].Contains(q) {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxWidth returns the widest real or imaginary part over all entries.
+func (m *IntervalMatrix) MaxWidth() float64 {
+	w := 0.0
+	for _, c := range m.Data {
+		w = math.Max(w, math.Max(c.Re.Width(), c.Im.Width()))
+	}
+	return w
+}
+
+// Midpoint returns the exact matrix of interval midpoints. It fails if an
+// enclosure is unbounded.
+func (m *IntervalMatrix) Midpoint() (*Matrix, error) {
+	r := NewMatrix(m.Rows, m.Cols)
+	half := big.NewRat(1, 2)
+	mid := func(a Interval) (*big.Rat, bool) {
+		if math.IsInf(a.Lo, 0) || math.IsInf(a.Hi, 0) || math.IsNaN(a.Lo) || math.IsNaN(a.Hi) {
+			return nil, false
+		}
+		s := new(big.Rat).Add(new(big.Rat).SetFloat64(a.Lo), new(big.Rat).SetFloat64(a.Hi))
+		return s.Mul(s, half), true
+	}
+	for i, c := range m.Data {
+		re, ok1 := mid(c.Re)
+		im, ok2 := mid(c.Im)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("interval: entry (%d, %d) is unbounded", i/m.Cols, i%m.Cols)
+		}
+		r.Data[i] = NewQI(re, im)
+	}
+	return r, nil
+}
+
+// IntervalMatMul returns an enclosure of A·B, or nil on a shape mismatch.
+func IntervalMatMul(A, B *IntervalMatrix) *IntervalMatrix {
+	if A.Cols != B.Rows {
+		return nil
+	}
+	C := NewIntervalMatrix(A.Rows, B.Cols)
+	for i := 0; i < A.Rows; i++ {
+		for k := 0; k < A.Cols; k++ {
+			a := A.Get(i, k)
+			if CIIsZero(a) {
+				continue
+			}
+			for j := 0; j < B.Cols; j++ {
+				b := B.Get(k, j)
+				if CIIsZero(b) {
+					continue
+				}
+				C.Set(i, j, CIAdd(C.Get(i, j), CIMul(a, b)))
+			}
+		}
+	}
+	return C
+}
+
+// IntervalMatAdd returns an enclosure of A + B, or nil on a shape mismatch.
+func IntervalMatAdd(A, B *IntervalMatrix) *IntervalMatrix {
+	if A.Rows != B.Rows || A.Cols != B.Cols {
+		return nil
+	}
+	C := NewIntervalMatrix(A.Rows, A.Cols)
+	for i := range A.Data {
+		C.Data[i] = CIAdd(A.Data[i], B.Data[i])
+	}
+	return C
+}
+
+// IntervalMatScale returns an enclosure of q·A.
+func IntervalMatScale(A *IntervalMatrix, q CInterval) *IntervalMatrix {
+	C := NewIntervalMatrix(A.Rows, A.Cols)
+	for i := range A.Data {
+		C.Data[i] = CIMul(A.Data[i], q)
+	}
+	return C
+}
+
+// IntervalDagger returns the conjugate transpose of A.
+func IntervalDagger(A *IntervalMatrix) *IntervalMatrix {
+	C := NewIntervalMatrix(A.Cols, A.Rows)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			C.Set(j, i, CIConj(A.Get(i, j)))
+		}
+	}
+	return C
+}
+
+// IntervalTrace returns an enclosure of Tr(A).
+func IntervalTrace(A *IntervalMatrix) CInterval {
+	var t CInterval
+	for i := 0; i < A.Rows && i < A.Cols; i++ {
+		t = CIAdd(t, A.Get(i, i))
+	}
+	return t
+}
+
+// intervalSubMatrix extracts the rows×cols block of A at (row, col).
+func intervalSubMatrix(A *IntervalMatrix, row, col, rows, cols int) *IntervalMatrix {
+	C := NewIntervalMatrix(rows, cols)
+	for i := 0; i < rows; i++ {
+		copy(C.Data[i*cols:(i+1)*cols], A.Data[(row+i)*A.Cols+col:(row+i)*A.Cols+col+cols])
+	}
+	return C
+}
+
+// intervalDirectSum returns the block-diagonal matrix of blocks.
+func intervalDirectSum(blocks ...*IntervalMatrix) *IntervalMatrix {
+	n := 0
+	for _, b := range blocks {
+		n += b.Rows
+	}
+	C := NewIntervalMatrix(n, n)
+	offset := 0
+	for _, b := range blocks {
+		for i := 0; i < b.Rows; i++ {
+			copy(C.Data[(offset+i)*n+offset:(offset+i)*n+offset+b.Cols], b.Data[i*b.Cols:(i+1)*b.Cols])
+		}
+		offset += b.Rows
+	}
+	return C
+}
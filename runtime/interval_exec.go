@@ -0,0 +1,538 @@
+package runtime
+
+import (
+	"fmt"
+)
+
+// Interval execution.
+//
+// ExecuteInterval runs the same circuit DAGs as Execute on matrices of
+// enclosures (see interval.go). Every result entry contains the entry
+// Execute would return for any exact input inside the enclosures, so an
+// exact input converted with IntervalMatrixFromMatrix yields a rigorous
+// error bound on the exact output.
+//
+// Compose, Tensor, Add, Scale, Id, Assert, Swap, Discard and Trace (full
+// and partial), Unitary, Kraus, Choi, Prepare, Witness, Instrument, Bisum
+// and Branch are evaluated directly in interval arithmetic. The remaining
+// primitives (Inject, Project, Copy, Delete, Encode, Decode, Zero) only
+// move entries around; each is applied through its exact Choi matrix,
+// probed once on its own domain and reused for the rest of the call.
+
+// ExecuteInterval executes c on an enclosure of the input state.
+func (e *Executor) ExecuteInterval(c Circuit, input *IntervalMatrix) (*IntervalMatrix, error) {
+	iv := &intervalExec{e: e, choi: make(map[[32]byte]*IntervalMatrix)}
+	return iv.run(c, input)
+}
+
+// intervalExec holds the state of one ExecuteInterval call.
+type intervalExec struct {
+	e    *Executor
+	choi map[[32]byte]*IntervalMatrix // enclosed Choi matrices of leaves by QGID
+}
+
+// run dispatches on c.Prim.
+func (iv *intervalExec) run(c Circuit, input *IntervalMatrix) (*IntervalMatrix, error) {
+	switch c.Prim {
+	case PrimId:
+		return input.Clone(), nil
+
+	case PrimCompose:
+		if len(c.Children) < 2 {
+			return nil, fmt.Errorf("compose requires at least 2 children")
+		}
+		current := input
+		for i, childID := range c.Children {
+			child, ok := iv.e.store.Get(childID)
+			if !ok {
+				return nil, fmt.Errorf("child %d not found", i)
+			}
+			next, err := iv.run(child, current)
+			if err != nil {
+				return nil, err
+			}
+			current = next
+		}
+		return current, nil
+
+	case PrimTensor:
+		return iv.tensor(c, input)
+
+	case PrimSwap:
+		return iv.swap(c, input)
+
+	case PrimDiscard, PrimTrace:
+		if !isNilData(c.Data) {
+			return iv.partialTrace(c, input)
+		}
+		result := NewIntervalMatrix(1, 1)
+		result.Set(0, 0, IntervalTrace(input))
+		return result, nil
+
+	case PrimUnitary:
+		U, ok := intervalMatrixFromValue(c.Data)
+		if !ok {
+			return nil, fmt.Errorf("unitary data must be matrix")
+		}
+		out := IntervalMatMul(IntervalMatMul(U, input), IntervalDagger(U))
+		if out == nil {
+			return nil, fmt.Errorf("unitary: %dx%d matrix on %dx%d input", U.Rows, U.Cols, input.Rows, input.Cols)
+		}
+		return out, nil
+
+	case PrimKraus:
+		ops, err := intervalKrausFromValue(c.Data)
+		if err != nil {
+			return nil, err
+		}
+		result, err := intervalKrausSum(ops, input, BlockDim(c.Codomain))
+		if err != nil {
+			return nil, fmt.Errorf("kraus: %w", err)
+		}
+		return result, nil
+
+	case PrimChoi:
+		J, ok := intervalMatrixFromValue(c.Data)
+		if !ok {
+			return nil, fmt.Errorf("choi data must be matrix")
+		}
+		return choiAction(J, BlockDim(c.Domain), BlockDim(c.Codomain), input), nil
+
+	case PrimPrepare, PrimWitness:
+		rho, ok := intervalMatrixFromValue(c.Data)
+		if !ok {
+			return nil, fmt.Errorf("prepare data must be matrix")
+		}
+		if input.Rows == 1 && input.Cols == 1 {
+			return IntervalMatScale(rho, input.Get(0, 0)), nil
+		}
+		return rho, nil
+
+	case PrimInstrument:
+		return iv.instrument(c, input)
+
+	case PrimBisum, PrimBranch:
+		return iv.blocks(c, input)
+
+	case PrimAdd:
+		if len(c.Children) != 2 {
+			return nil, fmt.Errorf("add requires 2 children")
+		}
+		var parts [2]*IntervalMatrix
+		for i, childID := range c.Children {
+			child, ok := iv.e.store.Get(childID)
+			if !ok {
+				return nil, fmt.Errorf("child %d not found", i)
+			}
+			out, err := iv.run(child, input)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = out
+		}
+		sum := IntervalMatAdd(parts[0], parts[1])
+		if sum == nil {
+			return nil, fmt.Errorf("add: children have different output shapes")
+		}
+		return sum, nil
+
+	case PrimScale:
+		if len(c.Children) != 1 {
+			return nil, fmt.Errorf("scale requires 1 child")
+		}
+		r, ok := c.Data.(Rat)
+		if !ok {
+			return nil, fmt.Errorf("scale data must be Rat")
+		}
+		child, ok := iv.e.store.Get(c.Children[0])
+		if !ok {
+			return nil, fmt.Errorf("child not found")
+		}
+		out, err := iv.run(child, input)
+		if err != nil {
+			return nil, err
+		}
+		return IntervalMatScale(out, CInterval{Re: IntervalFromRat(r.V)}), nil
+
+	case PrimAssert:
+		if !ObjectEqual(c.Domain, c.Codomain) {
+			return nil, fmt.Errorf("assert: domain %v does not match codomain %v",
+				c.Domain.Blocks, c.Codomain.Blocks)
+		}
+		return input.Clone(), nil
+
+	case PrimInject, PrimProject, PrimCopy, PrimDelete, PrimEncode, PrimDecode, PrimZero:
+		return iv.leaf(c, input)
+
+	default:
+		return nil, fmt.Errorf("unsupported primitive: %s (%d)", PrimName(c.Prim), int(c.Prim))
+	}
+}
+
+// tensor applies each child on its own factor, as applyTensor does.
+func (iv *intervalExec) tensor(c Circuit, input *IntervalMatrix) (*IntervalMatrix, error) {
+	if len(c.Children) < 2 {
+		return nil, fmt.Errorf("tensor requires at least 2 children")
+	}
+	children := make([]Circuit, len(c.Children))
+	dims := make([]int, len(c.Children))
+	total := 1
+	for i, childID := range c.Children {
+		child, ok := iv.e.store.Get(childID)
+		if !ok {
+			return nil, fmt.Errorf("child %d not found", i)
+		}
+		children[i] = child
+		dims[i] = BlockDim(child.Domain)
+		total *= dims[i]
+	}
+	if input.Rows != total || input.Cols != total {
+		return nil, fmt.Errorf("tensor: input is %dx%d, want %dx%d",
+			input.Rows, input.Cols, total, total)
+	}
+
+	current := input
+	for k, child := range children {
+		pre := 1
+		for _, d := range dims[:k] {
+			pre *= d
+		}
+		post := 1
+		for _, d := range dims[k+1:] {
+			post *= d
+		}
+		out, err := iv.factor(child, k, current, pre, dims[k], post)
+		if err != nil {
+			return nil, err
+		}
+		dims[k] = out.Rows / (pre * post)
+		current = out
+	}
+	return current, nil
+}
+
+// factor computes (id_pre ⊗ f ⊗ id_post)(ρ) slice by slice.
+func (iv *intervalExec) factor(f Circuit, k int, input *IntervalMatrix, pre, d, post int) (*IntervalMatrix, error) {
+	var result *IntervalMatrix
+	eF := 0
+	for a := 0; a < pre; a++ {
+		for b := 0; b < pre; b++ {
+			for p := 0; p < post; p++ {
+				for q := 0; q < post; q++ {
+					slice := NewIntervalMatrix(d, d)
+					for i := 0; i < d; i++ {
+						for j := 0; j < d; j++ {
+							slice.Set(i, j, input.Get((a*d+i)*post+p, (b*d+j)*post+q))
+						}
+					}
+					out, err := iv.run(f, slice)
+					if err != nil {
+						return nil, err
+					}
+					if result == nil {
+						eF = out.Rows
+						n := pre * eF * post
+						result = NewIntervalMatrix(n, n)
+					}
+					if out.Rows != eF || out.Cols != eF {
+						return nil, fmt.Errorf("tensor: child %d output is %dx%d, want %dx%d",
+							k, out.Rows, out.Cols, eF, eF)
+					}
+					for i := 0; i < eF; i++ {
+						for j := 0; j < eF; j++ {
+							result.Set((a*eF+i)*post+p, (b*eF+j)*post+q, out.Get(i, j))
+						}
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// swap permutes A⊗B to B⊗A.
+func (iv *intervalExec) swap(c Circuit, input *IntervalMatrix) (*IntervalMatrix, error) {
+	dimA, dimB, err := bipartiteDims(c.Domain, c.Codomain)
+	if err != nil {
+		return nil, err
+	}
+	if dimA*dimB != input.Rows {
+		return nil, fmt.Errorf("swap: %dx%d input, want %d", input.Rows, input.Cols, dimA*dimB)
+	}
+	perm := make([]int, dimA*dimB)
+	for i := 0; i < dimA; i++ {
+		for j := 0; j < dimB; j++ {
+			perm[i*dimB+j] = j*dimA + i
+		}
+	}
+	result := NewIntervalMatrix(input.Rows, input.Cols)
+	for r, pr := range perm {
+		for s, ps := range perm {
+			result.Set(pr, ps, input.Get(r, s))
+		}
+	}
+	return result, nil
+}
+
+// partialTrace traces out the domain factors named by c.Data.
+func (iv *intervalExec) partialTrace(c Circuit, input *IntervalMatrix) (*IntervalMatrix, error) {
+	factors, ok := FactorsFromValue(c.Data)
+	if !ok {
+		return nil, fmt.Errorf("partial trace: data must be Tag(\"factors\", Seq(Int ...)) with increasing indices")
+	}
+	if _, err := PartialTraceObject(c.Domain, factors); err != nil {
+		return nil, err
+	}
+	dims := make([]int, len(c.Domain.Factors))
+	for k, f := range c.Domain.Factors {
+		dims[k] = BlockDim(f)
+	}
+	traced, err := tracedSet(len(dims), factors)
+	if err != nil {
+		return nil, err
+	}
+	stride := make([]int, len(dims))
+	total := 1
+	for k := len(dims) - 1; k >= 0; k-- {
+		stride[k] = total
+		total *= dims[k]
+	}
+	if input.Rows != total || input.Cols != total {
+		return nil, fmt.Errorf("partial trace: input is %dx%d, want %dx%d", input.Rows, input.Cols, total, total)
+	}
+	var keptIdx, tracedIdx []int
+	for k := range dims {
+		if traced[k] {
+			tracedIdx = append(tracedIdx, k)
+		} else {
+			keptIdx = append(keptIdx, k)
+		}
+	}
+	kept := factorOffsets(keptIdx, dims, stride)
+	sum := factorOffsets(tracedIdx, dims, stride)
+
+	result := NewIntervalMatrix(len(kept), len(kept))
+	for i, ri := range kept {
+		for j, cj := range kept {
+			var acc CInterval
+			for _, t := range sum {
+				acc = CIAdd(acc, input.Get(ri+t, cj+t))
+			}
+			result.Set(i, j, acc)
+		}
+	}
+	return result, nil
+}
+
+// instrument applies either instrument payload; see instrument.go.
+func (iv *intervalExec) instrument(c Circuit, input *IntervalMatrix) (*IntervalMatrix, error) {
+	tag, ok := c.Data.(Tag)
+	if !ok {
+		return nil, fmt.Errorf("instrument: data must be a Tag")
+	}
+	label, ok := tag.Label.(Text)
+	if !ok {
+		return nil, fmt.Errorf("instrument: data label must be Text")
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok {
+		return nil, fmt.Errorf("instrument: payload must be a Seq")
+	}
+	if len(c.Codomain.Blocks) != len(seq.Items) {
+		return nil, fmt.Errorf("instrument: codomain has %d blocks, data has %d outcomes",
+			len(c.Codomain.Blocks), len(seq.Items))
+	}
+
+	blocks := make([]*IntervalMatrix, len(seq.Items))
+	switch label.V {
+	case "instrument":
+		for i, item := range seq.Items {
+			ops, err := intervalKrausFromValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("instrument: outcome %d: %w", i, err)
+			}
+			block, err := intervalKrausSum(ops, input, int(c.Codomain.Blocks[i]))
+			if err != nil {
+				return nil, fmt.Errorf("instrument: outcome %d: %w", i, err)
+			}
+			blocks[i] = block
+		}
+
+	case "povm":
+		for i, item := range seq.Items {
+			if c.Codomain.Blocks[i] != 1 {
+				return nil, fmt.Errorf("instrument: povm outcome %d has block size %d, want 1",
+					i, c.Codomain.Blocks[i])
+			}
+			E, ok := intervalMatrixFromValue(item)
+			if !ok {
+				return nil, fmt.Errorf("instrument: effect %d is not a valid matrix", i)
+			}
+			p := IntervalMatMul(E, input)
+			if p == nil || p.Rows != p.Cols {
+				return nil, fmt.Errorf("instrument: dimension mismatch for effect %d", i)
+			}
+			block := NewIntervalMatrix(1, 1)
+			block.Set(0, 0, IntervalTrace(p))
+			blocks[i] = block
+		}
+
+	default:
+		return nil, fmt.Errorf("instrument: unknown data label %q", label.V)
+	}
+	return intervalDirectSum(blocks...), nil
+}
+
+// blocks runs child i on the i-th diagonal block of the input and returns
+// the direct sum of the outputs (Bisum) or their sum (Branch).
+func (iv *intervalExec) blocks(c Circuit, input *IntervalMatrix) (*IntervalMatrix, error) {
+	name := "bisum"
+	if c.Prim == PrimBranch {
+		name = "branch"
+	}
+	if len(c.Children) == 0 {
+		return nil, fmt.Errorf("%s requires at least 1 child", name)
+	}
+	if dim := BlockDim(c.Domain); input.Rows != dim || input.Cols != dim {
+		return nil, fmt.Errorf("%s: input is %dx%d, domain dim is %d", name, input.Rows, input.Cols, dim)
+	}
+	if c.Prim == PrimBranch && len(c.Children) != len(c.Domain.Blocks) {
+		return nil, fmt.Errorf("branch: domain has %d blocks, got %d children",
+			len(c.Domain.Blocks), len(c.Children))
+	}
+
+	outputs := make([]*IntervalMatrix, len(c.Children))
+	offset := 0
+	for i, childID := range c.Children {
+		child, ok := iv.e.store.Get(childID)
+		if !ok {
+			return nil, fmt.Errorf("child %d not found", i)
+		}
+		if c.Prim == PrimBisum && len(child.Domain.Blocks) == 0 {
+			return nil, fmt.Errorf("%s: child %d domain has no blocks", name, i)
+		}
+		n := BlockDim(child.Domain)
+		if c.Prim == PrimBranch && n != int(c.Domain.Blocks[i]) {
+			return nil, fmt.Errorf("branch: child %d domain dim %d does not match block size %d",
+				i, n, c.Domain.Blocks[i])
+		}
+		if offset+n > input.Rows {
+			return nil, fmt.Errorf("%s: child %d overruns the %d-dimensional input", name, i, input.Rows)
+		}
+		out, err := iv.run(child, intervalSubMatrix(input, offset, offset, n, n))
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = out
+		offset += n
+	}
+	if offset != input.Rows {
+		return nil, fmt.Errorf("%s: children cover %d of %d dimensions", name, offset, input.Rows)
+	}
+	if c.Prim == PrimBisum {
+		return intervalDirectSum(outputs...), nil
+	}
+	result := outputs[0]
+	for i, out := range outputs[1:] {
+		if result = IntervalMatAdd(result, out); result == nil {
+			return nil, fmt.Errorf("branch: child %d output dimension mismatch", i+1)
+		}
+	}
+	return result, nil
+}
+
+// leaf applies a structural primitive through its exact Choi matrix.
+func (iv *intervalExec) leaf(c Circuit, input *IntervalMatrix) (*IntervalMatrix, error) {
+	id := QGID(CircuitToValue(c))
+	J, ok := iv.choi[id]
+	if !ok {
+		exact, err := iv.e.ProbeChoi(c)
+		if err != nil {
+			return nil, err
+		}
+		J = IntervalMatrixFromMatrix(exact)
+		iv.choi[id] = J
+	}
+	inDim := BlockDim(c.Domain)
+	if input.Rows != inDim || input.Cols != inDim {
+		return nil, fmt.Errorf("%s: input is %dx%d, domain dim is %d",
+			PrimName(c.Prim), input.Rows, input.Cols, inDim)
+	}
+	return choiAction(J, inDim, J.Rows/inDim, input), nil
+}
+
+// choiAction returns Φ(ρ)[a,b] = Σ_ij ρ[i,j]·J[(i,a),(j,b)], the action of
+// the channel with (unnormalized) Choi matrix J.
+func choiAction(J *IntervalMatrix, inDim, outDim int, input *IntervalMatrix) *IntervalMatrix {
+	result := NewIntervalMatrix(outDim, outDim)
+	for i := 0; i < inDim && i < input.Rows; i++ {
+		for j := 0; j < inDim && j < input.Cols; j++ {
+			r := input.Get(i, j)
+			if CIIsZero(r) {
+				continue
+			}
+			for a := 0; a < outDim; a++ {
+				for b := 0; b < outDim; b++ {
+					row, col := i*outDim+a, j*outDim+b
+					if row >= J.Rows || col >= J.Cols {
+						continue
+					}
+					if v := J.Get(row, col); !CIIsZero(v) {
+						result.Set(a, b, CIAdd(result.Get(a, b), CIMul(r, v)))
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// intervalKrausFromValue encloses a Tag("kraus", Seq(matrix, ...)) family
+// whose operators may be Q(i) or Q(ζ8) matrices.
+func intervalKrausFromValue(v Value) ([]*IntervalMatrix, error) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return nil, fmt.Errorf("kraus: data must be a Tag")
+	}
+	label, ok := tag.Label.(Text)
+	if !ok || label.V != "kraus" {
+		return nil, fmt.Errorf("kraus: data must be Tag(\"kraus\", ...)")
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok {
+		return nil, fmt.Errorf("kraus: payload must be a Seq of matrices")
+	}
+	ops := make([]*IntervalMatrix, len(seq.Items))
+	for i, item := range seq.Items {
+		K, ok := intervalMatrixFromValue(item)
+		if !ok {
+			return nil, fmt.Errorf("kraus: operator %d is not a valid matrix", i)
+		}
+		ops[i] = K
+	}
+	return ops, nil
+}
+
+// intervalKrausSum encloses Σ_k K_k ρ K_k†. An empty family is the zero
+// map onto an n×n output.
+func intervalKrausSum(ops []*IntervalMatrix, input *IntervalMatrix, n int) (*IntervalMatrix, error) {
+	if len(ops) == 0 {
+		return NewIntervalMatrix(n, n), nil
+	}
+	var result *IntervalMatrix
+	for i, K := range ops {
+		KX := IntervalMatMul(K, input)
+		if KX == nil {
+			return nil, fmt.Errorf("dimension mismatch for operator %d", i)
+		}
+		term := IntervalMatMul(KX, IntervalDagger(K))
+		if result == nil {
+			result = term
+			continue
+		}
+		if result = IntervalMatAdd(result, term); result == nil {
+			return nil, fmt.Errorf("dimension mismatch in sum at operator %d", i)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package runtime
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestIntervalArithmetic(t *testing.T) {
+	third := IntervalFromRat(big.NewRat(1, 3))
+	if !third.Contains(big.NewRat(1, 3)) || third.Lo == third.Hi {
+		t.Errorf("1/3 enclosed by %v", third)
+	}
+	if sum := IntervalAdd(IntervalAdd(third, third), third); !sum.Contains(big.NewRat(1, 1)) {
+		t.Errorf("1/3 + 1/3 + 1/3 enclosed by %v", sum)
+	}
+
+	// 0.1 + 0.2 rounds up to 0.30000000000000004 in float64.
+	tenth, fifth := IntervalFromRat(big.NewRat(1, 10)), IntervalFromRat(big.NewRat(1, 5))
+	if sum := IntervalAdd(tenth, fifth); !sum.Contains(big.NewRat(3, 10)) {
+		t.Errorf("1/10 + 1/5 enclosed by %v", sum)
+	}
+	if p := IntervalMul(third, IntervalFromRat(big.NewRat(-3, 1))); !p.Contains(big.NewRat(-1, 1)) {
+		t.Errorf("(1/3)·(-3) enclosed by %v", p)
+	}
+
+	// Dyadic arithmetic is exact and keeps point intervals.
+	if p := IntervalMul(PointInterval(0.75), PointInterval(-0.5)); p.Lo != -0.375 || p.Hi != -0.375 {
+		t.Errorf("0.75·(-0.5) = %v, want the point -0.375", p)
+	}
+
+	// Overflow widens instead of losing the value.
+	huge := PointInterval(math.MaxFloat64)
+	if s := IntervalAdd(huge, huge); s.Lo != math.MaxFloat64 || !math.IsInf(s.Hi, 1) {
+		t.Errorf("MaxFloat64 + MaxFloat64 = %v", s)
+	}
+
+	// (1 + 2i)(3 - i) = 5 + 5i.
+	a := CIntervalFromQI(NewQI(big.NewRat(1, 1), big.NewRat(2, 1)))
+	b := CIntervalFromQI(NewQI(big.NewRat(3, 1), big.NewRat(-1, 1)))
+	if !CIMul(a, b).Contains(NewQI(big.NewRat(5, 1), big.NewRat(5, 1))) {
+		t.Errorf("(1+2i)(3-i) enclosed by %v", CIMul(a, b))
+	}
+}
+
+func TestIntervalExecutionEnclosesExact(t *testing.T) {
+	r := rand.New(rand.NewSource(18))
+	store := NewStore()
+	exec := NewExecutor(store)
+	qutrit := QuantumObject(3)
+	ab := tensorObject(qubit(), qutrit)
+	ba := tensorObject(qutrit, qubit())
+	bits := ClassicalObject(2)
+
+	// Q(2)⊗Q(3) → Q(2): (Kraus ⊗ U) ; swap ; trace out the qubit ;
+	// measure {|0⟩⟨0|, |1⟩⟨1| + |2⟩⟨2|} ; prepare (I+Y)/2 or |1⟩ by outcome.
+	layer := store.Put(Circuit{Domain: ab, Codomain: ab, Prim: PrimTensor, Children: [][32]byte{
+		putKraus(store, randKraus(r, 2, 2, 2)),
+		putUnitary(store, randMatrix(r, 3, 3)),
+	}})
+	swap := store.Put(Circuit{Domain: ab, Codomain: ba, Prim: PrimSwap})
+	ptrace := store.Put(Circuit{Domain: ba, Codomain: qutrit, Prim: PrimDiscard, Data: FactorsToValue([]int{1})})
+	e0 := NewMatrix(3, 3)
+	e0.Set(0, 0, QIOne())
+	measure := store.Put(Circuit{Domain: qutrit, Codomain: bits, Prim: PrimInstrument,
+		Data: POVMToValue([]*Matrix{e0, MatSub(Identity(3), e0)})})
+	one := Object{Blocks: []uint32{1}}
+	branch := store.Put(Circuit{Domain: bits, Codomain: qubit(), Prim: PrimBranch, Children: [][32]byte{
+		store.Put(Circuit{Domain: one, Codomain: qubit(), Prim: PrimPrepare, Data: MatrixToValue(rhoYExact())}),
+		store.Put(Circuit{Domain: one, Codomain: qubit(), Prim: PrimPrepare, Data: MatrixToValue(ket1bra1())}),
+	}})
+	pipeline := store.Put(Circuit{Domain: ab, Codomain: qubit(), Prim: PrimCompose,
+		Children: [][32]byte{layer, swap, ptrace, measure, branch}})
+	scaled := store.Put(Circuit{Domain: ab, Codomain: qubit(), Prim: PrimScale, Children: [][32]byte{pipeline}, Data: MakeRat(1, 3)})
+
+	// Encode then Decode round-trips a classical state through Q(2).
+	classical := store.Put(Circuit{Domain: bits, Codomain: bits, Prim: PrimCompose, Children: [][32]byte{
+		store.Put(Circuit{Domain: bits, Codomain: qubit(), Prim: PrimEncode}),
+		store.Put(Circuit{Domain: qubit(), Codomain: bits, Prim: PrimDecode}),
+	}})
+
+	for _, tc := range []struct {
+		name  string
+		id    [32]byte
+		input *Matrix
+	}{
+		{"pipeline", scaled, randDensity(r, 6)},
+		{"classical", classical, MatAdd(ket0bra0(), MatScale(ket1bra1(), big.NewRat(2, 7)))},
+		{"empty kraus", store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimKraus, Data: KrausToValue(nil)}), ket1bra1()},
+	} {
+		c, _ := store.Get(tc.id)
+		want, err := exec.Execute(c, tc.input)
+		if err != nil {
+			t.Fatalf("%s: Execute: %v", tc.name, err)
+		}
+		got, err := exec.ExecuteInterval(c, IntervalMatrixFromMatrix(tc.input))
+		if err != nil {
+			t.Fatalf("%s: ExecuteInterval: %v", tc.name, err)
+		}
+		if !got.Contains(want) {
+			t.Errorf("%s: enclosure does not contain the exact output", tc.name)
+		}
+		if w := got.MaxWidth(); w > 1e-9 {
+			t.Errorf("%s: enclosure width %g", tc.name, w)
+		}
+	}
+}
+
+func TestIntervalCyclotomicGates(t *testing.T) {
+	// H = (√2/2)[[1, 1], [1, -1]] has no Q(i) form, so only the interval
+	// backend runs it directly. H T H on |0⟩⟨0| has ⟨0|ρ|0⟩ = (2+√2)/4.
+	half := NewQZ8(QIZero(), NewQI(big.NewRat(1, 2), new(big.Rat)))
+	H := NewMatrixZ8(2, 2)
+	H.Set(0, 0, half)
+	H.Set(0, 1, half)
+	H.Set(1, 0, half)
+	H.Set(1, 1, QZ8Neg(half))
+	T := IdentityZ8(2)
+	T.Set(1, 1, QZ8Zeta())
+
+	store := NewStore()
+	h := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixZ8ToValue(H)})
+	tg := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixZ8ToValue(T)})
+	c, _ := store.Get(store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{h, tg, h}}))
+
+	out, err := NewExecutor(store).ExecuteInterval(c, IntervalMatrixFromMatrix(ket0bra0()))
+	if err != nil {
+		t.Fatalf("ExecuteInterval: %v", err)
+	}
+	p := out.Get(0, 0).Re
+	want := (2 + math.Sqrt2) / 4
+	if p.Lo > want+1e-15 || p.Hi < want-1e-15 || p.Width() > 1e-14 {
+		t.Errorf("⟨0|ρ|0⟩ enclosed by %v, want ≈ %v", p, want)
+	}
+}
+
+func TestRunnerIntervalBackend(t *testing.T) {
+	// (H ⊗ I) then CNOT, with H as Scale(1/2, Unitary([[1,1],[1,-1]] ⊗ I)),
+	// on diag(1/3, 2/3) ⊗ |0⟩⟨0|. The thirds make the enclosure non-trivial.
+	store := NewStore()
+	two := tensorObject(qubit(), qubit())
+	h := putUnitary(store, Kronecker(hadamardUnnorm(), Identity(2)))
+	root := Circuit{Domain: two, Codomain: two, Prim: PrimCompose, Children: [][32]byte{
+		store.Put(Circuit{Domain: two, Codomain: two, Prim: PrimScale, Data: MakeRat(1, 2), Children: [][32]byte{h}}),
+		putUnitary(store, cnotUnitary()),
+	}}
+	data := Embed(store, store.Put(root), "interval", "1.0").Encode()
+	mixed := NewMatrix(2, 2)
+	mixed.Set(0, 0, NewQI(big.NewRat(1, 3), new(big.Rat)))
+	mixed.Set(1, 1, NewQI(big.NewRat(2, 3), new(big.Rat)))
+	rho := Kronecker(mixed, ket0bra0())
+
+	exact, err := NewRunner(data)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	want, err := exact.Run(rho)
+	if err != nil {
+		t.Fatalf("exact Run: %v", err)
+	}
+	if enc, err := exact.RunInterval(rho); err != nil || !enc.Contains(want) || enc.MaxWidth() > 1e-9 {
+		t.Errorf("exact RunInterval does not enclose Run: %v", err)
+	}
+
+	runner, err := NewRunner(data, WithBackend(BackendInterval))
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	enc, err := runner.RunInterval(rho)
+	if err != nil {
+		t.Fatalf("interval RunInterval: %v", err)
+	}
+	if !enc.Contains(want) {
+		t.Error("interval backend enclosure does not contain the exact output")
+	}
+	mid, err := runner.Run(rho)
+	if err != nil || !enc.Contains(mid) {
+		t.Errorf("interval Run midpoint outside its enclosure: %v", err)
+	}
+
+	if b, err := ParseBackend("interval"); err != nil || b != BackendInterval || b.String() != "interval" {
+		t.Errorf("ParseBackend(interval) = %v, %v", b, err)
+	}
+	if _, err := ParseBackend("float"); err == nil {
+		t.Error("ParseBackend accepted an unknown backend")
+	}
+}
@@ -0,0 +1,482 @@
+package runtime
+
+import (
+	"math/big"
+)
+
+// Common-denominator matrix kernels.
+//
+// Entry-by-entry QIMul/QIAdd on independent big.Rats pays for a GCD and
+// several allocations per operation, so a dense n×n product does O(n³)
+// normalizations. An IntMatrix stores the same exact matrix as Gaussian
+// integer numerators over one shared positive denominator,
+//
+//	A[i,j] = (Re[i,j] + i·Im[i,j]) / Den,
+//
+// so products and Kronecker products are integer multiply-adds with the
+// denominators multiplied once. Numerators are not reduced as they are
+// combined; ToMatrix normalizes each entry once on the way back, which
+// makes it O(n²) GCDs per kernel call instead of O(n³).
+//
+// MatMul and Kronecker use these kernels. Execute goes further: it
+// converts its input once, passes *IntMatrix between gates, and reduces
+// only the final output, so a circuit pays for O(n²) normalizations once
+// rather than at every gate. Compose and Tensor call Reduce between steps
+// to keep numerators from growing.
+
+// IntMatrix is an exact matrix over Q(i) stored as Gaussian-integer
+// numerators over a common positive denominator.
+type IntMatrix struct {
+	Rows int
+	Cols int
+	Re   []big.Int // row-major numerators of the real parts
+	Im   []big.Int // row-major numerators of the imaginary parts
+	Den  big.Int
+}
+
+// NewIntMatrix creates a zero matrix with denominator 1.
+func NewIntMatrix(rows, cols int) *IntMatrix {
+	m := &IntMatrix{Rows: rows, Cols: cols, Re: make([]big.Int, rows*cols), Im: make([]big.Int, rows*cols)}
+	m.Den.SetInt64(1)
+	return m
+}
+
+// ToIntMatrix converts A to common-denominator form. The denominator is
+// the least common multiple of the entries' denominators.
+func ToIntMatrix(A *Matrix) *IntMatrix {
+	return intMatrixFromEntries(A.Rows, A.Cols, nil, A.Data)
+}
+
+// intMatrixFromEntries builds an IntMatrix from the entries vals at flat
+// positions pos, or at positions 0, 1, ... when pos is nil.
+func intMatrixFromEntries(rows, cols int, pos []int, vals []QI) *IntMatrix {
+	m := NewIntMatrix(rows, cols)
+	var g big.Int
+	for _, q := range vals {
+		for _, r := range [2]*big.Rat{q.Re, q.Im} {
+			if r.IsInt() {
+				continue
+			}
+			d := r.Denom()
+			if g.GCD(nil, nil, &m.Den, d); g.Cmp(d) != 0 {
+				m.Den.Mul(&m.Den, g.Quo(d, &g))
+			}
+		}
+	}
+
+	// Entries mostly share a few denominators, so cache the last factor.
+	var lastDen, factor big.Int
+	scale := func(dst *big.Int, r *big.Rat) {
+		if r.Sign() == 0 {
+			return
+		}
+		d := r.Denom()
+		if lastDen.Cmp(d) != 0 {
+			lastDen.Set(d)
+			factor.Quo(&m.Den, d)
+		}
+		dst.Mul(r.Num(), &factor)
+	}
+	for k, q := range vals {
+		at := k
+		if pos != nil {
+			at = pos[k]
+		}
+		scale(&m.Re[at], q.Re)
+		scale(&m.Im[at], q.Im)
+	}
+	return m
+}
+
+// intMatrixFromValue decodes matrix data straight into common-denominator
+// form; the sparse encoding never materializes its zeros as big.Rats.
+func intMatrixFromValue(v Value) (*IntMatrix, bool) {
+	if tag, ok := v.(Tag); ok {
+		if label, ok := tag.Label.(Text); ok && label.V == sparseLabel {
+			s, ok := sparseFromPayload(tag.Payload)
+			if !ok {
+				return nil, false
+			}
+			pos := make([]int, 0, s.NNZ())
+			for i := 0; i < s.Rows; i++ {
+				for p := s.RowPtr[i]; p < s.RowPtr[i+1]; p++ {
+					pos = append(pos, i*s.Cols+s.ColIdx[p])
+				}
+			}
+			return intMatrixFromEntries(s.Rows, s.Cols, pos, s.Vals), true
+		}
+	}
+	m, ok := MatrixFromValue(v)
+	if !ok {
+		return nil, false
+	}
+	return ToIntMatrix(m), true
+}
+
+// ToMatrix converts m back to a Matrix, reducing every entry.
+func (m *IntMatrix) ToMatrix() *Matrix {
+	C := NewMatrix(m.Rows, m.Cols)
+	for k := range C.Data {
+		if m.Re[k].Sign() != 0 {
+			C.Data[k].Re.SetFrac(&m.Re[k], &m.Den)
+		}
+		if m.Im[k].Sign() != 0 {
+			C.Data[k].Im.SetFrac(&m.Im[k], &m.Den)
+		}
+	}
+	return C
+}
+
+// Clone returns a deep copy of m.
+func (m *IntMatrix) Clone() *IntMatrix {
+	C := NewIntMatrix(m.Rows, m.Cols)
+	C.Den.Set(&m.Den)
+	for k := range m.Re {
+		C.Re[k].Set(&m.Re[k])
+		C.Im[k].Set(&m.Im[k])
+	}
+	return C
+}
+
+// isZero reports whether entry k is zero.
+func (m *IntMatrix) isZero(k int) bool {
+	return m.Re[k].Sign() == 0 && m.Im[k].Sign() == 0
+}
+
+// IntMatMul computes A * B, or nil on a shape mismatch.
+func IntMatMul(A, B *IntMatrix) *IntMatrix {
+	if A.Cols != B.Rows {
+		return nil
+	}
+	C := NewIntMatrix(A.Rows, B.Cols)
+	C.Den.Mul(&A.Den, &B.Den)
+	var t big.Int
+	for i := 0; i < A.Rows; i++ {
+		for k := 0; k < A.Cols; k++ {
+			a := i*A.Cols + k
+			if A.isZero(a) {
+				continue
+			}
+			ar, ai := &A.Re[a], &A.Im[a]
+			isReal := ai.Sign() == 0
+			for j := 0; j < B.Cols; j++ {
+				b := k*B.Cols + j
+				if B.isZero(b) {
+					continue
+				}
+				mulAcc(&C.Re[i*C.Cols+j], &C.Im[i*C.Cols+j], ar, ai, &B.Re[b], &B.Im[b], isReal, &t)
+			}
+		}
+	}
+	return C
+}
+
+// mulAcc adds (ar + i·ai)(br + i·bi) to (cr + i·ci), using t as scratch.
+// isReal reports ai == 0, the common case for real gates.
+func mulAcc(cr, ci, ar, ai, br, bi *big.Int, isReal bool, t *big.Int) {
+	if isReal {
+		cr.Add(cr, t.Mul(ar, br))
+		if bi.Sign() != 0 {
+			ci.Add(ci, t.Mul(ar, bi))
+		}
+		return
+	}
+	cr.Add(cr, t.Mul(ar, br))
+	ci.Add(ci, t.Mul(ai, br))
+	if bi.Sign() != 0 {
+		cr.Sub(cr, t.Mul(ai, bi))
+		ci.Add(ci, t.Mul(ar, bi))
+	}
+}
+
+// IntKronecker computes A ⊗ B.
+func IntKronecker(A, B *IntMatrix) *IntMatrix {
+	C := NewIntMatrix(A.Rows*B.Rows, A.Cols*B.Cols)
+	C.Den.Mul(&A.Den, &B.Den)
+	var t big.Int
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			a := i*A.Cols + j
+			if A.isZero(a) {
+				continue
+			}
+			ar, ai := &A.Re[a], &A.Im[a]
+			isReal := ai.Sign() == 0
+			for k := 0; k < B.Rows; k++ {
+				for l := 0; l < B.Cols; l++ {
+					b := k*B.Cols + l
+					if B.isZero(b) {
+						continue
+					}
+					c := (i*B.Rows+k)*C.Cols + j*B.Cols + l
+					mulAcc(&C.Re[c], &C.Im[c], ar, ai, &B.Re[b], &B.Im[b], isReal, &t)
+				}
+			}
+		}
+	}
+	return C
+}
+
+// IntDagger computes the conjugate transpose of A.
+func IntDagger(A *IntMatrix) *IntMatrix {
+	C := NewIntMatrix(A.Cols, A.Rows)
+	C.Den.Set(&A.Den)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			C.Re[j*C.Cols+i].Set(&A.Re[i*A.Cols+j])
+			C.Im[j*C.Cols+i].Neg(&A.Im[i*A.Cols+j])
+		}
+	}
+	return C
+}
+
+// IntMatAdd computes A + B over the least common denominator, or nil on a
+// shape mismatch.
+func IntMatAdd(A, B *IntMatrix) *IntMatrix {
+	if A.Rows != B.Rows || A.Cols != B.Cols {
+		return nil
+	}
+	var g, fa, fb big.Int
+	g.GCD(nil, nil, &A.Den, &B.Den)
+	fa.Quo(&B.Den, &g)
+	fb.Quo(&A.Den, &g)
+	C := NewIntMatrix(A.Rows, A.Cols)
+	C.Den.Mul(&A.Den, &fa)
+	var t big.Int
+	for k := range C.Re {
+		C.Re[k].Add(t.Mul(&A.Re[k], &fa), C.Re[k].Mul(&B.Re[k], &fb))
+		C.Im[k].Add(t.Mul(&A.Im[k], &fa), C.Im[k].Mul(&B.Im[k], &fb))
+	}
+	return C
+}
+
+// Reduce divides the numerators and the denominator by their common GCD.
+// The kernels never need it for correctness; it keeps long chains of
+// products from growing.
+func (m *IntMatrix) Reduce() {
+	var g big.Int
+	g.Set(&m.Den)
+	one := big.NewInt(1)
+	for k := range m.Re {
+		for _, x := range [2]*big.Int{&m.Re[k], &m.Im[k]} {
+			if x.Sign() != 0 && g.Cmp(one) != 0 {
+				g.GCD(nil, nil, &g, new(big.Int).Abs(x))
+			}
+		}
+		if g.Cmp(one) == 0 {
+			return
+		}
+	}
+	m.Den.Quo(&m.Den, &g)
+	for k := range m.Re {
+		m.Re[k].Quo(&m.Re[k], &g)
+		m.Im[k].Quo(&m.Im[k], &g)
+	}
+}
+
+// IntMatMulDagger computes A * B† without forming B†, or nil on a shape
+// mismatch.
+func IntMatMulDagger(A, B *IntMatrix) *IntMatrix {
+	if A.Cols != B.Cols {
+		return nil
+	}
+	C := NewIntMatrix(A.Rows, B.Rows)
+	C.Den.Mul(&A.Den, &B.Den)
+	var t big.Int
+	for i := 0; i < A.Rows; i++ {
+		for k := 0; k < A.Cols; k++ {
+			a := i*A.Cols + k
+			if A.isZero(a) {
+				continue
+			}
+			ar, ai := &A.Re[a], &A.Im[a]
+			for j := 0; j < B.Rows; j++ {
+				b := j*B.Cols + k
+				if B.isZero(b) {
+					continue
+				}
+				// (ar + i·ai)(br - i·bi)
+				br, bi := &B.Re[b], &B.Im[b]
+				cr, ci := &C.Re[i*C.Cols+j], &C.Im[i*C.Cols+j]
+				cr.Add(cr, t.Mul(ar, br))
+				if ai.Sign() != 0 {
+					ci.Add(ci, t.Mul(ai, br))
+				}
+				if bi.Sign() != 0 {
+					ci.Sub(ci, t.Mul(ar, bi))
+					if ai.Sign() != 0 {
+						cr.Add(cr, t.Mul(ai, bi))
+					}
+				}
+			}
+		}
+	}
+	return C
+}
+
+// conjugateInt computes K ρ K† for ρ already in common-denominator form,
+// or nil on a shape mismatch.
+func conjugateInt(K, rho *IntMatrix) *IntMatrix {
+	KR := IntMatMul(K, rho)
+	if KR == nil {
+		return nil
+	}
+	return IntMatMulDagger(KR, K)
+}
+
+// conjugateSparseInt computes K ρ K† for a sparse K and ρ in
+// common-denominator form, or nil on a shape mismatch. Only the stored
+// entries of K are visited, in both products.
+func conjugateSparseInt(K *SparseMatrix, rho *IntMatrix) *IntMatrix {
+	if K.Cols != rho.Rows || rho.Rows != rho.Cols {
+		return nil
+	}
+	// The stored entries of K, as numerators over one denominator.
+	k := intMatrixFromEntries(1, K.NNZ(), nil, K.Vals)
+	n := rho.Cols
+	var t big.Int
+
+	// T = K ρ
+	T := NewIntMatrix(K.Rows, n)
+	T.Den.Mul(&k.Den, &rho.Den)
+	for i := 0; i < K.Rows; i++ {
+		for p := K.RowPtr[i]; p < K.RowPtr[i+1]; p++ {
+			ar, ai := &k.Re[p], &k.Im[p]
+			isReal := ai.Sign() == 0
+			row := K.ColIdx[p] * n
+			for l := 0; l < n; l++ {
+				if rho.isZero(row + l) {
+					continue
+				}
+				mulAcc(&T.Re[i*n+l], &T.Im[i*n+l], ar, ai, &rho.Re[row+l], &rho.Im[row+l], isReal, &t)
+			}
+		}
+	}
+
+	// C = T K†, entry (i, j) summing T[i, c]·conj(K[j, c]) over row j of K
+	C := NewIntMatrix(K.Rows, K.Rows)
+	C.Den.Mul(&T.Den, &k.Den)
+	var bi big.Int
+	for j := 0; j < K.Rows; j++ {
+		for q := K.RowPtr[j]; q < K.RowPtr[j+1]; q++ {
+			br, c := &k.Re[q], K.ColIdx[q]
+			bi.Neg(&k.Im[q])
+			for i := 0; i < K.Rows; i++ {
+				a := i*n + c
+				if T.isZero(a) {
+					continue
+				}
+				mulAcc(&C.Re[i*C.Cols+j], &C.Im[i*C.Cols+j], &T.Re[a], &T.Im[a], br, &bi, T.Im[a].Sign() == 0, &t)
+			}
+		}
+	}
+	return C
+}
+
+// IntMatScale computes r * A.
+func IntMatScale(A *IntMatrix, r *big.Rat) *IntMatrix {
+	C := NewIntMatrix(A.Rows, A.Cols)
+	C.Den.Mul(&A.Den, r.Denom())
+	num := r.Num()
+	for k := range A.Re {
+		C.Re[k].Mul(&A.Re[k], num)
+		C.Im[k].Mul(&A.Im[k], num)
+	}
+	return C
+}
+
+// IntTrace returns Tr(A) as a 1×1 matrix.
+func IntTrace(A *IntMatrix) *IntMatrix {
+	C := NewIntMatrix(1, 1)
+	C.Den.Set(&A.Den)
+	for i := 0; i < A.Rows && i < A.Cols; i++ {
+		C.Re[0].Add(&C.Re[0], &A.Re[i*A.Cols+i])
+		C.Im[0].Add(&C.Im[0], &A.Im[i*A.Cols+i])
+	}
+	return C
+}
+
+// intTraceMul returns Tr(A B) as a 1×1 matrix without forming A B, or nil
+// unless A B is square.
+func intTraceMul(A, B *IntMatrix) *IntMatrix {
+	if A.Cols != B.Rows || A.Rows != B.Cols {
+		return nil
+	}
+	C := NewIntMatrix(1, 1)
+	C.Den.Mul(&A.Den, &B.Den)
+	var t big.Int
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			a, b := i*A.Cols+j, j*B.Cols+i
+			if A.isZero(a) || B.isZero(b) {
+				continue
+			}
+			mulAcc(&C.Re[0], &C.Im[0], &A.Re[a], &A.Im[a], &B.Re[b], &B.Im[b], A.Im[a].Sign() == 0, &t)
+		}
+	}
+	return C
+}
+
+// IntSubMatrix extracts the rows×cols block of A whose top-left corner is
+// at (row, col), or nil if the block does not fit.
+func IntSubMatrix(A *IntMatrix, row, col, rows, cols int) *IntMatrix {
+	if row < 0 || col < 0 || row+rows > A.Rows || col+cols > A.Cols {
+		return nil
+	}
+	B := NewIntMatrix(rows, cols)
+	B.Den.Set(&A.Den)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			B.Re[i*cols+j].Set(&A.Re[(row+i)*A.Cols+col+j])
+			B.Im[i*cols+j].Set(&A.Im[(row+i)*A.Cols+col+j])
+		}
+	}
+	return B
+}
+
+// IntDirectSum computes A_1 ⊕ ... ⊕ A_n over the least common denominator.
+func IntDirectSum(blocks ...*IntMatrix) *IntMatrix {
+	rows, cols := 0, 0
+	for _, b := range blocks {
+		rows += b.Rows
+		cols += b.Cols
+	}
+	C := NewIntMatrix(rows, cols)
+	lcmDen(&C.Den, blocks)
+	r, c := 0, 0
+	for _, b := range blocks {
+		var f big.Int
+		f.Quo(&C.Den, &b.Den)
+		for i := 0; i < b.Rows; i++ {
+			for j := 0; j < b.Cols; j++ {
+				C.setScaled((r+i)*C.Cols+c+j, b, i*b.Cols+j, &f)
+			}
+		}
+		r += b.Rows
+		c += b.Cols
+	}
+	return C
+}
+
+// lcmDen sets den to the least common multiple of the denominators of ms.
+func lcmDen(den *big.Int, ms []*IntMatrix) {
+	den.SetInt64(1)
+	var g big.Int
+	for _, m := range ms {
+		if g.GCD(nil, nil, den, &m.Den); g.Cmp(&m.Den) != 0 {
+			den.Mul(den, g.Quo(&m.Den, &g))
+		}
+	}
+}
+
+// setScaled sets entry k of m to entry l of src times f, where f is
+// m.Den / src.Den.
+func (m *IntMatrix) setScaled(k int, src *IntMatrix, l int, f *big.Int) {
+	if f.IsInt64() && f.Int64() == 1 {
+		m.Re[k].Set(&src.Re[l])
+		m.Im[k].Set(&src.Im[l])
+		return
+	}
+	m.Re[k].Mul(&src.Re[l], f)
+	m.Im[k].Mul(&src.Im[l], f)
+}
@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestIntMatrixKernels(t *testing.T) {
+	r := rand.New(rand.NewSource(19))
+	A := randMatrix(r, 4, 3)
+	B := randMatrix(r, 3, 5)
+	C := randMatrix(r, 4, 3)
+	S := randSparse(r, 3, 5, 4)
+
+	if got := ToIntMatrix(A).ToMatrix(); !MatrixEqual(got, A) {
+		t.Error("ToIntMatrix does not round-trip")
+	}
+	if got := IntMatMul(ToIntMatrix(A), ToIntMatrix(B)).ToMatrix(); !MatrixEqual(got, refMatMul(A, B)) {
+		t.Error("IntMatMul differs from the entrywise product")
+	}
+	if got := IntMatMulDagger(ToIntMatrix(A), ToIntMatrix(C)).ToMatrix(); !MatrixEqual(got, refMatMul(A, Dagger(C))) {
+		t.Error("IntMatMulDagger differs from A·C†")
+	}
+	if got := IntDagger(ToIntMatrix(B)).ToMatrix(); !MatrixEqual(got, Dagger(B)) {
+		t.Error("IntDagger differs from Dagger")
+	}
+	if got := IntMatAdd(ToIntMatrix(A), ToIntMatrix(C)).ToMatrix(); !MatrixEqual(got, MatAdd(A, C)) {
+		t.Error("IntMatAdd differs from MatAdd")
+	}
+	if got := IntKronecker(ToIntMatrix(A), ToIntMatrix(S)).ToMatrix(); !MatrixEqual(got, SparseKronecker(ToSparse(A), ToSparse(S)).ToDense()) {
+		t.Error("IntKronecker differs from the sparse Kronecker product")
+	}
+	if IntMatMul(ToIntMatrix(A), ToIntMatrix(C)) != nil || IntMatAdd(ToIntMatrix(A), ToIntMatrix(B)) != nil {
+		t.Error("shape mismatch accepted")
+	}
+
+	// The sparse encoding decodes without materializing its zeros.
+	m, ok := intMatrixFromValue(SparseMatrixToValue(ToSparse(S)))
+	if !ok || !MatrixEqual(m.ToMatrix(), S) {
+		t.Error("intMatrixFromValue does not decode the sparse encoding")
+	}
+
+	// Products keep unreduced numerators; Reduce restores the lowest terms.
+	half := NewMatrix(2, 2)
+	half.Set(0, 0, NewQI(big.NewRat(1, 2), new(big.Rat)))
+	half.Set(1, 1, NewQI(new(big.Rat), big.NewRat(-1, 2)))
+	sq := IntMatMul(ToIntMatrix(half), ToIntMatrix(Identity(2)))
+	sq = IntMatAdd(sq, sq)
+	sq.Reduce()
+	if sq.Den.Cmp(big.NewInt(1)) != 0 || !MatrixEqual(sq.ToMatrix(), MatScale(half, big.NewRat(2, 1))) {
+		t.Errorf("Reduce left denominator %v", &sq.Den)
+	}
+}
+
+func TestIntMatrixDataflow(t *testing.T) {
+	r := rand.New(rand.NewSource(20))
+	A := randMatrix(r, 3, 3)
+	B := randMatrix(r, 2, 2)
+	third := big.NewRat(-1, 3)
+
+	if got := IntDirectSum(ToIntMatrix(A), ToIntMatrix(B)).ToMatrix(); !MatrixEqual(got, DirectSum(A, B)) {
+		t.Error("IntDirectSum differs from DirectSum")
+	}
+	if got := IntSubMatrix(ToIntMatrix(A), 1, 0, 2, 3).ToMatrix(); !MatrixEqual(got, SubMatrix(A, 1, 0, 2, 3)) {
+		t.Error("IntSubMatrix differs from SubMatrix")
+	}
+	if got := IntMatScale(ToIntMatrix(A), third).ToMatrix(); !MatrixEqual(got, MatScale(A, third)) {
+		t.Error("IntMatScale differs from MatScale")
+	}
+	if got := IntTrace(ToIntMatrix(A)).ToMatrix(); !QIEqual(got.Get(0, 0), Trace(A)) {
+		t.Error("IntTrace differs from Trace")
+	}
+	C := randMatrix(r, 3, 3)
+	if got := intTraceMul(ToIntMatrix(A), ToIntMatrix(C)).ToMatrix(); !QIEqual(got.Get(0, 0), Trace(MatMul(A, C))) {
+		t.Error("intTraceMul differs from Tr(A·C)")
+	}
+	got, err := IntPartialTrace(ToIntMatrix(Kronecker(A, B)), []int{3, 2}, []int{1})
+	if err != nil || !MatrixEqual(got.ToMatrix(), matScaleQI(A, Trace(B))) {
+		t.Errorf("Tr_B(A ⊗ B) should be Tr(B)·A: %v", err)
+	}
+
+	// Execute converts in and out once; the result is reduced.
+	store := NewStore()
+	c := Circuit{Domain: QuantumObject(3), Codomain: QuantumObject(3), Prim: PrimScale, Data: MakeRat(1, 3),
+		Children: [][32]byte{putUnitary(store, Identity(3))}}
+	out, err := NewExecutor(store).Execute(c, MatScale(Identity(3), big.NewRat(3, 1)))
+	if err != nil || !MatrixEqual(out, Identity(3)) {
+		t.Errorf("Scale(1/3) on 3·I: %v", err)
+	}
+}
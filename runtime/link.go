@@ -0,0 +1,211 @@
+package runtime
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Cross-binary linking.
+//
+// A circuit names its children by QGID, and a QGID does not depend on which
+// binary holds the entry. A binary can therefore use a circuit from a
+// library without containing it: the parent lists the child's QGID as
+// usual, and the store holds an external reference
+//
+//	Tag("extern", Seq(Bytes qgid, Bytes binary))
+//
+// saying that the entry qgid is found in the .qmb file whose SHA-256 is
+// binary. Store.Reachable follows a missing QGID to its reference, so gc
+// keeps references in use, and the type checker reports an unlinked child
+// as such.
+//
+// Link resolves every unresolved reference: it asks a Resolver for the
+// bytes of the named binary, checks their hash, and copies the entry and
+// everything reachable from it into the store. References inside the
+// copied entries are resolved the same way, and resolved references are
+// dropped. Nothing is rewritten, so every QGID, the entrypoint's
+// included, is the same before and after linking.
+
+// ExternRef is an external reference: the entry ID lives in the binary
+// whose file bytes hash to Binary.
+type ExternRef struct {
+	ID     [32]byte
+	Binary [32]byte
+}
+
+// ExternToValue converts an external reference to its store value.
+func ExternToValue(ref ExternRef) Value {
+	return MakeTag(MakeText("extern"), MakeSeq(MakeBytes(ref.ID[:]), MakeBytes(ref.Binary[:])))
+}
+
+// ExternFromValue parses a store value as an external reference.
+func ExternFromValue(v Value) (ExternRef, bool) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return ExternRef{}, false
+	}
+	label, ok := tag.Label.(Text)
+	if !ok || label.V != "extern" {
+		return ExternRef{}, false
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok || len(seq.Items) != 2 {
+		return ExternRef{}, false
+	}
+	id, ok1 := seq.Items[0].(Bytes)
+	bin, ok2 := seq.Items[1].(Bytes)
+	if !ok1 || !ok2 || len(id.V) != 32 || len(bin.V) != 32 {
+		return ExternRef{}, false
+	}
+	return ExternRef{ID: [32]byte(id.V), Binary: [32]byte(bin.V)}, true
+}
+
+// externPrefix is the encoding of an external reference up to its ID, and
+// externLen the length of the whole encoding. They let loadReachable find
+// references in a v2 index without decoding other entries.
+var (
+	externPrefix = ExternToValue(ExternRef{}).Encode()[:13]
+	externLen    = len(ExternToValue(ExternRef{}).Encode())
+)
+
+// PutExtern stores an external reference and returns its QGID.
+func (s *Store) PutExtern(ref ExternRef) [32]byte {
+	return s.PutValue(ExternToValue(ref))
+}
+
+// Unresolved returns the external references in the store whose entries
+// are missing, in ID order.
+func (s *Store) Unresolved() []ExternRef {
+	var refs []ExternRef
+	for _, v := range s.values {
+		if ref, ok := ExternFromValue(v); ok {
+			if _, ok := s.values[ref.ID]; !ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return bytes.Compare(refs[i].ID[:], refs[j].ID[:]) < 0 })
+	return refs
+}
+
+// externs maps the ID of each external reference in the store to the
+// reference's QGID.
+func (s *Store) externs() map[[32]byte][32]byte {
+	m := make(map[[32]byte][32]byte)
+	for id, v := range s.values {
+		if ref, ok := ExternFromValue(v); ok {
+			m[ref.ID] = id
+		}
+	}
+	return m
+}
+
+// Resolver returns the bytes of the binary whose SHA-256 is hash.
+type Resolver func(hash [32]byte) ([]byte, error)
+
+// LibraryResolver resolves to the given binaries.
+func LibraryResolver(libs ...[]byte) Resolver {
+	byHash := make(map[[32]byte][]byte, len(libs))
+	for _, data := range libs {
+		byHash[sha256.Sum256(data)] = data
+	}
+	return func(hash [32]byte) ([]byte, error) {
+		if data, ok := byHash[hash]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("link: no library binary %x", hash[:8])
+	}
+}
+
+// DirResolver resolves to the .qmb files in dir, which are read on the
+// first call.
+func DirResolver(dir string) Resolver {
+	var resolve Resolver
+	return func(hash [32]byte) ([]byte, error) {
+		if resolve == nil {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return nil, fmt.Errorf("link: %w", err)
+			}
+			var libs [][]byte
+			for _, ent := range entries {
+				if ent.IsDir() || !strings.HasSuffix(ent.Name(), ".qmb") {
+					continue
+				}
+				data, err := os.ReadFile(filepath.Join(dir, ent.Name()))
+				if err != nil {
+					return nil, fmt.Errorf("link: %w", err)
+				}
+				libs = append(libs, data)
+			}
+			resolve = LibraryResolver(libs...)
+		}
+		data, err := resolve(hash)
+		if err != nil {
+			return nil, fmt.Errorf("%w in %s", err, dir)
+		}
+		return data, nil
+	}
+}
+
+// Link resolves the external references in the store with resolve, as
+// described above, and returns the number of entries it imported.
+func Link(store *Store, resolve Resolver) (int, error) {
+	libs := make(map[[32]byte]*Store)
+	imported := 0
+	for refs := store.Unresolved(); len(refs) > 0; refs = store.Unresolved() {
+		for _, ref := range refs {
+			lib, ok := libs[ref.Binary]
+			if !ok {
+				var err error
+				if lib, err = loadLibrary(ref.Binary, resolve); err != nil {
+					return imported, err
+				}
+				libs[ref.Binary] = lib
+			}
+			if _, ok := lib.values[ref.ID]; !ok {
+				return imported, fmt.Errorf("link: binary %x has no entry %x", ref.Binary[:8], ref.ID[:8])
+			}
+			for id := range lib.Reachable(ref.ID) {
+				if _, ok := store.values[id]; !ok {
+					store.values[id] = lib.values[id]
+					if c, ok := lib.circuits[id]; ok {
+						store.circuits[id] = c
+					}
+					imported++
+				}
+			}
+		}
+	}
+	for target, id := range store.externs() {
+		if _, ok := store.values[target]; ok {
+			delete(store.values, id)
+		}
+	}
+	return imported, nil
+}
+
+// loadLibrary resolves and decodes the binary hash.
+func loadLibrary(hash [32]byte, resolve Resolver) (*Store, error) {
+	data, err := resolve(hash)
+	if err != nil {
+		return nil, err
+	}
+	if sha256.Sum256(data) != hash {
+		return nil, fmt.Errorf("link: binary %x resolved to different bytes", hash[:8])
+	}
+	binary, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("link: binary %x: %w", hash[:8], err)
+	}
+	lib, err := binary.LoadStore()
+	if err != nil {
+		return nil, fmt.Errorf("link: binary %x: %w", hash[:8], err)
+	}
+	return lib, nil
+}
@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// linkFixture builds a library a holding S ⊗ S with S = diag(1, i), a
+// library b composing that tensor with itself through a reference to a,
+// and a model composing b's circuit with a's through references to both,
+// so the model applies S³ ⊗ S³. It returns the three binaries and the
+// model's circuits in one store.
+func linkFixture(t *testing.T) (a, b, model []byte, whole *Store, top [32]byte) {
+	whole = NewStore()
+	libA := NewStore()
+	phase := Identity(2)
+	phase.Set(1, 1, QII())
+	sg := putUnitary(libA, phase)
+	two := tensorObject(qubit(), qubit())
+	root := Circuit{Domain: two, Codomain: two, Prim: PrimTensor, Children: [][32]byte{sg, sg}}
+	rootID := libA.Put(root)
+	a = EmbedV2(libA, rootID, "a", "1").Encode()
+
+	compose := func(x, y [32]byte) Circuit {
+		return Circuit{Domain: root.Domain, Codomain: root.Codomain, Prim: PrimCompose, Children: [][32]byte{x, y}}
+	}
+	libB := NewStore()
+	mid := libB.Put(compose(rootID, rootID))
+	libB.PutExtern(ExternRef{ID: rootID, Binary: sha256.Sum256(a)})
+	b = Embed(libB, mid, "b", "1").Encode()
+
+	m := NewStore()
+	top = m.Put(compose(mid, rootID))
+	m.PutExtern(ExternRef{ID: mid, Binary: sha256.Sum256(b)})
+	m.PutExtern(ExternRef{ID: rootID, Binary: sha256.Sum256(a)})
+	model = EmbedV2(m, top, "model", "1").Encode()
+
+	for _, s := range []*Store{libA, libB, m} {
+		for id, c := range s.circuits {
+			whole.circuits[id] = c
+			whole.values[id] = s.values[id]
+		}
+	}
+	if got := len(m.Reachable(top)); got != 3 {
+		t.Fatalf("model reaches %d entries, want the circuit and both references", got)
+	}
+	return a, b, model, whole, top
+}
+
+func TestLinkRunsAcrossBinaries(t *testing.T) {
+	a, b, model, whole, top := linkFixture(t)
+	c, _ := whole.Get(top)
+	rho := Kronecker(rhoPlusExact(), rhoPlusExact())
+	want, err := NewExecutor(whole).Execute(c, rho)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	unlinked, err := NewRunner(model)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	diags := unlinked.TypeCheck()
+	if len(diags) == 0 || !strings.Contains(diags[0].Message, "not linked") {
+		t.Errorf("unlinked model: diagnostics %v", diags)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.qmb"), a, 0644)
+	os.WriteFile(filepath.Join(dir, "b.qmb"), b, 0644)
+	for name, resolve := range map[string]Resolver{
+		"library": LibraryResolver(a, b),
+		"dir":     DirResolver(dir),
+	} {
+		runner, err := NewRunner(model, WithResolver(resolve))
+		if err != nil {
+			t.Fatalf("%s: NewRunner: %v", name, err)
+		}
+		// top, mid, the tensor and its unitary; the references are dropped.
+		if runner.StoreSize() != 4 {
+			t.Errorf("%s: linked store has %d entries, want 4", name, runner.StoreSize())
+		}
+		got, err := runner.Run(rho)
+		if err != nil || !MatrixEqual(got, want) {
+			t.Errorf("%s: linked Run differs: %v", name, err)
+		}
+	}
+
+	// Linking changes no QGID: the linked store embeds to the same binary as
+	// the model built in one store.
+	binary, _ := Decode(model)
+	store, _ := binary.LoadStore()
+	if n, err := Link(store, LibraryResolver(a, b)); err != nil || n != 3 {
+		t.Fatalf("Link imported %d entries: %v", n, err)
+	}
+	whole.Prune(top)
+	if string(EmbedV2(store, top, "model", "1").Encode()) != string(EmbedV2(whole, top, "model", "1").Encode()) {
+		t.Error("linked binary differs from the one built in a single store")
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	a, b, model, _, _ := linkFixture(t)
+	for name, tc := range map[string]struct {
+		resolve Resolver
+		want    string
+	}{
+		"missing": {LibraryResolver(a), "no library binary"},
+		"wrong bytes": {func(hash [32]byte) ([]byte, error) {
+			return append([]byte(nil), b...), nil
+		}, "resolved to different bytes"},
+	} {
+		if _, err := NewRunner(model, WithResolver(tc.resolve)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package runtime
+
+// Objects and their encoding.
+//
+// A finite-dimensional C*-algebra is a direct sum of full matrix algebras
+// ⊕_i M_{n_i}, so an Object is determined up to isomorphism by its block
+// sizes. Direct sums concatenate block lists and tensor products distribute
+// over sums:
+//
+//	(⊕_i M_{a_i}) ⊗ (⊕_j M_{b_j}) = ⊕_{i,j} M_{a_i·b_j}
+//
+// with blocks in row-major (i, j) order. Q(2)⊗Q(2) is therefore [4] and
+// Q(2)⊕Q(2) is [2, 2]. Objects built with TensorObjects also keep their
+// factors, so Swap and Tensor can find the split between subsystems. A
+// density matrix on such an object is indexed in Kronecker order of the
+// factors' Hilbert spaces.
+//
+// Two encodings are understood:
+//
+//	v1: Tag("object", Seq(Int n_1, ..., Int n_k))
+//	v2: Tag("object-v2", Seq(Seq(Int n_1, ..., Int n_k), Seq(factor, ...)))
+//
+// v2 is written for objects with factors, so almost every factor-free
+// object keeps its v1 encoding and its QGID. A v1 object decodes as the
+// direct sum of its blocks, with one exception handled by migrateObjectV1:
+// older stores wrote a tensor product A⊗B as the concatenated block list
+// [a, b], so a v1 list of exactly two nontrivial blocks decodes as
+// Q(a)⊗Q(b). This migration is the only place that reading survives; the
+// rest of the runtime tells a product from a sum by its Factors alone. A
+// direct sum with that shape, such as Q(2)⊕Q(2), is written as v2 with an
+// empty factor list so that it is not migrated.
+
+const (
+	objectLabelV1 = "object"
+	objectLabelV2 = "object-v2"
+)
+
+// QuantumObject returns Q(n), the algebra of n×n matrices.
+func QuantumObject(n int) Object {
+	return Object{Blocks: []uint32{uint32(n)}}
+}
+
+// ClassicalObject returns C(k), the algebra of k classical outcomes.
+func ClassicalObject(k int) Object {
+	blocks := make([]uint32, k)
+	for i := range blocks {
+		blocks[i] = 1
+	}
+	return Object{Blocks: blocks}
+}
+
+// TensorObjects returns the tensor product of objs. Nested products are
+// flattened and trivial factors, the unit I and Q(1), dropped, so the
+// result is associative, Q(1)⊗A equals A, and it has factors only when at
+// least two nontrivial factors remain. A product of trivial factors is Q(1)
+// if one of them was, otherwise I.
+func TensorObjects(objs ...Object) Object {
+	var factors []Object
+	unit := Object{Blocks: []uint32{}}
+	for _, o := range objs {
+		flat := o.Factors
+		if len(flat) == 0 {
+			flat = []Object{o}
+		}
+		for _, f := range flat {
+			switch {
+			case len(f.Blocks) == 0:
+			case len(f.Blocks) == 1 && f.Blocks[0] == 1:
+				unit = f
+			default:
+				factors = append(factors, f)
+			}
+		}
+	}
+
+	switch len(factors) {
+	case 0:
+		return unit
+	case 1:
+		return factors[0]
+	}
+	return Object{Blocks: tensorBlocks(factors), Factors: factors}
+}
+
+// tensorBlocks returns the block list of the product of factors, in
+// row-major order.
+func tensorBlocks(factors []Object) []uint32 {
+	blocks := []uint32{1}
+	for _, f := range factors {
+		next := make([]uint32, 0, len(blocks)*len(f.Blocks))
+		for _, a := range blocks {
+			for _, b := range f.Blocks {
+				next = append(next, a*b)
+			}
+		}
+		blocks = next
+	}
+	return blocks
+}
+
+// SumObjects returns the direct sum of objs. The result has no factors.
+func SumObjects(objs ...Object) Object {
+	blocks := []uint32{}
+	for _, o := range objs {
+		blocks = append(blocks, o.Blocks...)
+	}
+	return Object{Blocks: blocks}
+}
+
+// ObjectToValue converts an object to a Value. Objects without factors use
+// the v1 encoding unless migrateObjectV1 would read it as a product.
+func ObjectToValue(obj Object) Value {
+	blocks := make([]Value, len(obj.Blocks))
+	for i, n := range obj.Blocks {
+		blocks[i] = MakeInt(int64(n))
+	}
+	if len(obj.Factors) == 0 && !legacyTensorBlocks(obj.Blocks) {
+		return MakeTag(
+			MakeText(objectLabelV1),
+			MakeSeq(blocks...),
+		)
+	}
+
+	factors := make([]Value, len(obj.Factors))
+	for i, f := range obj.Factors {
+		factors[i] = ObjectToValue(f)
+	}
+	return MakeTag(
+		MakeText(objectLabelV2),
+		MakeSeq(MakeSeq(blocks...), MakeSeq(factors...)),
+	)
+}
+
+// ObjectFromValue parses an object from a v1 or v2 Value. v1 objects go
+// through migrateObjectV1. A v2 object is rejected unless its blocks are
+// the normal form of its factors, or, with no factors, unless v1 could not
+// have encoded it.
+func ObjectFromValue(v Value) (Object, bool) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return Object{}, false
+	}
+	label, ok := tag.Label.(Text)
+	if !ok {
+		return Object{}, false
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok {
+		return Object{}, false
+	}
+
+	switch label.V {
+	case objectLabelV1:
+		blocks, ok := blocksFromValue(seq)
+		if !ok {
+			return Object{}, false
+		}
+		return migrateObjectV1(blocks), true
+
+	case objectLabelV2:
+		if len(seq.Items) != 2 {
+			return Object{}, false
+		}
+		blockSeq, ok := seq.Items[0].(Seq)
+		if !ok {
+			return Object{}, false
+		}
+		blocks, ok := blocksFromValue(blockSeq)
+		if !ok {
+			return Object{}, false
+		}
+		factorSeq, ok := seq.Items[1].(Seq)
+		if !ok || len(factorSeq.Items) == 1 {
+			return Object{}, false
+		}
+		if len(factorSeq.Items) == 0 {
+			if !legacyTensorBlocks(blocks) {
+				return Object{}, false
+			}
+			return Object{Blocks: blocks}, true
+		}
+		factors := make([]Object, len(factorSeq.Items))
+		for i, item := range factorSeq.Items {
+			f, ok := ObjectFromValue(item)
+			if !ok {
+				return Object{}, false
+			}
+			factors[i] = f
+		}
+		// Blocks are checked against the factors as written, so objects
+		// stored before Q(1) factors were dropped still decode.
+		obj := Object{Blocks: blocks, Factors: factors}
+		if !ObjectEqual(Object{Blocks: tensorBlocks(factors)}, Object{Blocks: blocks}) {
+			return Object{}, false
+		}
+		return obj, true
+
+	default:
+		return Object{}, false
+	}
+}
+
+// legacyTensorBlocks reports whether a factor-free block list has the
+// shape older stores wrote for a bipartite product: exactly two blocks,
+// neither of them 0 or 1.
+func legacyTensorBlocks(blocks []uint32) bool {
+	return len(blocks) == 2 && blocks[0] >= 2 && blocks[1] >= 2
+}
+
+// migrateObjectV1 migrates a v1 block list to the current object model.
+// A legacy product [a, b] becomes Q(a)⊗Q(b), with blocks [a·b]; every
+// other list is its direct sum.
+func migrateObjectV1(blocks []uint32) Object {
+	if legacyTensorBlocks(blocks) {
+		return TensorObjects(QuantumObject(int(blocks[0])), QuantumObject(int(blocks[1])))
+	}
+	return Object{Blocks: blocks}
+}
+
+// blocksFromValue parses a Seq of Int block sizes.
+func blocksFromValue(seq Seq) ([]uint32, bool) {
+	blocks := make([]uint32, len(seq.Items))
+	for i, item := range seq.Items {
+		n, ok := item.(Int)
+		if !ok || !n.V.IsInt64() || n.V.Sign() < 0 || n.V.Int64() > 1<<32-1 {
+			return nil, false
+		}
+		blocks[i] = uint32(n.V.Int64())
+	}
+	return blocks, true
+}
+
+// ObjectEqual checks if two objects are equal, including their tensor
+// factors.
+func ObjectEqual(a, b Object) bool {
+	if len(a.Blocks) != len(b.Blocks) {
+		return false
+	}
+	for i := range a.Blocks {
+		if a.Blocks[i] != b.Blocks[i] {
+			return false
+		}
+	}
+	if len(a.Factors) != len(b.Factors) {
+		return false
+	}
+	for i := range a.Factors {
+		if !ObjectEqual(a.Factors[i], b.Factors[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,196 @@
+package runtime
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTensorObjectsNormalForm(t *testing.T) {
+	// C(2) ⊗ Q(3) = Q(3) ⊕ Q(3)
+	obj := TensorObjects(ClassicalObject(2), QuantumObject(3))
+	want := []uint32{3, 3}
+	if len(obj.Blocks) != len(want) || obj.Blocks[0] != 3 || obj.Blocks[1] != 3 {
+		t.Errorf("C(2)⊗Q(3) blocks = %v, want %v", obj.Blocks, want)
+	}
+
+	// (Q(1) ⊕ Q(2)) ⊗ (Q(2) ⊕ Q(3)) = [2, 3, 4, 6]
+	obj = TensorObjects(Object{Blocks: []uint32{1, 2}}, Object{Blocks: []uint32{2, 3}})
+	want = []uint32{2, 3, 4, 6}
+	for i, n := range want {
+		if i >= len(obj.Blocks) || obj.Blocks[i] != n {
+			t.Fatalf("blocks = %v, want %v", obj.Blocks, want)
+		}
+	}
+}
+
+func TestTensorObjectsDimensions(t *testing.T) {
+	q := QuantumObject(2)
+	three := TensorObjects(q, q, q)
+	if BlockDim(three) != 8 {
+		t.Errorf("BlockDim(Q(2)^⊗3) = %d, want 8", BlockDim(three))
+	}
+	if objectDim(three) != 64 {
+		t.Errorf("objectDim(Q(2)^⊗3) = %d, want 64", objectDim(three))
+	}
+
+	sum := SumObjects(q, q, q)
+	if BlockDim(sum) != 6 {
+		t.Errorf("BlockDim(Q(2)^⊕3) = %d, want 6", BlockDim(sum))
+	}
+}
+
+func TestTensorObjectsAssociativeAndUnital(t *testing.T) {
+	a, b, c := QuantumObject(2), QuantumObject(3), ClassicalObject(2)
+
+	left := TensorObjects(TensorObjects(a, b), c)
+	right := TensorObjects(a, TensorObjects(b, c))
+	if !ObjectEqual(left, right) {
+		t.Error("(A⊗B)⊗C should equal A⊗(B⊗C)")
+	}
+	if len(left.Factors) != 3 {
+		t.Errorf("flattened product should have 3 factors, got %d", len(left.Factors))
+	}
+
+	if !ObjectEqual(TensorObjects(unitObject(), a), a) {
+		t.Error("I⊗A should equal A")
+	}
+	one := QuantumObject(1)
+	if !ObjectEqual(TensorObjects(one, a), a) || !ObjectEqual(TensorObjects(a, b, one), TensorObjects(a, b)) {
+		t.Error("Q(1)⊗A should equal A")
+	}
+	if !ObjectEqual(TensorObjects(one, one), one) || !ObjectEqual(TensorObjects(one, unitObject()), one) {
+		t.Error("a product of Q(1) factors should be Q(1)")
+	}
+	if !ObjectEqual(TensorObjects(), unitObject()) {
+		t.Error("empty product should be I")
+	}
+}
+
+func TestObjectEqualDistinguishesTensorFromSum(t *testing.T) {
+	q := QuantumObject(2)
+	if ObjectEqual(TensorObjects(q, q), SumObjects(q, q)) {
+		t.Error("Q(2)⊗Q(2) and Q(2)⊕Q(2) should differ")
+	}
+	if ObjectEqual(TensorObjects(q, q), QuantumObject(4)) {
+		t.Error("Q(2)⊗Q(2) should keep its factors and differ from Q(4)")
+	}
+}
+
+func TestObjectValueV1Unchanged(t *testing.T) {
+	// Factor-free objects keep the v1 encoding byte for byte.
+	obj := Object{Blocks: []uint32{2, 1, 3}}
+	legacy := MakeTag(MakeText("object"), MakeSeq(MakeInt(2), MakeInt(1), MakeInt(3)))
+	if !bytes.Equal(ObjectToValue(obj).Encode(), legacy.Encode()) {
+		t.Error("factor-free object should encode as v1")
+	}
+
+	back, ok := ObjectFromValue(legacy)
+	if !ok || !ObjectEqual(back, obj) {
+		t.Error("v1 object should decode as its block list")
+	}
+}
+
+func TestObjectV1Migration(t *testing.T) {
+	// Older stores wrote Q(2)⊗Q(3) as the v1 list [2, 3].
+	legacy := MakeTag(MakeText("object"), MakeSeq(MakeInt(2), MakeInt(3)))
+	got, ok := ObjectFromValue(legacy)
+	if !ok || !ObjectEqual(got, TensorObjects(QuantumObject(2), QuantumObject(3))) {
+		t.Errorf("legacy [2, 3] decoded as %v, want Q(2)⊗Q(3)", got)
+	}
+
+	// Lists of another shape are not migrated.
+	for _, blocks := range [][]uint32{{4}, {1, 1}, {2, 1}, {2, 2, 2}} {
+		obj := Object{Blocks: blocks}
+		got, ok := ObjectFromValue(ObjectToValue(obj))
+		if !ok || !ObjectEqual(got, obj) {
+			t.Errorf("v1 %v decoded as %v", blocks, got)
+		}
+	}
+
+	// A direct sum of that shape is written as v2 and survives.
+	sum := SumObjects(QuantumObject(2), QuantumObject(2))
+	v := ObjectToValue(sum)
+	if v.(Tag).Label.(Text).V != "object-v2" {
+		t.Error("Q(2)⊕Q(2) should not use the v1 encoding")
+	}
+	got, ok = ObjectFromValue(v)
+	if !ok || !ObjectEqual(got, sum) {
+		t.Errorf("Q(2)⊕Q(2) round-tripped to %v", got)
+	}
+
+	// Without factors, v2 is only accepted where v1 would migrate.
+	plain := MakeTag(MakeText("object-v2"), MakeSeq(MakeSeq(MakeInt(4)), MakeSeq()))
+	if _, ok := ObjectFromValue(plain); ok {
+		t.Error("factor-free v2 [4] should be rejected")
+	}
+}
+
+func TestObjectValueV2RoundTrip(t *testing.T) {
+	objs := []Object{
+		TensorObjects(QuantumObject(2), QuantumObject(2)),
+		TensorObjects(ClassicalObject(2), QuantumObject(3), QuantumObject(2)),
+		TensorObjects(SumObjects(QuantumObject(1), QuantumObject(2)), QuantumObject(2)),
+	}
+	for i, obj := range objs {
+		v := ObjectToValue(obj)
+		back, ok := ObjectFromValue(v)
+		if !ok {
+			t.Errorf("case %d: ObjectFromValue failed", i)
+			continue
+		}
+		if !ObjectEqual(back, obj) {
+			t.Errorf("case %d: round trip mismatch: %v vs %v", i, back, obj)
+		}
+	}
+}
+
+func TestObjectValueV2RejectsInconsistentBlocks(t *testing.T) {
+	q := ObjectToValue(QuantumObject(2))
+	bad := MakeTag(
+		MakeText("object-v2"),
+		MakeSeq(MakeSeq(MakeInt(2), MakeInt(2)), MakeSeq(q, q)),
+	)
+	if _, ok := ObjectFromValue(bad); ok {
+		t.Error("v2 object whose blocks are not the product of its factors should be rejected")
+	}
+}
+
+func TestCircuitValueWithTensorObject(t *testing.T) {
+	twoQ := TensorObjects(qubit(), qubit())
+	c := Circuit{
+		Domain:   twoQ,
+		Codomain: twoQ,
+		Prim:     PrimUnitary,
+		Data:     MatrixToValue(cnotUnitary()),
+	}
+	back, ok := CircuitFromValue(CircuitToValue(c))
+	if !ok {
+		t.Fatal("CircuitFromValue failed")
+	}
+	if !ObjectEqual(back.Domain, twoQ) || !ObjectEqual(back.Codomain, twoQ) {
+		t.Error("tensor objects should survive circuit encoding")
+	}
+}
+
+func TestExecuteSwapWithFactors(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Swap Q(2)⊗Q(3) → Q(3)⊗Q(2) using factors rather than legacy blocks.
+	c := Circuit{
+		Domain:   TensorObjects(qubit(), QuantumObject(3)),
+		Codomain: TensorObjects(QuantumObject(3), qubit()),
+		Prim:     PrimSwap,
+	}
+
+	// |0,1⟩ has index 0*3+1 = 1 in A⊗B and |1,0⟩ index 1*2+0 = 2 in B⊗A.
+	input := NewMatrix(6, 6)
+	input.Set(1, 1, QIOne())
+	result, err := exec.Execute(c, input)
+	if err != nil {
+		t.Fatalf("Execute swap failed: %v", err)
+	}
+	if !QIEqual(result.Get(2, 2), QIOne()) {
+		t.Error("Swap|0,1⟩ should be at [2,2]")
+	}
+}
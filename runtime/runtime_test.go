@@ -299,7 +299,7 @@ func TestExecuteZero(t *testing.T) {
 	}
 
 	// Should be zero matrix
-	zero := NewMatrix(4, 4) // 2^2 = 4 for block size 2
+	zero := NewMatrix(2, 2) // density matrices on Q(2) are 2x2
 	if !MatrixEqual(result, zero) {
 		t.Error("Zero should return zero matrix")
 	}
@@ -479,6 +479,9 @@ func TestDecodeValueInt(t *testing.T) {
 		{"zero", MakeInt(0)},
 		{"small positive", MakeInt(42)},
 		{"max small", MakeInt(0x3F)},
+		{"first long form", MakeInt(0x40)},
+		{"seven bits", MakeInt(0x51)},
+		{"max seven bits", MakeInt(0x7F)},
 		{"large positive", MakeInt(1000)},
 		{"negative", MakeInt(-7)},
 		{"large negative", MakeInt(-999)},
@@ -848,8 +851,8 @@ func TestExecuteSwap2x3(t *testing.T) {
 	// Swap Q(2)⊗Q(3) → Q(3)⊗Q(2)
 	// dimA=2, dimB=3, total=6
 	c := Circuit{
-		Domain:   Object{Blocks: []uint32{2, 3}},
-		Codomain: Object{Blocks: []uint32{3, 2}},
+		Domain:   tensorObject(QuantumObject(2), QuantumObject(3)),
+		Codomain: tensorObject(QuantumObject(3), QuantumObject(2)),
 		Prim:     PrimSwap,
 	}
 
@@ -890,22 +893,22 @@ func TestExecuteSwapInvolution(t *testing.T) {
 
 	// Swap twice should be identity
 	swap1 := Circuit{
-		Domain:   Object{Blocks: []uint32{2, 3}},
-		Codomain: Object{Blocks: []uint32{3, 2}},
+		Domain:   tensorObject(QuantumObject(2), QuantumObject(3)),
+		Codomain: tensorObject(QuantumObject(3), QuantumObject(2)),
 		Prim:     PrimSwap,
 	}
 	id1 := store.Put(swap1)
 
 	swap2 := Circuit{
-		Domain:   Object{Blocks: []uint32{3, 2}},
-		Codomain: Object{Blocks: []uint32{2, 3}},
+		Domain:   tensorObject(QuantumObject(3), QuantumObject(2)),
+		Codomain: tensorObject(QuantumObject(2), QuantumObject(3)),
 		Prim:     PrimSwap,
 	}
 	id2 := store.Put(swap2)
 
 	composed := Circuit{
-		Domain:   Object{Blocks: []uint32{2, 3}},
-		Codomain: Object{Blocks: []uint32{2, 3}},
+		Domain:   tensorObject(QuantumObject(2), QuantumObject(3)),
+		Codomain: tensorObject(QuantumObject(2), QuantumObject(3)),
 		Prim:     PrimCompose,
 		Children: [][32]byte{id1, id2},
 	}
@@ -952,44 +955,24 @@ func TestExecuteInject(t *testing.T) {
 	store := NewStore()
 	exec := NewExecutor(store)
 
-	// Inject Q(2) → Q(2)⊕Q(1) = Object{2,1} with dim 4+1=5
+	// Inject Q(2) → Q(2)⊕Q(1) = Object{2,1}: density matrices go from 2x2
+	// to 3x3 (BlockDim), not to one entry per algebra element.
 	c := Circuit{
 		Domain:   Object{Blocks: []uint32{2}},
 		Codomain: Object{Blocks: []uint32{2, 1}},
 		Prim:     PrimInject,
 	}
 
-	input := Identity(4)
+	input := ket1bra1()
 	result, err := exec.Execute(c, input)
 	if err != nil {
 		t.Fatalf("Execute inject failed: %v", err)
 	}
 
-	if result.Rows != 5 || result.Cols != 5 {
-		t.Errorf("inject should produce 5x5, got %dx%d", result.Rows, result.Cols)
-	}
-
-	// Top-left 4x4 should be identity
-	for i := 0; i < 4; i++ {
-		for j := 0; j < 4; j++ {
-			expected := QIZero()
-			if i == j {
-				expected = QIOne()
-			}
-			if !QIEqual(result.Get(i, j), expected) {
-				t.Errorf("inject[%d,%d] = %v, want %v", i, j, result.Get(i, j), expected)
-			}
-		}
-	}
-
-	// Last row and column should be zero
-	for i := 0; i < 5; i++ {
-		if !QIIsZero(result.Get(4, i)) {
-			t.Errorf("inject[4,%d] should be 0", i)
-		}
-		if !QIIsZero(result.Get(i, 4)) {
-			t.Errorf("inject[%d,4] should be 0", i)
-		}
+	want := NewMatrix(3, 3)
+	want.Set(1, 1, QIOne())
+	if result.Rows != 3 || result.Cols != 3 || !MatrixEqual(result, want) {
+		t.Errorf("inject should produce |1⟩⟨1| ⊕ 0 as 3x3, got %dx%d", result.Rows, result.Cols)
 	}
 }
 
@@ -997,26 +980,26 @@ func TestExecuteProject(t *testing.T) {
 	store := NewStore()
 	exec := NewExecutor(store)
 
-	// Project Q(2)⊕Q(1) → Q(2), dim 5 → 4
+	// Project Q(2)⊕Q(1) → Q(2), dim 3 → 2
 	c := Circuit{
 		Domain:   Object{Blocks: []uint32{2, 1}},
 		Codomain: Object{Blocks: []uint32{2}},
 		Prim:     PrimProject,
 	}
 
-	input := Identity(5)
+	input := Identity(3)
 	result, err := exec.Execute(c, input)
 	if err != nil {
 		t.Fatalf("Execute project failed: %v", err)
 	}
 
-	if result.Rows != 4 || result.Cols != 4 {
-		t.Errorf("project should produce 4x4, got %dx%d", result.Rows, result.Cols)
+	if result.Rows != 2 || result.Cols != 2 {
+		t.Errorf("project should produce 2x2, got %dx%d", result.Rows, result.Cols)
 	}
 
-	// Should be I4
-	if !MatrixEqual(result, Identity(4)) {
-		t.Error("project(I5) should be I4")
+	// Should be I2
+	if !MatrixEqual(result, Identity(2)) {
+		t.Error("project(I3) should be I2")
 	}
 }
 
@@ -1046,7 +1029,7 @@ func TestExecuteInjectProjectRoundTrip(t *testing.T) {
 		Children: [][32]byte{injectID, projectID},
 	}
 
-	input := Identity(4)
+	input := rhoYExact()
 	result, err := exec.Execute(composed, input)
 	if err != nil {
 		t.Fatalf("Execute inject;project failed: %v", err)
@@ -1374,8 +1357,8 @@ func TestSwap2x2IsSymmetric(t *testing.T) {
 
 	// For 2x2 swap, swapping twice gives identity
 	c := Circuit{
-		Domain:   Object{Blocks: []uint32{2, 2}},
-		Codomain: Object{Blocks: []uint32{2, 2}},
+		Domain:   tensorObject(qubit(), qubit()),
+		Codomain: tensorObject(qubit(), qubit()),
 		Prim:     PrimSwap,
 	}
 
@@ -1402,8 +1385,8 @@ func TestSwapSpecificStates(t *testing.T) {
 
 	// Swap Q(2)⊗Q(2): dimA=2, dimB=2, total=4
 	c := Circuit{
-		Domain:   Object{Blocks: []uint32{2, 2}},
-		Codomain: Object{Blocks: []uint32{2, 2}},
+		Domain:   tensorObject(qubit(), qubit()),
+		Codomain: tensorObject(qubit(), qubit()),
 		Prim:     PrimSwap,
 	}
 
@@ -0,0 +1,375 @@
+package runtime
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Sectioned QMB v2 container.
+//
+// A v1 file ends in one Seq holding every store entry, so reading any
+// circuit means decoding the whole store. A v2 file splits the same content
+// into sections that can be checked and read independently. All integers
+// are big-endian:
+//
+//	magic      "QMB\x02"
+//	count      u32
+//	table      count × (kind u32, offset u64, length u64, sha256 [32]byte)
+//	sections   at the offsets named in the table
+//
+// Offsets are from the start of the file and each SHA-256 covers its
+// section's bytes. The section kinds are
+//
+//	header      entrypoint [32]byte
+//	metadata    Tag("metadata", Seq(Text name, Text version))
+//	index       n u32, then n × (qgid [32]byte, offset u64, length u64),
+//	            strictly ascending by qgid
+//	blobs       the canonical encodings of the entries, concatenated
+//	signatures  optional, see sign.go
+//
+// Each kind appears at most once, and all but signatures are required.
+// Index offsets are relative to the blobs section. Since a QGID is the
+// SHA-256 of a value's encoding, every entry is also checked against its own
+// QGID when it is read.
+//
+// Decode verifies the section checksums and the index, but decodes no store
+// entry; entries and metadata are decoded with DecodeCanonical when read.
+// NewRunner loads only the entries reachable from the entrypoint, following
+// the references Store.Reachable follows.
+
+// SectionKind identifies a v2 section.
+type SectionKind uint32
+
+const (
+	SectionHeader     SectionKind = 1
+	SectionMetadata   SectionKind = 2
+	SectionIndex      SectionKind = 3
+	SectionBlobs      SectionKind = 4
+	SectionSignatures SectionKind = 5
+)
+
+// String returns the name of the section kind.
+func (k SectionKind) String() string {
+	switch k {
+	case SectionHeader:
+		return "header"
+	case SectionMetadata:
+		return "metadata"
+	case SectionIndex:
+		return "index"
+	case SectionBlobs:
+		return "blobs"
+	case SectionSignatures:
+		return "signatures"
+	default:
+		return fmt.Sprintf("SectionKind(%d)", uint32(k))
+	}
+}
+
+// Section is one entry of a v2 section table.
+type Section struct {
+	Kind   SectionKind
+	Offset uint64
+	Length uint64
+	Sum    [32]byte // SHA-256 of the section bytes
+}
+
+const (
+	metadataLabel    = "metadata"
+	sectionEntrySize = 4 + 8 + 8 + 32
+	indexEntrySize   = 32 + 8 + 8
+)
+
+var (
+	magicV1 = [4]byte{'Q', 'M', 'B', 0x01}
+	magicV2 = [4]byte{'Q', 'M', 'B', 0x02}
+)
+
+// storeEntry locates one entry in the blobs section.
+type storeEntry struct {
+	id     [32]byte
+	offset uint64
+	length uint64
+}
+
+// sectionBody is a section to be written.
+type sectionBody struct {
+	kind SectionKind
+	data []byte
+}
+
+// EmbedV2 creates a v2 binary from a store and entrypoint. Entries are
+// ordered by QGID, so the same store always encodes to the same bytes.
+func EmbedV2(store *Store, entrypoint [32]byte, name, version string) *EmbeddedBinary {
+	ids := make([][32]byte, 0, len(store.values))
+	for id := range store.values {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+
+	e := &EmbeddedBinary{Magic: magicV2, Entrypoint: entrypoint, Name: name, Version: version}
+	for _, id := range ids {
+		enc := store.values[id].Encode()
+		e.entries = append(e.entries, storeEntry{id: id, offset: uint64(len(e.blobs)), length: uint64(len(enc))})
+		e.blobs = append(e.blobs, enc...)
+	}
+	return e
+}
+
+// encodeV2 writes e in the v2 layout.
+func (e *EmbeddedBinary) encodeV2() []byte {
+	index := e.indexBytes()
+	meta := MakeTag(MakeText(metadataLabel), MakeSeq(MakeText(e.Name), MakeText(e.Version))).Encode()
+
+	bodies := []sectionBody{
+		{SectionHeader, e.Entrypoint[:]},
+		{SectionMetadata, meta},
+		{SectionIndex, index},
+		{SectionBlobs, e.blobs},
+	}
+	if e.Signatures != nil {
+		bodies = append(bodies, sectionBody{SectionSignatures, e.Signatures})
+	}
+
+	offset := uint64(8 + sectionEntrySize*len(bodies))
+	out := append([]byte(nil), magicV2[:]...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(bodies)))
+	for _, b := range bodies {
+		s := Section{Kind: b.kind, Offset: offset, Length: uint64(len(b.data)), Sum: sha256.Sum256(b.data)}
+		out = binary.BigEndian.AppendUint32(out, uint32(s.Kind))
+		out = binary.BigEndian.AppendUint64(out, s.Offset)
+		out = binary.BigEndian.AppendUint64(out, s.Length)
+		out = append(out, s.Sum[:]...)
+		offset += s.Length
+	}
+	for _, b := range bodies {
+		out = append(out, b.data...)
+	}
+	return out
+}
+
+// indexBytes encodes the index section.
+func (e *EmbeddedBinary) indexBytes() []byte {
+	index := make([]byte, 4, 4+indexEntrySize*len(e.entries))
+	binary.BigEndian.PutUint32(index, uint32(len(e.entries)))
+	for _, ent := range e.entries {
+		index = append(index, ent.id[:]...)
+		index = binary.BigEndian.AppendUint64(index, ent.offset)
+		index = binary.BigEndian.AppendUint64(index, ent.length)
+	}
+	return index
+}
+
+// decodeV2 parses and verifies a v2 file. Store entries are left encoded.
+func decodeV2(data []byte) (*EmbeddedBinary, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("qmb v2: data too short for section count")
+	}
+	count := uint64(binary.BigEndian.Uint32(data[4:8]))
+	if uint64(len(data)-8)/sectionEntrySize < count {
+		return nil, fmt.Errorf("qmb v2: data too short for %d sections", count)
+	}
+
+	e := &EmbeddedBinary{Magic: magicV2}
+	found := make(map[SectionKind][]byte)
+	for i := uint64(0); i < count; i++ {
+		p := data[8+i*sectionEntrySize:]
+		s := Section{
+			Kind:   SectionKind(binary.BigEndian.Uint32(p)),
+			Offset: binary.BigEndian.Uint64(p[4:]),
+			Length: binary.BigEndian.Uint64(p[12:]),
+		}
+		copy(s.Sum[:], p[20:52])
+		if s.Kind < SectionHeader || s.Kind > SectionSignatures {
+			return nil, fmt.Errorf("qmb v2: section %d has unknown kind %d", i, uint32(s.Kind))
+		}
+		if _, dup := found[s.Kind]; dup {
+			return nil, fmt.Errorf("qmb v2: duplicate %s section", s.Kind)
+		}
+		if s.Offset > uint64(len(data)) || s.Length > uint64(len(data))-s.Offset {
+			return nil, fmt.Errorf("qmb v2: %s section [%d, +%d) exceeds the %d-byte file",
+				s.Kind, s.Offset, s.Length, len(data))
+		}
+		body := data[s.Offset : s.Offset+s.Length]
+		if sha256.Sum256(body) != s.Sum {
+			return nil, fmt.Errorf("qmb v2: %s section checksum mismatch", s.Kind)
+		}
+		found[s.Kind] = body
+		e.Sections = append(e.Sections, s)
+	}
+	for _, k := range []SectionKind{SectionHeader, SectionMetadata, SectionIndex, SectionBlobs} {
+		if _, ok := found[k]; !ok {
+			return nil, fmt.Errorf("qmb v2: missing %s section", k)
+		}
+	}
+
+	header := found[SectionHeader]
+	if len(header) != 32 {
+		return nil, fmt.Errorf("qmb v2: header section is %d bytes, want 32", len(header))
+	}
+	copy(e.Entrypoint[:], header)
+
+	name, version, err := metadataFromBytes(found[SectionMetadata])
+	if err != nil {
+		return nil, err
+	}
+	e.Name, e.Version = name, version
+
+	e.blobs = found[SectionBlobs]
+	e.entries, err = indexFromBytes(found[SectionIndex], uint64(len(e.blobs)))
+	if err != nil {
+		return nil, err
+	}
+	if sig, ok := found[SectionSignatures]; ok {
+		e.Signatures = sig
+	}
+	return e, nil
+}
+
+// metadataFromBytes parses the metadata section.
+func metadataFromBytes(data []byte) (name, version string, err error) {
+	v, err := DecodeCanonical(data, DefaultDecodeLimits)
+	if err != nil {
+		return "", "", fmt.Errorf("qmb v2: metadata: %w", err)
+	}
+	tag, ok := v.(Tag)
+	if !ok {
+		return "", "", fmt.Errorf("qmb v2: metadata must be a Tag")
+	}
+	label, ok := tag.Label.(Text)
+	seq, ok2 := tag.Payload.(Seq)
+	if !ok || label.V != metadataLabel || !ok2 || len(seq.Items) != 2 {
+		return "", "", fmt.Errorf("qmb v2: metadata must be Tag(\"metadata\", Seq(name, version))")
+	}
+	nameText, ok := seq.Items[0].(Text)
+	versionText, ok2 := seq.Items[1].(Text)
+	if !ok || !ok2 {
+		return "", "", fmt.Errorf("qmb v2: metadata name and version must be Text")
+	}
+	return nameText.V, versionText.V, nil
+}
+
+// indexFromBytes parses the index section and checks that its QGIDs are
+// strictly ascending and its entries lie within a blobs section of
+// blobLen bytes.
+func indexFromBytes(data []byte, blobLen uint64) ([]storeEntry, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("qmb v2: index section too short")
+	}
+	n := uint64(binary.BigEndian.Uint32(data))
+	if uint64(len(data)-4) != n*indexEntrySize {
+		return nil, fmt.Errorf("qmb v2: index section is %d bytes for %d entries", len(data), n)
+	}
+	entries := make([]storeEntry, n)
+	for i := range entries {
+		p := data[4+i*indexEntrySize:]
+		ent := &entries[i]
+		copy(ent.id[:], p[:32])
+		ent.offset = binary.BigEndian.Uint64(p[32:])
+		ent.length = binary.BigEndian.Uint64(p[40:])
+		if i > 0 && bytes.Compare(entries[i-1].id[:], ent.id[:]) >= 0 {
+			return nil, fmt.Errorf("qmb v2: index entry %d is out of order", i)
+		}
+		if ent.offset > blobLen || ent.length > blobLen-ent.offset {
+			return nil, fmt.Errorf("qmb v2: index entry %d [%d, +%d) exceeds the %d-byte blobs section",
+				i, ent.offset, ent.length, blobLen)
+		}
+	}
+	return entries, nil
+}
+
+// EntryIDs returns the QGIDs in a v2 binary's index in ascending order, or
+// nil for a v1 binary.
+func (e *EmbeddedBinary) EntryIDs() [][32]byte {
+	if e.entries == nil {
+		return nil
+	}
+	ids := make([][32]byte, len(e.entries))
+	for i, ent := range e.entries {
+		ids[i] = ent.id
+	}
+	return ids
+}
+
+// Entry decodes the store entry id of a v2 binary, checking it against its
+// QGID. It reports false if the binary has no such entry or is v1.
+func (e *EmbeddedBinary) Entry(id [32]byte) (Value, bool, error) {
+	return e.entry(id, DefaultDecodeLimits)
+}
+
+// entry is Entry with explicit decode limits.
+func (e *EmbeddedBinary) entry(id [32]byte, limits DecodeLimits) (Value, bool, error) {
+	i := sort.Search(len(e.entries), func(i int) bool { return bytes.Compare(e.entries[i].id[:], id[:]) >= 0 })
+	if i == len(e.entries) || e.entries[i].id != id {
+		return nil, false, nil
+	}
+	ent := e.entries[i]
+	blob := e.blobs[ent.offset : ent.offset+ent.length]
+	if sha256.Sum256(blob) != id {
+		return nil, true, fmt.Errorf("qmb v2: entry %x does not match its QGID", id[:8])
+	}
+	v, err := DecodeCanonical(blob, limits)
+	if err != nil {
+		return nil, true, fmt.Errorf("qmb v2: entry %x: %w", id[:8], err)
+	}
+	return v, true, nil
+}
+
+// loadReachable loads into store the entrypoint of a v2 binary and every
+// entry reachable from it, following the same references as
+// Store.Reachable, including the external reference for a missing QGID.
+// Other missing children are left for execution and type checking to
+// report.
+func loadReachable(store *Store, e *EmbeddedBinary, limits DecodeLimits) error {
+	queue := [][32]byte{e.Entrypoint}
+	seen := map[[32]byte]bool{e.Entrypoint: true}
+	visit := func(id [32]byte) {
+		if !seen[id] {
+			seen[id] = true
+			queue = append(queue, id)
+		}
+	}
+	var externs map[[32]byte][32]byte
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		v, ok, err := e.entry(id, limits)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if externs == nil {
+				externs = e.externs()
+			}
+			if ref, ok := externs[id]; ok {
+				visit(ref)
+			}
+			continue
+		}
+		store.values[id] = v
+		if c, ok := CircuitFromValue(v); ok {
+			store.circuits[id] = c
+			circuitRefs(c, visit)
+		} else {
+			valueRefs(v, visit)
+		}
+	}
+	return nil
+}
+
+// externs maps the ID of each external reference in a v2 index to the
+// reference's QGID, recognizing references by their encoding so that no
+// entry is decoded. The references are checked when loaded.
+func (e *EmbeddedBinary) externs() map[[32]byte][32]byte {
+	m := make(map[[32]byte][32]byte)
+	for _, ent := range e.entries {
+		blob := e.blobs[ent.offset : ent.offset+ent.length]
+		if len(blob) == externLen && bytes.HasPrefix(blob, externPrefix) {
+			m[[32]byte(blob[len(externPrefix):])] = ent.id
+		}
+	}
+	return m
+}
@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// sectionsFixture stores X ⊗ X on two qubits. Both children are the same
+// X, so two entries are reachable from the root.
+func sectionsFixture(store *Store) [32]byte {
+	x := putUnitary(store, pauliX())
+	two := tensorObject(qubit(), qubit())
+	return store.Put(Circuit{Domain: two, Codomain: two, Prim: PrimTensor, Children: [][32]byte{x, x}})
+}
+
+func TestSectionedBinaryRoundTrip(t *testing.T) {
+	store := NewStore()
+	ep := sectionsFixture(store)
+	unrelated := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimKraus, Data: KrausToValue([]*Matrix{pauliZ()})})
+
+	data := EmbedV2(store, ep, "sectioned", "2.0").Encode()
+	if !bytes.Equal(data, EmbedV2(store, ep, "sectioned", "2.0").Encode()) {
+		t.Fatal("EmbedV2 is not deterministic")
+	}
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.Name != "sectioned" || decoded.Version != "2.0" || decoded.Entrypoint != ep {
+		t.Errorf("header = %q %q %x", decoded.Name, decoded.Version, decoded.Entrypoint[:8])
+	}
+	if len(decoded.Sections) != 4 || len(decoded.EntryIDs()) != store.StoreSize() {
+		t.Errorf("%d sections, %d entries", len(decoded.Sections), len(decoded.EntryIDs()))
+	}
+	if !bytes.Equal(decoded.Encode(), data) {
+		t.Error("re-encoding a decoded v2 binary changed its bytes")
+	}
+
+	// The runner loads the tensor and its unitary, not the unrelated Kraus map.
+	runner, err := NewRunner(data)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	if runner.StoreSize() != 2 {
+		t.Errorf("loaded %d entries, want the 2 reachable ones", runner.StoreSize())
+	}
+	if _, ok := runner.GetCircuit(unrelated); !ok {
+		t.Error("unreached entry not available on demand")
+	}
+	v1, err := NewRunner(Embed(store, ep, "sectioned", "1.0").Encode())
+	if err != nil {
+		t.Fatalf("NewRunner v1: %v", err)
+	}
+	rho := Kronecker(rhoPlusExact(), ket0bra0())
+	want, _ := v1.Run(rho)
+	got, err := runner.Run(rho)
+	if err != nil || !MatrixEqual(got, want) {
+		t.Errorf("v2 Run differs from v1: %v", err)
+	}
+}
+
+func TestSectionedBinaryRejectsDamage(t *testing.T) {
+	store := NewStore()
+	data := EmbedV2(store, sectionsFixture(store), "sectioned", "2.0").Encode()
+	decoded, _ := Decode(data)
+	blobs := decoded.Sections[3]
+
+	flipped := append([]byte(nil), data...)
+	flipped[blobs.Offset+blobs.Length-1] ^= 1
+	truncated := data[:len(data)-1]
+	// Three sections in the table leaves the blobs section out.
+	missing := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(missing[4:], 3)
+
+	for name, tc := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"flipped":   {flipped, "blobs section checksum mismatch"},
+		"truncated": {truncated, "exceeds"},
+		"missing":   {missing, "missing blobs section"},
+	} {
+		if _, err := Decode(tc.data); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", name, err, tc.want)
+		}
+	}
+
+	// An index pointing at the wrong blob passes the section checksums but
+	// not the entry's own QGID.
+	ids := decoded.EntryIDs()
+	swapped := &EmbeddedBinary{Magic: magicV2, Entrypoint: ids[0], Name: "x", Version: "y", blobs: decoded.blobs,
+		entries: []storeEntry{{id: ids[0], offset: decoded.entries[1].offset, length: decoded.entries[1].length}}}
+	if _, err := NewRunner(swapped.Encode()); err == nil || !strings.Contains(err.Error(), "does not match its QGID") {
+		t.Errorf("swapped index: got %v", err)
+	}
+}
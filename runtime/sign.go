@@ -0,0 +1,305 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Binary signatures.
+//
+// A signature covers what a binary computes, not its layout: the
+// entrypoint QGID and the store hash, which is the SHA-256 of the v1 store
+// data or of the v2 index. The v2 index lists every entry's QGID, and each
+// entry is checked against its QGID when read, so the index commits to the
+// whole store. The signer metadata is signed with them; the message is the
+// encoding of
+//
+//	Tag("qmb-signature", Seq(Bytes entrypoint, Bytes storeHash,
+//	                         Text signer, Bytes keyID, Int unixTime))
+//
+// and a signature is stored as
+//
+//	Tag("signature", Seq(Text signer, Bytes keyID, Int unixTime, Bytes sig))
+//
+// where keyID is the first 8 bytes of the SHA-256 of the Ed25519 public
+// key. A v2 binary embeds a Seq of signatures in its signatures section,
+// which is outside the signed content, so signatures can be added without
+// invalidating others. A detached signature file holds the same Seq and
+// works for v1 binaries too.
+//
+// Keys and trust stores are text files with one key per line:
+//
+//	<hex Ed25519 seed> <signer>           signing key
+//	<hex Ed25519 public key> <signer>     trusted key
+//
+// Blank lines and lines starting with # are ignored.
+
+// ErrUnsigned is returned when a binary has no signatures to check.
+var ErrUnsigned = errors.New("qmb: binary is not signed")
+
+// ErrUntrusted is returned when no signature is valid under a trusted key.
+var ErrUntrusted = errors.New("qmb: no valid signature from a trusted key")
+
+// Signature is an Ed25519 signature of a binary with its signer metadata.
+type Signature struct {
+	Signer string    // who signed, as given by the signing key
+	KeyID  [8]byte   // see KeyID
+	Time   time.Time // signing time, to the second
+	Sig    []byte
+}
+
+// KeyID identifies a public key by the first 8 bytes of its SHA-256.
+func KeyID(pub ed25519.PublicKey) [8]byte {
+	sum := sha256.Sum256(pub)
+	return [8]byte(sum[:8])
+}
+
+// SigningKey is an Ed25519 private key with the signer it names.
+type SigningKey struct {
+	Signer string
+	Key    ed25519.PrivateKey
+}
+
+// GenerateSigningKey creates a new key for signer.
+func GenerateSigningKey(signer string) (*SigningKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{Signer: signer, Key: priv}, nil
+}
+
+// ParseSigningKey parses a signing key file.
+func ParseSigningKey(data []byte) (*SigningKey, error) {
+	lines, err := keyLines(data, ed25519.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) != 1 {
+		return nil, fmt.Errorf("signing key: want one key, found %d", len(lines))
+	}
+	return &SigningKey{Signer: lines[0].signer, Key: ed25519.NewKeyFromSeed(lines[0].key)}, nil
+}
+
+// Public returns the public key.
+func (k *SigningKey) Public() ed25519.PublicKey {
+	return k.Key.Public().(ed25519.PublicKey)
+}
+
+// Marshal returns the signing key file.
+func (k *SigningKey) Marshal() []byte {
+	return fmt.Appendf(nil, "%s %s\n", hex.EncodeToString(k.Key.Seed()), k.Signer)
+}
+
+// MarshalPublic returns the public key as a trust store line.
+func (k *SigningKey) MarshalPublic() []byte {
+	return fmt.Appendf(nil, "%s %s\n", hex.EncodeToString(k.Public()), k.Signer)
+}
+
+// TrustStore holds the public keys whose signatures are accepted.
+type TrustStore struct {
+	keys map[[8]byte]trustedKey
+}
+
+type trustedKey struct {
+	signer string
+	key    ed25519.PublicKey
+}
+
+// NewTrustStore creates an empty trust store.
+func NewTrustStore() *TrustStore {
+	return &TrustStore{keys: make(map[[8]byte]trustedKey)}
+}
+
+// ParseTrustStore parses a trust store file.
+func ParseTrustStore(data []byte) (*TrustStore, error) {
+	lines, err := keyLines(data, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, err
+	}
+	t := NewTrustStore()
+	for _, l := range lines {
+		t.Add(l.signer, l.key)
+	}
+	return t, nil
+}
+
+// LoadTrustStore reads and parses the trust store file at path.
+func LoadTrustStore(path string) (*TrustStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("trust store: %w", err)
+	}
+	return ParseTrustStore(data)
+}
+
+// Add trusts pub, named signer.
+func (t *TrustStore) Add(signer string, pub ed25519.PublicKey) {
+	t.keys[KeyID(pub)] = trustedKey{signer: signer, key: pub}
+}
+
+// Len returns the number of trusted keys.
+func (t *TrustStore) Len() int {
+	return len(t.keys)
+}
+
+// Check verifies s against e with the trusted key it names. It reports the
+// trust store's name for that key.
+func (t *TrustStore) Check(e *EmbeddedBinary, s Signature) (string, error) {
+	k, ok := t.keys[s.KeyID]
+	if !ok {
+		return "", fmt.Errorf("qmb: key %x is not trusted", s.KeyID)
+	}
+	if !ed25519.Verify(k.key, e.signedMessage(s), s.Sig) {
+		return "", fmt.Errorf("qmb: signature by key %x does not match the binary", s.KeyID)
+	}
+	return k.signer, nil
+}
+
+// Verify returns the first of e's embedded signatures, then detached, that
+// is valid under a trusted key. It fails with ErrUnsigned if there are no
+// signatures and with an error wrapping ErrUntrusted if none is valid.
+func (t *TrustStore) Verify(e *EmbeddedBinary, detached ...Signature) (Signature, error) {
+	sigs, err := e.EmbeddedSignatures()
+	if err != nil {
+		return Signature{}, err
+	}
+	sigs = append(sigs, detached...)
+	if len(sigs) == 0 {
+		return Signature{}, ErrUnsigned
+	}
+	var errs []error
+	for _, s := range sigs {
+		if _, err := t.Check(e, s); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return s, nil
+	}
+	return Signature{}, fmt.Errorf("%w: %w", ErrUntrusted, errors.Join(errs...))
+}
+
+// StoreHash returns the hash signatures cover along with the entrypoint.
+func (e *EmbeddedBinary) StoreHash() [32]byte {
+	if e.Magic != magicV2 {
+		return sha256.Sum256(e.StoreData)
+	}
+	return sha256.Sum256(e.indexBytes())
+}
+
+// Sign signs e with key at time t, truncated to the second.
+func (e *EmbeddedBinary) Sign(key *SigningKey, t time.Time) Signature {
+	s := Signature{Signer: key.Signer, KeyID: KeyID(key.Public()), Time: time.Unix(t.Unix(), 0).UTC()}
+	s.Sig = ed25519.Sign(key.Key, e.signedMessage(s))
+	return s
+}
+
+// AddSignature appends s to the signatures section of a v2 binary.
+func (e *EmbeddedBinary) AddSignature(s Signature) error {
+	if e.Magic != magicV2 {
+		return fmt.Errorf("qmb: only v2 binaries embed signatures; use a detached signature")
+	}
+	sigs, err := e.EmbeddedSignatures()
+	if err != nil {
+		return err
+	}
+	e.Signatures = EncodeSignatures(append(sigs, s))
+	return nil
+}
+
+// EmbeddedSignatures decodes the signatures section, if any.
+func (e *EmbeddedBinary) EmbeddedSignatures() ([]Signature, error) {
+	if e.Signatures == nil {
+		return nil, nil
+	}
+	sigs, err := DecodeSignatures(e.Signatures)
+	if err != nil {
+		return nil, fmt.Errorf("qmb v2: signatures: %w", err)
+	}
+	return sigs, nil
+}
+
+// signedMessage returns the bytes s signs for e.
+func (e *EmbeddedBinary) signedMessage(s Signature) []byte {
+	store := e.StoreHash()
+	return MakeTag(MakeText("qmb-signature"), MakeSeq(
+		MakeBytes(e.Entrypoint[:]), MakeBytes(store[:]),
+		MakeText(s.Signer), MakeBytes(s.KeyID[:]), MakeInt(s.Time.Unix()),
+	)).Encode()
+}
+
+// EncodeSignatures encodes signatures as a signatures section or detached
+// signature file.
+func EncodeSignatures(sigs []Signature) []byte {
+	items := make([]Value, len(sigs))
+	for i, s := range sigs {
+		items[i] = MakeTag(MakeText("signature"), MakeSeq(
+			MakeText(s.Signer), MakeBytes(s.KeyID[:]), MakeInt(s.Time.Unix()), MakeBytes(s.Sig),
+		))
+	}
+	return MakeSeq(items...).Encode()
+}
+
+// DecodeSignatures decodes a signatures section or detached signature file.
+func DecodeSignatures(data []byte) ([]Signature, error) {
+	v, err := DecodeCanonical(data, DefaultDecodeLimits)
+	if err != nil {
+		return nil, err
+	}
+	seq, ok := v.(Seq)
+	if !ok {
+		return nil, fmt.Errorf("signatures must be a Seq")
+	}
+	sigs := make([]Signature, len(seq.Items))
+	for i, item := range seq.Items {
+		tag, ok := item.(Tag)
+		label, ok2 := tag.Label.(Text)
+		fields, ok3 := tag.Payload.(Seq)
+		if !ok || !ok2 || label.V != "signature" || !ok3 || len(fields.Items) != 4 {
+			return nil, fmt.Errorf("signature %d must be Tag(\"signature\", Seq(signer, keyID, time, sig))", i)
+		}
+		signer, ok1 := fields.Items[0].(Text)
+		keyID, ok2 := fields.Items[1].(Bytes)
+		unix, ok3 := fields.Items[2].(Int)
+		sig, ok4 := fields.Items[3].(Bytes)
+		if !ok1 || !ok2 || !ok3 || !ok4 || len(keyID.V) != 8 || !unix.V.IsInt64() || len(sig.V) != ed25519.SignatureSize {
+			return nil, fmt.Errorf("signature %d is malformed", i)
+		}
+		sigs[i] = Signature{Signer: signer.V, KeyID: [8]byte(keyID.V), Time: time.Unix(unix.V.Int64(), 0).UTC(), Sig: sig.V}
+	}
+	return sigs, nil
+}
+
+type keyLine struct {
+	key    []byte
+	signer string
+}
+
+// keyLines parses the lines of a key or trust store file, each a hex key
+// of size bytes and a signer.
+func keyLines(data []byte, size int) ([]keyLine, error) {
+	var lines []keyLine
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		field, signer, _ := strings.Cut(line, " ")
+		key, err := hex.DecodeString(field)
+		if err != nil || len(key) != size {
+			return nil, fmt.Errorf("line %d: want a %d-byte hex key", n, size)
+		}
+		lines = append(lines, keyLine{key: key, signer: strings.TrimSpace(signer)})
+	}
+	return lines, sc.Err()
+}
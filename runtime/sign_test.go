@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignEmbeddedAndDetached(t *testing.T) {
+	// Any binary will do; this one holds a single X gate.
+	store := NewStore()
+	ep := putUnitary(store, pauliX())
+	alice, _ := GenerateSigningKey("alice")
+	mallory, _ := GenerateSigningKey("mallory")
+	when := time.Date(2026, 10, 16, 12, 0, 0, 500, time.UTC)
+
+	trust, err := ParseTrustStore(append([]byte("# accepted\n\n"), alice.MarshalPublic()...))
+	if err != nil || trust.Len() != 1 {
+		t.Fatalf("ParseTrustStore: %d keys, %v", trust.Len(), err)
+	}
+	key, err := ParseSigningKey(alice.Marshal())
+	if err != nil || key.Signer != "alice" || !key.Key.Equal(alice.Key) {
+		t.Fatalf("ParseSigningKey round trip: %v", err)
+	}
+
+	// Embedded: v2 signatures survive encoding and do not change the store.
+	bin := EmbedV2(store, ep, "signed", "1")
+	if _, err := trust.Verify(bin); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("unsigned binary: got %v", err)
+	}
+	unsigned := bin.Encode()
+	bin.AddSignature(bin.Sign(mallory, when))
+	bin.AddSignature(bin.Sign(alice, when))
+	decoded, err := Decode(bin.Encode())
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	s, err := trust.Verify(decoded)
+	if err != nil || s.Signer != "alice" || !s.Time.Equal(when.Truncate(time.Second)) {
+		t.Errorf("Verify = %+v, %v", s, err)
+	}
+	if decoded.StoreHash() != bin.StoreHash() || len(decoded.Encode()) <= len(unsigned) {
+		t.Error("embedding signatures changed the store hash")
+	}
+
+	// Detached: a v1 binary of the same store.
+	v1 := Embed(store, ep, "signed", "1")
+	if err := v1.AddSignature(v1.Sign(alice, when)); err == nil {
+		t.Error("v1 binary accepted an embedded signature")
+	}
+	sigs, err := DecodeSignatures(EncodeSignatures([]Signature{v1.Sign(alice, when)}))
+	if err != nil {
+		t.Fatalf("DecodeSignatures: %v", err)
+	}
+	if _, err := trust.Verify(v1, sigs...); err != nil {
+		t.Errorf("detached signature: %v", err)
+	}
+
+	// Tampering with the store, the entrypoint or the signer metadata, or
+	// signing with an untrusted key, leaves no valid signature.
+	tampered := *v1
+	tampered.StoreData = append(bytes.Clone(v1.StoreData), 0xF0)
+	moved := *v1
+	moved.Entrypoint[0] ^= 1
+	renamed := sigs[0]
+	renamed.Signer = "bob"
+	for name, tc := range map[string]struct {
+		bin *EmbeddedBinary
+		sig Signature
+	}{
+		"store":      {&tampered, sigs[0]},
+		"entrypoint": {&moved, sigs[0]},
+		"signer":     {v1, renamed},
+		"untrusted":  {v1, v1.Sign(mallory, when)},
+	} {
+		if _, err := trust.Verify(tc.bin, tc.sig); !errors.Is(err, ErrUntrusted) {
+			t.Errorf("%s: got %v, want ErrUntrusted", name, err)
+		}
+	}
+	if _, err := ParseTrustStore([]byte("abcd alice\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("short key: got %v", err)
+	}
+}
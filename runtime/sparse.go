@@ -0,0 +1,444 @@
+package runtime
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Sparse exact matrices.
+//
+// Permutation matrices, Paulis and expanded multi-qubit gates are almost
+// all zeros, but a dense Matrix still holds two big.Rat values for every
+// entry. SparseMatrix stores only the nonzero entries in compressed sparse
+// row (CSR) form, with its own kernels: SparseMatMul, SparseKronecker,
+// SparseDagger and SparseMatAdd between sparse matrices, and
+// SparseMulDense and SparseConjugate against a dense state.
+//
+// MatMul, Kronecker, Dagger and MatAdd stay dense and never inspect their
+// operands for sparsity. Instead the executor keeps sparse data sparse:
+// unitary and Kraus payloads written in the sparse encoding, and the swap
+// permutation, are applied to ρ in CSR form with SparseConjugate, and only
+// the ρ-shaped result is dense.
+//
+// MatrixToValue writes a sparse matrix in the compact form
+//
+//	Tag("matrix-sparse", Seq(Int rows, Int cols,
+//	    Seq(Int rowptr_0, ..., Int rowptr_rows),
+//	    Seq(Int col_0, ..., Int col_{nnz-1}),
+//	    Seq(Rat re_0, Rat im_0, ..., Rat re_{nnz-1}, Rat im_{nnz-1})))
+//
+// with column indices strictly increasing within each row. The choice of
+// encoding depends only on the matrix, so every matrix still has exactly
+// one encoding and one QGID. Matrices below sparseMinEntries entries are
+// always written densely and keep the QGIDs they had before sparse
+// encoding existed. Larger sparse matrices do not: their QGIDs, and those
+// of the circuits holding them, changed when this encoding was introduced.
+// Stores written before still decode and run, since both encodings are
+// read everywhere, but re-synthesizing such a circuit yields a new QGID.
+
+const (
+	sparseLabel = "matrix-sparse"
+
+	// sparseMinEntries is the smallest matrix (rows·cols) treated as sparse.
+	sparseMinEntries = 64
+
+	// sparseMaxDensity: a matrix is sparse when at most 1/sparseMaxDensity
+	// of its entries are nonzero.
+	sparseMaxDensity = 4
+)
+
+// SparseMatrix is a matrix over Gaussian rationals in CSR form. Row i
+// holds the entries Vals[RowPtr[i]:RowPtr[i+1]] in columns
+// ColIdx[RowPtr[i]:RowPtr[i+1]], in increasing column order. Zeros are
+// never stored.
+type SparseMatrix struct {
+	Rows   int
+	Cols   int
+	RowPtr []int
+	ColIdx []int
+	Vals   []QI
+}
+
+// NNZ returns the number of stored (nonzero) entries.
+func (s *SparseMatrix) NNZ() int {
+	return len(s.Vals)
+}
+
+// Get returns the element at (i, j).
+func (s *SparseMatrix) Get(i, j int) QI {
+	lo, hi := s.RowPtr[i], s.RowPtr[i+1]
+	p := lo + sort.SearchInts(s.ColIdx[lo:hi], j)
+	if p < hi && s.ColIdx[p] == j {
+		return s.Vals[p]
+	}
+	return QIZero()
+}
+
+// ToSparse converts a dense matrix to CSR form.
+func ToSparse(A *Matrix) *SparseMatrix {
+	s := &SparseMatrix{Rows: A.Rows, Cols: A.Cols, RowPtr: make([]int, A.Rows+1)}
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < A.Cols; j++ {
+			if q := A.Get(i, j); !QIIsZero(q) {
+				s.ColIdx = append(s.ColIdx, j)
+				s.Vals = append(s.Vals, q)
+			}
+		}
+		s.RowPtr[i+1] = len(s.Vals)
+	}
+	return s
+}
+
+// ToDense converts a sparse matrix to a dense Matrix.
+func (s *SparseMatrix) ToDense() *Matrix {
+	A := NewMatrix(s.Rows, s.Cols)
+	for i := 0; i < s.Rows; i++ {
+		for p := s.RowPtr[i]; p < s.RowPtr[i+1]; p++ {
+			A.Set(i, s.ColIdx[p], s.Vals[p])
+		}
+	}
+	return A
+}
+
+// SparseIdentity returns the n×n identity in CSR form.
+func SparseIdentity(n int) *SparseMatrix {
+	s := &SparseMatrix{Rows: n, Cols: n, RowPtr: make([]int, n+1), ColIdx: make([]int, n), Vals: make([]QI, n)}
+	for i := 0; i < n; i++ {
+		s.RowPtr[i+1] = i + 1
+		s.ColIdx[i] = i
+		s.Vals[i] = QIOne()
+	}
+	return s
+}
+
+// SparseMatMul computes A * B row by row, touching only products of
+// nonzero entries.
+func SparseMatMul(A, B *SparseMatrix) *SparseMatrix {
+	if A.Cols != B.Rows {
+		return nil
+	}
+	C := &SparseMatrix{Rows: A.Rows, Cols: B.Cols, RowPtr: make([]int, A.Rows+1)}
+	acc := make([]QI, B.Cols)
+	used := make([]bool, B.Cols)
+	var cols []int
+	for i := 0; i < A.Rows; i++ {
+		cols = cols[:0]
+		for p := A.RowPtr[i]; p < A.RowPtr[i+1]; p++ {
+			k, a := A.ColIdx[p], A.Vals[p]
+			for q := B.RowPtr[k]; q < B.RowPtr[k+1]; q++ {
+				j := B.ColIdx[q]
+				prod := QIMul(a, B.Vals[q])
+				if used[j] {
+					acc[j] = QIAdd(acc[j], prod)
+				} else {
+					used[j] = true
+					acc[j] = prod
+					cols = append(cols, j)
+				}
+			}
+		}
+		sort.Ints(cols)
+		for _, j := range cols {
+			if !QIIsZero(acc[j]) {
+				C.ColIdx = append(C.ColIdx, j)
+				C.Vals = append(C.Vals, acc[j])
+			}
+			used[j] = false
+		}
+		C.RowPtr[i+1] = len(C.Vals)
+	}
+	return C
+}
+
+// SparseMatAdd computes A + B.
+func SparseMatAdd(A, B *SparseMatrix) *SparseMatrix {
+	if A.Rows != B.Rows || A.Cols != B.Cols {
+		return nil
+	}
+	C := &SparseMatrix{Rows: A.Rows, Cols: A.Cols, RowPtr: make([]int, A.Rows+1)}
+	for i := 0; i < A.Rows; i++ {
+		p, pEnd := A.RowPtr[i], A.RowPtr[i+1]
+		q, qEnd := B.RowPtr[i], B.RowPtr[i+1]
+		for p < pEnd || q < qEnd {
+			var j int
+			var v QI
+			switch {
+			case q >= qEnd || (p < pEnd && A.ColIdx[p] < B.ColIdx[q]):
+				j, v = A.ColIdx[p], A.Vals[p]
+				p++
+			case p >= pEnd || B.ColIdx[q] < A.ColIdx[p]:
+				j, v = B.ColIdx[q], B.Vals[q]
+				q++
+			default:
+				j, v = A.ColIdx[p], QIAdd(A.Vals[p], B.Vals[q])
+				p++
+				q++
+			}
+			if !QIIsZero(v) {
+				C.ColIdx = append(C.ColIdx, j)
+				C.Vals = append(C.Vals, v)
+			}
+		}
+		C.RowPtr[i+1] = len(C.Vals)
+	}
+	return C
+}
+
+// SparseDagger computes the conjugate transpose.
+func SparseDagger(A *SparseMatrix) *SparseMatrix {
+	C := &SparseMatrix{
+		Rows:   A.Cols,
+		Cols:   A.Rows,
+		RowPtr: make([]int, A.Cols+1),
+		ColIdx: make([]int, A.NNZ()),
+		Vals:   make([]QI, A.NNZ()),
+	}
+	for _, j := range A.ColIdx {
+		C.RowPtr[j+1]++
+	}
+	for j := 0; j < A.Cols; j++ {
+		C.RowPtr[j+1] += C.RowPtr[j]
+	}
+	next := append([]int(nil), C.RowPtr[:A.Cols]...)
+	for i := 0; i < A.Rows; i++ {
+		for p := A.RowPtr[i]; p < A.RowPtr[i+1]; p++ {
+			j := A.ColIdx[p]
+			C.ColIdx[next[j]] = i
+			C.Vals[next[j]] = QIConj(A.Vals[p])
+			next[j]++
+		}
+	}
+	return C
+}
+
+// SparseKronecker computes the Kronecker product A ⊗ B.
+func SparseKronecker(A, B *SparseMatrix) *SparseMatrix {
+	C := &SparseMatrix{
+		Rows:   A.Rows * B.Rows,
+		Cols:   A.Cols * B.Cols,
+		RowPtr: make([]int, A.Rows*B.Rows+1),
+	}
+	for i := 0; i < A.Rows; i++ {
+		for k := 0; k < B.Rows; k++ {
+			for p := A.RowPtr[i]; p < A.RowPtr[i+1]; p++ {
+				a, col := A.Vals[p], A.ColIdx[p]*B.Cols
+				for q := B.RowPtr[k]; q < B.RowPtr[k+1]; q++ {
+					C.ColIdx = append(C.ColIdx, col+B.ColIdx[q])
+					C.Vals = append(C.Vals, QIMul(a, B.Vals[q]))
+				}
+			}
+			C.RowPtr[i*B.Rows+k+1] = len(C.Vals)
+		}
+	}
+	return C
+}
+
+// SparseMulDense computes A * B for sparse A and dense B, visiting only the
+// stored entries of A, or returns nil on a shape mismatch.
+func SparseMulDense(A *SparseMatrix, B *Matrix) *Matrix {
+	if A.Cols != B.Rows {
+		return nil
+	}
+	C := NewMatrix(A.Rows, B.Cols)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < B.Cols; j++ {
+			acc := QIZero()
+			for p := A.RowPtr[i]; p < A.RowPtr[i+1]; p++ {
+				if b := B.Get(A.ColIdx[p], j); !QIIsZero(b) {
+					acc = QIAdd(acc, QIMul(A.Vals[p], b))
+				}
+			}
+			C.Set(i, j, acc)
+		}
+	}
+	return C
+}
+
+// SparseConjugate computes K ρ K† for sparse K and a dense square ρ, or
+// returns nil on a shape mismatch. K is neither densified nor transposed,
+// and the products run over a common denominator (see conjugateSparseInt),
+// so a permutation costs O(n²) on an n×n state.
+func SparseConjugate(K *SparseMatrix, rho *Matrix) *Matrix {
+	out := conjugateSparseInt(K, ToIntMatrix(rho))
+	if out == nil {
+		return nil
+	}
+	return out.ToMatrix()
+}
+
+// isSparse reports whether MatrixToValue writes A in the sparse encoding:
+// it has at least sparseMinEntries entries, at most 1/sparseMaxDensity of
+// them nonzero.
+func isSparse(A *Matrix) bool {
+	n := len(A.Data)
+	if n < sparseMinEntries {
+		return false
+	}
+	limit := n / sparseMaxDensity
+	nnz := 0
+	for _, q := range A.Data {
+		if !QIIsZero(q) {
+			nnz++
+			if nnz > limit {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sparseEncoding reports whether a rows×cols matrix with nnz nonzero
+// entries is written as "matrix-sparse". It agrees with isSparse.
+func sparseEncoding(rows, cols, nnz int) bool {
+	n := rows * cols
+	return n >= sparseMinEntries && nnz <= n/sparseMaxDensity
+}
+
+// SparseMatrixToValue encodes s. Matrices that are not sparse enough get
+// the dense "matrix" encoding, so the result always equals
+// MatrixToValue(s.ToDense()).
+func SparseMatrixToValue(s *SparseMatrix) Value {
+	if !sparseEncoding(s.Rows, s.Cols, s.NNZ()) {
+		return denseMatrixToValue(s.ToDense())
+	}
+	rowPtr := make([]Value, len(s.RowPtr))
+	for i, p := range s.RowPtr {
+		rowPtr[i] = MakeInt(int64(p))
+	}
+	colIdx := make([]Value, len(s.ColIdx))
+	for i, j := range s.ColIdx {
+		colIdx[i] = MakeInt(int64(j))
+	}
+	vals := make([]Value, 0, 2*len(s.Vals))
+	for _, q := range s.Vals {
+		vals = append(vals, MakeBigRat(q.Re), MakeBigRat(q.Im))
+	}
+	return MakeTag(
+		MakeText(sparseLabel),
+		MakeSeq(
+			MakeInt(int64(s.Rows)),
+			MakeInt(int64(s.Cols)),
+			MakeSeq(rowPtr...),
+			MakeSeq(colIdx...),
+			MakeSeq(vals...),
+		),
+	)
+}
+
+// SparseMatrixFromValue parses a matrix in either the "matrix" or the
+// "matrix-sparse" encoding into CSR form.
+func SparseMatrixFromValue(v Value) (*SparseMatrix, bool) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return nil, false
+	}
+	label, ok := tag.Label.(Text)
+	if !ok {
+		return nil, false
+	}
+	switch label.V {
+	case "matrix":
+		m, ok := MatrixFromValue(v)
+		if !ok {
+			return nil, false
+		}
+		return ToSparse(m), true
+	case sparseLabel:
+		return sparseFromPayload(tag.Payload)
+	}
+	return nil, false
+}
+
+// sparseOperatorFromValue parses operator data written in the
+// "matrix-sparse" encoding. Data in the dense encoding is rejected rather
+// than converted: its encoding already says the matrix is not sparse.
+func sparseOperatorFromValue(v Value) (*SparseMatrix, bool) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return nil, false
+	}
+	if label, ok := tag.Label.(Text); !ok || label.V != sparseLabel {
+		return nil, false
+	}
+	return sparseFromPayload(tag.Payload)
+}
+
+// sparseFromPayload parses the payload of a "matrix-sparse" value. Row
+// pointers must be non-decreasing, columns strictly increasing within each
+// row and in range; explicit zeros are dropped.
+func sparseFromPayload(payload Value) (*SparseMatrix, bool) {
+	seq, ok := payload.(Seq)
+	if !ok || len(seq.Items) != 5 {
+		return nil, false
+	}
+	rows, ok := smallInt(seq.Items[0])
+	if !ok {
+		return nil, false
+	}
+	cols, ok := smallInt(seq.Items[1])
+	if !ok {
+		return nil, false
+	}
+	rowPtr, ok1 := seq.Items[2].(Seq)
+	colIdx, ok2 := seq.Items[3].(Seq)
+	vals, ok3 := seq.Items[4].(Seq)
+	if !ok1 || !ok2 || !ok3 || len(rowPtr.Items) != rows+1 || len(vals.Items) != 2*len(colIdx.Items) {
+		return nil, false
+	}
+
+	s := &SparseMatrix{Rows: rows, Cols: cols, RowPtr: make([]int, rows+1)}
+	if p, ok := smallInt(rowPtr.Items[0]); !ok || p != 0 {
+		return nil, false
+	}
+	for i := 0; i < rows; i++ {
+		end, ok := smallInt(rowPtr.Items[i+1])
+		start, _ := smallInt(rowPtr.Items[i])
+		if !ok || end < start || end > len(colIdx.Items) {
+			return nil, false
+		}
+		prev := -1
+		for p := start; p < end; p++ {
+			j, ok := smallInt(colIdx.Items[p])
+			if !ok || j <= prev || j >= cols {
+				return nil, false
+			}
+			prev = j
+			re, ok := vals.Items[2*p].(Rat)
+			if !ok {
+				return nil, false
+			}
+			im, ok := vals.Items[2*p+1].(Rat)
+			if !ok {
+				return nil, false
+			}
+			q := NewQI(ratOrZero(re.V), ratOrZero(im.V))
+			if QIIsZero(q) {
+				continue
+			}
+			s.ColIdx = append(s.ColIdx, j)
+			s.Vals = append(s.Vals, q)
+		}
+		s.RowPtr[i+1] = len(s.Vals)
+	}
+	if end, _ := smallInt(rowPtr.Items[rows]); end != len(colIdx.Items) {
+		return nil, false
+	}
+	return s, true
+}
+
+// smallInt reads a non-negative Int that fits in 31 bits.
+func smallInt(v Value) (int, bool) {
+	n, ok := v.(Int)
+	if !ok || n.V == nil || n.V.Sign() < 0 || n.V.BitLen() > 31 {
+		return 0, false
+	}
+	return int(n.V.Int64()), true
+}
+
+// ratOrZero returns r, or a fresh zero if r is nil.
+func ratOrZero(r *big.Rat) *big.Rat {
+	if r == nil {
+		return new(big.Rat)
+	}
+	return r
+}
@@ -0,0 +1,203 @@
+package runtime
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randSparse returns a random rows×cols matrix with about nnz nonzero
+// entries.
+func randSparse(r *rand.Rand, rows, cols, nnz int) *Matrix {
+	m := NewMatrix(rows, cols)
+	for k := 0; k < nnz; k++ {
+		m.Set(r.Intn(rows), r.Intn(cols), randQI(r))
+	}
+	return m
+}
+
+// refMatMul is the dense triple loop, independent of the IntMatrix kernels.
+func refMatMul(A, B *Matrix) *Matrix {
+	C := NewMatrix(A.Rows, B.Cols)
+	for i := 0; i < A.Rows; i++ {
+		for j := 0; j < B.Cols; j++ {
+			sum := QIZero()
+			for k := 0; k < A.Cols; k++ {
+				sum = QIAdd(sum, QIMul(A.Get(i, k), B.Get(k, j)))
+			}
+			C.Set(i, j, sum)
+		}
+	}
+	return C
+}
+
+// permutation returns the n×n matrix sending basis state i to perm[i].
+func permutation(perm []int) *Matrix {
+	P := NewMatrix(len(perm), len(perm))
+	for i, j := range perm {
+		P.Set(j, i, QIOne())
+	}
+	return P
+}
+
+func TestSparseKernels(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	for trial := 0; trial < 10; trial++ {
+		A := randSparse(r, 12, 10, 15)
+		B := randSparse(r, 10, 9, 12)
+		C := randSparse(r, 12, 10, 15)
+		if !isSparse(A) || !isSparse(B) {
+			t.Fatalf("trial %d: test matrices should be sparse", trial)
+		}
+
+		if !MatrixEqual(SparseMatMul(ToSparse(A), ToSparse(B)).ToDense(), refMatMul(A, B)) {
+			t.Fatalf("trial %d: SparseMatMul differs from dense product", trial)
+		}
+		D := randMatrix(r, 10, 3)
+		if !MatrixEqual(SparseMulDense(ToSparse(A), D), refMatMul(A, D)) {
+			t.Fatalf("trial %d: SparseMulDense differs from dense product", trial)
+		}
+		K := randSparse(r, 9, 10, 12)
+		rho := randMatrix(r, 10, 10)
+		if !MatrixEqual(SparseConjugate(ToSparse(K), rho), refMatMul(refMatMul(K, rho), Dagger(K))) {
+			t.Fatalf("trial %d: SparseConjugate differs from K ρ K†", trial)
+		}
+
+		sum := SparseMatAdd(ToSparse(A), ToSparse(C)).ToDense()
+		for i := range sum.Data {
+			if !QIEqual(sum.Data[i], QIAdd(A.Data[i], C.Data[i])) {
+				t.Fatalf("trial %d: SparseMatAdd differs at entry %d", trial, i)
+			}
+		}
+		// A - A drops every entry.
+		if s := SparseMatAdd(ToSparse(A), ToSparse(MatScale(A, big.NewRat(-1, 1)))); s.NNZ() != 0 {
+			t.Fatalf("trial %d: A + (-A) should have no stored entries, got %d", trial, s.NNZ())
+		}
+
+		dag := SparseDagger(ToSparse(A)).ToDense()
+		for i := 0; i < A.Rows; i++ {
+			for j := 0; j < A.Cols; j++ {
+				if !QIEqual(dag.Get(j, i), QIConj(A.Get(i, j))) {
+					t.Fatalf("trial %d: SparseDagger differs at (%d,%d)", trial, j, i)
+				}
+			}
+		}
+
+		E := randSparse(r, 3, 4, 3)
+		kron := SparseKronecker(ToSparse(A), ToSparse(E)).ToDense()
+		for i := 0; i < kron.Rows; i++ {
+			for j := 0; j < kron.Cols; j++ {
+				want := QIMul(A.Get(i/3, j/4), E.Get(i%3, j%4))
+				if !QIEqual(kron.Get(i, j), want) {
+					t.Fatalf("trial %d: SparseKronecker differs at (%d,%d)", trial, i, j)
+				}
+			}
+		}
+		if !MatrixEqual(Kronecker(A, E), kron) {
+			t.Fatalf("trial %d: SparseKronecker differs from Kronecker", trial)
+		}
+	}
+}
+
+func TestSparseEncoding(t *testing.T) {
+	r := rand.New(rand.NewSource(14))
+	P := permutation(r.Perm(64))
+
+	v := MatrixToValue(P)
+	if label := v.(Tag).Label.(Text).V; label != "matrix-sparse" {
+		t.Fatalf("64×64 permutation encoded as %q, want matrix-sparse", label)
+	}
+	if dense := denseMatrixToValue(P); len(v.Encode())*20 > len(dense.Encode()) {
+		t.Errorf("sparse encoding is %d bytes, dense %d; want at least 20× smaller",
+			len(v.Encode()), len(dense.Encode()))
+	}
+	back, ok := MatrixFromValue(v)
+	if !ok || !MatrixEqual(back, P) {
+		t.Fatal("sparse matrix should round-trip through MatrixFromValue")
+	}
+	s, ok := SparseMatrixFromValue(v)
+	if !ok || s.NNZ() != 64 || QGID(SparseMatrixToValue(s)) != QGID(v) {
+		t.Fatal("SparseMatrixFromValue should round-trip with the same QGID")
+	}
+
+	// Small and dense matrices keep the dense encoding and their QGIDs.
+	for _, m := range []*Matrix{pauliX(), cnotUnitary(), randMatrix(r, 8, 8)} {
+		if QGID(MatrixToValue(m)) != QGID(denseMatrixToValue(m)) {
+			t.Errorf("%dx%d matrix should keep the dense encoding", m.Rows, m.Cols)
+		}
+		if QGID(SparseMatrixToValue(ToSparse(m))) != QGID(MatrixToValue(m)) {
+			t.Errorf("SparseMatrixToValue should agree with MatrixToValue on %dx%d", m.Rows, m.Cols)
+		}
+	}
+
+	// Columns out of order are rejected.
+	bad := MakeTag(MakeText("matrix-sparse"), MakeSeq(
+		MakeInt(1), MakeInt(2),
+		MakeSeq(MakeInt(0), MakeInt(2)),
+		MakeSeq(MakeInt(1), MakeInt(0)),
+		MakeSeq(MakeRat(1, 1), MakeRat(0, 1), MakeRat(1, 1), MakeRat(0, 1)),
+	))
+	if _, ok := MatrixFromValue(bad); ok {
+		t.Error("unsorted column indices should be rejected")
+	}
+
+	// A 6-qubit permutation circuit survives a .qmb round trip.
+	store := NewStore()
+	id := putUnitary(store, P)
+	runner, err := NewRunner(Embed(store, id, "perm", "1").Encode())
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	rho := NewMatrix(64, 64)
+	rho.Set(5, 5, QIOne())
+	out, err := runner.Run(rho)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !MatrixEqual(out, refMatMul(refMatMul(P, rho), Dagger(P))) {
+		t.Error("sparse unitary should execute like its dense form")
+	}
+}
+
+// TestSparseKrausExecution runs a Kraus family mixing sparse-encoded and
+// dense operators, and an instrument built from it.
+func TestSparseKrausExecution(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	P := permutation(r.Perm(8))
+	D := randMatrix(r, 8, 8)
+	ops := []*Matrix{MatScale(P, big.NewRat(3, 5)), MatScale(D, big.NewRat(1, 7))}
+	data := KrausToValue(ops)
+	if label := data.(Tag).Payload.(Seq).Items[0].(Tag).Label.(Text).V; label != sparseLabel {
+		t.Fatalf("scaled permutation encoded as %q, want %s", label, sparseLabel)
+	}
+
+	rho := randDensity(r, 8)
+	want := MatAdd(refMatMul(refMatMul(ops[0], rho), Dagger(ops[0])),
+		refMatMul(refMatMul(ops[1], rho), Dagger(ops[1])))
+	q8 := QuantumObject(8)
+	store := NewStore()
+	kraus := store.Put(Circuit{Domain: q8, Codomain: q8, Prim: PrimKraus, Data: data})
+	inst := store.Put(Circuit{Domain: q8, Codomain: Object{Blocks: []uint32{8, 8}}, Prim: PrimInstrument,
+		Data: InstrumentToValue([][]*Matrix{ops[:1], ops[1:]})})
+	exec := NewExecutor(store)
+
+	c, _ := store.Get(kraus)
+	got, err := exec.Execute(c, rho)
+	if err != nil || !MatrixEqual(got, want) {
+		t.Fatalf("mixed sparse/dense Kraus family differs from Σ K ρ K†: %v", err)
+	}
+	c, _ = store.Get(inst)
+	got, err = exec.Execute(c, rho)
+	if err != nil {
+		t.Fatalf("instrument: %v", err)
+	}
+	top := NewMatrix(8, 8)
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			top.Set(i, j, got.Get(i, j))
+		}
+	}
+	if !MatrixEqual(top, refMatMul(refMatMul(ops[0], rho), Dagger(ops[0]))) {
+		t.Error("sparse instrument outcome differs from K ρ K†")
+	}
+}
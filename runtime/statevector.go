@@ -0,0 +1,296 @@
+package runtime
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Statevector execution.
+//
+// Execute works on density matrices, so a gate on a d-dimensional system
+// costs O(d³) operations on d² exact entries. A circuit built only from Id,
+// Unitary, Prepare (of a pure state), Scale, Compose and Tensor maps pure
+// states to pure states, and on a rank-1 input it can run on a
+// d-dimensional ket instead. A pure state is kept as a pair (v, w) with
+//
+//	ρ = w · v v†
+//
+// where the rational weight w absorbs the normalization of the input, of
+// every prepared state and of every Scale, such as the 1/2 of a Hadamard
+// written as Scale(1/2, Unitary([[1,1],[1,-1]])). No square roots are
+// taken, so the result is exactly the matrix Execute returns.
+//
+// ExecuteStatevector factors a rank-1 input, evolves the ket through the
+// pure part of the circuit, and falls back to density matrices at the first
+// other primitive: a Compose runs its pure prefix on the ket and every later
+// child on ρ = w·v v†. Inputs that are not rank-1 go straight to Execute.
+
+// ExecuteStatevector executes c on input, evolving rank-1 inputs as kets
+// through the unitary part of the circuit.
+func (e *Executor) ExecuteStatevector(c Circuit, input *Matrix) (*Matrix, error) {
+	v, w, ok := pureFromDensity(input)
+	if !ok {
+		return e.Execute(c, input)
+	}
+	sv := &statevector{e: e, pure: make(map[[32]byte]bool)}
+	return sv.run(c, v, w)
+}
+
+// statevector holds the state of one ExecuteStatevector call.
+type statevector struct {
+	e    *Executor
+	pure map[[32]byte]bool // memoized isPure by QGID
+}
+
+// run executes c on ρ = w·v v† and returns the output density matrix.
+func (sv *statevector) run(c Circuit, v *Matrix, w *big.Rat) (*Matrix, error) {
+	if sv.isPure(c) {
+		out, wc, err := sv.evolve(c, v, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return pureDensity(out, new(big.Rat).Mul(w, wc)), nil
+	}
+	if c.Prim != PrimCompose || len(c.Children) < 2 {
+		return sv.e.Execute(c, pureDensity(v, w))
+	}
+
+	// Run the pure prefix on the ket and the rest on density matrices.
+	var rho *Matrix
+	for i, childID := range c.Children {
+		child, ok := sv.e.store.Get(childID)
+		if !ok {
+			return nil, fmt.Errorf("child %d not found", i)
+		}
+		var err error
+		switch {
+		case rho != nil:
+			rho, err = sv.e.Execute(child, rho)
+		case sv.isPureID(childID, child):
+			var wc *big.Rat
+			v, wc, err = sv.evolve(child, v, 1, 1)
+			if err == nil {
+				w = new(big.Rat).Mul(w, wc)
+			}
+		default:
+			rho, err = sv.run(child, v, w)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rho == nil {
+		return pureDensity(v, w), nil
+	}
+	return rho, nil
+}
+
+// isPure reports whether c maps pure states to pure states using only the
+// primitives evolve understands.
+func (sv *statevector) isPure(c Circuit) bool {
+	switch c.Prim {
+	case PrimId:
+		return true
+	case PrimUnitary:
+		_, ok := SparseMatrixFromValue(c.Data)
+		return ok
+	case PrimPrepare:
+		rho, ok := MatrixFromValue(c.Data)
+		if !ok {
+			return false
+		}
+		_, _, ok = pureFromDensity(rho)
+		return ok
+	case PrimScale:
+		if _, ok := c.Data.(Rat); !ok || len(c.Children) != 1 {
+			return false
+		}
+		child, ok := sv.e.store.Get(c.Children[0])
+		return ok && sv.isPureID(c.Children[0], child)
+	case PrimCompose, PrimTensor:
+		if len(c.Children) < 2 {
+			return false
+		}
+		for _, childID := range c.Children {
+			child, ok := sv.e.store.Get(childID)
+			if !ok || !sv.isPureID(childID, child) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isPureID is isPure memoized by the circuit's QGID.
+func (sv *statevector) isPureID(id [32]byte, c Circuit) bool {
+	if p, ok := sv.pure[id]; ok {
+		return p
+	}
+	p := sv.isPure(c)
+	sv.pure[id] = p
+	return p
+}
+
+// evolve applies id_pre ⊗ c ⊗ id_post to the ket v on C^pre ⊗ C^d ⊗ C^post,
+// where c satisfies isPure. It returns the new ket and the weight c
+// contributes.
+func (sv *statevector) evolve(c Circuit, v *Matrix, pre, post int) (*Matrix, *big.Rat, error) {
+	switch c.Prim {
+	case PrimId:
+		return v, big.NewRat(1, 1), nil
+
+	case PrimUnitary:
+		U, ok := SparseMatrixFromValue(c.Data)
+		if !ok {
+			return nil, nil, fmt.Errorf("unitary data must be matrix")
+		}
+		out, err := applyKetFactor(U, v, pre, post)
+		return out, big.NewRat(1, 1), err
+
+	case PrimPrepare:
+		rho, ok := MatrixFromValue(c.Data)
+		if !ok {
+			return nil, nil, fmt.Errorf("prepare data must be matrix")
+		}
+		phi, wp, ok := pureFromDensity(rho)
+		if !ok {
+			return nil, nil, fmt.Errorf("statevector: prepared state is not pure")
+		}
+		out, err := applyKetFactor(ToSparse(phi), v, pre, post)
+		return out, wp, err
+
+	case PrimScale:
+		child, ok := sv.e.store.Get(c.Children[0])
+		if !ok {
+			return nil, nil, fmt.Errorf("child not found")
+		}
+		out, wc, err := sv.evolve(child, v, pre, post)
+		if err != nil {
+			return nil, nil, err
+		}
+		return out, new(big.Rat).Mul(wc, c.Data.(Rat).V), nil
+
+	case PrimCompose:
+		w := big.NewRat(1, 1)
+		for i, childID := range c.Children {
+			child, ok := sv.e.store.Get(childID)
+			if !ok {
+				return nil, nil, fmt.Errorf("child %d not found", i)
+			}
+			next, wc, err := sv.evolve(child, v, pre, post)
+			if err != nil {
+				return nil, nil, err
+			}
+			v = next
+			w.Mul(w, wc)
+		}
+		return v, w, nil
+
+	case PrimTensor:
+		// Children act in turn on their own factor, as in applyTensor.
+		children := make([]Circuit, len(c.Children))
+		dims := make([]int, len(c.Children))
+		for i, childID := range c.Children {
+			child, ok := sv.e.store.Get(childID)
+			if !ok {
+				return nil, nil, fmt.Errorf("child %d not found", i)
+			}
+			children[i] = child
+			dims[i] = BlockDim(child.Domain)
+		}
+		w := big.NewRat(1, 1)
+		for k, child := range children {
+			kpre := pre
+			for _, d := range dims[:k] {
+				kpre *= d
+			}
+			kpost := post
+			for _, d := range dims[k+1:] {
+				kpost *= d
+			}
+			next, wc, err := sv.evolve(child, v, kpre, kpost)
+			if err != nil {
+				return nil, nil, err
+			}
+			dims[k] = next.Rows / (kpre * kpost)
+			v = next
+			w.Mul(w, wc)
+		}
+		return v, w, nil
+	}
+	return nil, nil, fmt.Errorf("statevector: unsupported primitive %s", PrimName(c.Prim))
+}
+
+// applyKetFactor computes (I_pre ⊗ A ⊗ I_post) v for a column vector v of
+// length pre·A.Cols·post. Only the stored entries of A are visited, which
+// keeps permutation-like gates such as CNOT linear in the ket length.
+func applyKetFactor(A *SparseMatrix, v *Matrix, pre, post int) (*Matrix, error) {
+	d, e := A.Cols, A.Rows
+	if v.Cols != 1 || v.Rows != pre*d*post {
+		return nil, fmt.Errorf("statevector: %dx%d operator does not fit a ket of length %d on %d⊗%d⊗%d",
+			e, d, v.Rows, pre, d, post)
+	}
+	out := NewMatrix(pre*e*post, 1)
+	for a := 0; a < pre; a++ {
+		for p := 0; p < post; p++ {
+			for i := 0; i < e; i++ {
+				acc := QIZero()
+				for q := A.RowPtr[i]; q < A.RowPtr[i+1]; q++ {
+					x := v.Get((a*d+A.ColIdx[q])*post+p, 0)
+					if !QIIsZero(x) {
+						acc = QIAdd(acc, QIMul(A.Vals[q], x))
+					}
+				}
+				out.Set((a*e+i)*post+p, 0, acc)
+			}
+		}
+	}
+	return out, nil
+}
+
+// pureFromDensity factors a rank-1 positive matrix as ρ = w · v v† with
+// w > 0 rational. v is the first column of ρ with a nonzero diagonal entry
+// and w is the inverse of that entry. It returns false for the zero matrix
+// and for anything that is not exactly rank-1 positive.
+func pureFromDensity(rho *Matrix) (*Matrix, *big.Rat, bool) {
+	if rho == nil || rho.Rows != rho.Cols || rho.Rows == 0 {
+		return nil, nil, false
+	}
+	n := rho.Rows
+	col := -1
+	for j := 0; j < n; j++ {
+		if !QIIsZero(rho.Get(j, j)) {
+			col = j
+			break
+		}
+	}
+	if col < 0 {
+		return nil, nil, false
+	}
+	pivot := rho.Get(col, col)
+	if pivot.Im.Sign() != 0 || pivot.Re.Sign() <= 0 {
+		return nil, nil, false
+	}
+	w := new(big.Rat).Inv(pivot.Re)
+
+	v := NewMatrix(n, 1)
+	for i := 0; i < n; i++ {
+		v.Set(i, 0, rho.Get(i, col))
+	}
+	wq := NewQI(w, new(big.Rat))
+	for i := 0; i < n; i++ {
+		vi := QIMul(wq, v.Get(i, 0))
+		for k := 0; k < n; k++ {
+			if !QIEqual(rho.Get(i, k), QIMul(vi, QIConj(v.Get(k, 0)))) {
+				return nil, nil, false
+			}
+		}
+	}
+	return v, w, true
+}
+
+// pureDensity returns w · v v†.
+func pureDensity(v *Matrix, w *big.Rat) *Matrix {
+	return MatScale(OuterProduct(v, v), w)
+}
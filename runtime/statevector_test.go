@@ -0,0 +1,241 @@
+package runtime
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randKet returns a random nonzero n×1 column vector.
+func randKet(r *rand.Rand, n int) *Matrix {
+	for {
+		v := randMatrix(r, n, 1)
+		for i := 0; i < n; i++ {
+			if !QIIsZero(v.Get(i, 0)) {
+				return v
+			}
+		}
+	}
+}
+
+// putUnitary stores a Unitary circuit on Q(n) with data U. The executor
+// never checks unitarity, so random matrices exercise the same code paths.
+func putUnitary(store *Store, U *Matrix) [32]byte {
+	obj := QuantumObject(U.Rows)
+	return store.Put(Circuit{Domain: obj, Codomain: obj, Prim: PrimUnitary, Data: MatrixToValue(U)})
+}
+
+func TestPureFromDensity(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	for trial := 0; trial < 10; trial++ {
+		v := randKet(r, 4)
+		rho := MatScale(OuterProduct(v, v), big.NewRat(3, 7))
+		u, w, ok := pureFromDensity(rho)
+		if !ok {
+			t.Fatalf("trial %d: rank-1 matrix not recognised", trial)
+		}
+		if !MatrixEqual(pureDensity(u, w), rho) {
+			t.Fatalf("trial %d: w·u u† != ρ", trial)
+		}
+	}
+
+	mixed := MatAdd(ket0bra0(), MatScale(ket1bra1(), big.NewRat(1, 2)))
+	if _, _, ok := pureFromDensity(mixed); ok {
+		t.Error("rank-2 matrix should not factor as a pure state")
+	}
+	if _, _, ok := pureFromDensity(NewMatrix(2, 2)); ok {
+		t.Error("zero matrix should not factor as a pure state")
+	}
+	if _, _, ok := pureFromDensity(MatScale(ket0bra0(), big.NewRat(-1, 1))); ok {
+		t.Error("negative matrix should not factor as a pure state")
+	}
+}
+
+func TestStatevectorMatchesDensity(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	qutrit := QuantumObject(3)
+	joint := TensorObjects(qubit(), qutrit, qubit())
+
+	for trial := 0; trial < 5; trial++ {
+		store := NewStore()
+		exec := NewExecutor(store)
+
+		phi := randKet(r, 2)
+		prep := store.Put(Circuit{Domain: unitObject(), Codomain: qubit(), Prim: PrimPrepare, Data: MatrixToValue(OuterProduct(phi, phi))})
+		a := putUnitary(store, randMatrix(r, 2, 2))
+		b := putUnitary(store, randMatrix(r, 3, 3))
+		id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+
+		// Q(2)⊗Q(3) → Q(2)⊗Q(3)⊗Q(2): (A ⊗ B ⊗ prepare), then a joint gate.
+		layer := store.Put(Circuit{
+			Domain:   tensorObject(qubit(), qutrit),
+			Codomain: joint,
+			Prim:     PrimTensor,
+			Children: [][32]byte{a, b, prep},
+		})
+		inner := store.Put(Circuit{
+			Domain:   joint,
+			Codomain: joint,
+			Prim:     PrimTensor,
+			Children: [][32]byte{id, store.Put(Circuit{
+				Domain:   tensorObject(qutrit, qubit()),
+				Codomain: tensorObject(qutrit, qubit()),
+				Prim:     PrimCompose,
+				Children: [][32]byte{putUnitary(store, randMatrix(r, 6, 6)), putUnitary(store, randMatrix(r, 6, 6))},
+			})},
+		})
+		root, _ := store.Get(store.Put(Circuit{
+			Domain:   tensorObject(qubit(), qutrit),
+			Codomain: joint,
+			Prim:     PrimCompose,
+			Children: [][32]byte{layer, inner, putUnitary(store, randMatrix(r, 12, 12))},
+		}))
+
+		v := randKet(r, 6)
+		rho := MatScale(OuterProduct(v, v), big.NewRat(2, 5))
+		want, err := exec.Execute(root, rho)
+		if err != nil {
+			t.Fatalf("trial %d: Execute failed: %v", trial, err)
+		}
+		got, err := exec.ExecuteStatevector(root, rho)
+		if err != nil {
+			t.Fatalf("trial %d: ExecuteStatevector failed: %v", trial, err)
+		}
+		if !MatrixEqual(got, want) {
+			t.Errorf("trial %d: statevector result differs from density result", trial)
+		}
+	}
+}
+
+// TestStatevectorScale runs the exact Hadamard, Scale(1/2, Unitary(H')),
+// on a factor of a two-qubit ket.
+func TestStatevectorScale(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+	h := store.Put(Circuit{
+		Domain:   qubit(),
+		Codomain: qubit(),
+		Prim:     PrimScale,
+		Data:     MakeRat(1, 2),
+		Children: [][32]byte{putUnitary(store, hadamardUnnorm())},
+	})
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	root := Circuit{
+		Domain:   tensorObject(qubit(), qubit()),
+		Codomain: tensorObject(qubit(), qubit()),
+		Prim:     PrimTensor,
+		Children: [][32]byte{h, id},
+	}
+	sv := &statevector{e: exec, pure: make(map[[32]byte]bool)}
+	if !sv.isPure(root) {
+		t.Fatal("Scale over a unitary should run on kets")
+	}
+
+	// |00> -> |+0>, with every entry of the density matrix 0 or 1/2.
+	rho := NewMatrix(4, 4)
+	rho.Set(0, 0, QIOne())
+	got, err := exec.ExecuteStatevector(root, rho)
+	if err != nil {
+		t.Fatalf("ExecuteStatevector failed: %v", err)
+	}
+	want := NewMatrix(4, 4)
+	for _, i := range []int{0, 2} {
+		for _, j := range []int{0, 2} {
+			want.Set(i, j, NewQI(big.NewRat(1, 2), new(big.Rat)))
+		}
+	}
+	if !MatrixEqual(got, want) {
+		t.Errorf("H on the first qubit of |00> = %v, want |+0><+0|", got)
+	}
+}
+
+func TestStatevectorFallback(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	u1 := putUnitary(store, randMatrix(r, 2, 2))
+	noise := putKraus(store, randKraus(r, 2, 2, 2))
+	u2 := putUnitary(store, randMatrix(r, 2, 2))
+	nested := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{u1, noise}})
+	root, _ := store.Get(store.Put(Circuit{
+		Domain:   qubit(),
+		Codomain: qubit(),
+		Prim:     PrimCompose,
+		Children: [][32]byte{u1, nested, u2},
+	}))
+
+	v := randKet(r, 2)
+	for _, rho := range []*Matrix{OuterProduct(v, v), randDensity(r, 2)} {
+		want, err := exec.Execute(root, rho)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		got, err := exec.ExecuteStatevector(root, rho)
+		if err != nil {
+			t.Fatalf("ExecuteStatevector failed: %v", err)
+		}
+		if !MatrixEqual(got, want) {
+			t.Error("statevector fallback differs from density result")
+		}
+	}
+}
+
+func TestRunnerWithStatevector(t *testing.T) {
+	store := NewStore()
+	n := 5
+	dim := 1 << n
+	obj := QuantumObject(dim)
+
+	// GHZ(5) up to normalization: [[1,1],[1,-1]] on qubit 0, then CNOTs
+	// from qubit 0 to every other qubit.
+	H := NewMatrix(2, 2)
+	H.Set(0, 0, QIOne())
+	H.Set(0, 1, QIOne())
+	H.Set(1, 0, QIOne())
+	H.Set(1, 1, QINeg(QIOne()))
+	children := [][32]byte{putUnitary(store, Kronecker(H, Identity(dim/2)))}
+	for target := 1; target < n; target++ {
+		P := NewMatrix(dim, dim)
+		for i := 0; i < dim; i++ {
+			j := i
+			if i&(1<<(n-1)) != 0 {
+				j = i ^ (1 << (n - 1 - target))
+			}
+			P.Set(j, i, QIOne())
+		}
+		children = append(children, putUnitary(store, P))
+	}
+	entry := store.Put(Circuit{Domain: obj, Codomain: obj, Prim: PrimCompose, Children: children})
+
+	bin := Embed(store, entry, "ghz", "1").Encode()
+	fast, err := NewRunner(bin, WithStatevector())
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+	slow, err := NewRunner(bin)
+	if err != nil {
+		t.Fatalf("NewRunner failed: %v", err)
+	}
+
+	input := NewMatrix(dim, dim)
+	input.Set(0, 0, QIOne())
+	got, err := fast.Run(input)
+	if err != nil {
+		t.Fatalf("statevector Run failed: %v", err)
+	}
+	want, err := slow.Run(input)
+	if err != nil {
+		t.Fatalf("density Run failed: %v", err)
+	}
+	if !MatrixEqual(got, want) {
+		t.Fatal("statevector runner differs from density runner")
+	}
+	for _, i := range []int{0, dim - 1} {
+		for _, j := range []int{0, dim - 1} {
+			if !QIEqual(got.Get(i, j), QIOne()) {
+				t.Errorf("GHZ entry (%d,%d) = %s%+si, want 1", i, j, got.Get(i, j).Re, got.Get(i, j).Im)
+			}
+		}
+	}
+}
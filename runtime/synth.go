@@ -48,12 +48,9 @@ func qubit() Object {
 	return Object{Blocks: []uint32{2}}
 }
 
-// tensorObject returns A ⊗ B by concatenating block lists.
+// tensorObject returns A ⊗ B.
 func tensorObject(a, b Object) Object {
-	blocks := make([]uint32, 0, len(a.Blocks)+len(b.Blocks))
-	blocks = append(blocks, a.Blocks...)
-	blocks = append(blocks, b.Blocks...)
-	return Object{Blocks: blocks}
+	return TensorObjects(a, b)
 }
 
 // isQubit checks if an object is Q(2).
@@ -61,9 +58,10 @@ func isQubit(o Object) bool {
 	return len(o.Blocks) == 1 && o.Blocks[0] == 2
 }
 
-// isTwoQubit checks if an object is Q(2) ⊗ Q(2).
+// isTwoQubit checks if an object is Q(2) ⊗ Q(2). The factors are
+// required: [2, 2] without them is Q(2) ⊕ Q(2), and [4] is Q(4).
 func isTwoQubit(o Object) bool {
-	return len(o.Blocks) == 2 && o.Blocks[0] == 2 && o.Blocks[1] == 2
+	return ObjectEqual(o, tensorObject(qubit(), qubit()))
 }
 
 // ---------------------------------------------------------------------------
@@ -457,12 +455,13 @@ func AllSynthesisRules() []SynthesisRule {
 // 5. Structural Rewrite Rules
 // ---------------------------------------------------------------------------
 
-// LeftIdentityRewrite rewrites Compose(Id, f) -> f.
+// LeftIdentityRewrite rewrites Compose(Id, f, ...) -> Compose(f, ...), or
+// -> f when one child remains.
 func LeftIdentityRewrite() RewriteRule {
 	return RewriteRule{
 		Name: "LeftIdentity",
 		Apply: func(c Circuit, store *Store) (Circuit, bool) {
-			if c.Prim != PrimCompose || len(c.Children) != 2 {
+			if c.Prim != PrimCompose || len(c.Children) < 2 {
 				return c, false
 			}
 			left, ok := store.Get(c.Children[0])
@@ -470,41 +469,52 @@ func LeftIdentityRewrite() RewriteRule {
 				return c, false
 			}
 			if left.Prim == PrimId {
-				right, ok := store.Get(c.Children[1])
-				if !ok {
-					return c, false
-				}
-				return right, true
+				return dropComposeChild(c, 0, store)
 			}
 			return c, false
 		},
 	}
 }
 
-// RightIdentityRewrite rewrites Compose(f, Id) -> f.
+// RightIdentityRewrite rewrites Compose(..., f, Id) -> Compose(..., f), or
+// -> f when one child remains.
 func RightIdentityRewrite() RewriteRule {
 	return RewriteRule{
 		Name: "RightIdentity",
 		Apply: func(c Circuit, store *Store) (Circuit, bool) {
-			if c.Prim != PrimCompose || len(c.Children) != 2 {
+			if c.Prim != PrimCompose || len(c.Children) < 2 {
 				return c, false
 			}
-			right, ok := store.Get(c.Children[1])
+			last := len(c.Children) - 1
+			right, ok := store.Get(c.Children[last])
 			if !ok {
 				return c, false
 			}
 			if right.Prim == PrimId {
-				left, ok := store.Get(c.Children[0])
-				if !ok {
-					return c, false
-				}
-				return left, true
+				return dropComposeChild(c, last, store)
 			}
 			return c, false
 		},
 	}
 }
 
+// dropComposeChild removes child i of a compose. A single remaining child
+// replaces the compose.
+func dropComposeChild(c Circuit, i int, store *Store) (Circuit, bool) {
+	if len(c.Children) == 2 {
+		rest, ok := store.Get(c.Children[1-i])
+		if !ok {
+			return c, false
+		}
+		return rest, true
+	}
+	children := make([][32]byte, 0, len(c.Children)-1)
+	children = append(children, c.Children[:i]...)
+	children = append(children, c.Children[i+1:]...)
+	c.Children = children
+	return c, true
+}
+
 // SwapInvolutionRewrite rewrites Compose(Swap, Swap) -> Id.
 func SwapInvolutionRewrite() RewriteRule {
 	return RewriteRule{
@@ -534,42 +544,100 @@ func SwapInvolutionRewrite() RewriteRule {
 	}
 }
 
-// TensorIdentityRewrite rewrites Tensor(Id_A, Id_B) -> Id_{A ⊗ B}.
+// TensorIdentityRewrite rewrites Tensor(Id_A, Id_B, ...) -> Id_{A ⊗ B ⊗ ...}.
 func TensorIdentityRewrite() RewriteRule {
 	return RewriteRule{
 		Name: "TensorIdentity",
 		Apply: func(c Circuit, store *Store) (Circuit, bool) {
-			if c.Prim != PrimTensor || len(c.Children) != 2 {
+			if c.Prim != PrimTensor || len(c.Children) < 2 {
 				return c, false
 			}
-			left, ok := store.Get(c.Children[0])
-			if !ok {
-				return c, false
-			}
-			right, ok := store.Get(c.Children[1])
-			if !ok {
-				return c, false
-			}
-			if left.Prim == PrimId && right.Prim == PrimId {
-				combined := tensorObject(left.Domain, right.Domain)
-				return Circuit{
-					Domain:   combined,
-					Codomain: combined,
-					Prim:     PrimId,
-				}, true
+			domains := make([]Object, len(c.Children))
+			for i, childID := range c.Children {
+				child, ok := store.Get(childID)
+				if !ok || child.Prim != PrimId {
+					return c, false
+				}
+				domains[i] = child.Domain
 			}
-			return c, false
+			combined := TensorObjects(domains...)
+			return Circuit{
+				Domain:   combined,
+				Codomain: combined,
+				Prim:     PrimId,
+			}, true
 		},
 	}
 }
 
-// AllRewriteRules returns all 4 structural rewrite rules.
+// FlattenComposeRewrite splices nested composes into their parent:
+// Compose(Compose(f, g), h) and Compose(f, Compose(g, h)) both become
+// Compose(f, g, h). Only children without data are spliced, since their
+// data would otherwise be lost.
+func FlattenComposeRewrite() RewriteRule {
+	return RewriteRule{
+		Name: "FlattenCompose",
+		Apply: func(c Circuit, store *Store) (Circuit, bool) {
+			return flattenChildren(c, PrimCompose, store)
+		},
+	}
+}
+
+// FlattenTensorRewrite splices nested tensors into their parent:
+// Tensor(Tensor(f, g), h) and Tensor(f, Tensor(g, h)) both become
+// Tensor(f, g, h). Only children without data are spliced. The domain and
+// codomain are unchanged because TensorObjects is associative.
+func FlattenTensorRewrite() RewriteRule {
+	return RewriteRule{
+		Name: "FlattenTensor",
+		Apply: func(c Circuit, store *Store) (Circuit, bool) {
+			return flattenChildren(c, PrimTensor, store)
+		},
+	}
+}
+
+// flattenChildren replaces every data-free child of c with primitive prim
+// by that child's own children.
+func flattenChildren(c Circuit, prim Prim, store *Store) (Circuit, bool) {
+	if c.Prim != prim {
+		return c, false
+	}
+	var children [][32]byte
+	changed := false
+	for _, childID := range c.Children {
+		child, ok := store.Get(childID)
+		if ok && child.Prim == prim && isNilData(child.Data) && len(child.Children) >= 2 {
+			children = append(children, child.Children...)
+			changed = true
+			continue
+		}
+		children = append(children, childID)
+	}
+	if !changed {
+		return c, false
+	}
+	c.Children = children
+	return c, true
+}
+
+// isNilData reports whether circuit data is absent.
+func isNilData(v Value) bool {
+	if v == nil {
+		return true
+	}
+	_, ok := v.(Nil)
+	return ok
+}
+
+// AllRewriteRules returns all 6 structural rewrite rules.
 func AllRewriteRules() []RewriteRule {
 	return []RewriteRule{
 		LeftIdentityRewrite(),
 		RightIdentityRewrite(),
 		SwapInvolutionRewrite(),
 		TensorIdentityRewrite(),
+		FlattenComposeRewrite(),
+		FlattenTensorRewrite(),
 	}
 }
 
@@ -696,27 +764,16 @@ func Bootstrap() (v1 []byte, v2 []byte, fixpoint bool, log []string) {
 	logf("v1 size: %d bytes, entrypoint=%x", len(v1), composedID[:8])
 
 	// ---- Step 2: build v2 (normalized = clean toolchain only) ----
-	// We normalize the redundant composition conceptually: Compose(tc, Id) -> tc.
-	// Then we embed the clean result into a fresh store to ensure no leftover entries.
+	// Normalize the redundant composition, Compose(tc, Id) -> tc, in the v1
+	// store, then prune what the normalized circuit no longer reaches.
 	logf("step 2: building v2 with normalization")
 
-	// Build the composed circuit in a temporary store to normalize it.
-	tmpStore := NewStore()
-	tmpTCID := BuildToolchain(tmpStore)
-	tmpIdC := Circuit{Domain: unitObject(), Codomain: unitObject(), Prim: PrimId}
-	tmpIdID := tmpStore.Put(tmpIdC)
-	tmpComposed := Circuit{
-		Domain: unitObject(), Codomain: unitObject(),
-		Prim:     PrimCompose,
-		Children: [][32]byte{tmpTCID, tmpIdID},
-	}
-	normalized, didRewrite := NormalizeCircuit(tmpComposed, tmpStore)
+	normalized, didRewrite := NormalizeCircuit(composed, store1)
 	logf("normalization applied: %v", didRewrite)
 
-	// Now put the normalized circuit into a CLEAN store.
-	store2 := NewStore()
-	normalizedID := store2.Put(normalized)
-	bin2 := Embed(store2, normalizedID, "qbtm-synth", "1.0.0")
+	normalizedID := store1.Put(normalized)
+	logf("pruned %d unreachable entries", store1.Prune(normalizedID))
+	bin2 := Embed(store1, normalizedID, "qbtm-synth", "1.0.0")
 	v2 = bin2.Encode()
 	logf("v2 size: %d bytes, entrypoint=%x", len(v2), normalizedID[:8])
 
@@ -524,6 +524,90 @@ func TestTensorIdentityRewrite(t *testing.T) {
 	}
 }
 
+func TestLeftIdentityRewriteNary(t *testing.T) {
+	store := NewStore()
+	idID := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	xID := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixToValue(pauliX())})
+
+	comp := Circuit{
+		Domain:   qubit(),
+		Codomain: qubit(),
+		Prim:     PrimCompose,
+		Children: [][32]byte{idID, xID, xID},
+	}
+
+	result, changed := LeftIdentityRewrite().Apply(comp, store)
+	if !changed {
+		t.Fatal("LeftIdentity should apply")
+	}
+	if result.Prim != PrimCompose || len(result.Children) != 2 {
+		t.Errorf("expected Compose(X, X), got %v with %d children", result.Prim, len(result.Children))
+	}
+}
+
+func TestFlattenComposeRewrite(t *testing.T) {
+	store := NewStore()
+	xID := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixToValue(pauliX())})
+	zID := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixToValue(pauliZ())})
+	yID := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixToValue(pauliY())})
+
+	compose := func(data Value, children ...[32]byte) Circuit {
+		return Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Data: data, Children: children}
+	}
+
+	// (X ; Z) ; Y and X ; (Z ; Y)
+	left := compose(nil, store.Put(compose(nil, xID, zID)), yID)
+	right := compose(nil, xID, store.Put(compose(MakeNil(), zID, yID)))
+
+	nl, _ := NormalizeCircuit(left, store)
+	nr, _ := NormalizeCircuit(right, store)
+	if len(nl.Children) != 3 {
+		t.Fatalf("expected 3 children after flattening, got %d", len(nl.Children))
+	}
+	if QGID(CircuitToValue(nl)) != QGID(CircuitToValue(nr)) {
+		t.Error("both bracketings should normalize to the same QGID")
+	}
+
+	// A nested compose with data is kept.
+	tagged := compose(nil, store.Put(compose(MakeText("stage"), xID, zID)), yID)
+	if _, changed := FlattenComposeRewrite().Apply(tagged, store); changed {
+		t.Error("FlattenCompose should not splice a child with data")
+	}
+}
+
+func TestFlattenTensorRewrite(t *testing.T) {
+	store := NewStore()
+	xID := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixToValue(pauliX())})
+	q3 := Object{Blocks: []uint32{3}}
+	idID := store.Put(Circuit{Domain: q3, Codomain: q3, Prim: PrimId})
+
+	tensor := func(children ...[32]byte) Circuit {
+		objs := make([]Object, len(children))
+		for i, id := range children {
+			c, _ := store.Get(id)
+			objs[i] = c.Domain
+		}
+		dom := TensorObjects(objs...)
+		return Circuit{Domain: dom, Codomain: dom, Prim: PrimTensor, Children: children}
+	}
+
+	// (X ⊗ Id_3) ⊗ X and X ⊗ (Id_3 ⊗ X)
+	left := tensor(store.Put(tensor(xID, idID)), xID)
+	right := tensor(xID, store.Put(tensor(idID, xID)))
+	if !ObjectEqual(left.Domain, right.Domain) {
+		t.Fatal("bracketings should have the same domain")
+	}
+
+	nl, _ := NormalizeCircuit(left, store)
+	nr, _ := NormalizeCircuit(right, store)
+	if len(nl.Children) != 3 {
+		t.Fatalf("expected 3 children after flattening, got %d", len(nl.Children))
+	}
+	if QGID(CircuitToValue(nl)) != QGID(CircuitToValue(nr)) {
+		t.Error("both bracketings should normalize to the same QGID")
+	}
+}
+
 func TestRewriteNoMatchOnNonCompose(t *testing.T) {
 	store := NewStore()
 	c := Circuit{Prim: PrimId, Domain: qubit(), Codomain: qubit()}
@@ -687,8 +771,8 @@ func TestAllSynthesisRulesCount(t *testing.T) {
 
 func TestAllRewriteRulesCount(t *testing.T) {
 	rules := AllRewriteRules()
-	if len(rules) != 4 {
-		t.Errorf("expected 4 rewrite rules, got %d", len(rules))
+	if len(rules) != 6 {
+		t.Errorf("expected 6 rewrite rules, got %d", len(rules))
 	}
 }
 
@@ -1001,8 +1085,11 @@ func TestTensorObject(t *testing.T) {
 	a := qubit()
 	b := Object{Blocks: []uint32{3}}
 	ab := tensorObject(a, b)
-	if len(ab.Blocks) != 2 || ab.Blocks[0] != 2 || ab.Blocks[1] != 3 {
-		t.Errorf("tensorObject = %v, want [2,3]", ab.Blocks)
+	if len(ab.Blocks) != 1 || ab.Blocks[0] != 6 {
+		t.Errorf("tensorObject = %v, want [6]", ab.Blocks)
+	}
+	if len(ab.Factors) != 2 || !ObjectEqual(ab.Factors[0], a) || !ObjectEqual(ab.Factors[1], b) {
+		t.Errorf("tensorObject factors = %v, want [Q(2) Q(3)]", ab.Factors)
 	}
 }
 
@@ -1013,6 +1100,30 @@ func TestIsTwoQubit(t *testing.T) {
 	if isTwoQubit(qubit()) {
 		t.Error("single qubit should not satisfy isTwoQubit()")
 	}
+
+	// The factors are required.
+	sum := SumObjects(qubit(), qubit())
+	for _, o := range []Object{sum, QuantumObject(4)} {
+		if isTwoQubit(o) {
+			t.Errorf("%v should not satisfy isTwoQubit()", o)
+		}
+		// Only the catch-all identity rule may match.
+		for _, name := range []string{"CNOT", "SWAPGate"} {
+			c, ok := Synthesize(NewStore(), SynthesisSpec{Name: name, Domain: o, Codomain: o})
+			if ok && c.Prim != PrimId {
+				t.Errorf("%s synthesized on %v", name, o)
+			}
+		}
+	}
+
+	// A legacy v1 [2, 2] is migrated to Q(2)⊗Q(2) when decoded.
+	legacy, ok := ObjectFromValue(MakeTag(MakeText("object"), MakeSeq(MakeInt(2), MakeInt(2))))
+	if !ok || !isTwoQubit(legacy) {
+		t.Error("legacy v1 [2, 2] should satisfy isTwoQubit()")
+	}
+	if isTwoQubit(Object{Blocks: []uint32{2, 3}}) || isTwoQubit(TensorObjects(qubit(), QuantumObject(3))) {
+		t.Error("Q(2)⊗Q(3) should not satisfy isTwoQubit()")
+	}
 }
 
 func TestQiRat(t *testing.T) {
@@ -0,0 +1,307 @@
+package runtime
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randQI returns a Gaussian rational with small random numerators and
+// denominators.
+func randQI(r *rand.Rand) QI {
+	re := big.NewRat(int64(r.Intn(9)-4), int64(r.Intn(4)+1))
+	im := big.NewRat(int64(r.Intn(9)-4), int64(r.Intn(4)+1))
+	return NewQI(re, im)
+}
+
+// randMatrix returns a random rows×cols matrix over Q(i).
+func randMatrix(r *rand.Rand, rows, cols int) *Matrix {
+	m := NewMatrix(rows, cols)
+	for i := range m.Data {
+		m.Data[i] = randQI(r)
+	}
+	return m
+}
+
+// randDensity returns a random positive (unnormalized) n×n matrix A A†.
+// For n a composite dimension it is generically entangled.
+func randDensity(r *rand.Rand, n int) *Matrix {
+	A := randMatrix(r, n, n)
+	return MatMul(A, Dagger(A))
+}
+
+// randKraus returns k random out×in Kraus operators.
+func randKraus(r *rand.Rand, k, in, out int) []*Matrix {
+	ops := make([]*Matrix, k)
+	for i := range ops {
+		ops[i] = randMatrix(r, out, in)
+	}
+	return ops
+}
+
+// putKraus stores a Kraus circuit Q(in) → Q(out).
+func putKraus(store *Store, ops []*Matrix) [32]byte {
+	return store.Put(Circuit{
+		Domain:   Object{Blocks: []uint32{uint32(ops[0].Cols)}},
+		Codomain: Object{Blocks: []uint32{uint32(ops[0].Rows)}},
+		Prim:     PrimKraus,
+		Data:     KrausToValue(ops),
+	})
+}
+
+// bruteForceKraus applies the channel with Kraus operators {A_a ⊗ B_b}.
+func bruteForceKraus(opsA, opsB []*Matrix, rho *Matrix) *Matrix {
+	var result *Matrix
+	for _, A := range opsA {
+		for _, B := range opsB {
+			K := Kronecker(A, B)
+			term := MatMul(MatMul(K, rho), Dagger(K))
+			if result == nil {
+				result = term
+			} else {
+				result = MatAdd(result, term)
+			}
+		}
+	}
+	return result
+}
+
+func TestExecuteTensorProductInputs(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for trial := 0; trial < 10; trial++ {
+		store := NewStore()
+		exec := NewExecutor(store)
+
+		opsA := randKraus(r, 2, 2, 3)
+		opsB := randKraus(r, 2, 3, 2)
+		fID := putKraus(store, opsA)
+		gID := putKraus(store, opsB)
+		f, _ := store.Get(fID)
+		g, _ := store.Get(gID)
+
+		c := Circuit{
+			Domain:   tensorObject(f.Domain, g.Domain),
+			Codomain: tensorObject(f.Codomain, g.Codomain),
+			Prim:     PrimTensor,
+			Children: [][32]byte{fID, gID},
+		}
+
+		rhoA := randDensity(r, 2)
+		rhoB := randDensity(r, 3)
+
+		result, err := exec.Execute(c, Kronecker(rhoA, rhoB))
+		if err != nil {
+			t.Fatalf("trial %d: Execute tensor failed: %v", trial, err)
+		}
+
+		fA, err := exec.Execute(f, rhoA)
+		if err != nil {
+			t.Fatalf("trial %d: Execute f failed: %v", trial, err)
+		}
+		gB, err := exec.Execute(g, rhoB)
+		if err != nil {
+			t.Fatalf("trial %d: Execute g failed: %v", trial, err)
+		}
+
+		if !MatrixEqual(result, Kronecker(fA, gB)) {
+			t.Errorf("trial %d: (f⊗g)(ρA⊗ρB) != f(ρA)⊗g(ρB)", trial)
+		}
+	}
+}
+
+func TestExecuteTensorEntangledInputs(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for trial := 0; trial < 10; trial++ {
+		store := NewStore()
+		exec := NewExecutor(store)
+
+		opsA := randKraus(r, 2, 3, 2)
+		opsB := randKraus(r, 3, 2, 2)
+		fID := putKraus(store, opsA)
+		gID := putKraus(store, opsB)
+
+		c := Circuit{
+			Domain:   tensorObject(Object{Blocks: []uint32{3}}, qubit()),
+			Codomain: tensorObject(qubit(), qubit()),
+			Prim:     PrimTensor,
+			Children: [][32]byte{fID, gID},
+		}
+
+		rho := randDensity(r, 6)
+		result, err := exec.Execute(c, rho)
+		if err != nil {
+			t.Fatalf("trial %d: Execute tensor failed: %v", trial, err)
+		}
+
+		if !MatrixEqual(result, bruteForceKraus(opsA, opsB, rho)) {
+			t.Errorf("trial %d: (f⊗g)(ρ) disagrees with Kraus expansion {A_a⊗B_b}", trial)
+		}
+	}
+}
+
+func TestExecuteTensorPartialTrace(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Tensor(Id, Discard) on Q(2)⊗Q(2) traces out the second qubit.
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	discard := store.Put(Circuit{Domain: qubit(), Codomain: unitObject(), Prim: PrimDiscard})
+
+	c := Circuit{
+		Domain:   tensorObject(qubit(), qubit()),
+		Codomain: qubit(),
+		Prim:     PrimTensor,
+		Children: [][32]byte{id, discard},
+	}
+
+	// |Φ+⟩⟨Φ+| unnormalized: entries at (0,0), (0,3), (3,0), (3,3)
+	bell := NewMatrix(4, 4)
+	for _, i := range []int{0, 3} {
+		for _, j := range []int{0, 3} {
+			bell.Set(i, j, QIOne())
+		}
+	}
+
+	result, err := exec.Execute(c, bell)
+	if err != nil {
+		t.Fatalf("Execute tensor failed: %v", err)
+	}
+	if !MatrixEqual(result, Identity(2)) {
+		t.Error("Tr_B |Φ+⟩⟨Φ+| should be I (unnormalized)")
+	}
+}
+
+func TestExecuteTensorPrepare(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	// Tensor(Id, Prepare σ) maps ρ to ρ⊗σ.
+	sigma := randDensity(r, 2)
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	prep := store.Put(Circuit{
+		Domain:   unitObject(),
+		Codomain: qubit(),
+		Prim:     PrimPrepare,
+		Data:     MatrixToValue(sigma),
+	})
+
+	c := Circuit{
+		Domain:   qubit(),
+		Codomain: tensorObject(qubit(), qubit()),
+		Prim:     PrimTensor,
+		Children: [][32]byte{id, prep},
+	}
+
+	rho := randDensity(r, 2)
+	result, err := exec.Execute(c, rho)
+	if err != nil {
+		t.Fatalf("Execute tensor failed: %v", err)
+	}
+	if !MatrixEqual(result, Kronecker(rho, sigma)) {
+		t.Error("(id⊗prepare σ)(ρ) should be ρ⊗σ")
+	}
+}
+
+func TestExecuteTensorDimensionMismatch(t *testing.T) {
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	c := Circuit{
+		Domain:   tensorObject(qubit(), qubit()),
+		Codomain: tensorObject(qubit(), qubit()),
+		Prim:     PrimTensor,
+		Children: [][32]byte{id, id},
+	}
+
+	if _, err := exec.Execute(c, Identity(3)); err == nil {
+		t.Error("tensor on a 3x3 input should fail for Q(2)⊗Q(2)")
+	}
+}
+
+func TestExecuteTensorNary(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	opsA := randKraus(r, 2, 2, 3)
+	opsB := randKraus(r, 2, 3, 2)
+	opsC := randKraus(r, 1, 2, 2)
+	a, b, cc := putKraus(store, opsA), putKraus(store, opsB), putKraus(store, opsC)
+	fa, _ := store.Get(a)
+	fb, _ := store.Get(b)
+	fc, _ := store.Get(cc)
+
+	flat := Circuit{
+		Domain:   TensorObjects(fa.Domain, fb.Domain, fc.Domain),
+		Codomain: TensorObjects(fa.Codomain, fb.Codomain, fc.Codomain),
+		Prim:     PrimTensor,
+		Children: [][32]byte{a, b, cc},
+	}
+	inner := store.Put(Circuit{
+		Domain:   TensorObjects(fb.Domain, fc.Domain),
+		Codomain: TensorObjects(fb.Codomain, fc.Codomain),
+		Prim:     PrimTensor,
+		Children: [][32]byte{b, cc},
+	})
+	nested := Circuit{
+		Domain:   flat.Domain,
+		Codomain: flat.Codomain,
+		Prim:     PrimTensor,
+		Children: [][32]byte{a, inner},
+	}
+
+	rho := randDensity(r, 12)
+	got, err := exec.Execute(flat, rho)
+	if err != nil {
+		t.Fatalf("Execute n-ary tensor failed: %v", err)
+	}
+	want, err := exec.Execute(nested, rho)
+	if err != nil {
+		t.Fatalf("Execute nested tensor failed: %v", err)
+	}
+	if !MatrixEqual(got, want) {
+		t.Error("Tensor(f, g, h) should equal Tensor(f, Tensor(g, h))")
+	}
+
+	// Kraus expansion {A_a ⊗ B_b ⊗ C_c}
+	var opsBC []*Matrix
+	for _, B := range opsB {
+		for _, C := range opsC {
+			opsBC = append(opsBC, Kronecker(B, C))
+		}
+	}
+	if !MatrixEqual(got, bruteForceKraus(opsA, opsBC, rho)) {
+		t.Error("(f⊗g⊗h)(ρ) disagrees with Kraus expansion")
+	}
+}
+
+func TestExecuteComposeNary(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	store := NewStore()
+	exec := NewExecutor(store)
+
+	f := putKraus(store, randKraus(r, 2, 2, 3))
+	g := putKraus(store, randKraus(r, 2, 3, 3))
+	h := putKraus(store, randKraus(r, 1, 3, 2))
+
+	flat := Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{f, g, h}}
+	inner := store.Put(Circuit{Domain: qubit(), Codomain: Object{Blocks: []uint32{3}}, Prim: PrimCompose, Children: [][32]byte{f, g}})
+	nested := Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{inner, h}}
+
+	rho := randDensity(r, 2)
+	got, err := exec.Execute(flat, rho)
+	if err != nil {
+		t.Fatalf("Execute n-ary compose failed: %v", err)
+	}
+	want, err := exec.Execute(nested, rho)
+	if err != nil {
+		t.Fatalf("Execute nested compose failed: %v", err)
+	}
+	if !MatrixEqual(got, want) {
+		t.Error("Compose(f, g, h) should equal Compose(Compose(f, g), h)")
+	}
+}
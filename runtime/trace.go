@@ -0,0 +1,189 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Partial trace over tensor factors.
+//
+// PrimDiscard and PrimTrace with Nil data trace out their whole domain and
+// return Tr(ρ) as a 1×1 matrix. Either primitive may instead carry
+//
+//	Tag("factors", Seq(Int k_1, ..., Int k_m))    0 <= k_1 < ... < k_m
+//
+// naming the domain factors to trace out. The domain must then be a tensor
+// product (an Object with Factors) and the codomain is the tensor product
+// of the remaining factors in their original order, as computed by
+// PartialTraceObject. Naming every factor is the full trace. Indices are
+// kept strictly increasing so that each partial trace has one encoding
+// and one QGID.
+
+const factorsLabel = "factors"
+
+// FactorsToValue encodes the factor indices of a partial trace as
+// Tag("factors", Seq(Int ...)). The indices are sorted and deduplicated.
+func FactorsToValue(factors []int) Value {
+	sorted := append([]int(nil), factors...)
+	sort.Ints(sorted)
+	items := make([]Value, 0, len(sorted))
+	for i, k := range sorted {
+		if i > 0 && k == sorted[i-1] {
+			continue
+		}
+		items = append(items, MakeInt(int64(k)))
+	}
+	return MakeTag(MakeText(factorsLabel), MakeSeq(items...))
+}
+
+// FactorsFromValue parses a Tag("factors", Seq(Int ...)) value. The indices
+// must be non-negative and strictly increasing.
+func FactorsFromValue(v Value) ([]int, bool) {
+	tag, ok := v.(Tag)
+	if !ok {
+		return nil, false
+	}
+	label, ok := tag.Label.(Text)
+	if !ok || label.V != factorsLabel {
+		return nil, false
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok {
+		return nil, false
+	}
+	factors := make([]int, len(seq.Items))
+	for i, item := range seq.Items {
+		n, ok := item.(Int)
+		if !ok || n.V.Sign() < 0 || n.V.BitLen() > 31 {
+			return nil, false
+		}
+		factors[i] = int(n.V.Int64())
+		if i > 0 && factors[i] <= factors[i-1] {
+			return nil, false
+		}
+	}
+	return factors, true
+}
+
+// PartialTraceObject returns the object left after tracing the listed
+// factors out of domain.
+func PartialTraceObject(domain Object, factors []int) (Object, error) {
+	if len(domain.Factors) == 0 {
+		return Object{}, fmt.Errorf("partial trace: domain is not a tensor product")
+	}
+	traced, err := tracedSet(len(domain.Factors), factors)
+	if err != nil {
+		return Object{}, err
+	}
+	var kept []Object
+	for k, f := range domain.Factors {
+		if !traced[k] {
+			kept = append(kept, f)
+		}
+	}
+	return TensorObjects(kept...), nil
+}
+
+// PartialTrace traces the listed factors out of rho, a matrix on the
+// Kronecker product of spaces of dimensions dims. The result is indexed in
+// Kronecker order of the remaining factors.
+func PartialTrace(rho *Matrix, dims []int, factors []int) (*Matrix, error) {
+	out, err := IntPartialTrace(ToIntMatrix(rho), dims, factors)
+	if err != nil {
+		return nil, err
+	}
+	return out.ToMatrix(), nil
+}
+
+// IntPartialTrace is PartialTrace in common-denominator form. The traced
+// entries share rho's denominator, so the sums are integer additions.
+func IntPartialTrace(rho *IntMatrix, dims []int, factors []int) (*IntMatrix, error) {
+	traced, err := tracedSet(len(dims), factors)
+	if err != nil {
+		return nil, err
+	}
+
+	// stride[k] is the step in the joint index for one step in factor k.
+	stride := make([]int, len(dims))
+	total := 1
+	for k := len(dims) - 1; k >= 0; k-- {
+		stride[k] = total
+		total *= dims[k]
+	}
+	if rho.Rows != total || rho.Cols != total {
+		return nil, fmt.Errorf("partial trace: input is %dx%d, want %dx%d", rho.Rows, rho.Cols, total, total)
+	}
+
+	var keptIdx, tracedIdx []int
+	for k := range dims {
+		if traced[k] {
+			tracedIdx = append(tracedIdx, k)
+		} else {
+			keptIdx = append(keptIdx, k)
+		}
+	}
+	kept := factorOffsets(keptIdx, dims, stride)
+	sum := factorOffsets(tracedIdx, dims, stride)
+
+	result := NewIntMatrix(len(kept), len(kept))
+	result.Den.Set(&rho.Den)
+	for i, ri := range kept {
+		for j, cj := range kept {
+			k := i*result.Cols + j
+			for _, t := range sum {
+				l := (ri+t)*total + cj + t
+				result.Re[k].Add(&result.Re[k], &rho.Re[l])
+				result.Im[k].Add(&result.Im[k], &rho.Im[l])
+			}
+		}
+	}
+	return result, nil
+}
+
+// tracedSet validates factor indices against n factors and returns them as
+// a membership table.
+func tracedSet(n int, factors []int) ([]bool, error) {
+	traced := make([]bool, n)
+	for i, k := range factors {
+		if k < 0 || k >= n {
+			return nil, fmt.Errorf("partial trace: factor %d out of range [0, %d)", k, n)
+		}
+		if i > 0 && k <= factors[i-1] {
+			return nil, fmt.Errorf("partial trace: factors must be strictly increasing")
+		}
+		traced[k] = true
+	}
+	return traced, nil
+}
+
+// factorOffsets returns the joint-index offset of every basis state of the
+// listed factors, enumerated in Kronecker order.
+func factorOffsets(factors, dims, stride []int) []int {
+	offsets := []int{0}
+	for _, k := range factors {
+		next := make([]int, 0, len(offsets)*dims[k])
+		for _, o := range offsets {
+			for x := 0; x < dims[k]; x++ {
+				next = append(next, o+x*stride[k])
+			}
+		}
+		offsets = next
+	}
+	return offsets
+}
+
+// applyPartialTrace traces out the domain factors named by c.Data.
+func (e *Executor) applyPartialTrace(c Circuit, input *IntMatrix) (*IntMatrix, error) {
+	factors, ok := FactorsFromValue(c.Data)
+	if !ok {
+		return nil, fmt.Errorf("partial trace: data must be Tag(\"factors\", Seq(Int ...)) with increasing indices")
+	}
+	if _, err := PartialTraceObject(c.Domain, factors); err != nil {
+		return nil, err
+	}
+	dims := make([]int, len(c.Domain.Factors))
+	for k, f := range c.Domain.Factors {
+		dims[k] = BlockDim(f)
+	}
+	return IntPartialTrace(input, dims, factors)
+}
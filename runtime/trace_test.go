@@ -0,0 +1,180 @@
+package runtime
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPartialTraceMatchesTensorDiscard(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	qutrit := QuantumObject(3)
+	joint := TensorObjects(qubit(), qutrit)
+
+	for trial := 0; trial < 5; trial++ {
+		store := NewStore()
+		exec := NewExecutor(store)
+		rho := randDensity(r, 6)
+
+		// Tracing out factor 1 is Id ⊗ Discard.
+		idA := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+		discardB := store.Put(Circuit{Domain: qutrit, Codomain: unitObject(), Prim: PrimDiscard})
+		tensor, _ := store.Get(store.Put(Circuit{
+			Domain:   joint,
+			Codomain: qubit(),
+			Prim:     PrimTensor,
+			Children: [][32]byte{idA, discardB},
+		}))
+		want, err := exec.Execute(tensor, rho)
+		if err != nil {
+			t.Fatalf("trial %d: tensor failed: %v", trial, err)
+		}
+
+		for _, prim := range []Prim{PrimDiscard, PrimTrace} {
+			c := Circuit{Domain: joint, Codomain: qubit(), Prim: prim, Data: FactorsToValue([]int{1})}
+			got, err := exec.Execute(c, rho)
+			if err != nil {
+				t.Fatalf("trial %d: %s failed: %v", trial, PrimName(prim), err)
+			}
+			if !MatrixEqual(got, want) {
+				t.Errorf("trial %d: %s over factor 1 differs from Id ⊗ Discard", trial, PrimName(prim))
+			}
+		}
+
+		// Naming every factor is the full trace.
+		all := Circuit{Domain: joint, Codomain: unitObject(), Prim: PrimDiscard, Data: FactorsToValue([]int{0, 1})}
+		got, err := exec.Execute(all, rho)
+		if err != nil {
+			t.Fatalf("trial %d: full partial trace failed: %v", trial, err)
+		}
+		if got.Rows != 1 || !QIEqual(got.Get(0, 0), Trace(rho)) {
+			t.Errorf("trial %d: tracing every factor should give Tr(ρ)", trial)
+		}
+	}
+}
+
+func TestPartialTraceMiddleFactor(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	rhoA, rhoB, rhoC := randDensity(r, 2), randDensity(r, 3), randDensity(r, 2)
+	rho := Kronecker(Kronecker(rhoA, rhoB), rhoC)
+
+	dom := TensorObjects(qubit(), QuantumObject(3), qubit())
+	c := Circuit{Domain: dom, Codomain: tensorObject(qubit(), qubit()), Prim: PrimDiscard, Data: FactorsToValue([]int{1})}
+	got, err := NewExecutor(NewStore()).Execute(c, rho)
+	if err != nil {
+		t.Fatalf("partial trace failed: %v", err)
+	}
+
+	want := matScaleQI(Kronecker(rhoA, rhoC), Trace(rhoB))
+	if !MatrixEqual(got, want) {
+		t.Error("Tr_B(ρA ⊗ ρB ⊗ ρC) should be Tr(ρB) ρA ⊗ ρC")
+	}
+
+	cod, err := PartialTraceObject(dom, []int{1})
+	if err != nil || !ObjectEqual(cod, c.Codomain) {
+		t.Errorf("PartialTraceObject = %v, %v; want Q(2)⊗Q(2)", cod, err)
+	}
+}
+
+// TestPartialTraceTeleportation runs teleportation as a single circuit:
+// Alice's two qubits are measured and then discarded inside the circuit,
+// leaving Bob's corrected qubit.
+func TestPartialTraceTeleportation(t *testing.T) {
+	store := NewStore()
+	three := TensorObjects(qubit(), qubit(), qubit())
+
+	phiPlus := NewMatrix(4, 4)
+	for _, i := range []int{0, 3} {
+		for _, j := range []int{0, 3} {
+			phiPlus.Set(i, j, qiHalf())
+		}
+	}
+	idQ := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	bell := store.Put(Circuit{Domain: unitObject(), Codomain: tensorObject(qubit(), qubit()), Prim: PrimPrepare, Data: MatrixToValue(phiPlus)})
+	attach := store.Put(Circuit{Domain: qubit(), Codomain: three, Prim: PrimTensor, Children: [][32]byte{idQ, bell}})
+	cnot := store.Put(Circuit{Domain: three, Codomain: three, Prim: PrimUnitary, Data: MatrixToValue(Kronecker(cnotUnitary(), Identity(2)))})
+
+	// Measuring qubit 0 in the X basis is H followed by a Z measurement.
+	xBasis := []*Matrix{rhoPlusExact(), MatSub(Identity(2), rhoPlusExact())}
+	zBasis := []*Matrix{ket0bra0(), ket1bra1()}
+	outcomes := make([][]*Matrix, 0, 4)
+	corrections := make([][32]byte, 0, 4)
+	discardAlice := store.Put(Circuit{Domain: three, Codomain: qubit(), Prim: PrimDiscard, Data: FactorsToValue([]int{0, 1})})
+	for m1 := 0; m1 < 2; m1++ {
+		for m2 := 0; m2 < 2; m2++ {
+			outcomes = append(outcomes, []*Matrix{Kronecker(Kronecker(xBasis[m1], zBasis[m2]), Identity(2))})
+
+			// Bob applies Z^m1 X^m2.
+			C := Identity(2)
+			if m2 == 1 {
+				C = MatMul(C, pauliX())
+			}
+			if m1 == 1 {
+				C = MatMul(pauliZ(), C)
+			}
+			fix := store.Put(Circuit{Domain: three, Codomain: three, Prim: PrimUnitary, Data: MatrixToValue(Kronecker(Identity(4), C))})
+			corrections = append(corrections, store.Put(Circuit{
+				Domain:   three,
+				Codomain: qubit(),
+				Prim:     PrimCompose,
+				Children: [][32]byte{fix, discardAlice},
+			}))
+		}
+	}
+	measured := SumObjects(three, three, three, three)
+	measure := store.Put(Circuit{Domain: three, Codomain: measured, Prim: PrimInstrument, Data: InstrumentToValue(outcomes)})
+	correct := store.Put(Circuit{Domain: measured, Codomain: qubit(), Prim: PrimBranch, Children: corrections})
+
+	rootID := store.Put(Circuit{
+		Domain:   qubit(),
+		Codomain: qubit(),
+		Prim:     PrimCompose,
+		Children: [][32]byte{attach, cnot, measure, correct},
+	})
+	if diags := TypeCheck(store, rootID); diags != nil {
+		t.Fatalf("teleportation circuit reported %v", diags)
+	}
+
+	root, _ := store.Get(rootID)
+	exec := NewExecutor(store)
+	r := rand.New(rand.NewSource(9))
+	for trial := 0; trial < 5; trial++ {
+		rho := randDensity(r, 2)
+		out, err := exec.Execute(root, rho)
+		if err != nil {
+			t.Fatalf("trial %d: teleportation failed: %v", trial, err)
+		}
+		if !MatrixEqual(out, rho) {
+			t.Errorf("trial %d: teleportation should return the input state exactly", trial)
+		}
+	}
+}
+
+func TestPartialTraceTypeCheck(t *testing.T) {
+	store := NewStore()
+	joint := tensorObject(qubit(), QuantumObject(3))
+
+	ok := store.Put(Circuit{Domain: joint, Codomain: QuantumObject(3), Prim: PrimTrace, Data: FactorsToValue([]int{0})})
+	if diags := TypeCheck(store, ok); diags != nil {
+		t.Errorf("valid partial trace reported %v", diags)
+	}
+
+	wrongCod := store.Put(Circuit{Domain: joint, Codomain: qubit(), Prim: PrimTrace, Data: FactorsToValue([]int{0})})
+	expectDiag(t, TypeCheck(store, wrongCod), DiagType)
+
+	outOfRange := store.Put(Circuit{Domain: joint, Codomain: qubit(), Prim: PrimDiscard, Data: FactorsToValue([]int{2})})
+	expectDiag(t, TypeCheck(store, outOfRange), DiagData)
+
+	unordered := MakeTag(MakeText("factors"), MakeSeq(MakeInt(1), MakeInt(0)))
+	bad := store.Put(Circuit{Domain: joint, Codomain: unitObject(), Prim: PrimDiscard, Data: unordered})
+	expectDiag(t, TypeCheck(store, bad), DiagData)
+
+	flat := store.Put(Circuit{Domain: QuantumObject(6), Codomain: qubit(), Prim: PrimDiscard, Data: FactorsToValue([]int{1})})
+	expectDiag(t, TypeCheck(store, flat), DiagType)
+
+	if QGID(FactorsToValue([]int{2, 0, 2})) != QGID(FactorsToValue([]int{0, 2})) {
+		t.Error("FactorsToValue should sort and deduplicate indices")
+	}
+	if _, err := NewExecutor(store).Execute(Circuit{Domain: joint, Codomain: unitObject(), Prim: PrimDiscard, Data: unordered}, Identity(6)); err == nil {
+		t.Error("executing unordered factors should fail")
+	}
+}
@@ -0,0 +1,552 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// Static type checking of circuit DAGs.
+//
+// TypeCheck walks the children of a circuit through the store and checks
+// each node against the contract its primitive has in Execute: the number
+// of children, agreement between its domain and codomain and those of its
+// children, and the shape of its Data payload. Each circuit is checked
+// once, at the first path that reaches it.
+
+// DiagnosticKind classifies a type checking diagnostic.
+type DiagnosticKind int
+
+const (
+	// DiagArity reports a wrong number of children.
+	DiagArity DiagnosticKind = iota
+	// DiagMissing reports a child QGID that is not in the store.
+	DiagMissing
+	// DiagType reports a domain or codomain disagreement.
+	DiagType
+	// DiagData reports a Data payload of the wrong shape.
+	DiagData
+	// DiagPrim reports an unknown primitive.
+	DiagPrim
+)
+
+// String returns a short name for the diagnostic kind.
+func (k DiagnosticKind) String() string {
+	switch k {
+	case DiagArity:
+		return "arity"
+	case DiagMissing:
+		return "missing"
+	case DiagType:
+		return "type"
+	case DiagData:
+		return "data"
+	case DiagPrim:
+		return "prim"
+	default:
+		return fmt.Sprintf("DiagnosticKind(%d)", int(k))
+	}
+}
+
+// Diagnostic describes one problem found by TypeCheck.
+type Diagnostic struct {
+	Kind    DiagnosticKind
+	Circuit [32]byte // QGID of the offending circuit
+	Prim    Prim
+	Path    []int // child indices from the root to the offending circuit
+	Message string
+}
+
+// String formats the diagnostic as "kind: Prim at path (qgid): message".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s at %v (%s): %s",
+		d.Kind, PrimName(d.Prim), d.Path, hex.EncodeToString(d.Circuit[:4]), d.Message)
+}
+
+// TypeCheck checks the circuit id and everything reachable from it. It
+// returns nil if no problems were found.
+func TypeCheck(store *Store, id [32]byte) []Diagnostic {
+	tc := &typeChecker{store: store, seen: make(map[[32]byte]bool)}
+	c, ok := store.Get(id)
+	if !ok {
+		return []Diagnostic{{
+			Kind:    DiagMissing,
+			Circuit: id,
+			Path:    []int{},
+			Message: "circuit not found in store",
+		}}
+	}
+	tc.check(id, c, []int{})
+	return tc.diags
+}
+
+// TypeCheckAll checks every circuit in the store. Circuits that are not a
+// child of any other circuit are taken as roots, in QGID order, and paths
+// are relative to the root that first reaches a circuit.
+func TypeCheckAll(store *Store) []Diagnostic {
+	referenced := make(map[[32]byte]bool)
+	for _, c := range store.circuits {
+		for _, child := range c.Children {
+			referenced[child] = true
+		}
+	}
+	var roots [][32]byte
+	for id := range store.circuits {
+		if !referenced[id] {
+			roots = append(roots, id)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return bytes.Compare(roots[i][:], roots[j][:]) < 0
+	})
+
+	tc := &typeChecker{store: store, seen: make(map[[32]byte]bool)}
+	for _, id := range roots {
+		tc.check(id, store.circuits[id], []int{})
+	}
+	return tc.diags
+}
+
+type typeChecker struct {
+	store   *Store
+	seen    map[[32]byte]bool
+	diags   []Diagnostic
+	externs map[[32]byte][32]byte // built on the first missing child
+}
+
+func (tc *typeChecker) report(kind DiagnosticKind, id [32]byte, c Circuit, path []int, format string, args ...interface{}) {
+	tc.diags = append(tc.diags, Diagnostic{
+		Kind:    kind,
+		Circuit: id,
+		Prim:    c.Prim,
+		Path:    append([]int(nil), path...),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// check checks c, then recurses into its children.
+func (tc *typeChecker) check(id [32]byte, c Circuit, path []int) {
+	if tc.seen[id] {
+		return
+	}
+	tc.seen[id] = true
+
+	children := make([]Circuit, len(c.Children))
+	present := true
+	for i, childID := range c.Children {
+		child, ok := tc.store.Get(childID)
+		if !ok {
+			if tc.externs == nil {
+				tc.externs = tc.store.externs()
+			}
+			if ref, ok := ExternFromValue(tc.store.values[tc.externs[childID]]); ok {
+				tc.report(DiagMissing, id, c, path, "child %d (%s) is in binary %s, which is not linked",
+					i, hex.EncodeToString(childID[:4]), hex.EncodeToString(ref.Binary[:8]))
+			} else {
+				tc.report(DiagMissing, id, c, path, "child %d (%s) not found in store",
+					i, hex.EncodeToString(childID[:4]))
+			}
+			present = false
+			continue
+		}
+		children[i] = child
+	}
+
+	// Node rules that relate c to its children need every child present.
+	r := &nodeRule{tc: tc, id: id, c: c, path: path, children: children, present: present}
+	r.check()
+
+	for i, childID := range c.Children {
+		if child, ok := tc.store.Get(childID); ok {
+			tc.check(childID, child, append(path, i))
+		}
+	}
+}
+
+// nodeRule checks a single circuit against its primitive's contract.
+type nodeRule struct {
+	tc       *typeChecker
+	id       [32]byte
+	c        Circuit
+	path     []int
+	children []Circuit
+	present  bool
+}
+
+func (r *nodeRule) report(kind DiagnosticKind, format string, args ...interface{}) {
+	r.tc.report(kind, r.id, r.c, r.path, format, args...)
+}
+
+// arity reports and returns false unless c has exactly n children.
+func (r *nodeRule) arity(n int) bool {
+	if len(r.c.Children) != n {
+		r.report(DiagArity, "%s requires %d children, got %d", PrimName(r.c.Prim), n, len(r.c.Children))
+		return false
+	}
+	return r.present
+}
+
+// minArity reports and returns false unless c has at least n children.
+func (r *nodeRule) minArity(n int) bool {
+	if len(r.c.Children) < n {
+		r.report(DiagArity, "%s requires at least %d children, got %d", PrimName(r.c.Prim), n, len(r.c.Children))
+		return false
+	}
+	return r.present
+}
+
+// same reports a type error unless got equals want.
+func (r *nodeRule) same(what string, got, want Object) {
+	if !ObjectEqual(got, want) {
+		r.report(DiagType, "%s is %s, want %s", what, formatObjectDiag(got), formatObjectDiag(want))
+	}
+}
+
+// matrix parses c.Data as a matrix, reporting a data error if it is not one.
+func (r *nodeRule) matrix() (*Matrix, bool) {
+	m, ok := MatrixFromValue(r.c.Data)
+	if !ok {
+		r.report(DiagData, "data must be a matrix")
+	}
+	return m, ok
+}
+
+// shape reports a data error unless m is rows×cols.
+func (r *nodeRule) shape(what string, m *Matrix, rows, cols int) {
+	if m.Rows != rows || m.Cols != cols {
+		r.report(DiagData, "%s is %dx%d, want %dx%d", what, m.Rows, m.Cols, rows, cols)
+	}
+}
+
+func (r *nodeRule) check() {
+	c := r.c
+	dIn := BlockDim(c.Domain)
+	dOut := BlockDim(c.Codomain)
+
+	switch c.Prim {
+	case PrimId, PrimAssert:
+		r.arity(0)
+		r.same("codomain", c.Codomain, c.Domain)
+
+	case PrimCompose:
+		if !r.minArity(2) {
+			return
+		}
+		last := len(r.children) - 1
+		r.same("domain", c.Domain, r.children[0].Domain)
+		for i := 1; i <= last; i++ {
+			r.same(fmt.Sprintf("child %d domain", i), r.children[i].Domain, r.children[i-1].Codomain)
+		}
+		r.same("codomain", c.Codomain, r.children[last].Codomain)
+
+	case PrimTensor:
+		if !r.minArity(2) {
+			return
+		}
+		doms := make([]Object, len(r.children))
+		cods := make([]Object, len(r.children))
+		for i, child := range r.children {
+			doms[i], cods[i] = child.Domain, child.Codomain
+		}
+		r.same("domain", c.Domain, TensorObjects(doms...))
+		r.same("codomain", c.Codomain, TensorObjects(cods...))
+
+	case PrimBisum:
+		if !r.minArity(1) {
+			return
+		}
+		doms := make([]Object, len(r.children))
+		cods := make([]Object, len(r.children))
+		for i, child := range r.children {
+			doms[i], cods[i] = child.Domain, child.Codomain
+		}
+		r.same("domain", c.Domain, SumObjects(doms...))
+		r.same("codomain", c.Codomain, SumObjects(cods...))
+
+	case PrimSwap:
+		r.arity(0)
+		switch {
+		case len(c.Domain.Factors) == 2:
+			r.same("codomain", c.Codomain, TensorObjects(c.Domain.Factors[1], c.Domain.Factors[0]))
+		default:
+			r.report(DiagType, "domain %s is not bipartite", formatObjectDiag(c.Domain))
+		}
+
+	case PrimInject:
+		r.arity(0)
+		if !isNilData(c.Data) {
+			r.summand(c.Codomain, c.Domain)
+			return
+		}
+		if !containsBlocks(c.Codomain.Blocks, c.Domain.Blocks) {
+			r.report(DiagType, "domain %s is not a summand of codomain %s",
+				formatObjectDiag(c.Domain), formatObjectDiag(c.Codomain))
+		}
+
+	case PrimProject:
+		r.arity(0)
+		if !isNilData(c.Data) {
+			r.summand(c.Domain, c.Codomain)
+			return
+		}
+		if !containsBlocks(c.Domain.Blocks, c.Codomain.Blocks) {
+			r.report(DiagType, "codomain %s is not a summand of domain %s",
+				formatObjectDiag(c.Codomain), formatObjectDiag(c.Domain))
+		}
+
+	case PrimCopy:
+		r.arity(0)
+		if !isClassical(c.Domain) {
+			r.report(DiagType, "domain %s is not classical", formatObjectDiag(c.Domain))
+			return
+		}
+		if !blocksEqual(c.Codomain.Blocks, ClassicalObject(dIn*dIn).Blocks) {
+			r.report(DiagType, "codomain is %s, want C(%d)", formatObjectDiag(c.Codomain), dIn*dIn)
+		}
+
+	case PrimDelete:
+		r.arity(0)
+		if !isClassical(c.Domain) {
+			r.report(DiagType, "domain %s is not classical", formatObjectDiag(c.Domain))
+		}
+		r.unitCodomain()
+
+	case PrimDiscard, PrimTrace:
+		r.arity(0)
+		if isNilData(c.Data) {
+			r.unitCodomain()
+			return
+		}
+		factors, ok := FactorsFromValue(c.Data)
+		if !ok {
+			r.report(DiagData, "data must be Nil or Tag(\"factors\", Seq(Int ...)) with increasing indices")
+			return
+		}
+		if len(c.Domain.Factors) == 0 {
+			r.report(DiagType, "domain %s is not a tensor product", formatObjectDiag(c.Domain))
+			return
+		}
+		kept, err := PartialTraceObject(c.Domain, factors)
+		if err != nil {
+			r.report(DiagData, "%v", err)
+			return
+		}
+		r.same("codomain", c.Codomain, kept)
+
+	case PrimEncode:
+		r.arity(0)
+		if !isClassical(c.Domain) {
+			r.report(DiagType, "domain %s is not classical", formatObjectDiag(c.Domain))
+		}
+		r.same("codomain", c.Codomain, QuantumObject(dIn))
+
+	case PrimDecode:
+		r.arity(0)
+		r.same("codomain", c.Codomain, ClassicalObject(dIn))
+
+	case PrimUnitary:
+		r.arity(0)
+		r.same("codomain", c.Codomain, c.Domain)
+		if U, ok := r.matrix(); ok {
+			r.shape("unitary", U, dIn, dIn)
+		}
+
+	case PrimChoi:
+		r.arity(0)
+		if J, ok := r.matrix(); ok {
+			r.shape("Choi matrix", J, dIn*dOut, dIn*dOut)
+		}
+
+	case PrimKraus:
+		r.arity(0)
+		ops, err := KrausFromValue(c.Data)
+		if err != nil {
+			r.report(DiagData, "%v", err)
+			return
+		}
+		for i, K := range ops {
+			r.shape(fmt.Sprintf("Kraus operator %d", i), K, dOut, dIn)
+		}
+
+	case PrimInstrument:
+		r.arity(0)
+		r.checkInstrument(dIn)
+
+	case PrimBranch:
+		if len(c.Domain.Blocks) == 0 {
+			r.report(DiagType, "domain has no blocks")
+			return
+		}
+		if !r.arity(len(c.Domain.Blocks)) {
+			return
+		}
+		for i, child := range r.children {
+			if BlockDim(child.Domain) != int(c.Domain.Blocks[i]) {
+				r.report(DiagType, "child %d domain %s does not match block %d of size %d",
+					i, formatObjectDiag(child.Domain), i, c.Domain.Blocks[i])
+			}
+			r.same(fmt.Sprintf("child %d codomain", i), child.Codomain, c.Codomain)
+		}
+
+	case PrimPrepare, PrimWitness:
+		r.arity(0)
+		if dIn != 1 {
+			r.report(DiagType, "domain %s is not the unit", formatObjectDiag(c.Domain))
+		}
+		if rho, ok := r.matrix(); ok {
+			r.shape("state", rho, dOut, dOut)
+		}
+
+	case PrimAdd:
+		if !r.arity(2) {
+			return
+		}
+		for i, child := range r.children {
+			r.same(fmt.Sprintf("child %d domain", i), child.Domain, c.Domain)
+			r.same(fmt.Sprintf("child %d codomain", i), child.Codomain, c.Codomain)
+		}
+
+	case PrimScale:
+		if _, ok := c.Data.(Rat); !ok {
+			r.report(DiagData, "data must be Rat")
+		}
+		if !r.arity(1) {
+			return
+		}
+		r.same("child 0 domain", r.children[0].Domain, c.Domain)
+		r.same("child 0 codomain", r.children[0].Codomain, c.Codomain)
+
+	case PrimZero:
+		r.arity(0)
+
+	default:
+		r.report(DiagPrim, "unknown primitive %d", int(c.Prim))
+	}
+}
+
+// unitCodomain reports a type error unless the codomain is the unit.
+func (r *nodeRule) unitCodomain() {
+	if BlockDim(r.c.Codomain) != 1 {
+		r.report(DiagType, "codomain %s is not the unit", formatObjectDiag(r.c.Codomain))
+	}
+}
+
+// summand checks a Tag("summand", Int k) payload locating part inside the
+// direct sum whole.
+func (r *nodeRule) summand(whole, part Object) {
+	k, ok := SummandFromValue(r.c.Data)
+	if !ok {
+		r.report(DiagData, "data must be Nil or Tag(\"summand\", Int k)")
+		return
+	}
+	if _, _, err := summandSpan(whole, part, k); err != nil {
+		r.report(DiagType, "%v", err)
+	}
+}
+
+// checkInstrument checks both instrument payload forms against the
+// domain dimension dIn and the codomain's outcome blocks.
+func (r *nodeRule) checkInstrument(dIn int) {
+	c := r.c
+	tag, ok := c.Data.(Tag)
+	if !ok {
+		r.report(DiagData, "data must be a Tag")
+		return
+	}
+	label, ok := tag.Label.(Text)
+	if !ok {
+		r.report(DiagData, "data label must be Text")
+		return
+	}
+	seq, ok := tag.Payload.(Seq)
+	if !ok {
+		r.report(DiagData, "payload must be a Seq")
+		return
+	}
+	if len(seq.Items) != len(c.Codomain.Blocks) {
+		r.report(DiagType, "codomain has %d blocks, data has %d outcomes",
+			len(c.Codomain.Blocks), len(seq.Items))
+		return
+	}
+
+	switch label.V {
+	case "instrument":
+		for i, item := range seq.Items {
+			ops, err := KrausFromValue(item)
+			if err != nil {
+				r.report(DiagData, "outcome %d: %v", i, err)
+				continue
+			}
+			n := int(c.Codomain.Blocks[i])
+			for j, K := range ops {
+				r.shape(fmt.Sprintf("outcome %d Kraus operator %d", i, j), K, n, dIn)
+			}
+		}
+	case "povm":
+		for i, item := range seq.Items {
+			if c.Codomain.Blocks[i] != 1 {
+				r.report(DiagType, "povm outcome %d has block size %d, want 1", i, c.Codomain.Blocks[i])
+			}
+			E, ok := MatrixZ8FromValue(item)
+			if !ok {
+				r.report(DiagData, "effect %d is not a matrix", i)
+				continue
+			}
+			if E.Rows != dIn || E.Cols != dIn {
+				r.report(DiagData, "effect %d is %dx%d, want %dx%d", i, E.Rows, E.Cols, dIn, dIn)
+			}
+		}
+	default:
+		r.report(DiagData, "unknown instrument label %q", label.V)
+	}
+}
+
+// isClassical reports whether every block of o has size 1.
+func isClassical(o Object) bool {
+	for _, n := range o.Blocks {
+		if n != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func blocksEqual(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// containsBlocks reports whether sub occurs as a contiguous run of blocks.
+func containsBlocks(blocks, sub []uint32) bool {
+	for start := 0; start+len(sub) <= len(blocks); start++ {
+		if blocksEqual(blocks[start:start+len(sub)], sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatObjectDiag renders an object for diagnostics, e.g. [2 2] or
+// [4]{[2] ⊗ [2]}.
+func formatObjectDiag(o Object) string {
+	s := fmt.Sprintf("%v", o.Blocks)
+	if len(o.Factors) == 0 {
+		return s
+	}
+	s += "{"
+	for i, f := range o.Factors {
+		if i > 0 {
+			s += " ⊗ "
+		}
+		s += formatObjectDiag(f)
+	}
+	return s + "}"
+}
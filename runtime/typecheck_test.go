@@ -0,0 +1,153 @@
+package runtime
+
+import "testing"
+
+// expectDiag fails unless diags contains exactly one diagnostic, of kind k.
+func expectDiag(t *testing.T, diags []Diagnostic, k DiagnosticKind) {
+	t.Helper()
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics %v, want 1 of kind %s", len(diags), diags, k)
+	}
+	if diags[0].Kind != k {
+		t.Errorf("got %s, want kind %s", diags[0], k)
+	}
+}
+
+func TestTypeCheckWellTyped(t *testing.T) {
+	store := NewStore()
+
+	x := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixToValue(pauliX())})
+	prep := store.Put(Circuit{Domain: unitObject(), Codomain: qubit(), Prim: PrimPrepare, Data: MatrixToValue(ket0bra0())})
+	discard := store.Put(Circuit{Domain: qubit(), Codomain: unitObject(), Prim: PrimDiscard})
+	pair := store.Put(Circuit{
+		Domain:   qubit(),
+		Codomain: tensorObject(qubit(), qubit()),
+		Prim:     PrimTensor,
+		Children: [][32]byte{x, prep},
+	})
+	cnot := store.Put(Circuit{
+		Domain:   tensorObject(qubit(), qubit()),
+		Codomain: tensorObject(qubit(), qubit()),
+		Prim:     PrimUnitary,
+		Data:     MatrixToValue(cnotUnitary()),
+	})
+	measure := store.Put(Circuit{
+		Domain:   tensorObject(qubit(), qubit()),
+		Codomain: ClassicalObject(2),
+		Prim:     PrimInstrument,
+		Data:     POVMToValue([]*Matrix{Kronecker(ket0bra0(), Identity(2)), Kronecker(ket1bra1(), Identity(2))}),
+	})
+	branch := store.Put(Circuit{
+		Domain:   ClassicalObject(2),
+		Codomain: qubit(),
+		Prim:     PrimBranch,
+		Children: [][32]byte{prep, prep},
+	})
+
+	root := store.Put(Circuit{
+		Domain:   qubit(),
+		Codomain: qubit(),
+		Prim:     PrimCompose,
+		Children: [][32]byte{pair, store.Put(Circuit{
+			Domain:   tensorObject(qubit(), qubit()),
+			Codomain: qubit(),
+			Prim:     PrimCompose,
+			Children: [][32]byte{cnot, store.Put(Circuit{
+				Domain:   tensorObject(qubit(), qubit()),
+				Codomain: qubit(),
+				Prim:     PrimCompose,
+				Children: [][32]byte{measure, branch},
+			})},
+		})},
+	})
+
+	if diags := TypeCheck(store, root); diags != nil {
+		t.Errorf("well-typed circuit reported %v", diags)
+	}
+	if diags := TypeCheck(store, discard); diags != nil {
+		t.Errorf("discard reported %v", diags)
+	}
+	if diags := TypeCheckAll(store); diags != nil {
+		t.Errorf("TypeCheckAll reported %v", diags)
+	}
+}
+
+func TestTypeCheckArity(t *testing.T) {
+	store := NewStore()
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	c := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{id}})
+	expectDiag(t, TypeCheck(store, c), DiagArity)
+
+	branch := store.Put(Circuit{Domain: ClassicalObject(3), Codomain: qubit(), Prim: PrimBranch, Children: [][32]byte{id}})
+	expectDiag(t, TypeCheck(store, branch), DiagArity)
+}
+
+func TestTypeCheckMissingChild(t *testing.T) {
+	store := NewStore()
+	c := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimScale, Data: MakeRat(1, 2), Children: [][32]byte{{1}}})
+	expectDiag(t, TypeCheck(store, c), DiagMissing)
+
+	expectDiag(t, TypeCheck(store, [32]byte{2}), DiagMissing)
+}
+
+func TestTypeCheckComposeMismatch(t *testing.T) {
+	store := NewStore()
+	f := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	g := store.Put(Circuit{Domain: QuantumObject(3), Codomain: QuantumObject(3), Prim: PrimId})
+	c := store.Put(Circuit{Domain: qubit(), Codomain: QuantumObject(3), Prim: PrimCompose, Children: [][32]byte{f, g}})
+
+	diags := TypeCheck(store, c)
+	expectDiag(t, diags, DiagType)
+	if d := diags[0]; d.Circuit != c || d.Prim != PrimCompose || len(d.Path) != 0 {
+		t.Errorf("diagnostic should locate the root compose, got %s", d)
+	}
+}
+
+func TestTypeCheckTensorFactors(t *testing.T) {
+	store := NewStore()
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+
+	// [2, 2] is a direct sum, not Q(2)⊗Q(2).
+	c := store.Put(Circuit{
+		Domain:   Object{Blocks: []uint32{2, 2}},
+		Codomain: tensorObject(qubit(), qubit()),
+		Prim:     PrimTensor,
+		Children: [][32]byte{id, id},
+	})
+	expectDiag(t, TypeCheck(store, c), DiagType)
+}
+
+func TestTypeCheckKrausDimensions(t *testing.T) {
+	store := NewStore()
+	K := NewMatrix(2, 3)
+	c := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimKraus, Data: KrausToValue([]*Matrix{K})})
+	expectDiag(t, TypeCheck(store, c), DiagData)
+
+	bad := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimKraus, Data: MakeInt(1)})
+	expectDiag(t, TypeCheck(store, bad), DiagData)
+}
+
+func TestTypeCheckInstrumentOutcomes(t *testing.T) {
+	store := NewStore()
+	c := store.Put(Circuit{
+		Domain:   qubit(),
+		Codomain: ClassicalObject(3),
+		Prim:     PrimInstrument,
+		Data:     POVMToValue([]*Matrix{ket0bra0(), ket1bra1()}),
+	})
+	expectDiag(t, TypeCheck(store, c), DiagType)
+}
+
+func TestTypeCheckNestedPath(t *testing.T) {
+	store := NewStore()
+	id := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimId})
+	bad := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimUnitary, Data: MatrixToValue(Identity(3))})
+	inner := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{id, bad}})
+	root := store.Put(Circuit{Domain: qubit(), Codomain: qubit(), Prim: PrimCompose, Children: [][32]byte{id, inner}})
+
+	diags := TypeCheck(store, root)
+	expectDiag(t, diags, DiagData)
+	if d := diags[0]; d.Circuit != bad || len(d.Path) != 2 || d.Path[0] != 1 || d.Path[1] != 1 {
+		t.Errorf("diagnostic should be at path [1 1], got %s", d)
+	}
+}
@@ -118,8 +118,8 @@ func (v Int) Encode() []byte {
 	if v.V == nil || v.V.Sign() == 0 {
 		return []byte{0x00} // Zero
 	}
-	if v.V.Sign() > 0 && v.V.BitLen() <= 7 {
-		return []byte{byte(v.V.Int64())} // Small positive
+	if v.V.Sign() > 0 && v.V.BitLen() <= 6 {
+		return []byte{byte(v.V.Int64())} // Small positive, 0x01-0x3F
 	}
 	// Larger integers
 	bytes := v.V.Bytes()